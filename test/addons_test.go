@@ -4,41 +4,223 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/blang/semver"
 	"gopkg.in/yaml.v2"
-	"sigs.k8s.io/kind/pkg/apis/config/v1alpha3"
-	"sigs.k8s.io/kind/pkg/cluster"
 
-	"github.com/mesosphere/kubeaddons/hack/temp"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/history"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/installed"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/metrics"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/profile"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/status"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/testconfig"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
 	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
 	"github.com/mesosphere/kubeaddons/pkg/catalog"
+	"github.com/mesosphere/kubeaddons/pkg/repositories"
 	"github.com/mesosphere/kubeaddons/pkg/repositories/git"
 	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
-	"github.com/mesosphere/kubeaddons/pkg/test"
-	"github.com/mesosphere/kubeaddons/pkg/test/cluster/kind"
 )
 
 const (
-	defaultKubernetesVersion = "1.16.4"
-	patchStorageClass        = `{"metadata": {"annotations":{"storageclass.kubernetes.io/is-default-class":"false"}}}`
+	revisionAnnotation  = installed.RevisionAnnotation
+	defaultWaitInterval = 5 * time.Second
+	patchStorageClass   = `{"metadata": {"annotations":{"storageclass.kubernetes.io/is-default-class":"false"}}}`
 )
 
-var addonTestingGroups = make(map[string][]string)
+// testConfig is the configuration of the run, read from TEST_CONFIG (default:
+// config.yaml) with the overrides of the environment (including those of the
+// profile) once the profile is applied.
+var testConfig *testconfig.Config
+
+// addonTestingGroups are the testing groups defined in groups.yaml.
+var addonTestingGroups groups.Groups
+
+// groupBudgets are the duration budgets of the testing groups in groups.yaml.
+var groupBudgets groups.Budgets
+
+// architecture describes how addons are tested on a host architecture.
+type architecture struct {
+	// NodeImage is the kind node image, tagged with the kubernetes version
+	NodeImage string `yaml:"nodeImage"`
+
+	// BuildNodeImages is set for architectures whose kind node images are not
+	// published: they are built on the host with `make node-image`, and
+	// kubernetes versions without a node image fail before a cluster is
+	// created.
+	BuildNodeImages bool `yaml:"buildNodeImages"`
+
+	// Emulation is an image which registers qemu emulators for other
+	// architectures on the docker host, so the images of addons which are only
+	// published for amd64 run emulated. It is run before the first cluster is
+	// created.
+	Emulation string `yaml:"emulation"`
+
+	// Skip maps the names of addons which are not tested to the reason why
+	Skip map[string]string `yaml:"skip"`
+}
+
+var architectures = make(map[string]architecture)
+
+// runReport collects the results of all tests and is written to REPORT_DIR
+// (default: reports/) once all tests have run.
+var runReport = report.New()
+
+// statusServer exposes the progress of the run over HTTP when STATUS_ADDR is
+// set (e.g. STATUS_ADDR=:8080), it is nil (and does nothing) otherwise.
+var statusServer *status.Server
 
 func init() {
-	b, err := ioutil.ReadFile("groups.yaml")
+	var err error
+	if addonTestingGroups, err = groups.Load("groups.yaml"); err != nil {
+		panic(err)
+	}
+	if groupBudgets, err = groups.LoadBudgets("groups.yaml"); err != nil {
+		panic(err)
+	}
+
+	b, err := ioutil.ReadFile("architectures.yaml")
 	if err != nil {
 		panic(err)
 	}
 
-	if err := yaml.Unmarshal(b, addonTestingGroups); err != nil {
+	if err := yaml.Unmarshal(b, architectures); err != nil {
 		panic(err)
 	}
 }
 
+func TestMain(m *testing.M) {
+	// the self-tests run the test binary as the kubectl of a fake cluster
+	if state := os.Getenv("FAKE_CLUSTER"); state != "" {
+		os.Exit(fakeKubectl(state, os.Args[1:]))
+	}
+
+	if addr := os.Getenv("STATUS_ADDR"); addr != "" {
+		var err error
+		if statusServer, err = status.Start(addr); err != nil {
+			fmt.Fprintf(os.Stderr, "could not start status server: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("serving test status on http://%s\n", statusServer.Addr())
+	}
+
+	activeProfile, err := applyProfile()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not apply profile: %s\n", err)
+		os.Exit(1)
+	}
+
+	configFile := os.Getenv("TEST_CONFIG")
+	if configFile == "" {
+		configFile = "config.yaml"
+	}
+	if testConfig, err = testconfig.Load(configFile, os.Getenv); err != nil {
+		fmt.Fprintf(os.Stderr, "could not load the test configuration: %s\n", err)
+		os.Exit(1)
+	}
+	if err := joinExtraAddons(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not add the addons of the extra repositories: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := startDockerGC(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not start docker gc: %s\n", err)
+		os.Exit(1)
+	}
+	if checkpointing() {
+		fmt.Printf("scenarios of this run are checkpointed as run %s in %s\n", runID, checkpointDir())
+	}
+
+	code := m.Run()
+	statusServer.SetPhase("run", "finished")
+	finishDockerGC()
+
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	if len(runReport.Addons) > 0 {
+		if err := runReport.Write(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write report: %s\n", err)
+			code = 1
+		}
+		fmt.Print(runReport.Table())
+	}
+
+	runReport.Finish()
+	if activeProfile != nil {
+		if err := signOff(activeProfile, code == 0, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "could not write sign off: %s\n", err)
+			code = 1
+		}
+	}
+	for _, exporter := range exporters() {
+		if err := exporter.Export(runReport); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		}
+	}
+	if err := recordHistory(activeProfile); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+
+	// failed runs exit with the code of the type of their failures (see
+	// report.ExitCodes), so that CI can retry runs which failed on the
+	// infrastructure only
+	if code != 0 {
+		if failureCode := runReport.ExitCode(); failureCode != 0 {
+			code = failureCode
+		}
+	}
+	os.Exit(code)
+}
+
+// exporters returns the metrics exporters configured by METRICS_PUSHGATEWAY
+// and METRICS_INFLUXDB.
+func exporters() []metrics.Exporter {
+	job := os.Getenv("METRICS_JOB")
+	if job == "" {
+		job = "kommander-addons"
+	}
+
+	var exporters []metrics.Exporter
+	if url := os.Getenv("METRICS_PUSHGATEWAY"); url != "" {
+		exporters = append(exporters, metrics.Pushgateway{URL: url, Job: job})
+	}
+	if url := os.Getenv("METRICS_INFLUXDB"); url != "" {
+		database := os.Getenv("METRICS_INFLUXDB_DATABASE")
+		if database == "" {
+			database = job
+		}
+		exporters = append(exporters, metrics.InfluxDB{URL: url, Database: database})
+	}
+	return exporters
+}
+
+// recordHistory records the outcomes and timings of the run in the history
+// of runs at RUN_HISTORY (a file, or an s3://<bucket>/<prefix> URL), if set.
+// Runs which tested no addons are not recorded.
+func recordHistory(activeProfile *profile.Profile) error {
+	location := os.Getenv("RUN_HISTORY")
+	if location == "" || len(runReport.Addons) == 0 {
+		return nil
+	}
+	name := ""
+	if activeProfile != nil {
+		name = activeProfile.Name
+	}
+	store := history.Open(location)
+	if err := store.Record(history.FromReport(runID, name, runReport)); err != nil {
+		return fmt.Errorf("could not record the run in history %s: %w", store, err)
+	}
+	return nil
+}
+
 func TestValidateUnhandledAddons(t *testing.T) {
 	unhandled, err := findUnhandled()
 	if err != nil {
@@ -50,96 +232,133 @@ func TestValidateUnhandledAddons(t *testing.T) {
 		for _, addon := range unhandled {
 			names = append(names, addon.GetName())
 		}
-		t.Fatal(fmt.Errorf("the following addons are not handled as part of a testing group: %+v", names))
+		t.Fatal(fmt.Errorf("the following addons are not handled as part of a testing group: %+v (see `go run scripts/test-wrapper.go scaffold`)", names))
 	}
 }
 
 func TestKommanderGroup(t *testing.T) {
-	if err := testgroup(t, "kommander"); err != nil {
+	if err := testscenario(t, "kommander-minimal"); err != nil {
 		t.Fatal(err)
 	}
 }
 
-// -----------------------------------------------------------------------------
-// Private Functions
-// -----------------------------------------------------------------------------
-
-func testgroup(t *testing.T, groupname string) error {
-	t.Logf("testing group %s", groupname)
-
-	version, err := semver.Parse(defaultKubernetesVersion)
-	if err != nil {
-		return err
+// TestScenario runs the scenarios (see scenarios/) named in SCENARIO, which is
+// a comma separated list of names, e.g. SCENARIO=kommander-full-ha, or all
+// standalone ones for SCENARIO=all (e.g. to shard them, see SHARD_TOTAL); the
+// others are run by the tests which perform their steps.
+func TestScenario(t *testing.T) {
+	names := os.Getenv("SCENARIO")
+	if names == "" {
+		t.Skip("SCENARIO is not set")
 	}
 
-	cluster, err := kind.NewCluster(version, cluster.CreateWithV1Alpha3Config(&v1alpha3.Cluster{}))
-	if err != nil {
-		// try to clean up in case cluster was created and reference available
-		if cluster != nil {
-			_ = cluster.Cleanup()
+	scenarios := strings.Split(names, ",")
+	if names == "all" {
+		var err error
+		if scenarios, _, err = scenario.ListStandalone("scenarios"); err != nil {
+			t.Fatal(err)
 		}
-		return err
 	}
-	defer cluster.Cleanup()
+	for _, name := range scenarios {
+		name := strings.TrimSpace(name)
+		t.Run(name, func(t *testing.T) {
+			if err := testscenario(t, name); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
 
-	if err := temp.DeployController(cluster, "kind"); err != nil {
-		return err
+// TestKommanderScopes runs the kommander group once for each of the scopes
+// kommander (and the KubeFed control plane it ships) can be installed in.
+func TestKommanderScopes(t *testing.T) {
+	for _, name := range []string{"kommander-scope-cluster", "kommander-scope-namespaced"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			if err := testscenario(t, name); err != nil {
+				t.Fatal(err)
+			}
+		})
 	}
+}
+
+// -----------------------------------------------------------------------------
+// Private Functions
+// -----------------------------------------------------------------------------
+
+func addons(names ...string) ([]v1beta1.AddonInterface, error) {
+	var testAddons []v1beta1.AddonInterface
 
-	addons, err := addons(addonTestingGroups[groupname]...)
+	addons, err := latestAddons()
 	if err != nil {
-		return err
+		return testAddons, err
 	}
 
-	ph, err := test.NewBasicTestHarness(t, cluster, addons...)
-	if err != nil {
-		return err
+	for _, addon := range addons {
+		for _, name := range names {
+			if addon.GetName() == name {
+				testAddons = append(testAddons, addon)
+			}
+		}
 	}
-	defer ph.Cleanup()
 
-	ph.Validate()
-	ph.Deploy()
+	if len(testAddons) != len(names) {
+		return testAddons, fmt.Errorf("got %d addons, expected %d", len(testAddons), len(names))
+	}
 
-	return nil
+	return testAddons, nil
 }
 
-func addons(names ...string) ([]v1beta1.AddonInterface, error) {
-	var testAddons []v1beta1.AddonInterface
+// latestAddons returns the most recent revision of every addon in the catalog
+// (this repository plus the kubernetes base addons and the extra repositories
+// of the test configuration), with CI overrides applied.
+func latestAddons() ([]v1beta1.AddonInterface, error) {
+	var latest []v1beta1.AddonInterface
 
 	repo, err := local.NewRepository("base", "../addons")
 	if err != nil {
-		return testAddons, err
+		return latest, err
 	}
 
-	externalRepo, err := git.NewRemoteRepository("https://github.com/mesosphere/kubernetes-base-addons", "master", "origin")
+	externalRepo, err := git.NewRemoteRepository(testConfig.KBAURL, testConfig.KBARef, "origin")
 	if err != nil {
-		return testAddons, err
+		return latest, err
+	}
+	if err := verifyCatalog(externalRepo); err != nil {
+		return latest, err
 	}
 
-	cat, err := catalog.NewCatalog(repo, externalRepo)
+	extraRepos, err := extraRepositories()
 	if err != nil {
-		return testAddons, err
+		return latest, err
 	}
 
-	addons, err := cat.ListAddons()
+	return latestCatalogAddons(append([]repositories.Repository{repo, externalRepo}, extraRepos...)...)
+}
+
+// latestCatalogAddons returns the most recent revision of every addon in a
+// catalog of the given repositories, with CI overrides applied.
+func latestCatalogAddons(repos ...repositories.Repository) ([]v1beta1.AddonInterface, error) {
+	var latest []v1beta1.AddonInterface
+
+	cat, err := catalog.NewCatalog(repos...)
 	if err != nil {
-		return testAddons, err
+		return latest, err
 	}
 
-	for _, addon := range addons {
-		for _, name := range names {
-			overrides(addon[0])
-			if addon[0].GetName() == name {
-				testAddons = append(testAddons, addon[0])
-			}
-		}
+	addons, err := cat.ListAddons()
+	if err != nil {
+		return latest, err
 	}
 
-	if len(testAddons) != len(names) {
-		return testAddons, fmt.Errorf("got %d addons, expected %d", len(testAddons), len(names))
+	for _, revisions := range addons {
+		if err := overrides(revisions[0]); err != nil {
+			return latest, err
+		}
+		latest = append(latest, revisions[0])
 	}
 
-	return testAddons, nil
+	return latest, nil
 }
 
 func findUnhandled() ([]v1beta1.AddonInterface, error) {
@@ -157,8 +376,8 @@ func findUnhandled() ([]v1beta1.AddonInterface, error) {
 		addon := revisions[0]
 		found := false
 		for _, v := range addonTestingGroups {
-			for _, name := range v {
-				if name == addon.GetName() {
+			for _, entry := range v {
+				if entry.Name == addon.GetName() {
 					found = true
 				}
 			}
@@ -171,23 +390,112 @@ func findUnhandled() ([]v1beta1.AddonInterface, error) {
 	return unhandled, nil
 }
 
-func kubectl(args ...string) error {
-	cmd := exec.Command("kubectl", args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
-}
-
 // -----------------------------------------------------------------------------
 // Private - CI Values Overrides
 // -----------------------------------------------------------------------------
 
 // TODO: a temporary place to put configuration overrides for addons
 // See: https://jira.mesosphere.com/browse/DCOS-62137
-func overrides(addon v1beta1.AddonInterface) {
+func overrides(addon v1beta1.AddonInterface) error {
 	if v, ok := addonOverrides[addon.GetName()]; ok {
+		if err := validateOverride(addon, v); err != nil {
+			return err
+		}
 		addon.GetAddonSpec().ChartReference.Values = &v
 	}
+	return nil
+}
+
+// validateOverride verifies that an override parses as YAML and, merged over
+// the default values of the addon's chart, matches the values schema of the
+// chart (where the chart has one). Keys which do not exist in the default
+// values of the chart are warned about.
+func validateOverride(addon v1beta1.AddonInterface, override string) error {
+	parsed, err := values.Parse(override)
+	if err != nil {
+		return fmt.Errorf("override for addon %s is not valid YAML: %w", addon.GetName(), err)
+	}
+
+	ref := addon.GetAddonSpec().ChartReference
+	if ref == nil {
+		return fmt.Errorf("override for addon %s can not be applied, it does not reference a chart", addon.GetName())
+	}
+
+	chart, err := render.PullChart(ref)
+	if err != nil {
+		return err
+	}
+
+	if chart.Schema != nil {
+		violations, err := values.ValidateSchema(values.Merge(chart.Values, parsed), chart.Schema)
+		if err != nil {
+			return err
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("override for addon %s does not match the values schema of chart %s:\n%s", addon.GetName(), ref.Chart, strings.Join(violations, "\n"))
+		}
+	}
+
+	for _, key := range values.UnknownKeys(parsed, chart.Values) {
+		fmt.Fprintf(os.Stderr, "WARNING: override for addon %s sets %s, which does not exist in the default values of chart %s %s\n", addon.GetName(), key, ref.Chart, ref.Version)
+	}
+
+	return nil
+}
+
+// layerValues merges the overlay over the values of an addon's chart reference.
+func layerValues(addon v1beta1.AddonInterface, overlay string) error {
+	ref := addon.GetAddonSpec().ChartReference
+	if ref == nil {
+		return fmt.Errorf("can not layer values over addon %s, it does not reference a chart", addon.GetName())
+	}
+
+	base := ""
+	if ref.Values != nil {
+		base = *ref.Values
+	}
+
+	merged, err := mergeValues(base, overlay)
+	if err != nil {
+		return fmt.Errorf("could not layer values over addon %s: %w", addon.GetName(), err)
+	}
+	ref.Values = &merged
+
+	return nil
+}
+
+// mergeValues deep merges the YAML overlay over the YAML base, with maps being
+// merged recursively and all other values of the overlay replacing the base.
+func mergeValues(base, overlay string) (string, error) {
+	baseValues := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(base), &baseValues); err != nil {
+		return "", err
+	}
+
+	overlayValues := make(map[interface{}]interface{})
+	if err := yaml.Unmarshal([]byte(overlay), &overlayValues); err != nil {
+		return "", err
+	}
+
+	b, err := yaml.Marshal(mergeMaps(baseValues, overlayValues))
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+func mergeMaps(base, overlay map[interface{}]interface{}) map[interface{}]interface{} {
+	for k, v := range overlay {
+		baseMap, baseIsMap := base[k].(map[interface{}]interface{})
+		overlayMap, overlayIsMap := v.(map[interface{}]interface{})
+		if baseIsMap && overlayIsMap {
+			base[k] = mergeMaps(baseMap, overlayMap)
+			continue
+		}
+		base[k] = v
+	}
+	return base
 }
 
 var addonOverrides = map[string]string{
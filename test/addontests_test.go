@@ -0,0 +1,94 @@
+package test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kuttl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// addonTestsDir contains the declarative test cases of addons, in the layout
+// of kuttl: tests/<addon>/<case>/<index>-<name>.yaml.
+const addonTestsDir = "tests"
+
+func TestValidateAddonTests(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join(addonTestsDir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		known[addon.GetName()] = true
+	}
+	for _, dir := range dirs {
+		if !known[filepath.Base(dir)] {
+			t.Errorf("test cases %s are for an addon which does not exist", dir)
+			continue
+		}
+		if _, err := kuttl.Load(dir); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// runAddonTests runs the test cases of the deployed addons (see tests/), each
+// in a namespace of its own which is removed afterwards. The cases are
+// reported like checks, as tests/<addon>/<case>.
+func runAddonTests(t *testing.T, k kubectl.Client, s *scenario.Scenario, addons []v1beta1.AddonInterface, skipped map[string]string) error {
+	for _, addon := range addons {
+		name := addon.GetName()
+		cases, err := kuttl.Load(filepath.Join(addonTestsDir, name))
+		if err != nil {
+			return err
+		}
+		for _, c := range cases {
+			c := c
+			check := checks.Check{Name: fmt.Sprintf("%s/%s/%s", addonTestsDir, name, c.Name), Addon: name}
+			t.Run(check.Name, func(t *testing.T) {
+				if reason, ok := skipped[name]; ok {
+					recordCheck(s, check, report.Skipped, reason)
+					t.Skipf("addon %s is skipped: %s", name, reason)
+				}
+				if err := runAddonTest(t, k, name, c); err != nil {
+					recordCheck(s, check, report.Failed, err.Error())
+					t.Error(err)
+					return
+				}
+				recordCheck(s, check, report.Passed, "")
+			})
+		}
+	}
+	return nil
+}
+
+// runAddonTest runs the steps of a test case of an addon, removing the
+// objects it applied and its namespace afterwards.
+func runAddonTest(t *testing.T, k kubectl.Client, addon string, c kuttl.Case) error {
+	namespace := kuttl.Namespace(addon, c.Name)
+	if err := k.Run("create", "namespace", namespace); err != nil {
+		return err
+	}
+	defer func() {
+		if err := c.Cleanup(k, namespace); err != nil {
+			logf(t, "WARNING: could not remove the objects of test case %s: %s", c.Name, err)
+		}
+		if err := k.Run("delete", "namespace", namespace, "--ignore-not-found", "--wait=false"); err != nil {
+			logf(t, "WARNING: could not remove namespace %s: %s", namespace, err)
+		}
+	}()
+
+	logf(t, "running %d steps of test case %s of addon %s in namespace %s", len(c.Steps), c.Name, addon, namespace)
+	return c.Run(k, namespace, testConfig.Timeouts.Wait)
+}
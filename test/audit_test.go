@@ -0,0 +1,41 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/audit"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+)
+
+const auditFile = "audit.yaml"
+
+// auditLogging returns whether the API servers of kind clusters write an
+// audit log which is analyzed once the addons are deployed, which is the case
+// when AUDIT_LOG=true is set.
+func auditLogging() bool {
+	return os.Getenv("AUDIT_LOG") == "true" && clusterProvider() == provider.Kind
+}
+
+// analyzeAuditLog fails for every request forbidden by audit.yaml which the
+// API servers of a kind cluster received while the addons were deployed, from
+// start to end, and for every list storm.
+func analyzeAuditLog(t *testing.T, cluster string, start, end time.Time) error {
+	if !auditLogging() {
+		return nil
+	}
+	config, err := audit.Load(auditFile)
+	if err != nil {
+		return err
+	}
+	events, err := audit.Read(cluster)
+	if err != nil {
+		return err
+	}
+	logf(t, "analyzing %d audited requests", len(events))
+	for _, violation := range config.Analyze(events, start, end) {
+		t.Error(violation)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/availability"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// verifyAvailability fails the test for autoscalers of the deployed addons
+// which can't scale their target or compute their metrics and for disruption
+// budgets which select no pods or allow none of them to be evicted, which
+// would otherwise only show up when the cluster is scaled or drained.
+func verifyAvailability(t *testing.T, k kubectl.Client, objects []render.Object) error {
+	problems, err := availability.Verify(k, objects, testConfig.Timeouts.Wait, defaultWaitInterval)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		t.Errorf("availability problem: %s", problem)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha3"
+	"sigs.k8s.io/kind/pkg/cluster"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/bisect"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/repositories"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/git"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+	"github.com/mesosphere/kubeaddons/pkg/test"
+	testcluster "github.com/mesosphere/kubeaddons/pkg/test/cluster"
+	"github.com/mesosphere/kubeaddons/pkg/test/cluster/kind"
+)
+
+// TestBisect finds the first bad revision of an addon. It is
+// only run when BISECT_ADDON is set and is configured with:
+//
+//	BISECT_ADDON  - the name of the failing addon
+//	BISECT_GOOD   - a git revision where the addon is known to deploy
+//	BISECT_BAD    - a git revision where the addon is known to fail (default: HEAD)
+//	BISECT_SOURCE - "kommander" to bisect this repository (default) or "kba" to
+//	                bisect revisions (SHAs) of the kubernetes base addons repository
+//
+// A single cluster is reused to deploy and clean up the addon (along with the
+// addons it requires) for each candidate revision, which is good when the
+// addon deploys and the checks of its testing groups pass. The test fails
+// with the culprit revision and its diff when the first bad revision has been
+// found.
+func TestBisect(t *testing.T) {
+	name := os.Getenv("BISECT_ADDON")
+	if name == "" {
+		t.Skip("BISECT_ADDON is not set")
+	}
+
+	good, bad := os.Getenv("BISECT_GOOD"), os.Getenv("BISECT_BAD")
+	if good == "" {
+		t.Fatal("BISECT_GOOD is required for bisecting")
+	}
+	if bad == "" {
+		bad = "HEAD"
+	}
+
+	workdir, err := ioutil.TempDir("", "bisect-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workdir)
+
+	gitDir := ".."
+	source := os.Getenv("BISECT_SOURCE")
+	switch source {
+	case "", "kommander":
+		source = "kommander"
+	case "kba":
+		gitDir = filepath.Join(workdir, "kba")
+		if err := bisect.Clone(testConfig.KBAURL, gitDir); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("unknown BISECT_SOURCE %q", source)
+	}
+
+	addonPath := filepath.Join("addons", name)
+	candidates, err := bisect.Candidates(gitDir, good, bad, addonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("bisecting %d revisions of addon %s in %s between %s and %s", len(candidates), name, source, good, bad)
+
+	version := testConfig.KubernetesVersion
+
+	// like the clusters of scenarios, the cluster gets a kubeconfig of its own
+	k := kubectl.Client{Kubeconfig: filepath.Join(workdir, "kubeconfig")}
+	cluster, err := kind.NewCluster(version, cluster.CreateWithV1Alpha3Config(&v1alpha3.Cluster{}), cluster.CreateWithKubeconfigPath(k.Kubeconfig))
+	if cluster != nil {
+		recordCluster(cluster.Name())
+	}
+	if err != nil {
+		if cluster != nil {
+			_ = cluster.Cleanup()
+		}
+		t.Fatal(err)
+	}
+	defer cluster.Cleanup()
+
+	reached, err := reachableCluster(k, cluster)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := waitForCluster(t, k, reached.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if err := deployController(reached); err != nil {
+		t.Fatal(err)
+	}
+
+	firstBad, err := bisect.FirstBad(len(candidates), func(i int) (bool, error) {
+		return bisectRevision(t, k, reached, source, gitDir, filepath.Join(workdir, candidates[i]), candidates[i], name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	culprit := candidates[firstBad]
+	diff, err := bisect.Diff(gitDir, culprit, addonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Fatalf("the first bad revision of addon %s is %s:\n%s", name, culprit, diff)
+}
+
+// bisectRevision deploys an addon (and the addons it requires) at the given
+// revision of a bisect source, runs the checks of its testing groups which
+// cover the deployed addons and cleans up, reporting whether the deployment
+// and the checks succeeded.
+func bisectRevision(t *testing.T, k kubectl.Client, cluster testcluster.Cluster, source, gitDir, worktree, revision, name string) (bool, error) {
+	removeWorktree, err := bisect.Checkout(gitDir, revision, worktree)
+	if err != nil {
+		return false, err
+	}
+	defer removeWorktree()
+
+	var repos []repositories.Repository
+	switch source {
+	case "kommander":
+		repo, err := local.NewRepository("base", filepath.Join(worktree, "addons"))
+		if err != nil {
+			return false, err
+		}
+		externalRepo, err := git.NewRemoteRepository(testConfig.KBAURL, testConfig.KBARef, "origin")
+		if err != nil {
+			return false, err
+		}
+		repos = append(repos, repo, externalRepo)
+	case "kba":
+		repo, err := local.NewRepository("base", "../addons")
+		if err != nil {
+			return false, err
+		}
+		externalRepo, err := local.NewRepository("kba", filepath.Join(worktree, "addons"))
+		if err != nil {
+			return false, err
+		}
+		repos = append(repos, repo, externalRepo)
+	}
+	extraRepos, err := extraRepositories()
+	if err != nil {
+		return false, err
+	}
+	repos = append(repos, extraRepos...)
+
+	catalogAddons, err := latestCatalogAddons(repos...)
+	if err != nil {
+		return false, err
+	}
+
+	addons, err := requirements.Resolve(name, catalogAddons)
+	if err != nil {
+		return false, err
+	}
+
+	return t.Run(fmt.Sprintf("revision-%s", revision), func(t *testing.T) {
+		ph, err := test.NewBasicTestHarness(t, cluster, addons...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ph.Cleanup()
+
+		ph.Validate()
+		ph.Deploy()
+
+		if err := runBisectChecks(t, k, name, addons); err != nil {
+			t.Fatal(err)
+		}
+	}), nil
+}
+
+// runBisectChecks runs the checks of the testing groups of an addon which
+// cover the deployed addons, failing the test when any of them fails.
+func runBisectChecks(t *testing.T, k kubectl.Client, name string, deployed []v1beta1.AddonInterface) error {
+	s := &scenario.Scenario{Name: "bisect-" + name, Backend: scenario.ControllerBackend}
+	for _, groupname := range listGroups() {
+		for _, addonName := range addonTestingGroups.Addons(groupname) {
+			if addonName == name {
+				s.Groups = append(s.Groups, groupname)
+			}
+		}
+	}
+
+	groupChecks, err := scenarioChecks(s)
+	if err != nil {
+		return err
+	}
+	deployedNames := make(map[string]bool, len(deployed))
+	for _, addon := range deployed {
+		deployedNames[addon.GetName()] = true
+	}
+	var covered []checks.Check
+	for _, check := range groupChecks {
+		if deployedNames[check.Addon] {
+			check.Timeout = addonTimeout(check.Addon, testConfig.KubernetesVersion, check.Timeout)
+			covered = append(covered, check)
+		}
+	}
+	return runcheckset(t, k, s, covered, nil)
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// checkBudget compares the duration of a scenario with the budget of its
+// testing groups and records it in the run report. Scenarios exceeding their
+// budget by more than the budget factor fail with BUDGET_EXCEEDED=fail and
+// warn otherwise.
+func checkBudget(t *testing.T, s *scenario.Scenario, actual time.Duration) {
+	budget, ok := groupBudgets.Budget(s.Groups)
+	if !ok {
+		return
+	}
+	result := report.BudgetResult{Scenario: s.Name, Groups: s.Groups, Budget: budget, Actual: actual.Round(time.Second)}
+	limit := time.Duration(float64(budget) * testConfig.BudgetFactor)
+	result.Exceeded = actual > limit
+	runReport.AddBudget(result)
+
+	if !result.Exceeded {
+		logf(t, "scenario %s took %s of its budget of %s (groups: %v)", s.Name, result.Actual, budget, s.Groups)
+		return
+	}
+	if testConfig.BudgetFails {
+		t.Errorf("scenario %s took %s, exceeding the budget of %s of groups %v by more than a factor of %g", s.Name, result.Actual, budget, s.Groups, testConfig.BudgetFactor)
+		return
+	}
+	logf(t, "WARNING: scenario %s took %s, exceeding the budget of %s of groups %v by more than a factor of %g", s.Name, result.Actual, budget, s.Groups, testConfig.BudgetFactor)
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const (
+	// canaryNamespace and canaryIngress are the namespace (before its prefix,
+	// see seedParams) and the ingress of the releases of the kommander-canary
+	// seed.
+	canaryNamespace = "kommander-canary"
+	canaryIngress   = "echo"
+
+	// canaryPromoted are the weights of the services of the ingress once the
+	// canary is promoted.
+	canaryPromoted = "echo-canary: 100%\n"
+)
+
+// TestKommanderCanary runs the kommander-canary scenario, whose checks verify
+// that traefik splits the traffic between a stable and a canary release by
+// their weights, then promotes the canary by raising its weight to 100% and
+// checks that it serves all traffic (checks/kommander-canary-promoted.yaml).
+// The test is skipped unless KOMMANDER_CANARY is true.
+func TestKommanderCanary(t *testing.T) {
+	if os.Getenv("KOMMANDER_CANARY") != "true" {
+		t.Skip("KOMMANDER_CANARY is not true")
+	}
+
+	if err := testscenario(t, "kommander-canary", testCanaryPromotion); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCanaryPromotion(t *testing.T, k kubectl.Client) error {
+	s, err := scenario.Load("scenarios", "kommander-canary")
+	if err != nil {
+		return err
+	}
+	promoted, err := checks.Load(filepath.Join("checks", "kommander-canary-promoted.yaml"))
+	if err != nil {
+		return err
+	}
+
+	logf(t, "promoting the canary release")
+	if err := k.Run("annotate", "--namespace", seedParams().Namespace(canaryNamespace), "--overwrite", "ingress", canaryIngress, "traefik.ingress.kubernetes.io/service-weights="+canaryPromoted); err != nil {
+		return err
+	}
+	return runcheckset(t, k, s, promoted, skippedAddons())
+}
@@ -0,0 +1,111 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/appcatalog"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/federation"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+)
+
+// catalogProject is the project of the default workspace the application of
+// the catalog is installed into.
+const catalogProject = "catalog-test"
+
+// TestKommanderCatalog runs the kommander-catalog scenario and exercises the
+// application catalog of kommander the way its UI does: it lists the
+// applications of the kubeaddons-catalog API, gets an application from it
+// (KOMMANDER_CATALOG_APP, default: reloader) and installs it into a project of
+// the default workspace placed on an attached kind cluster. The application
+// must be ready on the attached cluster and kommander must report it as
+// propagated. The test is skipped unless KOMMANDER_CATALOG is true.
+func TestKommanderCatalog(t *testing.T) {
+	if os.Getenv("KOMMANDER_CATALOG") != "true" {
+		t.Skip("KOMMANDER_CATALOG is not true")
+	}
+	if clusterProvider() != provider.Kind {
+		t.Skipf("the attached cluster is created with kind, not the %s cluster provider", clusterProvider())
+	}
+	name := os.Getenv("KOMMANDER_CATALOG_APP")
+	if name == "" {
+		name = "reloader"
+	}
+
+	if err := testscenario(t, "kommander-catalog", func(t *testing.T, k kubectl.Client) error {
+		return testCatalogFlow(t, k, name)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCatalogFlow(t *testing.T, k kubectl.Client, name string) error {
+	proxy, err := k.StartProxy()
+	if err != nil {
+		return err
+	}
+	defer proxy.Stop()
+	client := appcatalog.New(proxy)
+
+	apps, err := client.Apps()
+	if err != nil {
+		return err
+	}
+	logf(t, "the catalog lists %d applications", len(apps))
+	listed, ok := appcatalog.Find(apps, name)
+	if !ok {
+		return fmt.Errorf("application %s is not listed by the catalog", name)
+	}
+	if !listed.Namespaced() {
+		return fmt.Errorf("application %s is a %s, which can't be installed into a workspace", name, listed.Kind)
+	}
+	app, err := client.App(name)
+	if err != nil {
+		return err
+	}
+
+	cleanup, err := attachCluster(t, k)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	project := federation.Project{
+		Name:      catalogProject,
+		Workspace: federationWorkspace,
+		Namespace: catalogProject,
+		Clusters:  []string{attachedCluster},
+	}
+	logf(t, "creating project %s on cluster %s", project.Name, attachedCluster)
+	if err := federation.CreateProject(k, project, testConfig.Timeouts.Federation); err != nil {
+		return err
+	}
+	defer func() {
+		if err := federation.DeleteProject(k, project); err != nil {
+			logf(t, "WARNING: could not delete project %s: %s", project.Name, err)
+		}
+	}()
+
+	logf(t, "installing application %s (revision %s) of the catalog into project %s", app.Name, app.Revision, project.Name)
+	if _, err := federation.DeployAddon(k, project, app.Manifest); err != nil {
+		return err
+	}
+	defer func() {
+		if err := federation.RemoveAddon(k, project, app.Name); err != nil {
+			logf(t, "WARNING: could not remove application %s: %s", app.Name, err)
+		}
+	}()
+
+	if err := federation.WaitReady(k, "kind-"+attachedCluster, project.Namespace, app.Name, testConfig.Timeouts.Federation); err != nil {
+		t.Error(err)
+		return nil
+	}
+	if err := federation.WaitPropagated(k, project, app.Name, testConfig.Timeouts.Federation); err != nil {
+		t.Error(err)
+		return nil
+	}
+	logf(t, "application %s of the catalog was installed on cluster %s", app.Name, attachedCluster)
+	return nil
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checkpoint"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	testcluster "github.com/mesosphere/kubeaddons/pkg/test/cluster"
+)
+
+// runID identifies this run in the checkpoints and the docker gc ledger. A
+// run resumed with RESUME_RUN keeps the ID of the run it resumes.
+var runID = func() string {
+	if id := os.Getenv("RESUME_RUN"); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%s-%d", time.Now().UTC().Format("20060102-150405"), os.Getpid())
+}()
+
+// checkpointing returns whether the progress of scenarios is checkpointed,
+// with CHECKPOINT=true or when a run is resumed.
+func checkpointing() bool {
+	return os.Getenv("CHECKPOINT") == "true" || os.Getenv("RESUME_RUN") != ""
+}
+
+// checkpointDir returns the directory of the checkpoints, CHECKPOINT_DIR
+// (default: kubeaddons-kommander-checkpoints in the temporary directory).
+func checkpointDir() string {
+	if dir := os.Getenv("CHECKPOINT_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "kubeaddons-kommander-checkpoints")
+}
+
+// loadCheckpoint returns the checkpoint of a scenario in this run, nil unless
+// checkpointing is enabled.
+func loadCheckpoint(t *testing.T, s *scenario.Scenario) (*checkpoint.Checkpoint, error) {
+	if !checkpointing() {
+		return nil, nil
+	}
+	cp, err := checkpoint.Load(checkpointDir(), runID, s.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cp.Completed != "" {
+		logf(t, "resuming scenario %s of run %s after stage %s on %s cluster %s", s.Name, runID, cp.Completed, cp.Provider, cp.Cluster)
+	}
+	return cp, nil
+}
+
+// completeStage records that a stage of a scenario was completed.
+func completeStage(cp *checkpoint.Checkpoint, stage checkpoint.Stage) error {
+	return cp.Complete(stage, time.Now())
+}
+
+// retained returns whether the cluster of a scenario is retained to resume
+// the scenario, i.e. when it is checkpointed and failed after the cluster was
+// provisioned.
+func retained(t *testing.T, cp *checkpoint.Checkpoint, err error) bool {
+	return cp.Done(checkpoint.Provisioned) && (err != nil || t.Failed())
+}
+
+// retainCluster keeps the cluster of a failed scenario, releasing it from
+// the docker garbage collection, and logs how to resume the scenario.
+func retainCluster(t *testing.T, s *scenario.Scenario, cp *checkpoint.Checkpoint, c testcluster.Cluster) {
+	if dockerRun != nil {
+		if err := dockerRun.Release(c.Name()); err != nil {
+			logf(t, "WARNING: %s", err)
+		}
+	}
+	logf(t, "retaining cluster %s of scenario %s, resume it after stage %s with RESUME_RUN=%s", c.Name(), s.Name, cp.Completed, cp.Run)
+}
+
+// resumeCluster returns the cluster retained by the run which is resumed,
+// using the kubeconfig kept along with the checkpoint.
+func resumeCluster(k kubectl.Client, cp *checkpoint.Checkpoint) (testcluster.Cluster, error) {
+	if cp.Provider != clusterProvider() {
+		return nil, fmt.Errorf("cluster %s of run %s was created by the %s cluster provider, not %s", cp.Cluster, cp.Run, cp.Provider, clusterProvider())
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", cp.Kubeconfig())
+	if err != nil {
+		return nil, fmt.Errorf("could not load the kubeconfig of retained cluster %s: %w", cp.Cluster, err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := client.Discovery().ServerVersion(); err != nil {
+		return nil, fmt.Errorf("retained cluster %s of run %s is not reachable: %w", cp.Cluster, cp.Run, err)
+	}
+
+	c := &resumedCluster{name: cp.Cluster, provider: cp.Provider, kubectl: k, config: config, client: client}
+	if cp.Provider == provider.Kind {
+		recordCluster(c.name)
+	}
+	return c, nil
+}
+
+// resumedCluster is a cluster retained by the run which is resumed.
+type resumedCluster struct {
+	name     string
+	provider string
+	kubectl  kubectl.Client
+	config   *rest.Config
+	client   kubernetes.Interface
+}
+
+func (c *resumedCluster) Name() string                 { return c.name }
+func (c *resumedCluster) Provider() string             { return c.provider }
+func (c *resumedCluster) Config() *rest.Config         { return c.config }
+func (c *resumedCluster) Client() kubernetes.Interface { return c.client }
+
+func (c *resumedCluster) Cleanup() error {
+	if c.provider == provider.Kind {
+		cmd := exec.Command("kind", "delete", "cluster", "--name", c.name)
+		cmd.Env = c.kubectl.Env()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("could not delete kind cluster %s: %w: %s", c.name, err, out)
+		}
+		return nil
+	}
+	p, err := provider.Get(c.provider)
+	if err != nil {
+		return err
+	}
+	return p.Delete(c.kubectl, c.name)
+}
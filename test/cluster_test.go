@@ -0,0 +1,295 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/kind/pkg/cluster"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checkpoint"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/container"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/spend"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/wait"
+	testcluster "github.com/mesosphere/kubeaddons/pkg/test/cluster"
+	"github.com/mesosphere/kubeaddons/pkg/test/cluster/kind"
+)
+
+// clusterProvider returns the provider which creates the clusters of
+// scenarios, selected with CLUSTER_PROVIDER (default: kind).
+func clusterProvider() string {
+	if name := os.Getenv("CLUSTER_PROVIDER"); name != "" {
+		return name
+	}
+	return provider.Kind
+}
+
+// missingCapabilities returns the capabilities the cluster provider lacks to
+// run a scenario: those its topology needs and those it requires explicitly.
+func missingCapabilities(s *scenario.Scenario) []provider.Capability {
+	required := make([]provider.Capability, 0, len(s.Requires))
+	for _, capability := range s.Requires {
+		required = append(required, provider.Capability(capability))
+	}
+	topology := provider.Topology{
+		ControlPlanes: s.Cluster.ControlPlanes,
+		Workers:       s.Cluster.Workers,
+		IPFamily:      s.Cluster.IPFamily,
+		Containerd:    !s.Cluster.Containerd.Default(),
+		Clock:         !s.Cluster.Clock.Default(),
+	}
+	return provider.Missing(clusterProvider(), provider.Required(topology, required...))
+}
+
+// nodeImage returns the kind node image of an architecture for a kubernetes
+// version, the node image tagged with it.
+func (a architecture) nodeImage(version semver.Version) string {
+	return fmt.Sprintf("%s:v%s", a.NodeImage, version)
+}
+
+// supportedVersion fails for kubernetes versions the cluster provider can't
+// create clusters of on the architecture, before anything is provisioned:
+// versions whose node image has not been built on architectures which build
+// their node images.
+func supportedVersion(arch architecture, version semver.Version) error {
+	if clusterProvider() != provider.Kind || !arch.BuildNodeImages {
+		return nil
+	}
+	image := arch.nodeImage(version)
+	if exec.Command("docker", "image", "inspect", image).Run() != nil {
+		return fmt.Errorf("node image %s of kubernetes %s is not built on %s, build it with `make node-image KUBERNETES_VERSION=%s`", image, version, runtime.GOARCH, version)
+	}
+	return nil
+}
+
+var (
+	emulationOnce sync.Once
+	emulationErr  error
+)
+
+// emulate registers the emulators of the emulation image of an architecture on
+// the docker host, once per run.
+func (a architecture) emulate() error {
+	if a.Emulation == "" {
+		return nil
+	}
+	emulationOnce.Do(func() {
+		if out, err := exec.Command("docker", "run", "--rm", "--privileged", a.Emulation, "--reset", "--persistent", "yes").CombinedOutput(); err != nil {
+			emulationErr = fmt.Errorf("could not register the emulators of %s: %w: %s", a.Emulation, err, out)
+		}
+	})
+	return emulationErr
+}
+
+// newCluster creates the cluster of a scenario with the cluster provider,
+// adding its context to the kubeconfig file of the client. Kind clusters use
+// the node image of the architecture and are recorded for the docker garbage
+// collection.
+func newCluster(k kubectl.Client, s *scenario.Scenario, version semver.Version, arch architecture) (testcluster.Cluster, error) {
+	name := clusterProvider()
+	if name == provider.Kind {
+		if err := arch.emulate(); err != nil {
+			return nil, err
+		}
+		nodeImage := arch.nodeImage(version)
+		config, err := kindConfig(s.Cluster, filepath.Join(os.TempDir(), "kubeaddons-clock", s.Name))
+		if err != nil {
+			return nil, fmt.Errorf("scenario %s: %w", s.Name, err)
+		}
+		c, err := kind.NewCluster(version, cluster.CreateWithV1Alpha3Config(config), cluster.CreateWithNodeImage(nodeImage), cluster.CreateWithKubeconfigPath(k.Kubeconfig))
+		if c != nil {
+			recordCluster(c.Name())
+		}
+		if err != nil {
+			return c, err
+		}
+		if !s.Cluster.Clock.Default() {
+			if err := s.Cluster.Clock.Verify(c.Name()); err != nil {
+				return c, fmt.Errorf("scenario %s: %w", s.Name, err)
+			}
+		}
+		return reachableCluster(k, c)
+	}
+
+	if mode, err := container.Current(); err != nil || mode == container.Socket {
+		if err == nil {
+			err = fmt.Errorf("only kind clusters can be reached from a container using the docker daemon of the host, run %s clusters with HARNESS_CONTAINER=%s", name, container.DinD)
+		}
+		return nil, err
+	}
+	p, err := provider.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	topology := provider.Topology{
+		KubernetesVersion: version.String(),
+		ControlPlanes:     s.Cluster.ControlPlanes,
+		Workers:           s.Cluster.Workers,
+		IPFamily:          s.Cluster.IPFamily,
+	}
+	c := &providerCluster{name: s.Name, provider: p, kubectl: k}
+	if provider.Cloud(name) {
+		if topology.Budget, err = budget(s); err != nil {
+			return nil, err
+		}
+		c.watchdog = time.AfterFunc(topology.Budget.Remaining(time.Now()), c.exceeded)
+	}
+	if err := p.Create(k, c.name, topology); err != nil {
+		return c, err
+	}
+
+	loadingRules := &clientcmd.ClientConfigLoadingRules{ExplicitPath: k.Kubeconfig}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: p.Context(c.name)}
+	if c.config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig(); err != nil {
+		return c, fmt.Errorf("could not load the kubeconfig of %s cluster %s: %w", name, c.name, err)
+	}
+	if c.client, err = kubernetes.NewForConfig(c.config); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// internalCluster is a kind cluster reached from a container using the docker
+// daemon of the host, over the network of its nodes rather than the port its
+// API server publishes on the host.
+type internalCluster struct {
+	testcluster.Cluster
+	config *rest.Config
+	client kubernetes.Interface
+}
+
+func (c *internalCluster) Config() *rest.Config         { return c.config }
+func (c *internalCluster) Client() kubernetes.Interface { return c.client }
+
+// reachableCluster returns a kind cluster as it is reached by the harness: in
+// a container using the docker daemon of the host (see container.Socket), the
+// container is connected to the docker network of the nodes and the
+// kubeconfig file of the client is replaced by the internal kubeconfig of the
+// cluster.
+func reachableCluster(k kubectl.Client, c testcluster.Cluster) (testcluster.Cluster, error) {
+	mode, err := container.Current()
+	if err != nil || mode != container.Socket {
+		return c, err
+	}
+	if err := container.InternalKubeconfig(c.Name(), dockerNetwork(), k.Kubeconfig); err != nil {
+		return c, err
+	}
+	ic := &internalCluster{Cluster: c}
+	if ic.config, err = clientcmd.BuildConfigFromFlags("", k.Kubeconfig); err != nil {
+		return c, fmt.Errorf("could not load the internal kubeconfig of kind cluster %s: %w", c.Name(), err)
+	}
+	if ic.client, err = kubernetes.NewForConfig(ic.config); err != nil {
+		return c, err
+	}
+	return ic, nil
+}
+
+// waitForCluster waits up to the cluster ready timeout for a new cluster to be
+// ready (see wait.Cluster) before anything is applied to it, so that the
+// controller isn't deployed to a cluster whose API server, CNI or DNS is still
+// starting. The arguments select the cluster, the current context of the
+// client by default.
+func waitForCluster(t *testing.T, k kubectl.Client, name string, args ...string) error {
+	start := time.Now()
+	if err := wait.Cluster(k, testConfig.Timeouts.ClusterReady, defaultWaitInterval, args...); err != nil {
+		return fmt.Errorf("cluster %s: %w", name, err)
+	}
+	logf(t, "cluster %s is ready after %s", name, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// providerCluster is a cluster created by a provider other than kind.
+type providerCluster struct {
+	name     string
+	provider provider.Provider
+	kubectl  kubectl.Client
+	config   *rest.Config
+	client   kubernetes.Interface
+
+	// watchdog deletes clusters on cloud providers which exceed the maximum
+	// duration of their budget.
+	watchdog *time.Timer
+}
+
+func (c *providerCluster) Name() string                 { return c.name }
+func (c *providerCluster) Provider() string             { return c.provider.Name() }
+func (c *providerCluster) Config() *rest.Config         { return c.config }
+func (c *providerCluster) Client() kubernetes.Interface { return c.client }
+
+func (c *providerCluster) Cleanup() error {
+	if c.watchdog != nil {
+		c.watchdog.Stop()
+	}
+	return c.provider.Delete(c.kubectl, c.name)
+}
+
+// exceeded deletes a cluster which exceeded its time budget and aborts the
+// run, which is presumably hung.
+func (c *providerCluster) exceeded() {
+	fmt.Fprintf(os.Stderr, "ERROR: cluster %s exceeded its time budget, deleting it and aborting the run\n", c.name)
+	if err := c.provider.Delete(c.kubectl, c.name); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: could not delete cluster %s, its resources are swept after their deadline: %s\n", c.name, err)
+	}
+	os.Exit(1)
+}
+
+// budget returns the budget of a scenario on a cloud provider, with the
+// limits in spend.yaml. CLOUD_MAX_DURATION overrides their maximum duration.
+func budget(s *scenario.Scenario) (*spend.Budget, error) {
+	limits, err := spend.Load("spend.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxDuration, err = durationEnv("CLOUD_MAX_DURATION", limits.MaxDuration); err != nil {
+		return nil, err
+	}
+	return limits.Start(s.Name, time.Now()), nil
+}
+
+// isolateKubeconfig returns the kubectl client of the cluster of a scenario,
+// whose kubeconfig file is its own rather than the kubeconfig of the
+// environment, so that concurrent scenarios and runs don't switch the current
+// context of each other. The client is passed to everything which reaches the
+// cluster, the environment of the process is left alone. The returned function
+// removes the file. The kubeconfig of a checkpointed scenario is kept along
+// with its checkpoint instead, to resume the scenario.
+func isolateKubeconfig(t *testing.T, s *scenario.Scenario, cp *checkpoint.Checkpoint) (kubectl.Client, func(), error) {
+	if cp != nil {
+		if err := os.MkdirAll(filepath.Dir(cp.Kubeconfig()), 0755); err != nil {
+			return kubectl.Client{}, nil, err
+		}
+		logf(t, "the kubeconfig of the cluster of scenario %s is %s", s.Name, cp.Kubeconfig())
+		return kubectl.Client{Kubeconfig: cp.Kubeconfig()}, func() {}, nil
+	}
+
+	dir, err := ioutil.TempDir("", "kubeconfig-"+s.Name+"-")
+	if err != nil {
+		return kubectl.Client{}, nil, err
+	}
+	path := filepath.Join(dir, "config")
+	logf(t, "the kubeconfig of the cluster of scenario %s is %s", s.Name, path)
+	return kubectl.Client{Kubeconfig: path}, func() { os.RemoveAll(dir) }, nil
+}
+
+// formatCapabilities formats capabilities for messages.
+func formatCapabilities(capabilities []provider.Capability) string {
+	s := make([]string, 0, len(capabilities))
+	for _, capability := range capabilities {
+		s = append(s, string(capability))
+	}
+	return strings.Join(s, ", ")
+}
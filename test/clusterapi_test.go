@@ -0,0 +1,130 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/helm"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+const clusterAPIScenario = "kommander-cluster-api"
+
+// provisioningAddons are the addons of the kommander group which provision
+// clusters (the cluster lifecycle of kommander), deployed to the Cluster API
+// management cluster.
+var provisioningAddons = []string{"kommander"}
+
+// managementIssuer is the issuer of the certificates of kommander, which the
+// cert-manager addon creates on the cluster of the scenario. The management
+// cluster runs the cert-manager clusterctl installs instead, so the issuer is
+// created on its own.
+const managementIssuer = `apiVersion: cert-manager.io/v1alpha2
+kind: ClusterIssuer
+metadata:
+  name: kubernetes-ca
+spec:
+  selfSigned: {}
+`
+
+// TestKommanderClusterAPI runs the kommander-cluster-api scenario on a
+// cluster provisioned with Cluster API and deploys the provisioning addons of
+// the kommander group to its management cluster with the helm backend. They
+// must become ready alongside the Cluster API controllers, which must keep the
+// cluster of the scenario ready. The test is skipped unless
+// KOMMANDER_CLUSTER_API is true, and by providers without the cluster-api
+// capability.
+func TestKommanderClusterAPI(t *testing.T) {
+	if os.Getenv("KOMMANDER_CLUSTER_API") != "true" {
+		t.Skip("KOMMANDER_CLUSTER_API is not true")
+	}
+
+	if err := testscenario(t, "kommander-cluster-api", testClusterAPIFlow); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testClusterAPIFlow(t *testing.T, k kubectl.Client) error {
+	s, err := scenario.Load("scenarios", clusterAPIScenario)
+	if err != nil {
+		return err
+	}
+	addons, _, err := scenarioaddons(s)
+	if err != nil {
+		return err
+	}
+	provisioning, err := managementAddons(addons)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "kubeconfig-"+clusterAPIScenario+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	management, err := provider.Management(k, s.Name, dir)
+	if err != nil {
+		return err
+	}
+
+	if err := management.Apply([]byte(managementIssuer)); err != nil {
+		return err
+	}
+	defer func() {
+		if err := management.Run("delete", "clusterissuer", "kubernetes-ca", "--ignore-not-found"); err != nil {
+			logf(t, "WARNING: could not delete the issuer of the management cluster: %s", err)
+		}
+	}()
+
+	var installed []v1beta1.AddonInterface
+	defer func() {
+		for i := len(installed) - 1; i >= 0; i-- {
+			if err := helm.Uninstall(management, installed[i]); err != nil {
+				logf(t, "WARNING: could not remove addon %s from the management cluster: %s", installed[i].GetName(), err)
+			}
+		}
+	}()
+	for _, addon := range provisioning {
+		logf(t, "deploying addon %s to the management cluster %s", addon.GetName(), provider.ManagementContext(s.Name))
+		if err := helm.Install(management, addon, testConfig.Timeouts.HelmInstall); err != nil {
+			t.Error(err)
+			return nil
+		}
+		installed = append(installed, addon)
+	}
+
+	timeout := testConfig.Timeouts.Wait.String()
+	if err := management.Run("wait", "deployment", "--all", "--namespace", "capi-system", "--for", "condition=Available", "--timeout", timeout); err != nil {
+		t.Errorf("the Cluster API controllers are not available along with the provisioning addons: %s", err)
+	}
+	if err := management.Run("wait", "cluster/"+s.Name, "--for", "condition=Ready", "--timeout", timeout); err != nil {
+		t.Errorf("cluster %s is not ready along with the provisioning addons: %s", s.Name, err)
+	}
+	return nil
+}
+
+// managementAddons returns the provisioning addons among the addons of the
+// scenario, in the order of their requirements.
+func managementAddons(addons []v1beta1.AddonInterface) ([]v1beta1.AddonInterface, error) {
+	var provisioning []v1beta1.AddonInterface
+	for _, name := range provisioningAddons {
+		var found bool
+		for _, addon := range addons {
+			if addon.GetName() == name {
+				provisioning = append(provisioning, addon)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("provisioning addon %s is not part of scenario %s", name, clusterAPIScenario)
+		}
+	}
+	return requirements.Order(provisioning)
+}
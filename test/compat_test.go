@@ -0,0 +1,99 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/compat"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// TestValidateKubernetesVersions verifies the kubernetes version constraints
+// of the addons of every testing group: the addons of a group must have
+// versions in common, and support every version the group is tested with
+// (the default version and the versions of the scenarios deploying it), so an
+// addon which drops support for a version we still test fails here instead of
+// being skipped by the controller at deployment.
+func TestValidateKubernetesVersions(t *testing.T) {
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ranges := make(map[string]compat.Range, len(addons))
+	for _, addon := range addons {
+		r, err := compat.RangeOf(addon)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		ranges[addon.GetName()] = r
+	}
+
+	tested, err := testedVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupnames := make([]string, 0, len(addonTestingGroups))
+	for groupname := range addonTestingGroups {
+		groupnames = append(groupnames, groupname)
+	}
+	sort.Strings(groupnames)
+
+	for _, groupname := range groupnames {
+		var groupRanges []compat.Range
+		for _, name := range addonTestingGroups.Addons(groupname) {
+			if r, ok := ranges[name]; ok {
+				groupRanges = append(groupRanges, r)
+			}
+		}
+		for _, err := range compat.Check(groupname, groupRanges, tested[groupname]) {
+			t.Error(err)
+		}
+	}
+}
+
+// testedVersions returns the kubernetes versions each testing group is tested
+// with: the default version and the versions of the scenarios deploying it.
+func testedVersions() (map[string][]semver.Version, error) {
+	defaultVersion := testConfig.KubernetesVersion
+	tested := make(map[string][]semver.Version, len(addonTestingGroups))
+	for groupname := range addonTestingGroups {
+		tested[groupname] = []semver.Version{defaultVersion}
+	}
+
+	names, err := scenario.List("scenarios")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		s, err := scenario.Load("scenarios", name)
+		if err != nil {
+			return nil, err
+		}
+		if s.Cluster.KubernetesVersion == "" {
+			continue
+		}
+		version, err := semver.Parse(s.Cluster.KubernetesVersion)
+		if err != nil {
+			return nil, err
+		}
+		for _, groupname := range s.Groups {
+			if !containsVersion(tested[groupname], version) {
+				tested[groupname] = append(tested[groupname], version)
+			}
+		}
+	}
+	return tested, nil
+}
+
+func containsVersion(versions []semver.Version, version semver.Version) bool {
+	for _, v := range versions {
+		if v.EQ(version) {
+			return true
+		}
+	}
+	return false
+}
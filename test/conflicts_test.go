@@ -0,0 +1,28 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/conflicts"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestValidateAddonConflicts(t *testing.T) {
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []render.Object
+	for _, addon := range addons {
+		rendered, err := render.Addon(addon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, rendered...)
+	}
+
+	for _, conflict := range conflicts.Detect(objects) {
+		t.Error(conflict)
+	}
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/contracts"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const contractsDir = "contracts"
+
+func TestValidateAddonContracts(t *testing.T) {
+	addonContracts, err := contracts.Load(contractsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		known[addon.GetName()] = true
+	}
+	for name := range addonContracts {
+		if !known[name] {
+			t.Errorf("contract %s/%s.yaml is for an addon which does not exist", contractsDir, name)
+		}
+	}
+
+	for _, err := range addonContracts.Validate() {
+		t.Error(err)
+	}
+}
+
+// verifyContracts verifies that the resources the deployed addons require from
+// each other (see contracts/) exist. Requirements on addons which were not
+// deployed are logged.
+func verifyContracts(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface) error {
+	addonContracts, err := contracts.Load(contractsDir)
+	if err != nil {
+		return err
+	}
+
+	deployed := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		deployed[addon.GetName()] = true
+	}
+
+	var edges []contracts.Edge
+	for _, edge := range addonContracts.Edges(names(addons)) {
+		if !deployed[edge.Requirement.Addon] {
+			logf(t, "WARNING: %s, which was not deployed", edge)
+			continue
+		}
+		edges = append(edges, edge)
+	}
+
+	unsatisfied, err := contracts.Verify(edges, func(resource contracts.Resource) (bool, error) {
+		return contracts.Exists(k, resource)
+	})
+	if err != nil {
+		return err
+	}
+	if len(unsatisfied) > 0 {
+		return fmt.Errorf("addon contracts are not satisfied: %v", unsatisfied)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/compat"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/deprecations"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// TestValidateAPIDeprecations scans the objects rendered by the addons of
+// every testing group for deprecated API versions. It fails for objects whose
+// API version is removed in the newest kubernetes version the group is tested
+// with (unless the addon doesn't support that version), and logs those which
+// are only deprecated by then.
+func TestValidateAPIDeprecations(t *testing.T) {
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := make(map[string]v1beta1.AddonInterface, len(addons))
+	for _, addon := range addons {
+		byName[addon.GetName()] = addon
+	}
+
+	tested, err := testedVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupnames := make([]string, 0, len(addonTestingGroups))
+	for groupname := range addonTestingGroups {
+		groupnames = append(groupnames, groupname)
+	}
+	sort.Strings(groupnames)
+
+	for _, groupname := range groupnames {
+		newest := newestVersion(tested[groupname])
+		for _, name := range addonTestingGroups.Addons(groupname) {
+			addon, ok := byName[name]
+			if !ok {
+				continue
+			}
+			if r, err := compat.RangeOf(addon); err == nil && !r.Supports(newest) {
+				logf(t, "not checking addon %s of group %s for APIs removed in kubernetes %s, it supports %s", name, groupname, newest, r)
+				continue
+			}
+			objects, err := render.Addon(addon)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, finding := range deprecations.Find(objects) {
+				switch {
+				case finding.RemovedIn(newest):
+					t.Errorf("%s, which group %s is tested with", finding, groupname)
+				case finding.DeprecatedIn(newest):
+					logf(t, "WARNING: %s", finding)
+				}
+			}
+		}
+	}
+}
+
+// newestVersion returns the newest of the kubernetes versions.
+func newestVersion(versions []semver.Version) semver.Version {
+	var newest semver.Version
+	for _, version := range versions {
+		if version.GT(newest) {
+			newest = version
+		}
+	}
+	return newest
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// addonDescription is an addon of a testing group as it would be deployed,
+// after catalog resolution.
+type addonDescription struct {
+	Name         string
+	Revision     string
+	Chart        string
+	ChartVersion string
+	Repo         string
+
+	// Requires are the addons the addon requires (transitively).
+	Requires []string
+
+	// Overrides are the values overrides applied to the addon: "ci" for the
+	// CI overrides and <layer> (<scenario>) for the override layers of the
+	// scenarios which deploy the group.
+	Overrides []string
+
+	// Skipped is the reason why the addon is skipped, if it is.
+	Skipped string
+}
+
+// TestDescribeGroups prints the resolved contents of the testing groups named
+// in DESCRIBE_GROUPS (a comma separated list, or "all") without deploying
+// anything. Also available as `go run scripts/test-wrapper.go describe`.
+func TestDescribeGroups(t *testing.T) {
+	groupNames := os.Getenv("DESCRIBE_GROUPS")
+	if groupNames == "" {
+		t.Skip("DESCRIBE_GROUPS is not set")
+	}
+
+	names := listGroups()
+	if groupNames != "all" {
+		names = strings.Split(groupNames, ",")
+	}
+
+	for _, name := range names {
+		addons, err := describeGroup(strings.TrimSpace(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Printf("GROUP %s\n%s\n", name, formatGroup(addons))
+	}
+}
+
+// listGroups returns the names of the testing groups.
+func listGroups() []string {
+	names := make([]string, 0, len(addonTestingGroups))
+	for name := range addonTestingGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// describeGroup resolves the addons of a testing group from the catalog.
+func describeGroup(name string) ([]addonDescription, error) {
+	if _, ok := addonTestingGroups[name]; !ok {
+		return nil, fmt.Errorf("testing group %s does not exist", name)
+	}
+
+	catalogAddons, err := latestAddons()
+	if err != nil {
+		return nil, err
+	}
+	layers, err := scenarioLayers(name)
+	if err != nil {
+		return nil, err
+	}
+	skipped := skippedAddons()
+
+	var descriptions []addonDescription
+	for _, addonName := range addonTestingGroups.Addons(name) {
+		var addon v1beta1.AddonInterface
+		for _, candidate := range catalogAddons {
+			if candidate.GetName() == addonName {
+				addon = candidate
+			}
+		}
+		if addon == nil {
+			return nil, fmt.Errorf("addon %s of group %s does not exist in the catalog", addonName, name)
+		}
+
+		d := addonDescription{
+			Name:     addonName,
+			Revision: addon.GetAnnotations()[revisionAnnotation],
+			Skipped:  skipped[addonName],
+		}
+		if ref := addon.GetAddonSpec().ChartReference; ref != nil {
+			d.Chart, d.ChartVersion = ref.Chart, ref.Version
+			if ref.Repo != nil {
+				d.Repo = *ref.Repo
+			}
+		}
+
+		required, err := requirements.Resolve(addonName, catalogAddons)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range required {
+			if r.GetName() != addonName {
+				d.Requires = append(d.Requires, r.GetName())
+			}
+		}
+
+		if _, ok := addonOverrides[addonName]; ok {
+			d.Overrides = append(d.Overrides, "ci")
+		}
+		d.Overrides = append(d.Overrides, layers[addonName]...)
+
+		descriptions = append(descriptions, d)
+	}
+	return descriptions, nil
+}
+
+// scenarioLayers maps addons to the override layers (as "<layer> (<scenario>)")
+// of the scenarios which deploy a testing group and override the addon.
+func scenarioLayers(group string) (map[string][]string, error) {
+	names, err := scenario.List("scenarios")
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make(map[string][]string)
+	for _, name := range names {
+		s, err := scenario.Load("scenarios", name)
+		if err != nil {
+			return nil, err
+		}
+		deploys := false
+		for _, g := range s.Groups {
+			deploys = deploys || g == group
+		}
+		if !deploys {
+			continue
+		}
+		for _, layerName := range s.Overrides {
+			layer, err := scenario.LoadLayer(filepath.Join("overrides", layerName))
+			if err != nil {
+				return nil, err
+			}
+			for addon := range layer {
+				layers[addon] = append(layers[addon], fmt.Sprintf("%s (%s)", layerName, s.Name))
+			}
+		}
+	}
+	return layers, nil
+}
+
+// formatGroup renders the addons of a group as a plain text table.
+func formatGroup(addons []addonDescription) string {
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDON\tREVISION\tCHART\tVERSION\tREPO\tREQUIRES\tOVERRIDES\tSKIPPED")
+	for _, a := range addons {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, a.Revision, a.Chart, a.ChartVersion, a.Repo, orNone(a.Requires), orNone(a.Overrides), a.Skipped)
+	}
+	w.Flush()
+	return b.String()
+}
+
+func orNone(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/gc"
+)
+
+// dockerRun is the record of this run in the ledger of the docker garbage
+// collection, nil unless DOCKER_GC is true.
+var dockerRun *gc.Run
+
+// dockerLedger returns the directory of the ledger of the docker garbage
+// collection, DOCKER_GC_LEDGER (default: kubeaddons-kommander-gc in the
+// temporary directory). Runs sharing a docker daemon have to share a ledger.
+func dockerLedger() string {
+	if dir := os.Getenv("DOCKER_GC_LEDGER"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "kubeaddons-kommander-gc")
+}
+
+// startDockerGC sweeps the docker resources left by previous runs which are
+// over, e.g. because they crashed, and records this run in the ledger. The
+// run resumed with RESUME_RUN is not swept, even if it crashed, since the
+// clusters it left behind are resumed. It does nothing unless DOCKER_GC is
+// true.
+func startDockerGC() error {
+	if os.Getenv("DOCKER_GC") != "true" {
+		return nil
+	}
+	if resumed := os.Getenv("RESUME_RUN"); resumed != "" {
+		sweepDocker(resumed)
+	} else {
+		sweepDocker()
+	}
+
+	var err error
+	dockerRun, err = gc.Start(dockerLedger(), runID, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("docker resources of this run are recorded as run %s in %s\n", dockerRun.ID, dockerLedger())
+	return nil
+}
+
+// recordCluster records a kind cluster created by this run, to sweep its
+// node containers if they are left behind.
+func recordCluster(name string) {
+	if dockerRun == nil {
+		return
+	}
+	if err := dockerRun.AddCluster(name); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+	}
+}
+
+// finishDockerGC marks this run as over and sweeps what is left of it.
+func finishDockerGC() {
+	if dockerRun == nil {
+		return
+	}
+	if err := dockerRun.Finish(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+		return
+	}
+	sweepDocker()
+}
+
+// sweepDocker removes the docker resources of the runs in the ledger which are
+// over, except those of the excluded runs, and records what it reclaimed in
+// the run report. Runs are kept in the ledger until all their resources are
+// removed.
+func sweepDocker(exclude ...string) {
+	runs, err := gc.Stale(dockerLedger(), exclude...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: could not read the docker gc ledger: %s\n", err)
+		return
+	}
+	for _, run := range runs {
+		resources, err := run.Resources()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: could not list the docker resources of run %s: %s\n", run.ID, err)
+			continue
+		}
+		reclaimed, err := gc.Sweep(resources)
+		for _, resource := range reclaimed {
+			fmt.Printf("reclaimed %s of run %s\n", resource, run.ID)
+			runReport.Reclaim(fmt.Sprintf("%s of run %s", resource, run.ID))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: %s\n", err)
+			continue
+		}
+		if err := run.Remove(); err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: could not remove run %s from the docker gc ledger: %s\n", run.ID, err)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/drain"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// drainNode drains the worker node running the most pods of the addons, as
+// upgrades of the nodes of customer clusters do one by one, waits for the
+// pods to be rescheduled on the other nodes and runs the checks of the
+// scenario again before the node is uncordoned. Nodes are not drained unless
+// NODE_DRAIN is true, and only in clusters with more than one worker.
+func drainNode(t *testing.T, k kubectl.Client, s *scenario.Scenario, addons []v1beta1.AddonInterface, skipped map[string]string) (err error) {
+	if os.Getenv("NODE_DRAIN") != "true" {
+		return nil
+	}
+	if name := clusterProvider(); !provider.Has(name, provider.MultiNode) {
+		logf(t, "WARNING: skipping the node drain, cluster provider %s can not create worker nodes", name)
+		return nil
+	}
+
+	workers, err := drain.Workers(k)
+	if err != nil {
+		return err
+	}
+	if len(workers) < 2 {
+		logf(t, "WARNING: skipping the node drain, the pods of a cluster with %d workers can't be rescheduled", len(workers))
+		return nil
+	}
+	namespaces := addonNamespaces(addons)
+	pods, err := drain.Pods(k, namespaces)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if pod.Owner == "" {
+			logf(t, "WARNING: pod %s has no controller and is lost when its node is drained", pod)
+		}
+	}
+	node := drain.Busiest(workers, pods)
+
+	setPhase(s.Name, "draining node")
+	logf(t, "draining node %s", node)
+	defer func() {
+		if uncordonErr := drain.Uncordon(k, node); uncordonErr != nil && err == nil {
+			err = fmt.Errorf("could not uncordon node %s: %w", node, uncordonErr)
+		}
+	}()
+	if err := drain.Drain(k, node, testConfig.Timeouts.Wait); err != nil {
+		return fmt.Errorf("could not drain node %s: %w", node, err)
+	}
+	if err := drain.WaitRescheduled(k, namespaces, node, testConfig.Timeouts.Wait); err != nil {
+		return err
+	}
+
+	setPhase(s.Name, "checking after drain")
+	return runchecks(t, k, s, skipped)
+}
@@ -0,0 +1,179 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/egress"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/faults"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/gc"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/ipfamily"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const defaultEgressProxyPort = 3128
+
+// egressProxy returns whether the outbound traffic of the nodes of a scenario
+// is restricted to the egress proxy, for scenarios which set egressProxy or
+// with EGRESS_PROXY=true, on providers which can restrict it.
+func egressProxy(s *scenario.Scenario) bool {
+	return wantsEgressProxy(s) && provider.Has(clusterProvider(), provider.NetworkFaults)
+}
+
+// wantsEgressProxy returns whether a scenario is run behind the egress proxy,
+// regardless of the cluster provider.
+func wantsEgressProxy(s *scenario.Scenario) bool {
+	return s.EgressProxy || os.Getenv("EGRESS_PROXY") == "true"
+}
+
+// egressPort returns the port of the docker host the egress proxy listens on,
+// EGRESS_PROXY_PORT (default: 3128).
+func egressPort() (int, error) {
+	port, err := envInt("EGRESS_PROXY_PORT")
+	if port == 0 {
+		port = defaultEgressProxyPort
+	}
+	return port, err
+}
+
+// egressValues returns the values of the egress proxy, which listens on the
+// gateway of the docker network of kind.
+func egressValues() (egress.Values, error) {
+	port, err := egressPort()
+	if err != nil {
+		return egress.Values{}, err
+	}
+	gateway, err := egress.Gateway(dockerNetwork())
+	if err != nil {
+		return egress.Values{}, err
+	}
+	subnets, err := ipfamily.DockerSubnets(dockerNetwork())
+	if err != nil {
+		return egress.Values{}, err
+	}
+	return egress.NewValues(gateway, port, subnets), nil
+}
+
+// applyEgressOverrides merges the overrides of egress.yaml, rendered with the
+// values of the egress proxy, over the values of the addons of a scenario run
+// behind the proxy.
+func applyEgressOverrides(s *scenario.Scenario, addons []v1beta1.AddonInterface) error {
+	if !egressProxy(s) {
+		return nil
+	}
+	config, err := egress.Load("egress.yaml")
+	if err != nil {
+		return err
+	}
+	v, err := egressValues()
+	if err != nil {
+		return err
+	}
+	rendered, err := config.Render(v)
+	if err != nil {
+		return err
+	}
+	for _, addon := range addons {
+		if values, ok := rendered[addon.GetName()]; ok {
+			if err := validateOverride(addon, values); err != nil {
+				return err
+			}
+			if err := layerValues(addon, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// restrictEgress starts the egress proxy of a scenario and restricts the
+// outbound traffic of the nodes of its cluster to it. The returned function
+// verifies that no connection bypassed the proxy and stops it. Nothing is
+// restricted unless the scenario sets egressProxy or EGRESS_PROXY is true.
+func restrictEgress(t *testing.T, k kubectl.Client, s *scenario.Scenario) (func(), error) {
+	if !wantsEgressProxy(s) {
+		return func() {}, nil
+	}
+	if name := clusterProvider(); !provider.Has(name, provider.NetworkFaults) {
+		logf(t, "WARNING: skipping the egress proxy, cluster provider %s can not restrict the egress of its nodes", name)
+		return func() {}, nil
+	}
+
+	config, err := egress.Load("egress.yaml")
+	if err != nil {
+		return func() {}, err
+	}
+	v, err := egressValues()
+	if err != nil {
+		return func() {}, err
+	}
+	port, err := egressPort()
+	if err != nil {
+		return func() {}, err
+	}
+
+	setPhase(s.Name, "restricting egress")
+	proxy, err := egress.Start(fmt.Sprintf("egress-proxy-%s", s.Name), port, config.Allow, map[string]string{gc.RunLabel: runID})
+	if err != nil {
+		return func() {}, err
+	}
+	logf(t, "restricting the egress of the nodes to proxy %s", v.Proxy)
+
+	nodes, err := faults.Nodes(k)
+	if err != nil {
+		proxy.Stop()
+		return func() {}, err
+	}
+	subnets, err := ipfamily.DockerSubnets(dockerNetwork())
+	if err != nil {
+		proxy.Stop()
+		return func() {}, err
+	}
+	for _, node := range nodes {
+		if err := egress.Restrict(node.Name, v, subnets); err != nil {
+			proxy.Stop()
+			return func() {}, err
+		}
+	}
+
+	return func() {
+		verifyEgress(t, proxy, nodes)
+		if err := proxy.Stop(); err != nil {
+			logf(t, "WARNING: %s", err)
+		}
+	}, nil
+}
+
+// verifyEgress logs the hosts the proxy denied and fails the test when
+// connections of the nodes or their pods bypassed the proxy.
+func verifyEgress(t *testing.T, proxy *egress.Proxy, nodes []faults.Node) {
+	denied, err := proxy.Denied()
+	if err != nil {
+		logf(t, "WARNING: %s", err)
+	}
+	hosts := make([]string, 0, len(denied))
+	for host := range denied {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		logf(t, "WARNING: the egress proxy denied %d requests to %s, which is not allowed in egress.yaml", denied[host], host)
+	}
+
+	for _, node := range nodes {
+		n, err := egress.Bypassed(node.Name)
+		if err != nil {
+			logf(t, "WARNING: %s", err)
+			continue
+		}
+		if n > 0 {
+			t.Errorf("%d connections from node %s bypassed the egress proxy, the addons must honor the proxy settings", n, node.Name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/events"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// logProblemEvents logs the events which indicate scheduling, image or startup
+// problems (see events.ProblemReasons) in the namespaces of the addons which
+// were seen since their deployment started, attributed to the addon whose
+// rendered object is named like the object of the event.
+func logProblemEvents(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface, objects []render.Object, since time.Time) error {
+	namespaces := make(map[string]bool)
+	for _, addon := range addons {
+		namespaces[render.Namespace(addon)] = true
+	}
+
+	for namespace := range namespaces {
+		problems, err := events.Find(k, events.Query{Namespace: namespace, Reasons: events.ProblemReasons, Since: since})
+		if err != nil {
+			return err
+		}
+		for _, e := range problems {
+			logf(t, "WARNING: event of addon %s: %s", eventAddon(e, objects), e)
+		}
+	}
+	return nil
+}
+
+// eventAddon returns the addon whose object (e.g. deployment) has the longest
+// name which prefixes the name of the object of an event (e.g. its pods).
+func eventAddon(e events.Event, objects []render.Object) string {
+	addon, longest := "unknown", 0
+	for _, obj := range objects {
+		if obj.Namespace == e.Namespace && len(obj.Name) > longest && strings.HasPrefix(e.Name, obj.Name) {
+			addon, longest = obj.Addon, len(obj.Name)
+		}
+	}
+	return addon
+}
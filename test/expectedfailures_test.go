@@ -0,0 +1,72 @@
+package test
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+)
+
+// TestValidateExpectedFailures verifies the expected failures of groups.yaml:
+// they must refer to checks covering their addon, so that renaming a check
+// doesn't silently turn its expected failure into a new failure, and their
+// versions must include a kubernetes version the group of the addon is tested
+// with, otherwise the expected failure is never exercised. Their architectures
+// must be configured in architectures.yaml.
+func TestValidateExpectedFailures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("checks", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addonChecks := make(map[string]map[string]bool)
+	for _, file := range files {
+		fileChecks, err := checks.Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, check := range fileChecks {
+			if addonChecks[check.Addon] == nil {
+				addonChecks[check.Addon] = make(map[string]bool)
+			}
+			addonChecks[check.Addon][check.Name] = true
+		}
+	}
+	tested, err := testedVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groupnames := make([]string, 0, len(addonTestingGroups))
+	for groupname := range addonTestingGroups {
+		groupnames = append(groupnames, groupname)
+	}
+	sort.Strings(groupnames)
+	for _, groupname := range groupnames {
+		for _, addon := range addonTestingGroups[groupname] {
+			for _, expected := range addon.ExpectedFailures {
+				if expected.Check != "" && !addonChecks[addon.Name][expected.Check] {
+					t.Errorf("the expected failure of addon %s on %s refers to check %q, which does not cover the addon", addon.Name, expected.Versions, expected.Check)
+				}
+				if len(addonChecks[addon.Name]) == 0 {
+					t.Errorf("addon %s has expected failures on %s, but no checks cover it", addon.Name, expected.Versions)
+				}
+				for _, arch := range expected.Architectures {
+					if _, ok := architectures[arch]; !ok {
+						t.Errorf("the expected failure of addon %s on %s refers to architecture %s, which is not in architectures.yaml", addon.Name, expected.Versions, arch)
+					}
+				}
+				versions := semver.MustParseRange(expected.Versions)
+				exercised := false
+				for _, version := range tested[groupname] {
+					exercised = exercised || versions(version)
+				}
+				if !exercised {
+					t.Errorf("the expected failure of addon %s on %s matches none of the kubernetes versions group %s is tested with %v, remove it: %s", addon.Name, expected.Versions, groupname, tested[groupname], expected.Issue)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mesosphere/kubeaddons/pkg/repositories"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/git"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/testconfig"
+)
+
+// extraRepository opens an extra addon repository of the test configuration
+// (see extraRepositories in config.yaml). The addons of git repositories are
+// verified like those of the kubernetes base addons.
+func extraRepository(i int, extra testconfig.Repository) (repositories.Repository, error) {
+	if extra.Path != "" {
+		repo, err := local.NewRepository(fmt.Sprintf("extra-%d", i), extra.Path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open extra repository %s: %w", extra, err)
+		}
+		return repo, nil
+	}
+	repo, err := git.NewRemoteRepository(extra.URL, extra.Ref, "origin")
+	if err != nil {
+		return nil, fmt.Errorf("could not clone extra repository %s: %w", extra, err)
+	}
+	if err := verifyCatalog(repo); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
+
+// extraRepositories opens all extra addon repositories of the test
+// configuration, which are merged into the catalog of the run.
+func extraRepositories() ([]repositories.Repository, error) {
+	var repos []repositories.Repository
+	for i, extra := range testConfig.ExtraRepositories {
+		repo, err := extraRepository(i, extra)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+	return repos, nil
+}
+
+// joinExtraAddons adds every addon of the extra repositories to the testing
+// group of its repository (the kommander group by default), so the scenarios
+// of the group deploy and check it along with the addons of this repository.
+func joinExtraAddons() error {
+	for i, extra := range testConfig.ExtraRepositories {
+		repo, err := extraRepository(i, extra)
+		if err != nil {
+			return err
+		}
+		addons, err := repo.ListAddons()
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(addons))
+		for name := range addons {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if err := addonTestingGroups.Extend(extra.Group, names...); err != nil {
+			return fmt.Errorf("the addons of extra repository %s can't join their group: %w", extra, err)
+		}
+		fmt.Printf("addons %v of extra repository %s join testing group %s\n", names, extra, extra.Group)
+	}
+	return nil
+}
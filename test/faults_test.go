@@ -0,0 +1,132 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/faults"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const (
+	defaultNetworkLatency   = 100 * time.Millisecond
+	defaultNetworkPartition = 30 * time.Second
+)
+
+// injectNetworkFaults runs the checks of a scenario while the traffic of all
+// nodes is delayed by NETWORK_LATENCY, then partitions the last node from the
+// others for NETWORK_PARTITION and runs the checks again once it is healed.
+// The addons fail when any of their containers ends up in CrashLoopBackOff.
+// Nothing is injected unless NETWORK_FAULTS is true.
+func injectNetworkFaults(t *testing.T, k kubectl.Client, s *scenario.Scenario, addons []v1beta1.AddonInterface, skipped map[string]string) error {
+	if os.Getenv("NETWORK_FAULTS") != "true" {
+		return nil
+	}
+	if name := clusterProvider(); !provider.Has(name, provider.NetworkFaults) {
+		logf(t, "WARNING: skipping network faults, cluster provider %s can not inject them", name)
+		return nil
+	}
+
+	latency, err := durationEnv("NETWORK_LATENCY", defaultNetworkLatency)
+	if err != nil {
+		return err
+	}
+	partition, err := durationEnv("NETWORK_PARTITION", defaultNetworkPartition)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := faults.Nodes(k)
+	if err != nil {
+		return err
+	}
+	namespaces := addonNamespaces(addons)
+	before, _, err := faults.CurrentRestarts(k, namespaces)
+	if err != nil {
+		return err
+	}
+
+	setPhase(s.Name, "injecting network latency")
+	logf(t, "delaying the traffic of %d nodes by %s", len(nodes), latency)
+	heal, err := faults.Latency(nodes, latency, latency/5)
+	if err != nil {
+		return err
+	}
+	err = runchecks(t, k, s, skipped)
+	if healErr := heal(); healErr != nil {
+		return fmt.Errorf("could not remove network latency: %w", healErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) > 1 {
+		node := nodes[len(nodes)-1]
+		setPhase(s.Name, "partitioning network")
+		logf(t, "partitioning node %s from the other nodes for %s", node.Name, partition)
+		heal, err := faults.Partition(node, nodes)
+		if err != nil {
+			return err
+		}
+		time.Sleep(partition)
+		if err := heal(); err != nil {
+			return fmt.Errorf("could not heal network partition: %w", err)
+		}
+		if err := k.Run("wait", "--for=condition=Ready", "nodes", "--all", "--timeout=5m"); err != nil {
+			return fmt.Errorf("nodes did not recover from the network partition: %w", err)
+		}
+
+		setPhase(s.Name, "checking after partition")
+		if err := runchecks(t, k, s, skipped); err != nil {
+			return err
+		}
+	}
+
+	after, crashLooping, err := faults.CurrentRestarts(k, namespaces)
+	if err != nil {
+		return err
+	}
+	for _, container := range faults.Increased(before, after) {
+		logf(t, "WARNING: container %s restarted during network faults", container)
+	}
+	if len(crashLooping) > 0 {
+		return fmt.Errorf("containers are crash looping after network faults: %v", crashLooping)
+	}
+	return nil
+}
+
+// addonNamespaces returns the sorted namespaces of the addons.
+func addonNamespaces(addons []v1beta1.AddonInterface) []string {
+	set := make(map[string]bool)
+	for _, addon := range addons {
+		set[render.Namespace(addon)] = true
+	}
+	namespaces := make([]string, 0, len(set))
+	for namespace := range set {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// durationEnv parses the duration in an environment variable, which defaults
+// to def when unset.
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/container"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/federation"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+const (
+	// attachedCluster is the kind cluster attached to kommander.
+	attachedCluster = "kommander-attached"
+
+	// federationWorkspace is the namespace of the workspace the cluster is
+	// attached to and the project is created in.
+	federationWorkspace = "kommander-default-workspace"
+
+	federationProject = "federation-test"
+)
+
+// TestKommanderFederation deploys the kommander group, attaches a second kind
+// cluster to kommander, creates a project placed on it and deploys a catalog
+// addon (KOMMANDER_FEDERATION_ADDON, default: reloader) to the project as a
+// FederatedAddon. The addon must be ready on the attached cluster and
+// kommander must report it as propagated. The test is skipped unless
+// KOMMANDER_FEDERATION is true.
+func TestKommanderFederation(t *testing.T) {
+	if os.Getenv("KOMMANDER_FEDERATION") != "true" {
+		t.Skip("KOMMANDER_FEDERATION is not true")
+	}
+	if clusterProvider() != provider.Kind {
+		t.Skipf("the attached cluster is created with kind, not the %s cluster provider", clusterProvider())
+	}
+	name := os.Getenv("KOMMANDER_FEDERATION_ADDON")
+	if name == "" {
+		name = "reloader"
+	}
+	addon, err := federatedAddon(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testscenario(t, "kommander-federation", func(t *testing.T, k kubectl.Client) error {
+		return testFederationFlow(t, k, addon)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// federatedAddon returns the latest revision of a catalog addon to federate,
+// which has to be namespaced to be deployed to a project.
+func federatedAddon(name string) (*v1beta1.Addon, error) {
+	addons, err := latestAddons()
+	if err != nil {
+		return nil, err
+	}
+	for _, addon := range addons {
+		if addon.GetName() != name {
+			continue
+		}
+		namespaced, ok := addon.(*v1beta1.Addon)
+		if !ok {
+			return nil, fmt.Errorf("addon %s is a ClusterAddon, which can't be deployed to a project", name)
+		}
+		return namespaced, nil
+	}
+	return nil, fmt.Errorf("addon %s is not in the catalog", name)
+}
+
+func testFederationFlow(t *testing.T, k kubectl.Client, addon *v1beta1.Addon) error {
+	cleanup, err := attachCluster(t, k)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	project := federation.Project{
+		Name:      federationProject,
+		Workspace: federationWorkspace,
+		Namespace: federationProject,
+		Clusters:  []string{attachedCluster},
+	}
+	logf(t, "creating project %s on cluster %s", project.Name, attachedCluster)
+	if err := federation.CreateProject(k, project, testConfig.Timeouts.Federation); err != nil {
+		return err
+	}
+	defer func() {
+		if err := federation.DeleteProject(k, project); err != nil {
+			logf(t, "WARNING: could not delete project %s: %s", project.Name, err)
+		}
+	}()
+
+	b, err := json.Marshal(addon)
+	if err != nil {
+		return err
+	}
+	name, err := federation.DeployAddon(k, project, b)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := federation.RemoveAddon(k, project, name); err != nil {
+			logf(t, "WARNING: could not remove federated addon %s: %s", name, err)
+		}
+	}()
+
+	logf(t, "waiting for addon %s on cluster %s", name, attachedCluster)
+	if err := federation.WaitReady(k, "kind-"+attachedCluster, project.Namespace, name, testConfig.Timeouts.Federation); err != nil {
+		t.Error(err)
+		return nil
+	}
+	if err := federation.WaitPropagated(k, project, name, testConfig.Timeouts.Federation); err != nil {
+		t.Error(err)
+		return nil
+	}
+	logf(t, "addon %s was propagated to cluster %s", name, attachedCluster)
+	return nil
+}
+
+// attachCluster creates the attached kind cluster, adds its context to the
+// kubeconfig file of the client, deploys the kubeaddons controller to it and attaches it to
+// kommander with its internal kubeconfig (reachable from the nodes of the
+// kommander cluster). The returned function detaches and deletes the cluster.
+func attachCluster(t *testing.T, k kubectl.Client) (func(), error) {
+	dir, err := ioutil.TempDir("", "kubeconfig-"+attachedCluster+"-")
+	if err != nil {
+		return func() {}, err
+	}
+	attached := false
+	cleanup := func() {
+		if attached {
+			if err := federation.Detach(k, attachedCluster, federationWorkspace); err != nil {
+				logf(t, "WARNING: could not detach cluster %s: %s", attachedCluster, err)
+			}
+		}
+		cmd := exec.Command("kind", "delete", "cluster", "--name", attachedCluster)
+		cmd.Env = k.Env()
+		if out, err := cmd.CombinedOutput(); err != nil {
+			logf(t, "WARNING: could not delete kind cluster %s: %s: %s", attachedCluster, err, out)
+		}
+		os.RemoveAll(dir)
+	}
+
+	path := filepath.Join(dir, "config")
+	logf(t, "creating kind cluster %s to attach", attachedCluster)
+	recordCluster(attachedCluster)
+	if out, err := exec.Command("kind", "create", "cluster", "--name", attachedCluster, "--kubeconfig", path).CombinedOutput(); err != nil {
+		return cleanup, fmt.Errorf("could not create kind cluster %s: %w: %s", attachedCluster, err, out)
+	}
+	if mode, err := container.Current(); err != nil {
+		return cleanup, err
+	} else if mode == container.Socket {
+		if err := container.InternalKubeconfig(attachedCluster, dockerNetwork(), path); err != nil {
+			return cleanup, err
+		}
+	}
+	if err := k.Merge(path); err != nil {
+		return cleanup, err
+	}
+
+	config, err := clientcmd.BuildConfigFromFlags("", path)
+	if err != nil {
+		return cleanup, err
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return cleanup, err
+	}
+	if err := waitForCluster(t, k, attachedCluster, "--context", "kind-"+attachedCluster); err != nil {
+		return cleanup, err
+	}
+	// the cluster is handled like a retained one, which is only a kubeconfig
+	if err := deployController(&resumedCluster{name: attachedCluster, provider: provider.Kind, config: config, client: client}); err != nil {
+		return cleanup, fmt.Errorf("could not deploy the kubeaddons controller to cluster %s: %w", attachedCluster, err)
+	}
+
+	kubeconfig, err := exec.Command("kind", "get", "kubeconfig", "--internal", "--name", attachedCluster).Output()
+	if err != nil {
+		return cleanup, fmt.Errorf("could not get the internal kubeconfig of cluster %s: %w", attachedCluster, err)
+	}
+	logf(t, "attaching cluster %s to workspace %s", attachedCluster, federationWorkspace)
+	attached = true
+	if err := federation.Attach(k, attachedCluster, federationWorkspace, kubeconfig); err != nil {
+		return cleanup, err
+	}
+	return cleanup, federation.WaitJoined(k, attachedCluster, testConfig.Timeouts.Federation)
+}
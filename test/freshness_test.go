@@ -0,0 +1,84 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/freshness"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+)
+
+// stableRepo is the repository of charts referenced as stable/<chart>.
+const stableRepo = "https://kubernetes-charts.storage.googleapis.com"
+
+// TestChartFreshness compares the chart versions pinned by the addons in this
+// repository to the versions in their chart repositories and reports the
+// charts for which newer versions exist (written to REPORT_DIR as
+// chart-freshness.txt). It only runs when CHART_FRESHNESS is set: with
+// CHART_FRESHNESS=report the charts are only reported, with CHART_FRESHNESS=fail
+// (e.g. in a scheduled job) the test fails for charts which are behind by a
+// minor or major version.
+func TestChartFreshness(t *testing.T) {
+	mode := os.Getenv("CHART_FRESHNESS")
+	if mode == "" {
+		t.Skip("CHART_FRESHNESS is not set")
+	}
+
+	repo, err := local.NewRepository("base", "../addons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := repo.ListAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := freshness.NewIndex()
+	var updates []freshness.Update
+	for _, revisions := range addons {
+		addon := revisions[0]
+		ref := addon.GetAddonSpec().ChartReference
+		if ref == nil {
+			continue
+		}
+
+		chart := freshness.Chart{Addon: addon.GetName(), Name: ref.Chart, Version: ref.Version}
+		switch {
+		case ref.Repo != nil:
+			chart.Repo = *ref.Repo
+		case strings.HasPrefix(ref.Chart, "stable/"):
+			chart.Repo, chart.Name = stableRepo, strings.TrimPrefix(ref.Chart, "stable/")
+		default:
+			t.Logf("WARNING: chart %s of addon %s has no repository", ref.Chart, addon.GetName())
+			continue
+		}
+
+		update, err := index.Check(chart)
+		if err != nil {
+			t.Errorf("could not check chart of addon %s: %s", addon.GetName(), err)
+			continue
+		}
+		updates = append(updates, update)
+
+		if update.Behind() && mode == "fail" {
+			t.Errorf("chart %s of addon %s is pinned to %s, which is a %s version behind %s", chart.Name, chart.Addon, chart.Version, update.Severity, update.Latest)
+		}
+	}
+
+	table := freshness.Table(updates)
+	t.Logf("chart freshness:\n%s", table)
+
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "chart-freshness.txt"), []byte(table), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
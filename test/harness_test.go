@@ -0,0 +1,1008 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"sigs.k8s.io/kind/pkg/apis/config/v1alpha3"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/audit"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checkpoint"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/container"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/installed"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/ipfamily"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/quarantine"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/retry"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/seeds"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/wait"
+	"github.com/mesosphere/kubeaddons/hack/temp"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/test"
+	testcluster "github.com/mesosphere/kubeaddons/pkg/test/cluster"
+)
+
+// -----------------------------------------------------------------------------
+// Private - Scenarios
+// -----------------------------------------------------------------------------
+
+// testscenario runs the scenario with the given name (see scenarios/): a cluster
+// with the scenario's topology is created, the addons of its groups are deployed
+// with its override layers applied, then the seeds and checks of the groups (and
+// the additional checks of the scenario) are run, followed by the given steps
+// (tests which need Go code), and the logs of the addons are scanned for errors
+// before everything is removed.
+func testscenario(t *testing.T, name string, steps ...func(t *testing.T, k kubectl.Client) error) (err error) {
+	s, err := scenario.Load("scenarios", name)
+	if err != nil {
+		return err
+	}
+	if test := strings.SplitN(t.Name(), "/", 2)[0]; !s.Standalone() && test != s.Test {
+		return fmt.Errorf("scenario %s can only be run by %s, which performs its steps", s.Name, s.Test)
+	}
+	ok, err := inShard(s.Name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		t.Skipf("scenario %s is run by another shard", s.Name)
+	}
+	logf(t, "testing scenario %s (groups: %v)", s.Name, s.Groups)
+	runningScenarios.Store(s.Name, runningScenario{t: t, err: &err})
+	defer runningScenarios.Delete(s.Name)
+
+	if os.Getenv("EXISTING_CLUSTER") == "true" {
+		return verifyscenario(t, kubectl.Client{}, s)
+	}
+
+	arch, ok := architectures[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("architecture %s is not supported, see architectures.yaml", runtime.GOARCH)
+	}
+	if missing := missingCapabilities(s); len(missing) > 0 {
+		t.Skipf("scenario %s requires %s, which the %s cluster provider lacks", s.Name, formatCapabilities(missing), clusterProvider())
+	}
+	if missing := s.Cluster.Containerd.MissingCredentials(); len(missing) > 0 {
+		t.Skipf("scenario %s pulls with registry credentials, %s are not set", s.Name, strings.Join(missing, ", "))
+	}
+
+	version, err := scenarioVersion(s)
+	if err != nil {
+		return err
+	}
+	if err := supportedVersion(arch, version); err != nil {
+		return err
+	}
+
+	scenarioAddons, groupOf, err := scenarioaddons(s)
+	if err != nil {
+		return err
+	}
+
+	skipped := skippedAddons()
+	for name, reason := range arch.Skip {
+		skipped[name] = fmt.Sprintf("not tested on %s: %s", runtime.GOARCH, reason)
+	}
+	for name, reason := range addonTestingGroups.Unsupported(version) {
+		skipped[name] = reason
+	}
+	addons := deployable(t, s.Name, scenarioAddons, groupOf, skipped)
+	started := time.Now()
+	defer func() {
+		checkBudget(t, s, time.Since(started))
+		outcome := report.Passed
+		if err != nil || t.Failed() {
+			outcome = report.Failed
+		}
+		for _, addon := range addons {
+			record(result(s.Name, groupOf[addon.GetName()], addon, outcome, ""))
+		}
+		setPhase(s.Name, "finished")
+	}()
+
+	if err := verifyProvenance(t, s, addons); err != nil {
+		return err
+	}
+
+	cp, err := loadCheckpoint(t, s)
+	if err != nil {
+		return err
+	}
+	// the stages completed by the run which is resumed are not repeated, and
+	// the cluster of a failed scenario is retained to resume it
+	provisioned, deployed, seeded := cp.Done(checkpoint.Provisioned), cp.Done(checkpoint.Deployed), cp.Done(checkpoint.Seeded)
+	retain := func() bool { return retained(t, cp, err) }
+
+	k, removeKubeconfig, err := isolateKubeconfig(t, s, cp)
+	if err != nil {
+		return err
+	}
+	defer removeKubeconfig()
+
+	var cluster testcluster.Cluster
+	if provisioned {
+		if cluster, err = resumeCluster(k, cp); err != nil {
+			return err
+		}
+	} else {
+		setPhase(s.Name, "provisioning cluster")
+		cluster, err = newCluster(k, s, version, arch)
+		if err != nil {
+			// try to clean up in case cluster was created and reference available
+			if cluster != nil {
+				_ = cluster.Cleanup()
+			}
+			return err
+		}
+	}
+	defer func() {
+		if retain() {
+			retainCluster(t, s, cp, cluster)
+			return
+		}
+		cluster.Cleanup()
+		if err := cp.Remove(); err != nil {
+			logf(t, "WARNING: could not remove the checkpoint of scenario %s: %s", s.Name, err)
+		}
+	}()
+	// the reproduction bundle is written before the cluster is removed, from
+	// which the controller is exported
+	var waves [][]v1beta1.AddonInterface
+	defer func() {
+		if err != nil || t.Failed() {
+			writeReproBundle(t, k, s, version, arch, addons, waves)
+		}
+	}()
+
+	if !provisioned {
+		setPhase(s.Name, "waiting for cluster")
+		if err := waitForCluster(t, k, cluster.Name()); err != nil {
+			return err
+		}
+	}
+
+	unrestrictEgress, err := restrictEgress(t, k, s)
+	if err != nil {
+		return err
+	}
+	defer unrestrictEgress()
+
+	if !provisioned {
+		if s.Backend != scenario.HelmBackend {
+			setPhase(s.Name, "deploying controller")
+			if err := deployController(cluster); err != nil {
+				return err
+			}
+		}
+		cp.Provider, cp.Cluster = clusterProvider(), cluster.Name()
+		if err := completeStage(cp, checkpoint.Provisioned); err != nil {
+			return err
+		}
+	}
+
+	setPhase(s.Name, "rendering")
+	objects, err := renderAddons(addons)
+	if err != nil {
+		return err
+	}
+	crds := teardown.OwnedCRDs(objects)
+	var monitoring teardown.Monitoring
+	defer func() {
+		if !retain() && !deployed {
+			verifyTeardown(t, k, s.Name, crds, objects)
+			verifyMonitoringTeardown(t, k, s.Name, monitoring, objects)
+		}
+	}()
+
+	waves, err = deploymentWaves(t, s, addons, objects)
+	if err != nil {
+		return err
+	}
+	if waves, err = deployFirst(s, waves); err != nil {
+		return err
+	}
+	labeled := labeledNamespaces(s, addons)
+	labelWave := 0
+	if len(s.DeployFirst) > 0 {
+		labelWave = 1
+	}
+	verifyTelemetry := func() error { return nil }
+	if !deployed {
+		if verifyTelemetry, err = observeTelemetry(t, k, s, objects); err != nil {
+			return err
+		}
+		// prerequisites are removed after the addons which use them
+		removePrerequisites, err := deployPrerequisites(t, k, s)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if !retain() {
+				removePrerequisites()
+			}
+		}()
+	}
+	var harnesses []test.Harness
+	rolledBack := false
+	defer func() {
+		if retain() || deployed || rolledBack {
+			// resumed deployments are removed along with their cluster
+			return
+		}
+		setPhase(s.Name, "analyzing removal")
+		analyzeRemoval(t, k, addons, objects, crds)
+		setPhase(s.Name, "cleanup")
+		for i := len(harnesses) - 1; i >= 0; i-- {
+			harnesses[i].Cleanup()
+		}
+	}()
+
+	// the addons of a scenario which fails to deploy are rolled back
+	var rolledOut []v1beta1.AddonInterface
+	deploying := true
+	defer func() {
+		if deploying && !deployed && rollbackOnFailure() && !retain() && (err != nil || t.Failed()) {
+			rollback(t, k, s, err, rolledOut, objects, crds, monitoring)
+			rolledBack = true
+		}
+	}()
+	// the logs of the addons of a failed scenario are archived for the run
+	// report before the addons are removed, unless they were scanned
+	logsScanned := false
+	defer func() {
+		if !logsScanned && (err != nil || t.Failed()) {
+			archiveLogs(t, k, s.Name, objects)
+		}
+	}()
+
+	if deployed {
+		logf(t, "resuming scenario %s with the addons deployed by run %s", s.Name, cp.Run)
+	} else {
+		for _, addon := range addons {
+			statusServer.SetAddonStatus(s.Name, addon.GetName(), "deploying")
+		}
+		baseline, err := teardown.ClusterCRDs(k)
+		if err != nil {
+			return err
+		}
+		if monitoring, err = teardown.MonitoringSnapshot(k); err != nil {
+			return err
+		}
+
+		if err := verifyHardenedImages(t, s, objects); err != nil {
+			return err
+		}
+		if err := prepullImages(t, k, s, cluster.Name(), objects); err != nil {
+			return err
+		}
+
+		removeQuota, err := applyQuotaEnvelope(t, k, s, addons, objects)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if !retain() {
+				removeQuota()
+			}
+		}()
+		verifyWebhook, removeWebhook, err := injectWebhook(t, k, s)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if !retain() {
+				removeWebhook()
+			}
+		}()
+
+		deployStart := time.Now()
+		fallback := newMirrorFallback(t, k, objects, deployStart)
+		for i, wave := range waves {
+			if i == labelWave {
+				if err := labelNamespaces(t, k, s, labeled); err != nil {
+					return err
+				}
+			}
+			verifyIsolation, err := isolateWave(t, k, wave)
+			if err != nil {
+				return err
+			}
+			ph, err := newHarness(t, k, s, version, cluster, fallback, wave)
+			if err != nil {
+				return err
+			}
+			harnesses = append(harnesses, ph)
+			rolledOut = append(rolledOut, wave...)
+
+			phase := "deploying"
+			if len(waves) > 1 {
+				phase = fmt.Sprintf("deploying wave %d/%d", i+1, len(waves))
+				logf(t, "%s: %v", phase, names(wave))
+			}
+			setPhase(s.Name, "validating")
+			ph.Validate()
+			setPhase(s.Name, phase)
+			stopFallback := func() {}
+			if s.Backend != scenario.HelmBackend {
+				// stopped when the deployment fails the test, too
+				stopFallback = fallback.watch(wave)
+				defer stopFallback()
+			}
+			ph.Deploy()
+			err = waitForConditions(t, k, s, version, wave)
+			stopFallback()
+			if err != nil {
+				return err
+			}
+			if err := verifyIsolation(); err != nil {
+				return err
+			}
+		}
+		deploying = false
+		if err := logProblemEvents(t, k, addons, objects, deployStart); err != nil {
+			return err
+		}
+		if err := analyzeAuditLog(t, cluster.Name(), deployStart, time.Now()); err != nil {
+			return err
+		}
+		if err := verifyHooks(t, k, objects); err != nil {
+			return err
+		}
+		logCRDOwners(t, k, crds)
+		recordReleaseValues(t, k, s, addons)
+		if err := verifyInventory(t, k, s, baseline); err != nil {
+			return err
+		}
+		if err := verifyContracts(t, k, addons); err != nil {
+			return err
+		}
+		if err := verifyAvailability(t, k, objects); err != nil {
+			return err
+		}
+		if err := verifyMonitoring(t, k, addons); err != nil {
+			return err
+		}
+		if err := verifyNamespaceLabels(t, k, addons); err != nil {
+			return err
+		}
+		if err := verifyPriorityClasses(t, k, addons); err != nil {
+			return err
+		}
+		if err := verifySidecars(t, k, s, labeled); err != nil {
+			return err
+		}
+		if err := verifyWebhook(addons); err != nil {
+			return err
+		}
+		if err := completeStage(cp, checkpoint.Deployed); err != nil {
+			return err
+		}
+	}
+
+	if os.Getenv("POLICY_AUDIT") == "true" {
+		setPhase(s.Name, "auditing policies")
+		if err := auditPolicies(t, k, objects); err != nil {
+			return err
+		}
+	}
+
+	if !seeded {
+		setPhase(s.Name, "seeding")
+		removeIdentities, err := provisionIdentities(t, k, addons)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if !retain() {
+				removeIdentities()
+			}
+		}()
+		for _, name := range append(append([]string{}, s.Groups...), s.Name) {
+			cleanupSeeds, err := seed(t, k, name)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if !retain() {
+					cleanupSeeds()
+				}
+			}()
+		}
+		if err := completeStage(cp, checkpoint.Seeded); err != nil {
+			return err
+		}
+	}
+
+	setPhase(s.Name, "checking")
+	if err := runchecks(t, k, s, skipped); err != nil {
+		return err
+	}
+	setPhase(s.Name, "running addon tests")
+	if err := runAddonTests(t, k, s, addons, skipped); err != nil {
+		return err
+	}
+	if err := injectNetworkFaults(t, k, s, addons, skipped); err != nil {
+		return err
+	}
+	if err := drainNode(t, k, s, addons, skipped); err != nil {
+		return err
+	}
+
+	if len(steps) > 0 {
+		setPhase(s.Name, "running steps")
+	}
+	for _, step := range steps {
+		if err := step(t, k); err != nil {
+			return err
+		}
+	}
+
+	setPhase(s.Name, "verifying telemetry")
+	if err := verifyTelemetry(); err != nil {
+		return err
+	}
+
+	setPhase(s.Name, "scanning logs")
+	logsScanned = true
+	return scanLogs(t, k, s.Name, objects)
+}
+
+// newHarness returns the harness which deploys the addons with the backend of
+// the scenario. Helm releases fall back to the image mirror by themselves.
+func newHarness(t *testing.T, k kubectl.Client, s *scenario.Scenario, version semver.Version, c testcluster.Cluster, fallback *mirrorFallback, addons []v1beta1.AddonInterface) (test.Harness, error) {
+	if s.Backend == scenario.HelmBackend {
+		return newHelmHarness(t, k, version, fallback, addons...)
+	}
+	return test.NewBasicTestHarness(t, c, addons...)
+}
+
+// deployController deploys the kubeaddons controller, retrying transient
+// failures of the API server which are common while a kind cluster starts.
+func deployController(c testcluster.Cluster) error {
+	return retry.Do(retry.Default, func() error {
+		return temp.DeployController(c, testConfig.ControllerBundle)
+	})
+}
+
+// scenarioaddons returns the addons of all groups of a scenario and its
+// additional addons with the override layers of the scenario, the overrides
+// of the address family of its cluster and those of the egress proxy applied,
+// along with the group of each addon (the scenario itself for additional
+// addons).
+func scenarioaddons(s *scenario.Scenario) ([]v1beta1.AddonInterface, map[string]string, error) {
+	var names []string
+	groupOf := make(map[string]string)
+	for _, groupname := range s.Groups {
+		if _, ok := addonTestingGroups[groupname]; !ok {
+			return nil, nil, fmt.Errorf("scenario %s references unknown group %s", s.Name, groupname)
+		}
+		for _, name := range addonTestingGroups.Addons(groupname) {
+			if _, ok := groupOf[name]; !ok {
+				groupOf[name] = groupname
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, name := range s.Addons {
+		if _, ok := groupOf[name]; !ok {
+			groupOf[name] = s.Name
+			names = append(names, name)
+		}
+	}
+
+	addons, err := addons(names...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := applyLayers(s, addons); err != nil {
+		return nil, nil, err
+	}
+	if err := applyFamilyOverrides(s, addons); err != nil {
+		return nil, nil, err
+	}
+	if err := applyEgressOverrides(s, addons); err != nil {
+		return nil, nil, err
+	}
+
+	return addons, groupOf, nil
+}
+
+// applyLayers merges the override layers of a scenario over the values of the
+// addons, in order.
+func applyLayers(s *scenario.Scenario, addons []v1beta1.AddonInterface) error {
+	for _, layerName := range s.Overrides {
+		layer, err := scenario.LoadLayer(filepath.Join("overrides", layerName))
+		if err != nil {
+			return err
+		}
+		for _, addon := range addons {
+			if values, ok := layer[addon.GetName()]; ok {
+				if err := layerValues(addon, values); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyFamilyOverrides merges the overrides of ipfamilies.yaml, rendered for the
+// address family of the cluster of a scenario and the subnets of the docker
+// network of kind, over the values of the addons. IPv4 clusters use the CI
+// overrides only.
+func applyFamilyOverrides(s *scenario.Scenario, addons []v1beta1.AddonInterface) error {
+	family := ipfamily.Family(s.Cluster.IPFamily)
+	if family == ipfamily.IPv4 {
+		return nil
+	}
+	templates, err := ipfamily.Load("ipfamilies.yaml")
+	if err != nil {
+		return err
+	}
+	subnets, err := ipfamily.DockerSubnets(dockerNetwork())
+	if err != nil {
+		return err
+	}
+	v, err := ipfamily.NewValues(family, subnets)
+	if err != nil {
+		return fmt.Errorf("scenario %s: %w", s.Name, err)
+	}
+	rendered, err := templates.Render(v)
+	if err != nil {
+		return err
+	}
+	for _, addon := range addons {
+		if values, ok := rendered[addon.GetName()]; ok {
+			if err := validateOverride(addon, values); err != nil {
+				return err
+			}
+			if err := layerValues(addon, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dockerNetwork returns the docker network the nodes of kind clusters are
+// attached to, KIND_DOCKER_NETWORK (default: bridge).
+func dockerNetwork() string {
+	if network := os.Getenv("KIND_DOCKER_NETWORK"); network != "" {
+		return network
+	}
+	return "bridge"
+}
+
+// kindConfig returns the kind configuration for the topology, the container
+// runtime and the clock of the cluster of a scenario, with the audit log
+// enabled for AUDIT_LOG. The files configuring the clock of the nodes and the
+// audit policy are written to dir, which must outlive the cluster.
+func kindConfig(c scenario.Cluster, dir string) (*v1alpha3.Cluster, error) {
+	config := &v1alpha3.Cluster{}
+	containerd, kubeadm, err := c.Containerd.Patches()
+	if err != nil {
+		return nil, err
+	}
+	config.ContainerdConfigPatches = containerd
+	config.KubeadmConfigPatches = kubeadm
+	if ipfamily.Family(c.IPFamily) == ipfamily.IPv6 {
+		config.Networking.IPFamily = v1alpha3.IPv6Family
+	}
+	for i := 0; i < c.ControlPlanes; i++ {
+		config.Nodes = append(config.Nodes, v1alpha3.Node{Role: v1alpha3.ControlPlaneRole})
+	}
+	for i := 0; i < c.Workers; i++ {
+		config.Nodes = append(config.Nodes, v1alpha3.Node{Role: v1alpha3.WorkerRole})
+	}
+
+	mounts, err := c.Clock.Mounts(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mounts {
+		// the timezone database is read from the docker host itself, only
+		// the written files are translated to the paths of the host
+		hostPath := m.HostPath
+		if strings.HasPrefix(hostPath, dir) {
+			if hostPath, err = container.HostPath(hostPath); err != nil {
+				return nil, err
+			}
+		}
+		for i := range config.Nodes {
+			config.Nodes[i].ExtraMounts = append(config.Nodes[i].ExtraMounts, v1alpha3.Mount{HostPath: hostPath, ContainerPath: m.ContainerPath, Readonly: true})
+		}
+	}
+
+	if auditLogging() {
+		config.KubeadmConfigPatches = append(config.KubeadmConfigPatches, audit.Patch)
+		mounts, err := audit.Mounts(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range mounts {
+			hostPath, err := container.HostPath(m.HostPath)
+			if err != nil {
+				return nil, err
+			}
+			// the control planes come first
+			for i := 0; i < c.ControlPlanes; i++ {
+				config.Nodes[i].ExtraMounts = append(config.Nodes[i].ExtraMounts, v1alpha3.Mount{HostPath: hostPath, ContainerPath: m.ContainerPath, Readonly: true})
+			}
+		}
+	}
+	return config, nil
+}
+
+// verifyscenario runs only the non-destructive phases for the groups of a
+// scenario against the cluster of the kubectl client, which is
+// expected to already have the addons installed. Nothing is deployed or removed.
+func verifyscenario(t *testing.T, k kubectl.Client, s *scenario.Scenario) error {
+	logf(t, "verifying existing installation of scenario %s", s.Name)
+
+	scenarioAddons, groupOf, err := scenarioaddons(s)
+	if err != nil {
+		return err
+	}
+	skipped := skippedAddons()
+	addons := deployable(t, s.Name, scenarioAddons, groupOf, skipped)
+
+	installedAddons, err := installed.Discover(k)
+	if err != nil {
+		return err
+	}
+
+	for _, addon := range addons {
+		groupname := groupOf[addon.GetName()]
+		installedAddon, ok := installedAddons[addon.GetName()]
+		if !ok {
+			t.Errorf("addon %s is not installed", addon.GetName())
+			record(result(s.Name, groupname, addon, report.Failed, "not installed"))
+			continue
+		}
+
+		revision := addon.GetAnnotations()[revisionAnnotation]
+		logf(t, "addon %s is installed at revision %s (repository revision %s)", installedAddon.Name, installedAddon.Revision, revision)
+		if installedAddon.Revision != revision {
+			logf(t, "WARNING: installed revision of addon %s differs from the repository", installedAddon.Name)
+		}
+
+		if !installedAddon.Ready {
+			t.Errorf("addon %s is installed but not ready (stage: %s)", installedAddon.Name, installedAddon.Stage)
+			record(result(s.Name, groupname, addon, report.Failed, "not ready"))
+			continue
+		}
+		record(result(s.Name, groupname, addon, report.Passed, ""))
+	}
+
+	return runchecks(t, k, s, skipped)
+}
+
+// skippedAddons maps the addons which are not tested to the reason why: those
+// marked to be skipped in groups.yaml and those listed in SKIP_ADDONS (e.g.
+// SKIP_ADDONS=kubecost,velero).
+func skippedAddons() map[string]string {
+	return addonTestingGroups.Skipped(os.Getenv("SKIP_ADDONS"))
+}
+
+// deployable returns the addons which are not skipped and records the skipped
+// ones of the scenario in the report. Addons which require a skipped addon are likely to fail,
+// so a warning is logged for them.
+func deployable(t *testing.T, scenario string, addons []v1beta1.AddonInterface, groupOf, skipped map[string]string) []v1beta1.AddonInterface {
+	var deployed []v1beta1.AddonInterface
+	for _, addon := range addons {
+		if reason, ok := skipped[addon.GetName()]; ok {
+			logf(t, "skipping addon %s: %s", addon.GetName(), reason)
+			record(result(scenario, groupOf[addon.GetName()], addon, report.Skipped, reason))
+			continue
+		}
+		deployed = append(deployed, addon)
+	}
+
+	for _, addon := range deployed {
+		for _, required := range requirements.Required(addon, addons) {
+			if _, ok := skipped[required.GetName()]; ok {
+				logf(t, "WARNING: addon %s requires skipped addon %s", addon.GetName(), required.GetName())
+			}
+		}
+	}
+
+	return deployed
+}
+
+// seed deploys the seeds of a testing group or scenario (see seeds/<name>/)
+// and waits for them to be ready. The returned function removes the seeds
+// again.
+func seed(t *testing.T, k kubectl.Client, name string) (func(), error) {
+	groupSeeds, err := seeds.Load(filepath.Join(seedsDir, name), seedParams())
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := testConfig.Timeouts.Seed
+
+	cleanup := func() {
+		for _, s := range groupSeeds {
+			if err := s.Delete(k); err != nil {
+				logf(t, "WARNING: could not remove seed %s: %s", s.Name, err)
+			}
+		}
+	}
+
+	for _, s := range groupSeeds {
+		logf(t, "seeding %s for %s", s.Name, name)
+		if err := s.Apply(k); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	for _, s := range groupSeeds {
+		if err := s.Wait(k, timeout); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	return cleanup, nil
+}
+
+// seedParams returns the parameters of the seeds: the registry of their
+// images (SEED_REGISTRY), the prefix of their namespaces (SEED_NAMESPACE) and
+// their query target (see config.yaml).
+func seedParams() seeds.Params {
+	return seeds.Params{
+		Registry:        os.Getenv("SEED_REGISTRY"),
+		NamespacePrefix: os.Getenv("SEED_NAMESPACE"),
+		QueryTarget:     testConfig.SeedQueryTarget,
+	}
+}
+
+// deployPrerequisites deploys the prerequisite seeds of a scenario, in order,
+// and returns a function removing them in reverse order.
+func deployPrerequisites(t *testing.T, k kubectl.Client, s *scenario.Scenario) (func(), error) {
+	var cleanups []func()
+	cleanup := func() {
+		for i := len(cleanups) - 1; i >= 0; i-- {
+			cleanups[i]()
+		}
+	}
+	for _, name := range s.Prerequisites {
+		setPhase(s.Name, "deploying prerequisite "+name)
+		remove, err := seed(t, k, name)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		cleanups = append(cleanups, remove)
+	}
+	return cleanup, nil
+}
+
+// runchecks runs the checks defined in checks/<name>.yaml for each group of a
+// scenario and its additional checks against the cluster of the kubectl
+// client. Checks of skipped addons and checks for another backend are skipped.
+func runchecks(t *testing.T, k kubectl.Client, s *scenario.Scenario, skipped map[string]string) error {
+	groupChecks, err := scenarioChecks(s)
+	if err != nil {
+		return err
+	}
+	version, err := scenarioVersion(s)
+	if err != nil {
+		return err
+	}
+	for i, check := range groupChecks {
+		groupChecks[i].Timeout = addonTimeout(check.Addon, version, check.Timeout)
+	}
+	return runcheckset(t, k, s, groupChecks, skipped)
+}
+
+// scenarioVersion returns the kubernetes version of the cluster of a scenario.
+func scenarioVersion(s *scenario.Scenario) (semver.Version, error) {
+	if s.Cluster.KubernetesVersion == "" {
+		return testConfig.KubernetesVersion, nil
+	}
+	return semver.Parse(s.Cluster.KubernetesVersion)
+}
+
+// addonTimeout returns the timeout of an addon on a kubernetes version (see
+// the timeouts of groups.yaml) when it is longer than timeout, otherwise
+// timeout.
+func addonTimeout(name string, version semver.Version, timeout time.Duration) time.Duration {
+	if versionTimeout := addonTestingGroups.Timeout(name, version); versionTimeout > timeout {
+		return versionTimeout
+	}
+	return timeout
+}
+
+// waitForConditions waits for the conditions of deployed addons (see the
+// waitFor of groups.yaml) within the timeout of each addon.
+func waitForConditions(t *testing.T, k kubectl.Client, s *scenario.Scenario, version semver.Version, addons []v1beta1.AddonInterface) error {
+	for _, addon := range addons {
+		conditions := addonTestingGroups.Conditions(addon.GetName())
+		if len(conditions) == 0 {
+			continue
+		}
+		setPhase(s.Name, "waiting for "+addon.GetName())
+		logf(t, "waiting for addon %s: %v", addon.GetName(), conditions)
+		if err := wait.Until(k, conditions, addonTimeout(addon.GetName(), version, testConfig.Timeouts.Wait), defaultWaitInterval); err != nil {
+			return fmt.Errorf("addon %s: %w", addon.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// scenarioChecks loads the checks of the groups of a scenario and its
+// additional checks.
+func scenarioChecks(s *scenario.Scenario) ([]checks.Check, error) {
+	var loaded []checks.Check
+	for _, name := range append(append([]string{}, s.Groups...), s.Checks...) {
+		fileChecks, err := checks.Load(filepath.Join("checks", name+".yaml"))
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, fileChecks...)
+	}
+	return loaded, nil
+}
+
+// runcheckset runs checks of a scenario as subtests, skipping those of skipped
+// addons and other backends. Checks which fail as expected on the kubernetes
+// version of the scenario (see the expectedFailures of groups.yaml) and
+// quarantined checks don't fail the test, expected failures which pass do.
+func runcheckset(t *testing.T, k kubectl.Client, s *scenario.Scenario, groupChecks []checks.Check, skipped map[string]string) error {
+	if len(groupChecks) == 0 {
+		return nil
+	}
+
+	proxy, err := k.StartProxy()
+	if err != nil {
+		return err
+	}
+	defer proxy.Stop()
+
+	quarantined, err := quarantine.Load("quarantine.yaml")
+	if err != nil {
+		return err
+	}
+	selection, err := checks.ParseSelection(os.Getenv("CHECK_TAGS"), os.Getenv("SKIP_CHECK_TAGS"))
+	if err != nil {
+		return err
+	}
+
+	fixture, err := testIdentities()
+	if err != nil {
+		return err
+	}
+	version, err := scenarioVersion(s)
+	if err != nil {
+		return err
+	}
+
+	env := &checks.Env{Kubectl: k, Proxy: proxy, Identities: fixture}
+	for _, check := range groupChecks {
+		check := check
+		t.Run(check.Name, func(t *testing.T) {
+			if reason, ok := skipped[check.Addon]; ok {
+				recordCheck(s, check, report.Skipped, reason)
+				t.Skipf("addon %s is skipped: %s", check.Addon, reason)
+			}
+			if reason, skip := selection.Skips(check); skip {
+				recordCheck(s, check, report.Skipped, reason)
+				t.Skip(reason)
+			}
+			if check.Backend != "" && check.Backend != s.Backend {
+				recordCheck(s, check, report.Skipped, "backend "+s.Backend)
+				t.Skipf("check only applies to the %s backend", check.Backend)
+			}
+			entry, isQuarantined := quarantined.Lookup(s.Name, check.Name, check.Addon)
+			if isQuarantined && entry.Expired(time.Now()) {
+				t.Errorf("the quarantine of %s expired on %s, fix the check or extend the quarantine: %s", entry, entry.Expires.Format(quarantine.DateFormat), entry.Issue)
+				isQuarantined = false
+			}
+			expected, isExpected := addonTestingGroups.ExpectedFailure(check.Addon, check.Name, version, runtime.GOARCH)
+			failures, err := check.RunAttempts(env)
+			if err != nil && isExpected {
+				recordCheck(s, check, report.ExpectedFailure, err.Error())
+				logf(t, "check failed as expected on kubernetes %s (%s, %s): %s", version, runtime.GOARCH, expected.Issue, err)
+				return
+			}
+			if err == nil && isExpected {
+				recordCheck(s, check, report.Failed, "passed although expected to fail")
+				t.Errorf("check passed on kubernetes %s (%s) although it is expected to fail, remove the expected failure of addon %s once %s is fixed", version, runtime.GOARCH, check.Addon, expected.Issue)
+				return
+			}
+			if err != nil && isQuarantined {
+				recordCheck(s, check, report.Quarantined, err.Error())
+				logf(t, "WARNING: quarantined check failed (%s): %s", entry.Issue, err)
+				return
+			}
+			if err != nil {
+				recordCheck(s, check, report.Failed, err.Error())
+				t.Error(err)
+				return
+			}
+			if isQuarantined {
+				logf(t, "quarantined check passed, lift its quarantine once the flake is fixed: %s", entry.Issue)
+			}
+			recordCheck(s, check, report.Passed, "")
+			if failures > 0 {
+				runReport.Flake(s.Name+"/"+check.Name, failures)
+			}
+		})
+	}
+
+	return nil
+}
+
+// -----------------------------------------------------------------------------
+// Private - Reporting
+// -----------------------------------------------------------------------------
+
+// record adds the result of an addon to the run report and the status server.
+func record(r report.AddonResult) {
+	runReport.Add(r)
+	statusServer.SetAddonStatus(r.Scenario, r.Addon, string(r.Status))
+}
+
+// recordCheck adds the result of a check of a scenario to the run report.
+func recordCheck(s *scenario.Scenario, check checks.Check, status report.Status, reason string) {
+	runReport.AddCheck(report.CheckResult{Scenario: s.Name, Check: check.Name, Status: status, Reason: reason})
+}
+
+// runningScenario is the test and the result of a running scenario.
+type runningScenario struct {
+	t   *testing.T
+	err *error
+}
+
+// runningScenarios are the running scenarios by name, whose failures are
+// classified by the phase they happened in when their next phase starts.
+var runningScenarios sync.Map
+
+// setPhase records the phase of a scenario in the run report and the status
+// server. When the scenario has failed, the failure is recorded in the report
+// with the phase it failed in.
+func setPhase(scenario, phase string) {
+	if v, ok := runningScenarios.Load(scenario); ok {
+		running := v.(runningScenario)
+		if *running.err != nil {
+			runReport.Fail(scenario, runReport.Phase(scenario), (*running.err).Error())
+		} else if running.t.Failed() {
+			runReport.Fail(scenario, runReport.Phase(scenario), "")
+		}
+	}
+	runReport.SetPhase(scenario, phase)
+	statusServer.SetPhase(scenario, phase)
+}
+
+// logf logs to the test and to the status server.
+func logf(t *testing.T, format string, args ...interface{}) {
+	t.Helper()
+	t.Logf(format, args...)
+	statusServer.Logf(format, args...)
+}
+
+func result(scenario, groupname string, addon v1beta1.AddonInterface, status report.Status, reason string) report.AddonResult {
+	return report.AddonResult{
+		Scenario:     scenario,
+		Group:        groupname,
+		Addon:        addon.GetName(),
+		Revision:     addon.GetAnnotations()[revisionAnnotation],
+		Architecture: runtime.GOARCH,
+		Status:       status,
+		Reason:       reason,
+	}
+}
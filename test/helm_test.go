@@ -0,0 +1,89 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/helm"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/test"
+)
+
+// helmHarness deploys addons as helm releases using the embedded helm client
+// instead of the kubeaddons controller. It is used by scenarios with the helm backend, so
+// that chart regressions can be told apart from controller regressions.
+type helmHarness struct {
+	t         *testing.T
+	kubectl   kubectl.Client
+	version   semver.Version
+	fallback  *mirrorFallback
+	addons    []v1beta1.AddonInterface
+	installed []v1beta1.AddonInterface
+}
+
+var _ test.Harness = &helmHarness{}
+
+func newHelmHarness(t *testing.T, k kubectl.Client, version semver.Version, fallback *mirrorFallback, addons ...v1beta1.AddonInterface) (*helmHarness, error) {
+	ordered, err := requirements.Order(addons)
+	if err != nil {
+		return nil, err
+	}
+	return &helmHarness{t: t, kubectl: k, version: version, fallback: fallback, addons: ordered}, nil
+}
+
+// Validate fails the test unless every addon is deployed via a helm chart.
+func (h *helmHarness) Validate() {
+	for _, addon := range h.addons {
+		if addon.GetAddonSpec().ChartReference == nil {
+			h.t.Fatalf("addon %s can not be deployed with the helm backend, it has no chart", addon.GetName())
+		}
+	}
+}
+
+// Deploy installs the addons in the order of their requirements, waiting for
+// each with its timeout on the kubernetes version of the cluster. Addons whose
+// image pulls were rate limited are installed again with their images pulled
+// from the image mirror.
+func (h *helmHarness) Deploy() {
+	for _, addon := range h.addons {
+		logf(h.t, "installing addon %s as a helm release", addon.GetName())
+		timeout := addonTimeout(addon.GetName(), h.version, testConfig.Timeouts.HelmInstall)
+		err := helm.Install(h.kubectl, addon, timeout)
+		if err != nil {
+			if retry, fallbackErr := h.fallBack(addon); fallbackErr != nil {
+				logf(h.t, "WARNING: addon %s could not fall back to the image mirror: %s", addon.GetName(), fallbackErr)
+			} else if retry {
+				err = helm.Install(h.kubectl, addon, timeout)
+			}
+		}
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		h.installed = append(h.installed, addon)
+	}
+}
+
+// fallBack returns whether an addon whose install failed fell back to the
+// image mirror.
+func (h *helmHarness) fallBack(addon v1beta1.AddonInterface) (bool, error) {
+	found, err := h.fallback.rateLimitEvents()
+	if err != nil {
+		return false, err
+	}
+	return h.fallback.fallBack(addon, found)
+}
+
+// Default does nothing, there are no default addons without the controller.
+func (h *helmHarness) Default() {}
+
+// Cleanup uninstalls the installed addons in reverse order.
+func (h *helmHarness) Cleanup() {
+	for i := len(h.installed) - 1; i >= 0; i-- {
+		if err := helm.Uninstall(h.kubectl, h.installed[i]); err != nil {
+			h.t.Error(err)
+		}
+	}
+}
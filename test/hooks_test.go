@@ -0,0 +1,40 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/hooks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestValidateHelmHooks(t *testing.T) {
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := renderAddons(addons)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, problems := hooks.Extract(objects)
+	for _, problem := range problems {
+		t.Error(problem)
+	}
+}
+
+// verifyHooks fails the test when the install hook jobs of the deployed
+// addons failed, did not complete, or were not garbage collected as their
+// delete policies declare, instead of leaving it to readiness timeouts.
+func verifyHooks(t *testing.T, k kubectl.Client, objects []render.Object) error {
+	installHooks, _ := hooks.Extract(objects)
+	problems, err := hooks.Verify(k, installHooks)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		t.Errorf("helm hook problem: %s", problem)
+	}
+	return nil
+}
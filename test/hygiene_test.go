@@ -0,0 +1,84 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/hygiene"
+)
+
+// hygieneDirs are the directories whose YAML files must pass the hygiene
+// checks, apart from the seeds, which are templates rather than YAML.
+var hygieneDirs = []string{"../addons", "../metadata", "."}
+
+// seedsDir contains the seeds of the testing groups and scenarios.
+const seedsDir = "seeds"
+
+// TestValidateYAMLHygiene fails for every YAML file of the addons, their
+// metadata and the configuration of the tests (groups.yaml, overrides,
+// profiles, ...) with CRLF line endings, tabs in the indentation, duplicate
+// keys or content which changes when it is normalized, including the values
+// of the addons.
+func TestValidateYAMLHygiene(t *testing.T) {
+	var report []string
+	for _, dir := range hygieneDirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() && path == seedsDir {
+				return filepath.SkipDir
+			}
+			if info.IsDir() || !(strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+				return nil
+			}
+
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, problem := range hygiene.Check(b) {
+				report = append(report, fmt.Sprintf("%s: %s", path, problem))
+			}
+			for _, problem := range valuesHygiene(b) {
+				report = append(report, fmt.Sprintf("%s: values: %s", path, problem))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(report) > 0 {
+		t.Errorf("the following YAML files need to be normalized:\n%s", strings.Join(report, "\n"))
+	}
+}
+
+// valuesHygiene checks the values of the addons in a YAML file, which are
+// embedded as strings. Files which aren't addon manifests have no values, and
+// documents which can't be decoded are reported by the checks of the file.
+func valuesHygiene(b []byte) []hygiene.Problem {
+	var problems []hygiene.Problem
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for {
+		var addon struct {
+			Spec struct {
+				ChartReference struct {
+					Values string `yaml:"values"`
+				} `yaml:"chartReference"`
+			} `yaml:"spec"`
+		}
+		if err := decoder.Decode(&addon); err != nil {
+			return problems
+		}
+		problems = append(problems, hygiene.Check([]byte(addon.Spec.ChartReference.Values))...)
+	}
+}
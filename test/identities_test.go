@@ -0,0 +1,43 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/identities"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// testIdentities loads the test identities of identities.yaml.
+func testIdentities() (*identities.Fixture, error) {
+	return identities.Load("identities.yaml")
+}
+
+// provisionIdentities provisions the test identities in the cluster of a
+// scenario, the users only when dex is among its addons. The returned function
+// removes them.
+func provisionIdentities(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface) (func(), error) {
+	fixture, err := testIdentities()
+	if err != nil {
+		return nil, err
+	}
+	dex := false
+	for _, addon := range addons {
+		if addon.GetName() == "dex" {
+			dex = true
+		}
+	}
+
+	logf(t, "provisioning %d test users (dex: %t), %d service accounts and %d groups", len(fixture.Users), dex, len(fixture.ServiceAccounts), len(fixture.Groups))
+	cleanup := func() {
+		if err := identities.Remove(k, dex); err != nil {
+			logf(t, "WARNING: could not remove the test identities: %s", err)
+		}
+	}
+	if err := identities.Provision(k, fixture, dex); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return cleanup, nil
+}
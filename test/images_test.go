@@ -0,0 +1,179 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/images"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const imageLockfile = "images.lock.yaml"
+
+// TestValidateImageDigests resolves the digest of every image used by the
+// kommander group and fails when a digest differs from the one recorded in the
+// lockfile while the revision of its addon did not change. This defends
+// against mutable upstream tags silently changing what is shipped. Addons
+// which are not locked at their current revision, and locked addons which
+// aren't tested anymore, fail as well, so that the lockfile is regenerated
+// along with every bump: run with UPDATE_IMAGE_LOCK=true (`make image-lock`)
+// to record the current digests in the lockfile instead of verifying them.
+func TestValidateImageDigests(t *testing.T) {
+	lock, err := images.LoadLock(imageLockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update := os.Getenv("UPDATE_IMAGE_LOCK") == "true"
+
+	addons, err := addons(addonTestingGroups.Addons("kommander")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := make(images.Lock)
+	var drift []images.Drift
+	for _, addon := range addons {
+		objects, err := render.Addon(addon)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		current := images.LockedAddon{
+			Revision: addon.GetAnnotations()[revisionAnnotation],
+			Images:   make(map[string]string),
+		}
+		for _, image := range images.Extract(objects) {
+			ref, err := images.Parse(image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			digest, err := images.Digest(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current.Images[image] = digest
+		}
+		updated[addon.GetName()] = current
+		drift = append(drift, lock.Verify(addon.GetName(), current)...)
+	}
+	drift = append(drift, lock.Stale(names(addons))...)
+
+	if update {
+		for _, d := range drift {
+			logf(t, "updating: %s", d)
+		}
+		if err := updated.Save(imageLockfile); err != nil {
+			t.Fatal(err)
+		}
+		logf(t, "updated %s", imageLockfile)
+		return
+	}
+	for _, d := range drift {
+		t.Error(d)
+	}
+}
+
+// TestImageManifest writes the images required by every testing group and
+// scenario to the file IMAGE_MANIFEST ("-" for stdout), one image per line or
+// as JSON (with the images of each group and scenario) when it ends in .json,
+// e.g. for CI to pull them into a shared docker cache before the tests run.
+// Also available as `go run scripts/test-wrapper.go images`.
+func TestImageManifest(t *testing.T) {
+	path := os.Getenv("IMAGE_MANIFEST")
+	if path == "" {
+		t.Skip("IMAGE_MANIFEST is not set")
+	}
+
+	manifest := images.NewManifest()
+	for _, groupname := range listGroups() {
+		addons, err := addons(addonTestingGroups.Addons(groupname)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects, err := renderAddons(addons)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.Add("group/"+groupname, objects)
+	}
+
+	names, err := scenario.List("scenarios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		s, err := scenario.Load("scenarios", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addons, _, err := scenarioaddons(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects, err := renderAddons(addons)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.Add("scenario/"+name, objects)
+	}
+
+	format := "text"
+	if filepath.Ext(path) == ".json" {
+		format = "json"
+	}
+	if path == "-" {
+		if err := manifest.Write(os.Stdout, format); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := manifest.Write(f, format); err != nil {
+		t.Fatal(err)
+	}
+	logf(t, "wrote %d images to %s", len(manifest.Images), path)
+}
+
+// verifyHardenedImages verifies that the images of the rendered addons match
+// the hardenedImages of a scenario, failing the test for the images which
+// don't. Scenarios without hardenedImages verify HARDENED_IMAGE_PATTERN (e.g.
+// the tags of FIPS variants) instead, logging the images which don't match it
+// unless HARDENED_IMAGES is strict.
+func verifyHardenedImages(t *testing.T, s *scenario.Scenario, objects []render.Object) error {
+	expr, strict := s.HardenedImages, true
+	if expr == "" {
+		expr, strict = os.Getenv("HARDENED_IMAGE_PATTERN"), os.Getenv("HARDENED_IMAGES") == "strict"
+	}
+	if expr == "" {
+		return nil
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid HARDENED_IMAGE_PATTERN: %w", err)
+	}
+
+	unmatched := images.Unmatched(objects, pattern)
+	addonNames := make([]string, 0, len(unmatched))
+	for addon := range unmatched {
+		addonNames = append(addonNames, addon)
+	}
+	sort.Strings(addonNames)
+	for _, addon := range addonNames {
+		logf(t, "WARNING: addon %s uses images which are not hardened: %v", addon, unmatched[addon])
+	}
+
+	if len(addonNames) > 0 && strict {
+		return fmt.Errorf("addons %v use images which are not hardened", addonNames)
+	}
+	return nil
+}
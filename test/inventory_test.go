@@ -0,0 +1,74 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/inventory"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+)
+
+// verifyInventory fails the test unless the CRDs which were created while the
+// addons of a scenario were deployed (those which don't exist in baseline)
+// match the inventories in crds/ of its groups and the scenario itself exactly.
+// With UPDATE_CRD_INVENTORY=true the inventory is written instead: the group's
+// for scenarios of a single group, the scenario's otherwise.
+func verifyInventory(t *testing.T, k kubectl.Client, s *scenario.Scenario, baseline map[string]bool) error {
+	existing, err := teardown.ClusterCRDs(k)
+	if err != nil {
+		return err
+	}
+	var created []string
+	for name := range existing {
+		if _, ok := baseline[name]; !ok {
+			created = append(created, name)
+		}
+	}
+
+	var expected []string
+	found := false
+	for _, name := range append(append([]string{}, s.Groups...), s.Name) {
+		crds, ok, err := inventory.Load(filepath.Join("crds", name+".yaml"))
+		if err != nil {
+			return err
+		}
+		if ok && name == s.Name && os.Getenv("UPDATE_CRD_INVENTORY") == "true" {
+			// the scenario's inventory is being regenerated
+			continue
+		}
+		found = found || ok
+		expected = append(expected, crds...)
+	}
+
+	if os.Getenv("UPDATE_CRD_INVENTORY") == "true" {
+		path := filepath.Join("crds", s.Name+".yaml")
+		crds := created
+		if len(s.Groups) == 1 && len(s.Addons) == 0 {
+			path = filepath.Join("crds", s.Groups[0]+".yaml")
+		} else {
+			_, crds = inventory.Diff(expected, created)
+		}
+		if err := inventory.Save(path, crds); err != nil {
+			return err
+		}
+		logf(t, "updated %s", path)
+		return nil
+	}
+
+	if !found {
+		logf(t, "WARNING: no CRD inventory for scenario %s, see crds/", s.Name)
+		return nil
+	}
+
+	missing, unexpected := inventory.Diff(expected, created)
+	for _, name := range missing {
+		t.Errorf("expected CRD %s was not installed", name)
+	}
+	for _, name := range unexpected {
+		t.Errorf("unexpected CRD %s was installed, add it to the inventory in crds/ if it is expected", name)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/isolation"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// isolationAudit returns whether the install isolation of the addons is
+// verified, with ISOLATION_AUDIT=true.
+func isolationAudit() bool {
+	return os.Getenv("ISOLATION_AUDIT") == "true"
+}
+
+// isolateWave takes a snapshot of the cluster before a wave is deployed, the
+// returned function verifies that the addons of the wave only changed the
+// namespaces they install into (see isolation.yaml).
+func isolateWave(t *testing.T, k kubectl.Client, wave []v1beta1.AddonInterface) (func() error, error) {
+	if !isolationAudit() {
+		return func() error { return nil }, nil
+	}
+	config, err := isolation.Load("isolation.yaml")
+	if err != nil {
+		return nil, err
+	}
+	before, err := config.Take(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		after, err := config.Take(k)
+		if err != nil {
+			return err
+		}
+		set := make(map[string]bool)
+		for _, addon := range wave {
+			set[render.Namespace(addon)], set[addon.GetNamespace()] = true, true
+			for _, namespace := range addonTestingGroups.Namespaces(addon.GetName()) {
+				set[namespace] = true
+			}
+		}
+		allowed := make([]string, 0, len(set))
+		for namespace := range set {
+			allowed = append(allowed, namespace)
+		}
+		sort.Strings(allowed)
+		violations := config.Violations(before.Diff(after), allowed)
+		for _, violation := range violations {
+			logf(t, "ISOLATION: %v changed %s", names(wave), violation)
+		}
+		if len(violations) > 0 {
+			return fmt.Errorf("%v changed %d objects outside of the namespaces %s they install into (see isolation.yaml)", names(wave), len(violations), strings.Join(allowed, ", "))
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/license"
+)
+
+const testLicense = "kommander-test-license"
+
+// TestKommanderLicense deploys the kommander group, applies the test license
+// from the file in KOMMANDER_TEST_LICENSE and verifies that kommander reports
+// it as valid (with its cluster capacity) and as invalid once it is revoked,
+// and that attaching clusters, which the license gates, is allowed while it is
+// valid and denied once it is revoked.
+func TestKommanderLicense(t *testing.T) {
+	path := os.Getenv("KOMMANDER_TEST_LICENSE")
+	if path == "" {
+		t.Skip("KOMMANDER_TEST_LICENSE is not set")
+	}
+	token, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testscenario(t, "kommander-license", func(t *testing.T, k kubectl.Client) error {
+		return testLicenseFlow(t, k, token)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testLicenseFlow(t *testing.T, k kubectl.Client, token []byte) error {
+	if err := license.Apply(k, testLicense, token); err != nil {
+		return err
+	}
+	defer func() {
+		if err := license.Delete(k, testLicense); err != nil {
+			logf(t, "WARNING: could not remove license %s: %s", testLicense, err)
+		}
+	}()
+
+	status, err := license.Wait(k, testLicense, true, testConfig.Timeouts.License)
+	if err != nil {
+		t.Errorf("license was not accepted: %s", err)
+		return nil
+	}
+	logf(t, "license %s is valid until %s for %d clusters", testLicense, status.ExpiryDate, status.ClusterCapacity)
+	if status.ClusterCapacity <= 0 {
+		t.Errorf("valid license %s reports a cluster capacity of %d", testLicense, status.ClusterCapacity)
+	}
+	if err := license.WaitAttachAllowed(k, true, testConfig.Timeouts.License); err != nil {
+		t.Errorf("attaching a cluster is not allowed with a valid license: %s", err)
+	}
+
+	if err := license.Revoke(k, testLicense); err != nil {
+		return err
+	}
+	if _, err := license.Wait(k, testLicense, false, testConfig.Timeouts.License); err != nil {
+		t.Errorf("license was not invalidated after it was revoked: %s", err)
+	}
+	if err := license.WaitAttachAllowed(k, false, testConfig.Timeouts.License); err != nil {
+		t.Errorf("attaching a cluster is still allowed after the license was revoked: %s", err)
+	}
+	return nil
+}
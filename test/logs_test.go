@@ -0,0 +1,79 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/logscan"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// scanLogs scans the logs of the pods of the addons which rendered the given
+// objects for the patterns in logpatterns.yaml, failing the test for matches
+// of fail patterns and logging matches of warn patterns. The logs are archived
+// for the page of the run report (see archiveLogs).
+func scanLogs(t *testing.T, k kubectl.Client, scenario string, objects []render.Object) error {
+	config, err := logscan.Load("logpatterns.yaml")
+	if err != nil {
+		return err
+	}
+
+	logs, err := logscan.Collect(k, objects)
+	if err != nil {
+		return err
+	}
+	writeLogs(t, scenario, logs)
+
+	for addon, addonLogs := range logs {
+		rules, err := config.Rules(addon)
+		if err != nil {
+			return err
+		}
+		for _, log := range addonLogs {
+			for _, match := range rules.Scan(addon, log) {
+				if match.Fatal {
+					t.Errorf("log error: %s", match)
+				} else {
+					logf(t, "WARNING: log error: %s", match)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// archiveLogs writes the logs of the pods of the addons which rendered the
+// given objects to REPORT_DIR/logs/<scenario>/<addon>.log, from which they are
+// rendered into report.html, e.g. of a scenario which failed before its logs
+// were scanned.
+func archiveLogs(t *testing.T, k kubectl.Client, scenario string, objects []render.Object) {
+	logs, err := logscan.Collect(k, objects)
+	if err != nil {
+		logf(t, "WARNING: could not collect the logs of scenario %s: %s", scenario, err)
+		return
+	}
+	writeLogs(t, scenario, logs)
+}
+
+// writeLogs writes the logs of the addons of a scenario to REPORT_DIR, one
+// file per addon with a header per container.
+func writeLogs(t *testing.T, scenario string, logs map[string][]logscan.Log) {
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	for addon, addonLogs := range logs {
+		text := new(strings.Builder)
+		for _, log := range addonLogs {
+			fmt.Fprintf(text, "==> %s <==\n%s\n", log.Source, strings.TrimRight(log.Text, "\n"))
+		}
+		if err := report.WriteLog(dir, scenario, addon, text.String()); err != nil {
+			logf(t, "WARNING: could not archive the logs of addon %s of scenario %s: %s", addon, scenario, err)
+		}
+	}
+}
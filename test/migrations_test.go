@@ -0,0 +1,150 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/migrate"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+const migrationsDir = "migrations"
+
+// TestValidateValuesMigrations verifies the values migrations of addon
+// revisions (see migrations/): every representative override must be accepted
+// by the chart of the previous revision and, once migrated, by the chart of the
+// new revision, which must also render with the migrated values.
+func TestValidateValuesMigrations(t *testing.T) {
+	migrations, err := migrate.Load(migrationsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo, err := local.NewRepository("base", "../addons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := repo.ListAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range migrations {
+		revisions, ok := addons[m.Addon]
+		if !ok || len(revisions) == 0 {
+			t.Errorf("migration %s/%s/%s.yaml is for an addon which does not exist", migrationsDir, m.Addon, m.Revision)
+			continue
+		}
+		addon := revisions[0]
+		ref := addon.GetAddonSpec().ChartReference
+		if ref == nil {
+			t.Errorf("migration %s/%s/%s.yaml is for an addon which does not reference a chart", migrationsDir, m.Addon, m.Revision)
+			continue
+		}
+		if addon.GetAnnotations()[revisionAnnotation] == m.Revision && ref.Version != m.Chart.To {
+			t.Errorf("migration %s/%s/%s.yaml migrates to chart version %s, but the revision references %s", migrationsDir, m.Addon, m.Revision, m.Chart.To, ref.Version)
+			continue
+		}
+
+		for _, err := range verifyMigration(addon, m) {
+			t.Error(err)
+		}
+	}
+}
+
+// verifyMigration applies a migration to its overrides and verifies the charts
+// of both revisions accept them.
+func verifyMigration(addon v1beta1.AddonInterface, m migrate.Migration) []error {
+	ref := addon.GetAddonSpec().ChartReference
+	from, to := *ref, *ref
+	from.Version, to.Version = m.Chart.From, m.Chart.To
+
+	fromChart, err := render.PullChart(&from)
+	if err != nil {
+		return []error{err}
+	}
+	toChart, err := render.PullChart(&to)
+	if err != nil {
+		return []error{err}
+	}
+
+	base := ""
+	if ref.Values != nil {
+		base = *ref.Values
+	}
+
+	names := make([]string, 0, len(m.Overrides))
+	for name := range m.Overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		override := m.Overrides[name]
+		desc := fmt.Sprintf("override %s of migration %s/%s", name, m.Addon, m.Revision)
+
+		if problems, err := acceptedValues(override, fromChart); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", desc, err))
+			continue
+		} else if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("%s is not accepted by chart %s %s, it is not representative of the previous revision:\n%s", desc, ref.Chart, m.Chart.From, strings.Join(problems, "\n")))
+			continue
+		}
+
+		migrated, err := m.Apply(override)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", desc, err))
+			continue
+		}
+		if problems, err := acceptedValues(migrated, toChart); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", desc, err))
+			continue
+		} else if len(problems) > 0 {
+			errs = append(errs, fmt.Errorf("%s is not accepted by chart %s %s once migrated:\n%s", desc, ref.Chart, m.Chart.To, strings.Join(problems, "\n")))
+			continue
+		}
+
+		merged, err := mergeValues(base, migrated)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", desc, err))
+			continue
+		}
+		ref.Version, ref.Values = m.Chart.To, &merged
+		if _, err := render.Addon(addon); err != nil {
+			errs = append(errs, fmt.Errorf("%s does not render once migrated: %w", desc, err))
+		}
+	}
+
+	return errs
+}
+
+// acceptedValues returns the problems of a values block with a chart: schema
+// violations of the block merged over the default values of the chart, and
+// keys which do not exist in its default values.
+func acceptedValues(valuesYAML string, chart *render.Chart) ([]string, error) {
+	parsed, err := values.Parse(valuesYAML)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if chart.Schema != nil {
+		violations, err := values.ValidateSchema(values.Merge(chart.Values, parsed), chart.Schema)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, violations...)
+	}
+	for _, key := range values.UnknownKeys(parsed, chart.Values) {
+		problems = append(problems, fmt.Sprintf("%s does not exist in the default values", key))
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/monitoring"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const monitoringInventory = "monitoring.yaml"
+
+func TestValidateMonitoringInventory(t *testing.T) {
+	inventory, err := monitoring.Load(monitoringInventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the addons of the testing groups and the catalog addons of scenarios
+	tested := make(map[string]bool)
+	for groupname := range addonTestingGroups {
+		for _, name := range addonTestingGroups.Addons(groupname) {
+			tested[name] = true
+		}
+	}
+	scenarios, err := scenario.List("scenarios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range scenarios {
+		s, err := scenario.Load("scenarios", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, addon := range s.Addons {
+			tested[addon] = true
+		}
+	}
+
+	if !tested[inventory.Addon] {
+		t.Errorf("%s: addon %s, which deploys prometheus, is deployed by no testing group or scenario", monitoringInventory, inventory.Addon)
+	}
+	for name := range inventory.Addons {
+		if !tested[name] {
+			t.Errorf("%s lists addon %s, which is deployed by no testing group or scenario", monitoringInventory, name)
+		}
+	}
+}
+
+// verifyMonitoring fails the test when the runtime configuration of
+// prometheus doesn't match the monitoring inventory of the deployed addons
+// (see monitoring.yaml), e.g. because a ServiceMonitor silently stopped
+// selecting the service it scrapes. Scenarios without prometheus are skipped.
+func verifyMonitoring(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface) error {
+	inventory, err := monitoring.Load(monitoringInventory)
+	if err != nil {
+		return err
+	}
+	deployed := names(addons)
+	withPrometheus := false
+	for _, name := range deployed {
+		withPrometheus = withPrometheus || name == inventory.Addon
+	}
+	if !withPrometheus {
+		logf(t, "not verifying the monitoring inventory, addon %s is not deployed", inventory.Addon)
+		return nil
+	}
+
+	state, problems, err := inventory.Verify(k, deployed, testConfig.Timeouts.Wait, defaultWaitInterval)
+	if err != nil {
+		return err
+	}
+	for _, job := range inventory.Unlisted(state) {
+		logf(t, "WARNING: job %s is scraped, but it is not listed in %s", job, monitoringInventory)
+	}
+	for _, problem := range problems {
+		t.Errorf("monitoring problem: %s", problem)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// deployFirst moves the addons a scenario deploys first (see DeployFirst) out
+// of the waves into a wave of their own before them.
+func deployFirst(s *scenario.Scenario, waves [][]v1beta1.AddonInterface) ([][]v1beta1.AddonInterface, error) {
+	if len(s.DeployFirst) == 0 {
+		return waves, nil
+	}
+
+	first := make(map[string]bool, len(s.DeployFirst))
+	for _, name := range s.DeployFirst {
+		first[name] = true
+	}
+
+	var firstWave []v1beta1.AddonInterface
+	reordered := [][]v1beta1.AddonInterface{nil}
+	for _, wave := range waves {
+		var rest []v1beta1.AddonInterface
+		for _, addon := range wave {
+			if first[addon.GetName()] {
+				firstWave = append(firstWave, addon)
+				continue
+			}
+			rest = append(rest, addon)
+		}
+		if len(rest) > 0 {
+			reordered = append(reordered, rest)
+		}
+	}
+	if len(firstWave) != len(first) {
+		return nil, fmt.Errorf("scenario %s deploys %v first, but only %v are deployed", s.Name, s.DeployFirst, names(firstWave))
+	}
+	reordered[0] = firstWave
+	return reordered, nil
+}
+
+// labeledNamespaces returns the namespaces of the addons which the namespace
+// labels of a scenario are applied to: all but those of the addons deployed
+// first.
+func labeledNamespaces(s *scenario.Scenario, addons []v1beta1.AddonInterface) []string {
+	if len(s.NamespaceLabels) == 0 {
+		return nil
+	}
+
+	first := make(map[string]bool, len(s.DeployFirst))
+	for _, name := range s.DeployFirst {
+		first[name] = true
+	}
+	var firstAddons, labeled []v1beta1.AddonInterface
+	for _, addon := range addons {
+		if first[addon.GetName()] {
+			firstAddons = append(firstAddons, addon)
+		} else {
+			labeled = append(labeled, addon)
+		}
+	}
+
+	excluded := make(map[string]bool)
+	for _, namespace := range addonNamespaces(firstAddons) {
+		excluded[namespace] = true
+	}
+	var namespaces []string
+	for _, namespace := range addonNamespaces(labeled) {
+		if !excluded[namespace] {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// labelNamespaces creates the namespaces with the namespace labels of a
+// scenario, or labels them when they already exist.
+func labelNamespaces(t *testing.T, k kubectl.Client, s *scenario.Scenario, namespaces []string) error {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	items := make([]interface{}, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": namespace, "labels": s.NamespaceLabels},
+		})
+	}
+	b, err := json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+	if err != nil {
+		return err
+	}
+
+	setPhase(s.Name, "labeling namespaces")
+	logf(t, "labeling namespaces %v with %v", namespaces, s.NamespaceLabels)
+	if err := k.Apply(b); err != nil {
+		return fmt.Errorf("could not label namespaces: %w", err)
+	}
+	return nil
+}
+
+// verifySidecars fails the test for every pod in the namespaces which the
+// sidecar of a scenario has not been injected into. Completed pods are
+// ignored.
+func verifySidecars(t *testing.T, k kubectl.Client, s *scenario.Scenario, namespaces []string) error {
+	if s.Sidecar == "" {
+		return nil
+	}
+
+	for _, namespace := range namespaces {
+		out, err := k.Output("get", "pods", "--namespace", namespace, "--output", "json")
+		if err != nil {
+			return err
+		}
+		var pods struct {
+			Items []struct {
+				Metadata struct {
+					Name string `json:"name"`
+				} `json:"metadata"`
+				Spec struct {
+					Containers []struct {
+						Name string `json:"name"`
+					} `json:"containers"`
+				} `json:"spec"`
+				Status struct {
+					Phase string `json:"phase"`
+				} `json:"status"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(out, &pods); err != nil {
+			return fmt.Errorf("could not decode the pods of namespace %s: %w", namespace, err)
+		}
+
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == "Succeeded" {
+				continue
+			}
+			injected := false
+			for _, container := range pod.Spec.Containers {
+				if container.Name == s.Sidecar {
+					injected = true
+				}
+			}
+			if !injected {
+				t.Errorf("sidecar %s was not injected into pod %s/%s", s.Sidecar, namespace, pod.Metadata.Name)
+			}
+		}
+	}
+	return nil
+}
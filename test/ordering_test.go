@@ -0,0 +1,160 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/ordering"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+const (
+	defaultOrderingTrials  = 5
+	defaultOrderingTimeout = 3 * time.Minute
+)
+
+// TestOrderingRaces deploys the scenario named in ORDERING_RACES, then removes
+// and deploys its addons again one at a time in ORDERING_TRIALS (default: 5)
+// random orders on the same cluster, each addon waited for within
+// ORDERING_TIMEOUT (default: 3m). Addons which only fail in some orders have
+// implicit dependencies on the addons which came after them whenever they
+// failed, which are reported (and written to REPORT_DIR as ordering.json) to
+// be declared explicitly. ORDERING_SEED reproduces the orders of a run.
+func TestOrderingRaces(t *testing.T) {
+	name := os.Getenv("ORDERING_RACES")
+	if name == "" {
+		t.Skip("ORDERING_RACES is not set")
+	}
+
+	s, err := scenario.Load("scenarios", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Backend == scenario.HelmBackend {
+		t.Skipf("scenario %s uses the helm backend, which deploys addons without the controller", s.Name)
+	}
+	if err := testscenario(t, name, orderingStep(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// orderingStep returns the step which deploys the addons of a scenario in
+// random orders and reports the implicit dependencies among them.
+func orderingStep(s *scenario.Scenario) func(t *testing.T, k kubectl.Client) error {
+	return func(t *testing.T, k kubectl.Client) error {
+		trials := defaultOrderingTrials
+		if env := os.Getenv("ORDERING_TRIALS"); env != "" {
+			var err error
+			if trials, err = strconv.Atoi(env); err != nil || trials < 1 {
+				return fmt.Errorf("invalid ORDERING_TRIALS %q, expected a positive number", env)
+			}
+		}
+		timeout, err := durationEnv("ORDERING_TIMEOUT", defaultOrderingTimeout)
+		if err != nil {
+			return err
+		}
+		seed := time.Now().UnixNano()
+		if env := os.Getenv("ORDERING_SEED"); env != "" {
+			if seed, err = strconv.ParseInt(env, 10, 64); err != nil {
+				return fmt.Errorf("invalid ORDERING_SEED %q: %w", env, err)
+			}
+		}
+		logf(t, "deploying the addons of scenario %s in %d random orders (ORDERING_SEED=%d)", s.Name, trials, seed)
+
+		scenarioAddons, groupOf, err := scenarioaddons(s)
+		if err != nil {
+			return err
+		}
+		addons := deployable(t, s.Name, scenarioAddons, groupOf, skippedAddons())
+		byName := make(map[string]v1beta1.AddonInterface, len(addons))
+		for _, addon := range addons {
+			byName[addon.GetName()] = addon
+		}
+
+		r := rand.New(rand.NewSource(seed))
+		var results []ordering.Trial
+		for i := 1; i <= trials; i++ {
+			order := ordering.Permutation(names(addons), explicitRequires(addons), r)
+			setPhase(s.Name, fmt.Sprintf("ordering trial %d/%d", i, trials))
+			logf(t, "ordering trial %d/%d: %v", i, trials, order)
+
+			for j := len(order) - 1; j >= 0; j-- {
+				if err := deleteAddon(k, byName[order[j]], testConfig.Timeouts.Teardown); err != nil {
+					return err
+				}
+			}
+			trial := ordering.Trial{Order: order, Failed: make(map[string]string)}
+			for _, name := range order {
+				if err := applyAddon(k, byName[name], timeout); err != nil {
+					logf(t, "ordering trial %d/%d: %s", i, trials, err)
+					trial.Failed[name] = err.Error()
+				}
+			}
+			results = append(results, trial)
+		}
+
+		report := ordering.Analyze(results)
+		for _, dep := range report.Dependencies {
+			t.Errorf("implicit dependency: %s", dep)
+		}
+		if len(report.Unexplained) > 0 {
+			logf(t, "WARNING: addons which failed regardless of the order: %v", report.Unexplained)
+		}
+		return writeOrderingReport(report)
+	}
+}
+
+// explicitRequires maps the addons to the addons they require explicitly.
+func explicitRequires(addons []v1beta1.AddonInterface) map[string][]string {
+	requires := make(map[string][]string)
+	for _, addon := range addons {
+		for _, required := range requirements.Required(addon, addons) {
+			requires[addon.GetName()] = append(requires[addon.GetName()], required.GetName())
+		}
+	}
+	return requires
+}
+
+// deleteAddon removes an addon and waits up to timeout for it to be gone.
+func deleteAddon(k kubectl.Client, addon v1beta1.AddonInterface, timeout time.Duration) error {
+	kind := "addon"
+	if _, ok := addon.(*v1beta1.ClusterAddon); ok {
+		kind = "clusteraddon"
+	}
+	args := []string{"delete", kind + "/" + addon.GetName(), "--ignore-not-found", "--wait", "--timeout", timeout.String()}
+	if kind == "addon" {
+		args = append(args, "--namespace", addon.GetNamespace())
+	}
+	if _, err := k.Output(args...); err != nil {
+		return fmt.Errorf("could not remove addon %s: %w", addon.GetName(), err)
+	}
+	return nil
+}
+
+// writeOrderingReport writes the report of the ordering trials to REPORT_DIR
+// (default: reports/) as ordering.json.
+func writeOrderingReport(report ordering.Report) error {
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "ordering.json"), b, 0644)
+}
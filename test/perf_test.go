@@ -0,0 +1,262 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/catalog"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/perf"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+)
+
+const (
+	// syntheticAddons is the number of addons of the synthetic catalog the
+	// benchmarks run against, several times the size of the catalog of this
+	// repository and the kubernetes base addons.
+	syntheticAddons = 500
+
+	// syntheticRevisions is the number of revisions of each synthetic addon.
+	syntheticRevisions = 3
+
+	// syntheticChain is the length of the chains of requirements of the
+	// synthetic addons: every addon requires the one before it, except the
+	// first of each chain.
+	syntheticChain = 10
+)
+
+// syntheticCatalog writes a catalog of syntheticAddons addons, each with
+// syntheticRevisions revisions, and a groups file listing them to a
+// temporary directory, returning the directory and a function removing it.
+func syntheticCatalog(tb testing.TB) (string, func()) {
+	dir, err := ioutil.TempDir("", "synthetic-catalog")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	groupsFile := new(strings.Builder)
+	for i := 0; i < syntheticAddons; i++ {
+		name := fmt.Sprintf("synthetic-%03d", i)
+		if i%50 == 0 {
+			fmt.Fprintf(groupsFile, "synthetic-%02d:\n", i/50)
+		}
+		switch i % 5 {
+		case 0:
+			fmt.Fprintf(groupsFile, "    - name: %q\n      skip: true\n      reason: \"synthetic\"\n", name)
+		case 1:
+			fmt.Fprintf(groupsFile, "    - name: %q\n      supportedVersions: \">=1.15.0\"\n      timeouts:\n        \">=1.16.0\": \"15m\"\n", name)
+		default:
+			fmt.Fprintf(groupsFile, "    - %q\n", name)
+		}
+
+		requires := ""
+		if i%syntheticChain != 0 {
+			requires = fmt.Sprintf("  requires:\n    - matchLabels:\n        kubeaddons.mesosphere.io/name: synthetic-%03d\n", i-1)
+		}
+		for revision := 1; revision <= syntheticRevisions; revision++ {
+			path := filepath.Join(dir, "addons", name, fmt.Sprintf("%d.x", revision), name+".yaml")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				tb.Fatal(err)
+			}
+			addon := fmt.Sprintf(`---
+apiVersion: kubeaddons.mesosphere.io/v1beta1
+kind: Addon
+metadata:
+  name: %[1]s
+  namespace: kubeaddons
+  labels:
+    kubeaddons.mesosphere.io/name: %[1]s
+  annotations:
+    catalog.kubeaddons.mesosphere.io/addon-revision: "%[2]d.0.0-1"
+spec:
+  kubernetes:
+    minSupportedVersion: v1.15.0
+%[3]s  chartReference:
+    chart: %[1]s
+    repo: https://charts.example.com/stable
+    version: %[2]d.0.0
+    values: |
+      ---
+      replicas: 1
+`, name, revision, requires)
+			if err := ioutil.WriteFile(path, []byte(addon), 0644); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "groups.yaml"), []byte(groupsFile.String()), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func BenchmarkCatalogConstruction(b *testing.B) {
+	dir, cleanup := syntheticCatalog(b)
+	defer cleanup()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		repo, err := local.NewRepository("synthetic", filepath.Join(dir, "addons"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := catalog.NewCatalog(repo); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkListAddons(b *testing.B) {
+	dir, cleanup := syntheticCatalog(b)
+	defer cleanup()
+	repo, err := local.NewRepository("synthetic", filepath.Join(dir, "addons"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	cat, err := catalog.NewCatalog(repo)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		addons, err := cat.ListAddons()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(addons) != syntheticAddons {
+			b.Fatalf("expected %d addons, got %d", syntheticAddons, len(addons))
+		}
+	}
+}
+
+func BenchmarkRevisionResolution(b *testing.B) {
+	dir, cleanup := syntheticCatalog(b)
+	defer cleanup()
+	repo, err := local.NewRepository("synthetic", filepath.Join(dir, "addons"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		latest, err := latestCatalogAddons(repo)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		for _, addon := range latest {
+			if revision := addon.GetAnnotations()[revisionAnnotation]; revision != fmt.Sprintf("%d.0.0-1", syntheticRevisions) {
+				b.Fatalf("expected the latest revision of addon %s, got %s", addon.GetName(), revision)
+			}
+		}
+		b.StartTimer()
+	}
+}
+
+func BenchmarkRequirementResolution(b *testing.B) {
+	dir, cleanup := syntheticCatalog(b)
+	defer cleanup()
+	repo, err := local.NewRepository("synthetic", filepath.Join(dir, "addons"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	latest, err := latestCatalogAddons(repo)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		required, err := requirements.Resolve(fmt.Sprintf("synthetic-%03d", syntheticAddons-1), latest)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(required) != syntheticChain {
+			b.Fatalf("expected %d required addons, got %d", syntheticChain, len(required))
+		}
+	}
+}
+
+func BenchmarkGroupsLoad(b *testing.B) {
+	dir, cleanup := syntheticCatalog(b)
+	defer cleanup()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		g, err := groups.Load(filepath.Join(dir, "groups.yaml"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if addons := g.Addons("synthetic-00"); len(addons) != 50 {
+			b.Fatalf("expected 50 addons in group synthetic-00, got %d", len(addons))
+		}
+	}
+}
+
+// benchmarks are the benchmarks TestPerf compares against the thresholds of
+// perf.yaml.
+var benchmarks = map[string]func(*testing.B){
+	"BenchmarkCatalogConstruction":   BenchmarkCatalogConstruction,
+	"BenchmarkListAddons":            BenchmarkListAddons,
+	"BenchmarkRevisionResolution":    BenchmarkRevisionResolution,
+	"BenchmarkRequirementResolution": BenchmarkRequirementResolution,
+	"BenchmarkGroupsLoad":            BenchmarkGroupsLoad,
+}
+
+// TestValidatePerfThresholds verifies that every benchmark of TestPerf, and
+// only those, have a threshold in perf.yaml.
+func TestValidatePerfThresholds(t *testing.T) {
+	thresholds, err := perf.Load("perf.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name := range benchmarks {
+		if _, ok := thresholds[name]; !ok {
+			t.Errorf("benchmark %s has no threshold in perf.yaml", name)
+		}
+	}
+	for name := range thresholds {
+		if _, ok := benchmarks[name]; !ok {
+			t.Errorf("perf.yaml has a threshold for benchmark %s, which does not exist", name)
+		}
+	}
+}
+
+// TestPerf runs the benchmarks of the catalog and repository operations every
+// CI job depends on and fails for those which exceed their thresholds in
+// perf.yaml. It is skipped unless PERF=true.
+func TestPerf(t *testing.T) {
+	if os.Getenv("PERF") != "true" {
+		t.Skip("PERF is not true")
+	}
+	thresholds, err := perf.Load("perf.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var results []perf.Result
+	for name, benchmark := range benchmarks {
+		r := testing.Benchmark(benchmark)
+		if r.N == 0 {
+			t.Errorf("benchmark %s failed", name)
+			continue
+		}
+		logf(t, "%s: %s %s", name, r.String(), r.MemString())
+		results = append(results, perf.Result{Name: name, NsPerOp: r.NsPerOp(), AllocsPerOp: r.AllocsPerOp()})
+	}
+	for _, regression := range thresholds.Regressions(results) {
+		t.Error(regression)
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// outcomePhases are the phases which are only set once the outcome of a
+// scenario is known, so no failure is classified by them.
+var outcomePhases = map[string]bool{
+	"finished":                    true,
+	"writing reproduction bundle": true,
+}
+
+// TestValidatePhases fails for phases set by the tests of the package whose
+// failures are not classified (see pkg/report), which would make the run exit
+// with an unclassified failure.
+func TestValidatePhases(t *testing.T) {
+	phases, err := setPhases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(phases) == 0 {
+		t.Fatal("found no phases set by the tests")
+	}
+	for _, phase := range phases {
+		if outcomePhases[phase] {
+			continue
+		}
+		if failure := report.Classify(phase, ""); failure == report.UnknownFailure {
+			t.Errorf("failures in phase %q are not classified, add it to the phases of pkg/report", phase)
+		}
+	}
+}
+
+// setPhases returns the phases the tests of the package set with setPhase,
+// the constant prefix of those which are formatted.
+func setPhases() ([]string, error) {
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		return nil, err
+	}
+	var phases []string
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 2 {
+				return true
+			}
+			if ident, ok := call.Fun.(*ast.Ident); !ok || ident.Name != "setPhase" {
+				return true
+			}
+			if phase, ok := phasePrefix(call.Args[1]); ok {
+				phases = append(phases, phase)
+			}
+			return true
+		})
+	}
+	return phases, nil
+}
+
+// phasePrefix returns the constant prefix of a phase: a string literal, the
+// literal a string is appended to, or the format of fmt.Sprintf up to its
+// first verb.
+func phasePrefix(expr ast.Expr) (string, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.STRING {
+			return "", false
+		}
+		phase, err := strconv.Unquote(e.Value)
+		return phase, err == nil
+	case *ast.BinaryExpr:
+		if e.Op != token.ADD {
+			return "", false
+		}
+		return phasePrefix(e.X)
+	case *ast.CallExpr:
+		sel, ok := e.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "Sprintf" || len(e.Args) == 0 {
+			return "", false
+		}
+		format, ok := phasePrefix(e.Args[0])
+		if !ok {
+			return "", false
+		}
+		return strings.SplitN(format, "%", 2)[0], true
+	}
+	return "", false
+}
@@ -0,0 +1,148 @@
+package appcatalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const (
+	// Namespace and Service are the namespace and the service of the
+	// kubeaddons-catalog API of kommander, which serves the catalog of
+	// applications the kommander UI installs into workspaces.
+	Namespace = "kommander"
+	Service   = "http:kommander-kubeaddons-catalog:8080"
+
+	// appsPath lists the applications of the catalog, appPath (with the name
+	// of an application) returns one of them.
+	appsPath = "/api/v1beta1/addons"
+	appPath  = "/api/v1beta1/addons/%s"
+
+	revisionAnnotation = "catalog.kubeaddons.mesosphere.io/addon-revision"
+)
+
+// App is an application of the catalog: an Addon or ClusterAddon of one of
+// the repositories of kommander, along with its manifest as JSON.
+type App struct {
+	Name     string
+	Kind     string
+	Revision string
+	Manifest json.RawMessage
+}
+
+// Namespaced returns whether an application can be installed into the
+// projects of a workspace, which only takes Addons.
+func (a App) Namespaced() bool {
+	return a.Kind == "Addon"
+}
+
+// Client requests the catalog API through the API server proxy.
+type Client struct {
+	proxy *kubectl.Proxy
+}
+
+// New returns a client of the catalog API of kommander.
+func New(proxy *kubectl.Proxy) *Client {
+	return &Client{proxy: proxy}
+}
+
+// Apps lists the applications of the catalog, sorted by name.
+func (c *Client) Apps() ([]App, error) {
+	b, err := c.get(appsPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseApps(b)
+}
+
+// App returns an application of the catalog.
+func (c *Client) App(name string) (App, error) {
+	b, err := c.get(fmt.Sprintf(appPath, name))
+	if err != nil {
+		return App{}, err
+	}
+	return parseApp(b)
+}
+
+func (c *Client) get(path string) ([]byte, error) {
+	target := c.proxy.ServiceURL(Namespace, Service, path)
+	resp, err := http.Get(target)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d: %s", target, resp.StatusCode, b)
+	}
+	return b, nil
+}
+
+// ParseApps parses the applications of a response of the catalog API, either
+// a list of addons or an object with the list as its items.
+func ParseApps(b []byte) ([]App, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(b, &items); err != nil {
+		list := struct {
+			Items []json.RawMessage `json:"items"`
+		}{}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, fmt.Errorf("could not decode the applications of the catalog: %w", err)
+		}
+		items = list.Items
+	}
+
+	apps := make([]App, 0, len(items))
+	for _, item := range items {
+		app, err := parseApp(item)
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps, nil
+}
+
+func parseApp(b []byte) (App, error) {
+	addon := struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(b, &addon); err != nil {
+		return App{}, fmt.Errorf("could not decode application of the catalog: %w", err)
+	}
+	if addon.Metadata.Name == "" {
+		return App{}, fmt.Errorf("application of the catalog has no name")
+	}
+	if addon.Kind != "Addon" && addon.Kind != "ClusterAddon" {
+		return App{}, fmt.Errorf("application %s of the catalog is a %q, not an Addon or ClusterAddon", addon.Metadata.Name, addon.Kind)
+	}
+	return App{
+		Name:     addon.Metadata.Name,
+		Kind:     addon.Kind,
+		Revision: addon.Metadata.Annotations[revisionAnnotation],
+		Manifest: json.RawMessage(b),
+	}, nil
+}
+
+// Find returns the application of the given name.
+func Find(apps []App, name string) (App, bool) {
+	for _, app := range apps {
+		if app.Name == name {
+			return app, true
+		}
+	}
+	return App{}, false
+}
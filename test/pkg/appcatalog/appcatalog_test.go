@@ -0,0 +1,86 @@
+package appcatalog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseApps(t *testing.T) {
+	const (
+		reloader = `{"apiVersion": "kubeaddons.mesosphere.io/v1beta1", "kind": "Addon", "metadata": {"name": "reloader", "annotations": {"catalog.kubeaddons.mesosphere.io/addon-revision": "v0.0.38-1"}}}`
+		traefik  = `{"apiVersion": "kubeaddons.mesosphere.io/v1beta1", "kind": "ClusterAddon", "metadata": {"name": "traefik"}}`
+	)
+
+	for _, tc := range []struct {
+		response string
+		expected []App
+		err      string
+	}{
+		{
+			response: "[" + traefik + "," + reloader + "]",
+			expected: []App{
+				{Name: "reloader", Kind: "Addon", Revision: "v0.0.38-1"},
+				{Name: "traefik", Kind: "ClusterAddon"},
+			},
+		},
+		{
+			response: `{"items": [` + reloader + "]}",
+			expected: []App{{Name: "reloader", Kind: "Addon", Revision: "v0.0.38-1"}},
+		},
+		{
+			response: `{"items": []}`,
+			expected: []App{},
+		},
+		{
+			response: `[{"kind": "Addon", "metadata": {}}]`,
+			err:      "has no name",
+		},
+		{
+			response: `[{"kind": "ConfigMap", "metadata": {"name": "reloader"}}]`,
+			err:      `is a "ConfigMap"`,
+		},
+		{
+			response: `not json`,
+			err:      "could not decode",
+		},
+	} {
+		apps, err := ParseApps([]byte(tc.response))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected an error containing %q for %s, got %v", tc.err, tc.response, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", tc.response, err)
+			continue
+		}
+		if len(apps) != len(tc.expected) {
+			t.Errorf("expected %d applications for %s, got %d", len(tc.expected), tc.response, len(apps))
+			continue
+		}
+		for i, app := range apps {
+			expected := tc.expected[i]
+			if app.Name != expected.Name || app.Kind != expected.Kind || app.Revision != expected.Revision {
+				t.Errorf("expected application %d of %s to be %+v, got %+v", i, tc.response, expected, app)
+			}
+			if len(app.Manifest) == 0 {
+				t.Errorf("expected application %s to have a manifest", app.Name)
+			}
+		}
+	}
+
+	apps, err := ParseApps([]byte("[" + reloader + "," + traefik + "]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app, ok := Find(apps, "reloader"); !ok || !app.Namespaced() {
+		t.Errorf("expected to find the namespaced application reloader, got %+v", app)
+	}
+	if app, ok := Find(apps, "traefik"); !ok || app.Namespaced() {
+		t.Errorf("expected to find the cluster-scoped application traefik, got %+v", app)
+	}
+	if _, ok := Find(apps, "jenkins"); ok {
+		t.Error("expected not to find application jenkins")
+	}
+}
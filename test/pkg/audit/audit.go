@@ -0,0 +1,337 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// policyDir is the directory of the audit policy on the control plane
+	// nodes and in the API server.
+	policyDir = "/etc/kubernetes/audit"
+
+	// logDir is the directory of the audit log on the control plane nodes and
+	// in the API server.
+	logDir = "/var/log/kubernetes/audit"
+
+	logFile = "audit.log"
+)
+
+// Policy is the audit policy of the API servers: the metadata of every
+// request once it is complete, except for health checks.
+const Policy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - RequestReceived
+rules:
+  - level: None
+    nonResourceURLs:
+      - /healthz*
+      - /livez*
+      - /readyz*
+      - /version
+  - level: Metadata
+`
+
+// Patch is the kubeadm patch enabling the audit log of the API servers, with
+// the policy mounted from the control plane nodes (see Mounts) and the log
+// written to them.
+const Patch = `apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+apiServer:
+  extraArgs:
+    audit-policy-file: ` + policyDir + `/policy.yaml
+    audit-log-path: ` + logDir + `/` + logFile + `
+    audit-log-format: json
+  extraVolumes:
+    - name: audit-policy
+      hostPath: ` + policyDir + `
+      mountPath: ` + policyDir + `
+      readOnly: true
+      pathType: DirectoryOrCreate
+    - name: audit-log
+      hostPath: ` + logDir + `
+      mountPath: ` + logDir + `
+      pathType: DirectoryOrCreate
+`
+
+// Mount is a file of the docker host which is mounted into the control plane
+// nodes.
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// Mounts writes the policy to a directory and returns it as the mount of the
+// control plane nodes.
+func Mounts(dir string) ([]Mount, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	policy := filepath.Join(dir, "audit-policy.yaml")
+	if err := ioutil.WriteFile(policy, []byte(Policy), 0644); err != nil {
+		return nil, err
+	}
+	return []Mount{{HostPath: policy, ContainerPath: policyDir + "/policy.yaml"}}, nil
+}
+
+// Event is a complete request of the audit log.
+type Event struct {
+	Time         time.Time
+	User         string
+	Impersonated string
+	Verb         string
+	Resource     string
+	Namespace    string
+	Name         string
+	Code         int
+}
+
+func (e Event) String() string {
+	object := e.Resource
+	if e.Name != "" {
+		object += "/" + e.Name
+	}
+	if e.Namespace != "" {
+		object += " in " + e.Namespace
+	}
+	user := e.User
+	if e.Impersonated != "" {
+		user += " as " + e.Impersonated
+	}
+	return fmt.Sprintf("%s %s %s (%d) at %s", user, e.Verb, object, e.Code, e.Time.Format(time.RFC3339))
+}
+
+type event struct {
+	Stage string `json:"stage"`
+	Verb  string `json:"verb"`
+	User  struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	ImpersonatedUser *struct {
+		Username string `json:"username"`
+	} `json:"impersonatedUser"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Subresource string `json:"subresource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	RequestReceivedTimestamp time.Time `json:"requestReceivedTimestamp"`
+}
+
+// Parse reads the complete requests of an audit log (JSON, one event per
+// line) to resources. Requests to other URLs are skipped.
+func Parse(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("line %d of the audit log is no event: %w", line, err)
+		}
+		if e.Stage != "ResponseComplete" || e.ObjectRef == nil {
+			continue
+		}
+		parsed := Event{
+			Time:      e.RequestReceivedTimestamp,
+			User:      e.User.Username,
+			Verb:      e.Verb,
+			Resource:  e.ObjectRef.Resource,
+			Namespace: e.ObjectRef.Namespace,
+			Name:      e.ObjectRef.Name,
+		}
+		if e.ObjectRef.Subresource != "" {
+			parsed.Resource += "/" + e.ObjectRef.Subresource
+		}
+		if e.ImpersonatedUser != nil {
+			parsed.Impersonated = e.ImpersonatedUser.Username
+		}
+		if e.ResponseStatus != nil {
+			parsed.Code = e.ResponseStatus.Code
+		}
+		events = append(events, parsed)
+	}
+	return events, scanner.Err()
+}
+
+// Read returns the requests of the audit logs of the control plane nodes of a
+// kind cluster, sorted by time.
+func Read(cluster string) ([]Event, error) {
+	out, err := exec.Command("kind", "get", "nodes", "--name", cluster).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not list the nodes of kind cluster %s: %w", cluster, err)
+	}
+	var events []Event
+	for _, node := range strings.Fields(string(out)) {
+		if !strings.Contains(node, "control-plane") {
+			continue
+		}
+		log, err := exec.Command("docker", "exec", node, "cat", logDir+"/"+logFile).Output()
+		if err != nil {
+			return nil, fmt.Errorf("could not read the audit log of node %s: %w", node, err)
+		}
+		parsed, err := Parse(bytes.NewReader(log))
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", node, err)
+		}
+		events = append(events, parsed...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
+
+// Config are the patterns of requests which are forbidden while addons are
+// deployed (see audit.yaml).
+type Config struct {
+	Forbidden []Rule `yaml:"forbidden"`
+
+	ListStorm ListStorm `yaml:"listStorm"`
+}
+
+// Rule is a pattern of forbidden requests. Users are patterns (see
+// filepath.Match), all other lists match any value when empty.
+type Rule struct {
+	Name string `yaml:"name"`
+
+	Users       []string `yaml:"users"`
+	ExceptUsers []string `yaml:"exceptUsers"`
+	Verbs       []string `yaml:"verbs"`
+	Resources   []string `yaml:"resources"`
+	Namespaces  []string `yaml:"namespaces"`
+
+	// Impersonation limits the rule to requests impersonating another user.
+	Impersonation bool `yaml:"impersonation"`
+}
+
+// ListStorm limits the list requests of a user for a resource.
+type ListStorm struct {
+	// Limit is the number of list requests a user may send for a resource
+	// within the window.
+	Limit int `yaml:"limit"`
+
+	Window time.Duration `yaml:"window"`
+
+	// ExceptUsers are the users (patterns) whose lists are not limited.
+	ExceptUsers []string `yaml:"exceptUsers"`
+}
+
+// Load reads the configuration from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse audit configuration %s: %w", path, err)
+	}
+	for _, rule := range c.Forbidden {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("%s: forbidden requests require a name", path)
+		}
+		for _, user := range append(append([]string{}, rule.Users...), rule.ExceptUsers...) {
+			if _, err := filepath.Match(user, ""); err != nil {
+				return nil, fmt.Errorf("%s: user %q of %s is not a pattern", path, user, rule.Name)
+			}
+		}
+	}
+	if c.ListStorm.Limit <= 0 || c.ListStorm.Window <= 0 {
+		return nil, fmt.Errorf("%s: list storms require a positive limit and window", path)
+	}
+	return c, nil
+}
+
+func (r Rule) matches(e Event) bool {
+	return matchesUser(r.Users, e.User, true) && !matchesUser(r.ExceptUsers, e.User, false) &&
+		matches(r.Verbs, e.Verb) && matches(r.Resources, e.Resource) && matches(r.Namespaces, e.Namespace) &&
+		(!r.Impersonation || e.Impersonated != "")
+}
+
+func matches(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesUser(patterns []string, user string, empty bool) bool {
+	if len(patterns) == 0 {
+		return empty
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, user); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Analyze returns the violations of the requests between from and to: the
+// requests matching a forbidden pattern and the users which listed a resource
+// more often than the limit of list storms within a window.
+func (c *Config) Analyze(events []Event, from, to time.Time) []string {
+	var violations []string
+	lists := make(map[string][]time.Time)
+	for _, e := range events {
+		if e.Time.Before(from) || e.Time.After(to) {
+			continue
+		}
+		for _, rule := range c.Forbidden {
+			if rule.matches(e) {
+				violations = append(violations, fmt.Sprintf("forbidden request (%s): %s", rule.Name, e))
+			}
+		}
+		if e.Verb == "list" && !matchesUser(c.ListStorm.ExceptUsers, e.User, false) {
+			key := e.User + " listed " + e.Resource
+			lists[key] = append(lists[key], e.Time)
+		}
+	}
+
+	keys := make([]string, 0, len(lists))
+	for key := range lists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		times := lists[key]
+		sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+		most, start := 0, 0
+		for end := range times {
+			for times[end].Sub(times[start]) >= c.ListStorm.Window {
+				start++
+			}
+			if n := end - start + 1; n > most {
+				most = n
+			}
+		}
+		if most > c.ListStorm.Limit {
+			violations = append(violations, fmt.Sprintf("list storm: %s %d times within %s, more than %d", key, most, c.ListStorm.Window, c.ListStorm.Limit))
+		}
+	}
+	return violations
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMounts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	mounts, err := Mounts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mounts) != 1 || !strings.HasPrefix(mounts[0].HostPath, dir) || mounts[0].ContainerPath != policyDir+"/policy.yaml" {
+		t.Fatalf("expected the policy to be mounted, got %+v", mounts)
+	}
+	if b, err := ioutil.ReadFile(mounts[0].HostPath); err != nil || string(b) != Policy {
+		t.Errorf("expected the policy to be written, got %q, %v", b, err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	log := `{"stage":"ResponseStarted","verb":"watch","user":{"username":"system:kube-scheduler"},"objectRef":{"resource":"pods"},"requestReceivedTimestamp":"2020-03-01T10:00:00.000000Z"}
+{"stage":"ResponseComplete","verb":"get","user":{"username":"system:anonymous"},"requestURI":"/healthz","responseStatus":{"code":200},"requestReceivedTimestamp":"2020-03-01T10:00:00.000000Z"}
+
+{"stage":"ResponseComplete","verb":"create","user":{"username":"system:serviceaccount:kubeaddons:traefik"},"impersonatedUser":{"username":"admin"},"objectRef":{"resource":"secrets","namespace":"kube-system","name":"token"},"responseStatus":{"code":201},"requestReceivedTimestamp":"2020-03-01T10:00:01.500000Z"}
+{"stage":"ResponseComplete","verb":"patch","user":{"username":"system:serviceaccount:kommander:kommander"},"objectRef":{"resource":"deployments","subresource":"scale","namespace":"kommander","name":"ui"},"responseStatus":{"code":200},"requestReceivedTimestamp":"2020-03-01T10:00:02.000000Z"}
+`
+	events, err := Parse(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{Time: time.Date(2020, 3, 1, 10, 0, 1, 500000000, time.UTC), User: "system:serviceaccount:kubeaddons:traefik", Impersonated: "admin", Verb: "create", Resource: "secrets", Namespace: "kube-system", Name: "token", Code: 201},
+		{Time: time.Date(2020, 3, 1, 10, 0, 2, 0, time.UTC), User: "system:serviceaccount:kommander:kommander", Verb: "patch", Resource: "deployments/scale", Namespace: "kommander", Name: "ui", Code: 200},
+	}
+	if !reflect.DeepEqual(expected, events) {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+	if expected := "system:serviceaccount:kubeaddons:traefik as admin create secrets/token in kube-system (201) at 2020-03-01T10:00:01Z"; events[0].String() != expected {
+		t.Errorf("expected %q, got %q", expected, events[0])
+	}
+
+	if _, err := Parse(strings.NewReader("{}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected the invalid line to be reported, got %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for content, valid := range map[string]bool{
+		"forbidden:\n- name: impersonation\n  impersonation: true\nlistStorm:\n  limit: 100\n  window: 1m\n": true,
+		"forbidden:\n- impersonation: true\nlistStorm:\n  limit: 100\n  window: 1m\n":                        false,
+		"forbidden:\n- name: secrets\n  users: [\"[\"]\nlistStorm:\n  limit: 100\n  window: 1m\n":            false,
+		"listStorm:\n  limit: 100\n": false,
+	} {
+		path := filepath.Join(dir, "audit.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); (err == nil) != valid {
+			t.Errorf("expected %q to be valid: %t, got %v", content, valid, err)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	c := &Config{
+		Forbidden: []Rule{
+			{Name: "addons write secrets of kube-system", Users: []string{"system:serviceaccount:*"}, ExceptUsers: []string{"system:serviceaccount:kube-system:*"}, Verbs: []string{"create", "update", "patch", "delete"}, Resources: []string{"secrets"}, Namespaces: []string{"kube-system"}},
+			{Name: "impersonation", Impersonation: true},
+		},
+		ListStorm: ListStorm{Limit: 3, Window: time.Minute, ExceptUsers: []string{"system:kube-*"}},
+	}
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	events := []Event{
+		{Time: at(-10), User: "system:serviceaccount:kubeaddons:traefik", Verb: "create", Resource: "secrets", Namespace: "kube-system"},
+		{Time: at(1), User: "system:serviceaccount:kubeaddons:traefik", Verb: "create", Resource: "secrets", Namespace: "kube-system", Name: "token"},
+		{Time: at(2), User: "system:serviceaccount:kube-system:bootstrap-signer", Verb: "update", Resource: "secrets", Namespace: "kube-system"},
+		{Time: at(3), User: "system:serviceaccount:kubeaddons:dex", Verb: "get", Resource: "secrets", Namespace: "kube-system"},
+		{Time: at(4), User: "admin", Impersonated: "system:serviceaccount:kommander:ui", Verb: "get", Resource: "pods", Namespace: "kommander"},
+	}
+	for i := 0; i < 4; i++ {
+		// 4 lists within 45s, but only 3 within a minute of each other for
+		// configmaps listed 30s apart
+		events = append(events,
+			Event{Time: at(10 + 15*i), User: "system:serviceaccount:kommander:kommander", Verb: "list", Resource: "secrets"},
+			Event{Time: at(10 + 30*i), User: "system:serviceaccount:kommander:kommander", Verb: "list", Resource: "configmaps"},
+			Event{Time: at(10 + i), User: "system:kube-controller-manager", Verb: "list", Resource: "pods"},
+		)
+	}
+
+	violations := c.Analyze(events, start, at(600))
+	expected := []string{
+		fmt.Sprintf("forbidden request (addons write secrets of kube-system): %s", events[1]),
+		fmt.Sprintf("forbidden request (impersonation): %s", events[4]),
+		"list storm: system:serviceaccount:kommander:kommander listed secrets 4 times within 1m0s, more than 3",
+	}
+	if !reflect.DeepEqual(expected, violations) {
+		t.Errorf("expected %q, got %q", expected, violations)
+	}
+	if violations := c.Analyze(events, at(100), at(600)); len(violations) != 0 {
+		t.Errorf("expected no violations outside of the window, got %q", violations)
+	}
+}
@@ -0,0 +1,137 @@
+package availability
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// resources are the resources of the verified kinds, by kind.
+var resources = map[string]string{
+	"HorizontalPodAutoscaler": "horizontalpodautoscalers.v2beta2.autoscaling",
+	"PodDisruptionBudget":     "poddisruptionbudgets.v1beta1.policy",
+}
+
+// Problem is an autoscaler which can't scale its target or a disruption
+// budget which blocks evictions of the pods of an addon.
+type Problem struct {
+	Addon     string
+	Kind      string
+	Namespace string
+	Name      string
+	Reason    string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s %s/%s of addon %s %s", p.Kind, p.Namespace, p.Name, p.Addon, p.Reason)
+}
+
+// Verify verifies the HorizontalPodAutoscalers and PodDisruptionBudgets among
+// the rendered objects of deployed addons against the cluster of a kubectl
+// client: autoscalers must be able to get the scale of their target
+// and compute their metrics, and disruption budgets must select pods and allow
+// at least one of them to be evicted at the deployed replica counts. As
+// autoscalers are only evaluated periodically, problems are retried until the
+// timeout expires.
+func Verify(k kubectl.Client, objects []render.Object, timeout, interval time.Duration) ([]Problem, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var problems []Problem
+		for _, obj := range objects {
+			resource, ok := resources[obj.Kind]
+			if !ok {
+				continue
+			}
+			out, err := k.Output("get", resource, obj.Name, "--namespace", obj.Namespace, "--output", "json")
+			if err != nil {
+				return nil, err
+			}
+			var reason string
+			if obj.Kind == "HorizontalPodAutoscaler" {
+				reason = autoscalerProblem(out)
+			} else {
+				reason = budgetProblem(out)
+			}
+			if reason != "" {
+				problems = append(problems, Problem{Addon: obj.Addon, Kind: obj.Kind, Namespace: obj.Namespace, Name: obj.Name, Reason: reason})
+			}
+		}
+		if len(problems) == 0 || time.Now().Add(interval).After(deadline) {
+			return problems, nil
+		}
+		time.Sleep(interval)
+	}
+}
+
+type autoscaler struct {
+	Spec struct {
+		ScaleTargetRef struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"scaleTargetRef"`
+	} `json:"spec"`
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// autoscalerProblem returns the problem of an autoscaler, if any: its
+// AbleToScale condition is false when its target does not exist and its
+// ScalingActive condition when its metrics are not available.
+func autoscalerProblem(out []byte) string {
+	var a autoscaler
+	if err := json.Unmarshal(out, &a); err != nil {
+		return fmt.Sprintf("could not be decoded: %s", err)
+	}
+	target := strings.ToLower(a.Spec.ScaleTargetRef.Kind) + "/" + a.Spec.ScaleTargetRef.Name
+	if len(a.Status.Conditions) == 0 {
+		return fmt.Sprintf("has not been evaluated for %s yet", target)
+	}
+	for _, c := range a.Status.Conditions {
+		if c.Status != "False" {
+			continue
+		}
+		switch c.Type {
+		case "AbleToScale":
+			return fmt.Sprintf("can't scale %s (%s): %s", target, c.Reason, c.Message)
+		case "ScalingActive":
+			return fmt.Sprintf("can't compute the metrics of %s (%s): %s", target, c.Reason, c.Message)
+		}
+	}
+	return ""
+}
+
+type budget struct {
+	Status struct {
+		ExpectedPods       int `json:"expectedPods"`
+		CurrentHealthy     int `json:"currentHealthy"`
+		DesiredHealthy     int `json:"desiredHealthy"`
+		DisruptionsAllowed int `json:"disruptionsAllowed"`
+	} `json:"status"`
+}
+
+// budgetProblem returns the problem of a disruption budget, if any: it must
+// select pods and allow at least one of them to be evicted, otherwise it
+// blocks draining the nodes they run on.
+func budgetProblem(out []byte) string {
+	var b budget
+	if err := json.Unmarshal(out, &b); err != nil {
+		return fmt.Sprintf("could not be decoded: %s", err)
+	}
+	switch {
+	case b.Status.ExpectedPods == 0:
+		return "selects no pods"
+	case b.Status.DisruptionsAllowed < 1:
+		return fmt.Sprintf("allows no disruption with %d of %d pods healthy and %d desired healthy", b.Status.CurrentHealthy, b.Status.ExpectedPods, b.Status.DesiredHealthy)
+	}
+	return ""
+}
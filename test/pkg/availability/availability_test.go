@@ -0,0 +1,45 @@
+package availability
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAutoscalerProblem(t *testing.T) {
+	for _, test := range []struct {
+		status   string
+		expected string
+	}{
+		{status: `{}`, expected: "has not been evaluated for deployment/traefik yet"},
+		{status: `{"conditions": [{"type": "AbleToScale", "status": "True"}, {"type": "ScalingActive", "status": "True"}]}`},
+		{
+			status:   `{"conditions": [{"type": "AbleToScale", "status": "False", "reason": "FailedGetScale", "message": "deployments/scale.apps \"traefik\" not found"}]}`,
+			expected: `can't scale deployment/traefik (FailedGetScale): deployments/scale.apps "traefik" not found`,
+		},
+		{
+			status:   `{"conditions": [{"type": "AbleToScale", "status": "True"}, {"type": "ScalingActive", "status": "False", "reason": "FailedGetResourceMetric", "message": "unable to get metrics for resource cpu"}]}`,
+			expected: "can't compute the metrics of deployment/traefik (FailedGetResourceMetric): unable to get metrics for resource cpu",
+		},
+	} {
+		out := `{"spec": {"scaleTargetRef": {"kind": "Deployment", "name": "traefik"}}, "status": ` + test.status + `}`
+		if actual := autoscalerProblem([]byte(out)); actual != test.expected {
+			t.Errorf("expected %q for status %s, got %q", test.expected, test.status, actual)
+		}
+	}
+}
+
+func TestBudgetProblem(t *testing.T) {
+	for _, test := range []struct {
+		status   string
+		expected string
+	}{
+		{status: `{"expectedPods": 2, "currentHealthy": 2, "desiredHealthy": 1, "disruptionsAllowed": 1}`},
+		{status: `{"expectedPods": 0, "currentHealthy": 0, "desiredHealthy": 1, "disruptionsAllowed": 0}`, expected: "selects no pods"},
+		{status: `{"expectedPods": 1, "currentHealthy": 1, "desiredHealthy": 1, "disruptionsAllowed": 0}`, expected: "allows no disruption"},
+	} {
+		actual := budgetProblem([]byte(`{"status": ` + test.status + `}`))
+		if (test.expected == "") != (actual == "") || !strings.HasPrefix(actual, test.expected) {
+			t.Errorf("expected %q for status %s, got %q", test.expected, test.status, actual)
+		}
+	}
+}
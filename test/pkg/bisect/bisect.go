@@ -0,0 +1,95 @@
+package bisect
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// FirstBad performs a binary search over n candidates which are ordered from
+// oldest to newest and returns the index of the first candidate for which test
+// reports a failure. Candidates are assumed to pass up to some point and fail
+// from then on. The newest candidate is tested first, if it passes there is no
+// regression within the candidates and an error is returned.
+func FirstBad(n int, test func(i int) (bool, error)) (int, error) {
+	if n < 1 {
+		return -1, fmt.Errorf("there are no candidates to bisect")
+	}
+
+	good, err := test(n - 1)
+	if err != nil {
+		return -1, err
+	}
+	if good {
+		return -1, fmt.Errorf("the newest candidate passes, there is no regression to bisect")
+	}
+
+	lo, hi := 0, n-1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		good, err := test(mid)
+		if err != nil {
+			return -1, err
+		}
+		if good {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	return hi, nil
+}
+
+// Candidates lists the commits of the git repository at dir after good and up
+// to and including bad which modified any of the given paths, ordered from
+// oldest to newest.
+func Candidates(dir, good, bad string, paths ...string) ([]string, error) {
+	args := append([]string{"rev-list", "--reverse", "--ancestry-path", fmt.Sprintf("%s..%s", good, bad), "--"}, paths...)
+	out, err := git(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// Diff returns the changes to the given paths introduced by a commit of the git
+// repository at dir.
+func Diff(dir, commit string, paths ...string) ([]byte, error) {
+	args := append([]string{"show", commit, "--"}, paths...)
+	return git(dir, args...)
+}
+
+// Checkout creates a detached worktree of the git repository at dir for the
+// given commit in path. The returned function removes the worktree again.
+func Checkout(dir, commit, path string) (func() error, error) {
+	if _, err := git(dir, "worktree", "add", "--detach", path, commit); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := git(dir, "worktree", "remove", "--force", path)
+		return err
+	}, nil
+}
+
+// Clone creates a clone of the git repository at url in path.
+func Clone(url, path string) error {
+	_, err := git("", "clone", "--quiet", url, path)
+	return err
+}
+
+func git(dir string, args ...string) ([]byte, error) {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	stdout := new(bytes.Buffer)
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,38 @@
+package bisect
+
+import "testing"
+
+func TestFirstBad(t *testing.T) {
+	for _, tc := range []struct {
+		candidates int
+		firstBad   int
+	}{
+		{candidates: 1, firstBad: 0},
+		{candidates: 2, firstBad: 0},
+		{candidates: 2, firstBad: 1},
+		{candidates: 7, firstBad: 3},
+		{candidates: 8, firstBad: 7},
+		{candidates: 16, firstBad: 0},
+	} {
+		tested := 0
+		actual, err := FirstBad(tc.candidates, func(i int) (bool, error) {
+			tested++
+			return i < tc.firstBad, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != tc.firstBad {
+			t.Errorf("expected first bad candidate %d of %d, got %d", tc.firstBad, tc.candidates, actual)
+		}
+		if tested > tc.candidates {
+			t.Errorf("tested %d times for %d candidates", tested, tc.candidates)
+		}
+	}
+}
+
+func TestFirstBadWithoutRegression(t *testing.T) {
+	if _, err := FirstBad(4, func(i int) (bool, error) { return true, nil }); err == nil {
+		t.Fatal("expected an error when the newest candidate passes")
+	}
+}
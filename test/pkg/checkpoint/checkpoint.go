@@ -0,0 +1,131 @@
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Stage is a stage of a scenario which is not repeated when a run is resumed.
+type Stage string
+
+const (
+	// Provisioned is the stage in which the cluster was created and the
+	// kubeaddons controller deployed.
+	Provisioned Stage = "provisioned"
+
+	// Deployed is the stage in which the addons were deployed and verified.
+	Deployed Stage = "deployed"
+
+	// Seeded is the stage in which the seeds were applied.
+	Seeded Stage = "seeded"
+)
+
+// stages are the stages in the order they are completed.
+var stages = []Stage{Provisioned, Deployed, Seeded}
+
+// Checkpoint is the progress of a scenario in a run, from which a later run
+// resumes it against the retained cluster.
+type Checkpoint struct {
+	Run      string `json:"run"`
+	Scenario string `json:"scenario"`
+
+	// Provider is the cluster provider and Cluster the name of the cluster.
+	Provider string `json:"provider,omitempty"`
+	Cluster  string `json:"cluster,omitempty"`
+
+	// Completed is the last stage which was completed.
+	Completed Stage     `json:"completed,omitempty"`
+	Updated   time.Time `json:"updated"`
+
+	dir string
+}
+
+// Load reads the checkpoint of a scenario in a run from dir, and returns a new
+// checkpoint when there is none yet.
+func Load(dir, run, scenario string) (*Checkpoint, error) {
+	c := &Checkpoint{Run: run, Scenario: scenario, dir: filepath.Join(dir, run)}
+	b, err := ioutil.ReadFile(c.path())
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("could not decode checkpoint %s: %w", c.path(), err)
+	}
+	if c.Run != run || c.Scenario != scenario {
+		return nil, fmt.Errorf("checkpoint %s is of scenario %s in run %s", c.path(), c.Scenario, c.Run)
+	}
+	return c, nil
+}
+
+func (c *Checkpoint) path() string {
+	return filepath.Join(c.dir, c.Scenario+".json")
+}
+
+// Kubeconfig returns the path of the kubeconfig file of the cluster, which is
+// kept along with the checkpoint.
+func (c *Checkpoint) Kubeconfig() string {
+	return filepath.Join(c.dir, c.Scenario+".kubeconfig")
+}
+
+// Done returns whether a stage was completed. It is false for every stage of
+// a nil checkpoint, i.e. when checkpointing is disabled.
+func (c *Checkpoint) Done(stage Stage) bool {
+	if c == nil {
+		return false
+	}
+	return index(stage) <= index(c.Completed)
+}
+
+func index(stage Stage) int {
+	for i, s := range stages {
+		if s == stage {
+			return i
+		}
+	}
+	return -1
+}
+
+// Complete records that a stage was completed. It does nothing for a nil
+// checkpoint.
+func (c *Checkpoint) Complete(stage Stage, now time.Time) error {
+	if c == nil {
+		return nil
+	}
+	c.Completed = stage
+	c.Updated = now
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(c.path(), b, 0644); err != nil {
+		return fmt.Errorf("could not write checkpoint of scenario %s: %w", c.Scenario, err)
+	}
+	return nil
+}
+
+// Remove removes the checkpoint and the kubeconfig of the cluster, once the
+// scenario passed and its cluster was deleted.
+func (c *Checkpoint) Remove() error {
+	if c == nil {
+		return nil
+	}
+	for _, path := range []string{c.path(), c.Kubeconfig()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// the directory of the run is removed with its last checkpoint
+	_ = os.Remove(c.dir)
+	return nil
+}
@@ -0,0 +1,55 @@
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var disabled *Checkpoint
+	if disabled.Done(Provisioned) || disabled.Complete(Provisioned, time.Now()) != nil {
+		t.Error("expected a nil checkpoint to have no completed stages")
+	}
+
+	c, err := Load(dir, "20200301-120000-42", "kommander-full-ha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Done(Provisioned) {
+		t.Error("expected a new checkpoint to have no completed stages")
+	}
+	c.Provider, c.Cluster = "kind", "kommander-full-ha-1"
+	if err := c.Complete(Deployed, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := Load(dir, "20200301-120000-42", "kommander-full-ha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resumed.Done(Provisioned) || !resumed.Done(Deployed) || resumed.Done(Seeded) {
+		t.Errorf("expected the stages up to deployed to be completed, got %s", resumed.Completed)
+	}
+	if resumed.Cluster != "kommander-full-ha-1" {
+		t.Errorf("expected the cluster to be recorded, got %q", resumed.Cluster)
+	}
+	if resumed.Kubeconfig() != filepath.Join(dir, "20200301-120000-42", "kommander-full-ha.kubeconfig") {
+		t.Errorf("expected the kubeconfig next to the checkpoint, got %s", resumed.Kubeconfig())
+	}
+
+	if err := resumed.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "20200301-120000-42")); !os.IsNotExist(err) {
+		t.Errorf("expected the checkpoints of the run to be removed, got %v", err)
+	}
+}
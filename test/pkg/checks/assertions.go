@@ -0,0 +1,173 @@
+package checks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/events"
+)
+
+// rolloutKinds can be checked for readiness with `kubectl rollout status`, all
+// other kinds are expected to have a Ready condition.
+var rolloutKinds = map[string]bool{
+	"deployment":  true,
+	"daemonset":   true,
+	"statefulset": true,
+}
+
+// Resource asserts the state of a resource, e.g. "deployment/grafana ready".
+// The supported states are "exists" and "ready".
+type Resource struct {
+	namespace string
+	kind      string
+	name      string
+	state     string
+}
+
+func parseResource(namespace, s string) (Resource, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Resource{}, fmt.Errorf("resource assertion %q must be of the form <kind>/<name> <exists|ready>", s)
+	}
+
+	ref := strings.SplitN(fields[0], "/", 2)
+	if len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+		return Resource{}, fmt.Errorf("resource %q must be of the form <kind>/<name>", fields[0])
+	}
+
+	r := Resource{namespace: namespace, kind: strings.ToLower(ref[0]), name: ref[1], state: fields[1]}
+	if r.state != "exists" && r.state != "ready" {
+		return Resource{}, fmt.Errorf("unknown resource state %q, expected exists or ready", r.state)
+	}
+
+	return r, nil
+}
+
+func (r Resource) Assert(env *Env) error {
+	ref := r.kind + "/" + r.name
+	args := namespaced(r.namespace)
+
+	switch {
+	case r.state == "exists":
+		_, err := env.Kubectl.Output(append(args, "get", ref)...)
+		return err
+	case rolloutKinds[r.kind]:
+		_, err := env.Kubectl.Output(append(args, "rollout", "status", ref, "--timeout", "1s")...)
+		return err
+	default:
+		_, err := env.Kubectl.Output(append(args, "wait", ref, "--for", "condition=Ready", "--timeout", "1s")...)
+		return err
+	}
+}
+
+// HTTPGet asserts the status code returned for a GET request, either to a URL
+// or to the path of a cluster service (proxied by the API server). Services are
+// referenced as [<scheme>:]<name>[:<port>], e.g. "https:kommander-kubeaddons-grafana:443".
+type HTTPGet struct {
+	URL     string `yaml:"url"`
+	Service string `yaml:"service"`
+	Path    string `yaml:"path"`
+
+	namespace    string
+	expectStatus int
+}
+
+func (h HTTPGet) Assert(env *Env) error {
+	target := h.URL
+	if h.Service != "" {
+		target = env.Proxy.ServiceURL(h.namespace, h.Service, h.Path)
+	}
+	if target == "" {
+		return fmt.Errorf("httpGet requires either a url or a service")
+	}
+
+	resp, err := http.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != h.expectStatus {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("GET %s returned status %d, expected %d: %s", target, resp.StatusCode, h.expectStatus, body)
+	}
+
+	return nil
+}
+
+// defaultPrometheus is the prometheus service PromQL assertions are evaluated
+// against unless a check specifies another one as <namespace>/<service>.
+const defaultPrometheus = "kubeaddons/http:prometheus-kubeaddons-prom-prometheus:9090"
+
+// PromQL asserts that a PromQL expression returns a non-empty result, e.g.
+// `up{job="kommander"} == 1` passes when any such target is up.
+type PromQL struct {
+	query     string
+	namespace string
+	service   string
+}
+
+func newPromQL(query, prometheus string) (PromQL, error) {
+	if prometheus == "" {
+		prometheus = defaultPrometheus
+	}
+	ref := strings.SplitN(prometheus, "/", 2)
+	if len(ref) != 2 {
+		return PromQL{}, fmt.Errorf("prometheus %q must be of the form <namespace>/<service>", prometheus)
+	}
+	return PromQL{query: query, namespace: ref[0], service: ref[1]}, nil
+}
+
+func (p PromQL) Assert(env *Env) error {
+	target := env.Proxy.ServiceURL(p.namespace, p.service, "/api/v1/query") + "?query=" + url.QueryEscape(p.query)
+	resp, err := http.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	result := struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []json.RawMessage `json:"result"`
+		} `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("could not decode response to query %q: %w", p.query, err)
+	}
+	if result.Status != "success" {
+		return fmt.Errorf("query %q failed: %s", p.query, result.Error)
+	}
+	if len(result.Data.Result) == 0 {
+		return fmt.Errorf("query %q returned no results", p.query)
+	}
+
+	return nil
+}
+
+// Events asserts that there are no events with the given reasons (all
+// reasons when empty) about objects of a kind whose name starts with a prefix,
+// e.g. no FailedScheduling events about pods named "kommander-".
+type Events struct {
+	Reasons []string `yaml:"reasons"`
+	Kind    string   `yaml:"kind"`
+	Name    string   `yaml:"name"`
+
+	namespace string
+}
+
+func (e Events) Assert(env *Env) error {
+	return events.Absent(env.Kubectl, events.Query{Namespace: e.namespace, Kind: e.Kind, Name: e.Name, Reasons: e.Reasons})
+}
+
+func namespaced(namespace string) []string {
+	if namespace == "" {
+		return nil
+	}
+	return []string{"--namespace", namespace}
+}
@@ -0,0 +1,49 @@
+package checks
+
+import "testing"
+
+func TestParseResource(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected Resource
+		invalid  bool
+	}{
+		{in: "deployment/grafana ready", expected: Resource{namespace: "kommander", kind: "deployment", name: "grafana", state: "ready"}},
+		{in: "Secret/kommander-tls exists", expected: Resource{namespace: "kommander", kind: "secret", name: "kommander-tls", state: "exists"}},
+		{in: "deployment/grafana", invalid: true},
+		{in: "grafana ready", invalid: true},
+		{in: "deployment/ ready", invalid: true},
+		{in: "deployment/grafana running", invalid: true},
+	} {
+		actual, err := parseResource("kommander", tc.in)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("expected %q to be invalid", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tc.in, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("expected %q to parse as %+v, got %+v", tc.in, tc.expected, actual)
+		}
+	}
+}
+
+func TestParseRestart(t *testing.T) {
+	restart, err := parseRestart("kubeaddons", "StatefulSet/elasticsearch-kubeaddons-master")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Restart{namespace: "kubeaddons", kind: "statefulset", name: "elasticsearch-kubeaddons-master"}); *restart != expected {
+		t.Errorf("expected %+v, got %+v", expected, *restart)
+	}
+
+	for _, invalid := range []string{"statefulset", "statefulset/", "service/elasticsearch-kubeaddons-client"} {
+		if _, err := parseRestart("kubeaddons", invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
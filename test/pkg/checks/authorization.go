@@ -0,0 +1,242 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Login is the expectation of a request which is redirected to dex to log in.
+const Login = "login"
+
+// maxHops is the maximum number of redirects and login forms of a request.
+const maxHops = 10
+
+var (
+	formAction     = regexp.MustCompile(`<form[^>]*action="([^"]*)"`)
+	passwordField  = regexp.MustCompile(`<input[^>]*name="password"`)
+	localConnector = regexp.MustCompile(`href="([^"]*/auth/local[^"]*)"`)
+)
+
+// Authorization asserts the responses to requests by users for routes served
+// by the ingress controller behind traefik-forward-auth: a matrix of users,
+// routes and the expected status, or "login" for a redirect to dex. Users log
+// in to dex with the passwords of its password database, which are those of
+// the test identities unless given, the requests of cases without a user are
+// unauthenticated. Requests are sent to the port of
+// the ingress controller service through `kubectl port-forward`, along with
+// the requests to dex and to the callback of traefik-forward-auth which are
+// served by the same ingress controller.
+type Authorization struct {
+	// Ingress is the service of the ingress controller as <name>:<port>,
+	// e.g. traefik-kubeaddons:443.
+	Ingress string `yaml:"ingress"`
+
+	// Host is the host of the requests (default: localhost).
+	Host string `yaml:"host"`
+
+	// Users are the passwords of users of the matrix which aren't test
+	// identities (see identities.yaml).
+	Users map[string]string `yaml:"users"`
+
+	Matrix []AuthorizationCase `yaml:"matrix"`
+
+	namespace string
+}
+
+// AuthorizationCase is a request of a user (none for unauthenticated requests)
+// for a route, and its expected status or "login".
+type AuthorizationCase struct {
+	User   string `yaml:"user"`
+	Route  string `yaml:"route"`
+	Expect string `yaml:"expect"`
+}
+
+func (a Authorization) validate() error {
+	if _, _, err := a.ingress(); err != nil {
+		return err
+	}
+	if len(a.Matrix) == 0 {
+		return fmt.Errorf("authorization requires a matrix")
+	}
+	for _, c := range a.Matrix {
+		if _, err := strconv.Atoi(c.Expect); err != nil && c.Expect != Login {
+			return fmt.Errorf("authorization of %s for %s expects %q, expected a status or %s", c.User, c.Route, c.Expect, Login)
+		}
+	}
+	return nil
+}
+
+func (a Authorization) ingress() (string, int, error) {
+	return parseIngress(a.Ingress)
+}
+
+// parseIngress parses the service of an ingress controller, <name>:<port>.
+func parseIngress(ingress string) (string, int, error) {
+	ref := strings.SplitN(ingress, ":", 2)
+	if len(ref) != 2 {
+		return "", 0, fmt.Errorf("ingress %q must be of the form <service>:<port>", ingress)
+	}
+	port, err := strconv.Atoi(ref[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("ingress %q has an invalid port: %w", ingress, err)
+	}
+	return ref[0], port, nil
+}
+
+func (a Authorization) Assert(env *Env) error {
+	service, port, err := a.ingress()
+	if err != nil {
+		return err
+	}
+	passwords, err := a.passwords(env)
+	if err != nil {
+		return err
+	}
+	forward, err := env.Kubectl.StartPortForward(a.namespace, "service/"+service, port)
+	if err != nil {
+		return err
+	}
+	defer forward.Stop()
+
+	return a.assertVia(forward.Addr, passwords)
+}
+
+// passwords returns the passwords of the users of the matrix, those of the
+// test identities of env unless the check gives them.
+func (a Authorization) passwords(env *Env) (map[string]string, error) {
+	passwords := make(map[string]string)
+	for _, c := range a.Matrix {
+		if c.User == "" {
+			continue
+		}
+		if password, ok := a.Users[c.User]; ok {
+			passwords[c.User] = password
+			continue
+		}
+		if env.Identities != nil {
+			if password, ok := env.Identities.Password(c.User); ok {
+				passwords[c.User] = password
+				continue
+			}
+		}
+		return nil, fmt.Errorf("authorization matrix references user %s, which is neither a test identity nor has a password", c.User)
+	}
+	return passwords, nil
+}
+
+// assertVia asserts the matrix with all connections made to addr, logging in
+// with the passwords of the users.
+func (a Authorization) assertVia(addr string, passwords map[string]string) error {
+	host := a.Host
+	if host == "" {
+		host = "localhost"
+	}
+
+	var failures []string
+	for _, c := range a.Matrix {
+		actual, err := request(addr, "https://"+host+c.Route, c.User, passwords[c.User])
+		if err != nil {
+			return err
+		}
+		if actual != c.Expect {
+			user := c.User
+			if user == "" {
+				user = "unauthenticated user"
+			}
+			failures = append(failures, fmt.Sprintf("%s got %s for %s, expected %s", user, actual, c.Route, c.Expect))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("authorization matrix failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// request requests a URL as a user, logging in to dex with the password when
+// its login form is shown, and returns the final status or "login" for
+// unauthenticated requests which are redirected to dex.
+func request(addr, target, user, password string) (string, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return "", err
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	client := &http.Client{
+		Jar:     jar,
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			// the ingress controller serves a self-signed certificate
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "", err
+	}
+	for hop := 0; hop < maxHops; hop++ {
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		var next *url.URL
+		switch {
+		case resp.StatusCode >= 300 && resp.StatusCode < 400:
+			if next, err = resp.Location(); err != nil {
+				return "", err
+			}
+			if user == "" && strings.Contains(next.Path, "/dex/") {
+				return Login, nil
+			}
+		case resp.StatusCode == http.StatusOK && user != "" && passwordField.Match(body):
+			action := req.URL
+			if m := formAction.FindSubmatch(body); m != nil {
+				if action, err = req.URL.Parse(html.UnescapeString(string(m[1]))); err != nil {
+					return "", err
+				}
+			}
+			form := url.Values{"login": {user}, "password": {password}}
+			if req, err = http.NewRequest(http.MethodPost, action.String(), strings.NewReader(form.Encode())); err != nil {
+				return "", err
+			}
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			continue
+		case resp.StatusCode == http.StatusOK && localConnector.Match(body):
+			// dex lists its connectors, choose the password database
+			m := localConnector.FindSubmatch(body)
+			if next, err = req.URL.Parse(html.UnescapeString(string(m[1]))); err != nil {
+				return "", err
+			}
+		default:
+			return strconv.Itoa(resp.StatusCode), nil
+		}
+
+		if req, err = http.NewRequest(http.MethodGet, next.String(), nil); err != nil {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("GET %s as %s was redirected more than %d times", target, user, maxHops)
+}
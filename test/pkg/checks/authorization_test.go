@@ -0,0 +1,105 @@
+package checks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/identities"
+)
+
+// forwardAuth emulates traefik-forward-auth in front of a route which only
+// admin@example.com is authorized for, and dex with a password database.
+func forwardAuth(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/dex/auth":
+		fmt.Fprint(w, `<a href="/dex/auth/local?req=abc&amp;state=`+url.QueryEscape(r.URL.Query().Get("state"))+`">Log in with Email</a>`)
+	case r.URL.Path == "/dex/auth/local" && r.Method == http.MethodGet:
+		fmt.Fprint(w, `<form method="post" action="/dex/auth/local?req=abc&amp;state=`+url.QueryEscape(r.URL.Query().Get("state"))+`"><input type="password" name="password"></form>`)
+	case r.URL.Path == "/dex/auth/local":
+		if r.PostFormValue("password") != "secret-"+r.PostFormValue("login") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "https://kommander.example/_oauth?code="+url.QueryEscape(r.PostFormValue("login"))+"&state="+url.QueryEscape(r.URL.Query().Get("state")), http.StatusSeeOther)
+	case r.URL.Path == "/_oauth":
+		http.SetCookie(w, &http.Cookie{Name: "_forward_auth", Value: r.URL.Query().Get("code"), Path: "/"})
+		http.Redirect(w, r, r.URL.Query().Get("state"), http.StatusTemporaryRedirect)
+	default:
+		cookie, err := r.Cookie("_forward_auth")
+		switch {
+		case err != nil:
+			http.Redirect(w, r, "https://dex.example/dex/auth?state="+url.QueryEscape(r.URL.Path), http.StatusTemporaryRedirect)
+		case cookie.Value != "admin@example.com":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(forwardAuth))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	a := Authorization{
+		Ingress: "traefik-kubeaddons:443",
+		Host:    "kommander.example",
+		Matrix: []AuthorizationCase{
+			{Route: "/ops/portal/", Expect: Login},
+			{User: "viewer@example.com", Route: "/ops/portal/", Expect: "403"},
+			{User: "admin@example.com", Route: "/ops/portal/", Expect: "200"},
+		},
+	}
+	if err := a.validate(); err != nil {
+		t.Fatal(err)
+	}
+	passwords := map[string]string{"admin@example.com": "secret-admin@example.com", "viewer@example.com": "secret-viewer@example.com"}
+	if err := a.assertVia(addr, passwords); err != nil {
+		t.Error(err)
+	}
+
+	a.Matrix[1].Expect = "200"
+	err := a.assertVia(addr, passwords)
+	if err == nil || !strings.Contains(err.Error(), "viewer@example.com got 403 for /ops/portal/, expected 200") {
+		t.Errorf("expected the viewer to be forbidden, got %v", err)
+	}
+}
+
+func TestAuthorizationPasswords(t *testing.T) {
+	a := Authorization{
+		Users: map[string]string{"admin@example.com": "given"},
+		Matrix: []AuthorizationCase{
+			{Route: "/", Expect: Login},
+			{User: "admin@example.com", Route: "/", Expect: "200"},
+			{User: "viewer@example.com", Route: "/", Expect: "403"},
+		},
+	}
+	env := &Env{Identities: &identities.Fixture{Users: []identities.User{
+		{Email: "admin@example.com", Password: "identity"},
+		{Email: "viewer@example.com", Password: "identity"},
+	}}}
+	passwords, err := a.passwords(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"admin@example.com": "given", "viewer@example.com": "identity"}
+	if !reflect.DeepEqual(expected, passwords) {
+		t.Errorf("expected passwords %v, got %v", expected, passwords)
+	}
+
+	a.Matrix = append(a.Matrix, AuthorizationCase{User: "nobody@example.com", Route: "/", Expect: "200"})
+	if _, err := a.passwords(env); err == nil {
+		t.Error("expected an error for a user without a password")
+	}
+}
+
+func TestValidateAuthorization(t *testing.T) {
+	a := Authorization{Ingress: "traefik-kubeaddons", Matrix: []AuthorizationCase{{Route: "/", Expect: Login}}}
+	if err := a.validate(); err == nil {
+		t.Error("expected an error for an ingress without a port")
+	}
+}
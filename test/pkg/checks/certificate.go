@@ -0,0 +1,200 @@
+package checks
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+const (
+	defaultCertificateAPIVersion = "certmanager.k8s.io/v1alpha1"
+	defaultCertificateName       = "issuance-check"
+	defaultIssuerKind            = "ClusterIssuer"
+)
+
+// Certificate asserts that an issuer of cert-manager issues certificates: it
+// creates a Certificate for the DNS names, waits for it to become ready and
+// verifies the chain and DNS names of the certificate in its secret. The
+// Certificate and its secret are deleted once the check passes.
+type Certificate struct {
+	// Issuer is the name of the issuer, Kind its kind (default:
+	// ClusterIssuer).
+	Issuer string `yaml:"issuer"`
+	Kind   string `yaml:"kind"`
+
+	// DNSNames are the DNS names of the certificate.
+	DNSNames []string `yaml:"dnsNames"`
+
+	// Name is the name of the Certificate and its secret (default:
+	// issuance-check).
+	Name string `yaml:"name"`
+
+	// APIVersion is the API version of the Certificate (default:
+	// certmanager.k8s.io/v1alpha1).
+	APIVersion string `yaml:"apiVersion"`
+
+	namespace string
+}
+
+func (c *Certificate) validate() error {
+	if c.Issuer == "" {
+		return fmt.Errorf("certificate checks require an issuer")
+	}
+	if len(c.DNSNames) == 0 {
+		return fmt.Errorf("certificate checks require dnsNames")
+	}
+	if c.Kind == "" {
+		c.Kind = defaultIssuerKind
+	}
+	if c.Name == "" {
+		c.Name = defaultCertificateName
+	}
+	if c.APIVersion == "" {
+		c.APIVersion = defaultCertificateAPIVersion
+	}
+	return nil
+}
+
+func (c Certificate) manifest() ([]byte, error) {
+	certificate := map[string]interface{}{
+		"apiVersion": c.APIVersion,
+		"kind":       "Certificate",
+		"metadata":   map[string]interface{}{"name": c.Name},
+		"spec": map[string]interface{}{
+			"secretName": c.Name,
+			"commonName": c.DNSNames[0],
+			"dnsNames":   c.DNSNames,
+			"issuerRef":  map[string]interface{}{"name": c.Issuer, "kind": c.Kind},
+		},
+	}
+	return json.Marshal(certificate)
+}
+
+func (c Certificate) Assert(env *Env) error {
+	manifest, err := c.manifest()
+	if err != nil {
+		return err
+	}
+	if err := env.Kubectl.Apply(manifest, namespaced(c.namespace)...); err != nil {
+		return fmt.Errorf("could not create certificate %s: %w", c.Name, err)
+	}
+
+	out, err := env.Kubectl.Output(append(namespaced(c.namespace), "get", "certificate", c.Name, "--output", "json")...)
+	if err != nil {
+		return err
+	}
+	var certificate struct {
+		Status struct {
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &certificate); err != nil {
+		return fmt.Errorf("could not decode certificate %s: %w", c.Name, err)
+	}
+	ready := false
+	message := "no Ready condition"
+	for _, condition := range certificate.Status.Conditions {
+		if condition.Type == "Ready" {
+			ready, message = condition.Status == "True", condition.Message
+		}
+	}
+	if !ready {
+		return fmt.Errorf("certificate %s issued by %s %s is not ready: %s", c.Name, c.Kind, c.Issuer, message)
+	}
+
+	out, err = env.Kubectl.Output(append(namespaced(c.namespace), "get", "secret", c.Name, "--output", "json")...)
+	if err != nil {
+		return err
+	}
+	var secret struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(out, &secret); err != nil {
+		return fmt.Errorf("could not decode secret %s: %w", c.Name, err)
+	}
+	tlsCrt, err := base64.StdEncoding.DecodeString(secret.Data["tls.crt"])
+	if err != nil {
+		return fmt.Errorf("could not decode tls.crt of secret %s: %w", c.Name, err)
+	}
+	caCrt, err := base64.StdEncoding.DecodeString(secret.Data["ca.crt"])
+	if err != nil {
+		return fmt.Errorf("could not decode ca.crt of secret %s: %w", c.Name, err)
+	}
+	if err := verifyCertificate(tlsCrt, caCrt, c.DNSNames); err != nil {
+		return fmt.Errorf("certificate %s issued by %s %s: %w", c.Name, c.Kind, c.Issuer, err)
+	}
+
+	return env.Kubectl.Run(append(namespaced(c.namespace), "delete", "certificate/"+c.Name, "secret/"+c.Name, "--ignore-not-found")...)
+}
+
+// verifyCertificate verifies that the first certificate of a PEM chain is valid
+// for the DNS names, chaining up through the rest of the chain to the CA
+// certificate (the last certificate of the chain when there is none).
+func verifyCertificate(chainPEM, caPEM []byte, dnsNames []string) error {
+	chain, err := parseCertificates(chainPEM)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 {
+		return fmt.Errorf("the secret contains no certificate")
+	}
+
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	cas, err := parseCertificates(caPEM)
+	if err != nil {
+		return err
+	}
+	if len(cas) == 0 && len(chain) == 1 {
+		return fmt.Errorf("the secret contains neither a CA certificate nor a chain to verify the certificate with")
+	}
+	for _, ca := range cas {
+		roots.AddCert(ca)
+	}
+	for i, cert := range chain[1:] {
+		if len(cas) == 0 && i == len(chain)-2 {
+			roots.AddCert(cert)
+			continue
+		}
+		intermediates.AddCert(cert)
+	}
+
+	leaf := chain[0]
+	var problems []string
+	for _, name := range dnsNames {
+		if err := leaf.VerifyHostname(name); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+func parseCertificates(b []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		if block, b = pem.Decode(b); block == nil {
+			return certs, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+}
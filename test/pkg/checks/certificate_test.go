@@ -0,0 +1,85 @@
+package checks
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issue creates a certificate for the DNS names signed by parent (self-signed
+// when parent is nil), returning it PEM encoded.
+func issue(t *testing.T, name string, dnsNames []string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              dnsNames,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  parent == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestVerifyCertificate(t *testing.T) {
+	ca, caKey, caPEM := issue(t, "kubernetes-ca", nil, nil, nil)
+	_, _, leafPEM := issue(t, "issuance-check.example.com", []string{"issuance-check.example.com"}, ca, caKey)
+	_, _, otherCAPEM := issue(t, "other-ca", nil, nil, nil)
+
+	if err := verifyCertificate(leafPEM, caPEM, []string{"issuance-check.example.com"}); err != nil {
+		t.Errorf("expected the certificate to verify with its CA: %s", err)
+	}
+	if err := verifyCertificate(append(leafPEM, caPEM...), nil, []string{"issuance-check.example.com"}); err != nil {
+		t.Errorf("expected the certificate to verify with the CA at the end of its chain: %s", err)
+	}
+	if err := verifyCertificate(leafPEM, caPEM, []string{"other.example.com"}); err == nil || !strings.Contains(err.Error(), "other.example.com") {
+		t.Errorf("expected an error for a missing DNS name, got %v", err)
+	}
+	if err := verifyCertificate(leafPEM, otherCAPEM, []string{"issuance-check.example.com"}); err == nil {
+		t.Error("expected an error for a certificate of another CA")
+	}
+	if err := verifyCertificate(leafPEM, nil, []string{"issuance-check.example.com"}); err == nil {
+		t.Error("expected an error for a certificate without a CA")
+	}
+	if err := verifyCertificate(nil, caPEM, nil); err == nil {
+		t.Error("expected an error for a secret without a certificate")
+	}
+}
+
+func TestCertificateDefinition(t *testing.T) {
+	check, err := definition{Name: "issues", Namespace: "kommander", Certificate: &Certificate{Issuer: "kubernetes-ca", DNSNames: []string{"a.example.com"}}}.check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate := check.Assertion.(Certificate)
+	if certificate.Kind != defaultIssuerKind || certificate.Name != defaultCertificateName || certificate.APIVersion != defaultCertificateAPIVersion || certificate.namespace != "kommander" {
+		t.Errorf("expected defaults to be set, got %+v", certificate)
+	}
+
+	if _, err := (definition{Name: "issues", Certificate: &Certificate{DNSNames: []string{"a.example.com"}}}).check(); err == nil {
+		t.Error("expected an error for a certificate check without an issuer")
+	}
+}
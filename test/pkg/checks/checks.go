@@ -0,0 +1,255 @@
+package checks
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/identities"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const (
+	defaultTimeout  = 5 * time.Minute
+	defaultInterval = 5 * time.Second
+)
+
+// Env is the environment checks are run in.
+type Env struct {
+	// Kubectl runs kubectl commands against the cluster.
+	Kubectl kubectl.Client
+
+	// Proxy provides access to the Kubernetes API and cluster services.
+	Proxy *kubectl.Proxy
+
+	// Identities are the test identities provisioned in the cluster, if any.
+	Identities *identities.Fixture
+}
+
+// Assertion is a single condition about the state of a cluster.
+type Assertion interface {
+	Assert(env *Env) error
+}
+
+// Check is a named assertion which is retried until it passes or its timeout
+// expires. Checks with a restart then keep asserting while the workload is
+// restarted.
+type Check struct {
+	Name string
+
+	// Addon is the addon the check covers, if any. Checks of addons which are
+	// not tested are skipped.
+	Addon string
+
+	// Backend is the backend (see scenarios) the check applies to, if any.
+	// Checks of resources created by the kubeaddons controller only apply
+	// to the controller backend.
+	Backend string
+
+	// Tags classify the check (e.g. monitoring, slow, destructive), so runs
+	// can select the checks they run by tag.
+	Tags []string
+
+	Timeout   time.Duration
+	Interval  time.Duration
+	Assertion Assertion
+	Restart   *Restart
+}
+
+// Run asserts the check until it passes, returning the last assertion error
+// when the timeout expires.
+func (c Check) Run(env *Env) error {
+	_, err := c.RunAttempts(env)
+	return err
+}
+
+// RunAttempts is like Run, but also returns the number of failed attempts
+// before the check passed (or timed out).
+func (c Check) RunAttempts(env *Env) (int, error) {
+	deadline := time.Now().Add(c.Timeout)
+	for failures := 0; ; failures++ {
+		err := c.Assertion.Assert(env)
+		if err == nil {
+			if c.Restart != nil {
+				if err := c.Restart.during(env, c.Assertion, c.Interval, c.Timeout); err != nil {
+					return failures, fmt.Errorf("check %q: %w", c.Name, err)
+				}
+			}
+			return failures, nil
+		}
+		if time.Now().Add(c.Interval).After(deadline) {
+			return failures + 1, fmt.Errorf("check %q did not pass within %s: %w", c.Name, c.Timeout, err)
+		}
+		time.Sleep(c.Interval)
+	}
+}
+
+// definition is the YAML representation of a check. Exactly one of the
+// assertion fields (resource, httpGet, promql, jsonpath, events,
+// authorization, certificate, metrics, logs, traffic) must be set.
+type definition struct {
+	Name      string   `yaml:"name"`
+	Addon     string   `yaml:"addon"`
+	Backend   string   `yaml:"backend"`
+	Namespace string   `yaml:"namespace"`
+	Timeout   string   `yaml:"timeout"`
+	Restart   string   `yaml:"restart"`
+	Tags      []string `yaml:"tags"`
+
+	Resource      string         `yaml:"resource"`
+	HTTPGet       *HTTPGet       `yaml:"httpGet"`
+	ExpectStatus  int            `yaml:"expectStatus"`
+	PromQL        string         `yaml:"promql"`
+	Prometheus    string         `yaml:"prometheus"`
+	JSONPath      *JSONPath      `yaml:"jsonpath"`
+	Events        *Events        `yaml:"events"`
+	Authorization *Authorization `yaml:"authorization"`
+	Certificate   *Certificate   `yaml:"certificate"`
+	Metrics       *Metrics       `yaml:"metrics"`
+	Logs          *Logs          `yaml:"logs"`
+	Traffic       *Traffic       `yaml:"traffic"`
+}
+
+// Load reads the check definitions from a YAML file. A file which does not
+// exist contains no checks.
+func Load(path string) ([]Check, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var definitions []definition
+	if err := yaml.UnmarshalStrict(b, &definitions); err != nil {
+		return nil, fmt.Errorf("could not parse checks from %s: %w", path, err)
+	}
+
+	checks := make([]Check, 0, len(definitions))
+	for i, d := range definitions {
+		check, err := d.check()
+		if err != nil {
+			return nil, fmt.Errorf("invalid check #%d in %s: %w", i+1, path, err)
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, nil
+}
+
+func (d definition) check() (Check, error) {
+	if d.Name == "" {
+		return Check{}, fmt.Errorf("checks require a name")
+	}
+
+	check := Check{Name: d.Name, Addon: d.Addon, Backend: d.Backend, Tags: d.Tags, Timeout: defaultTimeout, Interval: defaultInterval}
+	for _, tag := range d.Tags {
+		if !tagPattern.MatchString(tag) {
+			return check, fmt.Errorf("check %q has invalid tag %q, tags are lower case words separated by dashes", d.Name, tag)
+		}
+	}
+	if d.Timeout != "" {
+		timeout, err := time.ParseDuration(d.Timeout)
+		if err != nil {
+			return check, err
+		}
+		check.Timeout = timeout
+	}
+
+	var assertions []Assertion
+	if d.Resource != "" {
+		resource, err := parseResource(d.Namespace, d.Resource)
+		if err != nil {
+			return check, err
+		}
+		assertions = append(assertions, resource)
+	}
+	if d.HTTPGet != nil {
+		httpGet := *d.HTTPGet
+		httpGet.namespace = d.Namespace
+		httpGet.expectStatus = d.ExpectStatus
+		if httpGet.expectStatus == 0 {
+			httpGet.expectStatus = 200
+		}
+		assertions = append(assertions, httpGet)
+	}
+	if d.PromQL != "" {
+		promQL, err := newPromQL(d.PromQL, d.Prometheus)
+		if err != nil {
+			return check, err
+		}
+		assertions = append(assertions, promQL)
+	}
+	if d.JSONPath != nil {
+		jsonPath := *d.JSONPath
+		jsonPath.namespace = d.Namespace
+		if err := jsonPath.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, jsonPath)
+	}
+	if d.Events != nil {
+		events := *d.Events
+		events.namespace = d.Namespace
+		assertions = append(assertions, events)
+	}
+	if d.Authorization != nil {
+		authorization := *d.Authorization
+		authorization.namespace = d.Namespace
+		if err := authorization.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, authorization)
+	}
+	if d.Certificate != nil {
+		certificate := *d.Certificate
+		certificate.namespace = d.Namespace
+		if err := certificate.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, certificate)
+	}
+	if d.Metrics != nil {
+		metrics := *d.Metrics
+		metrics.namespace = d.Namespace
+		if err := metrics.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, metrics)
+	}
+	if d.Logs != nil {
+		logs := *d.Logs
+		logs.namespace = d.Namespace
+		if err := logs.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, logs)
+	}
+	if d.Traffic != nil {
+		traffic := *d.Traffic
+		traffic.namespace = d.Namespace
+		if err := traffic.validate(); err != nil {
+			return check, err
+		}
+		assertions = append(assertions, traffic)
+	}
+
+	if len(assertions) != 1 {
+		return check, fmt.Errorf("check %q must have exactly one of resource, httpGet, promql, jsonpath, events, authorization, certificate, metrics, logs or traffic (has %d)", d.Name, len(assertions))
+	}
+	check.Assertion = assertions[0]
+
+	if d.Restart != "" {
+		restart, err := parseRestart(d.Namespace, d.Restart)
+		if err != nil {
+			return check, err
+		}
+		check.Restart = restart
+	}
+
+	return check, nil
+}
@@ -0,0 +1,134 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// JSONPath asserts that a JSONPath expression evaluated on a resource equals a
+// value, e.g. `{.status.ready}` of clusteraddon/kommander equals "true". The
+// resource is either a single object (<kind>/<name>) or all objects of a kind,
+// optionally matching a label selector, which must all pass.
+//
+// Instead of path and equals, expect compares a path with an operator: ==,
+// != or =~ (a regular expression), surrounded by spaces, e.g.
+// ".status.sync.status == Synced". A path without an operator passes when its
+// value is not empty.
+type JSONPath struct {
+	Resource string `yaml:"resource"`
+	Selector string `yaml:"selector"`
+	Path     string `yaml:"path"`
+	Equals   string `yaml:"equals"`
+	Expect   string `yaml:"expect"`
+
+	namespace string
+	operator  string
+	value     string
+	pattern   *regexp.Regexp
+}
+
+// operators are the operators of expect, in the order they are looked for.
+var operators = []string{"==", "!=", "=~"}
+
+func (j *JSONPath) validate() error {
+	if j.Resource == "" {
+		return fmt.Errorf("jsonpath requires a resource")
+	}
+	if j.Selector != "" && strings.Contains(j.Resource, "/") {
+		return fmt.Errorf("jsonpath resource %s is a single object, a selector requires a kind", j.Resource)
+	}
+
+	if j.Expect == "" {
+		if j.Path == "" {
+			return fmt.Errorf("jsonpath requires either a path or expect")
+		}
+		j.operator, j.value = "==", j.Equals
+		return nil
+	}
+	if j.Path != "" || j.Equals != "" {
+		return fmt.Errorf("jsonpath requires either a path and equals, or expect, not both")
+	}
+
+	j.Path = strings.TrimSpace(j.Expect)
+	for _, operator := range operators {
+		if i := strings.Index(j.Expect, " "+operator+" "); i >= 0 {
+			j.Path = strings.TrimSpace(j.Expect[:i])
+			j.operator, j.value = operator, strings.TrimSpace(j.Expect[i+len(operator)+2:])
+			break
+		}
+	}
+	if j.Path == "" {
+		return fmt.Errorf("jsonpath expectation %q has no path", j.Expect)
+	}
+	if !strings.HasPrefix(j.Path, "{") {
+		j.Path = "{" + j.Path + "}"
+	}
+	if j.operator == "=~" {
+		pattern, err := regexp.Compile(j.value)
+		if err != nil {
+			return fmt.Errorf("jsonpath expectation %q has an invalid regular expression: %w", j.Expect, err)
+		}
+		j.pattern = pattern
+	}
+	return nil
+}
+
+func (j JSONPath) Assert(env *Env) error {
+	// every object is printed on a line of its own as <name>\t<value>
+	template := "{.metadata.name}{\"\\t\"}" + j.Path + "{\"\\n\"}"
+	args := append(namespaced(j.namespace), "get", j.Resource)
+	if !strings.Contains(j.Resource, "/") {
+		template = "{range .items[*]}" + template + "{end}"
+		if j.Selector != "" {
+			args = append(args, "--selector", j.Selector)
+		}
+	}
+	out, err := env.Kubectl.Output(append(args, "--output", "jsonpath="+template)...)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		if j.Selector != "" {
+			return fmt.Errorf("no %s matches selector %s", j.Resource, j.Selector)
+		}
+		return fmt.Errorf("no %s exists", j.Resource)
+	}
+	for _, line := range lines {
+		fields := strings.SplitN(line, "\t", 2)
+		name, actual := fields[0], ""
+		if len(fields) == 2 {
+			actual = strings.TrimSpace(fields[1])
+		}
+		if err := j.compare(actual); err != nil {
+			return fmt.Errorf("%s of %s %s %s", j.Path, j.Resource, name, err)
+		}
+	}
+	return nil
+}
+
+// compare returns an error unless the value of the path meets the
+// expectation.
+func (j JSONPath) compare(actual string) error {
+	switch j.operator {
+	case "==":
+		if actual != j.value {
+			return fmt.Errorf("is %q, expected %q", actual, j.value)
+		}
+	case "!=":
+		if actual == j.value {
+			return fmt.Errorf("is %q, expected anything else", actual)
+		}
+	case "=~":
+		if !j.pattern.MatchString(actual) {
+			return fmt.Errorf("is %q, expected to match %q", actual, j.value)
+		}
+	default:
+		if actual == "" {
+			return fmt.Errorf("is empty")
+		}
+	}
+	return nil
+}
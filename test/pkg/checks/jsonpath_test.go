@@ -0,0 +1,66 @@
+package checks
+
+import "testing"
+
+func TestJSONPathValidate(t *testing.T) {
+	for _, tc := range []struct {
+		in       JSONPath
+		path     string
+		operator string
+		value    string
+		invalid  bool
+	}{
+		{in: JSONPath{Resource: "clusteraddon/kommander", Path: "{.status.ready}", Equals: "true"}, path: "{.status.ready}", operator: "==", value: "true"},
+		{in: JSONPath{Resource: "applications.argoproj.io", Expect: ".status.sync.status == Synced"}, path: "{.status.sync.status}", operator: "==", value: "Synced"},
+		{in: JSONPath{Resource: "applications.argoproj.io", Selector: "team=ops", Expect: "{.status.health.status} != Degraded"}, path: "{.status.health.status}", operator: "!=", value: "Degraded"},
+		{in: JSONPath{Resource: "certificates", Expect: ".status.notAfter =~ ^20[0-9]{2}-"}, path: "{.status.notAfter}", operator: "=~", value: "^20[0-9]{2}-"},
+		{in: JSONPath{Resource: "deployment/grafana", Expect: ".status.readyReplicas"}, path: "{.status.readyReplicas}"},
+		{in: JSONPath{Path: "{.status.ready}"}, invalid: true},
+		{in: JSONPath{Resource: "deployment/grafana"}, invalid: true},
+		{in: JSONPath{Resource: "deployment/grafana", Selector: "app=grafana", Path: "{.status.ready}"}, invalid: true},
+		{in: JSONPath{Resource: "deployment/grafana", Path: "{.status.ready}", Expect: ".status.ready == true"}, invalid: true},
+		{in: JSONPath{Resource: "deployment/grafana", Expect: " == true"}, invalid: true},
+		{in: JSONPath{Resource: "deployment/grafana", Expect: ".status.ready =~ (true"}, invalid: true},
+	} {
+		j := tc.in
+		err := j.validate()
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("expected %+v to be invalid", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error validating %+v: %s", tc.in, err)
+			continue
+		}
+		if j.Path != tc.path || j.operator != tc.operator || j.value != tc.value {
+			t.Errorf("expected %+v to compare %s %s %q, got %s %s %q", tc.in, tc.path, tc.operator, tc.value, j.Path, j.operator, j.value)
+		}
+	}
+}
+
+func TestJSONPathCompare(t *testing.T) {
+	for _, tc := range []struct {
+		expect string
+		actual string
+		passes bool
+	}{
+		{expect: ".status.sync.status == Synced", actual: "Synced", passes: true},
+		{expect: ".status.sync.status == Synced", actual: "OutOfSync"},
+		{expect: ".status.health.status != Degraded", actual: "Healthy", passes: true},
+		{expect: ".status.health.status != Degraded", actual: "Degraded"},
+		{expect: ".status.version =~ ^1\\.", actual: "1.2.3", passes: true},
+		{expect: ".status.version =~ ^1\\.", actual: "2.0.0"},
+		{expect: ".status.observedGeneration", actual: "3", passes: true},
+		{expect: ".status.observedGeneration", actual: ""},
+	} {
+		j := JSONPath{Resource: "applications.argoproj.io", Expect: tc.expect}
+		if err := j.validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.compare(tc.actual); (err == nil) != tc.passes {
+			t.Errorf("expected %q on %q to pass: %t, got %v", tc.expect, tc.actual, tc.passes, err)
+		}
+	}
+}
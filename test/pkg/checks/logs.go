@@ -0,0 +1,100 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Logs asserts that a line matching a pattern appears in the logs of the pods
+// selected by a label selector, or with absent that it never appears in them,
+// e.g. no "TLS handshake error" in the logs of traefik. The logs of the
+// previous instances of restarted containers are searched too.
+type Logs struct {
+	// Selector is the label selector of the pods, e.g. app=traefik.
+	Selector string `yaml:"selector"`
+
+	// Container is the container whose logs are searched, all containers of
+	// the pods when empty.
+	Container string `yaml:"container"`
+
+	// Pattern is the regular expression lines are matched against.
+	Pattern string `yaml:"pattern"`
+
+	Absent bool `yaml:"absent"`
+
+	namespace string
+	pattern   *regexp.Regexp
+}
+
+func (l *Logs) validate() error {
+	if l.Selector == "" {
+		return fmt.Errorf("logs requires a selector")
+	}
+	if l.Pattern == "" {
+		return fmt.Errorf("logs requires a pattern")
+	}
+	pattern, err := regexp.Compile(l.Pattern)
+	if err != nil {
+		return fmt.Errorf("logs pattern %q is not a regular expression: %w", l.Pattern, err)
+	}
+	l.pattern = pattern
+	return nil
+}
+
+func (l Logs) Assert(env *Env) error {
+	out, err := env.Kubectl.Output(append(namespaced(l.namespace), "get", "pods", "--selector", l.Selector, "--output", "jsonpath={.items[*].metadata.name}")...)
+	if err != nil {
+		return err
+	}
+	pods := strings.Fields(string(out))
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods match selector %s", l.Selector)
+	}
+
+	for _, pod := range pods {
+		logs, err := l.logs(env, pod)
+		if err != nil {
+			return err
+		}
+		line, found := l.match(logs)
+		switch {
+		case found && l.Absent:
+			return fmt.Errorf("pod %s logged %q", pod, line)
+		case found:
+			return nil
+		}
+	}
+	if l.Absent {
+		return nil
+	}
+	return fmt.Errorf("no pod selected by %s logged a line matching %q", l.Selector, l.Pattern)
+}
+
+// logs returns the logs of the current and previous instances of the
+// containers of a pod. Containers which were never restarted have no
+// previous logs.
+func (l Logs) logs(env *Env, pod string) (string, error) {
+	args := append(namespaced(l.namespace), "logs", pod)
+	if l.Container != "" {
+		args = append(args, "--container", l.Container)
+	} else {
+		args = append(args, "--all-containers")
+	}
+	current, err := env.Kubectl.Output(args...)
+	if err != nil {
+		return "", err
+	}
+	previous, _ := env.Kubectl.Output(append(args, "--previous")...)
+	return string(previous) + string(current), nil
+}
+
+// match returns the first line of logs matching the pattern.
+func (l Logs) match(logs string) (string, bool) {
+	for _, line := range strings.Split(logs, "\n") {
+		if l.pattern.MatchString(line) {
+			return line, true
+		}
+	}
+	return "", false
+}
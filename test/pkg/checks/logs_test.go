@@ -0,0 +1,46 @@
+package checks
+
+import "testing"
+
+const traefikLogs = `time="2020-05-11T09:12:01Z" level=info msg="Server configuration reloaded on :443"
+2020/05/11 09:12:07 http: TLS handshake error from 10.244.0.1:52314: remote error: tls: bad certificate
+time="2020-05-11T09:12:09Z" level=info msg="Skipping same configuration for provider kubernetes"
+`
+
+func TestLogsMatch(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"TLS handshake error":         "2020/05/11 09:12:07 http: TLS handshake error from 10.244.0.1:52314: remote error: tls: bad certificate",
+		`configuration reloaded on`:   `time="2020-05-11T09:12:01Z" level=info msg="Server configuration reloaded on :443"`,
+		`level=(error|fatal)`:         "",
+		`^time=.*provider kubernetes`: `time="2020-05-11T09:12:09Z" level=info msg="Skipping same configuration for provider kubernetes"`,
+	} {
+		l := Logs{Selector: "app=traefik", Pattern: pattern}
+		if err := l.validate(); err != nil {
+			t.Fatal(err)
+		}
+		line, found := l.match(traefikLogs)
+		if line != expected || found != (expected != "") {
+			t.Errorf("expected %q to match %q, got %q (%t)", pattern, expected, line, found)
+		}
+	}
+}
+
+func TestLogsDefinition(t *testing.T) {
+	check, err := definition{Name: "no handshake errors", Namespace: "kubeaddons", Logs: &Logs{Selector: "app=traefik", Pattern: "TLS handshake error", Absent: true}}.check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs := check.Assertion.(Logs); logs.namespace != "kubeaddons" || logs.pattern == nil || !logs.Absent {
+		t.Errorf("unexpected assertion %+v", logs)
+	}
+
+	for _, invalid := range []*Logs{
+		{Pattern: "sync complete"},
+		{Selector: "app=kommander"},
+		{Selector: "app=kommander", Pattern: "sync (complete"},
+	} {
+		if _, err := (definition{Name: "logged", Logs: invalid}).check(); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
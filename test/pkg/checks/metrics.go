@@ -0,0 +1,216 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// metricsExpectation matches the expectation of a metrics assertion, e.g.
+// `thanos_blocks_meta_synced{state="loaded"} > 0`.
+var metricsExpectation = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{[^}]*\})?\s*(==|!=|>=|<=|>|<)\s*(\S+)$`)
+
+// Metrics asserts the value of a metric a cluster service exposes in the
+// prometheus text format, for components whose metrics no prometheus scrapes.
+// The expectation compares the sum of the samples of the metric whose labels
+// match (e.g. `thanos_compact_halted == 0`) with a value, a metric without
+// samples sums to zero.
+type Metrics struct {
+	// Service is the service exposing the metrics, as
+	// [<scheme>:]<name>[:<port>].
+	Service string `yaml:"service"`
+
+	// Path is the path of the metrics (default: /metrics).
+	Path string `yaml:"path"`
+
+	Expect string `yaml:"expect"`
+
+	namespace string
+	metric    string
+	labels    map[string]string
+	operator  string
+	value     float64
+}
+
+func (m *Metrics) validate() error {
+	if m.Service == "" {
+		return fmt.Errorf("metrics requires a service")
+	}
+	if m.Path == "" {
+		m.Path = "/metrics"
+	}
+	match := metricsExpectation.FindStringSubmatch(strings.TrimSpace(m.Expect))
+	if match == nil {
+		return fmt.Errorf("metrics expectation %q must be of the form <metric>[{<label>=\"<value>\",...}] <==|!=|>|>=|<|<=> <number>", m.Expect)
+	}
+	m.metric, m.operator = match[1], match[3]
+	labels, err := parseLabels(strings.Trim(match[2], "{}"))
+	if err != nil {
+		return fmt.Errorf("metrics expectation %q: %w", m.Expect, err)
+	}
+	m.labels = labels
+	if m.value, err = strconv.ParseFloat(match[4], 64); err != nil {
+		return fmt.Errorf("metrics expectation %q compares with %q, which is not a number", m.Expect, match[4])
+	}
+	return nil
+}
+
+func (m Metrics) Assert(env *Env) error {
+	target := env.Proxy.ServiceURL(m.namespace, m.Service, m.Path)
+	resp, err := http.Get(target)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", target, resp.StatusCode)
+	}
+	return m.assert(resp.Body)
+}
+
+// assert asserts the expectation on metrics in the prometheus text format.
+func (m Metrics) assert(r io.Reader) error {
+	var sum float64
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, labels, value, err := parseSample(line)
+		if err != nil {
+			return err
+		}
+		if name == m.metric && matchLabels(labels, m.labels) {
+			sum += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var ok bool
+	switch m.operator {
+	case "==":
+		ok = sum == m.value
+	case "!=":
+		ok = sum != m.value
+	case ">":
+		ok = sum > m.value
+	case ">=":
+		ok = sum >= m.value
+	case "<":
+		ok = sum < m.value
+	case "<=":
+		ok = sum <= m.value
+	}
+	if !ok {
+		return fmt.Errorf("expected %s, got %g", m.Expect, sum)
+	}
+	return nil
+}
+
+// parseSample parses a sample of the prometheus text format, e.g.
+// `thanos_compact_halted{instance="a"} 0 1589198713000`.
+func parseSample(line string) (string, map[string]string, float64, error) {
+	name, rest := line, ""
+	labels := map[string]string{}
+	if i := strings.IndexAny(line, "{ "); i >= 0 {
+		name, rest = line[:i], line[i:]
+	}
+	if strings.HasPrefix(rest, "{") {
+		end := labelsEnd(rest)
+		if end < 0 {
+			return "", nil, 0, fmt.Errorf("sample %q has unterminated labels", line)
+		}
+		var err error
+		if labels, err = parseLabels(rest[1:end]); err != nil {
+			return "", nil, 0, fmt.Errorf("sample %q: %w", line, err)
+		}
+		rest = rest[end+1:]
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, 0, fmt.Errorf("sample %q has no value", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("sample %q has an invalid value: %w", line, err)
+	}
+	return name, labels, value, nil
+}
+
+// labelsEnd returns the index of the brace closing the labels at the start of
+// s, skipping braces in quoted values.
+func labelsEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '}' && !quoted:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseLabels parses comma separated label pairs, e.g. `state="loaded",a="b"`.
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for s = strings.TrimSpace(s); s != ""; {
+		eq := strings.Index(s, "=")
+		if eq < 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, fmt.Errorf("invalid labels %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		value, n, err := unquote(s[eq+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of label %s: %w", name, err)
+		}
+		labels[name] = value
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s[eq+1+n:]), ","))
+	}
+	return labels, nil
+}
+
+// unquote returns the value of the quoted string at the start of s and its
+// length including the quotes.
+func unquote(s string) (string, int, error) {
+	b := new(strings.Builder)
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 == len(s) {
+				return "", 0, fmt.Errorf("unterminated string %s", s)
+			}
+			i++
+			if s[i] == 'n' {
+				b.WriteByte('\n')
+			} else {
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", s)
+}
+
+func matchLabels(labels, matchers map[string]string) bool {
+	for name, value := range matchers {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
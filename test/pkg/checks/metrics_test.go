@@ -0,0 +1,68 @@
+package checks
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const exposition = `# HELP thanos_blocks_meta_synced Number of block metadata synced
+# TYPE thanos_blocks_meta_synced gauge
+thanos_blocks_meta_synced{state="loaded"} 3
+thanos_blocks_meta_synced{state="failed"} 0
+thanos_blocks_meta_synced{state="marked-for-deletion",note="a \"quoted\" {brace}"} 1
+thanos_compact_halted 0
+thanos_compact_group_compactions_total{group="0@{}"} 2 1589198713000
+`
+
+func TestMetrics(t *testing.T) {
+	for expect, passes := range map[string]bool{
+		`thanos_blocks_meta_synced{state="loaded"} > 0`: true,
+		`thanos_blocks_meta_synced{state="failed"} > 0`: false,
+		`thanos_blocks_meta_synced == 4`:                true,
+		`thanos_compact_halted == 0`:                    true,
+		`thanos_compact_group_compactions_total >= 2`:   true,
+		`thanos_objstore_bucket_operations_total > 0`:   false,
+	} {
+		m := Metrics{Service: "http:thanos-store:10902", Expect: expect}
+		if err := m.validate(); err != nil {
+			t.Fatal(err)
+		}
+		err := m.assert(strings.NewReader(exposition))
+		if passes && err != nil {
+			t.Errorf("expected %s to pass: %s", expect, err)
+		}
+		if !passes && err == nil {
+			t.Errorf("expected %s to fail", expect)
+		}
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	for _, expect := range []string{
+		`thanos_compact_halted`,
+		`thanos_compact_halted = 0`,
+		`thanos_compact_halted == zero`,
+		`thanos_blocks_meta_synced{state=loaded} > 0`,
+	} {
+		m := Metrics{Service: "http:thanos-store:10902", Expect: expect}
+		if err := m.validate(); err == nil {
+			t.Errorf("expected %q to be invalid", expect)
+		}
+	}
+	m := Metrics{Expect: `thanos_compact_halted == 0`}
+	if err := m.validate(); err == nil {
+		t.Error("expected an error without a service")
+	}
+}
+
+func TestParseSample(t *testing.T) {
+	name, labels, value, err := parseSample(`thanos_blocks_meta_synced{state="marked-for-deletion",note="a \"quoted\" {brace}"} 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"state": "marked-for-deletion", "note": `a "quoted" {brace}`}
+	if name != "thanos_blocks_meta_synced" || value != 1 || !reflect.DeepEqual(expected, labels) {
+		t.Errorf("unexpected sample %s %v %g", name, labels, value)
+	}
+}
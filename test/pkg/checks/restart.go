@@ -0,0 +1,63 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Restart is a rolling restart of a workload, e.g. "statefulset/elasticsearch-kubeaddons-master".
+// A check with a restart first asserts until it passes, then restarts the
+// workload and keeps asserting until the rollout completes: any failure during
+// the rollout means the addon does not tolerate its pods being replaced one by one.
+type Restart struct {
+	namespace string
+	kind      string
+	name      string
+}
+
+func parseRestart(namespace, s string) (*Restart, error) {
+	ref := strings.SplitN(s, "/", 2)
+	if len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+		return nil, fmt.Errorf("restart %q must be of the form <kind>/<name>", s)
+	}
+
+	r := &Restart{namespace: namespace, kind: strings.ToLower(ref[0]), name: ref[1]}
+	if !rolloutKinds[r.kind] {
+		return nil, fmt.Errorf("cannot restart %s, only deployments, daemonsets and statefulsets can be restarted", s)
+	}
+	return r, nil
+}
+
+func (r *Restart) String() string {
+	return r.kind + "/" + r.name
+}
+
+// during restarts the workload and asserts a continuously (every interval)
+// until the rollout has completed or timeout expires.
+func (r *Restart) during(env *Env, a Assertion, interval, timeout time.Duration) error {
+	args := namespaced(r.namespace)
+	if _, err := env.Kubectl.Output(append(args, "rollout", "restart", r.String())...); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := a.Assert(env); err != nil {
+			return fmt.Errorf("failed during rolling restart of %s: %w", r, err)
+		}
+
+		out, err := env.Kubectl.Output(append(args, "rollout", "status", r.String(), "--watch=false")...)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(string(out), "successfully rolled out") || strings.Contains(string(out), "rolling update complete") {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("rolling restart of %s did not complete within %s: %s", r, timeout, strings.TrimSpace(string(out)))
+		}
+		time.Sleep(interval)
+	}
+}
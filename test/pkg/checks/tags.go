@@ -0,0 +1,62 @@
+package checks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagPattern matches the tags of checks, e.g. monitoring or rolling-restart.
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Selection selects the checks a run executes by their tags: checks with a
+// skipped tag are skipped, and when tags are included, so are checks without
+// any of them.
+type Selection struct {
+	Include []string
+	Skip    []string
+}
+
+// ParseSelection parses the comma separated tags which are included and
+// skipped (e.g. CHECK_TAGS and SKIP_CHECK_TAGS).
+func ParseSelection(include, skip string) (Selection, error) {
+	var s Selection
+	for _, list := range []struct {
+		tags   string
+		parsed *[]string
+	}{{include, &s.Include}, {skip, &s.Skip}} {
+		for _, tag := range strings.Split(list.tags, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" {
+				continue
+			}
+			if !tagPattern.MatchString(tag) {
+				return Selection{}, fmt.Errorf("invalid check tag %q", tag)
+			}
+			*list.parsed = append(*list.parsed, tag)
+		}
+	}
+	return s, nil
+}
+
+// Skips returns whether a check is skipped, and why.
+func (s Selection) Skips(c Check) (string, bool) {
+	tags := make(map[string]bool, len(c.Tags))
+	for _, tag := range c.Tags {
+		tags[tag] = true
+	}
+	for _, tag := range s.Skip {
+		if tags[tag] {
+			return fmt.Sprintf("tag %s is skipped", tag), true
+		}
+	}
+	if len(s.Include) == 0 {
+		return "", false
+	}
+	for _, tag := range s.Include {
+		if tags[tag] {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("none of the tags %s is selected", strings.Join(s.Include, ", ")), true
+}
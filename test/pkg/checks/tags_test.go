@@ -0,0 +1,50 @@
+package checks
+
+import "testing"
+
+func TestSelection(t *testing.T) {
+	checks := map[string]Check{
+		"untagged":    {Name: "untagged"},
+		"monitoring":  {Name: "monitoring", Tags: []string{"monitoring"}},
+		"slow":        {Name: "slow", Tags: []string{"monitoring", "slow"}},
+		"destructive": {Name: "destructive", Tags: []string{"destructive"}},
+	}
+	for _, tc := range []struct {
+		include, skip string
+		runs          []string
+	}{
+		{runs: []string{"untagged", "monitoring", "slow", "destructive"}},
+		{skip: "slow, destructive", runs: []string{"untagged", "monitoring"}},
+		{include: "monitoring", runs: []string{"monitoring", "slow"}},
+		{include: "monitoring,destructive", skip: "slow", runs: []string{"monitoring", "destructive"}},
+	} {
+		s, err := ParseSelection(tc.include, tc.skip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs := make(map[string]bool)
+		for _, name := range tc.runs {
+			runs[name] = true
+		}
+		for name, check := range checks {
+			if reason, skipped := s.Skips(check); skipped == runs[name] {
+				t.Errorf("expected check %s to run (%v) with CHECK_TAGS=%q and SKIP_CHECK_TAGS=%q, skipped: %q", name, runs[name], tc.include, tc.skip, reason)
+			}
+		}
+	}
+
+	if _, err := ParseSelection("Monitoring", ""); err == nil {
+		t.Error("expected an invalid tag to be rejected")
+	}
+}
+
+func TestInvalidTag(t *testing.T) {
+	d := definition{Name: "traefik is ready", Resource: "deployment/traefik ready", Tags: []string{"slow test"}}
+	if _, err := d.check(); err == nil {
+		t.Error("expected an invalid tag to be rejected")
+	}
+	d.Tags = []string{"rolling-restart"}
+	if c, err := d.check(); err != nil || len(c.Tags) != 1 {
+		t.Errorf("expected a valid tag to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTrafficRequests  = 100
+	defaultTrafficTolerance = 10
+)
+
+// Traffic asserts how the ingress controller splits the requests for a route
+// among the backends of weighted routing, e.g. between the stable and the
+// canary release of a service. Backends are told apart by the body of their
+// responses (e.g. of an echo server), and the share of the requests each one
+// served must be within the tolerance of its expected share. Requests are sent
+// to the port of the ingress controller service through `kubectl
+// port-forward`.
+type Traffic struct {
+	// Ingress is the service of the ingress controller as <name>:<port>,
+	// e.g. traefik-kubeaddons:443.
+	Ingress string `yaml:"ingress"`
+
+	// Scheme is the scheme of the port, https unless http.
+	Scheme string `yaml:"scheme"`
+
+	// Host is the host of the requests, which selects the ingress.
+	Host string `yaml:"host"`
+
+	Route string `yaml:"route"`
+
+	// Requests is the number of requests sent (default: 100).
+	Requests int `yaml:"requests"`
+
+	// Split are the expected shares of the backends in percent, by the body
+	// of their responses, which add up to 100.
+	Split map[string]int `yaml:"split"`
+
+	// Tolerance is how many percentage points the share of a backend may
+	// deviate from its expected share (default: 10).
+	Tolerance int `yaml:"tolerance"`
+
+	namespace string
+}
+
+func (tr *Traffic) validate() error {
+	if _, _, err := parseIngress(tr.Ingress); err != nil {
+		return err
+	}
+	switch tr.Scheme {
+	case "":
+		tr.Scheme = "https"
+	case "http", "https":
+	default:
+		return fmt.Errorf("traffic scheme %q is neither http nor https", tr.Scheme)
+	}
+	if tr.Host == "" {
+		return fmt.Errorf("traffic requires a host")
+	}
+	if tr.Route == "" {
+		tr.Route = "/"
+	}
+	if tr.Requests == 0 {
+		tr.Requests = defaultTrafficRequests
+	}
+	if tr.Tolerance == 0 {
+		tr.Tolerance = defaultTrafficTolerance
+	}
+	if tr.Requests < 0 || tr.Tolerance < 0 {
+		return fmt.Errorf("traffic requests and tolerance must be positive")
+	}
+	total := 0
+	for backend, share := range tr.Split {
+		if share < 0 {
+			return fmt.Errorf("traffic share of %s must not be negative", backend)
+		}
+		total += share
+	}
+	if total != 100 {
+		return fmt.Errorf("traffic split %v adds up to %d%%, expected 100%%", tr.Split, total)
+	}
+	return nil
+}
+
+func (tr Traffic) Assert(env *Env) error {
+	service, port, err := parseIngress(tr.Ingress)
+	if err != nil {
+		return err
+	}
+	forward, err := env.Kubectl.StartPortForward(tr.namespace, "service/"+service, port)
+	if err != nil {
+		return err
+	}
+	defer forward.Stop()
+
+	return tr.assertVia(forward.Addr)
+}
+
+// assertVia sends the requests with all connections made to addr and
+// compares the split of the responses.
+func (tr Traffic) assertVia(addr string) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			// the ingress controller serves a self-signed certificate
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			// every request may be routed anew
+			DisableKeepAlives: true,
+		},
+	}
+
+	served := make(map[string]int)
+	for i := 0; i < tr.Requests; i++ {
+		resp, err := client.Get(tr.Scheme + "://" + tr.Host + tr.Route)
+		if err != nil {
+			return err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s%s returned %d", tr.Host, tr.Route, resp.StatusCode)
+		}
+		served[strings.TrimSpace(string(body))]++
+	}
+	return tr.compare(served)
+}
+
+// compare returns an error when the share of the responses of a backend
+// deviates from its expected share by more than the tolerance, or responses
+// came from unexpected backends.
+func (tr Traffic) compare(served map[string]int) error {
+	total := 0
+	backends := make(map[string]bool)
+	for backend, n := range served {
+		total += n
+		backends[backend] = true
+	}
+	for backend := range tr.Split {
+		backends[backend] = true
+	}
+	names := make([]string, 0, len(backends))
+	for backend := range backends {
+		names = append(names, backend)
+	}
+	sort.Strings(names)
+
+	var failures []string
+	for _, backend := range names {
+		expected, ok := tr.Split[backend]
+		share := 100 * float64(served[backend]) / float64(total)
+		switch {
+		case !ok:
+			failures = append(failures, fmt.Sprintf("unexpected backend %q served %.0f%%", backend, share))
+		case math.Abs(share-float64(expected)) > float64(tr.Tolerance):
+			failures = append(failures, fmt.Sprintf("%s served %.0f%%, expected %d%% ± %d", backend, share, expected, tr.Tolerance))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("traffic split of %d requests for %s%s failed: %s", total, tr.Host, tr.Route, strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package checks
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraffic(t *testing.T) {
+	// every fifth request is served by the canary
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "canary.kommander.test" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+		if requests%5 == 0 {
+			fmt.Fprintln(w, "canary")
+			return
+		}
+		fmt.Fprintln(w, "stable")
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	tr := Traffic{Ingress: "traefik-kubeaddons:443", Host: "canary.kommander.test", Split: map[string]int{"stable": 80, "canary": 20}}
+	if err := tr.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if tr.Requests != 100 || tr.Tolerance != 10 || tr.Route != "/" || tr.Scheme != "https" {
+		t.Errorf("expected the defaults, got %+v", tr)
+	}
+	if err := tr.assertVia(addr); err != nil {
+		t.Error(err)
+	}
+
+	tr.Split = map[string]int{"canary": 100}
+	err := tr.assertVia(addr)
+	if err == nil || !strings.Contains(err.Error(), "canary served 20%, expected 100% ± 10; unexpected backend \"stable\" served 80%") {
+		t.Errorf("expected the promotion to fail, got %v", err)
+	}
+
+	tr.Host = "stable.kommander.test"
+	if err := tr.assertVia(addr); err == nil || !strings.Contains(err.Error(), "returned 404") {
+		t.Errorf("expected requests which are not routed to fail, got %v", err)
+	}
+}
+
+func TestTrafficDefinition(t *testing.T) {
+	check, err := definition{Name: "traffic is split", Namespace: "kubeaddons", Traffic: &Traffic{Ingress: "traefik-kubeaddons:80", Scheme: "http", Host: "canary.kommander.test", Split: map[string]int{"stable": 50, "canary": 50}, Tolerance: 5}}.check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if traffic := check.Assertion.(Traffic); traffic.namespace != "kubeaddons" || traffic.Tolerance != 5 {
+		t.Errorf("unexpected assertion %+v", traffic)
+	}
+
+	for _, invalid := range []*Traffic{
+		{Host: "canary.kommander.test", Split: map[string]int{"stable": 100}},
+		{Ingress: "traefik-kubeaddons:443", Split: map[string]int{"stable": 100}},
+		{Ingress: "traefik-kubeaddons:443", Host: "canary.kommander.test", Split: map[string]int{"stable": 80, "canary": 10}},
+		{Ingress: "traefik-kubeaddons:443", Host: "canary.kommander.test", Scheme: "tcp", Split: map[string]int{"stable": 100}},
+	} {
+		if _, err := (definition{Name: "traffic", Traffic: invalid}).check(); err == nil {
+			t.Errorf("expected an error for %+v", invalid)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package clock
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// zoneinfo is the directory of the timezone database, on the docker host and
+// on the nodes.
+const zoneinfo = "/usr/share/zoneinfo"
+
+// Config is the timezone and locale of the nodes of a kind cluster. The zero
+// value is the default of kind nodes, UTC and the POSIX locale.
+type Config struct {
+	// Timezone is the name of a timezone of the timezone database, e.g.
+	// Asia/Kolkata.
+	Timezone string `yaml:"timezone"`
+
+	// Locale is the locale of the services of the nodes, e.g. de_DE.UTF-8.
+	Locale string `yaml:"locale"`
+}
+
+// locale matches locale names, <language>[_<territory>][.<codeset>][@<modifier>].
+var locale = regexp.MustCompile(`^[a-zA-Z]{2,3}(_[a-zA-Z]{2})?(\.[a-zA-Z0-9-]+)?(@[a-zA-Z0-9]+)?$`)
+
+// Validate returns an error when the configuration is invalid.
+func (c *Config) Validate() error {
+	if c.Timezone != "" {
+		if filepath.IsAbs(c.Timezone) || strings.Contains(c.Timezone, "..") {
+			return fmt.Errorf("timezone %s is not the name of a timezone", c.Timezone)
+		}
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %s: %w", c.Timezone, err)
+		}
+	}
+	if c.Locale != "" && !locale.MatchString(c.Locale) {
+		return fmt.Errorf("locale %s is not a locale name, e.g. de_DE.UTF-8", c.Locale)
+	}
+	return nil
+}
+
+// Default returns whether the configuration is the default of kind nodes.
+func (c *Config) Default() bool {
+	return c.Timezone == "" && c.Locale == ""
+}
+
+// Mount is a file of the docker host which is mounted into the nodes.
+type Mount struct {
+	HostPath      string
+	ContainerPath string
+}
+
+// Mounts writes the files configuring the nodes to a directory and returns
+// them, along with the timezone of the docker host, as the mounts of the
+// nodes. The timezone replaces /etc/localtime, so it is read from the
+// timezone database of the docker host, which must have it.
+func (c *Config) Mounts(dir string) ([]Mount, error) {
+	if c.Default() {
+		return nil, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	var mounts []Mount
+	write := func(name, content string) error {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+		mounts = append(mounts, Mount{HostPath: path, ContainerPath: filepath.Join("/etc", name)})
+		return nil
+	}
+	if c.Timezone != "" {
+		zone := filepath.Join(zoneinfo, c.Timezone)
+		if _, err := os.Stat(zone); err != nil {
+			return nil, fmt.Errorf("timezone %s is not in the timezone database of the docker host: %w", c.Timezone, err)
+		}
+		mounts = append(mounts, Mount{HostPath: zone, ContainerPath: "/etc/localtime"})
+		if err := write("timezone", c.Timezone+"\n"); err != nil {
+			return nil, err
+		}
+	}
+	if c.Locale != "" {
+		if err := write("locale.conf", fmt.Sprintf("LANG=%s\nLC_ALL=%s\n", c.Locale, c.Locale)); err != nil {
+			return nil, err
+		}
+	}
+	return mounts, nil
+}
+
+// Verify returns an error unless every node of a kind cluster runs in the
+// timezone and with the locale of the configuration, as reported by date and
+// by the environment of systemd, which the kubelet and containerd inherit.
+func (c *Config) Verify(cluster string) error {
+	out, err := exec.Command("kind", "get", "nodes", "--name", cluster).Output()
+	if err != nil {
+		return fmt.Errorf("could not list the nodes of kind cluster %s: %w", cluster, err)
+	}
+	nodes := strings.Fields(string(out))
+	if len(nodes) == 0 {
+		return fmt.Errorf("kind cluster %s has no nodes", cluster)
+	}
+
+	var problems []string
+	for _, node := range nodes {
+		if c.Timezone != "" {
+			out, err := exec.Command("docker", "exec", node, "date", "+%z").Output()
+			if err != nil {
+				return fmt.Errorf("could not read the time of node %s: %w", node, err)
+			}
+			if offset := strings.TrimSpace(string(out)); offset != c.offset(time.Now()) {
+				problems = append(problems, fmt.Sprintf("node %s runs at UTC offset %s rather than the %s of %s", node, offset, c.offset(time.Now()), c.Timezone))
+			}
+		}
+		if c.Locale != "" {
+			out, err := exec.Command("docker", "exec", node, "systemctl", "show-environment").Output()
+			if err != nil {
+				return fmt.Errorf("could not read the environment of systemd on node %s: %w", node, err)
+			}
+			if lang := environment(string(out), "LANG"); lang != c.Locale {
+				problems = append(problems, fmt.Sprintf("node %s runs with locale %q rather than %s", node, lang, c.Locale))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// offset returns the UTC offset of the timezone at a time in the format of
+// date +%z, e.g. +0530.
+func (c *Config) offset(at time.Time) string {
+	location, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return ""
+	}
+	return at.In(location).Format("-0700")
+}
+
+// environment returns the value of a variable in the output of systemctl
+// show-environment, one <name>=<value> per line.
+func environment(out, name string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, name+"=") {
+			return strings.TrimPrefix(line, name+"=")
+		}
+	}
+	return ""
+}
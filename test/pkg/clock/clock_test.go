@@ -0,0 +1,96 @@
+package clock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	for _, c := range []Config{
+		{},
+		{Timezone: "Asia/Kolkata"},
+		{Timezone: "Pacific/Chatham", Locale: "de_DE.UTF-8"},
+		{Locale: "sr_RS@latin"},
+	} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("expected %+v to be valid, got %v", c, err)
+		}
+	}
+	for _, c := range []Config{
+		{Timezone: "Mars/Olympus_Mons"},
+		{Timezone: "../../etc/passwd"},
+		{Locale: "German"},
+		{Locale: "de_DE.UTF-8; rm -rf /"},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", c)
+		}
+	}
+}
+
+func TestMounts(t *testing.T) {
+	if _, err := os.Stat(filepath.Join(zoneinfo, "Asia/Kolkata")); err != nil {
+		t.Skip("the timezone database is not installed")
+	}
+	dir, err := ioutil.TempDir("", "clock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if mounts, err := (&Config{}).Mounts(dir); err != nil || len(mounts) != 0 {
+		t.Errorf("expected the default configuration to mount nothing, got %v, %v", mounts, err)
+	}
+
+	mounts, err := (&Config{Timezone: "Asia/Kolkata", Locale: "de_DE.UTF-8"}).Mounts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"/etc/localtime":   filepath.Join(zoneinfo, "Asia/Kolkata"),
+		"/etc/timezone":    filepath.Join(dir, "timezone"),
+		"/etc/locale.conf": filepath.Join(dir, "locale.conf"),
+	}
+	if len(mounts) != len(expected) {
+		t.Fatalf("expected %d mounts, got %v", len(expected), mounts)
+	}
+	for _, m := range mounts {
+		if expected[m.ContainerPath] != m.HostPath {
+			t.Errorf("expected %s to be mounted from %s, got %s", m.ContainerPath, expected[m.ContainerPath], m.HostPath)
+		}
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "locale.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "LANG=de_DE.UTF-8\nLC_ALL=de_DE.UTF-8\n" {
+		t.Errorf("unexpected locale.conf %q", b)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	at := time.Date(2020, time.January, 15, 12, 0, 0, 0, time.UTC)
+	for timezone, expected := range map[string]string{
+		"":                "+0000",
+		"Asia/Kolkata":    "+0530",
+		"Pacific/Chatham": "+1345",
+		"America/Denver":  "-0700",
+	} {
+		if actual := (&Config{Timezone: timezone}).offset(at); actual != expected {
+			t.Errorf("expected the offset of %q to be %s, got %s", timezone, expected, actual)
+		}
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	out := "LANG=de_DE.UTF-8\nPATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin\n"
+	if lang := environment(out, "LANG"); lang != "de_DE.UTF-8" {
+		t.Errorf("expected LANG de_DE.UTF-8, got %q", lang)
+	}
+	if lc := environment(out, "LC_ALL"); lc != "" {
+		t.Errorf("expected no LC_ALL, got %q", lc)
+	}
+}
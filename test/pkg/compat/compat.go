@@ -0,0 +1,112 @@
+package compat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// Range is the range of kubernetes versions an addon supports. Min and Max
+// are nil when unbounded.
+type Range struct {
+	Addon string
+	Min   *semver.Version
+	Max   *semver.Version
+}
+
+// RangeOf returns the range of kubernetes versions an addon supports, from
+// its minSupportedVersion and maxSupportedVersion.
+func RangeOf(addon v1beta1.AddonInterface) (Range, error) {
+	r := Range{Addon: addon.GetName()}
+	spec := addon.GetAddonSpec().Kubernetes
+	if spec == nil {
+		return r, nil
+	}
+
+	var err error
+	if r.Min, err = parse(spec.MinSupportedVersion); err != nil {
+		return r, fmt.Errorf("addon %s has an invalid minSupportedVersion: %w", r.Addon, err)
+	}
+	if r.Max, err = parse(spec.MaxSupportedVersion); err != nil {
+		return r, fmt.Errorf("addon %s has an invalid maxSupportedVersion: %w", r.Addon, err)
+	}
+	if r.Min != nil && r.Max != nil && r.Max.LT(*r.Min) {
+		return r, fmt.Errorf("addon %s supports no kubernetes version: %s", r.Addon, r)
+	}
+	return r, nil
+}
+
+func parse(version string) (*semver.Version, error) {
+	if version == "" {
+		return nil, nil
+	}
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Supports returns whether a kubernetes version is within the range.
+func (r Range) Supports(version semver.Version) bool {
+	return (r.Min == nil || version.GTE(*r.Min)) && (r.Max == nil || version.LTE(*r.Max))
+}
+
+func (r Range) String() string {
+	var bounds []string
+	if r.Min != nil {
+		bounds = append(bounds, ">= "+r.Min.String())
+	}
+	if r.Max != nil {
+		bounds = append(bounds, "<= "+r.Max.String())
+	}
+	if len(bounds) == 0 {
+		return "any version"
+	}
+	return strings.Join(bounds, " and ")
+}
+
+// Intersect returns the range of versions all ranges support, and whether
+// there is any such version.
+func Intersect(ranges []Range) (Range, bool) {
+	var intersection Range
+	for _, r := range ranges {
+		if r.Min != nil && (intersection.Min == nil || r.Min.GT(*intersection.Min)) {
+			intersection.Min = r.Min
+		}
+		if r.Max != nil && (intersection.Max == nil || r.Max.LT(*intersection.Max)) {
+			intersection.Max = r.Max
+		}
+	}
+	ok := intersection.Min == nil || intersection.Max == nil || intersection.Max.GTE(*intersection.Min)
+	return intersection, ok
+}
+
+// Check verifies that the addons of a group, with the given ranges, have
+// kubernetes versions in common and support every version the group is
+// tested with.
+func Check(group string, ranges []Range, tested []semver.Version) []error {
+	var errs []error
+	if _, ok := Intersect(ranges); !ok {
+		bounds := make([]string, 0, len(ranges))
+		for _, r := range ranges {
+			if r.Min != nil || r.Max != nil {
+				bounds = append(bounds, fmt.Sprintf("%s supports %s", r.Addon, r))
+			}
+		}
+		sort.Strings(bounds)
+		errs = append(errs, fmt.Errorf("group %s has no kubernetes version all of its addons support: %s", group, strings.Join(bounds, ", ")))
+	}
+
+	for _, r := range ranges {
+		for _, version := range tested {
+			if !r.Supports(version) {
+				errs = append(errs, fmt.Errorf("addon %s of group %s does not support kubernetes %s which the group is tested with (it supports %s)", r.Addon, group, version, r))
+			}
+		}
+	}
+	return errs
+}
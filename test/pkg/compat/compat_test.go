@@ -0,0 +1,52 @@
+package compat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/blang/semver"
+)
+
+func version(s string) *semver.Version {
+	v := semver.MustParse(s)
+	return &v
+}
+
+func TestIntersect(t *testing.T) {
+	ranges := []Range{
+		{Addon: "kommander", Min: version("1.15.6")},
+		{Addon: "dex", Min: version("1.14.0"), Max: version("1.17.0")},
+		{Addon: "traefik"},
+	}
+	intersection, ok := Intersect(ranges)
+	if !ok || intersection.String() != ">= 1.15.6 and <= 1.17.0" {
+		t.Errorf("expected >= 1.15.6 and <= 1.17.0, got %s (%v)", intersection, ok)
+	}
+
+	ranges = append(ranges, Range{Addon: "reloader", Max: version("1.15.0")})
+	if _, ok := Intersect(ranges); ok {
+		t.Error("expected no version in common")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ranges := []Range{
+		{Addon: "kommander", Min: version("1.15.6")},
+		{Addon: "dex", Max: version("1.15.0")},
+	}
+	errs := Check("kommander", ranges, []semver.Version{semver.MustParse("1.16.4")})
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "group kommander has no kubernetes version all of its addons support") {
+		t.Errorf("unexpected error: %s", errs[0])
+	}
+	expected := "addon dex of group kommander does not support kubernetes 1.16.4 which the group is tested with (it supports <= 1.15.0)"
+	if errs[1].Error() != expected {
+		t.Errorf("expected %q, got %q", expected, errs[1])
+	}
+
+	if errs := Check("kommander", ranges[:1], []semver.Version{semver.MustParse("1.16.4")}); len(errs) != 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
@@ -0,0 +1,127 @@
+package conflicts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Type describes the kind of conflict that was found between addons.
+type Type string
+
+const (
+	// CRD indicates that more than one addon ships the same CustomResourceDefinition.
+	CRD Type = "CustomResourceDefinition"
+
+	// Namespace indicates that more than one addon creates the same Namespace.
+	Namespace Type = "Namespace"
+
+	// ClusterRole indicates that more than one addon ships a ClusterRole with the same name.
+	ClusterRole Type = "ClusterRole"
+
+	// HostPort indicates that more than one addon binds the same host port.
+	HostPort Type = "HostPort"
+)
+
+// Conflict is a single resource claimed by more than one addon.
+type Conflict struct {
+	Type     Type
+	Resource string
+	Addons   []string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s %q is claimed by multiple addons: %s", c.Type, c.Resource, strings.Join(c.Addons, ", "))
+}
+
+// Detect finds resources which are claimed by more than one addon in the given
+// set of rendered objects. Objects must have their Addon field set.
+func Detect(objects []render.Object) []Conflict {
+	claims := make(map[Type]map[string]map[string]struct{})
+	claim := func(t Type, resource, addon string) {
+		if claims[t] == nil {
+			claims[t] = make(map[string]map[string]struct{})
+		}
+		if claims[t][resource] == nil {
+			claims[t][resource] = make(map[string]struct{})
+		}
+		claims[t][resource][addon] = struct{}{}
+	}
+
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "CustomResourceDefinition":
+			claim(CRD, obj.Name, obj.Addon)
+		case "Namespace":
+			claim(Namespace, obj.Name, obj.Addon)
+		case "ClusterRole":
+			claim(ClusterRole, obj.Name, obj.Addon)
+		}
+
+		for _, port := range hostPorts(obj) {
+			claim(HostPort, port, obj.Addon)
+		}
+	}
+
+	var conflicts []Conflict
+	for t, resources := range claims {
+		for resource, addonSet := range resources {
+			if len(addonSet) < 2 {
+				continue
+			}
+			addons := make([]string, 0, len(addonSet))
+			for addon := range addonSet {
+				addons = append(addons, addon)
+			}
+			sort.Strings(addons)
+			conflicts = append(conflicts, Conflict{Type: t, Resource: resource, Addons: addons})
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Type != conflicts[j].Type {
+			return conflicts[i].Type < conflicts[j].Type
+		}
+		return conflicts[i].Resource < conflicts[j].Resource
+	})
+
+	return conflicts
+}
+
+// hostPorts returns the host ports (as "<port>/<protocol>") bound by any
+// container of an object which runs pods. Pods using the host network bind
+// their container ports on the host as well.
+func hostPorts(obj render.Object) []string {
+	podSpec, ok := obj.PodSpec()
+	if !ok {
+		return nil
+	}
+	hostNetwork, _ := podSpec["hostNetwork"].(bool)
+
+	var ports []string
+	for _, container := range obj.Containers() {
+		containerPorts, _ := container["ports"].([]interface{})
+		for _, p := range containerPorts {
+			port, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			hostPort := port["hostPort"]
+			if hostPort == nil && hostNetwork {
+				hostPort = port["containerPort"]
+			}
+			if hostPort == nil {
+				continue
+			}
+			protocol, _ := port["protocol"].(string)
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			ports = append(ports, fmt.Sprintf("%v/%s", hostPort, protocol))
+		}
+	}
+
+	return ports
+}
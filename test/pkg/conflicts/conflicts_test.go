@@ -0,0 +1,57 @@
+package conflicts
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func daemonSet(addon, name string, hostPort int) render.Object {
+	return render.Object{
+		Addon: addon,
+		Kind:  "DaemonSet",
+		Name:  name,
+		Content: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name": name,
+								"ports": []interface{}{
+									map[string]interface{}{"containerPort": 8080, "hostPort": hostPort},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestDetect(t *testing.T) {
+	objects := []render.Object{
+		{Addon: "prometheus", Kind: "CustomResourceDefinition", Name: "servicemonitors.monitoring.coreos.com"},
+		{Addon: "kommander", Kind: "CustomResourceDefinition", Name: "servicemonitors.monitoring.coreos.com"},
+		{Addon: "kommander", Kind: "CustomResourceDefinition", Name: "clusters.kommander.mesosphere.io"},
+		{Addon: "dex", Kind: "ClusterRole", Name: "dex"},
+		{Addon: "dex", Kind: "ClusterRole", Name: "dex"},
+		{Addon: "traefik", Kind: "Namespace", Name: "kubeaddons"},
+		{Addon: "kommander", Kind: "Namespace", Name: "kubeaddons"},
+		daemonSet("metallb", "speaker", 7472),
+		daemonSet("traefik", "traefik", 7472),
+		daemonSet("traefik", "traefik-metrics", 9100),
+	}
+
+	expected := []Conflict{
+		{Type: CRD, Resource: "servicemonitors.monitoring.coreos.com", Addons: []string{"kommander", "prometheus"}},
+		{Type: HostPort, Resource: "7472/TCP", Addons: []string{"metallb", "traefik"}},
+		{Type: Namespace, Resource: "kubeaddons", Addons: []string{"kommander", "traefik"}},
+	}
+
+	if actual := Detect(objects); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
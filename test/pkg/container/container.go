@@ -0,0 +1,133 @@
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Mode is how the harness runs in a container, selected with
+// HARNESS_CONTAINER by the image of the harness (see Dockerfile).
+type Mode string
+
+const (
+	// None is a harness which runs on the host.
+	None Mode = ""
+
+	// DinD is a harness with a docker daemon of its own in its container,
+	// whose clusters and paths are those of the container.
+	DinD Mode = "dind"
+
+	// Socket is a harness using the docker daemon of the host through its
+	// mounted socket: the containers it starts (e.g. kind nodes) run next to
+	// its own, so the paths it mounts into them are paths of the host and
+	// the ports they publish are ports of the host.
+	Socket Mode = "socket"
+)
+
+// Current returns the mode of the harness.
+func Current() (Mode, error) {
+	switch mode := Mode(os.Getenv("HARNESS_CONTAINER")); mode {
+	case None, DinD, Socket:
+		return mode, nil
+	default:
+		return None, fmt.Errorf("HARNESS_CONTAINER must be %q or %q, not %q", DinD, Socket, mode)
+	}
+}
+
+// Mount is a directory of the host mounted into the container.
+type Mount struct {
+	Container string
+	Host      string
+}
+
+// ParseMounts parses the mounts of HOST_PATHS: comma separated
+// <container path>:<host path> pairs, like the volumes of docker run.
+func ParseMounts(s string) ([]Mount, error) {
+	var mounts []Mount
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || !filepath.IsAbs(parts[0]) || !filepath.IsAbs(parts[1]) {
+			return nil, fmt.Errorf("invalid mount %q, expected <absolute container path>:<absolute host path>", pair)
+		}
+		mounts = append(mounts, Mount{Container: filepath.Clean(parts[0]), Host: filepath.Clean(parts[1])})
+	}
+	return mounts, nil
+}
+
+// translate returns the path of the host of a path in the container, within
+// the longest mount containing it.
+func translate(path string, mounts []Mount) (string, bool) {
+	path = filepath.Clean(path)
+	var match *Mount
+	for i, m := range mounts {
+		if (path == m.Container || strings.HasPrefix(path, m.Container+"/")) && (match == nil || len(m.Container) > len(match.Container)) {
+			match = &mounts[i]
+		}
+	}
+	if match == nil {
+		return "", false
+	}
+	return match.Host + strings.TrimPrefix(path, match.Container), true
+}
+
+// HostPath returns the path the docker daemon knows a file of the harness by,
+// for mounting it into containers: in Socket mode the path of the host, by the
+// mounts of HOST_PATHS, otherwise the path itself. Files outside of the
+// mounts don't exist on the host and can't be mounted.
+func HostPath(path string) (string, error) {
+	mode, err := Current()
+	if err != nil || mode != Socket {
+		return path, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	mounts, err := ParseMounts(os.Getenv("HOST_PATHS"))
+	if err != nil {
+		return "", fmt.Errorf("HOST_PATHS: %w", err)
+	}
+	host, ok := translate(abs, mounts)
+	if !ok {
+		return "", fmt.Errorf("%s is not in a directory of the host mounted into the container (HOST_PATHS=%q), set TMPDIR to one", abs, os.Getenv("HOST_PATHS"))
+	}
+	return host, nil
+}
+
+// Connect connects the container of the harness to a docker network (e.g. the
+// network of the kind nodes), so it reaches the containers on it. The hostname
+// of a container is its ID unless it was overridden.
+func Connect(network string) error {
+	id, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("docker", "network", "connect", network, id).CombinedOutput()
+	if err != nil && !strings.Contains(string(out), "already exists") {
+		return fmt.Errorf("could not connect container %s to docker network %s: %w: %s", id, network, err, out)
+	}
+	return nil
+}
+
+// InternalKubeconfig connects the container of the harness to the docker
+// network of the nodes of a kind cluster and writes the kubeconfig of the
+// cluster with the address of its API server on that network, rather than the
+// port it publishes on the host, to path. This makes the cluster reachable
+// from a container using the docker daemon of the host.
+func InternalKubeconfig(cluster, network, path string) error {
+	if err := Connect(network); err != nil {
+		return err
+	}
+	kubeconfig, err := exec.Command("kind", "get", "kubeconfig", "--internal", "--name", cluster).Output()
+	if err != nil {
+		return fmt.Errorf("could not get the internal kubeconfig of cluster %s: %w", cluster, err)
+	}
+	return ioutil.WriteFile(path, kubeconfig, 0600)
+}
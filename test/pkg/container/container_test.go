@@ -0,0 +1,65 @@
+package container
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseMounts(t *testing.T) {
+	mounts, err := ParseMounts("/work:/home/ci/build/test, /tmp/work/:/var/lib/ci/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Mount{{Container: "/work", Host: "/home/ci/build/test"}, {Container: "/tmp/work", Host: "/var/lib/ci/tmp"}}
+	if !reflect.DeepEqual(expected, mounts) {
+		t.Errorf("expected %v, got %v", expected, mounts)
+	}
+
+	for _, invalid := range []string{"/work", "work:/home/ci", "/work:/home/ci:ro"} {
+		if _, err := ParseMounts(invalid); err == nil {
+			t.Errorf("expected mount %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	mounts := []Mount{{Container: "/work", Host: "/home/ci/build"}, {Container: "/work/reports", Host: "/var/reports"}}
+	for path, expected := range map[string]string{
+		"/work":                      "/home/ci/build",
+		"/work/tmp/squid.conf":       "/home/ci/build/tmp/squid.conf",
+		"/work/reports/report.json":  "/var/reports/report.json",
+		"/work/../work/kubeconfig":   "/home/ci/build/kubeconfig",
+		"/workspace/squid.conf":      "",
+		"/tmp/egress-123/squid.conf": "",
+	} {
+		actual, ok := translate(path, mounts)
+		if ok != (expected != "") || actual != expected {
+			t.Errorf("expected %s to translate to %q, got %q", path, expected, actual)
+		}
+	}
+}
+
+func TestHostPath(t *testing.T) {
+	defer os.Setenv("HARNESS_CONTAINER", os.Getenv("HARNESS_CONTAINER"))
+	defer os.Setenv("HOST_PATHS", os.Getenv("HOST_PATHS"))
+
+	os.Setenv("HARNESS_CONTAINER", "")
+	if path, err := HostPath("/tmp/squid.conf"); err != nil || path != "/tmp/squid.conf" {
+		t.Errorf("expected paths to be left as they are on the host, got %q (%v)", path, err)
+	}
+
+	os.Setenv("HARNESS_CONTAINER", string(Socket))
+	os.Setenv("HOST_PATHS", "/work:/home/ci/build")
+	if path, err := HostPath("/work/tmp/squid.conf"); err != nil || path != "/home/ci/build/tmp/squid.conf" {
+		t.Errorf("expected the path of the host, got %q (%v)", path, err)
+	}
+	if _, err := HostPath("/tmp/squid.conf"); err == nil {
+		t.Error("expected an error for a path which is not mounted from the host")
+	}
+
+	os.Setenv("HARNESS_CONTAINER", "podman")
+	if _, err := Current(); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
@@ -0,0 +1,133 @@
+package containerd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Cgroupfs is the default cgroup driver of kind nodes.
+	Cgroupfs = "cgroupfs"
+
+	// Systemd is the cgroup driver of nodes whose cgroups are managed by
+	// systemd, the default of most distributions running cgroups v2.
+	Systemd = "systemd"
+)
+
+// Config is the configuration of the container runtime of the nodes of a kind
+// cluster, and of the kubelet where both have to agree. The zero value is the
+// default configuration of kind.
+type Config struct {
+	// CgroupDriver is the cgroup driver of containerd and the kubelet,
+	// cgroupfs (the default) or systemd.
+	CgroupDriver string `yaml:"cgroupDriver"`
+
+	// RegistryAuth are the credentials containerd pulls from registries with.
+	RegistryAuth []RegistryAuth `yaml:"registryAuth"`
+
+	// ImageGC are the disk usage thresholds of the image garbage collection
+	// of the kubelet.
+	ImageGC *ImageGC `yaml:"imageGC"`
+}
+
+// RegistryAuth are the credentials of a registry, read from environment
+// variables so they are never part of a scenario.
+type RegistryAuth struct {
+	// Registry is the host of the registry, e.g. registry-1.docker.io.
+	Registry    string `yaml:"registry"`
+	UsernameEnv string `yaml:"usernameEnv"`
+	PasswordEnv string `yaml:"passwordEnv"`
+}
+
+// ImageGC are the thresholds of the image garbage collection of the kubelet,
+// in percent of the disk usage: images are removed once the usage exceeds
+// HighThresholdPercent until it is below LowThresholdPercent.
+type ImageGC struct {
+	HighThresholdPercent int `yaml:"highThresholdPercent"`
+	LowThresholdPercent  int `yaml:"lowThresholdPercent"`
+}
+
+// Validate returns an error when the configuration is invalid.
+func (c *Config) Validate() error {
+	switch c.CgroupDriver {
+	case "", Cgroupfs, Systemd:
+	default:
+		return fmt.Errorf("unknown cgroup driver %s, expected %s or %s", c.CgroupDriver, Cgroupfs, Systemd)
+	}
+	registries := make(map[string]bool, len(c.RegistryAuth))
+	for _, auth := range c.RegistryAuth {
+		if auth.Registry == "" || auth.UsernameEnv == "" || auth.PasswordEnv == "" {
+			return fmt.Errorf("registry auth requires a registry, a usernameEnv and a passwordEnv")
+		}
+		if registries[auth.Registry] {
+			return fmt.Errorf("registry %s has more than one registry auth", auth.Registry)
+		}
+		registries[auth.Registry] = true
+	}
+	if gc := c.ImageGC; gc != nil {
+		if gc.LowThresholdPercent < 0 || gc.HighThresholdPercent > 100 || gc.LowThresholdPercent >= gc.HighThresholdPercent {
+			return fmt.Errorf("image gc thresholds must satisfy 0 <= low (%d) < high (%d) <= 100", gc.LowThresholdPercent, gc.HighThresholdPercent)
+		}
+	}
+	return nil
+}
+
+// Default returns whether the configuration is the default of kind, which
+// every provider can run.
+func (c *Config) Default() bool {
+	return (c.CgroupDriver == "" || c.CgroupDriver == Cgroupfs) && len(c.RegistryAuth) == 0 && c.ImageGC == nil
+}
+
+// MissingCredentials returns the environment variables of registry
+// credentials which are not set.
+func (c *Config) MissingCredentials() []string {
+	var missing []string
+	for _, auth := range c.RegistryAuth {
+		for _, env := range []string{auth.UsernameEnv, auth.PasswordEnv} {
+			if os.Getenv(env) == "" {
+				missing = append(missing, env)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Patches returns the patches of the containerd configuration (TOML) and of
+// the kubeadm configuration (YAML) of the nodes which apply the
+// configuration, with the credentials of registries read from the
+// environment.
+func (c *Config) Patches() (containerd []string, kubeadm []string, err error) {
+	if missing := c.MissingCredentials(); len(missing) > 0 {
+		return nil, nil, fmt.Errorf("registry credentials %s are not set", strings.Join(missing, ", "))
+	}
+
+	if c.CgroupDriver == Systemd {
+		containerd = append(containerd, `[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc]
+  runtime_type = "io.containerd.runc.v2"
+[plugins."io.containerd.grpc.v1.cri".containerd.runtimes.runc.options]
+  SystemdCgroup = true
+`)
+	}
+	for _, auth := range c.RegistryAuth {
+		containerd = append(containerd, fmt.Sprintf(`[plugins."io.containerd.grpc.v1.cri".registry.configs.%s.auth]
+  username = %s
+  password = %s
+`, strconv.Quote(auth.Registry), strconv.Quote(os.Getenv(auth.UsernameEnv)), strconv.Quote(os.Getenv(auth.PasswordEnv))))
+	}
+
+	kubelet := new(strings.Builder)
+	if c.CgroupDriver == Systemd {
+		fmt.Fprintf(kubelet, "cgroupDriver: %s\n", Systemd)
+	}
+	if gc := c.ImageGC; gc != nil {
+		fmt.Fprintf(kubelet, "imageGCHighThresholdPercent: %d\nimageGCLowThresholdPercent: %d\n", gc.HighThresholdPercent, gc.LowThresholdPercent)
+	}
+	if kubelet.Len() > 0 {
+		kubeadm = append(kubeadm, "apiVersion: kubelet.config.k8s.io/v1beta1\nkind: KubeletConfiguration\n"+kubelet.String())
+	}
+	return containerd, kubeadm, nil
+}
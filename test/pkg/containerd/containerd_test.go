@@ -0,0 +1,86 @@
+package containerd
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Config{
+		CgroupDriver: Systemd,
+		RegistryAuth: []RegistryAuth{{Registry: "registry-1.docker.io", UsernameEnv: "USER", PasswordEnv: "PASSWORD"}},
+		ImageGC:      &ImageGC{HighThresholdPercent: 70, LowThresholdPercent: 50},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Error(err)
+	}
+	if valid.Default() {
+		t.Error("expected a configuration which isn't the default")
+	}
+	if !(&Config{CgroupDriver: Cgroupfs}).Default() {
+		t.Error("expected the cgroupfs driver to be the default")
+	}
+
+	for _, invalid := range []Config{
+		{CgroupDriver: "cgroupv2"},
+		{RegistryAuth: []RegistryAuth{{Registry: "quay.io", UsernameEnv: "USER"}}},
+		{RegistryAuth: []RegistryAuth{
+			{Registry: "quay.io", UsernameEnv: "USER", PasswordEnv: "PASSWORD"},
+			{Registry: "quay.io", UsernameEnv: "USER", PasswordEnv: "PASSWORD"},
+		}},
+		{ImageGC: &ImageGC{HighThresholdPercent: 50, LowThresholdPercent: 70}},
+		{ImageGC: &ImageGC{HighThresholdPercent: 101, LowThresholdPercent: 70}},
+	} {
+		invalid := invalid
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", invalid)
+		}
+	}
+}
+
+func TestPatches(t *testing.T) {
+	c := Config{
+		CgroupDriver: Systemd,
+		RegistryAuth: []RegistryAuth{{Registry: "registry-1.docker.io", UsernameEnv: "CONTAINERD_TEST_USER", PasswordEnv: "CONTAINERD_TEST_PASSWORD"}},
+		ImageGC:      &ImageGC{HighThresholdPercent: 70, LowThresholdPercent: 50},
+	}
+	if _, _, err := c.Patches(); err == nil || !strings.Contains(err.Error(), "CONTAINERD_TEST_PASSWORD, CONTAINERD_TEST_USER") {
+		t.Errorf("expected an error for the missing credentials, got %v", err)
+	}
+
+	os.Setenv("CONTAINERD_TEST_USER", "ci")
+	os.Setenv("CONTAINERD_TEST_PASSWORD", `se"cret`)
+	defer os.Unsetenv("CONTAINERD_TEST_USER")
+	defer os.Unsetenv("CONTAINERD_TEST_PASSWORD")
+
+	containerd, kubeadm, err := c.Patches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containerd) != 2 || !strings.Contains(containerd[0], "SystemdCgroup = true") {
+		t.Errorf("expected the systemd cgroup driver to be patched, got %v", containerd)
+	}
+	expected := `[plugins."io.containerd.grpc.v1.cri".registry.configs."registry-1.docker.io".auth]
+  username = "ci"
+  password = "se\"cret"
+`
+	if len(containerd) == 2 && containerd[1] != expected {
+		t.Errorf("expected registry auth\n%s\ngot\n%s", expected, containerd[1])
+	}
+	expectedKubeadm := []string{`apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+cgroupDriver: systemd
+imageGCHighThresholdPercent: 70
+imageGCLowThresholdPercent: 50
+`}
+	if !reflect.DeepEqual(expectedKubeadm, kubeadm) {
+		t.Errorf("expected kubeadm patches %v, got %v", expectedKubeadm, kubeadm)
+	}
+
+	containerd, kubeadm, err = (&Config{}).Patches()
+	if err != nil || len(containerd) != 0 || len(kubeadm) != 0 {
+		t.Errorf("expected no patches for the default configuration, got %v %v %v", containerd, kubeadm, err)
+	}
+}
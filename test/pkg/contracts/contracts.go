@@ -0,0 +1,198 @@
+package contracts
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// Resource is a resource an addon provides to other addons. Exactly one of
+// its fields is set: services and secrets as <namespace>/<name>, CRDs by their
+// name.
+type Resource struct {
+	Service string `yaml:"service,omitempty"`
+	CRD     string `yaml:"crd,omitempty"`
+	Secret  string `yaml:"secret,omitempty"`
+}
+
+// String returns the resource as <kind>/<name>, e.g. service/kubeaddons/dex-kubeaddons.
+func (r Resource) String() string {
+	kind, name := r.kind()
+	return kind + "/" + name
+}
+
+func (r Resource) kind() (string, string) {
+	switch {
+	case r.Service != "":
+		return "service", r.Service
+	case r.CRD != "":
+		return "crd", r.CRD
+	default:
+		return "secret", r.Secret
+	}
+}
+
+func (r Resource) validate() error {
+	set := 0
+	for _, field := range []string{r.Service, r.CRD, r.Secret} {
+		if field != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("expected exactly one of service, crd or secret, got %d", set)
+	}
+	if kind, name := r.kind(); kind != "crd" && len(strings.Split(name, "/")) != 2 {
+		return fmt.Errorf("expected %s %q to be <namespace>/<name>", kind, name)
+	}
+	return nil
+}
+
+// Requirement is a resource an addon requires from another addon.
+type Requirement struct {
+	Addon    string `yaml:"addon"`
+	Resource `yaml:",inline"`
+}
+
+// Contract declares what an addon provides to and requires from other addons.
+type Contract struct {
+	Addon    string        `yaml:"-"`
+	Provides []Resource    `yaml:"provides"`
+	Requires []Requirement `yaml:"requires"`
+}
+
+// Contracts are the contracts of addons, by addon name.
+type Contracts map[string]Contract
+
+// Load reads the contracts in a directory, where each <addon>.yaml file
+// contains the contract of that addon.
+func Load(dir string) (Contracts, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := make(Contracts, len(files))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		contract := Contract{Addon: strings.TrimSuffix(filepath.Base(file), ".yaml")}
+		if err := yaml.UnmarshalStrict(b, &contract); err != nil {
+			return nil, fmt.Errorf("could not parse contract %s: %w", file, err)
+		}
+		for _, resource := range contract.Provides {
+			if err := resource.validate(); err != nil {
+				return nil, fmt.Errorf("contract %s: invalid provided resource: %w", file, err)
+			}
+		}
+		for _, requirement := range contract.Requires {
+			if requirement.Addon == "" {
+				return nil, fmt.Errorf("contract %s: requirement %s has no addon", file, requirement.Resource)
+			}
+			if err := requirement.Resource.validate(); err != nil {
+				return nil, fmt.Errorf("contract %s: invalid requirement of addon %s: %w", file, requirement.Addon, err)
+			}
+		}
+		contracts[contract.Addon] = contract
+	}
+
+	return contracts, nil
+}
+
+// Edge is a requirement of an addon on another addon.
+type Edge struct {
+	Addon string
+	Requirement
+}
+
+func (e Edge) String() string {
+	return fmt.Sprintf("%s requires %s from %s", e.Addon, e.Resource, e.Requirement.Addon)
+}
+
+// Validate returns an error for every requirement which the required addon
+// does not declare to provide.
+func (c Contracts) Validate() []error {
+	var errs []error
+	for _, edge := range c.Edges(nil) {
+		provider, ok := c[edge.Requirement.Addon]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s, which has no contract", edge))
+			continue
+		}
+		if !provides(provider, edge.Resource) {
+			errs = append(errs, fmt.Errorf("%s, which its contract does not provide", edge))
+		}
+	}
+	return errs
+}
+
+func provides(contract Contract, resource Resource) bool {
+	for _, provided := range contract.Provides {
+		if provided == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// Edges returns the requirements of the given addons (all addons when nil),
+// sorted by addon.
+func (c Contracts) Edges(addons []string) []Edge {
+	if addons == nil {
+		for addon := range c {
+			addons = append(addons, addon)
+		}
+	}
+	sorted := append([]string(nil), addons...)
+	sort.Strings(sorted)
+
+	var edges []Edge
+	for _, addon := range sorted {
+		for _, requirement := range c[addon].Requires {
+			edges = append(edges, Edge{Addon: addon, Requirement: requirement})
+		}
+	}
+	return edges
+}
+
+// Verify returns the edges whose resources don't exist according to exists.
+func Verify(edges []Edge, exists func(Resource) (bool, error)) ([]Edge, error) {
+	var unsatisfied []Edge
+	for _, edge := range edges {
+		ok, err := exists(edge.Resource)
+		if err != nil {
+			return nil, fmt.Errorf("could not verify that %s: %w", edge, err)
+		}
+		if !ok {
+			unsatisfied = append(unsatisfied, edge)
+		}
+	}
+	return unsatisfied, nil
+}
+
+// Exists returns whether a resource exists in the cluster.
+func Exists(k kubectl.Client, resource Resource) (bool, error) {
+	kind, name := resource.kind()
+	args := []string{"get", kind}
+	if kind == "crd" {
+		args = append(args, name)
+	} else {
+		parts := strings.Split(name, "/")
+		args = append(args, parts[1], "--namespace", parts[0])
+	}
+
+	out, err := k.Output(append(args, "--ignore-not-found", "--output", "name")...)
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) != "", nil
+}
@@ -0,0 +1,76 @@
+package contracts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contracts-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("dex.yaml", "provides:\n- service: kubeaddons/dex-kubeaddons\n")
+	write("kommander.yaml", "requires:\n- addon: dex\n  service: kubeaddons/dex-kubeaddons\n")
+
+	contracts, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := contracts.Edges([]string{"kommander"})
+	if len(edges) != 1 || edges[0].String() != "kommander requires service/kubeaddons/dex-kubeaddons from dex" {
+		t.Errorf("unexpected edges: %v", edges)
+	}
+
+	write("traefik.yaml", "provides:\n- service: traefik-kubeaddons\n")
+	if _, err := Load(dir); err == nil {
+		t.Error("expected an error for a service without a namespace")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	contracts := Contracts{
+		"dex": {Addon: "dex", Provides: []Resource{{Service: "kubeaddons/dex-kubeaddons"}}},
+		"kommander": {Addon: "kommander", Requires: []Requirement{
+			{Addon: "dex", Resource: Resource{Service: "kubeaddons/dex-kubeaddons"}},
+			{Addon: "dex", Resource: Resource{Secret: "kubeaddons/dex-client"}},
+			{Addon: "traefik", Resource: Resource{Service: "kubeaddons/traefik-kubeaddons"}},
+		}},
+	}
+
+	errs := contracts.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Error() != "kommander requires secret/kubeaddons/dex-client from dex, which its contract does not provide" {
+		t.Errorf("unexpected error: %s", errs[0])
+	}
+	if errs[1].Error() != "kommander requires service/kubeaddons/traefik-kubeaddons from traefik, which has no contract" {
+		t.Errorf("unexpected error: %s", errs[1])
+	}
+}
+
+func TestVerify(t *testing.T) {
+	edges := []Edge{
+		{Addon: "kommander", Requirement: Requirement{Addon: "dex", Resource: Resource{Service: "kubeaddons/dex-kubeaddons"}}},
+		{Addon: "kommander", Requirement: Requirement{Addon: "cert-manager", Resource: Resource{CRD: "clusterissuers.certmanager.k8s.io"}}},
+	}
+	exists := func(r Resource) (bool, error) { return r.CRD != "", nil }
+
+	unsatisfied, err := Verify(edges, exists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unsatisfied) != 1 || unsatisfied[0].Resource.Service != "kubeaddons/dex-kubeaddons" {
+		t.Errorf("expected the dex service to be unsatisfied, got %v", unsatisfied)
+	}
+}
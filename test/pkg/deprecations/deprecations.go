@@ -0,0 +1,134 @@
+package deprecations
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// API is a kind of a deprecated API version, with the kubernetes versions
+// which deprecated and removed it.
+type API struct {
+	APIVersion  string
+	Kind        string
+	Deprecated  semver.Version
+	Removed     semver.Version
+	Replacement string
+}
+
+func api(apiVersion, deprecated, removed, replacement string, kinds ...string) []API {
+	apis := make([]API, 0, len(kinds))
+	for _, kind := range kinds {
+		apis = append(apis, API{
+			APIVersion:  apiVersion,
+			Kind:        kind,
+			Deprecated:  semver.MustParse(deprecated),
+			Removed:     semver.MustParse(removed),
+			Replacement: replacement,
+		})
+	}
+	return apis
+}
+
+// APIs are the deprecated API versions of kubernetes, see
+// https://kubernetes.io/docs/reference/using-api/deprecation-guide/.
+var APIs = concat(
+	api("extensions/v1beta1", "1.8.0", "1.16.0", "apps/v1", "Deployment", "DaemonSet", "ReplicaSet"),
+	api("extensions/v1beta1", "1.9.0", "1.16.0", "networking.k8s.io/v1", "NetworkPolicy"),
+	api("extensions/v1beta1", "1.11.0", "1.16.0", "policy/v1beta1", "PodSecurityPolicy"),
+	api("extensions/v1beta1", "1.14.0", "1.22.0", "networking.k8s.io/v1", "Ingress"),
+	api("apps/v1beta1", "1.9.0", "1.16.0", "apps/v1", "Deployment", "StatefulSet", "ReplicaSet"),
+	api("apps/v1beta2", "1.9.0", "1.16.0", "apps/v1", "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet"),
+	api("networking.k8s.io/v1beta1", "1.19.0", "1.22.0", "networking.k8s.io/v1", "Ingress", "IngressClass"),
+	api("apiextensions.k8s.io/v1beta1", "1.16.0", "1.22.0", "apiextensions.k8s.io/v1", "CustomResourceDefinition"),
+	api("admissionregistration.k8s.io/v1beta1", "1.16.0", "1.22.0", "admissionregistration.k8s.io/v1", "MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"),
+	api("apiregistration.k8s.io/v1beta1", "1.19.0", "1.22.0", "apiregistration.k8s.io/v1", "APIService"),
+	api("rbac.authorization.k8s.io/v1alpha1", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1", "ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"),
+	api("rbac.authorization.k8s.io/v1beta1", "1.17.0", "1.22.0", "rbac.authorization.k8s.io/v1", "ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding"),
+	api("scheduling.k8s.io/v1alpha1", "1.14.0", "1.17.0", "scheduling.k8s.io/v1", "PriorityClass"),
+	api("scheduling.k8s.io/v1beta1", "1.14.0", "1.22.0", "scheduling.k8s.io/v1", "PriorityClass"),
+	api("storage.k8s.io/v1beta1", "1.19.0", "1.22.0", "storage.k8s.io/v1", "CSIDriver", "CSINode", "StorageClass", "VolumeAttachment"),
+	api("certificates.k8s.io/v1beta1", "1.19.0", "1.22.0", "certificates.k8s.io/v1", "CertificateSigningRequest"),
+	api("coordination.k8s.io/v1beta1", "1.19.0", "1.22.0", "coordination.k8s.io/v1", "Lease"),
+	api("batch/v1beta1", "1.21.0", "1.25.0", "batch/v1", "CronJob"),
+	api("policy/v1beta1", "1.21.0", "1.25.0", "policy/v1", "PodDisruptionBudget"),
+	api("policy/v1beta1", "1.21.0", "1.25.0", "pod security admission", "PodSecurityPolicy"),
+	api("autoscaling/v2beta1", "1.22.0", "1.25.0", "autoscaling/v2", "HorizontalPodAutoscaler"),
+	api("autoscaling/v2beta2", "1.23.0", "1.26.0", "autoscaling/v2", "HorizontalPodAutoscaler"),
+	api("discovery.k8s.io/v1beta1", "1.21.0", "1.25.0", "discovery.k8s.io/v1", "EndpointSlice"),
+	api("events.k8s.io/v1beta1", "1.19.0", "1.25.0", "events.k8s.io/v1", "Event"),
+	api("node.k8s.io/v1beta1", "1.20.0", "1.25.0", "node.k8s.io/v1", "RuntimeClass"),
+)
+
+func concat(lists ...[]API) []API {
+	var apis []API
+	for _, list := range lists {
+		apis = append(apis, list...)
+	}
+	return apis
+}
+
+// Finding is a rendered object of an addon using a deprecated API version.
+type Finding struct {
+	Addon     string
+	Kind      string
+	Namespace string
+	Name      string
+	API       API
+}
+
+// RemovedIn returns whether the API version of the object is removed in a
+// kubernetes version.
+func (f Finding) RemovedIn(version semver.Version) bool {
+	return version.GTE(f.API.Removed)
+}
+
+// DeprecatedIn returns whether the API version of the object is deprecated in
+// a kubernetes version.
+func (f Finding) DeprecatedIn(version semver.Version) bool {
+	return version.GTE(f.API.Deprecated)
+}
+
+func (f Finding) String() string {
+	ref := f.Kind + "/" + f.Name
+	if f.Namespace != "" {
+		ref = f.Kind + "/" + f.Namespace + "/" + f.Name
+	}
+	return fmt.Sprintf("%s of addon %s uses %s, deprecated in kubernetes %d.%d and removed in %d.%d (use %s)",
+		ref, f.Addon, f.API.APIVersion, f.API.Deprecated.Major, f.API.Deprecated.Minor, f.API.Removed.Major, f.API.Removed.Minor, f.API.Replacement)
+}
+
+// Find returns the rendered objects which use deprecated API versions, sorted
+// by addon, kind, namespace and name.
+func Find(objects []render.Object) []Finding {
+	deprecated := make(map[[2]string]API, len(APIs))
+	for _, a := range APIs {
+		deprecated[[2]string{a.APIVersion, a.Kind}] = a
+	}
+
+	var findings []Finding
+	for _, obj := range objects {
+		a, ok := deprecated[[2]string{obj.APIVersion, obj.Kind}]
+		if !ok {
+			continue
+		}
+		findings = append(findings, Finding{Addon: obj.Addon, Kind: obj.Kind, Namespace: obj.Namespace, Name: obj.Name, API: a})
+	}
+	sort.Slice(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Addon != b.Addon {
+			return a.Addon < b.Addon
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return findings
+}
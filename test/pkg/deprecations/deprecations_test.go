@@ -0,0 +1,40 @@
+package deprecations
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestFind(t *testing.T) {
+	objects := []render.Object{
+		{Addon: "traefik", APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "kubeaddons", Name: "traefik-dashboard"},
+		{Addon: "traefik", APIVersion: "apps/v1", Kind: "Deployment", Namespace: "kubeaddons", Name: "traefik"},
+		{Addon: "dex", APIVersion: "rbac.authorization.k8s.io/v1beta1", Kind: "ClusterRole", Name: "dex"},
+		{Addon: "dex", APIVersion: "apiextensions.k8s.io/v1beta1", Kind: "CustomResourceDefinition", Name: "authcodes.dex.coreos.com"},
+	}
+
+	findings := Find(objects)
+	if len(findings) != 3 {
+		t.Fatalf("expected 3 findings, got %v", findings)
+	}
+	if f := findings[0]; f.Addon != "dex" || f.Kind != "ClusterRole" {
+		t.Errorf("expected the findings to be sorted by addon and kind, got %v", findings)
+	}
+
+	ingress := findings[2]
+	expected := "Ingress/kubeaddons/traefik-dashboard of addon traefik uses extensions/v1beta1, deprecated in kubernetes 1.14 and removed in 1.22 (use networking.k8s.io/v1)"
+	if ingress.String() != expected {
+		t.Errorf("expected %q, got %q", expected, ingress)
+	}
+	for version, removed := range map[string]bool{"1.16.4": false, "1.21.9": false, "1.22.0": true, "1.23.1": true} {
+		if ingress.RemovedIn(semver.MustParse(version)) != removed {
+			t.Errorf("expected the ingress removed in %s: %t", version, removed)
+		}
+	}
+	if ingress.DeprecatedIn(semver.MustParse("1.13.0")) || !ingress.DeprecatedIn(semver.MustParse("1.16.4")) {
+		t.Errorf("expected the ingress to be deprecated from 1.14 on")
+	}
+}
@@ -0,0 +1,216 @@
+package drain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const interval = 5 * time.Second
+
+// controlPlaneLabels label the control plane nodes, which are not drained.
+var controlPlaneLabels = []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"}
+
+// Pod is a pod of an addon.
+type Pod struct {
+	Namespace string
+	Name      string
+	Node      string
+
+	// Owner is the kind of the controller of the pod, empty for bare pods.
+	Owner string
+
+	Phase string
+	Ready bool
+}
+
+func (p Pod) String() string {
+	return p.Namespace + "/" + p.Name
+}
+
+// Rescheduled returns whether a pod is replaced on another node when its node
+// is drained: pods of DaemonSets stay on their node, bare pods are deleted
+// and completed pods are not replaced.
+func (p Pod) Rescheduled() bool {
+	return p.Owner != "" && p.Owner != "DaemonSet" && p.Phase != "Succeeded" && p.Phase != "Failed"
+}
+
+// Workers returns the sorted names of the worker nodes of the cluster of a
+// kubectl client.
+func Workers(k kubectl.Client) ([]string, error) {
+	out, err := k.Output("get", "nodes", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	return decodeWorkers(out)
+}
+
+func decodeWorkers(b []byte) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("could not decode nodes: %w", err)
+	}
+
+	var workers []string
+nodes:
+	for _, node := range list.Items {
+		for _, label := range controlPlaneLabels {
+			if _, ok := node.Metadata.Labels[label]; ok {
+				continue nodes
+			}
+		}
+		workers = append(workers, node.Metadata.Name)
+	}
+	sort.Strings(workers)
+	return workers, nil
+}
+
+// Pods returns the pods in the namespaces.
+func Pods(k kubectl.Client, namespaces []string) ([]Pod, error) {
+	var pods []Pod
+	for _, namespace := range namespaces {
+		out, err := k.Output("get", "pods", "--namespace", namespace, "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+		found, err := decodePods(out)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, found...)
+	}
+	return pods, nil
+}
+
+func decodePods(b []byte) ([]Pod, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace       string `json:"namespace"`
+				Name            string `json:"name"`
+				OwnerReferences []struct {
+					Kind       string `json:"kind"`
+					Controller bool   `json:"controller"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName string `json:"nodeName"`
+			} `json:"spec"`
+			Status struct {
+				Phase      string `json:"phase"`
+				Conditions []struct {
+					Type   string `json:"type"`
+					Status string `json:"status"`
+				} `json:"conditions"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("could not decode pods: %w", err)
+	}
+
+	pods := make([]Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pod := Pod{
+			Namespace: item.Metadata.Namespace,
+			Name:      item.Metadata.Name,
+			Node:      item.Spec.NodeName,
+			Phase:     item.Status.Phase,
+		}
+		for _, owner := range item.Metadata.OwnerReferences {
+			if owner.Controller {
+				pod.Owner = owner.Kind
+			}
+		}
+		for _, condition := range item.Status.Conditions {
+			if condition.Type == "Ready" {
+				pod.Ready = condition.Status == "True"
+			}
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// Busiest returns the worker running the most pods which are rescheduled when
+// it is drained, the first of the workers when there are none.
+func Busiest(workers []string, pods []Pod) string {
+	counts := make(map[string]int)
+	for _, pod := range pods {
+		if pod.Rescheduled() {
+			counts[pod.Node]++
+		}
+	}
+	busiest := ""
+	for _, worker := range workers {
+		if busiest == "" || counts[worker] > counts[busiest] {
+			busiest = worker
+		}
+	}
+	return busiest
+}
+
+// Unscheduled returns the pods which are not yet rescheduled after a node was
+// drained: pods which are still on the node, and replacements which are not
+// ready.
+func Unscheduled(pods []Pod, node string) []string {
+	var unscheduled []string
+	for _, pod := range pods {
+		if !pod.Rescheduled() {
+			continue
+		}
+		switch {
+		case pod.Node == node:
+			unscheduled = append(unscheduled, fmt.Sprintf("%s is still on node %s", pod, node))
+		case pod.Node == "":
+			unscheduled = append(unscheduled, fmt.Sprintf("%s is not scheduled", pod))
+		case !pod.Ready:
+			unscheduled = append(unscheduled, fmt.Sprintf("%s is not ready on node %s", pod, pod.Node))
+		}
+	}
+	sort.Strings(unscheduled)
+	return unscheduled
+}
+
+// Drain cordons a node and evicts its pods, respecting their disruption
+// budgets, as upgrades of the nodes of a cluster do. It fails when the pods
+// can't be evicted within timeout, e.g. because a disruption budget doesn't
+// allow it.
+func Drain(k kubectl.Client, node string, timeout time.Duration) error {
+	return k.Run("drain", node, "--ignore-daemonsets", "--delete-local-data", "--force", "--timeout", timeout.String())
+}
+
+// Uncordon makes a drained node schedulable again.
+func Uncordon(k kubectl.Client, node string) error {
+	return k.Run("uncordon", node)
+}
+
+// WaitRescheduled waits up to timeout for the pods in the namespaces to be
+// rescheduled after a node was drained.
+func WaitRescheduled(k kubectl.Client, namespaces []string, node string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, err := Pods(k, namespaces)
+		if err != nil {
+			return err
+		}
+		unscheduled := Unscheduled(pods, node)
+		if len(unscheduled) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("pods were not rescheduled within %s after node %s was drained: %v", timeout, node, unscheduled)
+		}
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,80 @@
+package drain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeWorkers(t *testing.T) {
+	nodes := `{"items": [
+		{"metadata": {"name": "kind-worker2", "labels": {}}},
+		{"metadata": {"name": "kind-control-plane", "labels": {"node-role.kubernetes.io/master": ""}}},
+		{"metadata": {"name": "kind-worker", "labels": {"kubernetes.io/os": "linux"}}},
+		{"metadata": {"name": "kind-control-plane2", "labels": {"node-role.kubernetes.io/control-plane": ""}}}
+	]}`
+	workers, err := decodeWorkers([]byte(nodes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kind-worker", "kind-worker2"}; !reflect.DeepEqual(workers, expected) {
+		t.Errorf("expected workers %v, got %v", expected, workers)
+	}
+}
+
+func TestDecodePods(t *testing.T) {
+	pods := `{"items": [
+		{
+			"metadata": {"namespace": "kubeaddons", "name": "traefik-1", "ownerReferences": [{"kind": "ReplicaSet", "controller": true}]},
+			"spec": {"nodeName": "kind-worker"},
+			"status": {"phase": "Running", "conditions": [{"type": "PodScheduled", "status": "True"}, {"type": "Ready", "status": "True"}]}
+		},
+		{
+			"metadata": {"namespace": "kubeaddons", "name": "fluent-bit-1", "ownerReferences": [{"kind": "DaemonSet", "controller": true}]},
+			"spec": {"nodeName": "kind-worker"},
+			"status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "False"}]}
+		},
+		{
+			"metadata": {"namespace": "kommander", "name": "bare"},
+			"status": {"phase": "Pending"}
+		}
+	]}`
+	decoded, err := decodePods([]byte(pods))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Pod{
+		{Namespace: "kubeaddons", Name: "traefik-1", Node: "kind-worker", Owner: "ReplicaSet", Phase: "Running", Ready: true},
+		{Namespace: "kubeaddons", Name: "fluent-bit-1", Node: "kind-worker", Owner: "DaemonSet", Phase: "Running"},
+		{Namespace: "kommander", Name: "bare", Phase: "Pending"},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected pods %+v, got %+v", expected, decoded)
+	}
+}
+
+func TestUnscheduled(t *testing.T) {
+	pods := []Pod{
+		{Namespace: "kubeaddons", Name: "traefik-1", Node: "kind-worker2", Owner: "ReplicaSet", Phase: "Running", Ready: true},
+		{Namespace: "kubeaddons", Name: "dex-1", Node: "kind-worker", Owner: "ReplicaSet", Phase: "Running", Ready: true},
+		{Namespace: "kubeaddons", Name: "prometheus-0", Owner: "StatefulSet", Phase: "Pending"},
+		{Namespace: "kubeaddons", Name: "grafana-1", Node: "kind-worker3", Owner: "ReplicaSet", Phase: "Running"},
+		{Namespace: "kubeaddons", Name: "fluent-bit-1", Node: "kind-worker", Owner: "DaemonSet", Phase: "Running", Ready: true},
+		{Namespace: "kubeaddons", Name: "hook-1", Node: "kind-worker", Owner: "Job", Phase: "Succeeded"},
+		{Namespace: "kommander", Name: "bare", Node: "kind-worker", Phase: "Running", Ready: true},
+	}
+	expected := []string{
+		"kubeaddons/dex-1 is still on node kind-worker",
+		"kubeaddons/grafana-1 is not ready on node kind-worker3",
+		"kubeaddons/prometheus-0 is not scheduled",
+	}
+	if unscheduled := Unscheduled(pods, "kind-worker"); !reflect.DeepEqual(unscheduled, expected) {
+		t.Errorf("expected unscheduled pods %q, got %q", expected, unscheduled)
+	}
+
+	if busiest := Busiest([]string{"kind-worker", "kind-worker2", "kind-worker3"}, pods); busiest != "kind-worker" {
+		t.Errorf("expected kind-worker to be the busiest worker, got %s", busiest)
+	}
+	if busiest := Busiest([]string{"kind-worker4", "kind-worker5"}, pods); busiest != "kind-worker4" {
+		t.Errorf("expected the first worker without pods to be the busiest, got %s", busiest)
+	}
+}
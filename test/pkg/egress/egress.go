@@ -0,0 +1,285 @@
+package egress
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/container"
+)
+
+const (
+	// image is the image of the proxy, squid.
+	image = "ubuntu/squid:4.10-20.04_beta"
+
+	// chain is the iptables chain on the nodes which drops egress traffic
+	// other than to the proxy and the networks of the cluster.
+	chain = "KOMMANDER-EGRESS"
+)
+
+// ClusterNetworks are the pod and service networks of kind clusters, which
+// aren't proxied.
+var ClusterNetworks = []string{"10.244.0.0/16", "10.96.0.0/12"}
+
+// Config is the configuration of the egress proxy (see egress.yaml): the
+// domains it allows and the values overrides which configure addons to use
+// it.
+type Config struct {
+	// Allow are the domains the proxy allows, where a leading dot allows its
+	// subdomains too (e.g. .docker.io).
+	Allow []string `yaml:"allow"`
+
+	// Overrides are the values overrides of addons, text/templates rendered
+	// with Values.
+	Overrides map[string]string `yaml:"overrides"`
+}
+
+// Values are the values the overrides are rendered with.
+type Values struct {
+	// Proxy is the URL of the proxy, e.g. http://172.17.0.1:3128.
+	Proxy string
+
+	// NoProxy are the comma separated hosts and networks which aren't
+	// proxied.
+	NoProxy string
+}
+
+// Load reads the configuration of the egress proxy from a file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse egress configuration %s: %w", path, err)
+	}
+	if len(c.Allow) == 0 {
+		return nil, fmt.Errorf("egress configuration %s allows no domains", path)
+	}
+	return c, nil
+}
+
+// Render renders the overrides of the addons with values.
+func (c *Config) Render(v Values) (map[string]string, error) {
+	rendered := make(map[string]string, len(c.Overrides))
+	for addon, text := range c.Overrides {
+		tmpl, err := template.New(addon).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid egress override of addon %s: %w", addon, err)
+		}
+		b := new(bytes.Buffer)
+		if err := tmpl.Execute(b, v); err != nil {
+			return nil, fmt.Errorf("could not render the egress override of addon %s: %w", addon, err)
+		}
+		rendered[addon] = b.String()
+	}
+	return rendered, nil
+}
+
+// NewValues returns the values of a proxy listening on a port of the gateway
+// of a docker network with the given subnets.
+func NewValues(gateway string, port int, subnets []string) Values {
+	noProxy := append([]string{"localhost", "127.0.0.1", ".svc", ".cluster.local"}, ClusterNetworks...)
+	return Values{
+		Proxy:   fmt.Sprintf("http://%s:%d", gateway, port),
+		NoProxy: strings.Join(append(noProxy, subnets...), ","),
+	}
+}
+
+// Gateway returns the gateway of a docker network, which is the address of
+// the docker host on that network.
+func Gateway(network string) (string, error) {
+	out, err := exec.Command("docker", "network", "inspect", network, "--format", "{{range .IPAM.Config}}{{.Gateway}} {{end}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not inspect docker network %s: %w", network, err)
+	}
+	for _, gateway := range strings.Fields(string(out)) {
+		if !strings.Contains(gateway, ":") {
+			return gateway, nil
+		}
+	}
+	return "", fmt.Errorf("docker network %s has no IPv4 gateway", network)
+}
+
+// Proxy is a squid proxy in a docker container which publishes its port on
+// the docker host.
+type Proxy struct {
+	Name string
+	dir  string
+}
+
+// Start starts a proxy which allows the domains on a port of the docker host,
+// labeling its container with labels (e.g. for the docker garbage collection).
+// An existing container of the same name is replaced.
+func Start(name string, port int, allow []string, labels map[string]string) (*Proxy, error) {
+	dir, err := ioutil.TempDir("", name+"-")
+	if err != nil {
+		return nil, err
+	}
+	p := &Proxy{Name: name, dir: dir}
+	if err := ioutil.WriteFile(filepath.Join(dir, "squid.conf"), []byte(squidConfig(allow)), 0644); err != nil {
+		p.Stop()
+		return nil, err
+	}
+
+	config, err := container.HostPath(filepath.Join(dir, "squid.conf"))
+	if err != nil {
+		p.Stop()
+		return nil, err
+	}
+
+	exec.Command("docker", "rm", "--force", name).Run()
+	args := []string{"run", "--detach", "--name", name,
+		"--publish", fmt.Sprintf("%d:3128", port),
+		"--volume", config + ":/etc/squid/squid.conf:ro",
+	}
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--label", key+"="+labels[key])
+	}
+	if out, err := exec.Command("docker", append(args, image)...).CombinedOutput(); err != nil {
+		p.Stop()
+		return nil, fmt.Errorf("could not start proxy %s: %w: %s", name, err, out)
+	}
+	return p, nil
+}
+
+// squidConfig returns the configuration of squid allowing only the domains,
+// logging every request to stdout.
+func squidConfig(allow []string) string {
+	return fmt.Sprintf(`http_port 3128
+acl allowed dstdomain %s
+http_access allow allowed
+http_access deny all
+access_log stdio:/dev/stdout squid
+cache deny all
+`, strings.Join(allow, " "))
+}
+
+// Stop removes the container of the proxy.
+func (p *Proxy) Stop() error {
+	defer os.RemoveAll(p.dir)
+	if out, err := exec.Command("docker", "rm", "--force", p.Name).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not remove proxy %s: %w: %s", p.Name, err, out)
+	}
+	return nil
+}
+
+// Denied returns the hosts the proxy denied requests to, with the number of
+// requests.
+func (p *Proxy) Denied() (map[string]int, error) {
+	out, err := exec.Command("docker", "logs", p.Name).Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not read the log of proxy %s: %w", p.Name, err)
+	}
+	return denied(out), nil
+}
+
+// denied returns the hosts of the denied requests in the access log of squid,
+// whose lines are e.g.
+//
+//	1589198713.531      0 10.244.0.5 TCP_DENIED/403 3950 CONNECT example.com:443 - HIER_NONE/- text/html
+func denied(log []byte) map[string]int {
+	hosts := make(map[string]int)
+	for _, line := range strings.Split(string(log), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || !strings.HasPrefix(fields[3], "TCP_DENIED/") {
+			continue
+		}
+		host := fields[6]
+		if strings.Contains(host, "://") {
+			host = strings.SplitN(host, "://", 2)[1]
+		}
+		host = strings.SplitN(host, "/", 2)[0]
+		hosts[host]++
+	}
+	return hosts
+}
+
+// Restrict makes a node (a docker container named after it) send its egress
+// traffic through the proxy: containerd pulls images through the proxy, and
+// TCP and UDP traffic to destinations other than the networks (the docker
+// network, which includes the proxy, and those of the cluster), loopback and
+// DNS is dropped.
+func Restrict(node string, v Values, networks []string) error {
+	script := new(strings.Builder)
+	fmt.Fprintf(script, "set -e\n")
+	fmt.Fprintf(script, "mkdir -p /etc/systemd/system/containerd.service.d\n")
+	fmt.Fprintf(script, "cat > /etc/systemd/system/containerd.service.d/http-proxy.conf <<EOF\n[Service]\nEnvironment=\"HTTP_PROXY=%s\" \"HTTPS_PROXY=%s\" \"NO_PROXY=%s\"\nEOF\n", v.Proxy, v.Proxy, v.NoProxy)
+	fmt.Fprintf(script, "systemctl daemon-reload\nsystemctl restart containerd\n")
+	fmt.Fprintf(script, "iptables -N %s 2>/dev/null || iptables -F %s\n", chain, chain)
+	for _, rule := range rules(networks) {
+		fmt.Fprintf(script, "iptables -A %s %s\n", chain, strings.Join(rule, " "))
+	}
+	for _, parent := range []string{"OUTPUT", "FORWARD"} {
+		fmt.Fprintf(script, "iptables -C %s -j %s 2>/dev/null || iptables -I %s -j %s\n", parent, chain, parent, chain)
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("docker", "exec", node, "sh", "-c", script.String())
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not restrict the egress of node %s: %w: %s", node, err, stderr.String())
+	}
+	return nil
+}
+
+// rules returns the rules of the chain, which drop TCP connections (counted
+// as bypassing the proxy) and other traffic to destinations which aren't
+// allowed.
+func rules(networks []string) [][]string {
+	r := [][]string{
+		{"--destination", "127.0.0.0/8", "--jump", "RETURN"},
+		{"--protocol", "udp", "--destination-port", "53", "--jump", "RETURN"},
+		{"--protocol", "tcp", "--destination-port", "53", "--jump", "RETURN"},
+	}
+	for _, network := range append(append([]string{}, ClusterNetworks...), networks...) {
+		r = append(r, []string{"--destination", network, "--jump", "RETURN"})
+	}
+	return append(r,
+		[]string{"--protocol", "tcp", "--syn", "--jump", "DROP"},
+		[]string{"--jump", "DROP"},
+	)
+}
+
+// Bypassed returns the number of TCP connections from a node and its pods
+// which were dropped because they didn't go through the proxy.
+func Bypassed(node string) (int, error) {
+	out, err := exec.Command("docker", "exec", node, "iptables", "--list", chain, "--verbose", "--exact", "--numeric").Output()
+	if err != nil {
+		return 0, fmt.Errorf("could not read the egress rules of node %s: %w", node, err)
+	}
+	return bypassed(out), nil
+}
+
+// dropRule matches the rule dropping TCP connections in the output of
+// iptables --list --verbose --exact --numeric, e.g.
+//
+//	pkts bytes target prot opt in out source      destination
+//	  12   720 DROP   tcp  --  *  *   0.0.0.0/0   0.0.0.0/0   tcp flags:0x17/0x02
+var dropRule = regexp.MustCompile(`^\s*(\d+)\s+\d+\s+DROP\s+(tcp|6)\s.*flags:`)
+
+func bypassed(out []byte) int {
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := dropRule.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package egress
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	c := &Config{
+		Allow: []string{".docker.io"},
+		Overrides: map[string]string{
+			"kommander": "kubeaddons-catalog:\n  proxy:\n    http: {{ .Proxy }}\n    noProxy: {{ .NoProxy }}\n",
+		},
+	}
+	v := NewValues("172.17.0.1", 3128, []string{"172.17.0.0/16"})
+	if v.Proxy != "http://172.17.0.1:3128" {
+		t.Errorf("unexpected proxy URL %s", v.Proxy)
+	}
+	rendered, err := c.Render(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "kubeaddons-catalog:\n  proxy:\n    http: http://172.17.0.1:3128\n    noProxy: localhost,127.0.0.1,.svc,.cluster.local,10.244.0.0/16,10.96.0.0/12,172.17.0.0/16\n"
+	if rendered["kommander"] != expected {
+		t.Errorf("expected %q, got %q", expected, rendered["kommander"])
+	}
+
+	c.Overrides["kommander"] = "{{ .Unknown }}"
+	if _, err := c.Render(v); err == nil {
+		t.Errorf("expected an unknown value to fail")
+	}
+}
+
+func TestDenied(t *testing.T) {
+	log := `1589198713.531      0 10.244.0.5 TCP_DENIED/403 3950 CONNECT example.com:443 - HIER_NONE/- text/html
+1589198714.002     85 10.244.0.6 TCP_TUNNEL/200 4120 CONNECT registry-1.docker.io:443 - HIER_DIRECT/52.1.2.3 -
+1589198715.120      0 10.244.0.5 TCP_DENIED/403 3950 CONNECT example.com:443 - HIER_NONE/- text/html
+1589198716.441      0 10.244.0.7 TCP_DENIED/403 3800 GET http://updates.example.org/check? - HIER_NONE/- text/html
+`
+	expected := map[string]int{"example.com:443": 2, "updates.example.org": 1}
+	if actual := denied([]byte(log)); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestBypassed(t *testing.T) {
+	out := `Chain KOMMANDER-EGRESS (2 references)
+    pkts      bytes target     prot opt in     out     source               destination
+      40     2400 RETURN     all  --  *      *       0.0.0.0/0            127.0.0.0/8
+       3      180 RETURN     udp  --  *      *       0.0.0.0/0            0.0.0.0/0            udp dpt:53
+      12      720 DROP       tcp  --  *      *       0.0.0.0/0            0.0.0.0/0            tcp flags:0x17/0x02
+       2      120 DROP       all  --  *      *       0.0.0.0/0            0.0.0.0/0
+`
+	if n := bypassed([]byte(out)); n != 12 {
+		t.Errorf("expected 12 bypassing connections, got %d", n)
+	}
+}
+
+func TestRules(t *testing.T) {
+	r := rules([]string{"172.17.0.0/16"})
+	last := strings.Join(r[len(r)-2], " ")
+	if last != "--protocol tcp --syn --jump DROP" {
+		t.Errorf("expected TCP connections to be dropped after the allowed networks, got %v", r)
+	}
+	if network := strings.Join(r[len(r)-3], " "); network != "--destination 172.17.0.0/16 --jump RETURN" {
+		t.Errorf("expected the docker network to be allowed, got %v", r)
+	}
+}
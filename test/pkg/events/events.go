@@ -0,0 +1,218 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// ProblemReasons are the reasons of events which indicate scheduling, image
+// or startup problems.
+var ProblemReasons = []string{
+	"FailedScheduling",
+	"FailedMount",
+	"FailedCreate",
+	"Failed", // e.g. failed image pulls
+	"BackOff",
+	"Unhealthy",
+}
+
+var (
+	// rateLimited matches the messages of failed image pulls which a registry
+	// rejected for exceeding its pull rate limit, e.g. `toomanyrequests: You
+	// have reached your pull rate limit` of Docker Hub.
+	rateLimited = regexp.MustCompile(`(?i)(toomanyrequests|pull rate limit|429 Too Many Requests)`)
+
+	// pulledImage matches the image of the message of a failed image pull.
+	pulledImage = regexp.MustCompile(`image "([^"]+)"`)
+)
+
+// Event is a Kubernetes event about an object.
+type Event struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Type      string
+	Reason    string
+	Message   string
+	Count     int
+	LastSeen  time.Time
+}
+
+func (e Event) String() string {
+	return fmt.Sprintf("%s on %s/%s in %s (%dx): %s", e.Reason, strings.ToLower(e.Kind), e.Name, e.Namespace, e.Count, e.Message)
+}
+
+// List returns the events in a namespace (all namespaces when empty).
+func List(k kubectl.Client, namespace string) ([]Event, error) {
+	args := []string{"get", "events", "--output", "json"}
+	if namespace == "" {
+		args = append(args, "--all-namespaces")
+	} else {
+		args = append(args, "--namespace", namespace)
+	}
+
+	out, err := k.Output(args...)
+	if err != nil {
+		return nil, err
+	}
+	return decode(out)
+}
+
+type eventList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		InvolvedObject struct {
+			Kind      string `json:"kind"`
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"involvedObject"`
+		Type           string    `json:"type"`
+		Reason         string    `json:"reason"`
+		Message        string    `json:"message"`
+		Count          int       `json:"count"`
+		LastTimestamp  time.Time `json:"lastTimestamp"`
+		EventTime      time.Time `json:"eventTime"`
+		FirstTimestamp time.Time `json:"firstTimestamp"`
+	} `json:"items"`
+}
+
+func decode(b []byte) ([]Event, error) {
+	var list eventList
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("could not decode events: %w", err)
+	}
+
+	events := make([]Event, 0, len(list.Items))
+	for _, item := range list.Items {
+		e := Event{
+			Namespace: item.InvolvedObject.Namespace,
+			Kind:      item.InvolvedObject.Kind,
+			Name:      item.InvolvedObject.Name,
+			Type:      item.Type,
+			Reason:    item.Reason,
+			Message:   strings.TrimSpace(item.Message),
+			Count:     item.Count,
+			LastSeen:  item.LastTimestamp,
+		}
+		if e.Namespace == "" {
+			e.Namespace = item.Metadata.Namespace
+		}
+		if e.Count == 0 {
+			e.Count = 1
+		}
+		// events of the events.k8s.io API only set an event time
+		for _, t := range []time.Time{item.EventTime, item.FirstTimestamp} {
+			if e.LastSeen.IsZero() {
+				e.LastSeen = t
+			}
+		}
+		events = append(events, e)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.Before(events[j].LastSeen) })
+	return events, nil
+}
+
+// Query selects events. Empty fields match all events.
+type Query struct {
+	Namespace string
+	Kind      string
+
+	// Name is a prefix of the name of the object, e.g. "kommander-" matches
+	// the pods of the kommander deployments.
+	Name    string
+	Reasons []string
+
+	// Since ignores events which were last seen before it.
+	Since time.Time
+}
+
+// Matches returns whether an event is selected by the query.
+func (q Query) Matches(e Event) bool {
+	if q.Namespace != "" && e.Namespace != q.Namespace {
+		return false
+	}
+	if q.Kind != "" && !strings.EqualFold(e.Kind, q.Kind) {
+		return false
+	}
+	if !strings.HasPrefix(e.Name, q.Name) {
+		return false
+	}
+	if !q.Since.IsZero() && e.LastSeen.Before(q.Since) {
+		return false
+	}
+	if len(q.Reasons) == 0 {
+		return true
+	}
+	for _, reason := range q.Reasons {
+		if e.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns the events selected by the query.
+func Filter(events []Event, q Query) []Event {
+	var matches []Event
+	for _, e := range events {
+		if q.Matches(e) {
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+// Find returns the events in the cluster selected by the query.
+func Find(k kubectl.Client, q Query) ([]Event, error) {
+	events, err := List(k, q.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	return Filter(events, q), nil
+}
+
+// RateLimitedImages returns the images (as named by the events) whose pulls
+// were rejected for exceeding the pull rate limit of their registry, sorted.
+func RateLimitedImages(events []Event) []string {
+	seen := make(map[string]bool)
+	var images []string
+	for _, e := range events {
+		if !rateLimited.MatchString(e.Message) {
+			continue
+		}
+		match := pulledImage.FindStringSubmatch(e.Message)
+		if match == nil || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		images = append(images, match[1])
+	}
+	sort.Strings(images)
+	return images
+}
+
+// Absent asserts that the cluster has no events selected by the query.
+func Absent(k kubectl.Client, q Query) error {
+	matches, err := Find(k, q)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	lines := make([]string, 0, len(matches))
+	for _, e := range matches {
+		lines = append(lines, e.String())
+	}
+	return fmt.Errorf("found %d unexpected events:\n%s", len(matches), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,86 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const eventsJSON = `{"items": [
+  {
+    "metadata": {"namespace": "kommander"},
+    "involvedObject": {"kind": "Pod", "name": "kommander-kubeaddons-ui-6d9f", "namespace": "kommander"},
+    "type": "Warning",
+    "reason": "FailedScheduling",
+    "message": "0/1 nodes are available: 1 Insufficient cpu.",
+    "count": 3,
+    "lastTimestamp": "2020-03-01T10:05:00Z"
+  },
+  {
+    "metadata": {"namespace": "kubeaddons"},
+    "involvedObject": {"kind": "Pod", "name": "dex-kubeaddons-7c4b", "namespace": "kubeaddons"},
+    "type": "Warning",
+    "reason": "BackOff",
+    "message": "Back-off restarting failed container",
+    "lastTimestamp": null,
+    "eventTime": "2020-03-01T10:01:00Z"
+  }
+]}`
+
+func TestDecode(t *testing.T) {
+	events, err := decode([]byte(eventsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	// sorted by the time they were last seen
+	if events[0].Reason != "BackOff" || events[0].Count != 1 || events[0].LastSeen.IsZero() {
+		t.Errorf("unexpected event: %+v", events[0])
+	}
+	expected := "FailedScheduling on pod/kommander-kubeaddons-ui-6d9f in kommander (3x): 0/1 nodes are available: 1 Insufficient cpu."
+	if events[1].String() != expected {
+		t.Errorf("expected %q, got %q", expected, events[1].String())
+	}
+}
+
+func TestFilter(t *testing.T) {
+	events, err := decode([]byte(eventsJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		query    Query
+		expected int
+	}{
+		{"all", Query{}, 2},
+		{"namespace", Query{Namespace: "kommander"}, 1},
+		{"name prefix", Query{Name: "dex-"}, 1},
+		{"kind", Query{Kind: "deployment"}, 0},
+		{"reasons", Query{Reasons: ProblemReasons}, 2},
+		{"other reasons", Query{Reasons: []string{"FailedMount"}}, 0},
+		{"since", Query{Since: time.Date(2020, 3, 1, 10, 2, 0, 0, time.UTC)}, 1},
+	} {
+		if matches := Filter(events, tc.query); len(matches) != tc.expected {
+			t.Errorf("%s: expected %d events, got %d", tc.name, tc.expected, len(matches))
+		}
+	}
+}
+
+func TestRateLimitedImages(t *testing.T) {
+	events := []Event{
+		{Reason: "Failed", Message: `Failed to pull image "traefik:1.7.24": rpc error: code = Unknown desc = failed to pull and unpack image "docker.io/library/traefik:1.7.24": failed to copy: httpReaderSeeker: failed open: unexpected status code https://registry-1.docker.io/v2/library/traefik/manifests/sha256:5ec3: 429 Too Many Requests - Server message: toomanyrequests: You have reached your pull rate limit.`},
+		{Reason: "Failed", Message: `Failed to pull image "traefik:1.7.24": rpc error: code = Unknown desc = toomanyrequests: You have reached your pull rate limit.`},
+		{Reason: "Failed", Message: `Failed to pull image "mesosphere/kommander:v1.0.0": rpc error: code = Unknown desc = toomanyrequests: Too Many Requests.`},
+		{Reason: "Failed", Message: `Failed to pull image "quay.io/dexidp/dex:v2.22.0": rpc error: code = NotFound desc = not found`},
+		{Reason: "BackOff", Message: `Back-off pulling image "traefik:1.7.24"`},
+	}
+	expected := []string{"mesosphere/kommander:v1.0.0", "traefik:1.7.24"}
+	if images := RateLimitedImages(events); !reflect.DeepEqual(expected, images) {
+		t.Errorf("expected %v, got %v", expected, images)
+	}
+}
@@ -0,0 +1,201 @@
+package faults
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// device is the network interface of kind nodes.
+const device = "eth0"
+
+// Node is a cluster node, which is a docker container named after the node.
+type Node struct {
+	Name string
+	IP   string
+}
+
+// Nodes returns the nodes of the cluster of a kubectl client.
+func Nodes(k kubectl.Client) ([]Node, error) {
+	out, err := k.Output("get", "nodes", "--output", `jsonpath={range .items[*]}{.metadata.name} {.status.addresses[?(@.type=="InternalIP")].address}{"\n"}{end}`)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []Node
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		nodes = append(nodes, Node{Name: fields[0], IP: fields[1]})
+	}
+	return nodes, nil
+}
+
+// Latency delays all traffic of the nodes by delay (varying by jitter) until
+// the returned function is called.
+func Latency(nodes []Node, delay, jitter time.Duration) (func() error, error) {
+	heal := func() error {
+		var errs []string
+		for _, node := range nodes {
+			if err := run(node, "tc", "qdisc", "del", "dev", device, "root"); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		return joined(errs)
+	}
+
+	for _, node := range nodes {
+		if err := run(node, latencyArgs(delay, jitter)...); err != nil {
+			heal()
+			return nil, err
+		}
+	}
+	return heal, nil
+}
+
+func latencyArgs(delay, jitter time.Duration) []string {
+	return []string{"tc", "qdisc", "add", "dev", device, "root", "netem", "delay", milliseconds(delay), milliseconds(jitter), "distribution", "normal"}
+}
+
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+// Partition drops all traffic between a node and the other nodes until the
+// returned function is called.
+func Partition(node Node, others []Node) (func() error, error) {
+	var rules [][]string
+	for _, other := range others {
+		if other.Name != node.Name {
+			rules = append(rules, partitionRules(other.IP)...)
+		}
+	}
+
+	heal := func() error {
+		var errs []string
+		for _, rule := range rules {
+			if err := run(node, append([]string{"iptables", "-D"}, rule...)...); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+		return joined(errs)
+	}
+
+	for _, rule := range rules {
+		if err := run(node, append([]string{"iptables", "-I"}, rule...)...); err != nil {
+			heal()
+			return nil, err
+		}
+	}
+	return heal, nil
+}
+
+func partitionRules(ip string) [][]string {
+	return [][]string{
+		{"INPUT", "--source", ip, "--jump", "DROP"},
+		{"OUTPUT", "--destination", ip, "--jump", "DROP"},
+	}
+}
+
+// run runs a command in the container of a node.
+func run(node Node, args ...string) error {
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("docker", append([]string{"exec", node.Name}, args...)...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s on node %s failed: %w: %s", strings.Join(args, " "), node.Name, err, stderr.String())
+	}
+	return nil
+}
+
+func joined(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
+// Restarts are the restart counts of containers, keyed by
+// <namespace>/<pod>/<container>.
+type Restarts map[string]int
+
+// CurrentRestarts returns the restart counts of the containers in the
+// namespaces, along with the containers waiting in CrashLoopBackOff.
+func CurrentRestarts(k kubectl.Client, namespaces []string) (Restarts, []string, error) {
+	restarts := make(Restarts)
+	var crashLooping []string
+	for _, namespace := range namespaces {
+		out, err := k.Output("get", "pods", "--namespace", namespace, "--output", "json")
+		if err != nil {
+			return nil, nil, err
+		}
+		looping, err := decodeRestarts(out, restarts)
+		if err != nil {
+			return nil, nil, err
+		}
+		crashLooping = append(crashLooping, looping...)
+	}
+	sort.Strings(crashLooping)
+	return restarts, crashLooping, nil
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			ContainerStatuses []struct {
+				Name         string `json:"name"`
+				RestartCount int    `json:"restartCount"`
+				State        struct {
+					Waiting *struct {
+						Reason string `json:"reason"`
+					} `json:"waiting"`
+				} `json:"state"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func decodeRestarts(b []byte, restarts Restarts) ([]string, error) {
+	var list podList
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("could not decode pods: %w", err)
+	}
+
+	var crashLooping []string
+	for _, pod := range list.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			key := pod.Metadata.Namespace + "/" + pod.Metadata.Name + "/" + container.Name
+			restarts[key] = container.RestartCount
+			if container.State.Waiting != nil && container.State.Waiting.Reason == "CrashLoopBackOff" {
+				crashLooping = append(crashLooping, key)
+			}
+		}
+	}
+	return crashLooping, nil
+}
+
+// Increased returns the containers which restarted between two counts, with
+// the number of restarts, sorted. Containers of new pods count as restarted
+// when they restarted at all.
+func Increased(before, after Restarts) []string {
+	var increased []string
+	for key, count := range after {
+		if delta := count - before[key]; delta > 0 {
+			increased = append(increased, fmt.Sprintf("%s (%dx)", key, delta))
+		}
+	}
+	sort.Strings(increased)
+	return increased
+}
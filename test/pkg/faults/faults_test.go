@@ -0,0 +1,57 @@
+package faults
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyArgs(t *testing.T) {
+	expected := "tc qdisc add dev eth0 root netem delay 100ms 20ms distribution normal"
+	if args := strings.Join(latencyArgs(100*time.Millisecond, 20*time.Millisecond), " "); args != expected {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+}
+
+func TestPartitionRules(t *testing.T) {
+	expected := [][]string{
+		{"INPUT", "--source", "172.17.0.3", "--jump", "DROP"},
+		{"OUTPUT", "--destination", "172.17.0.3", "--jump", "DROP"},
+	}
+	if rules := partitionRules("172.17.0.3"); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("expected %v, got %v", expected, rules)
+	}
+}
+
+const podsJSON = `{"items": [
+  {
+    "metadata": {"namespace": "kommander", "name": "kommander-thanos-query-0"},
+    "status": {"containerStatuses": [
+      {"name": "thanos-query", "restartCount": 4, "state": {"waiting": {"reason": "CrashLoopBackOff"}}}
+    ]}
+  },
+  {
+    "metadata": {"namespace": "kommander", "name": "kommander-kubeaddons-ui-6d9f"},
+    "status": {"containerStatuses": [
+      {"name": "ui", "restartCount": 0, "state": {"running": {}}}
+    ]}
+  }
+]}`
+
+func TestRestarts(t *testing.T) {
+	restarts := make(Restarts)
+	crashLooping, err := decodeRestarts([]byte(podsJSON), restarts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kommander/kommander-thanos-query-0/thanos-query"}; !reflect.DeepEqual(crashLooping, expected) {
+		t.Errorf("expected %v crash looping, got %v", expected, crashLooping)
+	}
+
+	before := Restarts{"kommander/kommander-thanos-query-0/thanos-query": 1, "kommander/kommander-kubeaddons-ui-6d9f/ui": 0}
+	expected := []string{"kommander/kommander-thanos-query-0/thanos-query (3x)"}
+	if increased := Increased(before, restarts); !reflect.DeepEqual(increased, expected) {
+		t.Errorf("expected %v, got %v", expected, increased)
+	}
+}
@@ -0,0 +1,302 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const (
+	// KubeFedNamespace is the namespace of the clusters joined to the
+	// federation control plane of kommander.
+	KubeFedNamespace = "kube-federation-system"
+
+	// ClusterLabel labels the attached clusters with their name, which the
+	// placement of projects selects.
+	ClusterLabel = "kommander.mesosphere.io/cluster"
+
+	interval = 5 * time.Second
+)
+
+// Attach attaches a cluster to kommander in the namespace of a workspace: a
+// secret holding the kubeconfig of the cluster and the KommanderCluster
+// referencing it, which kommander joins to its federation control plane.
+func Attach(k kubectl.Client, name, workspace string, kubeconfig []byte) error {
+	return apply(k,
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": name + "-kubeconfig", "namespace": workspace},
+			"stringData": map[string]interface{}{"kubeconfig": string(kubeconfig)},
+		},
+		map[string]interface{}{
+			"apiVersion": "kommander.mesosphere.io/v1beta1",
+			"kind":       "KommanderCluster",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": workspace,
+				"labels":    map[string]interface{}{ClusterLabel: name},
+			},
+			"spec": map[string]interface{}{"kubeconfigRef": map[string]interface{}{"name": name + "-kubeconfig"}},
+		},
+	)
+}
+
+// Detach removes an attached cluster from kommander along with the secret
+// holding its kubeconfig.
+func Detach(k kubectl.Client, name, workspace string) error {
+	if err := k.Run("delete", "kommandercluster", name, "--namespace", workspace, "--ignore-not-found", "--wait"); err != nil {
+		return err
+	}
+	return k.Run("delete", "secret", name+"-kubeconfig", "--namespace", workspace, "--ignore-not-found")
+}
+
+// WaitJoined waits up to timeout for an attached cluster to be joined to the
+// federation control plane and ready.
+func WaitJoined(k kubectl.Client, name string, timeout time.Duration) error {
+	if err := waitFor(timeout, func() error {
+		_, err := k.Output("get", "kubefedcluster", name, "--namespace", KubeFedNamespace)
+		return err
+	}); err != nil {
+		return fmt.Errorf("cluster %s was not joined to the federation within %s: %w", name, timeout, err)
+	}
+	return k.Run("wait", "kubefedcluster/"+name, "--namespace", KubeFedNamespace, "--for", "condition=Ready", "--timeout", timeout.String())
+}
+
+// Project is a kommander project: a namespace of a workspace which is
+// federated to the attached clusters it is placed on.
+type Project struct {
+	Name      string
+	Workspace string
+
+	// Namespace is the namespace of the project, on kommander and on the
+	// clusters it is placed on.
+	Namespace string
+
+	// Clusters are the names of the attached clusters the project is placed
+	// on, none when empty.
+	Clusters []string
+}
+
+// placement returns the placement of a project, selecting its clusters by
+// their name label.
+func (p Project) placement() map[string]interface{} {
+	if len(p.Clusters) == 0 {
+		return map[string]interface{}{"clusters": []interface{}{}}
+	}
+	return map[string]interface{}{
+		"clusterSelector": map[string]interface{}{
+			"matchExpressions": []interface{}{
+				map[string]interface{}{"key": ClusterLabel, "operator": "In", "values": p.Clusters},
+			},
+		},
+	}
+}
+
+// CreateProject creates a project and waits up to timeout for its namespace
+// to be federated.
+func CreateProject(k kubectl.Client, p Project, timeout time.Duration) error {
+	if err := apply(k, map[string]interface{}{
+		"apiVersion": "workspaces.kommander.mesosphere.io/v1alpha1",
+		"kind":       "Project",
+		"metadata":   map[string]interface{}{"name": p.Name, "namespace": p.Workspace},
+		"spec": map[string]interface{}{
+			"namespaceName": p.Namespace,
+			"placement":     p.placement(),
+		},
+	}); err != nil {
+		return err
+	}
+	if err := waitFor(timeout, func() error {
+		_, err := k.Output("get", "federatednamespace", p.Namespace, "--namespace", p.Namespace)
+		return err
+	}); err != nil {
+		return fmt.Errorf("namespace %s of project %s was not federated within %s: %w", p.Namespace, p.Name, timeout, err)
+	}
+	return nil
+}
+
+// CreateWorkspace creates a workspace and waits up to timeout for its
+// namespace, which holds its projects and attached clusters, to be created.
+func CreateWorkspace(k kubectl.Client, name, namespace string, timeout time.Duration) error {
+	if err := apply(k, map[string]interface{}{
+		"apiVersion": "workspaces.kommander.mesosphere.io/v1alpha1",
+		"kind":       "Workspace",
+		"metadata":   map[string]interface{}{"name": name},
+		"spec":       map[string]interface{}{"namespaceName": namespace},
+	}); err != nil {
+		return err
+	}
+	if err := waitFor(timeout, func() error {
+		_, err := k.Output("get", "namespace", namespace)
+		return err
+	}); err != nil {
+		return fmt.Errorf("namespace %s of workspace %s was not created within %s: %w", namespace, name, timeout, err)
+	}
+	return nil
+}
+
+// DeleteWorkspace deletes a workspace along with its projects.
+func DeleteWorkspace(k kubectl.Client, name string) error {
+	return k.Run("delete", "workspace", name, "--ignore-not-found", "--wait")
+}
+
+// DeleteProject deletes a project, which removes its namespace from the
+// clusters it is placed on.
+func DeleteProject(k kubectl.Client, p Project) error {
+	return k.Run("delete", "project", p.Name, "--namespace", p.Workspace, "--ignore-not-found", "--wait")
+}
+
+// DeployAddon deploys an addon (its manifest as JSON) to the clusters of a
+// project, as a FederatedAddon in the namespace of the project.
+func DeployAddon(k kubectl.Client, p Project, addon []byte) (string, error) {
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(addon, &obj); err != nil {
+		return "", fmt.Errorf("could not decode addon: %w", err)
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	if name == "" {
+		return "", fmt.Errorf("addon has no name")
+	}
+
+	clusters := make([]interface{}, 0, len(p.Clusters))
+	for _, cluster := range p.Clusters {
+		clusters = append(clusters, map[string]interface{}{"name": cluster})
+	}
+	template := map[string]interface{}{"spec": obj["spec"]}
+	if labels, ok := metadata["labels"]; ok {
+		template["metadata"] = map[string]interface{}{"labels": labels}
+	}
+	return name, apply(k, map[string]interface{}{
+		"apiVersion": "types.kubefed.io/v1beta1",
+		"kind":       "FederatedAddon",
+		"metadata":   map[string]interface{}{"name": name, "namespace": p.Namespace},
+		"spec": map[string]interface{}{
+			"placement": map[string]interface{}{"clusters": clusters},
+			"template":  template,
+		},
+	})
+}
+
+// RemoveAddon removes a federated addon, which removes the addon from the
+// clusters of its project.
+func RemoveAddon(k kubectl.Client, p Project, name string) error {
+	return k.Run("delete", "federatedaddon", name, "--namespace", p.Namespace, "--ignore-not-found", "--wait")
+}
+
+// WaitPropagated waits up to timeout for kommander to report a federated
+// addon as propagated to every cluster of its project.
+func WaitPropagated(k kubectl.Client, p Project, name string, timeout time.Duration) error {
+	if err := waitFor(timeout, func() error {
+		b, err := k.Output("get", "federatedaddon", name, "--namespace", p.Namespace, "--output", "json")
+		if err != nil {
+			return err
+		}
+		return Propagated(b, p.Clusters)
+	}); err != nil {
+		return fmt.Errorf("federated addon %s was not propagated within %s: %w", name, timeout, err)
+	}
+	return nil
+}
+
+// WaitReady waits up to timeout for an addon to be deployed and ready on an
+// attached cluster, reached through its kubectl context.
+func WaitReady(k kubectl.Client, context, namespace, name string, timeout time.Duration) error {
+	if err := waitFor(timeout, func() error {
+		out, err := k.Output("--context", context, "get", "addon", name, "--namespace", namespace, "--output", "jsonpath={.status.ready}")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(out)) != "true" {
+			return fmt.Errorf("addon %s is not ready", name)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("addon %s was not ready on cluster %s within %s: %w", name, context, timeout, err)
+	}
+	return nil
+}
+
+// federatedStatus is the status kubefed reports for a federated resource.
+type federatedStatus struct {
+	Status struct {
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+		Clusters []struct {
+			Name   string `json:"name"`
+			Status string `json:"status"`
+		} `json:"clusters"`
+	} `json:"status"`
+}
+
+// Propagated returns an error unless the status of a federated resource (as
+// JSON) reports it as propagated to each of the clusters.
+func Propagated(b []byte, clusters []string) error {
+	resource := federatedStatus{}
+	if err := json.Unmarshal(b, &resource); err != nil {
+		return fmt.Errorf("could not decode federated resource: %w", err)
+	}
+
+	propagated := false
+	for _, condition := range resource.Status.Conditions {
+		if condition.Type != "Propagation" {
+			continue
+		}
+		if condition.Status != "True" {
+			return fmt.Errorf("propagation failed (%s): %s", condition.Reason, condition.Message)
+		}
+		propagated = true
+	}
+	if !propagated {
+		return fmt.Errorf("no propagation status was reported")
+	}
+
+	// kubefed reports the clusters a resource was placed on, with a status
+	// only when the propagation to a cluster failed
+	reported := make(map[string]string, len(resource.Status.Clusters))
+	for _, cluster := range resource.Status.Clusters {
+		reported[cluster.Name] = cluster.Status
+	}
+	for _, cluster := range clusters {
+		status, ok := reported[cluster]
+		if !ok {
+			return fmt.Errorf("cluster %s is not reported", cluster)
+		}
+		if status != "" {
+			return fmt.Errorf("cluster %s reports %s", cluster, status)
+		}
+	}
+	return nil
+}
+
+func apply(k kubectl.Client, objects ...interface{}) error {
+	b, err := json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": objects})
+	if err != nil {
+		return err
+	}
+	return k.Apply(b)
+}
+
+// waitFor retries f every interval until it succeeds, or until timeout with
+// its last error.
+func waitFor(timeout time.Duration, f func() error) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(interval)
+	}
+}
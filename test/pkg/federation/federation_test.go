@@ -0,0 +1,44 @@
+package federation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPropagated(t *testing.T) {
+	for _, tc := range []struct {
+		status   string
+		expected string
+	}{
+		{
+			status: `{"status": {"conditions": [{"type": "Propagation", "status": "True"}], "clusters": [{"name": "attached"}]}}`,
+		},
+		{
+			status:   `{"status": {}}`,
+			expected: "no propagation status",
+		},
+		{
+			status:   `{"status": {"conditions": [{"type": "Propagation", "status": "False", "reason": "CheckClusters", "message": "cluster not ready"}]}}`,
+			expected: "propagation failed (CheckClusters): cluster not ready",
+		},
+		{
+			status:   `{"status": {"conditions": [{"type": "Propagation", "status": "True"}], "clusters": [{"name": "other"}]}}`,
+			expected: "cluster attached is not reported",
+		},
+		{
+			status:   `{"status": {"conditions": [{"type": "Propagation", "status": "True"}], "clusters": [{"name": "attached", "status": "CreationFailed"}]}}`,
+			expected: "cluster attached reports CreationFailed",
+		},
+	} {
+		err := Propagated([]byte(tc.status), []string{"attached"})
+		if tc.expected == "" {
+			if err != nil {
+				t.Errorf("expected %s to be propagated, got %s", tc.status, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("expected an error containing %q for %s, got %v", tc.expected, tc.status, err)
+		}
+	}
+}
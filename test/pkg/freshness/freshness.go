@@ -0,0 +1,168 @@
+package freshness
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/blang/semver"
+	"gopkg.in/yaml.v2"
+)
+
+// Chart is a chart pinned by an addon.
+type Chart struct {
+	Addon   string
+	Repo    string
+	Name    string
+	Version string
+}
+
+// Update is a pinned chart along with the newer versions its repository
+// provides.
+type Update struct {
+	Chart
+	Latest string
+
+	// Newer are the versions newer than the pinned version, newest first.
+	Newer []string
+
+	// Severity is "major", "minor" or "patch" for the largest difference
+	// between the pinned and the latest version, empty when up to date.
+	Severity string
+}
+
+// Behind returns whether the latest version differs in its major or minor
+// version, i.e. whether the chart missed more than bug fixes.
+func (u Update) Behind() bool {
+	return u.Severity == "major" || u.Severity == "minor"
+}
+
+type index struct {
+	Entries map[string][]struct {
+		Version string `yaml:"version"`
+	} `yaml:"entries"`
+}
+
+// Index holds the chart repository indexes which have been fetched, so that
+// every repository is only queried once.
+type Index struct {
+	lock    sync.Mutex
+	indexes map[string]index
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{indexes: make(map[string]index)}
+}
+
+func (i *Index) fetch(repo string) (index, error) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	if idx, ok := i.indexes[repo]; ok {
+		return idx, nil
+	}
+
+	url := strings.TrimSuffix(repo, "/") + "/index.yaml"
+	resp, err := http.Get(url)
+	if err != nil {
+		return index{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return index{}, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return index{}, err
+	}
+	idx := index{}
+	if err := yaml.Unmarshal(b, &idx); err != nil {
+		return index{}, fmt.Errorf("could not parse chart repository index %s: %w", url, err)
+	}
+
+	i.indexes[repo] = idx
+	return idx, nil
+}
+
+// Check returns the versions of the chart in its repository which are newer
+// than the pinned version. Pre-releases are ignored.
+func (i *Index) Check(c Chart) (Update, error) {
+	idx, err := i.fetch(c.Repo)
+	if err != nil {
+		return Update{}, err
+	}
+
+	entries, ok := idx.Entries[c.Name]
+	if !ok {
+		return Update{}, fmt.Errorf("chart %s does not exist in repository %s", c.Name, c.Repo)
+	}
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, entry.Version)
+	}
+
+	return compare(c, versions)
+}
+
+func compare(c Chart, versions []string) (Update, error) {
+	pinned, err := semver.ParseTolerant(c.Version)
+	if err != nil {
+		return Update{}, fmt.Errorf("pinned version %s of chart %s is not a semantic version: %w", c.Version, c.Name, err)
+	}
+
+	var newer []semver.Version
+	for _, v := range versions {
+		version, err := semver.ParseTolerant(v)
+		if err != nil || len(version.Pre) > 0 {
+			continue
+		}
+		if version.GT(pinned) {
+			newer = append(newer, version)
+		}
+	}
+	sort.Slice(newer, func(i, j int) bool { return newer[i].GT(newer[j]) })
+
+	u := Update{Chart: c, Latest: c.Version}
+	if len(newer) == 0 {
+		return u, nil
+	}
+
+	latest := newer[0]
+	u.Latest = latest.String()
+	for _, v := range newer {
+		u.Newer = append(u.Newer, v.String())
+	}
+	switch {
+	case latest.Major != pinned.Major:
+		u.Severity = "major"
+	case latest.Minor != pinned.Minor:
+		u.Severity = "minor"
+	default:
+		u.Severity = "patch"
+	}
+	return u, nil
+}
+
+// Table renders the updates as a plain text table, charts which are up to date
+// are included.
+func Table(updates []Update) string {
+	b := new(bytes.Buffer)
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDON\tCHART\tPINNED\tLATEST\tNEWER\tSEVERITY")
+	for _, u := range updates {
+		severity := u.Severity
+		if severity == "" {
+			severity = "up to date"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", u.Addon, u.Name, u.Version, u.Latest, len(u.Newer), severity)
+	}
+	w.Flush()
+	return b.String()
+}
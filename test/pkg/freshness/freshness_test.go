@@ -0,0 +1,33 @@
+package freshness
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	c := Chart{Addon: "kommander", Name: "kommander", Version: "0.4.18"}
+
+	for _, tc := range []struct {
+		versions []string
+		newer    []string
+		severity string
+	}{
+		{versions: []string{"0.4.17", "0.4.18"}},
+		{versions: []string{"0.4.18", "0.4.19", "0.4.20"}, newer: []string{"0.4.20", "0.4.19"}, severity: "patch"},
+		{versions: []string{"0.4.19", "0.5.0", "0.5.1-rc.1"}, newer: []string{"0.5.0", "0.4.19"}, severity: "minor"},
+		{versions: []string{"1.0.0", "latest"}, newer: []string{"1.0.0"}, severity: "major"},
+	} {
+		u, err := compare(c, tc.versions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tc.newer, u.Newer) || tc.severity != u.Severity {
+			t.Errorf("expected %v (%q) for %v, got %v (%q)", tc.newer, tc.severity, tc.versions, u.Newer, u.Severity)
+		}
+	}
+
+	if _, err := compare(Chart{Name: "broken", Version: "main"}, []string{"1.0.0"}); err == nil {
+		t.Error("expected a non-semantic pinned version to be rejected")
+	}
+}
@@ -0,0 +1,270 @@
+package gc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// RunLabel is the label of docker resources with the ID of the test run
+	// which created them, the same key as spend.RunTag on cloud resources.
+	RunLabel = "kubeaddons-kommander.mesosphere.io/run"
+
+	// KindClusterLabel is the label kind puts on the node containers of its
+	// clusters, with the name of the cluster.
+	KindClusterLabel = "io.x-k8s.kind.cluster"
+)
+
+// Run is the record of a test run in the ledger: the clusters it created, so
+// that their docker resources can be swept once the run is over, even if it
+// crashed before it could delete them.
+type Run struct {
+	ID       string    `json:"id"`
+	PID      int       `json:"pid"`
+	Started  time.Time `json:"started"`
+	Clusters []string  `json:"clusters,omitempty"`
+	Finished bool      `json:"finished,omitempty"`
+
+	path string
+}
+
+// Start records a run of this process in the ledger directory. A run which is
+// already in the ledger (i.e. a resumed run) is taken over along with its
+// clusters.
+func Start(dir, id string, now time.Time) (*Run, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create the ledger: %w", err)
+	}
+	r := &Run{ID: id, Started: now, path: filepath.Join(dir, id+".json")}
+	if b, err := ioutil.ReadFile(r.path); err == nil {
+		if err := json.Unmarshal(b, r); err != nil {
+			return nil, fmt.Errorf("could not decode run %s: %w", r.path, err)
+		}
+	}
+	r.PID, r.Finished = os.Getpid(), false
+	return r, r.save()
+}
+
+// AddCluster records a cluster created by the run.
+func (r *Run) AddCluster(name string) error {
+	r.Clusters = append(r.Clusters, name)
+	return r.save()
+}
+
+// Release removes a cluster from the run, e.g. because it is retained to
+// resume the run, so that it is not swept.
+func (r *Run) Release(name string) error {
+	for i, cluster := range r.Clusters {
+		if cluster == name {
+			r.Clusters = append(r.Clusters[:i], r.Clusters[i+1:]...)
+			return r.save()
+		}
+	}
+	return nil
+}
+
+// Finish records that the run is over, so that its resources may be swept.
+func (r *Run) Finish() error {
+	r.Finished = true
+	return r.save()
+}
+
+// Remove removes the run from the ledger, once its resources are swept.
+func (r *Run) Remove() error {
+	if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *Run) save() error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(r.path, b, 0644); err != nil {
+		return fmt.Errorf("could not record run %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+// Stale returns the runs of the ledger directory which are over: those which
+// finished and those whose process is gone, e.g. because it crashed or was
+// killed by CI. The excluded runs are never stale, e.g. a crashed run which
+// is about to be resumed along with the clusters it left behind.
+func Stale(dir string, exclude ...string) ([]*Run, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		excluded[id] = true
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []*Run
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		r := &Run{path: path}
+		if err := json.Unmarshal(b, r); err != nil {
+			return nil, fmt.Errorf("could not decode run %s: %w", path, err)
+		}
+		if excluded[r.ID] {
+			continue
+		}
+		if r.Finished || !running(r.PID) {
+			stale = append(stale, r)
+		}
+	}
+	return stale, nil
+}
+
+// running returns whether a process is running, by sending it signal 0.
+func running(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// Kind is the kind of a docker resource.
+type Kind string
+
+const (
+	Container Kind = "container"
+	Volume    Kind = "volume"
+	Network   Kind = "network"
+)
+
+// Resource is a docker resource left by a run.
+type Resource struct {
+	Kind Kind
+	ID   string
+	Name string
+}
+
+func (r Resource) String() string {
+	if r.Name == "" || r.Name == r.ID {
+		return fmt.Sprintf("%s %s", r.Kind, r.ID)
+	}
+	return fmt.Sprintf("%s %s (%s)", r.Kind, r.Name, r.ID)
+}
+
+// Resources returns the docker resources which are left of a run: those
+// labeled with its ID, the node containers of its kind clusters and their
+// volumes, in the order they are removed.
+func (r *Run) Resources() ([]Resource, error) {
+	filters := []string{RunLabel + "=" + r.ID}
+	for _, cluster := range r.Clusters {
+		filters = append(filters, KindClusterLabel+"="+cluster)
+	}
+
+	var containers, volumes, networks []Resource
+	for _, filter := range filters {
+		out, err := docker("ps", "--all", "--filter", "label="+filter, "--format", "{{.ID}} {{.Names}}")
+		if err != nil {
+			return nil, err
+		}
+		containers = append(containers, parse(Container, out)...)
+	}
+	for _, container := range containers {
+		out, err := docker("inspect", "--format", `{{range .Mounts}}{{if eq .Type "volume"}}{{.Name}} {{end}}{{end}}`, container.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range strings.Fields(out) {
+			volumes = append(volumes, Resource{Kind: Volume, ID: name})
+		}
+	}
+	out, err := docker("volume", "ls", "--filter", "label="+filters[0], "--format", "{{.Name}}")
+	if err != nil {
+		return nil, err
+	}
+	volumes = append(volumes, parse(Volume, out)...)
+	if out, err = docker("network", "ls", "--filter", "label="+filters[0], "--format", "{{.ID}} {{.Name}}"); err != nil {
+		return nil, err
+	}
+	networks = parse(Network, out)
+
+	return unique(append(append(containers, volumes...), networks...)), nil
+}
+
+// parse parses the resources listed by docker, one per line with their ID
+// and optionally their name.
+func parse(kind Kind, out string) []Resource {
+	var resources []Resource
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 0:
+			continue
+		case 1:
+			resources = append(resources, Resource{Kind: kind, ID: fields[0]})
+		default:
+			resources = append(resources, Resource{Kind: kind, ID: fields[0], Name: fields[1]})
+		}
+	}
+	return resources
+}
+
+func unique(resources []Resource) []Resource {
+	seen := map[Resource]bool{}
+	var u []Resource
+	for _, resource := range resources {
+		key := Resource{Kind: resource.Kind, ID: resource.ID}
+		if !seen[key] {
+			seen[key] = true
+			u = append(u, resource)
+		}
+	}
+	return u
+}
+
+// removers are the docker commands which remove resources by kind. The
+// volumes of containers are removed on their own, to be reported.
+var removers = map[Kind][]string{
+	Container: {"rm", "--force"},
+	Volume:    {"volume", "rm"},
+	Network:   {"network", "rm"},
+}
+
+// Sweep removes resources in order and returns those it reclaimed. It keeps
+// going when a resource can't be removed and returns the errors together.
+func Sweep(resources []Resource) ([]Resource, error) {
+	var reclaimed []Resource
+	var errs []string
+	for _, resource := range resources {
+		if _, err := docker(append(removers[resource.Kind], resource.ID)...); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		reclaimed = append(reclaimed, resource)
+	}
+	if len(errs) > 0 {
+		return reclaimed, fmt.Errorf("could not remove %d docker resources: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return reclaimed, nil
+}
+
+func docker(args ...string) (string, error) {
+	var stdout, stderr strings.Builder
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s failed: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
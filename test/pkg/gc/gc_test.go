@@ -0,0 +1,142 @@
+package gc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStale(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	current, err := Start(dir, "20200301-120000-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := current.AddCluster("kind-1"); err != nil {
+		t.Fatal(err)
+	}
+	finished, err := Start(dir, "20200301-110000-1", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := finished.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	crashed := &Run{ID: "crashed", PID: -1, path: filepath.Join(dir, "crashed.json")}
+	if err := crashed.AddCluster("kind-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := Stale(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, r := range stale {
+		ids = append(ids, r.ID)
+	}
+	expected := []string{finished.ID, "crashed"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected stale runs %v, got %v", expected, ids)
+	}
+	if stale[1].Clusters[0] != "kind-2" {
+		t.Errorf("expected the clusters of the crashed run to be recorded, got %v", stale[1].Clusters)
+	}
+
+	if err := stale[0].Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, finished.ID+".json")); !os.IsNotExist(err) {
+		t.Errorf("expected the swept run to be removed from the ledger, got %v", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	crashed := &Run{ID: "20200301-120000-1", PID: -1, path: filepath.Join(dir, "20200301-120000-1.json")}
+	if err := crashed.AddCluster("kind-1"); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := Start(dir, crashed.ID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resumed.Clusters, []string{"kind-1"}) || resumed.PID != os.Getpid() {
+		t.Errorf("expected the resumed run to take over the clusters of the run, got %+v", resumed)
+	}
+	if stale, err := Stale(dir); err != nil || len(stale) != 0 {
+		t.Errorf("expected the resumed run not to be stale, got %v and %v", stale, err)
+	}
+
+	if err := resumed.Release("kind-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := resumed.Release("kind-2"); err != nil || len(resumed.Clusters) != 0 {
+		t.Errorf("expected the released cluster to be removed from the run, got %v and %v", resumed.Clusters, err)
+	}
+}
+
+func TestResumeCrashed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the run crashed before it could release the cluster it left behind
+	crashed := &Run{ID: "20200301-120000-1", PID: -1, path: filepath.Join(dir, "20200301-120000-1.json")}
+	if err := crashed.AddCluster("kind-1"); err != nil {
+		t.Fatal(err)
+	}
+	other := &Run{ID: "20200301-110000-1", PID: -1, path: filepath.Join(dir, "20200301-110000-1.json")}
+	if err := other.AddCluster("kind-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the run resuming it sweeps the other runs when it starts, then takes it
+	// over
+	stale, err := Stale(dir, crashed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 || stale[0].ID != other.ID {
+		t.Errorf("expected only run %s to be stale while run %s is resumed, got %v", other.ID, crashed.ID, stale)
+	}
+	resumed, err := Start(dir, crashed.ID, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resumed.Clusters, []string{"kind-1"}) {
+		t.Errorf("expected the resumed run to keep the clusters of the crashed run, got %v", resumed.Clusters)
+	}
+}
+
+func TestParse(t *testing.T) {
+	out := "3f2a1b kind-control-plane\n9c8d7e kind-worker\n\n"
+	expected := []Resource{
+		{Kind: Container, ID: "3f2a1b", Name: "kind-control-plane"},
+		{Kind: Container, ID: "9c8d7e", Name: "kind-worker"},
+	}
+	if resources := parse(Container, out); !reflect.DeepEqual(resources, expected) {
+		t.Errorf("expected %v, got %v", expected, resources)
+	}
+
+	volumes := unique(append(parse(Volume, "a1\nb2\n"), parse(Volume, "a1\n")...))
+	if len(volumes) != 2 || volumes[1].String() != "volume b2" {
+		t.Errorf("expected the volumes a1 and b2, got %v", volumes)
+	}
+}
@@ -0,0 +1,397 @@
+package groups
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/wait"
+)
+
+// Addon is an addon in a testing group. In groups.yaml addons are either listed
+// by name or as a mapping with a name and further options:
+//
+//	kommander:
+//	  - "traefik"
+//	  - name: "kubecost"
+//	    skip: true
+//	    reason: "blocked on upstream chart fix"
+//	  - name: "velero"
+//	    supportedVersions: ">=1.16.0 <1.18.0"
+//	    timeouts:
+//	      ">=1.17.0": "15m"
+//	  - name: "prometheus"
+//	    waitFor:
+//	      - "crd/prometheuses.monitoring.coreos.com established"
+//	  - name: "dex"
+//	    expectedFailures:
+//	      - versions: "<1.16.0"
+//	        check: "dex is ready"
+//	        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/1"
+//	      - versions: ">=1.16.0"
+//	        architectures: ["arm64"]
+//	        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/2"
+//	  - name: "kommander"
+//	    namespaces:
+//	      - "kubefed"
+type Addon struct {
+	Name string `yaml:"name"`
+
+	// Skip removes the addon from the deployment and checks of its group.
+	Skip bool `yaml:"skip"`
+
+	// Reason explains why the addon is skipped.
+	Reason string `yaml:"reason"`
+
+	// SupportedVersions is the range of kubernetes versions the addon is
+	// tested on, all versions when empty. The addon is skipped on others.
+	SupportedVersions string `yaml:"supportedVersions"`
+
+	// Timeouts are the timeouts of the deployment and the checks of the addon
+	// by range of kubernetes versions, for addons which are slower on some
+	// versions. The longest timeout of the matching ranges applies.
+	Timeouts map[string]string `yaml:"timeouts"`
+
+	// WaitFor are the conditions (see wait.Condition) the addon is waited for
+	// after it was deployed, before the addons of later waves are deployed.
+	WaitFor []wait.Condition `yaml:"waitFor"`
+
+	// ExpectedFailures are the checks of the addon which are known to fail on
+	// ranges of kubernetes versions, or on some architectures.
+	ExpectedFailures []ExpectedFailure `yaml:"expectedFailures"`
+
+	// Namespaces are the namespaces the addon installs into in addition to
+	// the namespace of its addon, which the install isolation verification
+	// allows it to change.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// ExpectedFailure marks the checks covering an addon, or one of them, as
+// known to fail on a range of kubernetes versions (on some architectures of
+// the host) until an issue is fixed.
+// The checks still run: their failures don't fail the suite, but passing does,
+// so the expected failure is removed once the issue is fixed.
+type ExpectedFailure struct {
+	// Versions is the range of kubernetes versions the checks fail on.
+	Versions string `yaml:"versions"`
+
+	// Check limits the expected failure to the check of that name.
+	Check string `yaml:"check,omitempty"`
+
+	// Architectures limits the expected failure to hosts of these
+	// architectures (see architectures.yaml), e.g. arm64 for addons whose
+	// images are only published for amd64.
+	Architectures []string `yaml:"architectures,omitempty"`
+
+	// Issue is the link to the issue tracking the failure.
+	Issue string `yaml:"issue"`
+
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// Covers returns whether the expected failure covers a check on a kubernetes
+// version and an architecture.
+func (e ExpectedFailure) Covers(check string, version semver.Version, arch string) bool {
+	if e.Check != "" && e.Check != check {
+		return false
+	}
+	if len(e.Architectures) > 0 && !contains(e.Architectures, arch) {
+		return false
+	}
+	matches, err := semver.ParseRange(e.Versions)
+	return err == nil && matches(version)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML supports addons listed by name only.
+func (a *Addon) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*a = Addon{Name: name}
+		return nil
+	}
+
+	type plain Addon
+	if err := unmarshal((*plain)(a)); err != nil {
+		return err
+	}
+	if a.Name == "" {
+		return fmt.Errorf("addons in testing groups require a name")
+	}
+	if a.SupportedVersions != "" {
+		if _, err := semver.ParseRange(a.SupportedVersions); err != nil {
+			return fmt.Errorf("addon %s has invalid supportedVersions %q: %w", a.Name, a.SupportedVersions, err)
+		}
+	}
+	for versions, timeout := range a.Timeouts {
+		if _, err := semver.ParseRange(versions); err != nil {
+			return fmt.Errorf("addon %s has a timeout for invalid versions %q: %w", a.Name, versions, err)
+		}
+		if _, err := time.ParseDuration(timeout); err != nil {
+			return fmt.Errorf("addon %s has an invalid timeout for versions %s: %w", a.Name, versions, err)
+		}
+	}
+	for _, e := range a.ExpectedFailures {
+		if _, err := semver.ParseRange(e.Versions); err != nil {
+			return fmt.Errorf("addon %s has an expected failure for invalid versions %q: %w", a.Name, e.Versions, err)
+		}
+		if !strings.HasPrefix(e.Issue, "https://") {
+			return fmt.Errorf("the expected failure of addon %s on %s must link its issue", a.Name, e.Versions)
+		}
+	}
+	return nil
+}
+
+// Supports reports whether the addon is tested on a kubernetes version.
+func (a Addon) Supports(version semver.Version) bool {
+	if a.SupportedVersions == "" {
+		return true
+	}
+	supported, err := semver.ParseRange(a.SupportedVersions)
+	return err == nil && supported(version)
+}
+
+// Timeout returns the timeout of the addon on a kubernetes version, zero when
+// none of its timeouts applies.
+func (a Addon) Timeout(version semver.Version) time.Duration {
+	var longest time.Duration
+	for versions, timeout := range a.Timeouts {
+		matches, err := semver.ParseRange(versions)
+		if err != nil || !matches(version) {
+			continue
+		}
+		if d, err := time.ParseDuration(timeout); err == nil && d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// Groups maps the names of testing groups to their addons.
+type Groups map[string][]Addon
+
+// Budgets maps the names of testing groups to the duration their scenarios
+// are expected to take, from the budgets key of groups.yaml:
+//
+//	budgets:
+//	  kommander: "25m"
+type Budgets map[string]time.Duration
+
+// file is the YAML representation of groups.yaml: the testing groups and
+// the reserved budgets key.
+type file struct {
+	Budgets map[string]string `yaml:"budgets"`
+	Groups  Groups            `yaml:",inline"`
+}
+
+func parse(path string) (*file, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &file{Groups: make(Groups)}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, fmt.Errorf("could not parse testing groups from %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Load reads the testing groups from a groups.yaml file.
+func Load(path string) (Groups, error) {
+	f, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.Groups, nil
+}
+
+// LoadBudgets reads the duration budgets of the testing groups from a
+// groups.yaml file. Budgets must be positive durations of existing groups.
+func LoadBudgets(path string) (Budgets, error) {
+	f, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	budgets := make(Budgets, len(f.Budgets))
+	for group, budget := range f.Budgets {
+		if _, ok := f.Groups[group]; !ok {
+			return nil, fmt.Errorf("%s has a budget for unknown testing group %s", path, group)
+		}
+		d, err := time.ParseDuration(budget)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s has an invalid budget %q for testing group %s, expected a positive duration", path, budget, group)
+		}
+		budgets[group] = d
+	}
+	return budgets, nil
+}
+
+// Budget returns the budget of a scenario deploying testing groups: the sum of
+// their budgets, false unless every group has one.
+func (b Budgets) Budget(groups []string) (time.Duration, bool) {
+	var total time.Duration
+	for _, group := range groups {
+		budget, ok := b[group]
+		if !ok {
+			return 0, false
+		}
+		total += budget
+	}
+	return total, len(groups) > 0
+}
+
+// Addons returns the names of all addons of a testing group, including the
+// skipped ones.
+func (g Groups) Addons(group string) []string {
+	names := make([]string, 0, len(g[group]))
+	for _, addon := range g[group] {
+		names = append(names, addon.Name)
+	}
+	return names
+}
+
+// Extend adds addons by name to an existing testing group, e.g. those of an
+// extra repository. Addons which are in the group already are left as they are.
+func (g Groups) Extend(group string, names ...string) error {
+	addons, ok := g[group]
+	if !ok {
+		return fmt.Errorf("testing group %s does not exist", group)
+	}
+	existing := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		existing[addon.Name] = true
+	}
+	for _, name := range names {
+		if !existing[name] {
+			addons = append(addons, Addon{Name: name})
+			existing[name] = true
+		}
+	}
+	g[group] = addons
+	return nil
+}
+
+// Skipped maps the names of the addons which are not tested to the reason why:
+// those marked to be skipped in any testing group and those in skipEnv, a
+// comma separated list of addon names (the value of SKIP_ADDONS).
+func (g Groups) Skipped(skipEnv string) map[string]string {
+	skipped := make(map[string]string)
+
+	var names []string
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, addon := range g[name] {
+			if !addon.Skip {
+				continue
+			}
+			reason := addon.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("skipped in group %s", name)
+			}
+			skipped[addon.Name] = reason
+		}
+	}
+
+	for _, name := range strings.Split(skipEnv, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			skipped[name] = "skipped by SKIP_ADDONS"
+		}
+	}
+
+	return skipped
+}
+
+// Unsupported maps the names of the addons which are not tested on a
+// kubernetes version (see Addon.SupportedVersions) to the reason why.
+func (g Groups) Unsupported(version semver.Version) map[string]string {
+	unsupported := make(map[string]string)
+	for _, addons := range g {
+		for _, addon := range addons {
+			if !addon.Supports(version) {
+				unsupported[addon.Name] = fmt.Sprintf("not tested on kubernetes %s (supported: %s)", version, addon.SupportedVersions)
+			}
+		}
+	}
+	return unsupported
+}
+
+// Timeout returns the longest timeout of an addon on a kubernetes version
+// among the testing groups, zero when it has none.
+func (g Groups) Timeout(name string, version semver.Version) time.Duration {
+	var longest time.Duration
+	for _, addons := range g {
+		for _, addon := range addons {
+			if timeout := addon.Timeout(version); addon.Name == name && timeout > longest {
+				longest = timeout
+			}
+		}
+	}
+	return longest
+}
+
+// ExpectedFailure returns the expected failure covering a check of an addon on
+// a kubernetes version and an architecture among the testing groups. Expected
+// failures of the check come before those of all checks of the addon.
+func (g Groups) ExpectedFailure(name, check string, version semver.Version, arch string) (ExpectedFailure, bool) {
+	for _, byCheck := range []bool{true, false} {
+		for _, addons := range g {
+			for _, addon := range addons {
+				if addon.Name != name {
+					continue
+				}
+				for _, e := range addon.ExpectedFailures {
+					if (e.Check != "") == byCheck && e.Covers(check, version, arch) {
+						return e, true
+					}
+				}
+			}
+		}
+	}
+	return ExpectedFailure{}, false
+}
+
+// Conditions returns the conditions an addon is waited for among the testing
+// groups.
+func (g Groups) Conditions(name string) []wait.Condition {
+	var conditions []wait.Condition
+	for _, addons := range g {
+		for _, addon := range addons {
+			if addon.Name == name {
+				conditions = append(conditions, addon.WaitFor...)
+			}
+		}
+	}
+	return conditions
+}
+
+// Namespaces returns the namespaces an addon installs into in addition to the
+// namespace of its addon among the testing groups.
+func (g Groups) Namespaces(name string) []string {
+	var namespaces []string
+	for _, addons := range g {
+		for _, addon := range addons {
+			if addon.Name == name {
+				namespaces = append(namespaces, addon.Namespaces...)
+			}
+		}
+	}
+	return namespaces
+}
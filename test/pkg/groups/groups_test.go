@@ -0,0 +1,246 @@
+package groups
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/wait"
+)
+
+func TestSkipped(t *testing.T) {
+	g := Groups{
+		"kommander": {
+			{Name: "traefik"},
+			{Name: "kubecost", Skip: true, Reason: "blocked on upstream chart fix"},
+			{Name: "velero", Skip: true},
+		},
+	}
+
+	expected := map[string]string{
+		"kubecost": "blocked on upstream chart fix",
+		"velero":   "skipped by SKIP_ADDONS",
+		"dex":      "skipped by SKIP_ADDONS",
+	}
+	if actual := g.Skipped("velero, dex,"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	if actual := g.Skipped(""); actual["velero"] != "skipped in group kommander" {
+		t.Errorf("expected velero to be skipped in group kommander, got %q", actual["velero"])
+	}
+
+	if expected, actual := []string{"traefik", "kubecost", "velero"}, g.Addons("kommander"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	g := Groups{
+		"kommander": {
+			{Name: "traefik"},
+			{Name: "velero", SupportedVersions: ">=1.16.0 <1.18.0", Timeouts: map[string]string{">=1.17.0": "15m", ">=1.16.0": "10m"}},
+		},
+	}
+
+	if unsupported := g.Unsupported(semver.MustParse("1.17.3")); len(unsupported) != 0 {
+		t.Errorf("expected all addons to support 1.17.3, got %v", unsupported)
+	}
+	expected := map[string]string{"velero": "not tested on kubernetes 1.18.0 (supported: >=1.16.0 <1.18.0)"}
+	if unsupported := g.Unsupported(semver.MustParse("1.18.0")); !reflect.DeepEqual(unsupported, expected) {
+		t.Errorf("expected %v, got %v", expected, unsupported)
+	}
+
+	for version, expected := range map[string]time.Duration{"1.16.4": 10 * time.Minute, "1.17.0": 15 * time.Minute, "1.15.0": 0} {
+		if timeout := g.Timeout("velero", semver.MustParse(version)); timeout != expected {
+			t.Errorf("expected a timeout of %s on %s, got %s", expected, version, timeout)
+		}
+	}
+	if timeout := g.Timeout("traefik", semver.MustParse("1.17.0")); timeout != 0 {
+		t.Errorf("expected no timeout for traefik, got %s", timeout)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	var g Groups
+	if err := yaml.UnmarshalStrict([]byte(`
+kommander:
+  - "traefik"
+  - name: "prometheus"
+    waitFor:
+      - "crd/prometheuses.monitoring.coreos.com established"
+`), &g); err != nil {
+		t.Fatal(err)
+	}
+	expected := []wait.Condition{{Kind: "crd", Name: "prometheuses.monitoring.coreos.com", State: "established"}}
+	if actual := g.Conditions("prometheus"); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := g.Conditions("traefik"); len(actual) != 0 {
+		t.Errorf("expected no conditions for traefik, got %v", actual)
+	}
+
+	if err := yaml.UnmarshalStrict([]byte(`
+kommander:
+  - name: "prometheus"
+    waitFor:
+      - "crd/prometheuses.monitoring.coreos.com ready"
+`), &g); err == nil {
+		t.Error("expected an invalid condition to be rejected")
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	var g Groups
+	if err := yaml.UnmarshalStrict([]byte(`
+kommander:
+  - "traefik"
+  - name: "kommander"
+    namespaces:
+      - "kubefed"
+`), &g); err != nil {
+		t.Fatal(err)
+	}
+	if actual := g.Namespaces("kommander"); !reflect.DeepEqual([]string{"kubefed"}, actual) {
+		t.Errorf("expected [kubefed], got %v", actual)
+	}
+	if actual := g.Namespaces("traefik"); len(actual) != 0 {
+		t.Errorf("expected no namespaces for traefik, got %v", actual)
+	}
+}
+
+func TestExtend(t *testing.T) {
+	g := Groups{"kommander": {{Name: "traefik"}, {Name: "kubecost", Skip: true}}}
+	if err := g.Extend("kommander", "kubecost", "kommander-gitops", "kommander-gitops"); err != nil {
+		t.Fatal(err)
+	}
+	if actual := g.Addons("kommander"); !reflect.DeepEqual([]string{"traefik", "kubecost", "kommander-gitops"}, actual) {
+		t.Errorf("expected kommander-gitops to be added once, got %v", actual)
+	}
+	if !g["kommander"][1].Skip {
+		t.Error("expected kubecost to remain skipped")
+	}
+	if err := g.Extend("gitops", "kommander-gitops"); err == nil {
+		t.Error("expected an unknown testing group to be rejected")
+	}
+}
+
+func TestExpectedFailures(t *testing.T) {
+	var g Groups
+	if err := yaml.UnmarshalStrict([]byte(`
+kommander:
+  - "traefik"
+  - name: "dex"
+    expectedFailures:
+      - versions: "<1.16.0"
+        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/1"
+      - versions: ">=1.17.0"
+        check: "dex is ready"
+        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/2"
+  - name: "kommander"
+    expectedFailures:
+      - versions: ">=1.16.0"
+        architectures: ["arm64"]
+        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/3"
+`), &g); err != nil {
+		t.Fatal(err)
+	}
+
+	if e, ok := g.ExpectedFailure("dex", "dex serves logins", semver.MustParse("1.15.7"), "amd64"); !ok || e.Versions != "<1.16.0" {
+		t.Errorf("expected all checks of dex to fail on 1.15.7, got %v %v", e, ok)
+	}
+	if e, ok := g.ExpectedFailure("dex", "dex is ready", semver.MustParse("1.17.3"), "amd64"); !ok || e.Check != "dex is ready" {
+		t.Errorf("expected dex is ready to fail on 1.17.3, got %v %v", e, ok)
+	}
+	if e, ok := g.ExpectedFailure("kommander", "kommander is ready", semver.MustParse("1.16.4"), "arm64"); !ok || len(e.Architectures) != 1 {
+		t.Errorf("expected the checks of kommander to fail on arm64, got %v %v", e, ok)
+	}
+	for _, c := range []struct{ addon, check, version, arch string }{
+		{"dex", "dex serves logins", "1.17.3", "amd64"},
+		{"dex", "dex is ready", "1.16.4", "amd64"},
+		{"traefik", "dex is ready", "1.15.7", "amd64"},
+		{"kommander", "kommander is ready", "1.16.4", "amd64"},
+	} {
+		if e, ok := g.ExpectedFailure(c.addon, c.check, semver.MustParse(c.version), c.arch); ok {
+			t.Errorf("expected %s of %s to pass on %s (%s), got %v", c.check, c.addon, c.version, c.arch, e)
+		}
+	}
+
+	for _, invalid := range []string{`
+kommander:
+  - name: "dex"
+    expectedFailures:
+      - versions: "1.15"
+        issue: "https://github.com/mesosphere/kubeaddons-kommander/issues/1"
+`, `
+kommander:
+  - name: "dex"
+    expectedFailures:
+      - versions: "<1.16.0"
+`} {
+		if err := yaml.UnmarshalStrict([]byte(invalid), &g); err == nil {
+			t.Errorf("expected an error for:%s", invalid)
+		}
+	}
+}
+
+func TestLoadBudgets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "groups-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "groups.yaml")
+
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`
+budgets:
+  kommander: "25m"
+  istio: "10m"
+kommander:
+  - "traefik"
+istio:
+  - "istio"
+minimal:
+  - "dex"
+`)
+	g, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := g["budgets"]; ok || len(g) != 3 {
+		t.Errorf("expected the budgets not to be a testing group, got %v", g)
+	}
+	budgets, err := LoadBudgets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if budget, ok := budgets.Budget([]string{"kommander", "istio"}); !ok || budget != 35*time.Minute {
+		t.Errorf("expected a budget of 35m, got %s (%t)", budget, ok)
+	}
+	if _, ok := budgets.Budget([]string{"kommander", "minimal"}); ok {
+		t.Errorf("expected no budget when a group has none")
+	}
+
+	for content, expected := range map[string]string{
+		"budgets:\n  unknown: 5m\nkommander:\n  - traefik\n":     "unknown testing group unknown",
+		"budgets:\n  kommander: soon\nkommander:\n  - traefik\n": `invalid budget "soon"`,
+		"budgets:\n  kommander: -5m\nkommander:\n  - traefik\n":  `invalid budget "-5m"`,
+	} {
+		write(content)
+		if _, err := LoadBudgets(path); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package helm
+
+import (
+	"fmt"
+	"time"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/storage/driver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// namespaceManifest creates the namespace of a release, which the install
+// action of helm expects to exist.
+const namespaceManifest = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+`
+
+// Install installs (or upgrades) the chart of an addon as a helm release in
+// the cluster of the current context of a kubectl client, named and configured like the
+// release the kubeaddons controller would create, and waits for it to be ready.
+func Install(k kubectl.Client, addon v1beta1.AddonInterface, timeout time.Duration) error {
+	ref := addon.GetAddonSpec().ChartReference
+	if ref == nil {
+		return fmt.Errorf("addon %s is not deployed via a helm chart", addon.GetName())
+	}
+
+	namespace := render.Namespace(addon)
+	if err := k.Apply([]byte(fmt.Sprintf(namespaceManifest, namespace))); err != nil {
+		return fmt.Errorf("could not create the namespace of addon %s: %w", addon.GetName(), err)
+	}
+	settings, cfg, err := configuration(k, namespace)
+	if err != nil {
+		return err
+	}
+
+	install := action.NewInstall(cfg)
+	install.ReleaseName = render.ReleaseName(addon)
+	install.Namespace = namespace
+	install.Wait = true
+	install.Timeout = timeout
+	install.Version = ref.Version
+	if ref.Repo != nil {
+		install.RepoURL = *ref.Repo
+	}
+
+	path, err := install.LocateChart(ref.Chart, settings)
+	if err != nil {
+		return fmt.Errorf("could not download the chart of addon %s: %w", addon.GetName(), err)
+	}
+	chart, err := loader.Load(path)
+	if err != nil {
+		return fmt.Errorf("could not load the chart of addon %s: %w", addon.GetName(), err)
+	}
+	values := make(map[string]interface{})
+	if ref.Values != nil {
+		parsed, err := chartutil.ReadValues([]byte(*ref.Values))
+		if err != nil {
+			return fmt.Errorf("could not parse the values of addon %s: %w", addon.GetName(), err)
+		}
+		values = parsed
+	}
+
+	// like `helm upgrade --install`, releases which exist are upgraded
+	history := action.NewHistory(cfg)
+	history.Max = 1
+	if _, err := history.Run(install.ReleaseName); err == driver.ErrReleaseNotFound {
+		if _, err := install.Run(chart, values); err != nil {
+			return fmt.Errorf("could not install addon %s: %w", addon.GetName(), err)
+		}
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("could not get the release of addon %s: %w", addon.GetName(), err)
+	}
+
+	upgrade := action.NewUpgrade(cfg)
+	upgrade.Namespace = namespace
+	upgrade.Wait = true
+	upgrade.Timeout = timeout
+	if _, err := upgrade.Run(install.ReleaseName, chart, values); err != nil {
+		return fmt.Errorf("could not upgrade addon %s: %w", addon.GetName(), err)
+	}
+	return nil
+}
+
+// Uninstall removes the helm release of an addon.
+func Uninstall(k kubectl.Client, addon v1beta1.AddonInterface) error {
+	_, cfg, err := configuration(k, render.Namespace(addon))
+	if err != nil {
+		return err
+	}
+	if _, err := action.NewUninstall(cfg).Run(render.ReleaseName(addon)); err != nil {
+		return fmt.Errorf("could not uninstall addon %s: %w", addon.GetName(), err)
+	}
+	return nil
+}
+
+// Values returns the computed values of the helm release of an addon: the
+// default values of its chart merged with those it was deployed with.
+func Values(k kubectl.Client, addon v1beta1.AddonInterface) (map[string]interface{}, error) {
+	_, cfg, err := configuration(k, render.Namespace(addon))
+	if err != nil {
+		return nil, err
+	}
+	get := action.NewGetValues(cfg)
+	get.AllValues = true
+	values, err := get.Run(render.ReleaseName(addon))
+	if err != nil {
+		return nil, fmt.Errorf("could not get the values of addon %s: %w", addon.GetName(), err)
+	}
+	return values, nil
+}
+
+// configuration returns the helm settings (e.g. the repositories of
+// HELM_REPOSITORY_CONFIG) and an action configuration for the releases in a
+// namespace of the cluster of a kubectl client, storing releases in secrets
+// like helm does by default.
+func configuration(k kubectl.Client, namespace string) (*cli.EnvSettings, *action.Configuration, error) {
+	settings := cli.New()
+	if k.Kubeconfig != "" {
+		settings.KubeConfig = k.Kubeconfig
+	}
+
+	cfg := new(action.Configuration)
+	getter := kube.GetConfig(settings.KubeConfig, settings.KubeContext, namespace)
+	if err := cfg.Init(getter, namespace, "secret", debug(settings)); err != nil {
+		return nil, nil, fmt.Errorf("could not configure helm: %w", err)
+	}
+	return settings, cfg, nil
+}
+
+// debug returns the debug log of helm, which logs like `helm --debug` when
+// HELM_DEBUG is true.
+func debug(settings *cli.EnvSettings) action.DebugLog {
+	return func(format string, v ...interface{}) {
+		if settings.Debug {
+			fmt.Printf("helm: "+format+"\n", v...)
+		}
+	}
+}
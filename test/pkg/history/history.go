@@ -0,0 +1,202 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// Run is the record of a finished test run in the history.
+type Run struct {
+	ID       string        `json:"id"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+
+	// Profile is the profile of the run, if any.
+	Profile string `json:"profile,omitempty"`
+
+	Addons []report.AddonResult `json:"addons"`
+	Checks []report.CheckResult `json:"checks,omitempty"`
+
+	// Scenarios are the durations of the scenarios of the run, the sum of
+	// their phases.
+	Scenarios map[string]time.Duration `json:"scenarios,omitempty"`
+}
+
+// FromReport returns the record of a finished run from its report.
+func FromReport(id, profile string, r *report.Report) Run {
+	run := Run{
+		ID:       id,
+		Started:  r.Started,
+		Duration: r.Finished.Sub(r.Started),
+		Profile:  profile,
+		Addons:   r.Addons,
+		Checks:   r.Checks,
+	}
+	for _, phase := range r.Phases {
+		if run.Scenarios == nil {
+			run.Scenarios = make(map[string]time.Duration)
+		}
+		run.Scenarios[phase.Scenario] += phase.Duration
+	}
+	return run
+}
+
+// Store is the history of runs: a file with a run per line (JSON), or a
+// prefix in S3 under which every run is an object of its own
+// (<prefix>/<id>.json), read and written with the aws CLI, so runs recorded at
+// the same time don't replace each other's record.
+type Store struct {
+	// Path is the path of the file of histories kept in a file.
+	Path string
+
+	// URL is the s3://<bucket>/<prefix> of histories kept in S3.
+	URL string
+}
+
+// Open returns the store at a location, the path of a file or the URL of a
+// prefix in S3 (s3://<bucket>/<prefix>).
+func Open(location string) Store {
+	if strings.HasPrefix(location, "s3://") {
+		return Store{URL: strings.TrimSuffix(location, "/")}
+	}
+	return Store{Path: location}
+}
+
+func (s Store) String() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return s.Path
+}
+
+// Record appends a run to the history, or uploads it as an object of its own
+// for histories in S3.
+func (s Store) Record(run Run) error {
+	b, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if s.URL != "" {
+		return s.upload(run.ID, b)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Runs returns the runs of the history, oldest first. A history which does not
+// exist yet has no runs.
+func (s Store) Runs() ([]Run, error) {
+	var runs []Run
+	var err error
+	if s.URL != "" {
+		runs, err = s.download()
+	} else {
+		runs, err = s.read()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+	return runs, nil
+}
+
+func (s Store) read() ([]Run, error) {
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("could not parse line %d of history %s: %w", line, s, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// download returns the runs of a history in S3, whose objects are copied to a
+// temporary directory of their own.
+func (s Store) download() ([]Run, error) {
+	dir, err := ioutil.TempDir("", "history-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if out, err := exec.Command("aws", "s3", "cp", "--recursive", "--exclude", "*", "--include", "*.json", s.URL+"/", dir).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("could not download history %s: %s", s.URL, strings.TrimSpace(string(out)))
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(files))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var run Run
+		if err := json.Unmarshal(b, &run); err != nil {
+			return nil, fmt.Errorf("could not parse run %s of history %s: %w", filepath.Base(file), s, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// upload uploads a run to a history in S3 as <prefix>/<id>.json, from a
+// temporary file of its own.
+func (s Store) upload(id string, b []byte) error {
+	f, err := ioutil.TempFile("", "history-"+id+"-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	url := s.URL + "/" + id + ".json"
+	if out, err := exec.Command("aws", "s3", "cp", f.Name(), url).CombinedOutput(); err != nil {
+		return fmt.Errorf("could not upload run %s to history %s: %s", id, s.URL, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package history
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// testRun returns a run started at an offset from a fixed time, in which the
+// kubecost addon and the check of its UI have a status.
+func testRun(id string, offset time.Duration, kubecost report.Status) Run {
+	started := time.Unix(1600000000, 0).Add(offset)
+	return FromReport(id, "", &report.Report{
+		Started:  started,
+		Finished: started.Add(20 * time.Minute),
+		Addons: []report.AddonResult{
+			{Group: "kommander", Addon: "kommander", Status: report.Passed},
+			{Group: "kommander", Addon: "kubecost", Status: kubecost},
+		},
+		Checks: []report.CheckResult{
+			{Scenario: "kommander-minimal", Check: "kubecost ui is ready", Status: kubecost},
+		},
+		Phases: []report.Phase{
+			{Scenario: "kommander-minimal", Phase: "provisioning", Duration: 5 * time.Minute},
+			{Scenario: "kommander-minimal", Phase: "deploying", Duration: time.Duration(offset.Hours()+10) * time.Minute},
+		},
+	})
+}
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := Open(filepath.Join(dir, "history", "runs.jsonl"))
+	if runs, err := store.Runs(); err != nil || len(runs) != 0 {
+		t.Fatalf("expected a new history to have no runs, got %v, %v", runs, err)
+	}
+
+	// runs are returned by the time they started, not by when they were
+	// recorded
+	for _, run := range []Run{testRun("b", time.Hour, report.Failed), testRun("a", 0, report.Passed)} {
+		if err := store.Record(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs, err := store.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].ID != "a" || runs[1].ID != "b" {
+		t.Fatalf("expected runs a and b, got %+v", runs)
+	}
+	expected := testRun("a", 0, report.Passed)
+	if !runs[0].Started.Equal(expected.Started) {
+		t.Errorf("expected run a to have started at %s, got %s", expected.Started, runs[0].Started)
+	}
+	expected.Started = runs[0].Started
+	if !reflect.DeepEqual(runs[0], expected) {
+		t.Errorf("expected the run to be recorded as it was, got %+v", runs[0])
+	}
+	if runs[1].Scenarios["kommander-minimal"] != 16*time.Minute || runs[1].Duration != 20*time.Minute {
+		t.Errorf("unexpected timings of run b: %+v", runs[1])
+	}
+
+	if s := Open("s3://test-history/kommander/"); s.URL != "s3://test-history/kommander" || s.Path != "" {
+		t.Errorf("expected a history under the prefix kommander in S3, got %+v", s)
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	var runs []Run
+	for i, status := range []report.Status{report.Failed, report.Passed, report.Skipped, report.Passed, report.Failed, report.Passed} {
+		runs = append(runs, testRun(string(rune('a'+i)), time.Duration(i)*time.Hour, status))
+	}
+
+	addons := Addons(Last(runs, 5))
+	expected := []Summary{{Name: "kommander", Runs: 5, Passed: 5}, {Name: "kubecost", Runs: 4, Passed: 3}}
+	if !reflect.DeepEqual(expected, addons) {
+		t.Fatalf("expected %v, got %v", expected, addons)
+	}
+	if kubecost, ok := Lookup(addons, "kubecost"); !ok || kubecost.PassRate() != 0.75 {
+		t.Errorf("expected kubecost to pass 75%% of the runs, got %v", kubecost)
+	}
+	if _, ok := Lookup(addons, "velero"); ok {
+		t.Error("expected no summary of an addon which was not tested")
+	}
+
+	checks := Checks(runs)
+	if expected := []Summary{{Name: "kommander-minimal/kubecost ui is ready", Runs: 5, Passed: 3}}; !reflect.DeepEqual(expected, checks) {
+		t.Errorf("expected %v, got %v", expected, checks)
+	}
+	if flaky := Flaky(append(addons, checks...), 5); !reflect.DeepEqual(flaky, checks) {
+		t.Errorf("expected the check to be flaky in 5 runs, got %v", flaky)
+	}
+
+	timings := Timings(runs)
+	if expected := []Timing{{Scenario: "kommander-minimal", Runs: 6, Mean: 17*time.Minute + 30*time.Second, Max: 20 * time.Minute}}; !reflect.DeepEqual(expected, timings) {
+		t.Errorf("expected %v, got %v", expected, timings)
+	}
+
+	table := Table(addons, checks, timings)
+	for _, line := range []string{"kubecost   4     3       75%", "kommander-minimal  6     17m30s  20m0s"} {
+		if !strings.Contains(table, line) {
+			t.Errorf("expected the table to contain %q, got:\n%s", line, table)
+		}
+	}
+}
+
+func TestWorse(t *testing.T) {
+	run := FromReport("a", "", &report.Report{Addons: []report.AddonResult{
+		{Group: "kommander", Addon: "cert-manager", Status: report.Passed},
+		{Group: "istio", Addon: "cert-manager", Status: report.Failed},
+		{Group: "kommander", Addon: "velero", Status: report.Skipped},
+	}})
+	if expected := []Summary{{Name: "cert-manager", Runs: 1}}; !reflect.DeepEqual(expected, Addons([]Run{run})) {
+		t.Errorf("expected an addon which failed in one group to fail, got %v", Addons([]Run{run}))
+	}
+}
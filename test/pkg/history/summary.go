@@ -0,0 +1,191 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// Summary is the outcome of an addon or a check over runs of the history.
+type Summary struct {
+	// Name is the addon, or the check as <scenario>/<check>.
+	Name string
+
+	// Runs are the runs which tested the addon or ran the check, skipped
+	// ones aside.
+	Runs int
+
+	// Passed are the runs it passed in. Quarantined checks and expected
+	// failures failed.
+	Passed int
+}
+
+// PassRate returns the share of the runs it passed in, 0 without runs.
+func (s Summary) PassRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Passed) / float64(s.Runs)
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%s passed %d of %d runs (%.0f%%)", s.Name, s.Passed, s.Runs, 100*s.PassRate())
+}
+
+// Last returns the last n runs, all runs when n is not positive.
+func Last(runs []Run, n int) []Run {
+	if n <= 0 || n >= len(runs) {
+		return runs
+	}
+	return runs[len(runs)-n:]
+}
+
+// Addons returns the summaries of the addons tested by the runs, sorted by
+// addon. An addon tested in several groups of a run counts once, as failed if
+// it failed in any.
+func Addons(runs []Run) []Summary {
+	outcomes := make(map[string][]report.Status)
+	for _, run := range runs {
+		statuses := make(map[string]report.Status)
+		for _, result := range run.Addons {
+			statuses[result.Addon] = worse(statuses[result.Addon], result.Status)
+		}
+		for addon, status := range statuses {
+			outcomes[addon] = append(outcomes[addon], status)
+		}
+	}
+	return summaries(outcomes)
+}
+
+// Checks returns the summaries of the checks run by the runs, sorted by
+// <scenario>/<check>.
+func Checks(runs []Run) []Summary {
+	outcomes := make(map[string][]report.Status)
+	for _, run := range runs {
+		for _, result := range run.Checks {
+			name := result.Scenario + "/" + result.Check
+			outcomes[name] = append(outcomes[name], result.Status)
+		}
+	}
+	return summaries(outcomes)
+}
+
+// Lookup returns the summary with a name, and whether there is one.
+func Lookup(summaries []Summary, name string) (Summary, bool) {
+	for _, s := range summaries {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Summary{Name: name}, false
+}
+
+// Flaky returns the summaries which neither always passed nor always failed
+// in at least minRuns runs, the candidates for quarantine (see
+// quarantine.yaml), sorted by their pass rate.
+func Flaky(summaries []Summary, minRuns int) []Summary {
+	var flaky []Summary
+	for _, s := range summaries {
+		if s.Runs >= minRuns && s.Passed > 0 && s.Passed < s.Runs {
+			flaky = append(flaky, s)
+		}
+	}
+	sort.SliceStable(flaky, func(i, j int) bool { return flaky[i].PassRate() < flaky[j].PassRate() })
+	return flaky
+}
+
+// Timing is the duration of a scenario over runs of the history.
+type Timing struct {
+	Scenario string
+	Runs     int
+	Mean     time.Duration
+	Max      time.Duration
+}
+
+// Timings returns the timings of the scenarios of the runs, sorted by
+// scenario.
+func Timings(runs []Run) []Timing {
+	durations := make(map[string][]time.Duration)
+	for _, run := range runs {
+		for scenario, d := range run.Scenarios {
+			durations[scenario] = append(durations[scenario], d)
+		}
+	}
+	timings := make([]Timing, 0, len(durations))
+	for scenario, ds := range durations {
+		t := Timing{Scenario: scenario, Runs: len(ds)}
+		var total time.Duration
+		for _, d := range ds {
+			total += d
+			if d > t.Max {
+				t.Max = d
+			}
+		}
+		t.Mean = total / time.Duration(len(ds))
+		timings = append(timings, t)
+	}
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Scenario < timings[j].Scenario })
+	return timings
+}
+
+// Table renders summaries and timings as text tables.
+func Table(addons, checks []Summary, timings []Timing) string {
+	b := new(strings.Builder)
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	for _, section := range []struct {
+		header    string
+		summaries []Summary
+	}{{"ADDON", addons}, {"CHECK", checks}} {
+		if len(section.summaries) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\tRUNS\tPASSED\tPASS RATE\n", section.header)
+		for _, s := range section.summaries {
+			fmt.Fprintf(w, "%s\t%d\t%d\t%.0f%%\n", s.Name, s.Runs, s.Passed, 100*s.PassRate())
+		}
+		fmt.Fprintln(w)
+	}
+	if len(timings) > 0 {
+		fmt.Fprintln(w, "SCENARIO\tRUNS\tMEAN\tMAX")
+		for _, t := range timings {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", t.Scenario, t.Runs, t.Mean.Round(time.Second), t.Max.Round(time.Second))
+		}
+	}
+	w.Flush()
+	return b.String()
+}
+
+func summaries(outcomes map[string][]report.Status) []Summary {
+	summaries := make([]Summary, 0, len(outcomes))
+	for name, statuses := range outcomes {
+		s := Summary{Name: name}
+		for _, status := range statuses {
+			switch status {
+			case report.Skipped:
+				continue
+			case report.Passed:
+				s.Passed++
+			}
+			s.Runs++
+		}
+		if s.Runs > 0 {
+			summaries = append(summaries, s)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+// worse returns the worse of two statuses of an addon: failed over passed over
+// skipped (or none).
+func worse(a, b report.Status) report.Status {
+	rank := map[report.Status]int{report.Skipped: 1, report.Passed: 2, report.Failed: 3}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,195 @@
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	hookAnnotation         = "helm.sh/hook"
+	weightAnnotation       = "helm.sh/hook-weight"
+	deletePolicyAnnotation = "helm.sh/hook-delete-policy"
+)
+
+// events are the hook events helm knows, crd-install is helm 2 only.
+var events = map[string]bool{
+	"pre-install": true, "post-install": true,
+	"pre-upgrade": true, "post-upgrade": true,
+	"pre-delete": true, "post-delete": true,
+	"pre-rollback": true, "post-rollback": true,
+	"crd-install": true, "test": true, "test-success": true, "test-failure": true,
+}
+
+// deletePolicies are the hook delete policies helm knows.
+var deletePolicies = map[string]bool{
+	"hook-succeeded":       true,
+	"hook-failed":          true,
+	"before-hook-creation": true,
+}
+
+// Hook is an object of a chart which helm creates on hook events rather than
+// as part of the release.
+type Hook struct {
+	Addon     string
+	Kind      string
+	Namespace string
+	Name      string
+
+	Events         []string
+	DeletePolicies []string
+}
+
+func (h Hook) String() string {
+	return fmt.Sprintf("%s hook %s/%s/%s of addon %s", strings.Join(h.Events, ","), strings.ToLower(h.Kind), h.Namespace, h.Name, h.Addon)
+}
+
+// Install returns whether the hook runs when an addon is installed.
+func (h Hook) Install() bool {
+	return h.has(h.Events, "pre-install") || h.has(h.Events, "post-install")
+}
+
+// Deleted returns whether helm deletes the hook once it succeeded.
+func (h Hook) Deleted() bool {
+	return h.has(h.DeletePolicies, "hook-succeeded")
+}
+
+func (Hook) has(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Problem is a hook which is invalid or did not run as declared.
+type Problem struct {
+	Hook   Hook
+	Reason string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Hook, p.Reason)
+}
+
+// Extract returns the hooks among rendered objects, along with the problems
+// of their annotations: unknown events or delete policies and weights which
+// aren't integers.
+func Extract(objects []render.Object) ([]Hook, []Problem) {
+	var hooks []Hook
+	var problems []Problem
+	for _, obj := range objects {
+		annotations := map[string]interface{}{}
+		if metadata, ok := obj.Content["metadata"].(map[string]interface{}); ok {
+			if a, ok := metadata["annotations"].(map[string]interface{}); ok {
+				annotations = a
+			}
+		}
+		hook, _ := annotations[hookAnnotation].(string)
+		if hook == "" {
+			continue
+		}
+		h := Hook{Addon: obj.Addon, Kind: obj.Kind, Namespace: obj.Namespace, Name: obj.Name, Events: split(hook)}
+		if policies, _ := annotations[deletePolicyAnnotation].(string); policies != "" {
+			h.DeletePolicies = split(policies)
+		}
+
+		for _, event := range h.Events {
+			if !events[event] {
+				problems = append(problems, Problem{Hook: h, Reason: fmt.Sprintf("unknown hook event %q", event)})
+			}
+		}
+		for _, policy := range h.DeletePolicies {
+			if !deletePolicies[policy] {
+				problems = append(problems, Problem{Hook: h, Reason: fmt.Sprintf("unknown hook delete policy %q", policy)})
+			}
+		}
+		if weight, ok := annotations[weightAnnotation]; ok {
+			if _, err := strconv.Atoi(fmt.Sprint(weight)); err != nil {
+				problems = append(problems, Problem{Hook: h, Reason: fmt.Sprintf("hook weight %q is not an integer", weight)})
+			}
+		}
+		hooks = append(hooks, h)
+	}
+	sort.Slice(hooks, func(i, j int) bool {
+		a, b := hooks[i], hooks[j]
+		return a.Addon+"/"+a.Kind+"/"+a.Namespace+"/"+a.Name < b.Addon+"/"+b.Kind+"/"+b.Namespace+"/"+b.Name
+	})
+	return hooks, problems
+}
+
+func split(s string) []string {
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// job is the state of a hook job in the cluster.
+type job struct {
+	Status struct {
+		Succeeded  int `json:"succeeded"`
+		Failed     int `json:"failed"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// Verify verifies the install hook jobs of deployed addons against the
+// cluster of a kubectl client: hook jobs must not have failed,
+// those deleted when they succeed must be gone and the others must have
+// completed.
+func Verify(k kubectl.Client, hooks []Hook) ([]Problem, error) {
+	var problems []Problem
+	for _, h := range hooks {
+		if h.Kind != "Job" || !h.Install() {
+			continue
+		}
+		out, err := k.Output("get", "job", h.Name, "--namespace", h.Namespace, "--ignore-not-found", "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(out))) == 0 {
+			if !h.Deleted() {
+				problems = append(problems, Problem{Hook: h, Reason: "was not found although its delete policy keeps it once it succeeded"})
+			}
+			continue
+		}
+		if reason := h.state(out); reason != "" {
+			problems = append(problems, Problem{Hook: h, Reason: reason})
+		}
+	}
+	return problems, nil
+}
+
+// state returns the problem of a hook job which exists, if any.
+func (h Hook) state(out []byte) string {
+	var j job
+	if err := json.Unmarshal(out, &j); err != nil {
+		return fmt.Sprintf("could not decode the job: %s", err)
+	}
+	for _, c := range j.Status.Conditions {
+		if c.Type == "Failed" && c.Status == "True" {
+			return fmt.Sprintf("failed: %s", c.Message)
+		}
+	}
+	switch {
+	case j.Status.Succeeded == 0:
+		return fmt.Sprintf("did not complete (%d failed pods)", j.Status.Failed)
+	case h.Deleted():
+		return "completed, but was not deleted although its delete policy is hook-succeeded"
+	}
+	return ""
+}
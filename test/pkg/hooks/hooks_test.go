@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func hookObject(kind, name string, annotations map[string]interface{}) render.Object {
+	return render.Object{
+		Addon:     "kommander",
+		Kind:      kind,
+		Namespace: "kommander",
+		Name:      name,
+		Content: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "annotations": annotations},
+		},
+	}
+}
+
+func TestExtract(t *testing.T) {
+	objects := []render.Object{
+		hookObject("Job", "kommander-init", map[string]interface{}{"helm.sh/hook": "post-install, post-upgrade", "helm.sh/hook-delete-policy": "hook-succeeded", "helm.sh/hook-weight": "-5"}),
+		hookObject("Job", "kommander-cleanup", map[string]interface{}{"helm.sh/hook": "pre-delete", "helm.sh/hook-delete-policy": "hook-suceeded"}),
+		hookObject("ConfigMap", "kommander-config", map[string]interface{}{"helm.sh/hook": "post-instal", "helm.sh/hook-weight": "first"}),
+		hookObject("Deployment", "kommander", nil),
+	}
+
+	hooks, problems := Extract(objects)
+	expected := []Hook{
+		{Addon: "kommander", Kind: "ConfigMap", Namespace: "kommander", Name: "kommander-config", Events: []string{"post-instal"}},
+		{Addon: "kommander", Kind: "Job", Namespace: "kommander", Name: "kommander-cleanup", Events: []string{"pre-delete"}, DeletePolicies: []string{"hook-suceeded"}},
+		{Addon: "kommander", Kind: "Job", Namespace: "kommander", Name: "kommander-init", Events: []string{"post-install", "post-upgrade"}, DeletePolicies: []string{"hook-succeeded"}},
+	}
+	if !reflect.DeepEqual(expected, hooks) {
+		t.Errorf("expected %+v, got %+v", expected, hooks)
+	}
+
+	var reasons []string
+	for _, p := range problems {
+		reasons = append(reasons, p.Reason)
+	}
+	expectedReasons := []string{`unknown hook delete policy "hook-suceeded"`, `unknown hook event "post-instal"`, `hook weight "first" is not an integer`}
+	if !reflect.DeepEqual(expectedReasons, reasons) {
+		t.Errorf("expected %v, got %v", expectedReasons, reasons)
+	}
+
+	if !hooks[2].Install() || !hooks[2].Deleted() || hooks[1].Install() {
+		t.Errorf("expected only kommander-init to be an install hook which is deleted, got %+v", hooks)
+	}
+}
+
+func TestState(t *testing.T) {
+	kept := Hook{Kind: "Job", Events: []string{"post-install"}, DeletePolicies: []string{"before-hook-creation"}}
+	deleted := Hook{Kind: "Job", Events: []string{"post-install"}, DeletePolicies: []string{"hook-succeeded"}}
+
+	for _, tc := range []struct {
+		hook     Hook
+		job      string
+		expected string
+	}{
+		{hook: kept, job: `{"status": {"succeeded": 1}}`},
+		{hook: kept, job: `{"status": {"failed": 3}}`, expected: "did not complete (3 failed pods)"},
+		{hook: kept, job: `{"status": {"failed": 6, "conditions": [{"type": "Failed", "status": "True", "message": "Job has reached the specified backoff limit"}]}}`, expected: "failed: Job has reached the specified backoff limit"},
+		{hook: deleted, job: `{"status": {"succeeded": 1}}`, expected: "completed, but was not deleted although its delete policy is hook-succeeded"},
+	} {
+		if actual := tc.hook.state([]byte(tc.job)); actual != tc.expected {
+			t.Errorf("expected %q for %s, got %q", tc.expected, tc.job, actual)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package hygiene
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a problem of a YAML file, at a line of the file (0 for the file
+// as a whole).
+type Problem struct {
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("line %d: %s", p.Line, p.Message)
+}
+
+// Check returns the problems of the YAML documents in b which make it fragile
+// to review and to parse: CRLF line endings, tabs in the indentation, keys
+// defined more than once in a mapping (which the strict parser of the
+// kubeaddons controller rejects, and other parsers resolve differently), and
+// content which changes when it is normalized, i.e. decoded and re-serialized
+// with sorted keys. Problems are sorted by line.
+func Check(b []byte) []Problem {
+	var problems []Problem
+	if n := bytes.Count(b, []byte("\r\n")); n > 0 {
+		problems = append(problems, Problem{Message: fmt.Sprintf("%d lines end with CRLF", n)})
+	}
+	for i, line := range strings.Split(string(b), "\n") {
+		indentation := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if strings.Contains(indentation, "\t") {
+			problems = append(problems, Problem{Line: i + 1, Message: "indented with tabs"})
+		}
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	for doc := 1; ; doc++ {
+		var node yaml.Node
+		if err := decoder.Decode(&node); err != nil {
+			if err != io.EOF {
+				problems = append(problems, Problem{Message: fmt.Sprintf("document %d can't be parsed: %s", doc, err)})
+			}
+			break
+		}
+		// documents with duplicate keys can't be decoded to be normalized
+		if duplicates := duplicateKeys(&node); len(duplicates) > 0 {
+			problems = append(problems, duplicates...)
+			continue
+		}
+		if err := roundTrip(&node); err != nil {
+			problems = append(problems, Problem{Line: node.Line, Message: fmt.Sprintf("document %d %s", doc, err)})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems
+}
+
+// duplicateKeys returns a problem for every key defined more than once in a
+// mapping of a node or of its children.
+func duplicateKeys(node *yaml.Node) []Problem {
+	var problems []Problem
+	if node.Kind == yaml.MappingNode {
+		defined := make(map[string]int)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				continue
+			}
+			if line, ok := defined[key.Value]; ok {
+				problems = append(problems, Problem{Line: key.Line, Message: fmt.Sprintf("key %q is already defined on line %d", key.Value, line)})
+				continue
+			}
+			defined[key.Value] = key.Line
+		}
+	}
+	for _, child := range node.Content {
+		problems = append(problems, duplicateKeys(child)...)
+	}
+	return problems
+}
+
+// roundTrip returns an error unless the content of a document is unchanged
+// when it is normalized, and its normalization is stable.
+func roundTrip(node *yaml.Node) error {
+	var content interface{}
+	if err := node.Decode(&content); err != nil {
+		return fmt.Errorf("can't be decoded: %w", err)
+	}
+	normalized, err := yaml.Marshal(content)
+	if err != nil {
+		return fmt.Errorf("can't be normalized: %w", err)
+	}
+
+	var decoded interface{}
+	if err := yaml.Unmarshal(normalized, &decoded); err != nil {
+		return fmt.Errorf("can't be decoded once normalized: %w", err)
+	}
+	if !reflect.DeepEqual(content, decoded) {
+		return fmt.Errorf("changes when it is normalized")
+	}
+	again, err := yaml.Marshal(decoded)
+	if err != nil {
+		return fmt.Errorf("can't be normalized again: %w", err)
+	}
+	if !bytes.Equal(normalized, again) {
+		return fmt.Errorf("has no stable normalization")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package hygiene
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:    "clean",
+			content: "kind: Addon\nmetadata:\n  name: reloader\n  labels:\n    kubeaddons.mesosphere.io/name: reloader\nspec:\n  values: |\n    a: 1\n---\nkind: ClusterAddon\n",
+		},
+		{
+			name:     "crlf",
+			content:  "kind: Addon\r\nmetadata:\r\n  name: reloader\r\n",
+			expected: []string{"3 lines end with CRLF"},
+		},
+		{
+			name:     "tabs",
+			content:  "kind: Addon\nmetadata:\n\tname: reloader\n",
+			expected: []string{"can't be parsed", "line 3: indented with tabs"},
+		},
+		{
+			name:    "tabs in values",
+			content: "description: \"a\\tb\"\n",
+		},
+		{
+			name:     "duplicate keys",
+			content:  "kind: Addon\nmetadata:\n  name: reloader\n  labels: {}\n  name: traefik\n",
+			expected: []string{`line 5: key "name" is already defined on line 3`},
+		},
+		{
+			name:     "duplicate keys in a later document",
+			content:  "kind: Addon\n---\nkind: Addon\nkind: ClusterAddon\n",
+			expected: []string{`line 4: key "kind" is already defined on line 3`},
+		},
+		{
+			name:    "merge keys",
+			content: "base: &base\n  a: 1\nderived:\n  <<: *base\n  b: 2\n",
+		},
+		{
+			name:    "empty",
+			content: "",
+		},
+	} {
+		var problems []string
+		for _, problem := range Check([]byte(tc.content)) {
+			problems = append(problems, problem.String())
+		}
+		if len(problems) != len(tc.expected) {
+			t.Errorf("%s: expected problems %q, got %q", tc.name, tc.expected, problems)
+			continue
+		}
+		for i, expected := range tc.expected {
+			if !strings.Contains(problems[i], expected) {
+				t.Errorf("%s: expected problem %d to contain %q, got %q", tc.name, i, expected, problems[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package identities
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// Label labels the objects provisioned for the identities, which are removed
+// by it.
+const Label = "kubeaddons-kommander.mesosphere.io/identities"
+
+// dexNames is the encoding of the names dex gives the objects of its
+// kubernetes storage.
+var dexNames = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567")
+
+// Fixture is the deterministic set of test identities (see identities.yaml):
+// users who log in to dex with its password database, service accounts, and
+// groups of both which are bound to cluster roles.
+type Fixture struct {
+	// DexNamespace is the namespace of dex, where the passwords of the users
+	// are stored.
+	DexNamespace string `yaml:"dexNamespace"`
+
+	Users           []User           `yaml:"users"`
+	ServiceAccounts []ServiceAccount `yaml:"serviceAccounts"`
+	Groups          []Group          `yaml:"groups"`
+}
+
+// User is a user of the password database of dex.
+type User struct {
+	Email    string `yaml:"email"`
+	Username string `yaml:"username"`
+	UserID   string `yaml:"userID"`
+
+	// Password is the password the user logs in with, and Hash its bcrypt
+	// hash which dex verifies it against.
+	Password string `yaml:"password"`
+	Hash     string `yaml:"hash"`
+
+	Groups []string `yaml:"groups"`
+}
+
+// ServiceAccount is a kubernetes service account.
+type ServiceAccount struct {
+	Name      string   `yaml:"name"`
+	Namespace string   `yaml:"namespace"`
+	Groups    []string `yaml:"groups"`
+}
+
+// Subject returns the name the service account is authorized as.
+func (s ServiceAccount) Subject() string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", s.Namespace, s.Name)
+}
+
+// Group is a group of users and service accounts. The password database of
+// dex has no groups, so its members are bound to the cluster role of the group
+// individually.
+type Group struct {
+	Name        string `yaml:"name"`
+	ClusterRole string `yaml:"clusterRole"`
+}
+
+// Load reads the identities from a file and validates them.
+func Load(path string) (*Fixture, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixture{}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, fmt.Errorf("could not parse identities from %s: %w", path, err)
+	}
+	if err := f.validate(); err != nil {
+		return nil, fmt.Errorf("invalid identities in %s: %w", path, err)
+	}
+	return f, nil
+}
+
+func (f *Fixture) validate() error {
+	if f.DexNamespace == "" {
+		return fmt.Errorf("dexNamespace is required")
+	}
+	groups := make(map[string]bool, len(f.Groups))
+	for _, g := range f.Groups {
+		if g.Name == "" || g.ClusterRole == "" {
+			return fmt.Errorf("groups require a name and a clusterRole")
+		}
+		if groups[g.Name] {
+			return fmt.Errorf("group %s is defined twice", g.Name)
+		}
+		groups[g.Name] = true
+	}
+
+	users := make(map[string]bool, len(f.Users))
+	for _, u := range f.Users {
+		if u.Email == "" || u.Username == "" || u.UserID == "" || u.Password == "" {
+			return fmt.Errorf("users require an email, a username, a userID and a password")
+		}
+		if !strings.HasPrefix(u.Hash, "$2") {
+			return fmt.Errorf("user %s requires the bcrypt hash of its password", u.Email)
+		}
+		email := strings.ToLower(u.Email)
+		if users[email] {
+			return fmt.Errorf("user %s is defined twice", u.Email)
+		}
+		users[email] = true
+		for _, g := range u.Groups {
+			if !groups[g] {
+				return fmt.Errorf("user %s is a member of unknown group %s", u.Email, g)
+			}
+		}
+	}
+
+	for _, s := range f.ServiceAccounts {
+		if s.Name == "" || s.Namespace == "" {
+			return fmt.Errorf("service accounts require a name and a namespace")
+		}
+		for _, g := range s.Groups {
+			if !groups[g] {
+				return fmt.Errorf("service account %s is a member of unknown group %s", s.Subject(), g)
+			}
+		}
+	}
+	return nil
+}
+
+// Password returns the password of a user.
+func (f *Fixture) Password(email string) (string, bool) {
+	for _, u := range f.Users {
+		if strings.EqualFold(u.Email, email) {
+			return u.Password, true
+		}
+	}
+	return "", false
+}
+
+// Passwords returns the passwords of all users by email.
+func (f *Fixture) Passwords() map[string]string {
+	passwords := make(map[string]string, len(f.Users))
+	for _, u := range f.Users {
+		passwords[u.Email] = u.Password
+	}
+	return passwords
+}
+
+// Members returns the members of a group: the emails of its users and the
+// subjects of its service accounts, sorted.
+func (f *Fixture) Members(group string) []string {
+	var members []string
+	for _, u := range f.Users {
+		if contains(u.Groups, group) {
+			members = append(members, u.Email)
+		}
+	}
+	for _, s := range f.ServiceAccounts {
+		if contains(s.Groups, group) {
+			members = append(members, s.Subject())
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// Objects returns the objects which provision the identities: the service
+// accounts, a cluster role binding of the members of each group and, with
+// dex, the passwords of the users in its kubernetes storage.
+func (f *Fixture) Objects(dex bool) []interface{} {
+	labels := map[string]interface{}{Label: "true"}
+	var objects []interface{}
+	if dex {
+		for _, u := range f.Users {
+			email := strings.ToLower(u.Email)
+			objects = append(objects, map[string]interface{}{
+				"apiVersion": "dex.coreos.com/v1",
+				"kind":       "Password",
+				"metadata":   map[string]interface{}{"name": DexName(email), "namespace": f.DexNamespace, "labels": labels},
+				"email":      email,
+				// dex stores the hash as bytes, which are base64 in JSON
+				"hash":     []byte(u.Hash),
+				"username": u.Username,
+				"userID":   u.UserID,
+			})
+		}
+	}
+	for _, s := range f.ServiceAccounts {
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ServiceAccount",
+			"metadata":   map[string]interface{}{"name": s.Name, "namespace": s.Namespace, "labels": labels},
+		})
+	}
+	for _, g := range f.Groups {
+		var subjects []interface{}
+		for _, u := range f.Users {
+			if contains(u.Groups, g.Name) {
+				subjects = append(subjects, map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "User", "name": u.Email})
+			}
+		}
+		for _, s := range f.ServiceAccounts {
+			if contains(s.Groups, g.Name) {
+				subjects = append(subjects, map[string]interface{}{"kind": "ServiceAccount", "name": s.Name, "namespace": s.Namespace})
+			}
+		}
+		objects = append(objects, map[string]interface{}{
+			"apiVersion": "rbac.authorization.k8s.io/v1",
+			"kind":       "ClusterRoleBinding",
+			"metadata":   map[string]interface{}{"name": "kommander-qa-identities-" + g.Name, "labels": labels},
+			"roleRef":    map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": "ClusterRole", "name": g.ClusterRole},
+			"subjects":   subjects,
+		})
+	}
+	return objects
+}
+
+// Provision creates the identities in the cluster of a kubectl
+// client, the users only with dex (whose CRDs must be installed).
+func Provision(k kubectl.Client, f *Fixture, dex bool) error {
+	b, err := json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": f.Objects(dex)})
+	if err != nil {
+		return err
+	}
+	if err := k.Apply(b); err != nil {
+		return fmt.Errorf("could not provision the test identities: %w", err)
+	}
+	return nil
+}
+
+// Remove removes the provisioned identities.
+func Remove(k kubectl.Client, dex bool) error {
+	kinds := "clusterrolebindings,serviceaccounts"
+	if dex {
+		kinds += ",passwords.dex.coreos.com"
+	}
+	return k.Run("delete", kinds, "--all-namespaces", "--selector", Label+"=true", "--ignore-not-found")
+}
+
+// DexName returns the name of the object dex stores for an ID (e.g. the email
+// of a password) in its kubernetes storage. Like dex, it appends the empty FNV
+// hash to the ID rather than hashing it.
+func DexName(id string) string {
+	return strings.TrimRight(dexNames.EncodeToString(fnv.New64().Sum([]byte(id))), "=")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
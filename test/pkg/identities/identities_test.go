@@ -0,0 +1,119 @@
+package identities
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixture = `dexNamespace: kubeaddons
+users:
+  - email: Admin@example.com
+    username: admin
+    userID: 08a8684b-db88-4b73-90a9-3cd1661f5466
+    password: password
+    hash: "$2a$10$2b2cU8CPhOTaGrs1HRQuAueS7JTT5ZHsHSzYiFPm1leZck7Mc8T4W"
+    groups: [admins]
+serviceAccounts:
+  - name: viewer
+    namespace: kubeaddons
+    groups: [admins, viewers]
+groups:
+  - name: admins
+    clusterRole: cluster-admin
+  - name: viewers
+    clusterRole: view
+`
+
+func load(t *testing.T, content string) (*Fixture, error) {
+	dir, err := ioutil.TempDir("", "identities-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "identities.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestLoad(t *testing.T) {
+	f, err := load(t, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if password, ok := f.Password("admin@example.com"); !ok || password != "password" {
+		t.Errorf("expected the password of admin@example.com, got %q", password)
+	}
+	expected := []string{"Admin@example.com", "system:serviceaccount:kubeaddons:viewer"}
+	if members := f.Members("admins"); !reflect.DeepEqual(expected, members) {
+		t.Errorf("expected members %v, got %v", expected, members)
+	}
+
+	for _, invalid := range []string{
+		strings.Replace(fixture, "groups: [admins]", "groups: [unknown]", 1),
+		strings.Replace(fixture, `hash: "$2a$10$2b2cU8CPhOTaGrs1HRQuAueS7JTT5ZHsHSzYiFPm1leZck7Mc8T4W"`, "hash: password", 1),
+		strings.Replace(fixture, "clusterRole: view", "clusterRole: \"\"", 1),
+		strings.Replace(fixture, "dexNamespace: kubeaddons", "", 1),
+	} {
+		if _, err := load(t, invalid); err == nil {
+			t.Errorf("expected an error for identities:\n%s", invalid)
+		}
+	}
+}
+
+func TestObjects(t *testing.T) {
+	f, err := load(t, fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(f.Objects(false)); n != 3 {
+		t.Errorf("expected a service account and two bindings without dex, got %d objects", n)
+	}
+
+	objects := f.Objects(true)
+	b, err := json.Marshal(objects[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	password := struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Email string `json:"email"`
+		Hash  []byte `json:"hash"`
+	}{}
+	if err := json.Unmarshal(b, &password); err != nil {
+		t.Fatal(err)
+	}
+	if password.Kind != "Password" || password.Email != "admin@example.com" || password.Metadata.Name != DexName("admin@example.com") {
+		t.Errorf("unexpected password %s", b)
+	}
+	if !strings.HasPrefix(string(password.Hash), "$2a$10$") {
+		t.Errorf("expected the bcrypt hash, got %q", password.Hash)
+	}
+
+	b, err = json.Marshal(objects[len(objects)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"subjects":[{"kind":"ServiceAccount","name":"viewer","namespace":"kubeaddons"}]`) {
+		t.Errorf("expected the binding of the viewers to the service account, got %s", b)
+	}
+}
+
+func TestDexName(t *testing.T) {
+	name := DexName("admin@example.com")
+	if name != strings.ToLower(name) || strings.Contains(name, "=") {
+		t.Errorf("expected a lower case name without padding, got %s", name)
+	}
+	if name == DexName("viewer@example.com") {
+		t.Errorf("expected distinct names for distinct IDs")
+	}
+}
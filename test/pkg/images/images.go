@@ -0,0 +1,126 @@
+package images
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	defaultRegistry = "docker.io"
+	defaultTag      = "latest"
+)
+
+// Reference is a parsed container image reference.
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// Parse parses an image reference, applying the same defaults as docker does
+// (docker.io as the registry, library/ for official images and latest as the tag).
+func Parse(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, fmt.Errorf("image reference is empty")
+	}
+
+	ref := Reference{Registry: defaultRegistry}
+	remainder := image
+
+	if i := strings.Index(remainder, "@"); i >= 0 {
+		ref.Digest = remainder[i+1:]
+		remainder = remainder[:i]
+	}
+
+	// the first path component is a registry if it looks like a host
+	if i := strings.Index(remainder, "/"); i >= 0 {
+		host := remainder[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Registry = host
+			remainder = remainder[i+1:]
+		}
+	}
+
+	// a tag follows the last colon after the last slash
+	if i := strings.LastIndex(remainder, ":"); i > strings.LastIndex(remainder, "/") {
+		ref.Tag = remainder[i+1:]
+		remainder = remainder[:i]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	if remainder == "" {
+		return Reference{}, fmt.Errorf("image reference %q has no repository", image)
+	}
+	if ref.Registry == defaultRegistry && !strings.Contains(remainder, "/") {
+		remainder = "library/" + remainder
+	}
+	ref.Repository = remainder
+
+	return ref, nil
+}
+
+// String returns the fully qualified reference.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Extract returns the unique images used by the containers of the given
+// objects, sorted.
+func Extract(objects []render.Object) []string {
+	seen := make(map[string]struct{})
+	for _, obj := range objects {
+		for _, container := range obj.Containers() {
+			if image, ok := container["image"].(string); ok && image != "" {
+				seen[image] = struct{}{}
+			}
+		}
+	}
+
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	return images
+}
+
+// Unmatched returns the images of the containers of the given objects which
+// don't match a pattern, keyed by addon and sorted.
+func Unmatched(objects []render.Object, pattern *regexp.Regexp) map[string][]string {
+	unmatched := make(map[string][]string)
+	for _, obj := range objects {
+		for _, image := range Extract([]render.Object{obj}) {
+			if !pattern.MatchString(image) && !contains(unmatched[obj.Addon], image) {
+				unmatched[obj.Addon] = append(unmatched[obj.Addon], image)
+			}
+		}
+	}
+	for _, addonImages := range unmatched {
+		sort.Strings(addonImages)
+	}
+	return unmatched
+}
+
+func contains(s []string, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
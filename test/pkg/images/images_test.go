@@ -0,0 +1,134 @@
+package images
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		image    string
+		expected Reference
+	}{
+		{image: "nginx", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{image: "nginx:1.17.8-alpine", expected: Reference{Registry: "docker.io", Repository: "library/nginx", Tag: "1.17.8-alpine"}},
+		{image: "mesosphere/kommander:v1.0.0", expected: Reference{Registry: "docker.io", Repository: "mesosphere/kommander", Tag: "v1.0.0"}},
+		{image: "quay.io/jetstack/cert-manager-controller:v0.10.1", expected: Reference{Registry: "quay.io", Repository: "jetstack/cert-manager-controller", Tag: "v0.10.1"}},
+		{image: "localhost:5000/kommander", expected: Reference{Registry: "localhost:5000", Repository: "kommander", Tag: "latest"}},
+		{image: "gcr.io/google-containers/pause@sha256:abc", expected: Reference{Registry: "gcr.io", Repository: "google-containers/pause", Digest: "sha256:abc"}},
+		{image: "traefik:1.7.20@sha256:abc", expected: Reference{Registry: "docker.io", Repository: "library/traefik", Tag: "1.7.20", Digest: "sha256:abc"}},
+	} {
+		actual, err := Parse(tc.image)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tc.image, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("expected %q to parse as %+v, got %+v", tc.image, tc.expected, actual)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	lock := Lock{"kommander": {Revision: "1.0.0-17", Images: map[string]string{"mesosphere/kommander:v1.0.0": "sha256:a"}}}
+
+	for _, tc := range []struct {
+		addon    string
+		current  LockedAddon
+		expected []string
+	}{
+		{
+			addon:   "kommander",
+			current: LockedAddon{Revision: "1.0.0-17", Images: map[string]string{"mesosphere/kommander:v1.0.0": "sha256:a"}},
+		},
+		{
+			addon:    "kommander",
+			current:  LockedAddon{Revision: "1.0.0-17", Images: map[string]string{"mesosphere/kommander:v1.0.0": "sha256:b"}},
+			expected: []string{"addon kommander revision 1.0.0-17: digest of image mesosphere/kommander:v1.0.0 changed from sha256:a to sha256:b without a revision bump"},
+		},
+		{
+			addon:   "kommander",
+			current: LockedAddon{Revision: "1.0.0-17", Images: map[string]string{"mesosphere/kommander:v1.0.1": "sha256:b"}},
+			expected: []string{
+				"addon kommander revision 1.0.0-17: image mesosphere/kommander:v1.0.0 (sha256:a) of the lockfile is not used anymore",
+				"addon kommander revision 1.0.0-17: image mesosphere/kommander:v1.0.1 (sha256:b) is not in the lockfile",
+			},
+		},
+		{
+			addon:    "kommander",
+			current:  LockedAddon{Revision: "1.0.0-18", Images: map[string]string{"mesosphere/kommander:v1.0.0": "sha256:b"}},
+			expected: []string{"addon kommander is locked at revision 1.0.0-17 but is at revision 1.0.0-18"},
+		},
+		{
+			addon:    "traefik",
+			current:  LockedAddon{Revision: "1.7.20-1", Images: map[string]string{"traefik:1.7.20": "sha256:c"}},
+			expected: []string{"addon traefik revision 1.7.20-1 is not in the lockfile"},
+		},
+	} {
+		var actual []string
+		for _, drift := range lock.Verify(tc.addon, tc.current) {
+			actual = append(actual, drift.String())
+		}
+		if strings.Join(actual, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("expected drift %q for %+v, got %q", tc.expected, tc.current, actual)
+		}
+	}
+
+	stale := lock.Stale([]string{"traefik"})
+	if len(stale) != 1 || stale[0].String() != "addon kommander revision 1.0.0-17 is in the lockfile but not tested anymore" {
+		t.Errorf("expected kommander to be stale, got %v", stale)
+	}
+	if stale := lock.Stale([]string{"kommander"}); len(stale) != 0 {
+		t.Errorf("expected no stale addons, got %v", stale)
+	}
+}
+
+func TestUnmatched(t *testing.T) {
+	deployment := func(addon string, images ...string) render.Object {
+		containers := make([]interface{}, 0, len(images))
+		for _, image := range images {
+			containers = append(containers, map[string]interface{}{"image": image})
+		}
+		return render.Object{Addon: addon, Kind: "Deployment", Content: map[string]interface{}{
+			"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": containers}}},
+		}}
+	}
+	objects := []render.Object{
+		deployment("kommander", "mesosphere/kubeaddons-catalog:v0.8.2-fips", "mesosphere/kommander:v1.0.0"),
+		deployment("kommander", "mesosphere/kommander:v1.0.0"),
+		deployment("traefik", "traefik:1.7.24-fips"),
+	}
+
+	unmatched := Unmatched(objects, regexp.MustCompile(`-fips$`))
+	expected := map[string][]string{"kommander": {"mesosphere/kommander:v1.0.0"}}
+	if !reflect.DeepEqual(unmatched, expected) {
+		t.Errorf("expected %v, got %v", expected, unmatched)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	pod := func(addon, image string) render.Object {
+		return render.Object{Addon: addon, Kind: "Pod", Content: map[string]interface{}{
+			"spec": map[string]interface{}{"containers": []interface{}{map[string]interface{}{"image": image}}},
+		}}
+	}
+	m := NewManifest()
+	m.Add("group/kommander", []render.Object{pod("traefik", "traefik:1.7.24"), pod("dex", "quay.io/dexidp/dex:v2.22.0")})
+	m.Add("scenario/kommander-fips", []render.Object{pod("traefik", "traefik:1.7.24-fips"), pod("dex", "quay.io/dexidp/dex:v2.22.0")})
+
+	b := new(strings.Builder)
+	if err := m.Write(b, "text"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "quay.io/dexidp/dex:v2.22.0\ntraefik:1.7.24\ntraefik:1.7.24-fips\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+	if images := m.Sets["scenario/kommander-fips"]; !reflect.DeepEqual(images, []string{"quay.io/dexidp/dex:v2.22.0", "traefik:1.7.24-fips"}) {
+		t.Errorf("unexpected images of scenario: %v", images)
+	}
+}
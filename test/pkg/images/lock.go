@@ -0,0 +1,132 @@
+package images
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Lock records the digests the images of each addon resolved to at a given
+// addon revision, keyed by addon name.
+type Lock map[string]LockedAddon
+
+// LockedAddon is the addon revision and the digests of its images (keyed by
+// image reference as it appears in the rendered manifests).
+type LockedAddon struct {
+	Revision string            `yaml:"revision"`
+	Images   map[string]string `yaml:"images"`
+}
+
+// Drift is a difference between the lockfile and the current images of an
+// addon: an addon which is not locked at its current revision, an image whose
+// digest changed without a revision bump, or an image which appeared or
+// disappeared.
+type Drift struct {
+	Addon          string
+	Revision       string
+	LockedRevision string
+	Image          string
+	Locked         string
+	Current        string
+}
+
+func (d Drift) String() string {
+	switch {
+	case d.Image == "" && d.LockedRevision == "":
+		return fmt.Sprintf("addon %s revision %s is not in the lockfile", d.Addon, d.Revision)
+	case d.Image == "" && d.Revision == "":
+		return fmt.Sprintf("addon %s revision %s is in the lockfile but not tested anymore", d.Addon, d.LockedRevision)
+	case d.Image == "":
+		return fmt.Sprintf("addon %s is locked at revision %s but is at revision %s", d.Addon, d.LockedRevision, d.Revision)
+	case d.Locked == "":
+		return fmt.Sprintf("addon %s revision %s: image %s (%s) is not in the lockfile", d.Addon, d.Revision, d.Image, d.Current)
+	case d.Current == "":
+		return fmt.Sprintf("addon %s revision %s: image %s (%s) of the lockfile is not used anymore", d.Addon, d.Revision, d.Image, d.Locked)
+	}
+	return fmt.Sprintf("addon %s revision %s: digest of image %s changed from %s to %s without a revision bump", d.Addon, d.Revision, d.Image, d.Locked, d.Current)
+}
+
+// LoadLock reads a lockfile, a lockfile which does not exist is empty.
+func LoadLock(path string) (Lock, error) {
+	lock := make(Lock)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return lock, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &lock); err != nil {
+		return nil, fmt.Errorf("could not parse lockfile %s: %w", path, err)
+	}
+	return lock, nil
+}
+
+// lockHeader is the comment at the top of a lockfile.
+const lockHeader = `# ------------------------------------------------------------------------------
+# Image Lockfile
+#
+# The digests the images of the kommander group resolved to, per addon revision.
+# TestValidateImageDigests fails when a digest changes without a revision bump
+# of its addon, and for addons which are not locked at their current revision.
+# Regenerate along with every bump of an addon with:
+#
+#   make image-lock
+# ------------------------------------------------------------------------------
+`
+
+// Save writes the lockfile.
+func (l Lock) Save(path string) error {
+	b, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(lockHeader), b...), 0644)
+}
+
+// Verify compares the current state of an addon with the lockfile. An addon
+// which is not locked at its current revision drifts as a whole, as the
+// lockfile must be regenerated whenever an addon is bumped (see
+// UPDATE_IMAGE_LOCK); otherwise every image whose digest changed, or which
+// appeared or disappeared, drifts.
+func (l Lock) Verify(addon string, current LockedAddon) []Drift {
+	locked, ok := l[addon]
+	if !ok || locked.Revision != current.Revision {
+		return []Drift{{Addon: addon, Revision: current.Revision, LockedRevision: locked.Revision}}
+	}
+
+	var drift []Drift
+	for image, digest := range current.Images {
+		if locked.Images[image] != digest {
+			drift = append(drift, Drift{Addon: addon, Revision: current.Revision, LockedRevision: locked.Revision, Image: image, Locked: locked.Images[image], Current: digest})
+		}
+	}
+	for image, digest := range locked.Images {
+		if _, ok := current.Images[image]; !ok {
+			drift = append(drift, Drift{Addon: addon, Revision: current.Revision, LockedRevision: locked.Revision, Image: image, Locked: digest})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Image < drift[j].Image })
+
+	return drift
+}
+
+// Stale returns the drift of the locked addons which are not among the given
+// addons anymore, sorted by addon.
+func (l Lock) Stale(addons []string) []Drift {
+	current := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		current[addon] = true
+	}
+	var drift []Drift
+	for addon, locked := range l {
+		if !current[addon] {
+			drift = append(drift, Drift{Addon: addon, LockedRevision: locked.Revision})
+		}
+	}
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Addon < drift[j].Addon })
+	return drift
+}
@@ -0,0 +1,63 @@
+package images
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Manifest lists the images required to deploy sets of addons (e.g. testing
+// groups and scenarios), so CI can pull them into a shared cache ahead of time.
+type Manifest struct {
+	// Sets are the images of each set of addons, sorted.
+	Sets map[string][]string `json:"sets"`
+
+	// Images are the images of all sets, sorted.
+	Images []string `json:"images"`
+}
+
+// NewManifest returns an empty manifest.
+func NewManifest() *Manifest {
+	return &Manifest{Sets: make(map[string][]string)}
+}
+
+// Add adds the images of the rendered objects of a set of addons.
+func (m *Manifest) Add(set string, objects []render.Object) {
+	m.Sets[set] = Extract(objects)
+
+	seen := make(map[string]bool, len(m.Images))
+	for _, image := range m.Images {
+		seen[image] = true
+	}
+	for _, image := range m.Sets[set] {
+		if !seen[image] {
+			seen[image] = true
+			m.Images = append(m.Images, image)
+		}
+	}
+	sort.Strings(m.Images)
+}
+
+// Write writes the manifest in a format: "text" writes one image of any set
+// per line (e.g. for `xargs -n 1 docker pull`), "json" the images of each set
+// along with all images.
+func (m *Manifest) Write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		for _, image := range m.Images {
+			if _, err := fmt.Fprintln(w, image); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	default:
+		return fmt.Errorf("unknown image manifest format %s, expected text or json", format)
+	}
+}
@@ -0,0 +1,215 @@
+package impact
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+)
+
+const (
+	ingressClassAnnotation = "kubernetes.io/ingress.class"
+
+	// defaultIngressClass serves ingresses which don't specify a class.
+	defaultIngressClass = "traefik"
+)
+
+// ingressControllers maps ingress classes to the addons which serve them.
+var ingressControllers = map[string]string{
+	"traefik": "traefik",
+}
+
+// Resource is a resource in the cluster.
+type Resource struct {
+	Kind        string
+	Namespace   string
+	Name        string
+	Annotations map[string]string
+}
+
+func (r Resource) String() string {
+	ref := strings.ToLower(r.Kind) + "/" + r.Name
+	if r.Namespace != "" {
+		ref = r.Namespace + "/" + ref
+	}
+	return ref
+}
+
+// Dependent is a resource which still uses an addon that is about to be removed.
+type Dependent struct {
+	Resource Resource
+
+	// Owner is the addon which created the resource, empty for resources
+	// which were created at runtime (e.g. by operators or seeds).
+	Owner string
+
+	// Needs is the addon the resource uses and Reason explains how.
+	Needs  string
+	Reason string
+}
+
+func (d Dependent) String() string {
+	owner := d.Owner
+	if owner == "" {
+		owner = "no addon"
+	}
+	return fmt.Sprintf("%s (created by %s) needs addon %s: %s", d.Resource, owner, d.Needs, d.Reason)
+}
+
+// Hazard returns whether removing the addon the resource needs before its
+// owner would break the owner, e.g. traefik being removed while the ingress of
+// another addon still routes through it.
+func (d Dependent) Hazard() bool {
+	return d.Owner != "" && d.Owner != d.Needs
+}
+
+// Analyze lists the resources in the cluster of a kubectl client
+// which use the addons that rendered the given objects: custom resources of the
+// CRDs shipped by an addon (e.g. certificates need cert-manager) and ingresses
+// served by an ingress controller addon (e.g. traefik).
+func Analyze(k kubectl.Client, objects []render.Object, crds []teardown.CRD) ([]Dependent, error) {
+	resources := make(map[string][]Resource)
+	for _, crd := range crds {
+		listed, err := list(k, crd.Name)
+		if err != nil {
+			return nil, err
+		}
+		resources[crd.Name] = listed
+	}
+	ingresses, err := list(k, "ingresses")
+	if err != nil {
+		return nil, err
+	}
+	return dependents(objects, crds, resources, ingresses), nil
+}
+
+func dependents(objects []render.Object, crds []teardown.CRD, resources map[string][]Resource, ingresses []Resource) []Dependent {
+	owners := make(map[string]string, len(objects))
+	addons := make(map[string]bool)
+	for _, obj := range objects {
+		owners[key(obj.Kind, obj.Namespace, obj.Name)] = obj.Addon
+		addons[obj.Addon] = true
+	}
+
+	var deps []Dependent
+	for _, crd := range crds {
+		for _, r := range resources[crd.Name] {
+			deps = append(deps, Dependent{
+				Resource: r,
+				Owner:    owners[key(r.Kind, r.Namespace, r.Name)],
+				Needs:    crd.Addon,
+				Reason:   fmt.Sprintf("is a custom resource of %s", crd.Name),
+			})
+		}
+	}
+
+	for _, r := range ingresses {
+		class := r.Annotations[ingressClassAnnotation]
+		if class == "" {
+			class = defaultIngressClass
+		}
+		controller, ok := ingressControllers[class]
+		if !ok || !addons[controller] {
+			continue
+		}
+		deps = append(deps, Dependent{
+			Resource: r,
+			Owner:    owners[key(r.Kind, r.Namespace, r.Name)],
+			Needs:    controller,
+			Reason:   fmt.Sprintf("is served by ingress class %s", class),
+		})
+	}
+
+	sort.Slice(deps, func(i, j int) bool { return deps[i].String() < deps[j].String() })
+	return deps
+}
+
+func key(kind, namespace, name string) string {
+	return strings.ToLower(kind) + "/" + namespace + "/" + name
+}
+
+type resourceList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Namespace   string            `json:"namespace"`
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+func list(k kubectl.Client, resource string) ([]Resource, error) {
+	b, err := k.Output("get", resource, "--all-namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	l := resourceList{}
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	resources := make([]Resource, 0, len(l.Items))
+	for _, item := range l.Items {
+		resources = append(resources, Resource{
+			Kind:        item.Kind,
+			Namespace:   item.Metadata.Namespace,
+			Name:        item.Metadata.Name,
+			Annotations: item.Metadata.Annotations,
+		})
+	}
+	return resources, nil
+}
+
+// RemovalOrder orders the addons so that every addon is removed before the
+// addons its resources need, addons without dependencies keep their order. It
+// fails when the dependencies are cyclic.
+func RemovalOrder(addons []string, deps []Dependent) ([]string, error) {
+	needs := make(map[string]map[string]bool)
+	for _, d := range deps {
+		if !d.Hazard() {
+			continue
+		}
+		if needs[d.Needs] == nil {
+			needs[d.Needs] = make(map[string]bool)
+		}
+		// d.Needs must wait for d.Owner to be removed
+		needs[d.Needs][d.Owner] = true
+	}
+
+	var order []string
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var visit func(string) error
+	visit = func(addon string) error {
+		switch state[addon] {
+		case visiting:
+			return fmt.Errorf("cyclic dependencies between addons involving %s", addon)
+		case done:
+			return nil
+		}
+		state[addon] = visiting
+		for _, dependent := range addons {
+			if needs[addon][dependent] {
+				if err := visit(dependent); err != nil {
+					return err
+				}
+			}
+		}
+		state[addon] = done
+		order = append(order, addon)
+		return nil
+	}
+	for _, addon := range addons {
+		if err := visit(addon); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,52 @@
+package impact
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+)
+
+func TestDependents(t *testing.T) {
+	objects := []render.Object{
+		{Addon: "kommander", Kind: "Certificate", Namespace: "kommander", Name: "kommander-tls"},
+		{Addon: "kommander", Kind: "Ingress", Namespace: "kommander", Name: "kommander"},
+		{Addon: "traefik", Kind: "Deployment", Namespace: "kubeaddons", Name: "traefik-kubeaddons"},
+		{Addon: "cert-manager", Kind: "Deployment", Namespace: "cert-manager", Name: "cert-manager"},
+	}
+	crds := []teardown.CRD{{Name: "certificates.cert-manager.io", Addon: "cert-manager"}}
+	resources := map[string][]Resource{
+		"certificates.cert-manager.io": {
+			{Kind: "Certificate", Namespace: "kommander", Name: "kommander-tls"},
+			{Kind: "Certificate", Namespace: "seed-sample-apps", Name: "sample-web"},
+		},
+	}
+	ingresses := []Resource{
+		{Kind: "Ingress", Namespace: "kommander", Name: "kommander"},
+		{Kind: "Ingress", Namespace: "kubeaddons", Name: "other", Annotations: map[string]string{ingressClassAnnotation: "nginx"}},
+	}
+
+	deps := dependents(objects, crds, resources, ingresses)
+	expected := []Dependent{
+		{Resource: resources["certificates.cert-manager.io"][0], Owner: "kommander", Needs: "cert-manager", Reason: "is a custom resource of certificates.cert-manager.io"},
+		{Resource: ingresses[0], Owner: "kommander", Needs: "traefik", Reason: "is served by ingress class traefik"},
+		{Resource: resources["certificates.cert-manager.io"][1], Needs: "cert-manager", Reason: "is a custom resource of certificates.cert-manager.io"},
+	}
+	if !reflect.DeepEqual(expected, deps) {
+		t.Fatalf("expected %v, got %v", expected, deps)
+	}
+
+	order, err := RemovalOrder([]string{"cert-manager", "traefik", "kommander"}, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kommander", "cert-manager", "traefik"}; !reflect.DeepEqual(expected, order) {
+		t.Errorf("expected removal order %v, got %v", expected, order)
+	}
+
+	cyclic := append(deps, Dependent{Owner: "cert-manager", Needs: "kommander"})
+	if _, err := RemovalOrder([]string{"cert-manager", "kommander"}, cyclic); err == nil {
+		t.Error("expected cyclic dependencies to be rejected")
+	}
+}
@@ -0,0 +1,63 @@
+package installed
+
+import (
+	"encoding/json"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// RevisionAnnotation is the annotation of addons with their revision.
+const RevisionAnnotation = "catalog.kubeaddons.mesosphere.io/addon-revision"
+
+// Addon is an addon which is already installed in a cluster.
+type Addon struct {
+	Name      string
+	Namespace string
+	Kind      string
+	Revision  string
+	Ready     bool
+	Stage     string
+}
+
+type addonList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Status struct {
+			Ready bool   `json:"ready"`
+			Stage string `json:"stage"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Discover lists the Addons and ClusterAddons installed in the cluster of a
+// kubectl client, keyed by addon name.
+func Discover(k kubectl.Client) (map[string]Addon, error) {
+	b, err := k.Output("get", "addons.kubeaddons.mesosphere.io,clusteraddons.kubeaddons.mesosphere.io", "--all-namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	list := addonList{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+
+	addons := make(map[string]Addon, len(list.Items))
+	for _, item := range list.Items {
+		addons[item.Metadata.Name] = Addon{
+			Name:      item.Metadata.Name,
+			Namespace: item.Metadata.Namespace,
+			Kind:      item.Kind,
+			Revision:  item.Metadata.Annotations[RevisionAnnotation],
+			Ready:     item.Status.Ready,
+			Stage:     item.Status.Stage,
+		}
+	}
+
+	return addons, nil
+}
@@ -0,0 +1,77 @@
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+const header = `# ------------------------------------------------------------------------------
+# CRD Inventory
+#
+# The CRDs which are expected to exist after the addons have been deployed, in
+# addition to those which existed before (e.g. of the kubeaddons controller).
+# Regenerate with UPDATE_CRD_INVENTORY=true.
+# ------------------------------------------------------------------------------
+`
+
+// Load reads the names of the CRDs in an inventory file. The returned bool is
+// false when the file does not exist.
+func Load(path string) ([]string, bool, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	var crds []string
+	if err := yaml.UnmarshalStrict(b, &crds); err != nil {
+		return nil, false, fmt.Errorf("could not parse CRD inventory %s: %w", path, err)
+	}
+	return crds, true, nil
+}
+
+// Save writes the names of the CRDs to an inventory file, sorted.
+func Save(path string, crds []string) error {
+	sorted := append([]string(nil), crds...)
+	sort.Strings(sorted)
+
+	b, err := yaml.Marshal(sorted)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(header), b...), 0644)
+}
+
+// Diff compares the expected CRDs with the CRDs which exist, returning the
+// expected CRDs which are missing and the existing CRDs which are unexpected.
+func Diff(expected, existing []string) (missing, unexpected []string) {
+	expectedSet := set(expected)
+	existingSet := set(existing)
+	for name := range expectedSet {
+		if !existingSet[name] {
+			missing = append(missing, name)
+		}
+	}
+	for name := range existingSet {
+		if !expectedSet[name] {
+			unexpected = append(unexpected, name)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+func set(names []string) map[string]bool {
+	s := make(map[string]bool, len(names))
+	for _, name := range names {
+		s[name] = true
+	}
+	return s
+}
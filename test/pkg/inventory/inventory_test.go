@@ -0,0 +1,23 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	expected := []string{"certificates.cert-manager.io", "issuers.cert-manager.io", "kubefedconfigs.core.kubefed.io"}
+	existing := []string{"issuers.cert-manager.io", "certificates.cert-manager.io", "federatedingresses.types.kubefed.io"}
+
+	missing, unexpected := Diff(expected, existing)
+	if e := []string{"kubefedconfigs.core.kubefed.io"}; !reflect.DeepEqual(e, missing) {
+		t.Errorf("expected missing %v, got %v", e, missing)
+	}
+	if e := []string{"federatedingresses.types.kubefed.io"}; !reflect.DeepEqual(e, unexpected) {
+		t.Errorf("expected unexpected %v, got %v", e, unexpected)
+	}
+
+	if missing, unexpected := Diff(expected, expected); missing != nil || unexpected != nil {
+		t.Errorf("expected no differences, got missing %v and unexpected %v", missing, unexpected)
+	}
+}
@@ -0,0 +1,189 @@
+package ipfamily
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os/exec"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Family is the IP address family of the network of a cluster.
+type Family string
+
+const (
+	// IPv4 clusters only have IPv4 addresses, the default.
+	IPv4 Family = "ipv4"
+
+	// IPv6 clusters only have IPv6 addresses.
+	IPv6 Family = "ipv6"
+
+	// DualStack clusters have IPv4 and IPv6 addresses.
+	DualStack Family = "dual"
+)
+
+// Parse parses an address family, which defaults to IPv4.
+func Parse(s string) (Family, error) {
+	switch f := Family(s); f {
+	case "":
+		return IPv4, nil
+	case IPv4, IPv6, DualStack:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown ip family %s, expected %s, %s or %s", s, IPv4, IPv6, DualStack)
+	}
+}
+
+// Has returns whether clusters of the family have addresses of the family
+// of an IP.
+func (f Family) Has(ip net.IP) bool {
+	if ip.To4() != nil {
+		return f != IPv6
+	}
+	return f != IPv4
+}
+
+// Any returns the unspecified address servers of the family listen on. Dual
+// stack servers listen on the IPv6 address, which accepts IPv4 connections
+// as well.
+func (f Family) Any() string {
+	if f == IPv4 {
+		return "0.0.0.0"
+	}
+	return "::"
+}
+
+// The load balancer address pools are at the same offsets in every subnet, for
+// IPv4 the CI default of 172.17.1.200-172.17.1.250 in the default docker
+// network.
+const (
+	poolStart = 1<<8 + 200
+	poolEnd   = 1<<8 + 250
+)
+
+// Pool returns the address range for load balancers in a subnet, e.g.
+// 172.17.1.200-172.17.1.250 in 172.17.0.0/16.
+func Pool(subnet string) (string, error) {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := network.Mask.Size()
+	if bits-ones < 9 {
+		return "", fmt.Errorf("subnet %s is too small for a load balancer address pool", subnet)
+	}
+	return offset(network.IP, poolStart).String() + "-" + offset(network.IP, poolEnd).String(), nil
+}
+
+func offset(ip net.IP, n int64) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	i := new(big.Int).SetBytes(ip)
+	b := i.Add(i, big.NewInt(n)).Bytes()
+	out := make(net.IP, len(ip))
+	copy(out[len(out)-len(b):], b)
+	return out
+}
+
+// Values are the values override templates are rendered with.
+type Values struct {
+	Family Family
+
+	// Any is the unspecified address of the family, e.g. :: for IPv6.
+	Any string
+
+	// Pools are the load balancer address pools of the family (one per
+	// address family of the network, IPv4 first).
+	Pools []string
+}
+
+// NewValues returns the values of a family on a network with the given
+// subnets. Subnets of other families are ignored.
+func NewValues(f Family, subnets []string) (Values, error) {
+	v := Values{Family: f, Any: f.Any()}
+	var v6 []string
+	for _, subnet := range subnets {
+		ip, _, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return v, err
+		}
+		if !f.Has(ip) {
+			continue
+		}
+		pool, err := Pool(subnet)
+		if err != nil {
+			return v, err
+		}
+		if ip.To4() != nil {
+			v.Pools = append(v.Pools, pool)
+		} else {
+			v6 = append(v6, pool)
+		}
+	}
+	v.Pools = append(v.Pools, v6...)
+	if len(v.Pools) == 0 {
+		return v, fmt.Errorf("the network has no %s subnet in %v", f, subnets)
+	}
+	return v, nil
+}
+
+var funcs = template.FuncMap{
+	// hostPort joins a host and a port, with brackets around IPv6 addresses.
+	"hostPort": func(host string, port int) string {
+		return net.JoinHostPort(host, fmt.Sprint(port))
+	},
+}
+
+// Overrides are values override templates by addon.
+type Overrides map[string]string
+
+// Load reads the override templates in a file.
+func Load(path string) (Overrides, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Overrides Overrides `yaml:"overrides"`
+	}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse ip family overrides %s: %w", path, err)
+	}
+	return file.Overrides, nil
+}
+
+// Render renders the override templates with the values of a family.
+func (o Overrides) Render(v Values) (map[string]string, error) {
+	rendered := make(map[string]string, len(o))
+	for addon, text := range o {
+		tmpl, err := template.New(addon).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse the ip family override of addon %s: %w", addon, err)
+		}
+		var b bytes.Buffer
+		if err := tmpl.Execute(&b, v); err != nil {
+			return nil, fmt.Errorf("could not render the ip family override of addon %s: %w", addon, err)
+		}
+		rendered[addon] = b.String()
+	}
+	return rendered, nil
+}
+
+// DockerSubnets returns the subnets of a docker network, e.g. bridge, which the
+// nodes of kind clusters are attached to.
+func DockerSubnets(network string) ([]string, error) {
+	var stdout, stderr strings.Builder
+	cmd := exec.Command("docker", "network", "inspect", network, "--format", "{{range .IPAM.Config}}{{.Subnet}} {{end}}")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not inspect docker network %s: %w: %s", network, err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.Fields(stdout.String()), nil
+}
@@ -0,0 +1,67 @@
+package ipfamily
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	for subnet, expected := range map[string]string{
+		"172.17.0.0/16":           "172.17.1.200-172.17.1.250",
+		"fc00:f853:ccd:e793::/64": "fc00:f853:ccd:e793::1c8-fc00:f853:ccd:e793::1fa",
+	} {
+		pool, err := Pool(subnet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pool != expected {
+			t.Errorf("expected pool %s in %s, got %s", expected, subnet, pool)
+		}
+	}
+	if _, err := Pool("172.17.0.0/24"); err == nil {
+		t.Errorf("expected an error for a subnet which is too small")
+	}
+}
+
+func TestRender(t *testing.T) {
+	subnets := []string{"fc00:f853:ccd:e793::/64", "172.17.0.0/16"}
+	overrides := Overrides{
+		"metallb":   `addresses:{{ range .Pools }} {{ . }}{{ end }}`,
+		"kommander": `address: {{ hostPort .Any 10902 }}`,
+	}
+
+	expected := map[Family]map[string]string{
+		IPv4: {
+			"metallb":   "addresses: 172.17.1.200-172.17.1.250",
+			"kommander": "address: 0.0.0.0:10902",
+		},
+		IPv6: {
+			"metallb":   "addresses: fc00:f853:ccd:e793::1c8-fc00:f853:ccd:e793::1fa",
+			"kommander": "address: [::]:10902",
+		},
+		DualStack: {
+			"metallb":   "addresses: 172.17.1.200-172.17.1.250 fc00:f853:ccd:e793::1c8-fc00:f853:ccd:e793::1fa",
+			"kommander": "address: [::]:10902",
+		},
+	}
+	for family, e := range expected {
+		v, err := NewValues(family, subnets)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, err := overrides.Render(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rendered, e) {
+			t.Errorf("expected %s overrides %v, got %v", family, e, rendered)
+		}
+	}
+
+	if _, err := NewValues(IPv6, []string{"172.17.0.0/16"}); err == nil {
+		t.Errorf("expected an error for a network without IPv6 subnets")
+	}
+	if _, err := Parse("ipv5"); err == nil {
+		t.Errorf("expected an error for an unknown family")
+	}
+}
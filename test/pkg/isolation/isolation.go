@@ -0,0 +1,195 @@
+package isolation
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// Config is the configuration of the install isolation verification (see
+// isolation.yaml).
+type Config struct {
+	// Shared are the namespaces every addon may install into, e.g.
+	// kube-system where helm and leader election keep their state.
+	Shared []string `yaml:"shared"`
+
+	// Ignored are the resources (e.g. events, leases) whose objects change
+	// on their own while addons are deployed, which are not compared.
+	Ignored []string `yaml:"ignored"`
+}
+
+// Load reads the configuration of the verification from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Snapshot is the state of the namespaced objects of a cluster, and of its
+// namespaces, as <kind>[.<group>]/<namespace>/<name> mapped to a digest of
+// their content.
+type Snapshot map[string]string
+
+// Change is an object which was created, modified or deleted between two
+// snapshots.
+type Change struct {
+	// Object is the object as <kind>[.<group>]/<namespace>/<name>.
+	Object string
+
+	// Namespace is the namespace of the object, or the namespace itself for
+	// namespaces.
+	Namespace string
+
+	// Change is either "created", "modified" or "deleted".
+	Change string
+}
+
+func (c Change) String() string {
+	return c.Object + " was " + c.Change
+}
+
+// Take returns a snapshot of the cluster of a kubectl client,
+// except of the ignored resources. Resources which can't be listed (e.g. of
+// unavailable aggregated APIs) are skipped.
+func (c *Config) Take(k kubectl.Client) (Snapshot, error) {
+	b, err := k.Output("api-resources", "--verbs", "list", "--namespaced", "--output", "name")
+	if err != nil {
+		return nil, err
+	}
+	ignored := make(map[string]bool, len(c.Ignored))
+	for _, resource := range c.Ignored {
+		ignored[resource] = true
+	}
+
+	snapshot := make(Snapshot)
+	for _, resource := range append(strings.Fields(string(b)), "namespaces") {
+		if ignored[resource] {
+			continue
+		}
+		args := []string{"get", resource, "--output", "json"}
+		if resource != "namespaces" {
+			args = append(args, "--all-namespaces")
+		}
+		out, err := k.Output(args...)
+		if err != nil {
+			if unavailable(err) {
+				continue
+			}
+			return nil, err
+		}
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(out, &list); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %w", resource, err)
+		}
+		for _, obj := range list.Items {
+			key, digest, err := digest(obj)
+			if err != nil {
+				return nil, err
+			}
+			snapshot[key] = digest
+		}
+	}
+	return snapshot, nil
+}
+
+// unavailable returns whether listing a resource failed because its API is
+// not served.
+func unavailable(err error) bool {
+	for _, reason := range []string{"the server is currently unable to handle the request", "the server could not find the requested resource", "the server doesn't have a resource type"} {
+		if strings.Contains(err.Error(), reason) {
+			return true
+		}
+	}
+	return false
+}
+
+// digest returns the key of an object and a digest of its content, except of
+// its status and metadata other than its labels, which change without the
+// object being modified (e.g. the annotations of leader election).
+func digest(obj map[string]interface{}) (string, string, error) {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	name, _ := metadata["name"].(string)
+	namespace, _ := metadata["namespace"].(string)
+	kind, _ := obj["kind"].(string)
+	apiVersion, _ := obj["apiVersion"].(string)
+
+	kind = strings.ToLower(kind)
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		kind += "." + apiVersion[:i]
+	}
+
+	content := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		if k != "metadata" && k != "status" {
+			content[k] = v
+		}
+	}
+	content["labels"] = metadata["labels"]
+	b, err := json.Marshal(content)
+	if err != nil {
+		return "", "", err
+	}
+	return kind + "/" + namespace + "/" + name, fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// Diff returns the objects which changed from the snapshot to a later one,
+// sorted by object.
+func (before Snapshot) Diff(after Snapshot) []Change {
+	var changes []Change
+	for object, digest := range after {
+		previous, existed := before[object]
+		switch {
+		case !existed:
+			changes = append(changes, change(object, "created"))
+		case previous != digest:
+			changes = append(changes, change(object, "modified"))
+		}
+	}
+	for object := range before {
+		if _, exists := after[object]; !exists {
+			changes = append(changes, change(object, "deleted"))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Object < changes[j].Object })
+	return changes
+}
+
+func change(object, kind string) Change {
+	parts := strings.SplitN(object, "/", 3)
+	namespace := parts[1]
+	if parts[0] == "namespace" {
+		namespace = parts[2]
+	}
+	return Change{Object: object, Namespace: namespace, Change: kind}
+}
+
+// Violations returns the changes outside of the namespaces the deployed
+// addons may change (their own and the shared namespaces).
+func (c *Config) Violations(changes []Change, allowed []string) []Change {
+	namespaces := make(map[string]bool, len(allowed)+len(c.Shared))
+	for _, namespace := range append(append([]string{}, allowed...), c.Shared...) {
+		namespaces[namespace] = true
+	}
+	var violations []Change
+	for _, change := range changes {
+		if !namespaces[change.Namespace] {
+			violations = append(violations, change)
+		}
+	}
+	return violations
+}
@@ -0,0 +1,92 @@
+package isolation
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDigest(t *testing.T) {
+	configMap := func(annotations, labels map[string]interface{}, data string) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":        "cert-manager-controller",
+				"namespace":   "cert-manager",
+				"annotations": annotations,
+				"labels":      labels,
+			},
+			"data": map[string]interface{}{"key": data},
+		}
+	}
+
+	key, original, err := digest(configMap(nil, nil, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "configmap/cert-manager/cert-manager-controller" {
+		t.Errorf("unexpected key %s", key)
+	}
+	leader := map[string]interface{}{"control-plane.alpha.kubernetes.io/leader": "{}"}
+	if _, d, _ := digest(configMap(leader, nil, "a")); d != original {
+		t.Error("expected annotations not to change the digest")
+	}
+	if _, d, _ := digest(configMap(nil, map[string]interface{}{"app": "b"}, "a")); d == original {
+		t.Error("expected labels to change the digest")
+	}
+	if _, d, _ := digest(configMap(nil, nil, "b")); d == original {
+		t.Error("expected data to change the digest")
+	}
+
+	key, _, _ = digest(map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "traefik", "namespace": "kubeaddons"},
+	})
+	if key != "deployment.apps/kubeaddons/traefik" {
+		t.Errorf("unexpected key %s", key)
+	}
+}
+
+func TestViolations(t *testing.T) {
+	before := Snapshot{
+		"namespace//kommander":              "1",
+		"configmap/kommander/settings":      "1",
+		"configmap/kube-system/leader":      "1",
+		"deployment.apps/kubeaddons/dex":    "1",
+		"secret/kubeaddons/dex-credentials": "1",
+	}
+	after := Snapshot{
+		"namespace//kommander":              "1",
+		"namespace//kubefed":                "1",
+		"configmap/kommander/settings":      "2",
+		"configmap/kube-system/leader":      "2",
+		"deployment.apps/kubeaddons/dex":    "2",
+		"deployment.apps/kubefed/kubefed":   "1",
+		"configmap/default/kommander-token": "1",
+	}
+
+	changes := before.Diff(after)
+	expected := []Change{
+		{Object: "configmap/default/kommander-token", Namespace: "default", Change: "created"},
+		{Object: "configmap/kommander/settings", Namespace: "kommander", Change: "modified"},
+		{Object: "configmap/kube-system/leader", Namespace: "kube-system", Change: "modified"},
+		{Object: "deployment.apps/kubeaddons/dex", Namespace: "kubeaddons", Change: "modified"},
+		{Object: "deployment.apps/kubefed/kubefed", Namespace: "kubefed", Change: "created"},
+		{Object: "namespace//kubefed", Namespace: "kubefed", Change: "created"},
+		{Object: "secret/kubeaddons/dex-credentials", Namespace: "kubeaddons", Change: "deleted"},
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Fatalf("expected changes %v, got %v", expected, changes)
+	}
+
+	c := &Config{Shared: []string{"kube-system"}}
+	expected = []Change{
+		{Object: "configmap/default/kommander-token", Namespace: "default", Change: "created"},
+		{Object: "deployment.apps/kubeaddons/dex", Namespace: "kubeaddons", Change: "modified"},
+		{Object: "secret/kubeaddons/dex-credentials", Namespace: "kubeaddons", Change: "deleted"},
+	}
+	if actual := c.Violations(changes, []string{"kommander", "kubefed"}); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected violations %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,215 @@
+package kubectl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/retry"
+)
+
+// Client runs kubectl commands against the clusters of a kubeconfig file,
+// e.g. the file of the cluster of a scenario, which is passed to every command
+// explicitly with --kubeconfig. Clusters created for a client add their
+// contexts to its file only (see Env), so concurrent runs don't switch the
+// current context of each other. The zero Client uses the kubeconfig of the
+// environment.
+type Client struct {
+	Kubeconfig string
+}
+
+// Env returns the environment of the process with KUBECONFIG set to the
+// kubeconfig file of the client, for other CLIs (e.g. kind and helm) run on
+// its behalf.
+func (k Client) Env() []string {
+	if k.Kubeconfig == "" {
+		return os.Environ()
+	}
+	return append(os.Environ(), "KUBECONFIG="+k.Kubeconfig)
+}
+
+// Merge merges the kubeconfig file at path into the kubeconfig file of the
+// client.
+func (k Client) Merge(path string) error {
+	if k.Kubeconfig == "" {
+		return fmt.Errorf("could not merge kubeconfig %s, the client has no kubeconfig file", path)
+	}
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command("kubectl", "config", "view", "--flatten")
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+k.Kubeconfig+string(os.PathListSeparator)+path)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not merge kubeconfig %s: %w: %s", path, err, stderr.String())
+	}
+	return ioutil.WriteFile(k.Kubeconfig, stdout.Bytes(), 0600)
+}
+
+// Command returns kubectl with the provided arguments and the kubeconfig file
+// of the client.
+func (k Client) Command(args ...string) *exec.Cmd {
+	if k.Kubeconfig != "" {
+		args = append([]string{"--kubeconfig", k.Kubeconfig}, args...)
+	}
+	return exec.Command("kubectl", args...)
+}
+
+// Run executes kubectl with the provided arguments, streaming its output to
+// the stdout and stderr of the current process.
+func (k Client) Run(args ...string) error {
+	cmd := k.Command(args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// RunWithInput executes kubectl with the provided arguments and input on its
+// stdin (e.g. manifests for `kubectl apply --filename -`).
+func (k Client) RunWithInput(input []byte, args ...string) error {
+	cmd := k.Command(args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Output executes kubectl with the provided arguments and returns its stdout.
+func (k Client) Output(args ...string) ([]byte, error) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := k.Command(args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %w: %s", strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Apply runs `kubectl apply` for the manifests in input, retrying transient
+// failures (e.g. an API server which is still starting) with exponential backoff.
+func (k Client) Apply(input []byte, args ...string) error {
+	return k.apply(input, append([]string{"apply", "--filename", "-"}, args...))
+}
+
+// ApplyFile runs `kubectl apply` for the manifests in a file or URL, retrying
+// transient failures like Apply.
+func (k Client) ApplyFile(filename string, args ...string) error {
+	return k.apply(nil, append([]string{"apply", "--filename", filename}, args...))
+}
+
+func (k Client) apply(input []byte, args []string) error {
+	return retry.Do(retry.Default, func() error {
+		stderr := new(bytes.Buffer)
+		cmd := k.Command(args...)
+		if input != nil {
+			cmd.Stdin = bytes.NewReader(input)
+		}
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("kubectl %s failed: %w: %s", strings.Join(args, " "), err, stderr.String())
+		}
+		return nil
+	})
+}
+
+// Proxy is a running `kubectl proxy` which provides unauthenticated access to
+// the Kubernetes API (and services via the API server proxy) on localhost.
+type Proxy struct {
+	URL string
+	cmd *exec.Cmd
+}
+
+// StartProxy starts `kubectl proxy` on a random local port and waits until it
+// is serving.
+func (k Client) StartProxy() (*Proxy, error) {
+	cmd := k.Command("proxy", "--port", "0")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// kubectl proxy reports the address it serves on as its first line of output
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("kubectl proxy did not start: %w", err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.Contains(line, "Starting to serve on") {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("unexpected output from kubectl proxy: %s", line)
+	}
+
+	return &Proxy{URL: "http://" + fields[len(fields)-1], cmd: cmd}, nil
+}
+
+// ServiceURL returns the URL through which the API server proxies requests for
+// the given path to a service, e.g. ServiceURL("kommander", "http:grafana:80", "/api/health").
+func (p *Proxy) ServiceURL(namespace, service, path string) string {
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s/proxy/%s", p.URL, namespace, service, strings.TrimPrefix(path, "/"))
+}
+
+// Stop terminates the proxy.
+func (p *Proxy) Stop() error {
+	if err := p.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	_ = p.cmd.Wait()
+	return nil
+}
+
+// PortForward is a running `kubectl port-forward` to a port of a resource.
+type PortForward struct {
+	// Addr is the local address which is forwarded, e.g. 127.0.0.1:34567.
+	Addr string
+	cmd  *exec.Cmd
+}
+
+// StartPortForward starts `kubectl port-forward` from a random local port to a
+// port of a resource (e.g. service/traefik-kubeaddons) and waits until it is
+// forwarding.
+func (k Client) StartPortForward(namespace, resource string, port int) (*PortForward, error) {
+	cmd := k.Command("port-forward", "--namespace", namespace, "--address", "127.0.0.1", resource, fmt.Sprintf(":%d", port))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	// kubectl port-forward reports "Forwarding from 127.0.0.1:<port> -> <port>"
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("kubectl port-forward to %s did not start: %w", resource, err)
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "Forwarding" {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("unexpected output from kubectl port-forward: %s", line)
+	}
+
+	return &PortForward{Addr: fields[2], cmd: cmd}, nil
+}
+
+// Stop terminates the port forward.
+func (p *PortForward) Stop() error {
+	if err := p.cmd.Process.Kill(); err != nil {
+		return err
+	}
+	_ = p.cmd.Wait()
+	return nil
+}
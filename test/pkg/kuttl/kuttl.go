@@ -0,0 +1,450 @@
+package kuttl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	// apiGroup is the API group of the TestStep and TestAssert objects of
+	// kuttl.
+	apiGroup = "kuttl.dev"
+
+	// interval is the interval in which the asserts of a step are verified.
+	interval = 2 * time.Second
+)
+
+var (
+	// stepFile matches the files of steps, <index>-<name>.yaml.
+	stepFile = regexp.MustCompile(`^(\d+)-([^.]+)\.yaml$`)
+
+	invalidNamespace = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// Case is a declarative test case of an addon: a directory of steps in the
+// layout of kuttl (https://kuttl.dev/docs/testing/steps.html).
+type Case struct {
+	Name  string
+	Steps []Step
+}
+
+// Step is a step of a case: the files with the same index. The objects of
+// <index>-<name>.yaml files are applied, those of <index>-assert.yaml files
+// must exist and those of <index>-errors.yaml files must not exist. Expected
+// objects only declare the fields they assert, and objects without a name
+// assert that any object of their kind matches.
+type Step struct {
+	Index int
+
+	// Name is the name of the first file of the step without its extension,
+	// e.g. 00-install.
+	Name string
+
+	Apply  []render.Object
+	Assert []render.Object
+	Errors []render.Object
+
+	// Delete are the objects deleted before those of the step are applied
+	// (see the delete list of TestStep).
+	Delete []render.Object
+
+	// Timeout is how long the asserts of the step may take to be met (see
+	// the timeout of TestAssert), the timeout of the run when zero.
+	Timeout time.Duration
+}
+
+// Load reads the cases of an addon from a directory, where every directory
+// within it is a case. There are no cases when the directory does not exist.
+func Load(dir string) ([]Case, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cases []Case
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c, err := loadCase(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+func loadCase(dir string) (Case, error) {
+	c := Case{Name: filepath.Base(dir)}
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return c, err
+	}
+	sort.Strings(files)
+
+	steps := make(map[int]*Step)
+	for _, file := range files {
+		match := stepFile.FindStringSubmatch(filepath.Base(file))
+		if match == nil {
+			return c, fmt.Errorf("%s: expected the file name of a step, <index>-<name>.yaml", file)
+		}
+		index, _ := strconv.Atoi(match[1])
+		step, ok := steps[index]
+		if !ok {
+			step = &Step{Index: index, Name: strings.TrimSuffix(filepath.Base(file), ".yaml")}
+			steps[index] = step
+		}
+		if err := step.load(file, match[2]); err != nil {
+			return c, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	if len(steps) == 0 {
+		return c, fmt.Errorf("test case %s has no steps", dir)
+	}
+
+	for _, step := range steps {
+		c.Steps = append(c.Steps, *step)
+	}
+	sort.Slice(c.Steps, func(i, j int) bool { return c.Steps[i].Index < c.Steps[j].Index })
+	return c, nil
+}
+
+// load adds the objects of a file of the step.
+func (s *Step) load(file, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	objects, err := render.Decode(f)
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		if obj.Kind == "" || obj.APIVersion == "" {
+			return fmt.Errorf("objects require an apiVersion and a kind")
+		}
+		if strings.HasPrefix(obj.APIVersion, apiGroup+"/") {
+			if err := s.configure(obj); err != nil {
+				return err
+			}
+			continue
+		}
+		switch {
+		case name == "assert" || strings.HasPrefix(name, "assert-"):
+			s.Assert = append(s.Assert, obj)
+		case name == "errors" || strings.HasPrefix(name, "errors-"):
+			s.Errors = append(s.Errors, obj)
+		default:
+			if obj.Name == "" {
+				return fmt.Errorf("%s to apply requires a name", obj.Kind)
+			}
+			s.Apply = append(s.Apply, obj)
+		}
+	}
+	return nil
+}
+
+// configure applies a TestStep or TestAssert object to the step. Commands
+// are not supported, steps only declare objects.
+func (s *Step) configure(obj render.Object) error {
+	for key := range obj.Content {
+		switch key {
+		case "apiVersion", "kind", "metadata":
+		case "timeout":
+			if obj.Kind != "TestAssert" {
+				return fmt.Errorf("the timeout of a step belongs to its TestAssert")
+			}
+			seconds, ok := obj.Content[key].(int)
+			if !ok || seconds <= 0 {
+				return fmt.Errorf("the timeout of TestAssert must be a positive number of seconds")
+			}
+			s.Timeout = time.Duration(seconds) * time.Second
+		case "delete":
+			if obj.Kind != "TestStep" {
+				return fmt.Errorf("the objects deleted by a step belong to its TestStep")
+			}
+			refs, _ := obj.Content[key].([]interface{})
+			for _, ref := range refs {
+				content, ok := ref.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("the objects deleted by TestStep must be references")
+				}
+				deleted := render.Object{Content: content}
+				deleted.APIVersion, _ = content["apiVersion"].(string)
+				deleted.Kind, _ = content["kind"].(string)
+				deleted.Name, _ = content["name"].(string)
+				deleted.Namespace, _ = content["namespace"].(string)
+				if deleted.APIVersion == "" || deleted.Kind == "" || deleted.Name == "" {
+					return fmt.Errorf("the objects deleted by TestStep require an apiVersion, a kind and a name")
+				}
+				s.Delete = append(s.Delete, deleted)
+			}
+		default:
+			return fmt.Errorf("%s of %s is not supported, steps declare objects only", key, obj.Kind)
+		}
+	}
+	if obj.Kind != "TestStep" && obj.Kind != "TestAssert" {
+		return fmt.Errorf("unknown kind %s of %s", obj.Kind, obj.APIVersion)
+	}
+	return nil
+}
+
+// Namespace returns the namespace the steps of a case of an addon run in.
+func Namespace(addon, name string) string {
+	namespace := strings.ToLower("test-" + addon + "-" + name)
+	namespace = invalidNamespace.ReplaceAllString(namespace, "-")
+	if len(namespace) > 63 {
+		namespace = namespace[:63]
+	}
+	return strings.TrimRight(namespace, "-")
+}
+
+// Run runs the steps of the case in order within a namespace, which is the
+// namespace of all objects which don't declare their own. The asserts of
+// every step must be met within the timeout of the step (or timeout) before
+// the next step is applied.
+func (c Case) Run(k kubectl.Client, namespace string, timeout time.Duration) error {
+	for _, step := range c.Steps {
+		if err := step.run(k, namespace, timeout); err != nil {
+			return fmt.Errorf("step %s of %s: %w", step.Name, c.Name, err)
+		}
+	}
+	return nil
+}
+
+// Cleanup deletes the objects the steps of the case applied, in reverse
+// order.
+func (c Case) Cleanup(k kubectl.Client, namespace string) error {
+	var objects []render.Object
+	for i := len(c.Steps) - 1; i >= 0; i-- {
+		apply := c.Steps[i].Apply
+		for j := len(apply) - 1; j >= 0; j-- {
+			objects = append(objects, apply[j])
+		}
+	}
+	if len(objects) == 0 {
+		return nil
+	}
+	b, err := list(objects)
+	if err != nil {
+		return err
+	}
+	return k.RunWithInput(b, "delete", "--namespace", namespace, "--ignore-not-found", "--wait", "--filename", "-")
+}
+
+func (s Step) run(k kubectl.Client, namespace string, timeout time.Duration) error {
+	for _, obj := range s.Delete {
+		if err := k.Run("delete", resource(obj), obj.Name, "--namespace", objectNamespace(obj, namespace), "--ignore-not-found", "--wait"); err != nil {
+			return err
+		}
+	}
+	if len(s.Apply) > 0 {
+		b, err := list(s.Apply)
+		if err != nil {
+			return err
+		}
+		if err := k.Apply(b, "--namespace", namespace); err != nil {
+			return err
+		}
+	}
+
+	if s.Timeout > 0 {
+		timeout = s.Timeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		err := s.verify(k, namespace)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("asserts were not met within %s: %w", timeout, err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// verify returns an error when an expected object does not exist or an
+// object of the errors of the step exists.
+func (s Step) verify(k kubectl.Client, namespace string) error {
+	for _, expected := range s.Assert {
+		actual, err := get(k, expected, namespace)
+		if err != nil {
+			return err
+		}
+		if err := matchAny(expected, actual); err != nil {
+			return err
+		}
+	}
+	for _, unexpected := range s.Errors {
+		actual, err := get(k, unexpected, namespace)
+		if err != nil {
+			return err
+		}
+		if matchAny(unexpected, actual) == nil {
+			return fmt.Errorf("%s exists", describe(unexpected))
+		}
+	}
+	return nil
+}
+
+// matchAny returns nil when one of the actual objects matches the expected
+// object, otherwise the mismatch of the first one.
+func matchAny(expected render.Object, actual []interface{}) error {
+	if len(actual) == 0 {
+		return fmt.Errorf("%s was not found", describe(expected))
+	}
+	normalized, err := normalize(expected.Content)
+	if err != nil {
+		return err
+	}
+	var first error
+	for _, obj := range actual {
+		err := match("", normalized, obj)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = fmt.Errorf("%s does not match: %w", describe(expected), err)
+		}
+	}
+	return first
+}
+
+// match returns an error when actual does not contain expected: the fields of
+// expected objects must match those of actual objects, while lists must match
+// item by item.
+func match(path string, expected, actual interface{}) error {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %v", field(path), actual)
+		}
+		keys := make([]string, 0, len(e))
+		for key := range e {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := match(path+"."+key, e[key], a[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(a) != len(e) {
+			return fmt.Errorf("%s: expected %d items, got %v", field(path), len(e), actual)
+		}
+		for i := range e {
+			if err := match(fmt.Sprintf("%s[%d]", path, i), e[i], a[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Errorf("%s: expected %v, got %v", field(path), expected, actual)
+		}
+	}
+	return nil
+}
+
+func field(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// normalize converts the content of an object to the types of JSON (e.g.
+// numbers to float64), which kubectl returns objects in.
+func normalize(content map[string]interface{}) (interface{}, error) {
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// get returns the objects of the kind of obj, only the object of its name
+// when it has one.
+func get(k kubectl.Client, obj render.Object, namespace string) ([]interface{}, error) {
+	args := []string{"get", resource(obj)}
+	if obj.Name != "" {
+		args = append(args, obj.Name)
+	}
+	args = append(args, "--namespace", objectNamespace(obj, namespace), "--ignore-not-found", "--output", "json")
+	out, err := k.Output(args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var content map[string]interface{}
+	if err := json.Unmarshal(out, &content); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", describe(obj), err)
+	}
+	if kind, _ := content["kind"].(string); kind == "List" {
+		items, _ := content["items"].([]interface{})
+		return items, nil
+	}
+	return []interface{}{content}, nil
+}
+
+// resource returns the fully qualified resource of the kind of an object,
+// e.g. Deployment.v1.apps, so that kinds of different groups don't clash.
+func resource(obj render.Object) string {
+	parts := strings.SplitN(obj.APIVersion, "/", 2)
+	if len(parts) == 1 {
+		return obj.Kind
+	}
+	return obj.Kind + "." + parts[1] + "." + parts[0]
+}
+
+func objectNamespace(obj render.Object, namespace string) string {
+	if obj.Namespace != "" {
+		return obj.Namespace
+	}
+	return namespace
+}
+
+func describe(obj render.Object) string {
+	if obj.Name == "" {
+		return "any " + obj.Kind
+	}
+	return obj.Kind + " " + obj.Name
+}
+
+// list returns the objects as a JSON List.
+func list(objects []render.Object) ([]byte, error) {
+	items := make([]interface{}, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, obj.Content)
+	}
+	return json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+}
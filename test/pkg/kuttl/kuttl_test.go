@@ -0,0 +1,131 @@
+package kuttl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func writeCase(t *testing.T, dir string, files map[string]string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kuttl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if cases, err := Load(filepath.Join(dir, "missing")); err != nil || len(cases) != 0 {
+		t.Fatalf("expected no cases of a missing directory, got %v, %v", cases, err)
+	}
+
+	writeCase(t, filepath.Join(dir, "traefik", "routing"), map[string]string{
+		"00-install.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: routes\n---\napiVersion: kuttl.dev/v1beta1\nkind: TestStep\ndelete:\n- apiVersion: v1\n  kind: ConfigMap\n  name: stale\n",
+		"00-assert.yaml":  "apiVersion: kuttl.dev/v1beta1\nkind: TestAssert\ntimeout: 30\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: routes\n",
+		"10-errors.yaml":  "apiVersion: v1\nkind: Secret\nmetadata:\n  name: routes\n",
+	})
+	if err := ioutil.WriteFile(filepath.Join(dir, "traefik", "README.md"), []byte("cases of traefik"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases, err := Load(filepath.Join(dir, "traefik"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cases) != 1 || cases[0].Name != "routing" || len(cases[0].Steps) != 2 {
+		t.Fatalf("expected one case with two steps, got %+v", cases)
+	}
+	install, errors := cases[0].Steps[0], cases[0].Steps[1]
+	if install.Index != 0 || install.Name != "00-assert" || len(install.Apply) != 1 || len(install.Assert) != 1 || install.Timeout != 30*time.Second {
+		t.Errorf("unexpected step %+v", install)
+	}
+	if len(install.Delete) != 1 || install.Delete[0].Name != "stale" || install.Delete[0].Kind != "ConfigMap" {
+		t.Errorf("expected the stale config map to be deleted, got %+v", install.Delete)
+	}
+	if errors.Index != 10 || len(errors.Errors) != 1 || len(errors.Apply) != 0 {
+		t.Errorf("unexpected step %+v", errors)
+	}
+
+	for name, files := range map[string]map[string]string{
+		"file name": {"install.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: routes\n"},
+		"commands":  {"00-install.yaml": "apiVersion: kuttl.dev/v1beta1\nkind: TestStep\ncommands:\n- command: kubectl get pods\n"},
+		"timeout":   {"00-install.yaml": "apiVersion: kuttl.dev/v1beta1\nkind: TestStep\ntimeout: 30\n"},
+		"name":      {"00-install.yaml": "apiVersion: v1\nkind: ConfigMap\n"},
+		"kind":      {"00-install.yaml": "apiVersion: kuttl.dev/v1beta1\nkind: TestSuite\n"},
+		"no steps":  {},
+	} {
+		invalid := filepath.Join(dir, name)
+		writeCase(t, filepath.Join(invalid, "case"), files)
+		if _, err := Load(invalid); err == nil {
+			t.Errorf("expected an error for the invalid %s", name)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	expected := render.Object{Kind: "Deployment", Name: "traefik", Content: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "traefik"},
+		"spec":     map[string]interface{}{"replicas": 2},
+		"status":   map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Available"}}},
+	}}
+	actual := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "traefik", "namespace": "kubeaddons"},
+		"spec":     map[string]interface{}{"replicas": float64(2), "paused": false},
+		"status":   map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Available", "status": "True"}}},
+	}
+	if err := matchAny(expected, []interface{}{actual}); err != nil {
+		t.Errorf("expected the deployment to match, got %v", err)
+	}
+
+	other := map[string]interface{}{"metadata": map[string]interface{}{"name": "traefik"}, "spec": map[string]interface{}{"replicas": float64(1)}}
+	if err := matchAny(expected, []interface{}{other, actual}); err != nil {
+		t.Errorf("expected any object to match, got %v", err)
+	}
+	err := matchAny(expected, []interface{}{other})
+	if err == nil || !strings.Contains(err.Error(), ".spec.replicas: expected 2, got 1") {
+		t.Errorf("expected the replicas not to match, got %v", err)
+	}
+	if err := matchAny(expected, nil); err == nil || !strings.Contains(err.Error(), "Deployment traefik was not found") {
+		t.Errorf("expected the deployment not to be found, got %v", err)
+	}
+
+	actual["status"] = map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Progressing"}, map[string]interface{}{"type": "Available"}}}
+	if err := matchAny(expected, []interface{}{actual}); err == nil || !strings.Contains(err.Error(), ".status.conditions: expected 1 items") {
+		t.Errorf("expected lists to match item by item, got %v", err)
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	if namespace := Namespace("cert-manager", "Issuer_ca"); namespace != "test-cert-manager-issuer-ca" {
+		t.Errorf("unexpected namespace %s", namespace)
+	}
+	if namespace := Namespace("kommander", strings.Repeat("a", 47)+"-b"); len(namespace) > 63 || strings.HasSuffix(namespace, "-") {
+		t.Errorf("expected a valid namespace, got %s", namespace)
+	}
+}
+
+func TestResource(t *testing.T) {
+	for apiVersion, expected := range map[string]string{
+		"v1":                           "Deployment",
+		"apps/v1":                      "Deployment.v1.apps",
+		"traefik.containo.us/v1alpha1": "Deployment.v1alpha1.traefik.containo.us",
+	} {
+		if r := resource(render.Object{APIVersion: apiVersion, Kind: "Deployment"}); r != expected {
+			t.Errorf("expected %s, got %s", expected, r)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package license
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const (
+	// Namespace is the namespace kommander looks for licenses in.
+	Namespace = "kommander"
+
+	apiPath = "/apis/kommander.mesosphere.io/v1beta1/namespaces/" + Namespace + "/licenses/"
+
+	// probeCluster is the name of the cluster AttachAllowed attempts to
+	// attach, which is never persisted.
+	probeCluster = "kommander-license-probe"
+)
+
+// Status is the licensing status kommander reports for a license.
+type Status struct {
+	Valid           bool   `json:"valid"`
+	ClusterCapacity int    `json:"clusterCapacity"`
+	ExpiryDate      string `json:"expiryDate"`
+}
+
+// Apply creates a license with the given name from a license token: a secret
+// holding the token and the license resource referencing it.
+func Apply(k kubectl.Client, name string, token []byte) error {
+	list := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": name, "namespace": Namespace},
+				"stringData": map[string]interface{}{"jwt": string(token)},
+			},
+			map[string]interface{}{
+				"apiVersion": "kommander.mesosphere.io/v1beta1",
+				"kind":       "License",
+				"metadata":   map[string]interface{}{"name": name, "namespace": Namespace},
+				"spec":       map[string]interface{}{"licenseRef": map[string]interface{}{"name": name}},
+			},
+		},
+	}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return k.Apply(b)
+}
+
+// Revoke removes the secret holding the token of a license, which must make
+// the license invalid.
+func Revoke(k kubectl.Client, name string) error {
+	return k.Run("delete", "secret", name, "--namespace", Namespace, "--ignore-not-found")
+}
+
+// Delete removes a license along with its secret.
+func Delete(k kubectl.Client, name string) error {
+	if err := k.Run("delete", "license", name, "--namespace", Namespace, "--ignore-not-found"); err != nil {
+		return err
+	}
+	return Revoke(k, name)
+}
+
+// Get returns the status of a license as served by the kommander API.
+func Get(k kubectl.Client, name string) (Status, error) {
+	b, err := k.Output("get", "--raw", apiPath+name)
+	if err != nil {
+		return Status{}, err
+	}
+	license := struct {
+		Status Status `json:"status"`
+	}{}
+	if err := json.Unmarshal(b, &license); err != nil {
+		return Status{}, fmt.Errorf("could not decode license %s: %w", name, err)
+	}
+	return license.Status, nil
+}
+
+// Wait waits up to timeout for kommander to report a license as valid or as
+// invalid, as given by valid, returning the last status.
+func Wait(k kubectl.Client, name string, valid bool, timeout time.Duration) (Status, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := Get(k, name)
+		if err == nil && status.Valid == valid {
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return status, err
+			}
+			return status, fmt.Errorf("license %s did not become valid=%t within %s", name, valid, timeout)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// AttachAllowed reports whether kommander admits attaching a cluster, the
+// feature gated by the license: it creates a KommanderCluster with a server
+// side dry run, so nothing is persisted, and returns false when admission
+// denies it.
+func AttachAllowed(k kubectl.Client) (bool, error) {
+	b, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "kommander.mesosphere.io/v1beta1",
+		"kind":       "KommanderCluster",
+		"metadata":   map[string]interface{}{"name": probeCluster, "namespace": Namespace},
+		"spec":       map[string]interface{}{"kubeconfigRef": map[string]interface{}{"name": probeCluster}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := k.Command("create", "--dry-run=server", "--filename", "-")
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "denied the request") {
+			return false, nil
+		}
+		return false, fmt.Errorf("could not attach cluster %s: %w: %s", probeCluster, err, stderr.String())
+	}
+	return true, nil
+}
+
+// WaitAttachAllowed waits up to timeout for attaching a cluster to be allowed
+// or denied, as given by allowed.
+func WaitAttachAllowed(k kubectl.Client, allowed bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := AttachAllowed(k)
+		if err == nil && ok == allowed {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err != nil {
+				return err
+			}
+			return fmt.Errorf("attaching a cluster was not allowed=%t within %s", allowed, timeout)
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
@@ -0,0 +1,150 @@
+package logscan
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Log is the log of a container (or the status of a pod, so that reasons like
+// CrashLoopBackOff and OOMKilled are scanned as well).
+type Log struct {
+	Source string
+	Text   string
+}
+
+// workloadKinds are the kinds whose pods are selected by spec.selector.
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"DaemonSet":   true,
+	"StatefulSet": true,
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			InitContainers []struct {
+				Name string `json:"name"`
+			} `json:"initContainers"`
+			Containers []struct {
+				Name string `json:"name"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			InitContainerStatuses []containerStatus `json:"initContainerStatuses"`
+			ContainerStatuses     []containerStatus `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type containerStatus struct {
+	Name         string `json:"name"`
+	RestartCount int    `json:"restartCount"`
+	State        state  `json:"state"`
+	LastState    state  `json:"lastState"`
+}
+
+type state struct {
+	Waiting *struct {
+		Reason string `json:"reason"`
+	} `json:"waiting"`
+	Terminated *struct {
+		Reason   string `json:"reason"`
+		ExitCode int    `json:"exitCode"`
+	} `json:"terminated"`
+}
+
+// Collect returns the logs of the pods of the workloads in the rendered objects
+// of addons, mapped to the addon. The logs of previous instances of restarted
+// containers are included.
+func Collect(k kubectl.Client, objects []render.Object) (map[string][]Log, error) {
+	logs := make(map[string][]Log)
+	for _, obj := range objects {
+		if !workloadKinds[obj.Kind] {
+			continue
+		}
+		selector := selector(obj)
+		if selector == "" {
+			continue
+		}
+
+		b, err := k.Output("get", "pods", "--namespace", obj.Namespace, "--selector", selector, "--output", "json")
+		if err != nil {
+			return nil, err
+		}
+		pods := podList{}
+		if err := json.Unmarshal(b, &pods); err != nil {
+			return nil, err
+		}
+
+		for _, pod := range pods.Items {
+			statuses := make(map[string]containerStatus)
+			var status []string
+			for _, s := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+				statuses[s.Name] = s
+				status = append(status, s.describe()...)
+			}
+			if len(status) > 0 {
+				logs[obj.Addon] = append(logs[obj.Addon], Log{
+					Source: fmt.Sprintf("%s/pod/%s status", obj.Namespace, pod.Metadata.Name),
+					Text:   strings.Join(status, "\n"),
+				})
+			}
+
+			var containers []string
+			for _, c := range pod.Spec.InitContainers {
+				containers = append(containers, c.Name)
+			}
+			for _, c := range pod.Spec.Containers {
+				containers = append(containers, c.Name)
+			}
+			for _, container := range containers {
+				source := fmt.Sprintf("%s/pod/%s container %s", obj.Namespace, pod.Metadata.Name, container)
+				args := []string{"logs", pod.Metadata.Name, "--namespace", obj.Namespace, "--container", container}
+				if text, err := k.Output(args...); err == nil {
+					logs[obj.Addon] = append(logs[obj.Addon], Log{Source: source, Text: string(text)})
+				}
+				if statuses[container].RestartCount > 0 {
+					if text, err := k.Output(append(args, "--previous")...); err == nil {
+						logs[obj.Addon] = append(logs[obj.Addon], Log{Source: source + " (previous)", Text: string(text)})
+					}
+				}
+			}
+		}
+	}
+	return logs, nil
+}
+
+// describe returns the reasons of the current and last state of a container,
+// e.g. "container traefik is waiting: CrashLoopBackOff".
+func (s containerStatus) describe() []string {
+	var lines []string
+	if w := s.State.Waiting; w != nil && w.Reason != "" {
+		lines = append(lines, fmt.Sprintf("container %s is waiting: %s", s.Name, w.Reason))
+	}
+	if t := s.LastState.Terminated; t != nil {
+		lines = append(lines, fmt.Sprintf("container %s was terminated (exit code %d, %d restarts): %s", s.Name, t.ExitCode, s.RestartCount, t.Reason))
+	}
+	return lines
+}
+
+// selector returns the label selector of the pods of a workload.
+func selector(obj render.Object) string {
+	spec, _ := obj.Content["spec"].(map[string]interface{})
+	sel, _ := spec["selector"].(map[string]interface{})
+	labels, _ := sel["matchLabels"].(map[string]interface{})
+
+	var terms []string
+	for k, v := range labels {
+		terms = append(terms, fmt.Sprintf("%s=%v", k, v))
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, ",")
+}
@@ -0,0 +1,121 @@
+package logscan
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Patterns are regular expressions which are matched against every line of
+// the logs of an addon. Matches of Fail patterns fail the test, matches of
+// Warn patterns are only logged.
+type Patterns struct {
+	Fail []string `yaml:"fail"`
+	Warn []string `yaml:"warn"`
+}
+
+// Config holds the patterns which are scanned for in the logs of all addons,
+// and the additional patterns of individual addons.
+type Config struct {
+	Default Patterns            `yaml:"default"`
+	Addons  map[string]Patterns `yaml:"addons"`
+}
+
+// Load reads the patterns from a YAML file. A file which does not exist
+// contains no patterns.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, err
+	}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse log patterns from %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Rules are the compiled patterns of an addon.
+type Rules struct {
+	fail []*regexp.Regexp
+	warn []*regexp.Regexp
+}
+
+// Rules compiles the default patterns together with the patterns of an addon.
+func (c *Config) Rules(addon string) (*Rules, error) {
+	r := &Rules{}
+	for _, p := range []Patterns{c.Default, c.Addons[addon]} {
+		fail, err := compile(p.Fail)
+		if err != nil {
+			return nil, err
+		}
+		warn, err := compile(p.Warn)
+		if err != nil {
+			return nil, err
+		}
+		r.fail = append(r.fail, fail...)
+		r.warn = append(r.warn, warn...)
+	}
+	return r, nil
+}
+
+func compile(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Match is a pattern which matched the log of a container.
+type Match struct {
+	Addon   string
+	Source  string
+	Pattern string
+	Fatal   bool
+
+	// Line is the first matching line, Count the number of matching lines.
+	Line  string
+	Count int
+}
+
+func (m Match) String() string {
+	return fmt.Sprintf("%s: %s matched %q %d time(s), first: %s", m.Addon, m.Source, m.Pattern, m.Count, m.Line)
+}
+
+// Scan matches every line of a log against the rules, returning one match per
+// pattern which matched.
+func (r *Rules) Scan(addon string, log Log) []Match {
+	var matches []Match
+	for _, rule := range []struct {
+		patterns []*regexp.Regexp
+		fatal    bool
+	}{{r.fail, true}, {r.warn, false}} {
+		for _, re := range rule.patterns {
+			m := Match{Addon: addon, Source: log.Source, Pattern: re.String(), Fatal: rule.fatal}
+			for _, line := range strings.Split(log.Text, "\n") {
+				if re.MatchString(line) {
+					if m.Count == 0 {
+						m.Line = strings.TrimSpace(line)
+					}
+					m.Count++
+				}
+			}
+			if m.Count > 0 {
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,45 @@
+package logscan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestScan(t *testing.T) {
+	c := &Config{
+		Default: Patterns{Fail: []string{`panic:`, `OOMKilled`}},
+		Addons:  map[string]Patterns{"traefik": {Warn: []string{`level=error`}}},
+	}
+	rules, err := c.Rules("traefik")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log := Log{Source: "kubeaddons/pod/traefik-0 container traefik", Text: "level=info msg=started\nlevel=error msg=\"backend not found\"\nlevel=error msg=retry\n"}
+	expected := []Match{{Addon: "traefik", Source: log.Source, Pattern: "level=error", Line: `level=error msg="backend not found"`, Count: 2}}
+	if actual := rules.Scan("traefik", log); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+
+	status := Log{Source: "kubeaddons/pod/traefik-0 status", Text: "container traefik was terminated (exit code 137, 1 restarts): OOMKilled"}
+	if actual := rules.Scan("traefik", status); len(actual) != 1 || !actual[0].Fatal {
+		t.Errorf("expected a fatal OOMKilled match, got %v", actual)
+	}
+
+	if _, err := (&Config{Default: Patterns{Fail: []string{"("}}}).Rules("traefik"); err == nil {
+		t.Error("expected an invalid pattern to be rejected")
+	}
+}
+
+func TestSelector(t *testing.T) {
+	obj := render.Object{Kind: "Deployment", Content: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"release": "traefik-kubeaddons", "app": "traefik"}},
+		},
+	}}
+	if expected, actual := "app=traefik,release=traefik-kubeaddons", selector(obj); expected != actual {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,223 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// Exporter sends the metrics of a finished test run to a metrics backend.
+type Exporter interface {
+	Export(r *report.Report) error
+}
+
+// Sample is a single metric value along with its labels.
+type Sample struct {
+	Name   string
+	Help   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Samples returns the metrics of a test run: the duration of the run and of
+// each phase, the status of each addon, the flakes of each check and the
+// latencies measured by scale scenarios. The durations of a phase which a
+// scenario entered repeatedly (e.g. validating every wave) are summed, and an
+// addon recorded repeatedly in a scenario ends in the status recorded last.
+func Samples(r *report.Report) []Sample {
+	samples := []Sample{{
+		Name:  "kommander_addons_test_run_duration_seconds",
+		Help:  "Duration of the test run.",
+		Value: r.Finished.Sub(r.Started).Seconds(),
+	}}
+
+	type phaseKey struct{ scenario, phase string }
+	var phases []phaseKey
+	durations := make(map[phaseKey]time.Duration)
+	for _, phase := range r.Phases {
+		key := phaseKey{phase.Scenario, phase.Phase}
+		if _, ok := durations[key]; !ok {
+			phases = append(phases, key)
+		}
+		durations[key] += phase.Duration
+	}
+	for _, key := range phases {
+		samples = append(samples, Sample{
+			Name:   "kommander_addons_test_phase_duration_seconds",
+			Help:   "Duration of a phase of a scenario, summed over the times the scenario entered it.",
+			Labels: map[string]string{"scenario": key.scenario, "phase": key.phase},
+			Value:  durations[key].Seconds(),
+		})
+	}
+
+	type addonKey struct{ scenario, group, addon string }
+	var addons []addonKey
+	results := make(map[addonKey]report.AddonResult)
+	for _, addon := range r.Addons {
+		key := addonKey{addon.Scenario, addon.Group, addon.Addon}
+		if _, ok := results[key]; !ok {
+			addons = append(addons, key)
+		}
+		results[key] = addon
+	}
+	for _, key := range addons {
+		addon := results[key]
+		for _, status := range []report.Status{report.Passed, report.Failed, report.Skipped} {
+			value := 0.0
+			if addon.Status == status {
+				value = 1
+			}
+			samples = append(samples, Sample{
+				Name: "kommander_addons_test_addon_status",
+				Help: "Status of an addon in a testing group of a scenario, 1 for the status the addon ended in.",
+				Labels: map[string]string{
+					"scenario":     addon.Scenario,
+					"group":        addon.Group,
+					"addon":        addon.Addon,
+					"revision":     addon.Revision,
+					"architecture": addon.Architecture,
+					"status":       string(status),
+				},
+				Value: value,
+			})
+		}
+	}
+
+	checks := make([]string, 0, len(r.Flakes))
+	for check := range r.Flakes {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	for _, check := range checks {
+		samples = append(samples, Sample{
+			Name:   "kommander_addons_test_check_flakes",
+			Help:   "Failed attempts of a check which eventually passed.",
+			Labels: map[string]string{"check": check},
+			Value:  float64(r.Flakes[check]),
+		})
+	}
+
+	for _, result := range r.Scale {
+		for _, quantile := range []struct {
+			name    string
+			latency time.Duration
+		}{{"0.5", result.P50}, {"0.95", result.P95}, {"1", result.Max}} {
+			samples = append(samples, Sample{
+				Name:   "kommander_addons_test_scale_latency_seconds",
+				Help:   "Latency of the reconciliation of the objects a scale scenario created, by quantile.",
+				Labels: map[string]string{"scenario": result.Scenario, "kind": result.Kind, "quantile": quantile.name},
+				Value:  quantile.latency.Seconds(),
+			})
+		}
+	}
+
+	return samples
+}
+
+// Pushgateway exports metrics to a Prometheus Pushgateway, replacing the
+// metrics previously pushed for its job.
+type Pushgateway struct {
+	URL string
+	Job string
+}
+
+// Export pushes the metrics of a test run.
+func (p Pushgateway) Export(r *report.Report) error {
+	target := fmt.Sprintf("%s/metrics/job/%s", strings.TrimSuffix(p.URL, "/"), url.PathEscape(p.Job))
+	return send(http.MethodPut, target, "text/plain; version=0.0.4", Exposition(Samples(r)))
+}
+
+// Exposition formats samples in the Prometheus text exposition format.
+func Exposition(samples []Sample) []byte {
+	b := new(bytes.Buffer)
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		if !seen[sample.Name] {
+			seen[sample.Name] = true
+			fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", sample.Name, sample.Help, sample.Name)
+		}
+		fmt.Fprintf(b, "%s%s %g\n", sample.Name, promLabels(sample.Labels), sample.Value)
+	}
+	return b.Bytes()
+}
+
+func promLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(labels))
+	for _, key := range sortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escape.Replace(labels[key])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// InfluxDB exports metrics to an InfluxDB (1.x) database.
+type InfluxDB struct {
+	URL      string
+	Database string
+}
+
+// Export writes the metrics of a test run, timestamped with the end of the
+// run.
+func (i InfluxDB) Export(r *report.Report) error {
+	target := fmt.Sprintf("%s/write?db=%s", strings.TrimSuffix(i.URL, "/"), url.QueryEscape(i.Database))
+	return send(http.MethodPost, target, "text/plain", LineProtocol(Samples(r), r.Finished))
+}
+
+// LineProtocol formats samples in the InfluxDB line protocol, with a
+// measurement per metric and its labels as tags.
+func LineProtocol(samples []Sample, timestamp time.Time) []byte {
+	escape := strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+	b := new(bytes.Buffer)
+	for _, sample := range samples {
+		b.WriteString(sample.Name)
+		for _, key := range sortedKeys(sample.Labels) {
+			if sample.Labels[key] == "" {
+				continue
+			}
+			fmt.Fprintf(b, ",%s=%s", escape.Replace(key), escape.Replace(sample.Labels[key]))
+		}
+		fmt.Fprintf(b, " value=%g %d\n", sample.Value, timestamp.UnixNano())
+	}
+	return b.Bytes()
+}
+
+func sortedKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func send(method, target, contentType string, body []byte) error {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not export metrics to %s: %w", target, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("could not export metrics to %s: %s: %s", target, resp.Status, msg)
+	}
+	return nil
+}
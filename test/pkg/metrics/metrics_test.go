@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+func testReport() *report.Report {
+	started := time.Unix(1600000000, 0)
+	return &report.Report{
+		Started:  started,
+		Finished: started.Add(90 * time.Second),
+		Addons: []report.AddonResult{
+			{Scenario: "kommander-minimal", Group: "kommander", Addon: "kommander", Revision: "1.1.0-1", Architecture: "amd64", Status: report.Passed},
+			{Scenario: "kommander-full-ha", Group: "kommander", Addon: "kommander", Revision: "1.1.0-1", Architecture: "amd64", Status: report.Failed},
+		},
+		Phases: []report.Phase{
+			{Scenario: "kommander-minimal", Phase: "validating", Started: started, Duration: 5 * time.Second},
+			{Scenario: "kommander-minimal", Phase: "deploying", Started: started.Add(5 * time.Second), Duration: 60 * time.Second},
+			{Scenario: "kommander-minimal", Phase: "validating", Started: started.Add(65 * time.Second), Duration: 10 * time.Second},
+		},
+		Flakes: map[string]int{"kommander-minimal/kommander-ui": 2},
+		Scale: []report.ScaleResult{
+			{Scenario: "kommander-scale", Kind: "project", Count: 90, P50: 2 * time.Second, P95: 8 * time.Second, Max: 12 * time.Second},
+		},
+	}
+}
+
+func TestExposition(t *testing.T) {
+	out := string(Exposition(Samples(testReport())))
+
+	for _, expected := range []string{
+		"kommander_addons_test_run_duration_seconds 90\n",
+		`kommander_addons_test_phase_duration_seconds{phase="deploying",scenario="kommander-minimal"} 60` + "\n",
+		`kommander_addons_test_phase_duration_seconds{phase="validating",scenario="kommander-minimal"} 15` + "\n",
+		`kommander_addons_test_addon_status{addon="kommander",architecture="amd64",group="kommander",revision="1.1.0-1",scenario="kommander-minimal",status="passed"} 1` + "\n",
+		`kommander_addons_test_addon_status{addon="kommander",architecture="amd64",group="kommander",revision="1.1.0-1",scenario="kommander-minimal",status="failed"} 0` + "\n",
+		`kommander_addons_test_addon_status{addon="kommander",architecture="amd64",group="kommander",revision="1.1.0-1",scenario="kommander-full-ha",status="failed"} 1` + "\n",
+		`kommander_addons_test_check_flakes{check="kommander-minimal/kommander-ui"} 2` + "\n",
+		`kommander_addons_test_scale_latency_seconds{kind="project",quantile="0.95",scenario="kommander-scale"} 8` + "\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected exposition to contain %q, got:\n%s", expected, out)
+		}
+	}
+	if n := strings.Count(out, "# TYPE kommander_addons_test_addon_status gauge"); n != 1 {
+		t.Errorf("expected a single TYPE line per metric, got %d", n)
+	}
+}
+
+func TestExpositionSeries(t *testing.T) {
+	r := testReport()
+	r.Addons = append(r.Addons, report.AddonResult{Scenario: "kommander-minimal", Group: "kommander", Addon: "kommander", Revision: "1.1.0-1", Architecture: "amd64", Status: report.Failed})
+
+	out := string(Exposition(Samples(r)))
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		series := line[:strings.LastIndex(line, " ")]
+		if seen[series] {
+			t.Errorf("duplicate series %s", series)
+		}
+		seen[series] = true
+	}
+	if expected := `kommander_addons_test_addon_status{addon="kommander",architecture="amd64",group="kommander",revision="1.1.0-1",scenario="kommander-minimal",status="failed"} 1`; !strings.Contains(out, expected+"\n") {
+		t.Errorf("expected the status recorded last %q, got:\n%s", expected, out)
+	}
+}
+
+func TestExpositionEscaping(t *testing.T) {
+	out := string(Exposition([]Sample{{
+		Name:   "kommander_addons_test_check_flakes",
+		Labels: map[string]string{"check": `kommander-minimal/"ui" \ready` + "\n"},
+		Value:  1,
+	}}))
+	expected := `kommander_addons_test_check_flakes{check="kommander-minimal/\"ui\" \\ready\n"} 1` + "\n"
+	if !strings.HasSuffix(out, expected) {
+		t.Errorf("expected exposition to end with %q, got:\n%s", expected, out)
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	samples := []Sample{{
+		Name:   "kommander_addons_test_phase_duration_seconds",
+		Labels: map[string]string{"scenario": "kommander-minimal", "phase": "deploying wave 1/2"},
+		Value:  60,
+	}}
+
+	out := string(LineProtocol(samples, time.Unix(1, 0)))
+	expected := `kommander_addons_test_phase_duration_seconds,phase=deploying\ wave\ 1/2,scenario=kommander-minimal value=60 1000000000` + "\n"
+	if out != expected {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+}
+
+func TestPushgateway(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+	}))
+	defer server.Close()
+
+	if err := (Pushgateway{URL: server.URL, Job: "kommander-addons"}).Export(testReport()); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPut || path != "/metrics/job/kommander-addons" {
+		t.Errorf("expected PUT /metrics/job/kommander-addons, got %s %s", method, path)
+	}
+	if !strings.Contains(body, "kommander_addons_test_run_duration_seconds 90") {
+		t.Errorf("expected the run duration to be pushed, got:\n%s", body)
+	}
+}
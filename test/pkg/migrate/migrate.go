@@ -0,0 +1,206 @@
+package migrate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// Migration migrates the values of an addon from the chart version of a
+// previous revision to the chart version of a revision which renamed or
+// restructured values keys, so that customer overrides keep working.
+type Migration struct {
+	// Addon is the name of the addon, taken from its directory.
+	Addon string `yaml:"-"`
+
+	// Revision is the addon revision migrated to, taken from the file name.
+	Revision string `yaml:"-"`
+
+	// Chart are the chart versions the values are migrated between.
+	Chart Versions `yaml:"chart"`
+
+	// Rules are applied to the values in order.
+	Rules []Rule `yaml:"rules"`
+
+	// Overrides are representative customer overrides of the previous
+	// revision, by name, which must be accepted by the previous revision and,
+	// once migrated, by the new revision.
+	Overrides map[string]string `yaml:"overrides"`
+}
+
+// Versions are the chart versions of the previous and the new revision.
+type Versions struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// Rule is a single change to the values. Exactly one of its fields is set.
+// Paths are dot separated keys, e.g. ingress.extraAnnotations.
+type Rule struct {
+	// Move moves the value at a path to another path.
+	Move *Move `yaml:"move,omitempty"`
+
+	// Delete removes the value at a path, for keys the new revision dropped.
+	Delete string `yaml:"delete,omitempty"`
+}
+
+// Move moves a value From a path To another path.
+type Move struct {
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+}
+
+// String describes the rule.
+func (r Rule) String() string {
+	if r.Move != nil {
+		return fmt.Sprintf("move %s to %s", r.Move.From, r.Move.To)
+	}
+	return fmt.Sprintf("delete %s", r.Delete)
+}
+
+func (r Rule) validate() error {
+	switch {
+	case r.Move != nil && r.Delete != "":
+		return fmt.Errorf("expected exactly one of move or delete, got both")
+	case r.Move != nil:
+		if r.Move.From == "" || r.Move.To == "" {
+			return fmt.Errorf("move requires a from and a to path")
+		}
+		if r.Move.From == r.Move.To || strings.HasPrefix(r.Move.To, r.Move.From+".") {
+			return fmt.Errorf("can not move %s into itself", r.Move.From)
+		}
+	case r.Delete == "":
+		return fmt.Errorf("expected exactly one of move or delete, got none")
+	}
+	return nil
+}
+
+// Load reads the migrations in a directory, where each <addon>/<revision>.yaml
+// file contains the migration to that revision of the addon. Migrations are
+// returned sorted by addon and revision.
+func Load(dir string) ([]Migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := Migration{
+			Addon:    filepath.Base(filepath.Dir(file)),
+			Revision: strings.TrimSuffix(filepath.Base(file), ".yaml"),
+		}
+		if err := yaml.UnmarshalStrict(b, &m); err != nil {
+			return nil, fmt.Errorf("could not parse migration %s: %w", file, err)
+		}
+		if m.Chart.From == "" || m.Chart.To == "" {
+			return nil, fmt.Errorf("migration %s requires the chart versions it migrates from and to", file)
+		}
+		if len(m.Overrides) == 0 {
+			return nil, fmt.Errorf("migration %s has no overrides to migrate", file)
+		}
+		for i, rule := range m.Rules {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("migration %s: rule %d: %w", file, i+1, err)
+			}
+		}
+		migrations = append(migrations, m)
+	}
+
+	return migrations, nil
+}
+
+// Apply applies the rules of the migration to a YAML values block and returns
+// the migrated values block. Rules whose path is not set are skipped.
+func (m Migration) Apply(valuesYAML string) (string, error) {
+	parsed, err := values.Parse(valuesYAML)
+	if err != nil {
+		return "", err
+	}
+
+	for _, rule := range m.Rules {
+		if err := apply(parsed, rule); err != nil {
+			return "", fmt.Errorf("could not %s: %w", rule, err)
+		}
+	}
+
+	b, err := yaml.Marshal(parsed)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func apply(v map[string]interface{}, rule Rule) error {
+	if rule.Move == nil {
+		remove(v, split(rule.Delete))
+		return nil
+	}
+
+	value, ok := remove(v, split(rule.Move.From))
+	if !ok {
+		return nil
+	}
+	return set(v, split(rule.Move.To), value)
+}
+
+func split(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// remove deletes the value at a path and returns it. Maps which are left empty
+// are removed as well.
+func remove(v map[string]interface{}, path []string) (interface{}, bool) {
+	if len(path) == 1 {
+		value, ok := v[path[0]]
+		delete(v, path[0])
+		return value, ok
+	}
+
+	child, ok := v[path[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := remove(child, path[1:])
+	if ok && len(child) == 0 {
+		delete(v, path[0])
+	}
+	return value, ok
+}
+
+// set sets the value at a path, creating the maps along it. Setting a path
+// which is already set (e.g. when an override sets both the old and the new
+// key) is an error, as is setting a path below a value which is not a map.
+func set(v map[string]interface{}, path []string, value interface{}) error {
+	for i, key := range path[:len(path)-1] {
+		existing, ok := v[key]
+		if !ok {
+			existing = make(map[string]interface{})
+			v[key] = existing
+		}
+		child, ok := existing.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not a map", strings.Join(path[:i+1], "."))
+		}
+		v = child
+	}
+
+	key := path[len(path)-1]
+	if _, ok := v[key]; ok {
+		return fmt.Errorf("%s is already set", strings.Join(path, "."))
+	}
+	v[key] = value
+	return nil
+}
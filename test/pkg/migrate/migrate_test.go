@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+func TestApply(t *testing.T) {
+	m := Migration{Rules: []Rule{
+		{Move: &Move{From: "ingress.annotations", To: "ingress.extraAnnotations"}},
+		{Move: &Move{From: "image", To: "catalog.image"}},
+		{Delete: "legacy.enabled"},
+		{Move: &Move{From: "unset", To: "elsewhere"}},
+	}}
+
+	migrated, err := m.Apply(`
+ingress:
+  annotations:
+    priority: "2"
+image:
+  tag: v1
+legacy:
+  enabled: true
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := values.Parse(migrated)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"ingress": map[string]interface{}{"extraAnnotations": map[string]interface{}{"priority": "2"}},
+		"catalog": map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}},
+	}
+	if !reflect.DeepEqual(parsed, expected) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+}
+
+func TestApplyConflicts(t *testing.T) {
+	m := Migration{Rules: []Rule{{Move: &Move{From: "old", To: "new"}}}}
+	if _, err := m.Apply("old: 1\nnew: 2\n"); err == nil || !strings.Contains(err.Error(), "new is already set") {
+		t.Errorf("expected an error for a path which is already set, got %v", err)
+	}
+
+	m = Migration{Rules: []Rule{{Move: &Move{From: "old", To: "new.key"}}}}
+	if _, err := m.Apply("old: 1\nnew: 2\n"); err == nil || !strings.Contains(err.Error(), "new is not a map") {
+		t.Errorf("expected an error for a path below a value, got %v", err)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	for _, rule := range []Rule{
+		{},
+		{Move: &Move{From: "a"}},
+		{Move: &Move{From: "a", To: "a.b"}},
+		{Move: &Move{From: "a", To: "b"}, Delete: "c"},
+	} {
+		if err := rule.validate(); err == nil {
+			t.Errorf("expected rule %+v to be invalid", rule)
+		}
+	}
+	if err := (Rule{Move: &Move{From: "a", To: "ab"}}).validate(); err != nil {
+		t.Error(err)
+	}
+}
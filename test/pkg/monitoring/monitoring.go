@@ -0,0 +1,271 @@
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// Inventory are the scrape targets and alert rule groups prometheus is
+// expected to have once addons are deployed (see monitoring.yaml).
+type Inventory struct {
+	// Addon is the addon deploying prometheus, without which there is
+	// nothing to verify.
+	Addon string `yaml:"addon"`
+
+	// Prometheus is the prometheus service whose runtime configuration is
+	// verified, as <namespace>/<service> like the prometheus of checks.
+	Prometheus string `yaml:"prometheus"`
+
+	// Ignored are the jobs whose targets aren't verified, e.g. of control
+	// plane components which kind binds to localhost.
+	Ignored []string `yaml:"ignored"`
+
+	// Addons are the expected jobs and rule groups of each addon.
+	Addons map[string]Expected `yaml:"addons"`
+}
+
+// Expected are the jobs and rule groups an addon configures prometheus with,
+// e.g. through its ServiceMonitors and PrometheusRules.
+type Expected struct {
+	Jobs       []string `yaml:"jobs"`
+	RuleGroups []string `yaml:"ruleGroups"`
+}
+
+// Load reads an inventory from a YAML file.
+func Load(path string) (*Inventory, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	i := &Inventory{}
+	if err := yaml.UnmarshalStrict(b, i); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	if _, _, err := i.service(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return i, nil
+}
+
+func (i *Inventory) service() (string, string, error) {
+	ref := strings.SplitN(i.Prometheus, "/", 2)
+	if len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+		return "", "", fmt.Errorf("prometheus %q must be of the form <namespace>/<service>", i.Prometheus)
+	}
+	return ref[0], ref[1], nil
+}
+
+// State is the runtime configuration of prometheus: its active targets and
+// its rule groups.
+type State struct {
+	Targets []Target
+	Groups  []Group
+}
+
+// Target is an active scrape target.
+type Target struct {
+	Job       string
+	Instance  string
+	Health    string
+	LastError string
+}
+
+// Group is a loaded rule group.
+type Group struct {
+	Name  string
+	Rules []Rule
+}
+
+// Rule is a rule of a group.
+type Rule struct {
+	Name      string
+	Health    string
+	LastError string
+}
+
+// Fetch returns the runtime configuration of the prometheus of the inventory
+// in the cluster of a kubectl client, through the API server proxy.
+func (i *Inventory) Fetch(k kubectl.Client) (*State, error) {
+	namespace, service, err := i.service()
+	if err != nil {
+		return nil, err
+	}
+	get := func(path string, data interface{}) error {
+		out, err := k.Output("get", "--raw", fmt.Sprintf("/api/v1/namespaces/%s/services/%s/proxy%s", namespace, service, path))
+		if err != nil {
+			return err
+		}
+		response := struct {
+			Status string          `json:"status"`
+			Error  string          `json:"error"`
+			Data   json.RawMessage `json:"data"`
+		}{}
+		if err := json.Unmarshal(out, &response); err != nil {
+			return fmt.Errorf("could not decode the response of prometheus to %s: %w", path, err)
+		}
+		if response.Status != "success" {
+			return fmt.Errorf("prometheus failed to serve %s: %s", path, response.Error)
+		}
+		return json.Unmarshal(response.Data, data)
+	}
+
+	targets := struct {
+		ActiveTargets []struct {
+			Labels    map[string]string `json:"labels"`
+			Health    string            `json:"health"`
+			LastError string            `json:"lastError"`
+		} `json:"activeTargets"`
+	}{}
+	if err := get("/api/v1/targets?state=active", &targets); err != nil {
+		return nil, err
+	}
+	rules := struct {
+		Groups []struct {
+			Name  string `json:"name"`
+			Rules []Rule `json:"rules"`
+		} `json:"groups"`
+	}{}
+	if err := get("/api/v1/rules", &rules); err != nil {
+		return nil, err
+	}
+
+	s := &State{}
+	for _, t := range targets.ActiveTargets {
+		s.Targets = append(s.Targets, Target{Job: t.Labels["job"], Instance: t.Labels["instance"], Health: t.Health, LastError: t.LastError})
+	}
+	for _, g := range rules.Groups {
+		s.Groups = append(s.Groups, Group{Name: g.Name, Rules: g.Rules})
+	}
+	return s, nil
+}
+
+// Problem is a difference between the inventory and the runtime
+// configuration of prometheus.
+type Problem struct {
+	// Addon is the addon expecting the job or rule group, empty for targets
+	// of jobs which aren't in the inventory.
+	Addon  string
+	Reason string
+}
+
+func (p Problem) String() string {
+	if p.Addon == "" {
+		return p.Reason
+	}
+	return fmt.Sprintf("addon %s: %s", p.Addon, p.Reason)
+}
+
+// Problems returns the differences between the inventory of deployed addons
+// and the runtime configuration of prometheus: expected jobs without targets
+// (e.g. of ServiceMonitors which select no service or aren't selected by
+// prometheus), targets which aren't up, expected rule groups which aren't
+// loaded and rules which fail to evaluate. Addons which aren't deployed
+// aren't expected to be configured.
+func (i *Inventory) Problems(addons []string, s *State) []Problem {
+	owners := make(map[string]string)
+	groups := make(map[string]string)
+	for _, addon := range addons {
+		for _, job := range i.Addons[addon].Jobs {
+			owners[job] = addon
+		}
+		for _, group := range i.Addons[addon].RuleGroups {
+			groups[group] = addon
+		}
+	}
+	ignored := make(map[string]bool, len(i.Ignored))
+	for _, job := range i.Ignored {
+		ignored[job] = true
+	}
+
+	var problems []Problem
+	scraped := make(map[string]bool)
+	for _, t := range s.Targets {
+		scraped[t.Job] = true
+		if ignored[t.Job] || t.Health == "up" {
+			continue
+		}
+		reason := fmt.Sprintf("target %s of job %s is %s", t.Instance, t.Job, t.Health)
+		if t.LastError != "" {
+			reason += ": " + t.LastError
+		}
+		problems = append(problems, Problem{Addon: owners[t.Job], Reason: reason})
+	}
+	for job, addon := range owners {
+		if !scraped[job] {
+			problems = append(problems, Problem{Addon: addon, Reason: fmt.Sprintf("job %s has no targets", job)})
+		}
+	}
+
+	loaded := make(map[string]bool)
+	for _, g := range s.Groups {
+		loaded[g.Name] = true
+		for _, r := range g.Rules {
+			if r.Health == "err" {
+				problems = append(problems, Problem{Addon: groups[g.Name], Reason: fmt.Sprintf("rule %s of group %s fails: %s", r.Name, g.Name, r.LastError)})
+			}
+		}
+	}
+	for group, addon := range groups {
+		if !loaded[group] {
+			problems = append(problems, Problem{Addon: addon, Reason: fmt.Sprintf("rule group %s is not loaded", group)})
+		}
+	}
+
+	sort.Slice(problems, func(a, b int) bool {
+		if problems[a].Addon != problems[b].Addon {
+			return problems[a].Addon < problems[b].Addon
+		}
+		return problems[a].Reason < problems[b].Reason
+	})
+	return problems
+}
+
+// Unlisted returns the jobs with targets which are neither in the inventory
+// nor ignored, sorted, so that they can be added to it.
+func (i *Inventory) Unlisted(s *State) []string {
+	listed := make(map[string]bool)
+	for _, job := range i.Ignored {
+		listed[job] = true
+	}
+	for _, expected := range i.Addons {
+		for _, job := range expected.Jobs {
+			listed[job] = true
+		}
+	}
+	var unlisted []string
+	for _, t := range s.Targets {
+		if !listed[t.Job] {
+			listed[t.Job] = true
+			unlisted = append(unlisted, t.Job)
+		}
+	}
+	sort.Strings(unlisted)
+	return unlisted
+}
+
+// Verify verifies the runtime configuration of prometheus against the
+// inventory of deployed addons (see Problems), retrying until there are no
+// problems or the timeout expires, since targets are unknown until they were
+// scraped and rules until they were evaluated. It returns the last state.
+func (i *Inventory) Verify(k kubectl.Client, addons []string, timeout, interval time.Duration) (*State, []Problem, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		s, err := i.Fetch(k)
+		if err != nil {
+			return nil, nil, err
+		}
+		problems := i.Problems(addons, s)
+		if len(problems) == 0 || time.Now().Add(interval).After(deadline) {
+			return s, problems, nil
+		}
+		time.Sleep(interval)
+	}
+}
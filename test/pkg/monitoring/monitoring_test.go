@@ -0,0 +1,49 @@
+package monitoring
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProblems(t *testing.T) {
+	i := &Inventory{
+		Ignored: []string{"kube-scheduler"},
+		Addons: map[string]Expected{
+			"prometheus": {Jobs: []string{"apiserver", "node-exporter"}, RuleGroups: []string{"k8s.rules", "node.rules"}},
+			"kommander":  {Jobs: []string{"kommander-kubeaddons-thanos-query"}, RuleGroups: []string{"thanos.rules"}},
+		},
+	}
+	s := &State{
+		Targets: []Target{
+			{Job: "apiserver", Instance: "172.17.0.2:6443", Health: "up"},
+			{Job: "node-exporter", Instance: "172.17.0.2:9100", Health: "down", LastError: "connection refused"},
+			{Job: "kube-scheduler", Instance: "172.17.0.2:10251", Health: "down"},
+			{Job: "grafana", Instance: "10.244.0.9:3000", Health: "unknown"},
+		},
+		Groups: []Group{
+			{Name: "k8s.rules", Rules: []Rule{{Name: "namespace:container_cpu_usage_seconds_total:sum_rate", Health: "ok"}}},
+			{Name: "node.rules", Rules: []Rule{{Name: "node:node_num_cpu:sum", Health: "err", LastError: "many-to-many matching not allowed"}}},
+		},
+	}
+
+	expected := []Problem{
+		{Reason: "target 10.244.0.9:3000 of job grafana is unknown"},
+		{Addon: "prometheus", Reason: "rule node:node_num_cpu:sum of group node.rules fails: many-to-many matching not allowed"},
+		{Addon: "prometheus", Reason: "target 172.17.0.2:9100 of job node-exporter is down: connection refused"},
+	}
+	if actual := i.Problems([]string{"prometheus"}, s); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected problems %v, got %v", expected, actual)
+	}
+
+	expected = append(expected[:1], append([]Problem{
+		{Addon: "kommander", Reason: "job kommander-kubeaddons-thanos-query has no targets"},
+		{Addon: "kommander", Reason: "rule group thanos.rules is not loaded"},
+	}, expected[1:]...)...)
+	if actual := i.Problems([]string{"kommander", "prometheus"}, s); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected problems %v, got %v", expected, actual)
+	}
+
+	if unlisted := i.Unlisted(s); !reflect.DeepEqual([]string{"grafana"}, unlisted) {
+		t.Errorf("expected grafana to be unlisted, got %v", unlisted)
+	}
+}
@@ -0,0 +1,152 @@
+package ordering
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// Permutation returns the addons in a random order in which every addon comes
+// after the addons it requires explicitly (requires maps addons to the addons
+// they require), so that only implicit dependencies are exercised.
+func Permutation(addons []string, requires map[string][]string, r *rand.Rand) []string {
+	remaining := append([]string{}, addons...)
+	r.Shuffle(len(remaining), func(i, j int) { remaining[i], remaining[j] = remaining[j], remaining[i] })
+
+	included := make(map[string]bool, len(addons))
+	for _, addon := range addons {
+		included[addon] = true
+	}
+	deployed := make(map[string]bool, len(addons))
+	order := make([]string, 0, len(addons))
+	for len(remaining) > 0 {
+		next := -1
+		for i, addon := range remaining {
+			ready := true
+			for _, required := range requires[addon] {
+				if included[required] && !deployed[required] {
+					ready = false
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		// a cycle of requirements is deployed in the shuffled order
+		if next < 0 {
+			next = 0
+		}
+		deployed[remaining[next]] = true
+		order = append(order, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return order
+}
+
+// Trial is a deployment of addons in an order, along with the addons which
+// failed (mapped to their error).
+type Trial struct {
+	Order  []string          `json:"order"`
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Dependency is an implicit dependency of an addon on another addon, inferred
+// from trials: the addon failed whenever it was deployed before the other
+// addon, and succeeded whenever it was deployed after it.
+type Dependency struct {
+	Addon     string `json:"addon"`
+	On        string `json:"on"`
+	Failures  int    `json:"failures"`
+	Successes int    `json:"successes"`
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s depends on %s (failed in %d trials deployed before it, passed in %d trials deployed after it)", d.Addon, d.On, d.Failures, d.Successes)
+}
+
+// Report is the analysis of the trials of a scenario.
+type Report struct {
+	Trials       []Trial      `json:"trials"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+
+	// Unexplained are addons which failed in some trials, but not in a way
+	// the order explains (e.g. flaky addons), or in every trial.
+	Unexplained []string `json:"unexplained,omitempty"`
+}
+
+// Analyze infers the implicit dependencies of the addons from the trials.
+// An addon which failed in some trials depends on the addons which came after
+// it in every trial it failed in and before it in every trial it passed in.
+func Analyze(trials []Trial) Report {
+	report := Report{Trials: trials}
+
+	failing := make(map[string]bool)
+	for _, trial := range trials {
+		for addon := range trial.Failed {
+			failing[addon] = true
+		}
+	}
+	names := make([]string, 0, len(failing))
+	for addon := range failing {
+		names = append(names, addon)
+	}
+	sort.Strings(names)
+
+	for _, addon := range names {
+		var candidates map[string]bool
+		failures, successes := 0, 0
+		for _, trial := range trials {
+			position := index(trial.Order, addon)
+			if position < 0 {
+				continue
+			}
+			_, failed := trial.Failed[addon]
+			if failed {
+				failures++
+			} else {
+				successes++
+			}
+
+			// the addons after the addon in failed trials, before it otherwise
+			consistent := make(map[string]bool)
+			for i, other := range trial.Order {
+				if other != addon && (i > position) == failed {
+					consistent[other] = true
+				}
+			}
+			if candidates == nil {
+				candidates = consistent
+				continue
+			}
+			for other := range candidates {
+				if !consistent[other] {
+					delete(candidates, other)
+				}
+			}
+		}
+
+		if successes == 0 || len(candidates) == 0 {
+			report.Unexplained = append(report.Unexplained, addon)
+			continue
+		}
+		var on []string
+		for other := range candidates {
+			on = append(on, other)
+		}
+		sort.Strings(on)
+		for _, other := range on {
+			report.Dependencies = append(report.Dependencies, Dependency{Addon: addon, On: other, Failures: failures, Successes: successes})
+		}
+	}
+	return report
+}
+
+func index(order []string, addon string) int {
+	for i, name := range order {
+		if name == addon {
+			return i
+		}
+	}
+	return -1
+}
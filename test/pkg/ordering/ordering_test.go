@@ -0,0 +1,50 @@
+package ordering
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestPermutation(t *testing.T) {
+	addons := []string{"cert-manager", "traefik", "dex", "kommander", "reloader"}
+	requires := map[string][]string{
+		"kommander": {"cert-manager", "dex"},
+		"dex":       {"cert-manager"},
+		"traefik":   {"prometheus"},
+	}
+
+	r := rand.New(rand.NewSource(42))
+	orders := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		order := Permutation(addons, requires, r)
+		if len(order) != len(addons) {
+			t.Fatalf("expected all addons in the order, got %v", order)
+		}
+		if index(order, "cert-manager") > index(order, "dex") || index(order, "dex") > index(order, "kommander") {
+			t.Errorf("expected the requirements to be deployed first, got %v", order)
+		}
+		orders[order[0]+order[len(order)-1]] = true
+	}
+	if len(orders) < 2 {
+		t.Errorf("expected random orders, got %v", orders)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	trials := []Trial{
+		{Order: []string{"kommander", "traefik", "dex", "velero"}, Failed: map[string]string{"kommander": "not ready", "velero": "not ready"}},
+		{Order: []string{"dex", "traefik", "kommander", "velero"}, Failed: map[string]string{"velero": "not ready"}},
+		{Order: []string{"traefik", "kommander", "dex", "velero"}},
+		{Order: []string{"velero", "dex", "kommander", "traefik"}, Failed: map[string]string{"kommander": "not ready"}},
+	}
+
+	expected := Report{
+		Trials:       trials,
+		Dependencies: []Dependency{{Addon: "kommander", On: "traefik", Failures: 2, Successes: 2}},
+		Unexplained:  []string{"velero"},
+	}
+	if actual := Analyze(trials); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
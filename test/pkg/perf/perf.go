@@ -0,0 +1,97 @@
+package perf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Threshold is the most a benchmark may take per operation before it is
+// considered a regression.
+type Threshold struct {
+	// MaxDuration is the maximum duration of an operation, e.g. 500ms.
+	MaxDuration time.Duration
+
+	// MaxAllocs is the maximum number of allocations of an operation, not
+	// checked when zero.
+	MaxAllocs int64
+}
+
+// Thresholds are the thresholds of benchmarks by name (see perf.yaml).
+type Thresholds map[string]Threshold
+
+// Load reads the thresholds in a file. Every benchmark must have a
+// maxDuration.
+func Load(path string) (Thresholds, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Benchmarks map[string]struct {
+			MaxDuration string `yaml:"maxDuration"`
+			MaxAllocs   int64  `yaml:"maxAllocs"`
+		} `yaml:"benchmarks"`
+	}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse thresholds %s: %w", path, err)
+	}
+
+	thresholds := make(Thresholds, len(file.Benchmarks))
+	for name, benchmark := range file.Benchmarks {
+		if benchmark.MaxDuration == "" {
+			return nil, fmt.Errorf("benchmark %s of %s has no maxDuration", name, path)
+		}
+		d, err := time.ParseDuration(benchmark.MaxDuration)
+		if err != nil {
+			return nil, fmt.Errorf("benchmark %s of %s has an invalid maxDuration: %w", name, path, err)
+		}
+		if d <= 0 || benchmark.MaxAllocs < 0 {
+			return nil, fmt.Errorf("benchmark %s of %s has a negative threshold", name, path)
+		}
+		thresholds[name] = Threshold{MaxDuration: d, MaxAllocs: benchmark.MaxAllocs}
+	}
+	return thresholds, nil
+}
+
+// Result is the result of a benchmark per operation.
+type Result struct {
+	Name        string
+	NsPerOp     int64
+	AllocsPerOp int64
+}
+
+// Regression is a benchmark which exceeded its threshold.
+type Regression struct {
+	Benchmark string
+	Reason    string
+}
+
+func (r Regression) String() string {
+	return r.Benchmark + " " + r.Reason
+}
+
+// Regressions returns the results which exceed their thresholds, along with
+// the results which have none, sorted by benchmark.
+func (t Thresholds) Regressions(results []Result) []Regression {
+	var regressions []Regression
+	for _, r := range results {
+		threshold, ok := t[r.Name]
+		if !ok {
+			regressions = append(regressions, Regression{Benchmark: r.Name, Reason: "has no threshold"})
+			continue
+		}
+		if d := time.Duration(r.NsPerOp); d > threshold.MaxDuration {
+			regressions = append(regressions, Regression{Benchmark: r.Name, Reason: fmt.Sprintf("took %s per operation, more than %s", d, threshold.MaxDuration)})
+		}
+		if threshold.MaxAllocs > 0 && r.AllocsPerOp > threshold.MaxAllocs {
+			regressions = append(regressions, Regression{Benchmark: r.Name, Reason: fmt.Sprintf("made %d allocations per operation, more than %d", r.AllocsPerOp, threshold.MaxAllocs)})
+		}
+	}
+	sort.SliceStable(regressions, func(i, j int) bool { return regressions[i].Benchmark < regressions[j].Benchmark })
+	return regressions
+}
@@ -0,0 +1,66 @@
+package perf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "perf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "perf.yaml")
+	write := func(content string) {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("benchmarks:\n  BenchmarkListAddons:\n    maxDuration: 500ms\n    maxAllocs: 1000\n")
+	thresholds, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (Thresholds{"BenchmarkListAddons": {MaxDuration: 500 * time.Millisecond, MaxAllocs: 1000}}); !reflect.DeepEqual(expected, thresholds) {
+		t.Errorf("expected %v, got %v", expected, thresholds)
+	}
+
+	for _, invalid := range []string{
+		"benchmarks:\n  BenchmarkListAddons:\n    maxAllocs: 1000\n",
+		"benchmarks:\n  BenchmarkListAddons:\n    maxDuration: fast\n",
+		"benchmarks:\n  BenchmarkListAddons:\n    maxDuration: -1s\n",
+		"benchmarks:\n  BenchmarkListAddons:\n    maxDuration: 1s\n    minDuration: 1ms\n",
+	} {
+		write(invalid)
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	thresholds := Thresholds{
+		"BenchmarkCatalogConstruction": {MaxDuration: time.Second},
+		"BenchmarkListAddons":          {MaxDuration: 100 * time.Millisecond, MaxAllocs: 1000},
+	}
+	regressions := thresholds.Regressions([]Result{
+		{Name: "BenchmarkListAddons", NsPerOp: int64(200 * time.Millisecond), AllocsPerOp: 2000},
+		{Name: "BenchmarkCatalogConstruction", NsPerOp: int64(900 * time.Millisecond), AllocsPerOp: 1000000},
+		{Name: "BenchmarkGroupsLoad", NsPerOp: 1},
+	})
+	expected := []Regression{
+		{Benchmark: "BenchmarkGroupsLoad", Reason: "has no threshold"},
+		{Benchmark: "BenchmarkListAddons", Reason: "took 200ms per operation, more than 100ms"},
+		{Benchmark: "BenchmarkListAddons", Reason: "made 2000 allocations per operation, more than 1000"},
+	}
+	if !reflect.DeepEqual(expected, regressions) {
+		t.Errorf("expected %v, got %v", expected, regressions)
+	}
+}
@@ -0,0 +1,308 @@
+package podsecurity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Level is a level of the Pod Security Standards.
+type Level string
+
+const (
+	// Privileged is unrestricted.
+	Privileged Level = "privileged"
+
+	// Baseline prevents known privilege escalations.
+	Baseline Level = "baseline"
+
+	// Restricted follows pod hardening best practices.
+	Restricted Level = "restricted"
+)
+
+// EnforceLabel is the namespace label of pod security admission which enforces
+// a level.
+const EnforceLabel = "pod-security.kubernetes.io/enforce"
+
+// Namespace is the pod security a namespace declares.
+type Namespace struct {
+	// Level is the level the pods in the namespace must pass.
+	Level Level `yaml:"level"`
+
+	// Labels are further labels the namespace must carry, e.g. the
+	// istio-injection policy.
+	Labels map[string]string `yaml:"labels"`
+}
+
+// RequiredLabels returns the labels the namespace must carry: those it declares
+// and the label enforcing its level.
+func (n Namespace) RequiredLabels() map[string]string {
+	labels := map[string]string{EnforceLabel: string(n.Level)}
+	for k, v := range n.Labels {
+		labels[k] = v
+	}
+	return labels
+}
+
+// Config is the pod security of the namespaces addons are deployed to.
+type Config struct {
+	// Default is the level of namespaces which are not declared.
+	Default Level `yaml:"default"`
+
+	Namespaces map[string]Namespace `yaml:"namespaces"`
+}
+
+// Load reads the pod security configuration from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse pod security configuration %s: %w", path, err)
+	}
+	if c.Default == "" {
+		c.Default = Baseline
+	}
+	if err := c.Default.validate(); err != nil {
+		return nil, fmt.Errorf("%s: default: %w", path, err)
+	}
+	for name, ns := range c.Namespaces {
+		if ns.Level == "" {
+			ns.Level = c.Default
+			c.Namespaces[name] = ns
+		}
+		if err := ns.Level.validate(); err != nil {
+			return nil, fmt.Errorf("%s: namespace %s: %w", path, name, err)
+		}
+	}
+	return c, nil
+}
+
+func (l Level) validate() error {
+	switch l {
+	case Privileged, Baseline, Restricted:
+		return nil
+	}
+	return fmt.Errorf("unknown pod security level %q, expected %s, %s or %s", l, Privileged, Baseline, Restricted)
+}
+
+// Namespace returns the pod security of a namespace, the default level for
+// namespaces which are not declared.
+func (c *Config) Namespace(name string) Namespace {
+	if ns, ok := c.Namespaces[name]; ok {
+		return ns
+	}
+	return Namespace{Level: c.Default}
+}
+
+// Violation is an object whose pods do not pass the level of its namespace.
+type Violation struct {
+	Addon     string
+	Kind      string
+	Name      string
+	Namespace string
+	Level     Level
+	Reasons   []string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s %s/%s of addon %s does not pass pod security level %s: %s", v.Kind, v.Namespace, v.Name, v.Addon, v.Level, strings.Join(v.Reasons, ", "))
+}
+
+// Check returns the rendered objects whose pods do not pass the level of their
+// namespace.
+func (c *Config) Check(objects []render.Object) []Violation {
+	var violations []Violation
+	for _, obj := range objects {
+		podSpec, ok := obj.PodSpec()
+		if !ok {
+			continue
+		}
+		level := c.Namespace(obj.Namespace).Level
+		if reasons := Violations(podSpec, level); len(reasons) > 0 {
+			violations = append(violations, Violation{
+				Addon:     obj.Addon,
+				Kind:      obj.Kind,
+				Name:      obj.Name,
+				Namespace: obj.Namespace,
+				Level:     level,
+				Reasons:   reasons,
+			})
+		}
+	}
+	return violations
+}
+
+// baselineCapabilities are the capabilities containers may add at the baseline
+// level.
+var baselineCapabilities = map[string]bool{
+	"AUDIT_WRITE": true, "CHOWN": true, "DAC_OVERRIDE": true, "FOWNER": true,
+	"FSETID": true, "KILL": true, "MKNOD": true, "NET_BIND_SERVICE": true,
+	"SETFCAP": true, "SETGID": true, "SETPCAP": true, "SETUID": true, "SYS_CHROOT": true,
+}
+
+// restrictedVolumes are the volume types pods may use at the restricted level.
+var restrictedVolumes = map[string]bool{
+	"configMap": true, "csi": true, "downwardAPI": true, "emptyDir": true,
+	"ephemeral": true, "persistentVolumeClaim": true, "projected": true, "secret": true,
+}
+
+// Violations returns the reasons why a pod spec does not pass a level, using
+// the controls of the Pod Security Standards which can be evaluated from a pod
+// spec alone.
+func Violations(podSpec map[string]interface{}, level Level) []string {
+	if level == Privileged {
+		return nil
+	}
+
+	var reasons []string
+	for _, field := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if podSpec[field] == true {
+			reasons = append(reasons, field+"=true")
+		}
+	}
+
+	podContext, _ := podSpec["securityContext"].(map[string]interface{})
+	volumes, _ := podSpec["volumes"].([]interface{})
+	for _, v := range volumes {
+		volume, _ := v.(map[string]interface{})
+		for volumeType := range volume {
+			switch {
+			case volumeType == "name":
+			case volumeType == "hostPath":
+				reasons = append(reasons, fmt.Sprintf("volume %v uses hostPath", volume["name"]))
+			case level == Restricted && !restrictedVolumes[volumeType]:
+				reasons = append(reasons, fmt.Sprintf("volume %v uses %s", volume["name"], volumeType))
+			}
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		items, _ := podSpec[field].([]interface{})
+		for _, item := range items {
+			container, _ := item.(map[string]interface{})
+			for _, reason := range containerViolations(container, podContext, level) {
+				reasons = append(reasons, fmt.Sprintf("container %v %s", container["name"], reason))
+			}
+		}
+	}
+
+	if level == Restricted && !restrictedSeccomp(podContext) {
+		for _, field := range []string{"initContainers", "containers"} {
+			items, _ := podSpec[field].([]interface{})
+			for _, item := range items {
+				container, _ := item.(map[string]interface{})
+				securityContext, _ := container["securityContext"].(map[string]interface{})
+				if !restrictedSeccomp(securityContext) {
+					reasons = append(reasons, fmt.Sprintf("container %v has no RuntimeDefault or Localhost seccomp profile", container["name"]))
+				}
+			}
+		}
+	}
+
+	sort.Strings(reasons)
+	return reasons
+}
+
+func containerViolations(container, podContext map[string]interface{}, level Level) []string {
+	var reasons []string
+	securityContext, _ := container["securityContext"].(map[string]interface{})
+
+	if securityContext["privileged"] == true {
+		reasons = append(reasons, "is privileged")
+	}
+	ports, _ := container["ports"].([]interface{})
+	for _, p := range ports {
+		if port, _ := p.(map[string]interface{}); port["hostPort"] != nil && fmt.Sprint(port["hostPort"]) != "0" {
+			reasons = append(reasons, fmt.Sprintf("binds host port %v", port["hostPort"]))
+		}
+	}
+
+	capabilities, _ := securityContext["capabilities"].(map[string]interface{})
+	added, _ := capabilities["add"].([]interface{})
+	for _, c := range added {
+		capability := strings.TrimPrefix(fmt.Sprint(c), "CAP_")
+		if (level == Restricted && capability != "NET_BIND_SERVICE") || !baselineCapabilities[capability] {
+			reasons = append(reasons, "adds capability "+capability)
+		}
+	}
+
+	if level != Restricted {
+		return reasons
+	}
+
+	if securityContext["allowPrivilegeEscalation"] != false {
+		reasons = append(reasons, "does not set allowPrivilegeEscalation=false")
+	}
+	dropsAll := false
+	dropped, _ := capabilities["drop"].([]interface{})
+	for _, c := range dropped {
+		if fmt.Sprint(c) == "ALL" {
+			dropsAll = true
+		}
+	}
+	if !dropsAll {
+		reasons = append(reasons, "does not drop ALL capabilities")
+	}
+	nonRoot := podContext["runAsNonRoot"]
+	if v, ok := securityContext["runAsNonRoot"]; ok {
+		nonRoot = v
+	}
+	if nonRoot != true {
+		reasons = append(reasons, "does not set runAsNonRoot=true")
+	}
+	for _, context := range []map[string]interface{}{podContext, securityContext} {
+		if user, ok := context["runAsUser"]; ok && fmt.Sprint(user) == "0" {
+			reasons = append(reasons, "runs as user 0")
+			break
+		}
+	}
+
+	return reasons
+}
+
+func restrictedSeccomp(securityContext map[string]interface{}) bool {
+	profile, _ := securityContext["seccompProfile"].(map[string]interface{})
+	return profile["type"] == "RuntimeDefault" || profile["type"] == "Localhost"
+}
+
+// MissingLabels returns the required labels which are not set to their value
+// in labels, as <key>=<value>.
+func MissingLabels(labels, required map[string]string) []string {
+	var missing []string
+	for k, v := range required {
+		if labels[k] != v {
+			missing = append(missing, k+"="+v)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Labels returns the labels of a namespace in the cluster.
+func Labels(k kubectl.Client, namespace string) (map[string]string, error) {
+	out, err := k.Output("get", "namespace", namespace, "--output", "json")
+	if err != nil {
+		return nil, fmt.Errorf("could not get the labels of namespace %s: %w", namespace, err)
+	}
+
+	var ns struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(out, &ns); err != nil {
+		return nil, fmt.Errorf("could not decode namespace %s: %w", namespace, err)
+	}
+	return ns.Metadata.Labels, nil
+}
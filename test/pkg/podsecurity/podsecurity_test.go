@@ -0,0 +1,95 @@
+package podsecurity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func container(securityContext map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"name": "app", "securityContext": securityContext}
+}
+
+func TestViolations(t *testing.T) {
+	hardened := map[string]interface{}{
+		"securityContext": map[string]interface{}{
+			"runAsNonRoot":   true,
+			"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"},
+		},
+		"volumes": []interface{}{map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app"}}},
+		"containers": []interface{}{container(map[string]interface{}{
+			"allowPrivilegeEscalation": false,
+			"capabilities":             map[string]interface{}{"drop": []interface{}{"ALL"}},
+		})},
+	}
+	if reasons := Violations(hardened, Restricted); len(reasons) != 0 {
+		t.Errorf("expected a hardened pod to pass the restricted level, got %v", reasons)
+	}
+
+	host := map[string]interface{}{
+		"hostNetwork": true,
+		"volumes":     []interface{}{map[string]interface{}{"name": "logs", "hostPath": map[string]interface{}{"path": "/var/log"}}},
+		"containers": []interface{}{map[string]interface{}{
+			"name":            "app",
+			"ports":           []interface{}{map[string]interface{}{"containerPort": 80, "hostPort": 80}},
+			"securityContext": map[string]interface{}{"privileged": true, "capabilities": map[string]interface{}{"add": []interface{}{"NET_ADMIN", "CHOWN"}}},
+		}},
+	}
+	expected := []string{
+		"container app adds capability NET_ADMIN",
+		"container app binds host port 80",
+		"container app is privileged",
+		"hostNetwork=true",
+		"volume logs uses hostPath",
+	}
+	if reasons := Violations(host, Baseline); !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("expected %v, got %v", expected, reasons)
+	}
+	if reasons := Violations(host, Privileged); len(reasons) != 0 {
+		t.Errorf("expected no violations of the privileged level, got %v", reasons)
+	}
+
+	plain := map[string]interface{}{"containers": []interface{}{container(nil)}}
+	if reasons := Violations(plain, Baseline); len(reasons) != 0 {
+		t.Errorf("expected a plain pod to pass the baseline level, got %v", reasons)
+	}
+	expected = []string{
+		"container app does not drop ALL capabilities",
+		"container app does not set allowPrivilegeEscalation=false",
+		"container app does not set runAsNonRoot=true",
+		"container app has no RuntimeDefault or Localhost seccomp profile",
+	}
+	if reasons := Violations(plain, Restricted); !reflect.DeepEqual(reasons, expected) {
+		t.Errorf("expected %v, got %v", expected, reasons)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := &Config{
+		Default:    Baseline,
+		Namespaces: map[string]Namespace{"kube-system": {Level: Privileged}},
+	}
+	deployment := func(namespace string) render.Object {
+		return render.Object{Addon: "agent", Kind: "DaemonSet", Name: "agent", Namespace: namespace, Content: map[string]interface{}{
+			"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"hostPID": true}}},
+		}}
+	}
+
+	violations := c.Check([]render.Object{deployment("kube-system"), deployment("kommander")})
+	if len(violations) != 1 || violations[0].Namespace != "kommander" || violations[0].Level != Baseline {
+		t.Fatalf("expected a violation in namespace kommander only, got %v", violations)
+	}
+	if s := violations[0].String(); s != "DaemonSet kommander/agent of addon agent does not pass pod security level baseline: hostPID=true" {
+		t.Errorf("unexpected violation: %s", s)
+	}
+}
+
+func TestMissingLabels(t *testing.T) {
+	ns := Namespace{Level: Baseline, Labels: map[string]string{"istio-injection": "disabled"}}
+	missing := MissingLabels(map[string]string{EnforceLabel: "privileged"}, ns.RequiredLabels())
+	expected := []string{"istio-injection=disabled", EnforceLabel + "=baseline"}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+}
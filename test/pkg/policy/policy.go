@@ -0,0 +1,216 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	gatekeeperNamespace  = "gatekeeper-system"
+	gatekeeperDeployment = "deployment/gatekeeper-controller-manager"
+	constraintTemplate   = "ConstraintTemplate"
+)
+
+// Policies are the Gatekeeper constraint templates and the constraints which
+// instantiate them, as defined in the YAML files of a directory.
+type Policies struct {
+	Templates   []render.Object
+	Constraints []render.Object
+}
+
+// Load reads the constraint templates and constraints from the YAML files in
+// dir. A missing directory yields no policies.
+func Load(dir string) (*Policies, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Policies{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		objects, err := render.Decode(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse policy file %s: %w", file, err)
+		}
+		for _, obj := range objects {
+			if obj.Kind == constraintTemplate {
+				p.Templates = append(p.Templates, obj)
+			} else {
+				p.Constraints = append(p.Constraints, obj)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Install deploys Gatekeeper from manifest (a file or URL accepted by
+// `kubectl apply --filename`) to the cluster of a kubectl client and
+// creates the constraint templates and constraints of the policies in it.
+func (p *Policies) Install(k kubectl.Client, manifest string, timeout time.Duration) error {
+	if err := k.ApplyFile(manifest); err != nil {
+		return fmt.Errorf("could not deploy gatekeeper: %w", err)
+	}
+	if err := k.Run("rollout", "status", gatekeeperDeployment, "--namespace", gatekeeperNamespace, "--timeout", timeout.String()); err != nil {
+		return fmt.Errorf("gatekeeper did not become ready: %w", err)
+	}
+
+	if err := apply(k, p.Templates); err != nil {
+		return err
+	}
+
+	// the CRDs of the constraint kinds are created by gatekeeper once it has
+	// compiled the templates, applying constraints is retried until they exist.
+	if err := apply(k, p.Constraints); err != nil {
+		return fmt.Errorf("could not create constraints: %w", err)
+	}
+	return nil
+}
+
+func apply(k kubectl.Client, objects []render.Object) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      contents(objects),
+	})
+	if err != nil {
+		return err
+	}
+	return k.Apply(b)
+}
+
+func contents(objects []render.Object) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, obj.Content)
+	}
+	return items
+}
+
+// Violation is a resource which does not comply with a constraint.
+type Violation struct {
+	Constraint string
+	Kind       string
+	Namespace  string
+	Name       string
+	Message    string
+}
+
+func (v Violation) String() string {
+	resource := v.Kind + "/" + v.Name
+	if v.Namespace != "" {
+		resource = v.Namespace + "/" + resource
+	}
+	return fmt.Sprintf("%s violates %s: %s", resource, v.Constraint, v.Message)
+}
+
+type constraintList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			AuditTimestamp  string `json:"auditTimestamp"`
+			TotalViolations int    `json:"totalViolations"`
+			Violations      []struct {
+				Kind      string `json:"kind"`
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+				Message   string `json:"message"`
+			} `json:"violations"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Audit waits up to timeout for gatekeeper to have audited the cluster against
+// every constraint and returns the violations it found. Gatekeeper only records
+// a limited number of violations per constraint, constraints with more
+// violations than recorded are listed as truncated.
+func Audit(k kubectl.Client, timeout time.Duration) ([]Violation, []string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		b, err := k.Output("get", "constraints", "--output", "json")
+		if err != nil {
+			return nil, nil, err
+		}
+		list := constraintList{}
+		if err := json.Unmarshal(b, &list); err != nil {
+			return nil, nil, err
+		}
+
+		audited := true
+		for _, item := range list.Items {
+			if item.Status.AuditTimestamp == "" {
+				audited = false
+			}
+		}
+		if !audited {
+			if time.Now().After(deadline) {
+				return nil, nil, fmt.Errorf("gatekeeper did not audit all constraints within %s", timeout)
+			}
+			time.Sleep(10 * time.Second)
+			continue
+		}
+
+		var violations []Violation
+		var truncated []string
+		for _, item := range list.Items {
+			constraint := strings.ToLower(item.Kind) + "/" + item.Metadata.Name
+			for _, v := range item.Status.Violations {
+				violations = append(violations, Violation{
+					Constraint: constraint,
+					Kind:       v.Kind,
+					Namespace:  v.Namespace,
+					Name:       v.Name,
+					Message:    v.Message,
+				})
+			}
+			if item.Status.TotalViolations > len(item.Status.Violations) {
+				truncated = append(truncated, constraint)
+			}
+		}
+		return violations, truncated, nil
+	}
+}
+
+// Owned returns the violations of resources which belong to the given rendered
+// objects: the objects themselves and everything in the namespaces they are
+// deployed to (e.g. the pods created by their workloads).
+func Owned(violations []Violation, objects []render.Object) []Violation {
+	namespaces := make(map[string]bool)
+	resources := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Namespace != "" {
+			namespaces[obj.Namespace] = true
+		}
+		if obj.Kind == "Namespace" {
+			namespaces[obj.Name] = true
+		}
+		resources[obj.Kind+"/"+obj.Name] = true
+	}
+
+	var owned []Violation
+	for _, v := range violations {
+		if (v.Namespace != "" && namespaces[v.Namespace]) || (v.Namespace == "" && resources[v.Kind+"/"+v.Name]) {
+			owned = append(owned, v)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].String() < owned[j].String() })
+	return owned
+}
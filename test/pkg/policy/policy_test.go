@@ -0,0 +1,32 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestOwned(t *testing.T) {
+	objects := []render.Object{
+		{Addon: "kommander", Kind: "Deployment", Namespace: "kubeaddons", Name: "kommander"},
+		{Addon: "kommander", Kind: "ClusterRole", Name: "kommander"},
+		{Addon: "kommander", Kind: "Namespace", Name: "kommander"},
+	}
+	violations := []Violation{
+		{Constraint: "k8sdisallowedtags/no-latest-tag", Kind: "Pod", Namespace: "kubeaddons", Name: "kommander-5d8f7"},
+		{Constraint: "k8sdisallowedhostpath/no-host-path", Kind: "Pod", Namespace: "kommander", Name: "kubefed-0"},
+		{Constraint: "k8sdisallowedhostpath/no-host-path", Kind: "Pod", Namespace: "kube-system", Name: "kube-proxy-x7k2p"},
+		{Constraint: "k8srequiredlabels/cluster-roles", Kind: "ClusterRole", Name: "kommander"},
+		{Constraint: "k8srequiredlabels/cluster-roles", Kind: "ClusterRole", Name: "admin"},
+	}
+
+	expected := []Violation{
+		{Constraint: "k8srequiredlabels/cluster-roles", Kind: "ClusterRole", Name: "kommander"},
+		{Constraint: "k8sdisallowedhostpath/no-host-path", Kind: "Pod", Namespace: "kommander", Name: "kubefed-0"},
+		{Constraint: "k8sdisallowedtags/no-latest-tag", Kind: "Pod", Namespace: "kubeaddons", Name: "kommander-5d8f7"},
+	}
+	if actual := Owned(violations, objects); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+}
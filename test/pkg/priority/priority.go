@@ -0,0 +1,284 @@
+package priority
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/quota"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Config is the priority of the critical components of kommander (see
+// priorityclasses.yaml).
+type Config struct {
+	// Classes are the priority classes critical workloads may run with.
+	// Classes other than the system-* classes of kubernetes must be
+	// shipped by an addon.
+	Classes []string `yaml:"classes"`
+
+	// Critical are the critical workloads of every addon, as patterns (see
+	// filepath.Match) of <kind>/<name>, e.g. Deployment/traefik-kubeaddons.
+	Critical map[string][]string `yaml:"critical"`
+
+	// BulkPriority is the priority of the bulk workloads of the preemption
+	// stress test, which is higher than that of pods without a priority
+	// class and lower than that of the critical classes.
+	BulkPriority int `yaml:"bulkPriority"`
+}
+
+// Load reads the configuration from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse priority configuration %s: %w", path, err)
+	}
+	if len(c.Classes) == 0 {
+		return nil, fmt.Errorf("%s: no priority classes for critical workloads", path)
+	}
+	if c.BulkPriority <= 0 {
+		return nil, fmt.Errorf("%s: the priority of bulk workloads must be positive", path)
+	}
+	for addon, patterns := range c.Critical {
+		for _, pattern := range patterns {
+			if _, err := filepath.Match(pattern, ""); err != nil || !strings.Contains(pattern, "/") {
+				return nil, fmt.Errorf("%s: critical workload %q of addon %s is not a pattern of <kind>/<name>", path, pattern, addon)
+			}
+		}
+	}
+	return c, nil
+}
+
+// Builtin returns whether a priority class exists in every cluster.
+func Builtin(class string) bool {
+	return strings.HasPrefix(class, "system-")
+}
+
+// Check returns the critical workloads among objects (rendered by the addons
+// of critical), which don't run with one of the classes, and the patterns
+// which match no workload. Classes which are neither builtin nor rendered are
+// violations too.
+func (c *Config) Check(objects []render.Object, critical []string) []string {
+	var violations []string
+	shipped := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Kind == "PriorityClass" {
+			shipped[obj.Name] = true
+		}
+	}
+	for _, class := range c.Classes {
+		if !Builtin(class) && !shipped[class] {
+			violations = append(violations, fmt.Sprintf("priority class %s is not shipped by an addon", class))
+		}
+	}
+
+	for _, addon := range critical {
+		for _, pattern := range c.Critical[addon] {
+			matched := false
+			for _, obj := range objects {
+				if obj.Addon != addon {
+					continue
+				}
+				if ok, _ := filepath.Match(pattern, obj.Kind+"/"+obj.Name); !ok {
+					continue
+				}
+				podSpec, ok := obj.PodSpec()
+				if !ok {
+					continue
+				}
+				matched = true
+				class, _ := podSpec["priorityClassName"].(string)
+				if !contains(c.Classes, class) {
+					violations = append(violations, fmt.Sprintf("critical %s %s of addon %s runs with priority class %q, expected one of %s", obj.Kind, obj.Name, addon, class, strings.Join(c.Classes, ", ")))
+				}
+			}
+			if !matched {
+				violations = append(violations, fmt.Sprintf("critical workload %s of addon %s is not rendered", pattern, addon))
+			}
+		}
+	}
+	return violations
+}
+
+// Missing returns the classes which don't exist in the cluster of a kubectl
+// client.
+func (c *Config) Missing(k kubectl.Client) ([]string, error) {
+	out, err := k.Output("get", "priorityclasses", "--output", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return nil, err
+	}
+	existing := strings.Fields(string(out))
+	var missing []string
+	for _, class := range c.Classes {
+		if !contains(existing, class) {
+			missing = append(missing, class)
+		}
+	}
+	return missing, nil
+}
+
+// Pod is a pod of the cluster.
+type Pod struct {
+	Namespace string
+	Name      string
+	UID       string
+	Phase     string
+	Restarts  int
+}
+
+func (p Pod) String() string {
+	return p.Namespace + "/" + p.Name
+}
+
+// CriticalPods returns the pods of the cluster of a kubectl client
+// which run with one of the classes, sorted.
+func (c *Config) CriticalPods(k kubectl.Client) ([]Pod, error) {
+	out, err := k.Output("get", "pods", "--all-namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	return c.criticalPods(out)
+}
+
+func (c *Config) criticalPods(list []byte) ([]Pod, error) {
+	var pods struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+				Name      string `json:"name"`
+				UID       string `json:"uid"`
+			} `json:"metadata"`
+			Spec struct {
+				PriorityClassName string `json:"priorityClassName"`
+			} `json:"spec"`
+			Status struct {
+				Phase             string `json:"phase"`
+				ContainerStatuses []struct {
+					RestartCount int `json:"restartCount"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(list, &pods); err != nil {
+		return nil, err
+	}
+	var critical []Pod
+	for _, item := range pods.Items {
+		if !contains(c.Classes, item.Spec.PriorityClassName) {
+			continue
+		}
+		pod := Pod{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, UID: item.Metadata.UID, Phase: item.Status.Phase}
+		for _, status := range item.Status.ContainerStatuses {
+			pod.Restarts += status.RestartCount
+		}
+		critical = append(critical, pod)
+	}
+	sort.Slice(critical, func(i, j int) bool { return critical[i].String() < critical[j].String() })
+	return critical, nil
+}
+
+// Disrupted returns the critical pods before a stress test which were removed
+// (e.g. preempted), restarted or stopped running by the pods after it.
+func Disrupted(before, after []Pod) []string {
+	current := make(map[string]Pod, len(after))
+	for _, pod := range after {
+		current[pod.UID] = pod
+	}
+	var disrupted []string
+	for _, pod := range before {
+		now, ok := current[pod.UID]
+		switch {
+		case !ok:
+			disrupted = append(disrupted, fmt.Sprintf("critical pod %s was removed", pod))
+		case now.Restarts > pod.Restarts:
+			disrupted = append(disrupted, fmt.Sprintf("critical pod %s restarted %d times", pod, now.Restarts-pod.Restarts))
+		case pod.Phase == "Running" && now.Phase != "Running":
+			disrupted = append(disrupted, fmt.Sprintf("critical pod %s is %s", pod, now.Phase))
+		}
+	}
+	return disrupted
+}
+
+// AllocatableCPU returns the allocatable cpu of the nodes of the cluster of a
+// kubectl client, in millicores.
+func AllocatableCPU(k kubectl.Client) (int64, error) {
+	out, err := k.Output("get", "nodes", "--output", "jsonpath={.items[*].status.allocatable.cpu}")
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, cpu := range strings.Fields(string(out)) {
+		milli, err := quota.ParseQuantity(cpu)
+		if err != nil {
+			return 0, err
+		}
+		total += milli
+	}
+	return total, nil
+}
+
+// BulkClass is the priority class of the bulk workloads.
+const BulkClass = "kommander-bulk"
+
+// Bulk returns the bulk workloads of the preemption stress test (JSON): their
+// priority class and a deployment of pause pods in namespace, with enough
+// replicas requesting cpu each to request more than allocatable (millicores)
+// so that the scheduler preempts pods of a lower priority to make room for
+// them.
+func (c *Config) Bulk(namespace string, allocatable, cpu int64) ([]byte, error) {
+	replicas := allocatable/cpu + 1
+	labels := map[string]string{"app": "kommander-bulk"}
+	return json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items": []interface{}{
+			map[string]interface{}{
+				"apiVersion":    "scheduling.k8s.io/v1",
+				"kind":          "PriorityClass",
+				"metadata":      map[string]interface{}{"name": BulkClass},
+				"value":         c.BulkPriority,
+				"description":   "Bulk workloads of the preemption stress test of the kommander addons.",
+				"globalDefault": false,
+			},
+			map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "kommander-bulk", "namespace": namespace},
+				"spec": map[string]interface{}{
+					"replicas": replicas,
+					"selector": map[string]interface{}{"matchLabels": labels},
+					"template": map[string]interface{}{
+						"metadata": map[string]interface{}{"labels": labels},
+						"spec": map[string]interface{}{
+							"priorityClassName": BulkClass,
+							"containers": []interface{}{map[string]interface{}{
+								"name":      "pause",
+								"image":     "k8s.gcr.io/pause:3.1",
+								"resources": map[string]interface{}{"requests": map[string]interface{}{"cpu": quota.FormatQuantity("cpu", cpu)}},
+							}},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func contains(s []string, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
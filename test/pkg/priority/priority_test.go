@@ -0,0 +1,142 @@
+package priority
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func workload(addon, kind, name, class string) render.Object {
+	podSpec := map[string]interface{}{"containers": []interface{}{}}
+	if class != "" {
+		podSpec["priorityClassName"] = class
+	}
+	return render.Object{Addon: addon, Kind: kind, Name: name, Content: map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": podSpec}},
+	}}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "priority")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for content, valid := range map[string]bool{
+		"classes: [kommander-critical]\nbulkPriority: 1000\ncritical:\n  traefik: [Deployment/traefik-kubeaddons]\n": true,
+		"bulkPriority: 1000\n":            false,
+		"classes: [kommander-critical]\n": false,
+		"classes: [kommander-critical]\nbulkPriority: 1000\ncritical:\n  traefik: [traefik-kubeaddons]\n":      false,
+		"classes: [kommander-critical]\nbulkPriority: 1000\ncritical:\n  traefik: [\"Deployment/[traefik\"]\n": false,
+	} {
+		path := filepath.Join(dir, "priorityclasses.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); (err == nil) != valid {
+			t.Errorf("expected %q to be valid: %t, got %v", content, valid, err)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	c := &Config{
+		Classes: []string{"kommander-critical", "system-cluster-critical"},
+		Critical: map[string][]string{
+			"traefik":      {"Deployment/traefik-kubeaddons"},
+			"cert-manager": {"Deployment/cert-manager-kubeaddons*"},
+			"kommander":    {"Deployment/kommander-kubeaddons-cluster-lifecycle"},
+		},
+	}
+	objects := []render.Object{
+		{Addon: "kommander", Kind: "PriorityClass", Name: "kommander-critical"},
+		workload("traefik", "Deployment", "traefik-kubeaddons", "kommander-critical"),
+		workload("cert-manager", "Deployment", "cert-manager-kubeaddons", "system-cluster-critical"),
+		workload("cert-manager", "Deployment", "cert-manager-kubeaddons-webhook", ""),
+		workload("dex", "Deployment", "dex-kubeaddons", ""),
+	}
+
+	expected := []string{
+		`critical Deployment cert-manager-kubeaddons-webhook of addon cert-manager runs with priority class "", expected one of kommander-critical, system-cluster-critical`,
+		"critical workload Deployment/kommander-kubeaddons-cluster-lifecycle of addon kommander is not rendered",
+	}
+	if violations := c.Check(objects, []string{"traefik", "cert-manager", "kommander"}); !reflect.DeepEqual(expected, violations) {
+		t.Errorf("expected %q, got %q", expected, violations)
+	}
+
+	if violations := c.Check(objects[1:2], []string{"traefik"}); len(violations) != 1 || violations[0] != "priority class kommander-critical is not shipped by an addon" {
+		t.Errorf("expected a violation for the class which is not shipped, got %q", violations)
+	}
+}
+
+func TestDisrupted(t *testing.T) {
+	c := &Config{Classes: []string{"kommander-critical"}}
+	before, err := c.criticalPods([]byte(`{"items": [
+		{"metadata": {"namespace": "kubeaddons", "name": "traefik-1", "uid": "a"}, "spec": {"priorityClassName": "kommander-critical"}, "status": {"phase": "Running", "containerStatuses": [{"restartCount": 1}]}},
+		{"metadata": {"namespace": "cert-manager", "name": "cert-manager-1", "uid": "b"}, "spec": {"priorityClassName": "kommander-critical"}, "status": {"phase": "Running"}},
+		{"metadata": {"namespace": "kommander", "name": "ui-1", "uid": "c"}, "spec": {"priorityClassName": "kommander-critical"}, "status": {"phase": "Running"}},
+		{"metadata": {"namespace": "kubeaddons", "name": "dex-1", "uid": "d"}, "spec": {}, "status": {"phase": "Running"}}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(before) != 3 || before[0].String() != "cert-manager/cert-manager-1" || before[2].Restarts != 1 {
+		t.Fatalf("expected the critical pods sorted, got %+v", before)
+	}
+
+	after := []Pod{
+		{Namespace: "kubeaddons", Name: "traefik-1", UID: "a", Phase: "Running", Restarts: 1},
+		{Namespace: "cert-manager", Name: "cert-manager-2", UID: "e", Phase: "Running"},
+		{Namespace: "kommander", Name: "ui-1", UID: "c", Phase: "Pending"},
+	}
+	expected := []string{"critical pod cert-manager/cert-manager-1 was removed", "critical pod kommander/ui-1 is Pending"}
+	if disrupted := Disrupted(before, after); !reflect.DeepEqual(expected, disrupted) {
+		t.Errorf("expected %q, got %q", expected, disrupted)
+	}
+	after[0].Restarts = 3
+	if disrupted := Disrupted(before[2:], after); len(disrupted) != 1 || disrupted[0] != "critical pod kubeaddons/traefik-1 restarted 2 times" {
+		t.Errorf("expected the restarted pod to be disrupted, got %q", disrupted)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	b, err := (&Config{BulkPriority: 1000}).Bulk("kommander-bulk", 7600, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Items []struct {
+			Kind  string `json:"kind"`
+			Value int    `json:"value"`
+			Spec  struct {
+				Replicas int `json:"replicas"`
+				Template struct {
+					Spec struct {
+						PriorityClassName string `json:"priorityClassName"`
+						Containers        []struct {
+							Resources struct {
+								Requests map[string]string `json:"requests"`
+							} `json:"resources"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || list.Items[0].Kind != "PriorityClass" || list.Items[0].Value != 1000 {
+		t.Fatalf("expected the priority class of the bulk workloads, got %s", b)
+	}
+	deployment := list.Items[1].Spec
+	if deployment.Replicas != 16 || deployment.Template.Spec.PriorityClassName != BulkClass || deployment.Template.Spec.Containers[0].Resources.Requests["cpu"] != "500m" {
+		t.Errorf("expected 16 bulk pods requesting 500m, more than allocatable, got %s", b)
+	}
+}
@@ -0,0 +1,185 @@
+package profile
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+// Profile is a named set of tests which are run together, e.g. to gate a
+// release.
+type Profile struct {
+	// Name is the name of the profile, taken from its file name.
+	Name string `yaml:"-"`
+
+	Description string `yaml:"description"`
+
+	// Tests are the names of the tests the profile runs.
+	Tests []string `yaml:"tests"`
+
+	// Scenarios are the scenarios run by TestScenario.
+	Scenarios []string `yaml:"scenarios"`
+
+	// Env are environment variables the profile sets (unless they are set
+	// already) to enable optional phases, e.g. POLICY_AUDIT.
+	Env map[string]string `yaml:"env"`
+
+	// Required are environment variables which must be set to run the
+	// profile, e.g. RELEASE_SIGNING_KEY for a profile whose sign off has to
+	// be signed.
+	Required []string `yaml:"required"`
+}
+
+var testName = regexp.MustCompile(`^Test[A-Za-z0-9_]*$`)
+
+// Load reads the profile with the given name from <dir>/<name>.yaml.
+func Load(dir, name string) (*Profile, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("profile %s does not exist in %s", name, dir)
+		}
+		return nil, err
+	}
+
+	p := &Profile{}
+	if err := yaml.UnmarshalStrict(b, p); err != nil {
+		return nil, fmt.Errorf("could not parse profile %s: %w", name, err)
+	}
+	p.Name = name
+
+	if len(p.Tests) == 0 {
+		return nil, fmt.Errorf("profile %s has no tests", name)
+	}
+	for _, test := range p.Tests {
+		if !testName.MatchString(test) {
+			return nil, fmt.Errorf("profile %s has invalid test name %q", name, test)
+		}
+	}
+	return p, nil
+}
+
+// Pattern returns the -test.run pattern which selects the tests of the
+// profile.
+func (p *Profile) Pattern() string {
+	return "^(" + strings.Join(p.Tests, "|") + ")$"
+}
+
+// Apply sets the environment of the profile, including SCENARIO for its
+// scenarios. Variables which are already set are left as they are. It fails
+// when a required variable is not set.
+func (p *Profile) Apply() error {
+	for _, k := range p.Required {
+		if os.Getenv(k) == "" {
+			return fmt.Errorf("profile %s requires %s to be set", p.Name, k)
+		}
+	}
+
+	env := make(map[string]string, len(p.Env)+1)
+	for k, v := range p.Env {
+		env[k] = v
+	}
+	if len(p.Scenarios) > 0 {
+		env["SCENARIO"] = strings.Join(p.Scenarios, ",")
+	}
+
+	for k, v := range env {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SignOff is the result of running a profile, meant to be attached to a
+// release.
+type SignOff struct {
+	Profile   string               `json:"profile"`
+	Revision  string               `json:"revision"`
+	Passed    bool                 `json:"passed"`
+	Started   time.Time            `json:"started"`
+	Finished  time.Time            `json:"finished"`
+	Tests     []string             `json:"tests"`
+	Scenarios []string             `json:"scenarios,omitempty"`
+	Env       map[string]string    `json:"env,omitempty"`
+	Addons    []report.AddonResult `json:"addons"`
+}
+
+// NewSignOff returns the sign off of a profile run at a revision, which
+// passed when all of its tests passed.
+func NewSignOff(p *Profile, revision string, passed bool, r *report.Report) *SignOff {
+	env := make(map[string]string, len(p.Env))
+	for k := range p.Env {
+		env[k] = os.Getenv(k)
+	}
+	addons := append([]report.AddonResult(nil), r.Addons...)
+	sort.SliceStable(addons, func(i, j int) bool {
+		if addons[i].Group != addons[j].Group {
+			return addons[i].Group < addons[j].Group
+		}
+		return addons[i].Addon < addons[j].Addon
+	})
+
+	return &SignOff{
+		Profile:   p.Name,
+		Revision:  revision,
+		Passed:    passed,
+		Started:   r.Started,
+		Finished:  r.Finished,
+		Tests:     p.Tests,
+		Scenarios: p.Scenarios,
+		Env:       env,
+		Addons:    addons,
+	}
+}
+
+// Write writes the sign off to <dir>/<profile>.json along with its SHA-256
+// checksum in <profile>.json.sha256, and returns the path of the sign off.
+func (s *SignOff) Write(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.Profile+".json")
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		return "", err
+	}
+
+	checksum := fmt.Sprintf("%x  %s\n", sha256.Sum256(b), filepath.Base(path))
+	if err := ioutil.WriteFile(path+".sha256", []byte(checksum), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Sign writes an armored detached signature of a file to <path>.asc using the
+// gpg CLI and the given key.
+func Sign(path, key string) error {
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("gpg", "--batch", "--yes", "--local-user", key, "--armor", "--detach-sign", "--output", path+".asc", path)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not sign %s: %w: %s", path, err, stderr.String())
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package profile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+)
+
+func TestApply(t *testing.T) {
+	os.Unsetenv("SCENARIO")
+	os.Unsetenv("CHART_FRESHNESS")
+	os.Setenv("POLICY_AUDIT", "false")
+	defer func() {
+		for _, k := range []string{"SCENARIO", "CHART_FRESHNESS", "POLICY_AUDIT"} {
+			os.Unsetenv(k)
+		}
+	}()
+
+	p := &Profile{
+		Name:      "release-gate",
+		Tests:     []string{"TestKommanderGroup", "TestScenario"},
+		Scenarios: []string{"kommander-full-ha", "kommander-minimal-helm"},
+		Env:       map[string]string{"POLICY_AUDIT": "true", "CHART_FRESHNESS": "report"},
+	}
+	if pattern := p.Pattern(); pattern != "^(TestKommanderGroup|TestScenario)$" {
+		t.Errorf("unexpected pattern %q", pattern)
+	}
+
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if v := os.Getenv("SCENARIO"); v != "kommander-full-ha,kommander-minimal-helm" {
+		t.Errorf("expected SCENARIO to be set to the scenarios, got %q", v)
+	}
+	if v := os.Getenv("CHART_FRESHNESS"); v != "report" {
+		t.Errorf("expected CHART_FRESHNESS to be set, got %q", v)
+	}
+	if v := os.Getenv("POLICY_AUDIT"); v != "false" {
+		t.Errorf("expected POLICY_AUDIT to be left as it was, got %q", v)
+	}
+}
+
+func TestApplyRequired(t *testing.T) {
+	os.Unsetenv("RELEASE_SIGNING_KEY")
+	defer os.Unsetenv("RELEASE_SIGNING_KEY")
+
+	p := &Profile{
+		Name:     "release-gate",
+		Tests:    []string{"TestScenario"},
+		Required: []string{"RELEASE_SIGNING_KEY"},
+	}
+	if err := p.Apply(); err == nil || !strings.Contains(err.Error(), "RELEASE_SIGNING_KEY") {
+		t.Errorf("expected the missing RELEASE_SIGNING_KEY to fail, got %v", err)
+	}
+
+	os.Setenv("RELEASE_SIGNING_KEY", "release@example.com")
+	if err := p.Apply(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSignOff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signoff-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := report.New()
+	r.Add(report.AddonResult{Group: "kommander", Addon: "traefik", Status: report.Passed})
+	r.Add(report.AddonResult{Group: "kommander", Addon: "dex", Status: report.Passed})
+
+	p := &Profile{Name: "release-gate", Tests: []string{"TestKommanderGroup"}}
+	path, err := NewSignOff(p, "abc123", true, r).Write(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signOff SignOff
+	if err := json.Unmarshal(b, &signOff); err != nil {
+		t.Fatal(err)
+	}
+	if !signOff.Passed || signOff.Revision != "abc123" || signOff.Addons[0].Addon != "dex" {
+		t.Errorf("unexpected sign off: %+v", signOff)
+	}
+
+	checksum, err := ioutil.ReadFile(filepath.Join(dir, "release-gate.json.sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(checksum), "  release-gate.json\n") {
+		t.Errorf("unexpected checksum file: %q", checksum)
+	}
+}
@@ -0,0 +1,202 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// SignatureAnnotation is the annotation of addon manifests with the base64
+// encoded detached signature of their payload.
+const SignatureAnnotation = "provenance.kubeaddons.mesosphere.io/signature"
+
+// Verifier verifies detached signatures.
+type Verifier interface {
+	Verify(payload, signature []byte) error
+}
+
+// GPG verifies gpg signatures with the keys of a keyring.
+type GPG struct {
+	Keyring string
+}
+
+func (g GPG) Verify(payload, signature []byte) error {
+	return withFiles(payload, signature, func(payloadPath, signaturePath string) *exec.Cmd {
+		return exec.Command("gpg", "--batch", "--no-default-keyring", "--keyring", g.Keyring, "--verify", signaturePath, payloadPath)
+	})
+}
+
+// Cosign verifies cosign signatures with a public key.
+type Cosign struct {
+	Key string
+}
+
+func (c Cosign) Verify(payload, signature []byte) error {
+	return withFiles(payload, signature, func(payloadPath, signaturePath string) *exec.Cmd {
+		return exec.Command("cosign", "verify-blob", "--key", c.Key, "--signature", signaturePath, payloadPath)
+	})
+}
+
+// withFiles writes the payload and the signature to temporary files and runs
+// the command verifying them.
+func withFiles(payload, signature []byte, command func(payloadPath, signaturePath string) *exec.Cmd) error {
+	dir, err := ioutil.TempDir("", "provenance-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	payloadPath, signaturePath := filepath.Join(dir, "payload.json"), filepath.Join(dir, "payload.sig")
+	if err := ioutil.WriteFile(payloadPath, payload, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(signaturePath, signature, 0644); err != nil {
+		return err
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := command(payloadPath, signaturePath)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// Verify verifies the signature of an addon manifest. It fails for unsigned
+// addons and for addons whose manifest changed after it was signed.
+func Verify(addon v1beta1.AddonInterface, verifier Verifier) error {
+	name := addon.GetName()
+	encoded, ok := addon.GetAnnotations()[SignatureAnnotation]
+	if !ok {
+		return fmt.Errorf("addon %s is not signed", name)
+	}
+	signature, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("addon %s has an invalid signature annotation: %w", name, err)
+	}
+	payload, err := Payload(addon)
+	if err != nil {
+		return err
+	}
+	if err := verifier.Verify(payload, signature); err != nil {
+		return fmt.Errorf("the signature of addon %s does not match its manifest, it may have been tampered with: %w", name, err)
+	}
+	return nil
+}
+
+// Payload returns the payload of an addon which is signed (see Canonical).
+func Payload(addon v1beta1.AddonInterface) ([]byte, error) {
+	b, err := json.Marshal(addon)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode addon %s: %w", addon.GetName(), err)
+	}
+	return Canonical(b)
+}
+
+// ManifestPayload returns the payload of the addon manifest in a YAML document
+// which is signed (see Canonical), e.g. for the catalog pipeline to sign.
+func ManifestPayload(manifest []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(manifest, &v); err != nil {
+		return nil, fmt.Errorf("could not parse addon manifest: %w", err)
+	}
+	b, err := json.Marshal(stringKeys(v))
+	if err != nil {
+		return nil, fmt.Errorf("could not encode addon manifest: %w", err)
+	}
+	return Canonical(b)
+}
+
+// Canonical returns the canonical form of the JSON encoding of an addon
+// manifest, which is the same for the manifest in the catalog and the addon
+// parsed from it: compact JSON with sorted keys, without empty values and
+// without the signature annotation.
+func Canonical(manifest []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(manifest, &v); err != nil {
+		return nil, err
+	}
+	if metadata, ok := lookup(v, "metadata").(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, SignatureAnnotation)
+		}
+	}
+	pruned, _ := prune(v)
+	if pruned == nil {
+		pruned = map[string]interface{}{}
+	}
+	return json.Marshal(pruned)
+}
+
+func lookup(v interface{}, key string) interface{} {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m[key]
+	}
+	return nil
+}
+
+// prune removes empty values (null, false, 0, "", empty lists and maps) in
+// maps, which encoding an addon adds for fields its manifest omits. It
+// returns false for empty values.
+func prune(v interface{}) (interface{}, bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if pruned, ok := prune(value); ok {
+				v[key] = pruned
+			} else {
+				delete(v, key)
+			}
+		}
+		return v, len(v) > 0
+	case []interface{}:
+		for i, value := range v {
+			if pruned, ok := prune(value); ok {
+				v[i] = pruned
+			} else {
+				v[i] = nil
+			}
+		}
+		return v, len(v) > 0
+	case nil:
+		return nil, false
+	case bool:
+		return v, v
+	case float64:
+		return v, v != 0
+	case string:
+		return v, v != ""
+	default:
+		return v, true
+	}
+}
+
+// stringKeys converts the maps of a document parsed with yaml.v2 to maps with
+// string keys, which can be encoded as JSON.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = stringKeys(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,75 @@
+package provenance
+
+import (
+	"testing"
+)
+
+const manifest = `{"apiVersion":"kubeaddons.mesosphere.io/v1beta1","kind":"Addon","metadata":{"name":"velero","annotations":{"catalog.kubeaddons.mesosphere.io/addon-revision":"1.0.2-3"}},"spec":{"namespace":"velero","chartReference":{"chart":"velero","version":"2.2.1"}}}`
+
+func TestCanonical(t *testing.T) {
+	// the encoding of a parsed addon has empty values for the fields the
+	// manifest omits
+	encoded := `{
+  "kind": "Addon",
+  "apiVersion": "kubeaddons.mesosphere.io/v1beta1",
+  "metadata": {
+    "name": "velero",
+    "creationTimestamp": null,
+    "annotations": {
+      "catalog.kubeaddons.mesosphere.io/addon-revision": "1.0.2-3",
+      "provenance.kubeaddons.mesosphere.io/signature": "c2lnbmF0dXJl"
+    }
+  },
+  "spec": {"namespace": "velero", "requires": [], "chartReference": {"chart": "velero", "version": "2.2.1", "values": null}},
+  "status": {"ready": false, "stage": ""}
+}`
+
+	expected, err := Canonical([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := Canonical([]byte(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != string(expected) {
+		t.Errorf("expected the payload\n%s\ngot\n%s", expected, payload)
+	}
+	if string(expected) != `{"apiVersion":"kubeaddons.mesosphere.io/v1beta1","kind":"Addon","metadata":{"annotations":{"catalog.kubeaddons.mesosphere.io/addon-revision":"1.0.2-3"},"name":"velero"},"spec":{"chartReference":{"chart":"velero","version":"2.2.1"},"namespace":"velero"}}` {
+		t.Errorf("expected compact JSON with sorted keys, got %s", expected)
+	}
+
+	tampered, err := Canonical([]byte(`{"metadata":{"name":"velero"},"spec":{"chartReference":{"chart":"velero","version":"2.2.2"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(tampered) == string(expected) {
+		t.Errorf("expected a changed manifest to have another payload")
+	}
+}
+
+func TestManifestPayload(t *testing.T) {
+	payload, err := ManifestPayload([]byte(`---
+apiVersion: kubeaddons.mesosphere.io/v1beta1
+kind: Addon
+metadata:
+  name: velero
+  annotations:
+    catalog.kubeaddons.mesosphere.io/addon-revision: "1.0.2-3"
+spec:
+  namespace: velero
+  chartReference:
+    chart: velero
+    version: 2.2.1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := Canonical([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != string(expected) {
+		t.Errorf("expected the payload\n%s\ngot\n%s", expected, payload)
+	}
+}
@@ -0,0 +1,201 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/spend"
+)
+
+const (
+	// capiTimeout is how long the machines of a Cluster API cluster may take
+	// to be provisioned.
+	capiTimeout = "30m"
+
+	// calicoManifest is the CNI installed into Cluster API clusters, which
+	// come without one.
+	calicoManifest = "https://docs.projectcalico.org/v3.15/manifests/calico.yaml"
+)
+
+// managementConfig is the kind configuration of the management cluster, which
+// mounts the docker socket for the docker infrastructure provider.
+const managementConfig = `kind: Cluster
+apiVersion: kind.x-k8s.io/v1alpha4
+nodes:
+- role: control-plane
+  extraMounts:
+  - hostPath: /var/run/docker.sock
+    containerPath: /var/run/docker.sock
+`
+
+// capi creates clusters with Cluster API, from a kind management cluster
+// named <name>-capi which is initialized with clusterctl for an
+// infrastructure provider (e.g. docker or aws). The management cluster is kept
+// while the cluster exists, so tests can use it to provision clusters.
+type capi struct {
+	infrastructure string
+}
+
+func (c capi) Name() string { return "capi-" + c.infrastructure }
+
+// ManagementContext returns the kubectl context of the management cluster of
+// a Cluster API cluster.
+func ManagementContext(name string) string {
+	return "kind-" + managementName(name)
+}
+
+func managementName(name string) string {
+	return name + "-capi"
+}
+
+func (c capi) Create(k kubectl.Client, name string, topology Topology) error {
+	config, err := ioutil.TempFile("", "capi-management-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(config.Name())
+	if _, err := config.WriteString(managementConfig); err != nil {
+		return err
+	}
+	if err := config.Close(); err != nil {
+		return err
+	}
+
+	management := ManagementContext(name)
+	if err := run(k, "kind", "create", "cluster", "--name", managementName(name), "--config", config.Name()); err != nil {
+		return err
+	}
+	if err := run(k, "clusterctl", "init", "--kubeconfig-context", management, "--infrastructure", c.infrastructure); err != nil {
+		return err
+	}
+
+	args := []string{"config", "cluster", name,
+		"--kubeconfig-context", management,
+		"--infrastructure", c.infrastructure,
+		"--kubernetes-version", "v" + topology.KubernetesVersion,
+		"--control-plane-machine-count", strconv.Itoa(topology.ControlPlanes),
+		"--worker-machine-count", strconv.Itoa(topology.Workers),
+	}
+	if c.infrastructure == "docker" {
+		args = append(args, "--flavor", "development")
+	}
+	manifests, err := output(k, "clusterctl", args...)
+	if err != nil {
+		return err
+	}
+	if topology.Budget != nil {
+		if manifests, err = budgeted(manifests, topology.Budget); err != nil {
+			return err
+		}
+	}
+	if err := k.Apply(manifests, "--context", management); err != nil {
+		return err
+	}
+	if err := k.Run("--context", management, "wait", "cluster/"+name, "--for", "condition=Ready", "--timeout", capiTimeout); err != nil {
+		return fmt.Errorf("cluster %s was not provisioned: %w", name, err)
+	}
+
+	if err := c.useKubeconfig(k, name); err != nil {
+		return err
+	}
+	if err := k.ApplyFile(calicoManifest, "--context", c.Context(name)); err != nil {
+		return err
+	}
+	return k.Run("--context", c.Context(name), "wait", "nodes", "--all", "--for", "condition=Ready", "--timeout", capiTimeout)
+}
+
+// useKubeconfig merges the kubeconfig of a cluster (from the secret Cluster
+// API creates for it) into the kubeconfig file of a kubectl client and makes
+// its context the current context.
+func (c capi) useKubeconfig(k kubectl.Client, name string) error {
+	out, err := k.Output("--context", ManagementContext(name), "get", "secret", name+"-kubeconfig", "--output", "jsonpath={.data.value}")
+	if err != nil {
+		return err
+	}
+	kubeconfig, err := base64.StdEncoding.DecodeString(string(out))
+	if err != nil {
+		return fmt.Errorf("could not decode the kubeconfig of cluster %s: %w", name, err)
+	}
+
+	dir, err := ioutil.TempDir("", "capi-kubeconfig-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, name+".kubeconfig")
+	if err := ioutil.WriteFile(path, kubeconfig, 0600); err != nil {
+		return err
+	}
+
+	if err := k.Merge(path); err != nil {
+		return err
+	}
+	return k.Run("config", "use-context", c.Context(name))
+}
+
+// Management returns a kubectl client for the management cluster of a Cluster
+// API cluster, whose kubeconfig file (written to dir) holds only the context
+// of the management cluster.
+func Management(k kubectl.Client, name, dir string) (kubectl.Client, error) {
+	kubeconfig, err := k.Output("config", "view", "--minify", "--flatten", "--context", ManagementContext(name))
+	if err != nil {
+		return kubectl.Client{}, fmt.Errorf("could not get the kubeconfig of the management cluster of %s: %w", name, err)
+	}
+	path := filepath.Join(dir, managementName(name)+".kubeconfig")
+	if err := ioutil.WriteFile(path, kubeconfig, 0600); err != nil {
+		return kubectl.Client{}, err
+	}
+	return kubectl.Client{Kubeconfig: path}, nil
+}
+
+// budgeted verifies that the manifests of a cluster stay within a budget and
+// tags its resources.
+func budgeted(manifests []byte, budget *spend.Budget) ([]byte, error) {
+	objects, err := render.Decode(bytes.NewReader(manifests))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode cluster manifests: %w", err)
+	}
+	if err := budget.Check(objects); err != nil {
+		return nil, err
+	}
+	budget.Tag(objects)
+
+	items := make([]interface{}, 0, len(objects))
+	for _, obj := range objects {
+		items = append(items, obj.Content)
+	}
+	return json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+}
+
+func (capi) Context(name string) string { return name + "-admin@" + name }
+
+func (capi) Delete(k kubectl.Client, name string) error {
+	// deleting the Cluster deletes its machines (and their instances)
+	management := ManagementContext(name)
+	if err := k.Run("--context", management, "delete", "cluster", name, "--ignore-not-found", "--wait", "--timeout", capiTimeout); err != nil {
+		return fmt.Errorf("could not delete cluster %s, its infrastructure may be left behind: %w", name, err)
+	}
+	return run(k, "kind", "delete", "cluster", "--name", managementName(name))
+}
+
+func output(k kubectl.Client, name string, args ...string) ([]byte, error) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command(name, args...)
+	cmd.Env = k.Env()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w: %s", name, strings.Join(args[:2], " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/ipfamily"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/spend"
+)
+
+const (
+	// Kind creates clusters with kind, the default.
+	Kind = "kind"
+
+	// K3d creates k3s clusters in docker with k3d.
+	K3d = "k3d"
+
+	// Minikube creates clusters with minikube, using its docker driver.
+	Minikube = "minikube"
+
+	// CAPIDocker creates clusters with Cluster API and its docker
+	// infrastructure provider, e.g. in CI.
+	CAPIDocker = "capi-docker"
+
+	// CAPIAWS creates clusters with Cluster API on AWS, e.g. for nightly
+	// runs. clusterctl reads the AWS configuration (e.g. AWS_REGION,
+	// AWS_SSH_KEY_NAME and AWS_B64ENCODED_CREDENTIALS) from the environment.
+	CAPIAWS = "capi-aws"
+)
+
+// Capability is a feature of a cluster provider which scenarios may need.
+type Capability string
+
+const (
+	// MultiNode is the capability to create worker nodes.
+	MultiNode Capability = "multi-node"
+
+	// HAControlPlane is the capability to create multiple control plane nodes.
+	HAControlPlane Capability = "ha-control-plane"
+
+	// NodeImages is the capability to run the kind node images configured for
+	// each architecture (see architectures.yaml).
+	NodeImages Capability = "node-images"
+
+	// ImagePreload is the capability to pull images onto the nodes with
+	// crictl, from docker containers named after the nodes.
+	ImagePreload Capability = "image-preload"
+
+	// ClusterAPI is the capability to provision clusters from a Cluster API
+	// management cluster (see ManagementContext).
+	ClusterAPI Capability = "cluster-api"
+
+	// NetworkFaults is the capability to inject network faults with tc and
+	// iptables, from docker containers named after the nodes.
+	NetworkFaults Capability = "network-faults"
+
+	// IPv6 is the capability to create clusters with an IPv6 network.
+	IPv6 Capability = "ipv6"
+
+	// DualStack is the capability to create clusters with an IPv4 and IPv6
+	// network. No provider has it yet: kind supports dual stack clusters only
+	// from v0.10 on.
+	DualStack Capability = "dual-stack"
+
+	// ContainerdConfig is the capability to patch the containerd and kubelet
+	// configuration of the nodes when the cluster is created.
+	ContainerdConfig Capability = "containerd-config"
+
+	// NodeClock is the capability to set the timezone and locale of the
+	// nodes when the cluster is created.
+	NodeClock Capability = "node-clock"
+)
+
+// capabilities are the capabilities of each provider.
+var capabilities = map[string][]Capability{
+	Kind:       {MultiNode, HAControlPlane, NodeImages, ImagePreload, NetworkFaults, IPv6, ContainerdConfig, NodeClock},
+	K3d:        {MultiNode, HAControlPlane, ImagePreload},
+	Minikube:   {MultiNode},
+	CAPIDocker: {MultiNode, HAControlPlane, ImagePreload, ClusterAPI, NetworkFaults},
+	CAPIAWS:    {MultiNode, HAControlPlane, ClusterAPI},
+}
+
+// Topology is the shape of a cluster to create.
+type Topology struct {
+	// KubernetesVersion is the kubernetes version, without a leading "v".
+	KubernetesVersion string
+	ControlPlanes     int
+	Workers           int
+
+	// IPFamily is the address family of the cluster network (see
+	// ipfamily.Family), ipv4 when empty.
+	IPFamily string
+
+	// Containerd is whether the container runtime of the nodes is configured
+	// rather than the default of the provider.
+	Containerd bool
+
+	// Clock is whether the timezone or locale of the nodes is configured
+	// rather than the default of the provider.
+	Clock bool
+
+	// Budget limits the instances of clusters on cloud providers and tags
+	// their resources. It is nil on local providers.
+	Budget *spend.Budget
+}
+
+// Provider creates clusters with a CLI. Kind clusters are created with the
+// kubeaddons test framework instead, so there is no provider for kind.
+type Provider interface {
+	// Name is the name of the provider, e.g. k3d.
+	Name() string
+
+	// Create creates a cluster and makes its context the current context of
+	// the kubeconfig file of a kubectl client.
+	Create(k kubectl.Client, name string, topology Topology) error
+
+	// Context returns the kubectl context of a cluster.
+	Context(name string) string
+
+	// Delete removes a cluster.
+	Delete(k kubectl.Client, name string) error
+}
+
+// Get returns the provider with the given name.
+func Get(name string) (Provider, error) {
+	switch name {
+	case K3d:
+		return k3d{}, nil
+	case Minikube:
+		return minikube{}, nil
+	case CAPIDocker:
+		return capi{infrastructure: "docker"}, nil
+	case CAPIAWS:
+		return capi{infrastructure: "aws"}, nil
+	case Kind:
+		return nil, fmt.Errorf("kind clusters are created by the kubeaddons test framework")
+	default:
+		return nil, fmt.Errorf("unknown cluster provider %s, expected %s, %s, %s, %s or %s", name, Kind, K3d, Minikube, CAPIDocker, CAPIAWS)
+	}
+}
+
+// Cloud returns whether the provider with the given name creates instances
+// of a cloud provider, which run within spend limits.
+func Cloud(name string) bool {
+	return name == CAPIAWS
+}
+
+// Has returns whether the provider with the given name has a capability.
+func Has(name string, capability Capability) bool {
+	for _, c := range capabilities[name] {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// Missing returns the capabilities the provider with the given name lacks.
+func Missing(name string, required []Capability) []Capability {
+	var missing []Capability
+	for _, capability := range required {
+		if !Has(name, capability) {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+// Required returns the capabilities needed to create a topology, in addition
+// to the given capabilities.
+func Required(topology Topology, additional ...Capability) []Capability {
+	var required []Capability
+	if topology.ControlPlanes > 1 {
+		required = append(required, HAControlPlane)
+	}
+	if topology.Workers > 0 {
+		required = append(required, MultiNode)
+	}
+	switch ipfamily.Family(topology.IPFamily) {
+	case ipfamily.IPv6:
+		required = append(required, IPv6)
+	case ipfamily.DualStack:
+		required = append(required, DualStack)
+	}
+	if topology.Containerd {
+		required = append(required, ContainerdConfig)
+	}
+	if topology.Clock {
+		required = append(required, NodeClock)
+	}
+	return append(required, additional...)
+}
+
+type k3d struct{}
+
+func (k3d) Name() string { return K3d }
+
+func (k3d) Create(k kubectl.Client, name string, topology Topology) error {
+	return run(k, "k3d", "cluster", "create", name,
+		"--servers", strconv.Itoa(topology.ControlPlanes),
+		"--agents", strconv.Itoa(topology.Workers),
+		"--image", fmt.Sprintf("rancher/k3s:v%s-k3s1", topology.KubernetesVersion),
+		"--wait",
+	)
+}
+
+func (k3d) Context(name string) string { return "k3d-" + name }
+
+func (k3d) Delete(k kubectl.Client, name string) error {
+	return run(k, "k3d", "cluster", "delete", name)
+}
+
+type minikube struct{}
+
+func (minikube) Name() string { return Minikube }
+
+func (minikube) Create(k kubectl.Client, name string, topology Topology) error {
+	if topology.ControlPlanes > 1 {
+		return fmt.Errorf("minikube does not support %d control plane nodes", topology.ControlPlanes)
+	}
+	return run(k, "minikube", "start",
+		"--profile", name,
+		"--driver", "docker",
+		"--kubernetes-version", "v"+topology.KubernetesVersion,
+		"--nodes", strconv.Itoa(1+topology.Workers),
+		"--wait", "all",
+	)
+}
+
+func (minikube) Context(name string) string { return name }
+
+func (minikube) Delete(k kubectl.Client, name string) error {
+	return run(k, "minikube", "delete", "--profile", name)
+}
+
+// run runs a CLI with the kubeconfig file of a kubectl client, to which it
+// adds the contexts of the clusters it creates.
+func run(k kubectl.Client, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Env = k.Env()
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w", name, strings.Join(args[:2], " "), err)
+	}
+	return nil
+}
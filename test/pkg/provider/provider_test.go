@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissing(t *testing.T) {
+	ha := Topology{ControlPlanes: 3, Workers: 2}
+
+	if missing := Missing(Kind, Required(ha, NodeImages)); len(missing) != 0 {
+		t.Errorf("expected kind to have all capabilities, missing %v", missing)
+	}
+	if missing := Missing(K3d, Required(ha)); len(missing) != 0 {
+		t.Errorf("expected k3d to create HA clusters, missing %v", missing)
+	}
+
+	expected := []Capability{HAControlPlane, NodeImages}
+	if missing := Missing(Minikube, Required(ha, NodeImages)); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected minikube to miss %v, got %v", expected, missing)
+	}
+
+	if required := Required(Topology{ControlPlanes: 1}); len(required) != 0 {
+		t.Errorf("expected a single node cluster to require no capabilities, got %v", required)
+	}
+
+	if missing := Missing(K3d, Required(Topology{ControlPlanes: 1, IPFamily: "ipv6"})); !reflect.DeepEqual(missing, []Capability{IPv6}) {
+		t.Errorf("expected k3d to miss ipv6, got %v", missing)
+	}
+	if missing := Missing(Kind, Required(Topology{ControlPlanes: 1, IPFamily: "dual"})); !reflect.DeepEqual(missing, []Capability{DualStack}) {
+		t.Errorf("expected kind to miss dual-stack, got %v", missing)
+	}
+	if missing := Missing(K3d, Required(Topology{ControlPlanes: 1, Containerd: true})); !reflect.DeepEqual(missing, []Capability{ContainerdConfig}) {
+		t.Errorf("expected k3d to miss containerd-config, got %v", missing)
+	}
+	if missing := Missing(Minikube, Required(Topology{ControlPlanes: 1, Clock: true})); !reflect.DeepEqual(missing, []Capability{NodeClock}) {
+		t.Errorf("expected minikube to miss node-clock, got %v", missing)
+	}
+}
+
+func TestGet(t *testing.T) {
+	for _, name := range []string{K3d, Minikube, CAPIDocker, CAPIAWS} {
+		p, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Name() != name {
+			t.Errorf("expected provider %s, got %s", name, p.Name())
+		}
+	}
+	if _, err := Get("docker-desktop"); err == nil {
+		t.Error("expected an error for an unknown provider")
+	}
+}
@@ -0,0 +1,139 @@
+package quarantine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DateFormat is the format of expiry dates.
+const DateFormat = "2006-01-02"
+
+// Entry quarantines a flaky check, or all checks covering an addon: the checks
+// still run, but their failures don't fail the suite until the entry expires.
+type Entry struct {
+	// Check is the name of the quarantined check.
+	Check string `yaml:"check,omitempty"`
+
+	// Addon quarantines all checks covering the addon.
+	Addon string `yaml:"addon,omitempty"`
+
+	// Scenario limits the entry to the checks of a scenario.
+	Scenario string `yaml:"scenario,omitempty"`
+
+	// Issue is the link to the issue tracking the flake.
+	Issue string `yaml:"issue"`
+
+	// Expires is the date (e.g. 2020-04-01) after which the entry no longer
+	// quarantines and fails the checks it covers instead.
+	Expires time.Time `yaml:"-"`
+
+	Reason string `yaml:"reason,omitempty"`
+}
+
+func (e Entry) String() string {
+	s := "check " + e.Check
+	if e.Addon != "" {
+		s = "addon " + e.Addon
+	}
+	if e.Scenario != "" {
+		s += " in scenario " + e.Scenario
+	}
+	return s
+}
+
+// Expired returns whether the entry expired at a point in time. Entries expire
+// at the end of their expiry date, in UTC.
+func (e Entry) Expired(now time.Time) bool {
+	return !now.UTC().Before(e.Expires.AddDate(0, 0, 1))
+}
+
+// Covers returns whether the entry covers a check of a scenario, which covers
+// an addon (or none).
+func (e Entry) Covers(scenario, check, addon string) bool {
+	if e.Scenario != "" && e.Scenario != scenario {
+		return false
+	}
+	if e.Check != "" {
+		return e.Check == check
+	}
+	return e.Addon == addon
+}
+
+func (e *Entry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type entry Entry
+	var v struct {
+		entry   `yaml:",inline"`
+		Expires string `yaml:"expires"`
+	}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	*e = Entry(v.entry)
+
+	if (e.Check == "") == (e.Addon == "") {
+		return fmt.Errorf("quarantine entry must have exactly one of check and addon")
+	}
+	if !strings.HasPrefix(e.Issue, "https://") {
+		return fmt.Errorf("quarantine of %s must link its issue", e)
+	}
+	if v.Expires == "" {
+		return fmt.Errorf("quarantine of %s has no expiry date", e)
+	}
+	expires, err := time.Parse(DateFormat, v.Expires)
+	if err != nil {
+		return fmt.Errorf("quarantine of %s has an invalid expiry date: %w", e, err)
+	}
+	e.Expires = expires
+	return nil
+}
+
+// Quarantine are the quarantined checks.
+type Quarantine []Entry
+
+// Load reads the quarantine in a file. A missing file quarantines nothing.
+func Load(path string) (Quarantine, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Quarantine Quarantine `yaml:"quarantine"`
+	}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse quarantine %s: %w", path, err)
+	}
+	return file.Quarantine, nil
+}
+
+// Lookup returns the entry covering a check of a scenario, which covers an
+// addon (or none). Entries of the check come before entries of its addon.
+func (q Quarantine) Lookup(scenario, check, addon string) (Entry, bool) {
+	for _, byCheck := range []bool{true, false} {
+		for _, e := range q {
+			if (e.Check != "") == byCheck && e.Covers(scenario, check, addon) {
+				return e, true
+			}
+		}
+	}
+	return Entry{}, false
+}
+
+// Expired returns the entries which expired at a point in time.
+func (q Quarantine) Expired(now time.Time) []Entry {
+	var expired []Entry
+	for _, e := range q {
+		if e.Expired(now) {
+			expired = append(expired, e)
+		}
+	}
+	return expired
+}
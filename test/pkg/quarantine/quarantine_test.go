@@ -0,0 +1,76 @@
+package quarantine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quarantine-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "quarantine.yaml")
+	if err := ioutil.WriteFile(path, []byte(`quarantine:
+  - check: kommander ui is served
+    issue: https://github.com/mesosphere/kubeaddons-kommander/issues/1
+    expires: 2020-04-01
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	q, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q) != 1 || !q[0].Expires.Equal(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected one entry expiring on 2020-04-01, got %+v", q)
+	}
+
+	for _, invalid := range []string{
+		"quarantine:\n  - check: a\n    addon: b\n    issue: https://example.com/1\n    expires: 2020-04-01\n",
+		"quarantine:\n  - check: a\n    expires: 2020-04-01\n",
+		"quarantine:\n  - check: a\n    issue: https://example.com/1\n",
+		"quarantine:\n  - check: a\n    issue: https://example.com/1\n    expires: april\n",
+	} {
+		if err := ioutil.WriteFile(path, []byte(invalid), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+
+	if q, err := Load(filepath.Join(dir, "missing.yaml")); err != nil || q != nil {
+		t.Errorf("expected a missing quarantine to quarantine nothing, got %v and %v", q, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	expires := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	q := Quarantine{
+		{Addon: "kommander", Issue: "https://example.com/1", Expires: expires},
+		{Check: "kommander ui is served", Scenario: "kommander-full-ha", Issue: "https://example.com/2", Expires: expires},
+	}
+
+	if e, ok := q.Lookup("kommander-full-ha", "kommander ui is served", "kommander"); !ok || e.Issue != "https://example.com/2" {
+		t.Errorf("expected the entry of the check, got %+v", e)
+	}
+	if e, ok := q.Lookup("kommander", "kommander ui is served", "kommander"); !ok || e.Issue != "https://example.com/1" {
+		t.Errorf("expected the entry of the addon in other scenarios, got %+v", e)
+	}
+	if _, ok := q.Lookup("kommander", "thanos is queried", "thanos"); ok {
+		t.Errorf("expected checks of other addons not to be quarantined")
+	}
+
+	if expired := q.Expired(expires.Add(23 * time.Hour)); len(expired) != 0 {
+		t.Errorf("expected entries to be quarantined until the end of their expiry date, got %v", expired)
+	}
+	if expired := q.Expired(expires.AddDate(0, 0, 1)); len(expired) != 2 {
+		t.Errorf("expected both entries to expire, got %v", expired)
+	}
+}
@@ -0,0 +1,344 @@
+package quota
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/throttle"
+)
+
+// Name is the name of the ResourceQuota and LimitRange of an envelope.
+const Name = "quota-envelope"
+
+// Envelope is a ResourceQuota and LimitRange which are applied to every
+// namespace addons are deployed to, e.g. as customers typically configure
+// them.
+type Envelope struct {
+	// Name is the name of the envelope, taken from its file name.
+	Name string `yaml:"-"`
+
+	Description string `yaml:"description"`
+
+	// Quota are the hard limits of the ResourceQuota, e.g. requests.cpu: "4".
+	Quota map[string]string `yaml:"quota"`
+
+	// Limits are the container limits of the LimitRange.
+	Limits Limits `yaml:"limits"`
+}
+
+// Limits are the limits of a LimitRange for containers, of cpu and memory.
+type Limits struct {
+	Default        map[string]string `yaml:"default"`
+	DefaultRequest map[string]string `yaml:"defaultRequest"`
+	Max            map[string]string `yaml:"max"`
+}
+
+// Load reads the envelope with the given name from <dir>/<name>.yaml.
+func Load(dir, name string) (*Envelope, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("quota envelope %s does not exist in %s", name, dir)
+		}
+		return nil, err
+	}
+
+	e := &Envelope{}
+	if err := yaml.UnmarshalStrict(b, e); err != nil {
+		return nil, fmt.Errorf("could not parse quota envelope %s: %w", name, err)
+	}
+	e.Name = name
+
+	for _, values := range []map[string]string{e.Quota, e.Limits.Default, e.Limits.DefaultRequest, e.Limits.Max} {
+		for resource, value := range values {
+			if _, err := ParseQuantity(value); err != nil {
+				return nil, fmt.Errorf("quota envelope %s: invalid quantity of %s: %w", name, resource, err)
+			}
+		}
+	}
+	return e, nil
+}
+
+// Manifests returns the ResourceQuota and LimitRange of the envelope for a
+// namespace, along with the namespace itself.
+func (e *Envelope) Manifests(namespace string) ([]byte, error) {
+	labels := map[string]interface{}{"kubeaddons-kommander.mesosphere.io/quota-envelope": e.Name}
+	metadata := map[string]interface{}{"name": Name, "namespace": namespace, "labels": labels}
+
+	items := []interface{}{
+		map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": namespace},
+		},
+	}
+	if len(e.Quota) > 0 {
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ResourceQuota",
+			"metadata":   metadata,
+			"spec":       map[string]interface{}{"hard": e.Quota},
+		})
+	}
+
+	limit := map[string]interface{}{"type": "Container"}
+	for key, values := range map[string]map[string]string{"default": e.Limits.Default, "defaultRequest": e.Limits.DefaultRequest, "max": e.Limits.Max} {
+		if len(values) > 0 {
+			limit[key] = values
+		}
+	}
+	if len(limit) > 1 {
+		items = append(items, map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "LimitRange",
+			"metadata":   metadata,
+			"spec":       map[string]interface{}{"limits": []interface{}{limit}},
+		})
+	}
+
+	return json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+}
+
+// Apply applies the envelope to the namespaces, creating them if necessary.
+func (e *Envelope) Apply(k kubectl.Client, namespaces []string) error {
+	for _, namespace := range namespaces {
+		b, err := e.Manifests(namespace)
+		if err != nil {
+			return err
+		}
+		if err := k.Apply(b); err != nil {
+			return fmt.Errorf("could not apply quota envelope %s to namespace %s: %w", e.Name, namespace, err)
+		}
+	}
+	return nil
+}
+
+// Remove removes the envelope from the namespaces.
+func Remove(k kubectl.Client, namespaces []string) error {
+	for _, namespace := range namespaces {
+		if err := k.Run("delete", "resourcequota,limitrange", Name, "--namespace", namespace, "--ignore-not-found"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Excess is a resource of a namespace (or a container) of which the addons
+// request more than the envelope allows.
+type Excess struct {
+	Namespace string
+	Addons    []string
+	Resource  string
+	Requested int64
+	Hard      int64
+
+	// Container is set for excesses of the maximum of a container.
+	Container string
+}
+
+func (e Excess) String() string {
+	requested, hard := FormatQuantity(e.Resource, e.Requested), FormatQuantity(e.Resource, e.Hard)
+	if e.Container != "" {
+		return fmt.Sprintf("container %s of addon %s in namespace %s sets %s %s, the maximum is %s", e.Container, e.Addons[0], e.Namespace, e.Resource, requested, hard)
+	}
+	return fmt.Sprintf("addons %s request %s %s in namespace %s, the quota is %s", strings.Join(e.Addons, ", "), e.Resource, requested, e.Namespace, hard)
+}
+
+// Check estimates the resources which the rendered objects of addons request
+// once deployed to a cluster with the given number of nodes (defaulting
+// containers without requests and limits to those of the envelope) and returns
+// the resources which exceed the envelope: those of which an addon alone
+// requests more than the quota and those of which all addons together in a
+// namespace request more, along with containers above the maximum.
+func (e *Envelope) Check(objects []render.Object, nodes int) []Excess {
+	// the resources requested by each addon in each namespace
+	usage := make(map[string]map[string]map[string]int64)
+	var excesses []Excess
+
+	for _, obj := range objects {
+		containers := obj.Containers()
+		if len(containers) == 0 {
+			continue
+		}
+		if usage[obj.Namespace] == nil {
+			usage[obj.Namespace] = make(map[string]map[string]int64)
+		}
+		addonUsage := usage[obj.Namespace][obj.Addon]
+		if addonUsage == nil {
+			addonUsage = make(map[string]int64)
+			usage[obj.Namespace][obj.Addon] = addonUsage
+		}
+
+		pods := int64(throttle.Pods([]render.Object{obj}, nodes))
+		addonUsage["pods"] += pods
+		for _, container := range containers {
+			resources := e.resources(container)
+			for resource, value := range resources {
+				addonUsage[resource] += pods * value
+			}
+
+			name, _ := container["name"].(string)
+			for _, resource := range []string{"cpu", "memory"} {
+				max, ok := e.Limits.Max[resource]
+				if !ok {
+					continue
+				}
+				hard, _ := ParseQuantity(max)
+				if limit := resources["limits."+resource]; limit > hard {
+					excesses = append(excesses, Excess{Namespace: obj.Namespace, Addons: []string{obj.Addon}, Resource: "limits." + resource, Requested: limit, Hard: hard, Container: name})
+				}
+			}
+		}
+	}
+
+	namespaces := make([]string, 0, len(usage))
+	for namespace := range usage {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	resources := make([]string, 0, len(e.Quota))
+	for resource := range e.Quota {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	for _, namespace := range namespaces {
+		addons := make([]string, 0, len(usage[namespace]))
+		for addon := range usage[namespace] {
+			addons = append(addons, addon)
+		}
+		sort.Strings(addons)
+
+		for _, resource := range resources {
+			hard, _ := ParseQuantity(e.Quota[resource])
+			var total int64
+			var requesting []string
+			for _, addon := range addons {
+				requested := usage[namespace][addon][resource]
+				if requested > hard {
+					excesses = append(excesses, Excess{Namespace: namespace, Addons: []string{addon}, Resource: resource, Requested: requested, Hard: hard})
+				}
+				if requested > 0 {
+					total += requested
+					requesting = append(requesting, addon)
+				}
+			}
+			if len(requesting) > 1 && total > hard {
+				excesses = append(excesses, Excess{Namespace: namespace, Addons: requesting, Resource: resource, Requested: total, Hard: hard})
+			}
+		}
+	}
+
+	return excesses
+}
+
+// resources returns the requests and limits of a container (as
+// requests.<resource> and limits.<resource>, in milli units), defaulted like
+// the LimitRange of the envelope defaults them.
+func (e *Envelope) resources(container map[string]interface{}) map[string]int64 {
+	spec, _ := container["resources"].(map[string]interface{})
+	requests, _ := spec["requests"].(map[string]interface{})
+	limits, _ := spec["limits"].(map[string]interface{})
+
+	quantity := func(values map[string]interface{}, resource string) (int64, bool) {
+		switch v := values[resource].(type) {
+		case string:
+			q, err := ParseQuantity(v)
+			return q, err == nil
+		case int:
+			return int64(v) * 1000, true
+		case float64:
+			return int64(v * 1000), true
+		}
+		return 0, false
+	}
+	defaulted := func(values map[string]string, resource string) (int64, bool) {
+		if v, ok := values[resource]; ok {
+			q, err := ParseQuantity(v)
+			return q, err == nil
+		}
+		return 0, false
+	}
+
+	resources := make(map[string]int64)
+	for _, resource := range []string{"cpu", "memory"} {
+		// as in kubernetes, requests default to the limit of the container,
+		// then to the default request, then to the default limit
+		limit, hasLimit := quantity(limits, resource)
+		request, hasRequest := quantity(requests, resource)
+		if !hasRequest {
+			request, hasRequest = limit, hasLimit
+		}
+		if !hasLimit {
+			limit, hasLimit = defaulted(e.Limits.Default, resource)
+		}
+		if hasLimit {
+			resources["limits."+resource] = limit
+		}
+		if !hasRequest {
+			request, hasRequest = defaulted(e.Limits.DefaultRequest, resource)
+		}
+		if !hasRequest {
+			request, hasRequest = limit, hasLimit
+		}
+		if hasRequest {
+			// quotas of "cpu" and "memory" are quotas of requests
+			resources["requests."+resource] = request
+			resources[resource] = request
+		}
+	}
+	return resources
+}
+
+// suffixes are the multipliers of the suffixes of quantities.
+var suffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40},
+	{"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12},
+	{"m", 1e-3},
+}
+
+// ParseQuantity parses a Kubernetes quantity (e.g. "500m", "2", "128Mi") and
+// returns it in milli units.
+func ParseQuantity(s string) (int64, error) {
+	multiplier := 1.0
+	number := s
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix.suffix) {
+			multiplier = suffix.multiplier
+			number = strings.TrimSuffix(s, suffix.suffix)
+			break
+		}
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return int64(f * multiplier * 1000), nil
+}
+
+// FormatQuantity formats a quantity in milli units for a resource: cpu in
+// millicores, memory in Mi and other resources as plain numbers.
+func FormatQuantity(resource string, milli int64) string {
+	switch {
+	case strings.HasSuffix(resource, "cpu"):
+		return fmt.Sprintf("%dm", milli)
+	case strings.HasSuffix(resource, "memory"):
+		return fmt.Sprintf("%dMi", milli/1000/(1<<20))
+	default:
+		return strconv.FormatInt(milli/1000, 10)
+	}
+}
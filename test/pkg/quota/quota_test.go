@@ -0,0 +1,124 @@
+package quota
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestParseQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected int64
+		invalid  bool
+	}{
+		{in: "500m", expected: 500},
+		{in: "2", expected: 2000},
+		{in: "1.5", expected: 1500},
+		{in: "128Mi", expected: 128 << 20 * 1000},
+		{in: "1G", expected: 1e12},
+		{in: "lots", invalid: true},
+		{in: "-1", invalid: true},
+	} {
+		actual, err := ParseQuantity(tc.in)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("expected %q to be invalid", tc.in)
+			}
+			continue
+		}
+		if err != nil || actual != tc.expected {
+			t.Errorf("ParseQuantity(%q): expected %d, got %d (%v)", tc.in, tc.expected, actual, err)
+		}
+	}
+}
+
+func deployment(addon, namespace, name string, replicas int, resources map[string]interface{}) render.Object {
+	container := map[string]interface{}{"name": name}
+	if resources != nil {
+		container["resources"] = resources
+	}
+	return render.Object{
+		Addon:     addon,
+		Kind:      "Deployment",
+		Name:      name,
+		Namespace: namespace,
+		Content: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": replicas,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{"containers": []interface{}{container}},
+				},
+			},
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	envelope := &Envelope{
+		Name:  "small",
+		Quota: map[string]string{"requests.cpu": "1", "pods": "10"},
+		Limits: Limits{
+			Default:        map[string]string{"cpu": "200m"},
+			DefaultRequest: map[string]string{"cpu": "100m"},
+			Max:            map[string]string{"cpu": "1"},
+		},
+	}
+
+	objects := []render.Object{
+		// 3 x 400m
+		deployment("kommander", "kommander", "kommander-ui", 3, map[string]interface{}{
+			"requests": map[string]interface{}{"cpu": "400m"},
+			"limits":   map[string]interface{}{"cpu": "2"},
+		}),
+		// 2 x 100m (defaulted)
+		deployment("dex", "kubeaddons", "dex", 2, nil),
+		// 5 x 200m (defaulted to the limit)
+		deployment("traefik", "kubeaddons", "traefik", 5, map[string]interface{}{
+			"limits": map[string]interface{}{"cpu": "200m"},
+		}),
+	}
+
+	excesses := envelope.Check(objects, 1)
+	expected := []string{
+		"container kommander-ui of addon kommander in namespace kommander sets limits.cpu 2000m, the maximum is 1000m",
+		"addons kommander request requests.cpu 1200m in namespace kommander, the quota is 1000m",
+		// traefik alone meets but doesn't exceed the quota
+		"addons dex, traefik request requests.cpu 1200m in namespace kubeaddons, the quota is 1000m",
+	}
+
+	if len(excesses) != len(expected) {
+		t.Fatalf("expected %d excesses, got %v", len(expected), excesses)
+	}
+	for i := range expected {
+		if excesses[i].String() != expected[i] {
+			t.Errorf("expected %q, got %q", expected[i], excesses[i].String())
+		}
+	}
+}
+
+func TestManifests(t *testing.T) {
+	b, err := (&Envelope{Name: "small", Quota: map[string]string{"pods": "10"}}).Manifests("kommander")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var list struct {
+		Items []struct {
+			Kind string `json:"kind"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []string
+	for _, item := range list.Items {
+		kinds = append(kinds, item.Kind)
+	}
+	// no LimitRange without limits
+	if strings.Join(kinds, ",") != "Namespace,ResourceQuota" {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+}
@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/images"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// Config is the configuration of the validation of the images of the addons
+// for private registries (see registries.yaml).
+type Config struct {
+	// PullPolicies are the pull policies containers may pull their images
+	// with. Containers without one pull with the default of kubernetes:
+	// Always for latest (or no) tags, IfNotPresent otherwise.
+	PullPolicies []string `yaml:"pullPolicies"`
+
+	// Default is the override of the registry of addons which are not
+	// listed.
+	Default Override `yaml:"default"`
+
+	Addons map[string]Override `yaml:"addons"`
+}
+
+// Override is how the registry of the images of an addon is overridden with
+// the values of its chart.
+type Override struct {
+	// Registry are the values (dot separated paths, e.g.
+	// global.imageRegistry) which are set to the registry.
+	Registry []string `yaml:"registry"`
+
+	// Repositories are the values of image repositories (e.g.
+	// kubeaddons-catalog.image.repository) which are prefixed with the
+	// registry, replacing the registry they name.
+	Repositories []string `yaml:"repositories"`
+
+	// Hardcoded are the images (without their tag) which can not be pulled
+	// from another registry, with the reason.
+	Hardcoded map[string]string `yaml:"hardcoded"`
+}
+
+// Load reads the configuration from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse registry configuration %s: %w", path, err)
+	}
+	if len(c.PullPolicies) == 0 {
+		return nil, fmt.Errorf("%s: no pull policies are allowed", path)
+	}
+	for _, policy := range c.PullPolicies {
+		if policy != "Always" && policy != "IfNotPresent" && policy != "Never" {
+			return nil, fmt.Errorf("%s: unknown pull policy %s", path, policy)
+		}
+	}
+	for name, o := range c.Addons {
+		if len(o.Registry) == 0 && len(o.Repositories) == 0 && len(o.Hardcoded) == 0 {
+			return nil, fmt.Errorf("%s: addon %s overrides no values", path, name)
+		}
+	}
+	return c, nil
+}
+
+// Override returns the override of the registry of an addon.
+func (c *Config) Override(addon string) Override {
+	if o, ok := c.Addons[addon]; ok {
+		return o
+	}
+	return c.Default
+}
+
+// PullPolicy returns the pull policy of a container, the default of kubernetes
+// if it has none.
+func PullPolicy(container map[string]interface{}) string {
+	if policy, ok := container["imagePullPolicy"].(string); ok && policy != "" {
+		return policy
+	}
+	image, _ := container["image"].(string)
+	if ref, err := images.Parse(image); err == nil && ref.Tag == "latest" {
+		return "Always"
+	}
+	return "IfNotPresent"
+}
+
+// PullPolicyViolations returns the containers of objects which pull their
+// images with a pull policy which is not allowed.
+func (c *Config) PullPolicyViolations(objects []render.Object) []string {
+	var violations []string
+	for _, obj := range objects {
+		for _, container := range obj.Containers() {
+			policy := PullPolicy(container)
+			if !contains(c.PullPolicies, policy) {
+				violations = append(violations, fmt.Sprintf("container %v of %s %s (addon %s) pulls %v with pull policy %s, expected one of %s",
+					container["name"], obj.Kind, obj.Name, obj.Addon, container["image"], policy, strings.Join(c.PullPolicies, ", ")))
+			}
+		}
+	}
+	return violations
+}
+
+// Values returns the values (YAML) which override the registry of the images
+// of an addon. The repositories are looked up in the values of the addon
+// merged over the defaults of its chart.
+func (o Override) Values(registry string, current map[string]interface{}) (string, error) {
+	overlay := make(map[string]interface{})
+	for _, path := range o.Registry {
+		set(overlay, path, registry)
+	}
+	if _, err := o.prefix(overlay, registry, current, func(images.Reference) bool { return true }); err != nil {
+		return "", err
+	}
+	return marshal(overlay)
+}
+
+// Mirror returns the values (YAML) which pull the images of the repositories
+// of an addon which are pulled from a registry (e.g. docker.io) from a mirror
+// of it instead, and the paths of the repositories. Images of other registries
+// are left alone, as are the registry values which would move all of them.
+func (o Override) Mirror(mirror, registry string, current map[string]interface{}) (string, []string, error) {
+	overlay := make(map[string]interface{})
+	paths, err := o.prefix(overlay, mirror, current, func(ref images.Reference) bool { return ref.Registry == registry })
+	if err != nil {
+		return "", nil, err
+	}
+	s, err := marshal(overlay)
+	return s, paths, err
+}
+
+// prefix sets the repositories selected by the function in the overlay,
+// prefixed with the registry (replacing the registry they name), and returns
+// their paths.
+func (o Override) prefix(overlay map[string]interface{}, registry string, current map[string]interface{}, selected func(images.Reference) bool) ([]string, error) {
+	var paths []string
+	for _, path := range o.Repositories {
+		repository, ok := values.Lookup(current, path)
+		if !ok {
+			return nil, fmt.Errorf("there is no repository %s", path)
+		}
+		s, ok := repository.(string)
+		if !ok {
+			return nil, fmt.Errorf("repository %s is not a string: %v", path, repository)
+		}
+		ref, err := images.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("repository %s: %w", path, err)
+		}
+		if !selected(ref) {
+			continue
+		}
+		set(overlay, path, registry+"/"+ref.Repository)
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func marshal(overlay map[string]interface{}) (string, error) {
+	b, err := yaml.Marshal(overlay)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Remaining returns the images of objects which are not pulled from a
+// registry, other than the images the override expects to be hardcoded,
+// sorted.
+func (o Override) Remaining(objects []render.Object, registry string) []string {
+	var hardcoded []string
+	for _, image := range images.Extract(objects) {
+		if strings.HasPrefix(image, registry+"/") || o.expected(image) || contains(hardcoded, image) {
+			continue
+		}
+		hardcoded = append(hardcoded, image)
+	}
+	sort.Strings(hardcoded)
+	return hardcoded
+}
+
+// expected returns whether an image is expected to be hardcoded. Images are
+// compared without their tag or digest and with the defaults of docker.
+func (o Override) expected(image string) bool {
+	ref, err := images.Parse(image)
+	if err != nil {
+		return false
+	}
+	for name := range o.Hardcoded {
+		if expected, err := images.Parse(name); err == nil && expected.Registry == ref.Registry && expected.Repository == ref.Repository {
+			return true
+		}
+	}
+	return false
+}
+
+// set sets the value at a dot separated path of keys, creating the maps on the
+// way.
+func set(m map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+}
+
+func contains(s []string, value string) bool {
+	for _, v := range s {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
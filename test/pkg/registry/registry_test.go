@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+func deployment(addon, name string, containers ...map[string]interface{}) render.Object {
+	items := make([]interface{}, 0, len(containers))
+	for _, c := range containers {
+		items = append(items, c)
+	}
+	return render.Object{Addon: addon, Kind: "Deployment", Name: name, Content: map[string]interface{}{
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": items}}},
+	}}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for content, valid := range map[string]bool{
+		"pullPolicies: [IfNotPresent]\ndefault:\n  registry: [global.imageRegistry]\n": true,
+		"default:\n  registry: [global.imageRegistry]\n":                               false,
+		"pullPolicies: [Sometimes]\n":                                                  false,
+		"pullPolicies: [IfNotPresent]\naddons:\n  dex: {}\n":                           false,
+		"pullPolicies: [IfNotPresent]\nregistries: [quay.io]\n":                        false,
+	} {
+		path := filepath.Join(dir, "registries.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); (err == nil) != valid {
+			t.Errorf("expected %q to be valid: %t, got %v", content, valid, err)
+		}
+	}
+}
+
+func TestPullPolicyViolations(t *testing.T) {
+	c := &Config{PullPolicies: []string{"IfNotPresent"}}
+	objects := []render.Object{
+		deployment("kommander", "kommander-kubeaddons-catalog",
+			map[string]interface{}{"name": "catalog", "image": "mesosphere/kubeaddons-catalog:v0.8.2", "imagePullPolicy": "IfNotPresent"},
+			map[string]interface{}{"name": "git", "image": "alpine/git"},
+		),
+		deployment("dex", "dex", map[string]interface{}{"name": "dex", "image": "quay.io/dexidp/dex:v2.22.0", "imagePullPolicy": "Always"}),
+		{Addon: "dex", Kind: "ConfigMap", Name: "dex"},
+	}
+
+	violations := c.PullPolicyViolations(objects)
+	if len(violations) != 2 {
+		t.Fatalf("expected the untagged and the always pulled image to violate the pull policies, got %v", violations)
+	}
+	for i, expected := range []string{"container git of Deployment kommander-kubeaddons-catalog (addon kommander) pulls alpine/git with pull policy Always", "container dex of Deployment dex"} {
+		if !strings.HasPrefix(violations[i], expected) {
+			t.Errorf("expected %q, got %q", expected, violations[i])
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	current, err := values.Parse(`
+kubeaddons-catalog:
+  image:
+    repository: mesosphere/kubeaddons-catalog
+thanos:
+  image:
+    repository: quay.io/thanos/thanos
+grafana:
+  image: grafana/grafana:6.6.0
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Override{
+		Registry:     []string{"global.imageRegistry"},
+		Repositories: []string{"kubeaddons-catalog.image.repository", "thanos.image.repository"},
+	}
+	overlay, err := o.Values("registry.invalid:5000", current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := values.Parse(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"global":             map[string]interface{}{"imageRegistry": "registry.invalid:5000"},
+		"kubeaddons-catalog": map[string]interface{}{"image": map[string]interface{}{"repository": "registry.invalid:5000/mesosphere/kubeaddons-catalog"}},
+		"thanos":             map[string]interface{}{"image": map[string]interface{}{"repository": "registry.invalid:5000/thanos/thanos"}},
+	}
+	if !reflect.DeepEqual(expected, parsed) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+
+	for _, path := range []string{"karma.image.repository", "grafana.image.repository", "grafana"} {
+		if _, err := (Override{Repositories: []string{path}}).Values("registry.invalid:5000", current); err == nil {
+			t.Errorf("expected an error for repository %s", path)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	current, err := values.Parse(`
+kubeaddons-catalog:
+  image:
+    repository: mesosphere/kubeaddons-catalog
+thanos:
+  image:
+    repository: quay.io/thanos/thanos
+traefik:
+  image: traefik
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := Override{
+		Registry:     []string{"global.imageRegistry"},
+		Repositories: []string{"kubeaddons-catalog.image.repository", "thanos.image.repository", "traefik.image"},
+	}
+	overlay, paths, err := o.Mirror("mirror.gcr.io", "docker.io", current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := values.Parse(overlay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"kubeaddons-catalog": map[string]interface{}{"image": map[string]interface{}{"repository": "mirror.gcr.io/mesosphere/kubeaddons-catalog"}},
+		"traefik":            map[string]interface{}{"image": "mirror.gcr.io/library/traefik"},
+	}
+	if !reflect.DeepEqual(expected, parsed) {
+		t.Errorf("expected %v, got %v", expected, parsed)
+	}
+	if expected := []string{"kubeaddons-catalog.image.repository", "traefik.image"}; !reflect.DeepEqual(expected, paths) {
+		t.Errorf("expected the paths %v, got %v", expected, paths)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	o := Override{Hardcoded: map[string]string{"busybox": "the init container of the chart has no image value"}}
+	objects := []render.Object{
+		deployment("kommander", "kommander-kubeaddons-catalog",
+			map[string]interface{}{"name": "catalog", "image": "registry.invalid:5000/mesosphere/kubeaddons-catalog:v0.8.2"},
+			map[string]interface{}{"name": "init", "image": "docker.io/library/busybox:1.31"},
+		),
+		deployment("kommander", "kommander-thanos", map[string]interface{}{"name": "thanos", "image": "quay.io/thanos/thanos:v0.10.1"}),
+		deployment("kommander", "kommander-karma", map[string]interface{}{"name": "karma", "image": "quay.io/thanos/thanos:v0.10.1"}),
+	}
+	if remaining := o.Remaining(objects, "registry.invalid:5000"); !reflect.DeepEqual(remaining, []string{"quay.io/thanos/thanos:v0.10.1"}) {
+		t.Errorf("expected the image of thanos to be hardcoded, got %v", remaining)
+	}
+}
@@ -0,0 +1,416 @@
+package render
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// Object is a single Kubernetes resource rendered from an addon's chart.
+type Object struct {
+	Addon      string
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+	Content    map[string]interface{}
+
+	// Source is the template of the chart the object was rendered from (e.g.
+	// traefik/crds/crd.yaml), if known.
+	Source string
+}
+
+// Addon renders the chart referenced by an addon (including any values set on
+// the addon) using the helm CLI and returns the resulting objects. Addons which
+// are not deployed via a helm chart render no objects.
+func Addon(addon v1beta1.AddonInterface) ([]Object, error) {
+	spec := addon.GetAddonSpec()
+	if spec.ChartReference == nil {
+		return nil, nil
+	}
+	ref := spec.ChartReference
+
+	namespace := Namespace(addon)
+	chartArgs, cleanup, err := ChartArgs(addon)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	args := append([]string{"template", ReleaseName(addon), ref.Chart, "--namespace", namespace, "--include-crds"}, chartArgs...)
+
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	cmd := exec.Command("helm", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not render addon %s: %w: %s", addon.GetName(), err, stderr.String())
+	}
+
+	objects, err := Decode(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode rendered manifests for addon %s: %w", addon.GetName(), err)
+	}
+
+	for i := range objects {
+		objects[i].Addon = addon.GetName()
+		if objects[i].Namespace == "" && namespaced(objects[i].Kind) {
+			objects[i].Namespace = namespace
+		}
+	}
+
+	return objects, nil
+}
+
+// ReleaseName returns the name of the helm release of an addon, as named by the
+// kubeaddons controller.
+func ReleaseName(addon v1beta1.AddonInterface) string {
+	return addon.GetName() + "-kubeaddons"
+}
+
+// Namespace returns the namespace an addon is deployed to.
+func Namespace(addon v1beta1.AddonInterface) string {
+	if ns := addon.GetAddonSpec().Namespace; ns != nil {
+		return *ns
+	}
+	return "default"
+}
+
+// ChartArgs returns the helm arguments which select the chart version,
+// repository and values of an addon. The values are written to a temporary
+// file which is removed by the returned function.
+func ChartArgs(addon v1beta1.AddonInterface) ([]string, func(), error) {
+	ref := addon.GetAddonSpec().ChartReference
+	args := []string{"--version", ref.Version}
+	if ref.Repo != nil {
+		args = append(args, "--repo", *ref.Repo)
+	}
+	if ref.Values == nil {
+		return args, func() {}, nil
+	}
+
+	valuesFile, err := ioutil.TempFile("", fmt.Sprintf("%s-values-*.yaml", addon.GetName()))
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.Remove(valuesFile.Name()) }
+
+	if _, err := valuesFile.WriteString(*ref.Values); err != nil {
+		valuesFile.Close()
+		cleanup()
+		return nil, nil, err
+	}
+	if err := valuesFile.Close(); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return append(args, "--values", valuesFile.Name()), cleanup, nil
+}
+
+// Decode reads a multi-document YAML stream and returns the Kubernetes objects
+// it contains, with the templates they were rendered from as given by the
+// "# Source:" comments of helm. Empty documents (e.g. templates which render
+// nothing) and List kinds are flattened.
+func Decode(r io.Reader) ([]Object, error) {
+	var objects []Object
+
+	decoder := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		content := make(map[string]interface{})
+		if err := doc.Decode(&content); err != nil {
+			return nil, err
+		}
+		if len(content) == 0 {
+			continue
+		}
+		source := templateSource(&doc)
+
+		if kind, _ := content["kind"].(string); kind == "List" {
+			items, _ := content["items"].([]interface{})
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					obj := newObject(m)
+					obj.Source = source
+					objects = append(objects, obj)
+				}
+			}
+			continue
+		}
+
+		obj := newObject(content)
+		obj.Source = source
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
+
+// templateSource returns the template of the "# Source:" comment helm heads
+// every rendered document with, empty if there is none.
+func templateSource(doc *yaml.Node) string {
+	comments := doc.HeadComment
+	if len(doc.Content) > 0 {
+		comments += "\n" + doc.Content[0].HeadComment
+		if len(doc.Content[0].Content) > 0 {
+			comments += "\n" + doc.Content[0].Content[0].HeadComment
+		}
+	}
+	for _, line := range strings.Split(comments, "\n") {
+		if source := strings.TrimPrefix(strings.TrimSpace(line), "# Source: "); source != strings.TrimSpace(line) {
+			return source
+		}
+	}
+	return ""
+}
+
+func newObject(content map[string]interface{}) Object {
+	obj := Object{Content: content}
+	obj.APIVersion, _ = content["apiVersion"].(string)
+	obj.Kind, _ = content["kind"].(string)
+	if metadata, ok := content["metadata"].(map[string]interface{}); ok {
+		obj.Name, _ = metadata["name"].(string)
+		obj.Namespace, _ = metadata["namespace"].(string)
+	}
+	return obj
+}
+
+// podSpecPaths are the kinds which carry a pod template, mapped to the path of
+// the pod spec within the object.
+var podSpecPaths = map[string][]string{
+	"Pod":         {"spec"},
+	"Deployment":  {"spec", "template", "spec"},
+	"DaemonSet":   {"spec", "template", "spec"},
+	"StatefulSet": {"spec", "template", "spec"},
+	"ReplicaSet":  {"spec", "template", "spec"},
+	"Job":         {"spec", "template", "spec"},
+	"CronJob":     {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// PodSpec returns the pod spec of objects which run pods (pods and workloads
+// with a pod template).
+func (o Object) PodSpec() (map[string]interface{}, bool) {
+	path, ok := podSpecPaths[o.Kind]
+	if !ok {
+		return nil, false
+	}
+
+	podSpec := o.Content
+	for _, key := range path {
+		next, ok := podSpec[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		podSpec = next
+	}
+
+	return podSpec, true
+}
+
+// Containers returns the init containers and containers of objects which run
+// pods.
+func (o Object) Containers() []map[string]interface{} {
+	podSpec, ok := o.PodSpec()
+	if !ok {
+		return nil
+	}
+
+	var containers []map[string]interface{}
+	for _, field := range []string{"initContainers", "containers"} {
+		items, _ := podSpec[field].([]interface{})
+		for _, item := range items {
+			if container, ok := item.(map[string]interface{}); ok {
+				containers = append(containers, container)
+			}
+		}
+	}
+
+	return containers
+}
+
+// clusterScopedKinds are the kinds commonly shipped by addons which are not
+// namespaced, and therefore must not be defaulted to the addon's namespace.
+var clusterScopedKinds = map[string]bool{
+	"APIService":                     true,
+	"ClusterIssuer":                  true,
+	"ClusterRole":                    true,
+	"ClusterRoleBinding":             true,
+	"CustomResourceDefinition":       true,
+	"MutatingWebhookConfiguration":   true,
+	"Namespace":                      true,
+	"PersistentVolume":               true,
+	"PodSecurityPolicy":              true,
+	"PriorityClass":                  true,
+	"StorageClass":                   true,
+	"ValidatingWebhookConfiguration": true,
+}
+
+func namespaced(kind string) bool {
+	return !clusterScopedKinds[kind]
+}
+
+// Chart is the default configuration of a chart.
+type Chart struct {
+	// Values are the default values of the chart, including the default values
+	// of its subcharts (under the name of each subchart).
+	Values map[string]interface{}
+
+	// Schema is the JSON schema of the chart's values, if the chart has one.
+	Schema []byte
+
+	// Readme is the README of the chart, which documents its values, if the
+	// chart has one.
+	Readme []byte
+}
+
+// charts caches the pulled charts by repository, name and version.
+var charts = struct {
+	sync.Mutex
+	pulled map[string]*Chart
+}{pulled: make(map[string]*Chart)}
+
+// PullChart downloads a chart using the helm CLI and loads its default
+// configuration. Every version of a chart is only pulled once per run, so the
+// returned chart is shared and must not be modified.
+func PullChart(ref *v1beta1.ChartReference) (*Chart, error) {
+	key := ref.Chart + "@" + ref.Version
+	if ref.Repo != nil {
+		key = *ref.Repo + "/" + key
+	}
+	charts.Lock()
+	defer charts.Unlock()
+	if chart, ok := charts.pulled[key]; ok {
+		return chart, nil
+	}
+
+	dir, err := ioutil.TempDir("", "chart-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"pull", ref.Chart, "--version", ref.Version, "--untar", "--untardir", dir}
+	if ref.Repo != nil {
+		args = append(args, "--repo", *ref.Repo)
+	}
+
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command("helm", args...)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("could not pull chart %s: %w: %s", ref.Chart, err, stderr.String())
+	}
+
+	chart, err := loadChart(filepath.Join(dir, filepath.Base(ref.Chart)))
+	if err != nil {
+		return nil, err
+	}
+	charts.pulled[key] = chart
+	return chart, nil
+}
+
+func loadChart(dir string) (*Chart, error) {
+	chart := &Chart{Values: make(map[string]interface{})}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "values.yaml"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &chart.Values); err != nil {
+		return nil, fmt.Errorf("could not parse default values of chart %s: %w", dir, err)
+	}
+
+	if chart.Schema, err = ioutil.ReadFile(filepath.Join(dir, "values.schema.json")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if chart.Readme, err = ioutil.ReadFile(filepath.Join(dir, "README.md")); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	subcharts, err := ioutil.ReadDir(filepath.Join(dir, "charts"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, subchart := range subcharts {
+		var values map[string]interface{}
+		name := subchart.Name()
+		switch {
+		case subchart.IsDir():
+			loaded, err := loadChart(filepath.Join(dir, "charts", name))
+			if err != nil {
+				return nil, err
+			}
+			values = loaded.Values
+		case strings.HasSuffix(name, ".tgz"):
+			if name, values, err = archivedValues(filepath.Join(dir, "charts", name)); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+
+		if existing, ok := chart.Values[name].(map[string]interface{}); ok {
+			for k, v := range existing {
+				values[k] = v
+			}
+		}
+		chart.Values[name] = values
+	}
+
+	return chart, nil
+}
+
+// archivedValues reads the name and default values of a packaged subchart.
+func archivedValues(path string) (string, map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return "", nil, fmt.Errorf("subchart %s has no values.yaml", path)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		parts := strings.Split(header.Name, "/")
+		if len(parts) != 2 || parts[1] != "values.yaml" {
+			continue
+		}
+
+		values := make(map[string]interface{})
+		if err := yaml.NewDecoder(tr).Decode(&values); err != nil && err != io.EOF {
+			return "", nil, fmt.Errorf("could not parse default values of subchart %s: %w", path, err)
+		}
+		return parts[0], values, nil
+	}
+}
@@ -0,0 +1,221 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MinRegression is the least a phase must slow down by to be a regression, so
+// that the noise of short phases is not reported.
+const MinRegression = 30 * time.Second
+
+// Load reads a report written by Write (report.json).
+func Load(path string) (*Report, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("could not parse report %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Change is a value which differs between two reports. Base is empty for values
+// only in the head report, Head for values only in the base report.
+type Change struct {
+	Name string
+	Base string
+	Head string
+}
+
+// Regression is a phase of a scenario which took longer in the head report.
+type Regression struct {
+	Scenario string
+	Phase    string
+	Base     time.Duration
+	Head     time.Duration
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%s/%s: %s -> %s (+%.0f%%)", r.Scenario, r.Phase, r.Base.Round(time.Second), r.Head.Round(time.Second), 100*(float64(r.Head)/float64(r.Base)-1))
+}
+
+// Diff is the difference between a base report (e.g. of master) and a head
+// report (e.g. of a pull request).
+type Diff struct {
+	// FailingChecks are the <scenario>/<check> which failed in the head report
+	// but not in the base report, FixedChecks the reverse.
+	FailingChecks []string
+	FixedChecks   []string
+
+	// FailingAddons are the <group>/<addon> which failed in the head report
+	// but not in the base report, FixedAddons the reverse.
+	FailingAddons []string
+	FixedAddons   []string
+
+	// Regressions are the phases which slowed down by more than the threshold.
+	Regressions []Regression
+
+	// Revisions are the <group>/<addon> whose revision changed.
+	Revisions []Change
+}
+
+// Compare returns the difference between two reports, where phases which took
+// more than threshold (e.g. 0.2 for 20%) and MinRegression longer in the head
+// report are regressions.
+func Compare(base, head *Report, threshold float64) Diff {
+	var d Diff
+
+	baseChecks, headChecks := checkStatuses(base), checkStatuses(head)
+	d.FailingChecks, d.FixedChecks = changedFailures(baseChecks, headChecks)
+
+	baseAddons, headAddons := addonResults(base), addonResults(head)
+	d.FailingAddons, d.FixedAddons = changedFailures(statuses(baseAddons), statuses(headAddons))
+
+	for _, name := range union(baseAddons, headAddons) {
+		b, h := baseAddons[name].Revision, headAddons[name].Revision
+		if b != h {
+			d.Revisions = append(d.Revisions, Change{Name: name, Base: b, Head: h})
+		}
+	}
+
+	baseDurations, headDurations := phaseDurations(base), phaseDurations(head)
+	keys := make([][2]string, 0, len(headDurations))
+	for key := range headDurations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		b, ok := baseDurations[key]
+		h := headDurations[key]
+		if !ok || b == 0 || h-b < MinRegression || float64(h) <= float64(b)*(1+threshold) {
+			continue
+		}
+		d.Regressions = append(d.Regressions, Regression{Scenario: key[0], Phase: key[1], Base: b, Head: h})
+	}
+
+	return d
+}
+
+// Empty reports whether the reports do not differ.
+func (d Diff) Empty() bool {
+	return len(d.FailingChecks)+len(d.FixedChecks)+len(d.FailingAddons)+len(d.FixedAddons)+len(d.Regressions)+len(d.Revisions) == 0
+}
+
+// String renders the difference as text, one section per kind of change.
+func (d Diff) String() string {
+	if d.Empty() {
+		return "no differences\n"
+	}
+
+	b := new(strings.Builder)
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(b, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(b, "  %s\n", line)
+		}
+	}
+
+	section("newly failing checks", d.FailingChecks)
+	section("newly failing addons", d.FailingAddons)
+	var regressions []string
+	for _, r := range d.Regressions {
+		regressions = append(regressions, r.String())
+	}
+	section("duration regressions", regressions)
+	var revisions []string
+	for _, c := range d.Revisions {
+		base, head := c.Base, c.Head
+		if base == "" {
+			base = "(none)"
+		}
+		if head == "" {
+			head = "(none)"
+		}
+		revisions = append(revisions, fmt.Sprintf("%s: %s -> %s", c.Name, base, head))
+	}
+	section("changed revisions", revisions)
+	section("fixed checks", d.FixedChecks)
+	section("fixed addons", d.FixedAddons)
+
+	return b.String()
+}
+
+func checkStatuses(r *Report) map[string]Status {
+	s := make(map[string]Status, len(r.Checks))
+	for _, check := range r.Checks {
+		s[check.Scenario+"/"+check.Check] = check.Status
+	}
+	return s
+}
+
+func addonResults(r *Report) map[string]AddonResult {
+	results := make(map[string]AddonResult, len(r.Addons))
+	for _, result := range r.Addons {
+		results[result.Group+"/"+result.Addon] = result
+	}
+	return results
+}
+
+func statuses(results map[string]AddonResult) map[string]Status {
+	s := make(map[string]Status, len(results))
+	for name, result := range results {
+		s[name] = result.Status
+	}
+	return s
+}
+
+// changedFailures returns the names which failed in head but not in base, and
+// those which failed in base and passed in head.
+func changedFailures(base, head map[string]Status) ([]string, []string) {
+	var failing, fixed []string
+	for name, status := range head {
+		if status == Failed && base[name] != Failed {
+			failing = append(failing, name)
+		}
+		if status == Passed && base[name] == Failed {
+			fixed = append(fixed, name)
+		}
+	}
+	sort.Strings(failing)
+	sort.Strings(fixed)
+	return failing, fixed
+}
+
+func union(base, head map[string]AddonResult) []string {
+	var names []string
+	for name := range base {
+		names = append(names, name)
+	}
+	for name := range head {
+		if _, ok := base[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// phaseDurations sums the durations of the phases of every scenario, keyed by
+// scenario and phase.
+func phaseDurations(r *Report) map[[2]string]time.Duration {
+	durations := make(map[[2]string]time.Duration)
+	for _, phase := range r.Phases {
+		durations[[2]string{phase.Scenario, phase.Phase}] += phase.Duration
+	}
+	return durations
+}
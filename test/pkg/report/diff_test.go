@@ -0,0 +1,107 @@
+package report
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	base := &Report{
+		Addons: []AddonResult{
+			{Group: "kommander", Addon: "traefik", Revision: "1.7.20-1", Status: Passed},
+			{Group: "kommander", Addon: "kommander", Revision: "1.0.0-16", Status: Failed},
+			{Group: "kommander", Addon: "dex", Revision: "2.22.0-1", Status: Passed},
+		},
+		Checks: []CheckResult{
+			{Scenario: "kommander-minimal", Check: "ui is reachable", Status: Passed},
+			{Scenario: "kommander-minimal", Check: "login works", Status: Failed},
+		},
+		Phases: []Phase{
+			{Scenario: "kommander-minimal", Phase: "deploying", Duration: 4 * time.Minute},
+			{Scenario: "kommander-minimal", Phase: "checking", Duration: 10 * time.Second},
+		},
+	}
+	head := &Report{
+		Addons: []AddonResult{
+			{Group: "kommander", Addon: "traefik", Revision: "1.7.20-1", Status: Failed},
+			{Group: "kommander", Addon: "kommander", Revision: "1.0.0-17", Status: Passed},
+			{Group: "kommander", Addon: "karma", Revision: "1.4.0-1", Status: Passed},
+		},
+		Checks: []CheckResult{
+			{Scenario: "kommander-minimal", Check: "ui is reachable", Status: Failed},
+			{Scenario: "kommander-minimal", Check: "login works", Status: Passed},
+			{Scenario: "kommander-minimal", Check: "new check", Status: Failed},
+		},
+		Phases: []Phase{
+			{Scenario: "kommander-minimal", Phase: "deploying", Duration: 3 * time.Minute},
+			{Scenario: "kommander-minimal", Phase: "deploying", Duration: 2 * time.Minute},
+			{Scenario: "kommander-minimal", Phase: "checking", Duration: 20 * time.Second},
+		},
+	}
+
+	d := Compare(base, head, 0.2)
+	if expected := []string{"kommander-minimal/new check", "kommander-minimal/ui is reachable"}; !reflect.DeepEqual(d.FailingChecks, expected) {
+		t.Errorf("expected failing checks %v, got %v", expected, d.FailingChecks)
+	}
+	if expected := []string{"kommander-minimal/login works"}; !reflect.DeepEqual(d.FixedChecks, expected) {
+		t.Errorf("expected fixed checks %v, got %v", expected, d.FixedChecks)
+	}
+	if expected := []string{"kommander/traefik"}; !reflect.DeepEqual(d.FailingAddons, expected) {
+		t.Errorf("expected failing addons %v, got %v", expected, d.FailingAddons)
+	}
+	if expected := []string{"kommander/kommander"}; !reflect.DeepEqual(d.FixedAddons, expected) {
+		t.Errorf("expected fixed addons %v, got %v", expected, d.FixedAddons)
+	}
+
+	// checking doubled, but by less than MinRegression
+	expectedRegressions := []Regression{{Scenario: "kommander-minimal", Phase: "deploying", Base: 4 * time.Minute, Head: 5 * time.Minute}}
+	if !reflect.DeepEqual(d.Regressions, expectedRegressions) {
+		t.Errorf("expected regressions %v, got %v", expectedRegressions, d.Regressions)
+	}
+	if s := d.Regressions[0].String(); s != "kommander-minimal/deploying: 4m0s -> 5m0s (+25%)" {
+		t.Errorf("unexpected regression: %s", s)
+	}
+
+	expectedRevisions := []Change{
+		{Name: "kommander/dex", Base: "2.22.0-1"},
+		{Name: "kommander/karma", Head: "1.4.0-1"},
+		{Name: "kommander/kommander", Base: "1.0.0-16", Head: "1.0.0-17"},
+	}
+	if !reflect.DeepEqual(d.Revisions, expectedRevisions) {
+		t.Errorf("expected revisions %v, got %v", expectedRevisions, d.Revisions)
+	}
+
+	if Compare(base, head, 0.5).Regressions != nil {
+		t.Error("expected no regressions below a threshold of 50%")
+	}
+	if !Compare(base, base, 0.2).Empty() {
+		t.Error("expected no differences between a report and itself")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.Add(AddonResult{Group: "kommander", Addon: "traefik", Status: Passed})
+	r.AddCheck(CheckResult{Scenario: "kommander-minimal", Check: "ui is reachable", Status: Passed})
+	if err := r.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Addons, r.Addons) || !reflect.DeepEqual(loaded.Checks, r.Checks) {
+		t.Errorf("expected the loaded report to match the written one, got %+v", loaded)
+	}
+}
@@ -0,0 +1,171 @@
+package report
+
+import (
+	"strings"
+)
+
+// FailureType classifies the failure of a scenario by its cause, so that CI
+// pipelines can retry infrastructure failures but fail on product regressions.
+type FailureType string
+
+const (
+	// InfraFailure is a failure to provision the cluster or its prerequisites.
+	InfraFailure FailureType = "infra"
+
+	// RenderFailure is a failure to render or validate the charts of addons.
+	RenderFailure FailureType = "chart-render"
+
+	// DeployFailure is a failure to deploy addons.
+	DeployFailure FailureType = "deploy"
+
+	// ReadinessFailure is a deployment of addons which timed out before they
+	// became ready.
+	ReadinessFailure FailureType = "readiness-timeout"
+
+	// CheckFailure is a failing check (or another verification) of deployed
+	// addons.
+	CheckFailure FailureType = "check-failure"
+
+	// CleanupFailure is a failure to remove addons or leftovers of them.
+	CleanupFailure FailureType = "cleanup"
+
+	// UnknownFailure is a failure in a phase which is not classified.
+	UnknownFailure FailureType = "unknown"
+)
+
+// ExitCodes are the exit codes of the test binary for the types of failures.
+var ExitCodes = map[FailureType]int{
+	InfraFailure:     10,
+	RenderFailure:    11,
+	DeployFailure:    12,
+	ReadinessFailure: 13,
+	CheckFailure:     14,
+	CleanupFailure:   15,
+}
+
+// exitPrecedence orders the types of failures by which determines the exit
+// code: product failures come before infrastructure failures, so that a run is
+// only retried when every failure was caused by the infrastructure.
+var exitPrecedence = []FailureType{RenderFailure, DeployFailure, ReadinessFailure, CheckFailure, CleanupFailure, InfraFailure}
+
+// phaseFailures classifies failures by prefixes of the phases they happened in,
+// the first matching prefix wins.
+var phaseFailures = []struct {
+	prefix  string
+	failure FailureType
+}{
+	{"provisioning", InfraFailure},
+	{"waiting for cluster", InfraFailure},
+	{"deploying controller", InfraFailure},
+	{"pulling images", InfraFailure},
+	{"loading images", InfraFailure},
+	{"applying quota envelope", InfraFailure},
+	{"injecting webhook", InfraFailure},
+	{"restricting egress", InfraFailure},
+	{"rendering", RenderFailure},
+	{"validating", RenderFailure},
+	{"labeling", DeployFailure},
+	{"deploying", DeployFailure},
+	{"redeploying", DeployFailure},
+	{"ordering trial", DeployFailure},
+	{"seeding", DeployFailure},
+	{"waiting for", ReadinessFailure},
+	{"checking", CheckFailure},
+	{"auditing", CheckFailure},
+	{"injecting", CheckFailure},
+	{"partitioning", CheckFailure},
+	{"draining node", CheckFailure},
+	{"running steps", CheckFailure},
+	{"running addon tests", CheckFailure},
+	{"scanning logs", CheckFailure},
+	{"verifying telemetry", CheckFailure},
+	{"watching", CheckFailure},
+	{"analyzing removal", CleanupFailure},
+	{"cleanup", CleanupFailure},
+	{"verifying teardown", CleanupFailure},
+	{"verifying monitoring teardown", CleanupFailure},
+	{"rolling back", CleanupFailure},
+	{"verifying rollback", CleanupFailure},
+}
+
+// timeoutMessages are parts of the errors of deployments which timed out.
+var timeoutMessages = []string{"timed out", "deadline exceeded", "not ready"}
+
+// Failure is the first failure of a scenario.
+type Failure struct {
+	Scenario string      `json:"scenario"`
+	Phase    string      `json:"phase"`
+	Type     FailureType `json:"type"`
+	Message  string      `json:"message,omitempty"`
+}
+
+// Classify returns the type of a failure in a phase with an error message
+// (which may be empty).
+func Classify(phase, message string) FailureType {
+	for _, p := range phaseFailures {
+		if !strings.HasPrefix(phase, p.prefix) {
+			continue
+		}
+		if p.failure == DeployFailure {
+			for _, timeout := range timeoutMessages {
+				if strings.Contains(message, timeout) {
+					return ReadinessFailure
+				}
+			}
+		}
+		return p.failure
+	}
+	return UnknownFailure
+}
+
+// Fail records that a scenario failed in a phase, unless it already failed:
+// later failures are most likely consequences of the first.
+func (r *Report) Fail(scenario, phase, message string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, failure := range r.Failures {
+		if failure.Scenario == scenario {
+			return
+		}
+	}
+	r.Failures = append(r.Failures, Failure{Scenario: scenario, Phase: phase, Type: Classify(phase, message), Message: message})
+}
+
+// Failure returns the failure of a scenario, and whether it failed.
+func (r *Report) Failure(scenario string) (Failure, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, failure := range r.Failures {
+		if failure.Scenario == scenario {
+			return failure, true
+		}
+	}
+	return Failure{Scenario: scenario}, false
+}
+
+// Phase returns the current phase of a scenario, empty if it has none.
+func (r *Report) Phase(scenario string) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := len(r.Phases) - 1; i >= 0; i-- {
+		if r.Phases[i].Scenario == scenario {
+			return r.Phases[i].Phase
+		}
+	}
+	return ""
+}
+
+// ExitCode returns the exit code for the failures of the run (see ExitCodes),
+// zero when none was classified.
+func (r *Report) ExitCode() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, failureType := range exitPrecedence {
+		for _, failure := range r.Failures {
+			if failure.Type == failureType {
+				return ExitCodes[failureType]
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+package report
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	for _, c := range []struct {
+		phase, message string
+		expected       FailureType
+	}{
+		{"provisioning cluster", "", InfraFailure},
+		{"injecting webhook", "", InfraFailure},
+		{"restricting egress", "", InfraFailure},
+		{"injecting network latency", "", CheckFailure},
+		{"validating", "", RenderFailure},
+		{"deploying wave 2/3", "could not install addon dex", DeployFailure},
+		{"ordering trial 2/5", "could not install addon dex", DeployFailure},
+		{"deploying", "addon dex timed out waiting for the condition", ReadinessFailure},
+		{"waiting for dex", "addon dex: job dex-migrate failed", ReadinessFailure},
+		{"checking after partition", "", CheckFailure},
+		{"draining node", "pods of node kind-worker2 were not rescheduled", CheckFailure},
+		{"verifying telemetry", "", CheckFailure},
+		{"running addon tests", "", CheckFailure},
+		{"verifying teardown", "", CleanupFailure},
+		{"verifying monitoring teardown", "", CleanupFailure},
+		{"", "", UnknownFailure},
+	} {
+		if actual := Classify(c.phase, c.message); actual != c.expected {
+			t.Errorf("expected a failure in phase %q with %q to be %s, got %s", c.phase, c.message, c.expected, actual)
+		}
+	}
+}
+
+// TestClassifyPhases covers every phase the harness sets (see setPhase), except
+// those which are only set once the outcome of a scenario is known.
+func TestClassifyPhases(t *testing.T) {
+	for phase, expected := range map[string]FailureType{
+		"provisioning cluster":          InfraFailure,
+		"waiting for cluster":           InfraFailure,
+		"deploying controller":          InfraFailure,
+		"pulling images":                InfraFailure,
+		"loading images":                InfraFailure,
+		"applying quota envelope":       InfraFailure,
+		"injecting webhook":             InfraFailure,
+		"restricting egress":            InfraFailure,
+		"rendering":                     RenderFailure,
+		"validating":                    RenderFailure,
+		"validating provenance":         RenderFailure,
+		"labeling namespaces":           DeployFailure,
+		"deploying":                     DeployFailure,
+		"deploying wave 1/2":            DeployFailure,
+		"deploying prerequisite minio":  DeployFailure,
+		"redeploying dex, traefik":      DeployFailure,
+		"ordering trial 1/3":            DeployFailure,
+		"seeding":                       DeployFailure,
+		"waiting for dex":               ReadinessFailure,
+		"checking":                      CheckFailure,
+		"checking after drain":          CheckFailure,
+		"checking after partition":      CheckFailure,
+		"auditing policies":             CheckFailure,
+		"injecting network latency":     CheckFailure,
+		"partitioning network":          CheckFailure,
+		"draining node":                 CheckFailure,
+		"running addon tests":           CheckFailure,
+		"running steps":                 CheckFailure,
+		"verifying telemetry":           CheckFailure,
+		"scanning logs":                 CheckFailure,
+		"watching":                      CheckFailure,
+		"analyzing removal":             CleanupFailure,
+		"cleanup":                       CleanupFailure,
+		"verifying teardown":            CleanupFailure,
+		"verifying monitoring teardown": CleanupFailure,
+		"rolling back":                  CleanupFailure,
+		"verifying rollback":            CleanupFailure,
+	} {
+		if actual := Classify(phase, ""); actual != expected {
+			t.Errorf("expected a failure in phase %q to be %s, got %s", phase, expected, actual)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	r := New()
+	if code := r.ExitCode(); code != 0 {
+		t.Errorf("expected no exit code without failures, got %d", code)
+	}
+
+	r.Fail("kommander-minimal", "provisioning cluster", "")
+	if code := r.ExitCode(); code != ExitCodes[InfraFailure] {
+		t.Errorf("expected the exit code of infra failures, got %d", code)
+	}
+
+	r.Fail("kommander-full-ha", "checking", "")
+	r.Fail("kommander-full-ha", "cleanup", "")
+	if code := r.ExitCode(); code != ExitCodes[CheckFailure] {
+		t.Errorf("expected product failures to take precedence over infra failures, got %d", code)
+	}
+	if len(r.Failures) != 2 || r.Failures[1].Type != CheckFailure {
+		t.Errorf("expected only the first failure of each scenario to be recorded, got %+v", r.Failures)
+	}
+	if failure, ok := r.Failure("kommander-full-ha"); !ok || failure.Phase != "checking" {
+		t.Errorf("expected the scenario to have failed while checking, got %+v", failure)
+	}
+	if _, ok := r.Failure("kommander-fips"); ok {
+		t.Error("expected no failure of a scenario which did not fail")
+	}
+}
+
+func TestPhase(t *testing.T) {
+	r := New()
+	r.SetPhase("kommander-minimal", "deploying")
+	r.SetPhase("kommander-full-ha", "provisioning cluster")
+	if phase := r.Phase("kommander-minimal"); phase != "deploying" {
+		t.Errorf("expected the current phase to be deploying, got %q", phase)
+	}
+	if phase := r.Phase("kommander-fips"); phase != "" {
+		t.Errorf("expected no phase for a scenario which did not run, got %q", phase)
+	}
+}
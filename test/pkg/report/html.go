@@ -0,0 +1,320 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogsDir is the directory of a report directory with the logs of the addons,
+// as logs/<scenario>/<addon>.log.
+const LogsDir = "logs"
+
+// Log is the log of the pods of an addon deployed by a scenario.
+type Log struct {
+	Scenario string
+	Addon    string
+	Text     string
+}
+
+// WriteLog writes the log of an addon deployed by a scenario to the report
+// directory dir.
+func WriteLog(dir, scenario, addon, text string) error {
+	scenarioDir := filepath.Join(dir, LogsDir, scenario)
+	if err := os.MkdirAll(scenarioDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(scenarioDir, addon+".log"), []byte(text), 0644)
+}
+
+// LoadLogs reads the logs of the addons in the report directory dir, sorted by
+// scenario and addon. A directory without logs has none.
+func LoadLogs(dir string) ([]Log, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, LogsDir, "*", "*.log"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var logs []Log
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, Log{
+			Scenario: filepath.Base(filepath.Dir(path)),
+			Addon:    strings.TrimSuffix(filepath.Base(path), ".log"),
+			Text:     string(b),
+		})
+	}
+	return logs, nil
+}
+
+// htmlReport is what the HTML page of a report renders.
+type htmlReport struct {
+	Started    time.Time
+	Finished   time.Time
+	Duration   time.Duration
+	Statuses   []statusCount
+	Failures   []Failure
+	Addons     []AddonResult
+	Scenarios  []htmlScenario
+	Budgets    []BudgetResult
+	Scale      []ScaleResult
+	Flakes     []flake
+	ValuesDiff string
+}
+
+type statusCount struct {
+	Status Status
+	Count  int
+}
+
+type flake struct {
+	Check    string
+	Failures int
+}
+
+// htmlScenario is a scenario with its phases on the timeline of the run, its
+// checks and the logs of its addons.
+type htmlScenario struct {
+	Name     string
+	Duration time.Duration
+	Failure  *Failure
+	Phases   []htmlPhase
+	Checks   []CheckResult
+	Logs     []Log
+}
+
+// htmlPhase is a phase with its offset from the start of the run and its
+// duration, as percentages of the duration of the run.
+type htmlPhase struct {
+	Phase
+	Offset string
+	Width  string
+}
+
+// WriteHTML renders the report with the logs of its addons as a single HTML
+// page, which embeds its styles and scripts so that it can be opened offline,
+// e.g. from the artifacts of a CI job. Every section can be collapsed, and the
+// search box filters the addons, checks and logs.
+func (r *Report) WriteHTML(w io.Writer, logs []Log) error {
+	valuesDiff := r.ValuesDiff()
+
+	r.lock.Lock()
+	view := htmlReport{
+		Started:    r.Started,
+		Finished:   r.Finished,
+		Duration:   r.Finished.Sub(r.Started).Round(time.Second),
+		Failures:   append([]Failure{}, r.Failures...),
+		Addons:     append([]AddonResult{}, r.Addons...),
+		Budgets:    append([]BudgetResult{}, r.Budgets...),
+		Scale:      append([]ScaleResult{}, r.Scale...),
+		ValuesDiff: valuesDiff,
+	}
+	counts := make(map[Status]int)
+	for _, result := range r.Addons {
+		counts[result.Status]++
+	}
+	for check, failures := range r.Flakes {
+		view.Flakes = append(view.Flakes, flake{Check: check, Failures: failures})
+	}
+	scenarios := r.htmlScenarios(logs)
+	r.lock.Unlock()
+
+	for _, status := range []Status{Passed, Failed, Skipped, Quarantined, ExpectedFailure} {
+		if counts[status] > 0 {
+			view.Statuses = append(view.Statuses, statusCount{Status: status, Count: counts[status]})
+		}
+	}
+	sort.SliceStable(view.Addons, func(i, j int) bool {
+		if view.Addons[i].Group != view.Addons[j].Group {
+			return view.Addons[i].Group < view.Addons[j].Group
+		}
+		return view.Addons[i].Addon < view.Addons[j].Addon
+	})
+	sort.Slice(view.Flakes, func(i, j int) bool { return view.Flakes[i].Check < view.Flakes[j].Check })
+	view.Scenarios = scenarios
+
+	return htmlTemplate.Execute(w, view)
+}
+
+// writeHTML writes the page of the report with the logs in dir to dir as
+// report.html.
+func (r *Report) writeHTML(dir string) error {
+	logs, err := LoadLogs(dir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, "report.html"))
+	if err != nil {
+		return err
+	}
+	if err := r.WriteHTML(f, logs); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// htmlScenarios returns the scenarios of the phases, checks and logs, sorted
+// by name. It must be called with the lock held.
+func (r *Report) htmlScenarios(logs []Log) []htmlScenario {
+	run := r.Finished.Sub(r.Started)
+	percent := func(d time.Duration) string {
+		if run <= 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.2f%%", 100*float64(d)/float64(run))
+	}
+
+	byName := make(map[string]*htmlScenario)
+	scenario := func(name string) *htmlScenario {
+		if s, ok := byName[name]; ok {
+			return s
+		}
+		s := &htmlScenario{Name: name}
+		byName[name] = s
+		return s
+	}
+	for _, phase := range r.Phases {
+		s := scenario(phase.Scenario)
+		s.Duration += phase.Duration
+		s.Phases = append(s.Phases, htmlPhase{Phase: phase, Offset: percent(phase.Started.Sub(r.Started)), Width: percent(phase.Duration)})
+	}
+	for _, check := range r.Checks {
+		s := scenario(check.Scenario)
+		s.Checks = append(s.Checks, check)
+	}
+	for _, log := range logs {
+		s := scenario(log.Scenario)
+		s.Logs = append(s.Logs, log)
+	}
+	for i := range r.Failures {
+		failure := r.Failures[i]
+		scenario(failure.Scenario).Failure = &failure
+	}
+
+	scenarios := make([]htmlScenario, 0, len(byName))
+	for _, s := range byName {
+		s.Duration = s.Duration.Round(time.Second)
+		for i := range s.Phases {
+			s.Phases[i].Duration = s.Phases[i].Duration.Round(time.Second)
+		}
+		scenarios = append(scenarios, *s)
+	}
+	sort.Slice(scenarios, func(i, j int) bool { return scenarios[i].Name < scenarios[j].Name })
+	return scenarios
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Run Report {{.Started.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; vertical-align: top; }
+details { margin: 0.4em 0; }
+details details { margin-left: 1.5em; }
+summary { cursor: pointer; font-weight: bold; }
+pre { background: #f6f6f6; padding: 0.5em; overflow-x: auto; max-height: 40em; }
+#search { width: 30em; padding: 0.3em; margin-bottom: 1em; }
+.passed { color: #1a7f37; }
+.failed, .exceeded { color: #cf222e; }
+.skipped, .quarantined, .expected-failure { color: #9a6700; }
+.timeline { position: relative; height: 1.2em; width: 40em; background: #eee; }
+.timeline div { position: absolute; top: 0; height: 100%; background: #4a90d9; border-right: 1px solid #fff; }
+.hidden { display: none; }
+</style>
+</head>
+<body>
+<h1>Run Report</h1>
+<p>Started {{.Started.Format "2006-01-02 15:04:05 MST"}}, finished {{.Finished.Format "2006-01-02 15:04:05 MST"}} ({{.Duration}}).
+{{range .Statuses}}<span class="{{.Status}}">{{.Count}} {{.Status}}</span> {{end}}</p>
+<input id="search" type="search" placeholder="Search addons, checks and logs">
+
+{{if .Failures}}<details open><summary>Failures ({{len .Failures}})</summary>
+<table>
+<tr><th>Scenario</th><th>Phase</th><th>Type</th><th>Message</th></tr>
+{{range .Failures}}<tr class="searchable"><td>{{.Scenario}}</td><td>{{.Phase}}</td><td>{{.Type}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+<details open><summary>Addons ({{len .Addons}})</summary>
+<table>
+<tr><th>Group</th><th>Addon</th><th>Revision</th><th>Arch</th><th>Status</th><th>Reason</th></tr>
+{{range .Addons}}<tr class="searchable"><td>{{.Group}}</td><td>{{.Addon}}</td><td>{{.Revision}}</td><td>{{.Architecture}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+</details>
+
+<details open><summary>Scenarios ({{len .Scenarios}})</summary>
+{{range .Scenarios}}<details class="searchable"{{if .Failure}} open{{end}}><summary{{if .Failure}} class="failed"{{end}}>{{.Name}} ({{.Duration}})</summary>
+{{with .Failure}}<p class="failed">Failed in phase {{.Phase}} ({{.Type}}){{if .Message}}: {{.Message}}{{end}}</p>
+{{end}}{{if .Phases}}<div class="timeline">{{range .Phases}}<div style="left: {{.Offset}}; width: {{.Width}}" title="{{.Phase.Phase}} ({{.Duration}})"></div>{{end}}</div>
+<table>
+<tr><th>Phase</th><th>Started</th><th>Duration</th></tr>
+{{range .Phases}}<tr><td>{{.Phase.Phase}}</td><td>{{.Started.Format "15:04:05"}}</td><td>{{.Duration}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Checks}}<table>
+<tr><th>Check</th><th>Status</th><th>Reason</th></tr>
+{{range .Checks}}<tr class="searchable"><td>{{.Check}}</td><td class="{{.Status}}">{{.Status}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+{{end}}{{range .Logs}}<details class="searchable"><summary>Logs of {{.Addon}}</summary>
+<pre>{{.Text}}</pre>
+</details>
+{{end}}</details>
+{{end}}</details>
+{{if .Budgets}}
+<details><summary>Budgets</summary>
+<table>
+<tr><th>Scenario</th><th>Groups</th><th>Budget</th><th>Actual</th></tr>
+{{range .Budgets}}<tr class="searchable{{if .Exceeded}} exceeded{{end}}"><td>{{.Scenario}}</td><td>{{range $i, $g := .Groups}}{{if $i}}, {{end}}{{$g}}{{end}}</td><td>{{.Budget}}</td><td>{{.Actual}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}{{if .Scale}}
+<details><summary>Scale</summary>
+<table>
+<tr><th>Scenario</th><th>Kind</th><th>Count</th><th>p50</th><th>p95</th><th>Max</th></tr>
+{{range .Scale}}<tr class="searchable"><td>{{.Scenario}}</td><td>{{.Kind}}</td><td>{{.Count}}</td><td>{{.P50}}</td><td>{{.P95}}</td><td>{{.Max}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}{{if .Flakes}}
+<details><summary>Flakes</summary>
+<table>
+<tr><th>Check</th><th>Failed Attempts</th></tr>
+{{range .Flakes}}<tr class="searchable"><td>{{.Check}}</td><td>{{.Failures}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}{{if .ValuesDiff}}
+<details><summary>Release Values</summary>
+<pre>{{.ValuesDiff}}</pre>
+</details>
+{{end}}
+<script>
+// hides the rows and logs which don't contain the search, and opens the
+// sections of those which do
+document.getElementById("search").addEventListener("input", function (e) {
+  var query = e.target.value.toLowerCase();
+  var searchable = document.querySelectorAll(".searchable");
+  for (var i = 0; i < searchable.length; i++) {
+    var element = searchable[i];
+    var found = element.textContent.toLowerCase().indexOf(query) >= 0;
+    element.classList.toggle("hidden", query !== "" && !found);
+    if (query !== "" && found && element.tagName === "DETAILS") {
+      element.open = true;
+    }
+  }
+});
+</script>
+</body>
+</html>
+`))
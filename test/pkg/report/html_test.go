@@ -0,0 +1,69 @@
+package report
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteHTML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteLog(dir, "kommander-minimal", "traefik", "level=error msg=\"<no backend>\"\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteLog(dir, "kommander-minimal", "dex", "listening on :5556\n"); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := LoadLogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 || logs[0].Addon != "dex" || logs[1].Addon != "traefik" || logs[1].Scenario != "kommander-minimal" {
+		t.Fatalf("unexpected logs %+v", logs)
+	}
+
+	started := time.Date(2020, 6, 1, 10, 0, 0, 0, time.UTC)
+	r := &Report{Started: started, Finished: started.Add(100 * time.Second)}
+	r.Add(AddonResult{Group: "kommander", Addon: "traefik", Revision: "1.7.20-1", Architecture: "amd64", Status: Failed, Reason: "check failed"})
+	r.Add(AddonResult{Group: "kommander", Addon: "dex", Revision: "2.4.0-1", Architecture: "amd64", Status: Passed})
+	r.Phases = []Phase{
+		{Scenario: "kommander-minimal", Phase: "provisioning", Started: started, Duration: 25 * time.Second},
+		{Scenario: "kommander-minimal", Phase: "checking", Started: started.Add(25 * time.Second), Duration: 50 * time.Second},
+	}
+	r.AddCheck(CheckResult{Scenario: "kommander-minimal", Check: "traefik is routing", Status: Failed, Reason: "503"})
+	r.Fail("kommander-minimal", "checking", "traefik is routing: 503")
+
+	b := new(bytes.Buffer)
+	if err := r.WriteHTML(b, logs); err != nil {
+		t.Fatal(err)
+	}
+	page := b.String()
+	for _, expected := range []string{
+		`<span class="passed">1 passed</span>`,
+		`<span class="failed">1 failed</span>`,
+		`<details class="searchable" open><summary class="failed">kommander-minimal (1m15s)</summary>`,
+		`Failed in phase checking (check-failure): traefik is routing: 503`,
+		`style="left: 25.00%; width: 50.00%"`,
+		`<summary>Logs of traefik</summary>`,
+		`level=error msg=&#34;&lt;no backend&gt;&#34;`,
+		`id="search"`,
+	} {
+		if !strings.Contains(page, expected) {
+			t.Errorf("expected the page to contain %q", expected)
+		}
+	}
+	if strings.Index(page, "<td>dex</td>") > strings.Index(page, "<td>traefik</td>") {
+		t.Error("expected the addons to be sorted")
+	}
+	if strings.Contains(page, "src=") || strings.Contains(page, "href=") {
+		t.Error("expected the page not to reference other files")
+	}
+}
@@ -0,0 +1,346 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Status is the outcome of testing an addon.
+type Status string
+
+const (
+	// Passed indicates the addon deployed and all its checks passed.
+	Passed Status = "passed"
+
+	// Failed indicates the addon failed to deploy or a check failed.
+	Failed Status = "failed"
+
+	// Skipped indicates the addon was not deployed.
+	Skipped Status = "skipped"
+
+	// Quarantined indicates a quarantined check failed, which doesn't fail
+	// the run (see quarantine.yaml).
+	Quarantined Status = "quarantined"
+
+	// ExpectedFailure indicates a check failed which is expected to fail on
+	// the kubernetes version (see the expectedFailures of groups.yaml), which
+	// doesn't fail the run.
+	ExpectedFailure Status = "expected-failure"
+)
+
+// AddonResult is the outcome of testing an addon as part of a testing group.
+type AddonResult struct {
+	Scenario     string `json:"scenario,omitempty"`
+	Group        string `json:"group"`
+	Addon        string `json:"addon"`
+	Revision     string `json:"revision"`
+	Architecture string `json:"architecture"`
+	Status       Status `json:"status"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// CheckResult is the outcome of a check of a scenario.
+type CheckResult struct {
+	Scenario string `json:"scenario"`
+	Check    string `json:"check"`
+	Status   Status `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Phase is a phase (e.g. "deploying") of a scenario or testing group.
+type Phase struct {
+	Scenario string        `json:"scenario"`
+	Phase    string        `json:"phase"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"duration"`
+}
+
+// BudgetResult is the duration of a scenario compared with the budget of its
+// testing groups (see groups.yaml).
+type BudgetResult struct {
+	Scenario string        `json:"scenario"`
+	Groups   []string      `json:"groups"`
+	Budget   time.Duration `json:"budget"`
+	Actual   time.Duration `json:"actual"`
+
+	// Exceeded is set when the actual duration exceeds the budget by more
+	// than the configured factor.
+	Exceeded bool `json:"exceeded"`
+}
+
+// ScaleResult is the latency with which the controllers of kommander
+// reconciled the objects of a kind a scale scenario created, by percentile.
+type ScaleResult struct {
+	Scenario string        `json:"scenario"`
+	Kind     string        `json:"kind"`
+	Count    int           `json:"count"`
+	P50      time.Duration `json:"p50"`
+	P95      time.Duration `json:"p95"`
+	Max      time.Duration `json:"max"`
+}
+
+// Rollback is the rollback of the addons of a scenario which failed to deploy
+// (ROLLBACK_ON_FAILURE=true).
+type Rollback struct {
+	Scenario string `json:"scenario"`
+
+	// Failure is the phase the deployment failed in, with its error.
+	Failure string `json:"failure"`
+
+	// Addons are the removed addons, in the order they were removed.
+	Addons []string `json:"addons"`
+
+	// Status is passed when the cluster returned to its baseline.
+	Status Status `json:"status"`
+
+	// Residue are what the addons left behind.
+	Residue []string `json:"residue,omitempty"`
+}
+
+// ReleaseValues are the values of the helm release of an addon deployed by a
+// scenario which differ from the default values of its chart
+// (RELEASE_VALUES=true), with the values of secrets redacted.
+type ReleaseValues struct {
+	Scenario string `json:"scenario"`
+	Addon    string `json:"addon"`
+
+	// Chart is the chart of the release, as <chart> <version>.
+	Chart string `json:"chart"`
+
+	Changes []ValueChange `json:"changes"`
+}
+
+// ValueChange is a value of a release which differs from its default, both as
+// JSON. Default is empty for values the chart doesn't set.
+type ValueChange struct {
+	Path    string `json:"path"`
+	Default string `json:"default,omitempty"`
+	Value   string `json:"value"`
+}
+
+// Report collects the results of a test run. It is safe for concurrent use.
+type Report struct {
+	Started  time.Time     `json:"started"`
+	Finished time.Time     `json:"finished"`
+	Addons   []AddonResult `json:"addons"`
+	Phases   []Phase       `json:"phases"`
+	Checks   []CheckResult `json:"checks,omitempty"`
+	Failures []Failure     `json:"failures,omitempty"`
+
+	// Budgets compares the duration of scenarios with their budgets.
+	Budgets []BudgetResult `json:"budgets,omitempty"`
+
+	// Rollbacks are the rollbacks of scenarios which failed to deploy.
+	Rollbacks []Rollback `json:"rollbacks,omitempty"`
+
+	// Values are the values the addons were deployed with which differ from
+	// the defaults of their charts.
+	Values []ReleaseValues `json:"values,omitempty"`
+
+	// Scale are the latencies measured by scale scenarios.
+	Scale []ScaleResult `json:"scale,omitempty"`
+
+	// Flakes counts the failed attempts of checks which eventually passed.
+	Flakes map[string]int `json:"flakes,omitempty"`
+
+	// Reclaimed lists the docker resources left by test runs which were swept
+	// during this run.
+	Reclaimed []string `json:"reclaimed,omitempty"`
+
+	lock sync.Mutex
+}
+
+// New creates a report for a run starting now.
+func New() *Report {
+	return &Report{Started: time.Now()}
+}
+
+// Add records the result of an addon.
+func (r *Report) Add(result AddonResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Addons = append(r.Addons, result)
+}
+
+// AddCheck records the result of a check.
+func (r *Report) AddCheck(result CheckResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Checks = append(r.Checks, result)
+}
+
+// AddBudget records the duration of a scenario compared with its budget.
+func (r *Report) AddBudget(result BudgetResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Budgets = append(r.Budgets, result)
+}
+
+// AddRollback records the rollback of a scenario.
+func (r *Report) AddRollback(rollback Rollback) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Rollbacks = append(r.Rollbacks, rollback)
+}
+
+// AddValues records the values of the release of an addon.
+func (r *Report) AddValues(values ReleaseValues) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Values = append(r.Values, values)
+}
+
+// AddScale records the latencies measured by a scale scenario.
+func (r *Report) AddScale(result ScaleResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Scale = append(r.Scale, result)
+}
+
+// SetPhase records that a scenario entered a phase, which ends its previous
+// phase.
+func (r *Report) SetPhase(scenario, phase string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	r.endPhase(scenario, now)
+	r.Phases = append(r.Phases, Phase{Scenario: scenario, Phase: phase, Started: now})
+}
+
+func (r *Report) endPhase(scenario string, now time.Time) {
+	for i := len(r.Phases) - 1; i >= 0; i-- {
+		if r.Phases[i].Scenario == scenario {
+			if r.Phases[i].Duration == 0 {
+				r.Phases[i].Duration = now.Sub(r.Phases[i].Started)
+			}
+			return
+		}
+	}
+}
+
+// Flake records the failed attempts of a check which eventually passed.
+func (r *Report) Flake(check string, failures int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.Flakes == nil {
+		r.Flakes = make(map[string]int)
+	}
+	r.Flakes[check] += failures
+}
+
+// Reclaim records docker resources left by test runs which were swept.
+func (r *Report) Reclaim(resources ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Reclaimed = append(r.Reclaimed, resources...)
+}
+
+// Finish marks the report as finished now, ending all phases.
+func (r *Report) Finish() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.finish()
+}
+
+func (r *Report) finish() {
+	r.Finished = time.Now()
+	for i := range r.Phases {
+		if r.Phases[i].Duration == 0 {
+			r.Phases[i].Duration = r.Finished.Sub(r.Phases[i].Started)
+		}
+	}
+}
+
+// Table renders the addon results as a text table.
+func (r *Report) Table() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	results := make([]AddonResult, len(r.Addons))
+	copy(results, r.Addons)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Group != results[j].Group {
+			return results[i].Group < results[j].Group
+		}
+		return results[i].Addon < results[j].Addon
+	})
+
+	b := new(strings.Builder)
+	w := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GROUP\tADDON\tREVISION\tARCH\tSTATUS\tREASON")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Group, result.Addon, result.Revision, result.Architecture, result.Status, result.Reason)
+	}
+	w.Flush()
+
+	return b.String()
+}
+
+// ValuesDiff renders the values of the releases as a text diff against the
+// defaults of their charts, by scenario and addon.
+func (r *Report) ValuesDiff() string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	releases := make([]ReleaseValues, len(r.Values))
+	copy(releases, r.Values)
+	sort.SliceStable(releases, func(i, j int) bool {
+		if releases[i].Scenario != releases[j].Scenario {
+			return releases[i].Scenario < releases[j].Scenario
+		}
+		return releases[i].Addon < releases[j].Addon
+	})
+
+	b := new(strings.Builder)
+	for _, release := range releases {
+		fmt.Fprintf(b, "%s/%s (%s):\n", release.Scenario, release.Addon, release.Chart)
+		for _, change := range release.Changes {
+			if change.Default == "" {
+				fmt.Fprintf(b, "  + %s: %s\n", change.Path, change.Value)
+				continue
+			}
+			fmt.Fprintf(b, "  ~ %s: %s -> %s\n", change.Path, change.Default, change.Value)
+		}
+	}
+	return b.String()
+}
+
+// Write finishes the report and writes it to dir as report.json along with its
+// table as report.txt, the values of the releases as values.txt when they
+// were recorded, and a page with the logs of the addons in dir (see LoadLogs)
+// as report.html.
+func (r *Report) Write(dir string) error {
+	r.lock.Lock()
+	r.finish()
+	b, err := json.MarshalIndent(r, "", "  ")
+	values := len(r.Values) > 0
+	r.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "report.json"), b, 0644); err != nil {
+		return err
+	}
+	if values {
+		if err := ioutil.WriteFile(filepath.Join(dir, "values.txt"), []byte(r.ValuesDiff()), 0644); err != nil {
+			return err
+		}
+	}
+	if err := r.writeHTML(dir); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "report.txt"), []byte(r.Table()), 0644)
+}
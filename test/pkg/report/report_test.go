@@ -0,0 +1,69 @@
+package report
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	r := New()
+	r.Add(AddonResult{Group: "kommander", Addon: "traefik", Revision: "1.7.20-1", Architecture: "amd64", Status: Passed})
+	r.Add(AddonResult{Group: "kommander", Addon: "kommander", Revision: "1.0.0-17", Architecture: "arm64", Status: Skipped, Reason: "no arm64 images"})
+
+	expected := []string{
+		"GROUP      ADDON      REVISION  ARCH   STATUS   REASON",
+		"kommander  kommander  1.0.0-17  arm64  skipped  no arm64 images",
+		"kommander  traefik    1.7.20-1  amd64  passed",
+	}
+	lines := strings.Split(strings.TrimRight(r.Table(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(expected), len(lines), r.Table())
+	}
+	for i := range expected {
+		if strings.TrimRight(lines[i], " ") != expected[i] {
+			t.Errorf("expected line %d to be %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestPhases(t *testing.T) {
+	r := New()
+	r.SetPhase("kommander-minimal", "deploying")
+	r.SetPhase("kommander-scope-cluster", "deploying")
+	r.SetPhase("kommander-minimal", "checking")
+
+	if r.Phases[0].Duration == 0 {
+		t.Error("expected the first phase of kommander-minimal to have ended")
+	}
+	if r.Phases[1].Duration != 0 || r.Phases[2].Duration != 0 {
+		t.Error("expected the phases of other scenarios to still be running")
+	}
+
+	r.Finish()
+	for _, phase := range r.Phases {
+		if phase.Duration == 0 {
+			t.Errorf("expected phase %s of %s to have ended", phase.Phase, phase.Scenario)
+		}
+	}
+}
+
+func TestValuesDiff(t *testing.T) {
+	r := New()
+	r.AddValues(ReleaseValues{Scenario: "kommander-minimal", Addon: "traefik", Chart: "traefik 1.72.1", Changes: []ValueChange{
+		{Path: "replicas", Default: "1", Value: "2"},
+		{Path: "ssl.defaultKey", Default: `""`, Value: `"<redacted>"`},
+	}})
+	r.AddValues(ReleaseValues{Scenario: "kommander-minimal", Addon: "dex", Chart: "dex 2.4.0", Changes: []ValueChange{
+		{Path: "ingress.enabled", Value: "true"},
+	}})
+
+	expected := `kommander-minimal/dex (dex 2.4.0):
+  + ingress.enabled: true
+kommander-minimal/traefik (traefik 1.72.1):
+  ~ replicas: 1 -> 2
+  ~ ssl.defaultKey: "" -> "<redacted>"
+`
+	if diff := r.ValuesDiff(); diff != expected {
+		t.Errorf("expected the diff\n%s\ngot\n%s", expected, diff)
+	}
+}
@@ -0,0 +1,371 @@
+package repro
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// Placeholder is replaced with the directory the bundle is extracted to by the
+// reproduction script, e.g. in the host paths of the kind configuration which
+// mount the node files of the bundle.
+const Placeholder = "@REPRO_DIR@"
+
+// Bundle is the minimal reproduction of a failed scenario: the exact addons it
+// deployed (with the override layers of the scenario merged into their
+// values), the controller they were deployed with and the cluster they were
+// deployed to, along with a script which recreates the failing state on a
+// fresh kind cluster.
+type Bundle struct {
+	Scenario string `yaml:"scenario"`
+
+	// Phase is the phase of the scenario which failed, e.g. "deploying wave
+	// 2/3", and Failure how it failed.
+	Phase   string `yaml:"phase"`
+	Failure string `yaml:"failure"`
+
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+	NodeImage         string `yaml:"nodeImage"`
+
+	// Backend is the backend of the scenario, either "controller" or
+	// "helm".
+	Backend string `yaml:"backend"`
+
+	// ControllerBundle is the bundle of the kubeaddons controller and
+	// ControllerImages the images it ran with. They are empty for the helm
+	// backend.
+	ControllerBundle string   `yaml:"controllerBundle,omitempty"`
+	ControllerImages []string `yaml:"controllerImages,omitempty"`
+
+	// Timeout is how long the script waits for every addon.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Waves are the names of the addons in the order they are deployed,
+	// every wave after the previous one is ready.
+	Waves [][]string `yaml:"waves"`
+
+	// KindConfig is the kind configuration of the cluster, which may refer
+	// to NodeFiles (by their name, under <Placeholder>/nodes).
+	KindConfig []byte            `yaml:"-"`
+	NodeFiles  map[string][]byte `yaml:"-"`
+
+	// Controller are the manifests of the kubeaddons controller (see
+	// ExportController).
+	Controller []byte `yaml:"-"`
+
+	Addons []Addon `yaml:"-"`
+}
+
+// Addon is an addon as it was deployed.
+type Addon struct {
+	Name string
+
+	// Kind is either Addon or ClusterAddon, and Namespace the namespace of
+	// the addon object.
+	Kind      string
+	Namespace string
+
+	// Manifest is the addon object.
+	Manifest []byte
+
+	// Release, ReleaseNamespace, Chart, Repo and Version are the helm release
+	// of the chart of the addon, and Values its merged values.
+	Release          string
+	ReleaseNamespace string
+	Chart            string
+	Repo             string
+	Version          string
+	Values           string
+}
+
+// Write writes the bundle as a gzipped tarball to path: bundle.yaml with the
+// scenario, its failure and the versions it ran with, kind.yaml (and nodes/)
+// with the configuration of the cluster, controller.yaml with the kubeaddons
+// controller, the addon objects in addons/ and the merged values of their
+// charts in values/, and reproduce.sh.
+func (b *Bundle) Write(path string) error {
+	files, err := b.files()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	root := "repro-" + b.Scenario
+	for _, name := range names {
+		mode := int64(0644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0755
+		}
+		header := &tar.Header{Name: root + "/" + name, Mode: mode, Size: int64(len(files[name])), ModTime: time.Now()}
+		if err := tw.WriteHeader(header); err != nil {
+			f.Close()
+			return err
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// files returns the contents of the bundle by their path in it.
+func (b *Bundle) files() (map[string][]byte, error) {
+	metadata, err := yaml.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	script, err := b.script()
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{
+		"bundle.yaml":  metadata,
+		"kind.yaml":    b.KindConfig,
+		"reproduce.sh": script,
+	}
+	for name, content := range b.NodeFiles {
+		files["nodes/"+name] = content
+	}
+	if b.Backend != "helm" {
+		files["controller.yaml"] = b.Controller
+	}
+	for _, addon := range b.Addons {
+		files["addons/"+addon.Name+".yaml"] = addon.Manifest
+		if addon.Values != "" {
+			files["values/"+addon.Name+".yaml"] = []byte(addon.Values)
+		}
+	}
+	return files, nil
+}
+
+// script returns the script which creates a kind cluster named after its
+// first argument (default: repro-<scenario>) and deploys the addons to it wave
+// by wave, like the scenario did.
+func (b *Bundle) script() ([]byte, error) {
+	addons := make(map[string]Addon, len(b.Addons))
+	for _, addon := range b.Addons {
+		addons[addon.Name] = addon
+	}
+	var waves [][]Addon
+	for _, wave := range b.Waves {
+		var addonWave []Addon
+		for _, name := range wave {
+			addon, ok := addons[name]
+			if !ok {
+				return nil, fmt.Errorf("addon %s of the waves is not part of the bundle", name)
+			}
+			addonWave = append(addonWave, addon)
+		}
+		waves = append(waves, addonWave)
+	}
+
+	out := new(bytes.Buffer)
+	err := scriptTemplate.Execute(out, struct {
+		*Bundle
+		AddonWaves  [][]Addon
+		Seconds     int
+		Placeholder string
+	}{b, waves, int(b.Timeout.Seconds()), Placeholder})
+	return out.Bytes(), err
+}
+
+var scriptTemplate = template.Must(template.New("reproduce.sh").Funcs(template.FuncMap{
+	"comment": func(s string) string { return strings.Replace(s, "\n", "\n#   ", -1) },
+	"quote":   quote,
+	"inc":     func(i int) int { return i + 1 },
+}).Parse(`#!/bin/sh
+# Reproduces the failure of scenario {{ .Scenario }} in phase "{{ .Phase }}":
+#   {{ comment .Failure }}
+#
+# usage: ./reproduce.sh [cluster name]
+set -e
+
+cd "$(dirname "$0")"
+CLUSTER=${1:-repro-{{ .Scenario }}}
+KUBECTL="kubectl --context kind-$CLUSTER"
+
+# wait_ready <kind> <namespace> <name> waits for an addon to be ready
+wait_ready() {
+	deadline=$(($(date +%s) + {{ .Seconds }}))
+	until [ "$($KUBECTL get "$1" ${2:+--namespace "$2"} "$3" --output 'jsonpath={.status.ready}')" = true ]; do
+		if [ "$(date +%s)" -gt "$deadline" ]; then
+			echo "$1 $3 is not ready after {{ .Timeout }}" >&2
+			return 1
+		fi
+		sleep 5
+	done
+}
+
+sed "s|{{ .Placeholder }}|$(pwd)|g" kind.yaml > kind.resolved.yaml
+kind create cluster --name "$CLUSTER" --image {{ quote .NodeImage }} --config kind.resolved.yaml --wait 5m
+{{ if eq .Backend "helm" }}
+# the addons are installed as helm releases, as the helm backend does
+{{- range $i, $wave := .AddonWaves }}
+
+echo "deploying wave {{ inc $i }}"
+{{- range $wave }}
+helm --kube-context "kind-$CLUSTER" upgrade {{ quote .Release }} {{ quote .Chart }} --install \
+	--namespace {{ quote .ReleaseNamespace }} --create-namespace --wait --timeout {{ $.Timeout }} \
+	--version {{ quote .Version }}{{ if .Repo }} --repo {{ quote .Repo }}{{ end }}{{ if .Values }} --values values/{{ .Name }}.yaml{{ end }}
+{{- end }}
+{{- end }}
+{{- else }}
+# the kubeaddons controller (bundle {{ .ControllerBundle }}{{ range .ControllerImages }}, {{ . }}{{ end }})
+$KUBECTL create namespace kubeaddons --dry-run --output yaml | $KUBECTL apply --filename -
+$KUBECTL apply --filename controller.yaml
+$KUBECTL wait --for condition=established crd --all --timeout 5m
+$KUBECTL wait --for condition=available deployment --namespace kubeaddons --all --timeout 5m
+{{- range $i, $wave := .AddonWaves }}
+
+echo "deploying wave {{ inc $i }}"
+{{- range $wave }}
+{{- if .Namespace }}
+$KUBECTL create namespace {{ quote .Namespace }} --dry-run --output yaml | $KUBECTL apply --filename -
+{{- end }}
+$KUBECTL apply --filename addons/{{ .Name }}.yaml
+{{- end }}
+{{- range $wave }}
+wait_ready {{ .Kind }} {{ quote .Namespace }} {{ .Name }}
+{{- end }}
+{{- end }}
+{{- end }}
+`))
+
+// quote quotes a string for the shell.
+func quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// ExportController returns the manifests of the kubeaddons controller deployed
+// to the cluster of a kubectl client: the custom resource
+// definitions of kubeaddons and the objects named kubeaddons-* in its
+// namespace and cluster wide, without the fields set by the API server. It
+// returns the images of the controller too.
+func ExportController(k kubectl.Client, namespace string) ([]byte, []string, error) {
+	var objects []map[string]interface{}
+	for _, args := range [][]string{
+		{"get", "customresourcedefinitions,clusterroles,clusterrolebindings"},
+		{"--namespace", namespace, "get", "deployments,serviceaccounts,services,roles,rolebindings,configmaps,secrets"},
+	} {
+		out, err := k.Output(append(args, "--output", "json")...)
+		if err != nil {
+			return nil, nil, err
+		}
+		var list struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		if err := json.Unmarshal(out, &list); err != nil {
+			return nil, nil, err
+		}
+		objects = append(objects, controllerObjects(list.Items)...)
+	}
+	if len(objects) == 0 {
+		return nil, nil, fmt.Errorf("the kubeaddons controller is not deployed to namespace %s", namespace)
+	}
+
+	b, err := json.MarshalIndent(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": objects}, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return b, images(objects), nil
+}
+
+// controllerObjects returns the objects of the kubeaddons controller among
+// objects, cleaned of the fields set by the API server.
+func controllerObjects(objects []map[string]interface{}) []map[string]interface{} {
+	var controller []map[string]interface{}
+	for _, o := range objects {
+		metadata, _ := o["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if o["kind"] == "CustomResourceDefinition" {
+			spec, _ := o["spec"].(map[string]interface{})
+			if group, _ := spec["group"].(string); group != "kubeaddons.mesosphere.io" {
+				continue
+			}
+		} else if !strings.HasPrefix(name, "kubeaddons-") {
+			continue
+		}
+		if o["kind"] == "Secret" && o["type"] == "kubernetes.io/service-account-token" {
+			continue
+		}
+
+		delete(o, "status")
+		for _, field := range []string{"uid", "resourceVersion", "selfLink", "creationTimestamp", "generation", "managedFields"} {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			delete(annotations, "deployment.kubernetes.io/revision")
+		}
+		switch o["kind"] {
+		case "ServiceAccount":
+			delete(o, "secrets")
+		case "Service":
+			if spec, ok := o["spec"].(map[string]interface{}); ok {
+				delete(spec, "clusterIP")
+			}
+		}
+		controller = append(controller, o)
+	}
+	return controller
+}
+
+// images returns the images of the containers of the deployments among
+// objects.
+func images(objects []map[string]interface{}) []string {
+	var images []string
+	for _, o := range objects {
+		if o["kind"] != "Deployment" {
+			continue
+		}
+		spec, _ := o["spec"].(map[string]interface{})
+		template, _ := spec["template"].(map[string]interface{})
+		podSpec, _ := template["spec"].(map[string]interface{})
+		containers, _ := podSpec["containers"].([]interface{})
+		for _, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				if image, ok := container["image"].(string); ok {
+					images = append(images, image)
+				}
+			}
+		}
+	}
+	return images
+}
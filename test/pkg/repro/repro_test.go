@@ -0,0 +1,188 @@
+package repro
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBundle(backend string) *Bundle {
+	return &Bundle{
+		Scenario:          "kommander-minimal",
+		Phase:             "deploying wave 2/2",
+		Failure:           "addon kubecost is not ready:\nprometheus is not reachable",
+		KubernetesVersion: "1.16.4",
+		NodeImage:         "kindest/node:v1.16.4",
+		Backend:           backend,
+		ControllerBundle:  "kind",
+		Timeout:           10 * time.Minute,
+		Waves:             [][]string{{"cert-manager"}, {"kommander", "kubecost"}},
+		KindConfig:        []byte(`{"kind":"Cluster","nodes":[{"extraMounts":[{"hostPath":"@REPRO_DIR@/nodes/timezone"}]}]}`),
+		NodeFiles:         map[string][]byte{"timezone": []byte("Pacific/Chatham\n")},
+		Controller:        []byte(`{"kind":"List"}`),
+		Addons: []Addon{
+			{Name: "cert-manager", Kind: "ClusterAddon", Manifest: []byte("kind: ClusterAddon"), Release: "cert-manager-kubeaddons", ReleaseNamespace: "cert-manager", Chart: "cert-manager", Repo: "https://charts.jetstack.io", Version: "v0.10.1"},
+			{Name: "kommander", Kind: "Addon", Namespace: "kommander", Manifest: []byte("kind: Addon"), Release: "kommander-kubeaddons", ReleaseNamespace: "kommander", Chart: "kommander", Version: "0.3.29", Values: "ui:\n  enabled: true\n"},
+			{Name: "kubecost", Kind: "Addon", Namespace: "kubecost", Manifest: []byte("kind: Addon"), Release: "kubecost-kubeaddons", ReleaseNamespace: "kubecost", Chart: "cost-analyzer", Version: "0.1.9", Values: "user's: values\n"},
+		},
+	}
+}
+
+// extract returns the files of a written bundle by their path.
+func extract(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(b)
+		if strings.HasSuffix(header.Name, ".sh") && header.Mode != 0755 {
+			t.Errorf("expected %s to be executable, got mode %o", header.Name, header.Mode)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "repro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "repro", "kommander-minimal.tar.gz")
+	if err := testBundle("controller").Write(path); err != nil {
+		t.Fatal(err)
+	}
+	files := extract(t, path)
+	for name, content := range map[string]string{
+		"bundle.yaml":              "controllerBundle: kind",
+		"kind.yaml":                "@REPRO_DIR@/nodes/timezone",
+		"nodes/timezone":           "Pacific/Chatham",
+		"controller.yaml":          `"kind":"List"`,
+		"addons/kubecost.yaml":     "kind: Addon",
+		"values/kommander.yaml":    "enabled: true",
+		"addons/cert-manager.yaml": "kind: ClusterAddon",
+		"reproduce.sh":             "#!/bin/sh",
+	} {
+		if !strings.Contains(files["repro-kommander-minimal/"+name], content) {
+			t.Errorf("expected %s to contain %q, got %q", name, content, files["repro-kommander-minimal/"+name])
+		}
+	}
+	if _, ok := files["repro-kommander-minimal/values/cert-manager.yaml"]; ok {
+		t.Error("expected no values of an addon without values")
+	}
+}
+
+func TestControllerScript(t *testing.T) {
+	script, err := testBundle("controller").script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"#   prometheus is not reachable\n",
+		"kind create cluster --name \"$CLUSTER\" --image 'kindest/node:v1.16.4' --config kind.resolved.yaml",
+		"$KUBECTL apply --filename controller.yaml\n",
+		"$KUBECTL apply --filename addons/cert-manager.yaml\nwait_ready ClusterAddon '' cert-manager\n",
+		"$KUBECTL apply --filename addons/kommander.yaml\n",
+		"$KUBECTL apply --filename addons/kubecost.yaml\nwait_ready Addon 'kommander' kommander\nwait_ready Addon 'kubecost' kubecost\n",
+		"+ 600))",
+	} {
+		if !strings.Contains(string(script), expected) {
+			t.Errorf("expected the script to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(string(script), "helm") {
+		t.Errorf("expected the controller to deploy the addons, got:\n%s", script)
+	}
+
+	b := testBundle("controller")
+	b.Waves = append(b.Waves, []string{"velero"})
+	if _, err := b.script(); err == nil {
+		t.Error("expected an error for an addon which is not part of the bundle")
+	}
+}
+
+func TestHelmScript(t *testing.T) {
+	script, err := testBundle("helm").script()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"upgrade 'cert-manager-kubeaddons' 'cert-manager' --install \\\n\t--namespace 'cert-manager' --create-namespace --wait --timeout 10m0s \\\n\t--version 'v0.10.1' --repo 'https://charts.jetstack.io'\n",
+		"--version '0.1.9' --values values/kubecost.yaml",
+	} {
+		if !strings.Contains(string(script), expected) {
+			t.Errorf("expected the script to contain %q, got:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(string(script), "controller.yaml") {
+		t.Errorf("expected no controller for the helm backend, got:\n%s", script)
+	}
+	if files, err := testBundle("helm").files(); err != nil || files["controller.yaml"] != nil {
+		t.Errorf("expected no controller in a bundle of the helm backend, got %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for s, expected := range map[string]string{"": "''", "kind": "'kind'", "user's": `'user'\''s'`} {
+		if quoted := quote(s); quoted != expected {
+			t.Errorf("expected %s to be quoted as %s, got %s", s, expected, quoted)
+		}
+	}
+}
+
+func TestControllerObjects(t *testing.T) {
+	objects := controllerObjects([]map[string]interface{}{
+		{"kind": "CustomResourceDefinition", "metadata": map[string]interface{}{"name": "addons.kubeaddons.mesosphere.io", "uid": "1"}, "spec": map[string]interface{}{"group": "kubeaddons.mesosphere.io"}, "status": map[string]interface{}{}},
+		{"kind": "CustomResourceDefinition", "metadata": map[string]interface{}{"name": "certificates.cert-manager.io"}, "spec": map[string]interface{}{"group": "cert-manager.io"}},
+		{"kind": "ClusterRole", "metadata": map[string]interface{}{"name": "traefik-kubeaddons"}},
+		{"kind": "Secret", "type": "kubernetes.io/service-account-token", "metadata": map[string]interface{}{"name": "kubeaddons-controller-token-x"}},
+		{"kind": "Service", "metadata": map[string]interface{}{"name": "kubeaddons-webhook"}, "spec": map[string]interface{}{"clusterIP": "10.96.0.12", "ports": []interface{}{}}},
+		{"kind": "Deployment", "metadata": map[string]interface{}{
+			"name":        "kubeaddons-controller-manager",
+			"annotations": map[string]interface{}{"kubectl.kubernetes.io/last-applied-configuration": "{}", "owner": "kubeaddons"},
+		}, "spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{"containers": []interface{}{
+			map[string]interface{}{"image": "mesosphere/kubeaddons:v0.9.2"},
+		}}}}},
+	})
+
+	if len(objects) != 3 {
+		t.Fatalf("expected the definition, service and deployment of the controller, got %v", objects)
+	}
+	if _, ok := objects[0]["status"]; ok || objects[0]["metadata"].(map[string]interface{})["uid"] != nil {
+		t.Errorf("expected the fields of the API server to be removed, got %v", objects[0])
+	}
+	if spec := objects[1]["spec"].(map[string]interface{}); spec["clusterIP"] != nil || spec["ports"] == nil {
+		t.Errorf("expected the cluster IP of the service to be removed, got %v", spec)
+	}
+	if annotations := objects[2]["metadata"].(map[string]interface{})["annotations"].(map[string]interface{}); len(annotations) != 1 {
+		t.Errorf("expected the last applied configuration to be removed, got %v", annotations)
+	}
+	if images := images(objects); len(images) != 1 || images[0] != "mesosphere/kubeaddons:v0.9.2" {
+		t.Errorf("expected the image of the controller, got %v", images)
+	}
+}
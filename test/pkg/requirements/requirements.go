@@ -0,0 +1,119 @@
+// Package requirements resolves the requirements between addons: an addon
+// requires the addons whose labels match the label selectors of its requires.
+package requirements
+
+import (
+	"fmt"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// Matches returns whether labels match the match labels of a requirement. A
+// requirement without match labels matches no addon, rather than every addon
+// (including the one requiring it).
+func Matches(labels, selector map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Required returns the addons among candidates which an addon requires
+// directly, in the order of its requires.
+func Required(addon v1beta1.AddonInterface, candidates []v1beta1.AddonInterface) []v1beta1.AddonInterface {
+	var required []v1beta1.AddonInterface
+	for _, selector := range addon.GetAddonSpec().Requires {
+		for _, candidate := range candidates {
+			if Matches(candidate.GetLabels(), selector.MatchLabels) {
+				required = append(required, candidate)
+			}
+		}
+	}
+	return required
+}
+
+// Resolve returns the named addon along with all the addons it requires
+// (transitively), resolved from the provided addons, with every addon after
+// the addons it requires. It fails when a requirement is provided by none of
+// them.
+func Resolve(name string, from []v1beta1.AddonInterface) ([]v1beta1.AddonInterface, error) {
+	var required []v1beta1.AddonInterface
+	seen := make(map[string]bool)
+
+	var resolve func(v1beta1.AddonInterface) error
+	resolve = func(addon v1beta1.AddonInterface) error {
+		if seen[addon.GetName()] {
+			return nil
+		}
+		seen[addon.GetName()] = true
+
+		for _, selector := range addon.GetAddonSpec().Requires {
+			found := false
+			for _, candidate := range from {
+				if Matches(candidate.GetLabels(), selector.MatchLabels) {
+					found = true
+					if err := resolve(candidate); err != nil {
+						return err
+					}
+				}
+			}
+			if !found {
+				return fmt.Errorf("addon %s requires %v, which no addon provides", addon.GetName(), selector.MatchLabels)
+			}
+		}
+
+		required = append(required, addon)
+		return nil
+	}
+
+	for _, addon := range from {
+		if addon.GetName() == name {
+			return required, resolve(addon)
+		}
+	}
+
+	return required, fmt.Errorf("addon %s not found", name)
+}
+
+// Order sorts addons so that every addon comes after the addons it requires
+// (as far as they are part of addons), keeping the order otherwise. It fails
+// when the requirements are cyclic.
+func Order(addons []v1beta1.AddonInterface) ([]v1beta1.AddonInterface, error) {
+	var ordered []v1beta1.AddonInterface
+	state := make(map[string]int)
+	const (
+		visiting = 1
+		done     = 2
+	)
+
+	var visit func(v1beta1.AddonInterface) error
+	visit = func(addon v1beta1.AddonInterface) error {
+		switch state[addon.GetName()] {
+		case visiting:
+			return fmt.Errorf("cyclic requirements between addons involving %s", addon.GetName())
+		case done:
+			return nil
+		}
+		state[addon.GetName()] = visiting
+		for _, required := range Required(addon, addons) {
+			if err := visit(required); err != nil {
+				return err
+			}
+		}
+		state[addon.GetName()] = done
+		ordered = append(ordered, addon)
+		return nil
+	}
+
+	for _, addon := range addons {
+		if err := visit(addon); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
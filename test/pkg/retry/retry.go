@@ -0,0 +1,120 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Backoff configures the delays between the attempts of an operation: the
+// delay starts at Initial and is multiplied by Factor after every attempt (up
+// to Max), with a random jitter of up to Jitter (a fraction of the delay)
+// added so that concurrent retries don't hit the API server in lockstep.
+type Backoff struct {
+	Initial  time.Duration
+	Max      time.Duration
+	Factor   float64
+	Jitter   float64
+	Attempts int
+}
+
+// Default retries for a little over two minutes, which covers the API server
+// restarts and leader elections which are common while a kind cluster starts.
+var Default = Backoff{
+	Initial:  time.Second,
+	Max:      30 * time.Second,
+	Factor:   2,
+	Jitter:   0.2,
+	Attempts: 8,
+}
+
+// delay returns the delay before the given attempt (starting at 1 for the
+// first retry), r returns a random number in [0.0,1.0).
+func (b Backoff) delay(attempt int, r func() float64) time.Duration {
+	d := float64(b.Initial)
+	for i := 1; i < attempt; i++ {
+		d *= b.Factor
+		if d > float64(b.Max) {
+			d = float64(b.Max)
+			break
+		}
+	}
+	return time.Duration(d + d*b.Jitter*r())
+}
+
+// Do runs op until it succeeds, fails with a permanent error (see IsTransient)
+// or the attempts of the backoff are exhausted, returning the last error.
+func Do(b Backoff, op func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = op(); err == nil {
+			return nil
+		}
+		if !IsTransient(err) {
+			return err
+		}
+		if attempt >= b.Attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		time.Sleep(b.delay(attempt, rand.Float64))
+	}
+}
+
+// transientError marks an error as transient regardless of its message.
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+func (e transientError) Unwrap() error { return e.err }
+
+// Transient marks err as transient, for operations which know that they may
+// succeed when retried.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return transientError{err: err}
+}
+
+// transientMessages are parts of the messages of errors (including the output
+// of kubectl) which are caused by an API server which is (re)starting,
+// overloaded or not yet serving resources which are being established.
+var transientMessages = []string{
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"unexpected EOF",
+	"http2: server sent GOAWAY",
+	"etcdserver: request timed out",
+	"etcdserver: leader changed",
+	"the server is currently unable to handle the request",
+	"the server was unable to return a response in the time allotted",
+	"Too Many Requests",
+	"context deadline exceeded",
+	"no matches for kind",
+	"failed calling webhook",
+}
+
+// IsTransient classifies an error as transient (the operation may succeed when
+// retried) or permanent (e.g. invalid manifests or missing permissions, which
+// retrying won't fix).
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var t transientError
+	if errors.As(err, &t) {
+		return true
+	}
+	msg := err.Error()
+	for _, m := range transientMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 10 * time.Second, Factor: 2, Jitter: 0.5}
+	for attempt, expected := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		if actual := b.delay(attempt, func() float64 { return 0 }); actual != expected {
+			t.Errorf("expected delay %s before attempt %d, got %s", expected, attempt, actual)
+		}
+	}
+	if actual := b.delay(1, func() float64 { return 0.5 }); actual != 1250*time.Millisecond {
+		t.Errorf("expected jitter to add 25%%, got %s", actual)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	for err, expected := range map[error]bool{
+		nil: false,
+		errors.New(`error validating data: unknown field "replica"`):                                                                      false,
+		errors.New("Unable to connect to the server: dial tcp 127.0.0.1:32768: connect: connection refused"):                              true,
+		fmt.Errorf("kubectl apply failed: %w", errors.New("the server is currently unable to handle the request")):                        true,
+		errors.New(`unable to recognize "STDIN": no matches for kind "K8sRequiredLabels" in version "constraints.gatekeeper.sh/v1beta1"`): true,
+		Transient(errors.New("controller not ready")):                                                                                     true,
+	} {
+		if actual := IsTransient(err); actual != expected {
+			t.Errorf("expected transient=%t for %v, got %t", expected, err, actual)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	b := Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 2, Attempts: 3}
+
+	attempts := 0
+	err := Do(b, func() error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil || attempts != 2 {
+		t.Errorf("expected success after 2 attempts, got %d attempts and %v", attempts, err)
+	}
+
+	attempts = 0
+	permanent := errors.New("forbidden")
+	if err := Do(b, func() error { attempts++; return permanent }); err != permanent || attempts != 1 {
+		t.Errorf("expected permanent error after 1 attempt, got %d attempts and %v", attempts, err)
+	}
+
+	attempts = 0
+	if err := Do(b, func() error { attempts++; return errors.New("i/o timeout") }); err == nil || attempts != 3 {
+		t.Errorf("expected failure after 3 attempts, got %d attempts and %v", attempts, err)
+	}
+}
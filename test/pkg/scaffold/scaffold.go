@@ -0,0 +1,270 @@
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+)
+
+// ProvidesLabel is the label of addons with the capability they provide, e.g.
+// ingress. Addons which provide the same capability are tested together.
+const ProvidesLabel = "kubeaddons.mesosphere.io/provides"
+
+// Addon is the metadata of an addon of the catalog which relates it to other
+// addons.
+type Addon struct {
+	Name   string
+	Labels map[string]string
+
+	// Requires are the label selectors (matchLabels) of the addons the addon
+	// requires.
+	Requires []map[string]string
+}
+
+// Suggestion proposes to add an addon which is not in any testing group to a
+// group.
+type Suggestion struct {
+	Addon string
+	Group string
+
+	// New is true when the group does not exist yet.
+	New bool
+
+	// Reason explains why the group is suggested.
+	Reason string
+}
+
+func (s Suggestion) String() string {
+	if s.New {
+		return fmt.Sprintf("addon %s: new group %s (%s)", s.Addon, s.Group, s.Reason)
+	}
+	return fmt.Sprintf("addon %s: group %s (%s)", s.Addon, s.Group, s.Reason)
+}
+
+// Suggest suggests a testing group for every addon of the catalog which is not
+// in any group: the group of an addon which provides the same capability,
+// else the group of an addon it requires or which requires it. Addons related
+// to none get a new group of their own. Addons whose group was suggested
+// relate further addons to that group.
+func Suggest(g groups.Groups, catalog []Addon) []Suggestion {
+	groupOf := make(map[string]string)
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, addon := range g[name] {
+			if _, ok := groupOf[addon.Name]; !ok {
+				groupOf[addon.Name] = name
+			}
+		}
+	}
+
+	var unhandled []Addon
+	for _, addon := range catalog {
+		if _, ok := groupOf[addon.Name]; !ok {
+			unhandled = append(unhandled, addon)
+		}
+	}
+	sort.Slice(unhandled, func(i, j int) bool { return unhandled[i].Name < unhandled[j].Name })
+
+	var suggestions []Suggestion
+	for progress := true; progress; {
+		progress = false
+		var remaining []Addon
+		for _, addon := range unhandled {
+			group, reason := related(addon, catalog, groupOf)
+			if group == "" {
+				remaining = append(remaining, addon)
+				continue
+			}
+			groupOf[addon.Name] = group
+			suggestions = append(suggestions, Suggestion{Addon: addon.Name, Group: group, Reason: reason})
+			progress = true
+		}
+		unhandled = remaining
+	}
+	for _, addon := range unhandled {
+		suggestions = append(suggestions, Suggestion{Addon: addon.Name, Group: addon.Name, New: true, Reason: "not related to an addon of any testing group"})
+	}
+	return suggestions
+}
+
+// related returns the group of the first addon in a group which is related to
+// an addon, along with the relation.
+func related(addon Addon, catalog []Addon, groupOf map[string]string) (string, string) {
+	if provides := addon.Labels[ProvidesLabel]; provides != "" {
+		for _, other := range catalog {
+			if group, ok := groupOf[other.Name]; ok && other.Labels[ProvidesLabel] == provides {
+				return group, fmt.Sprintf("provides %s like %s", provides, other.Name)
+			}
+		}
+	}
+	for _, selector := range addon.Requires {
+		for _, other := range catalog {
+			if group, ok := groupOf[other.Name]; ok && matches(other.Labels, selector) {
+				return group, fmt.Sprintf("requires %s", other.Name)
+			}
+		}
+	}
+	for _, other := range catalog {
+		group, ok := groupOf[other.Name]
+		if !ok {
+			continue
+		}
+		for _, selector := range other.Requires {
+			if matches(addon.Labels, selector) {
+				return group, fmt.Sprintf("required by %s", other.Name)
+			}
+		}
+	}
+	return "", ""
+}
+
+func matches(labels, matchLabels map[string]string) bool {
+	if len(matchLabels) == 0 {
+		return false
+	}
+	for k, v := range matchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	groupKey = regexp.MustCompile(`^"?([^"#\s:]+)"?:\s*(#.*)?$`)
+	listItem = regexp.MustCompile(`^(\s+)- `)
+)
+
+// Apply adds the addons of the suggestions to the groups of a groups.yaml
+// file, after the last addon of each group, and appends new groups, keeping
+// the comments and the layout of the file.
+func Apply(groupsYAML []byte, suggestions []Suggestion) ([]byte, error) {
+	lines := strings.Split(strings.TrimRight(string(groupsYAML), "\n"), "\n")
+
+	// the line of the last addon and the indentation of the addons of each group
+	last := make(map[string]int)
+	indent := make(map[string]string)
+	group := ""
+	for i, line := range lines {
+		if m := groupKey.FindStringSubmatch(line); m != nil {
+			group = m[1]
+			continue
+		}
+		if group == "" || strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		if m := listItem.FindStringSubmatch(line); m != nil && indent[group] == "" {
+			indent[group] = m[1]
+		}
+		last[group] = i
+	}
+
+	added := make(map[int][]string)
+	var appended []string
+	for _, s := range suggestions {
+		if s.New {
+			appended = append(appended, "", s.Group+":", fmt.Sprintf("    - %q", s.Addon))
+			continue
+		}
+		i, ok := last[s.Group]
+		if !ok {
+			return nil, fmt.Errorf("testing group %s of addon %s is not in groups.yaml", s.Group, s.Addon)
+		}
+		prefix := indent[s.Group]
+		if prefix == "" {
+			prefix = "    "
+		}
+		added[i] = append(added[i], fmt.Sprintf("%s- %q", prefix, s.Addon))
+	}
+
+	var out []string
+	for i, line := range lines {
+		out = append(out, line)
+		out = append(out, added[i]...)
+	}
+	out = append(out, appended...)
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// contextLines is the number of unchanged lines around the changes of a diff.
+const contextLines = 3
+
+// Diff returns the unified diff of a file to which lines were added, as
+// returned by Apply.
+func Diff(path string, before, after []byte) string {
+	old := strings.Split(strings.TrimRight(string(before), "\n"), "\n")
+	updated := strings.Split(strings.TrimRight(string(after), "\n"), "\n")
+
+	// added marks the lines of the updated file which are not in the old one,
+	// which are only additions
+	added := make([]bool, len(updated))
+	for i, j := 0, 0; j < len(updated); j++ {
+		if i < len(old) && old[i] == updated[j] {
+			i++
+			continue
+		}
+		added[j] = true
+	}
+
+	var b strings.Builder
+	for j := 0; j < len(updated); {
+		if !added[j] {
+			j++
+			continue
+		}
+		// a hunk spans the additions which are closer than twice the context
+		start, end := j, j
+		for k := j; k < len(updated) && k <= end+2*contextLines; k++ {
+			if added[k] {
+				end = k
+			}
+		}
+		from, to := max(start-contextLines, 0), min(end+contextLines+1, len(updated))
+
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- a/%s\n+++ b/%s\n", path, path)
+		}
+		oldFrom, count := from, 0
+		for k := 0; k < from; k++ {
+			if added[k] {
+				oldFrom--
+			}
+		}
+		for k := from; k < to; k++ {
+			if !added[k] {
+				count++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldFrom+1, count, from+1, to-from)
+		for k := from; k < to; k++ {
+			if added[k] {
+				fmt.Fprintf(&b, "+%s\n", updated[k])
+			} else {
+				fmt.Fprintf(&b, " %s\n", updated[k])
+			}
+		}
+		j = to
+	}
+	return b.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,94 @@
+package scaffold
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+)
+
+const groupsYAML = `# testing groups
+kommander:
+    - "traefik"
+    - "kommander"
+
+# logging
+logging:
+    - "fluentbit"
+`
+
+func TestSuggest(t *testing.T) {
+	g := groups.Groups{
+		"kommander": {{Name: "traefik"}, {Name: "kommander"}},
+		"logging":   {{Name: "fluentbit"}},
+	}
+	catalog := []Addon{
+		{Name: "traefik", Labels: map[string]string{ProvidesLabel: "ingress"}},
+		{Name: "kommander", Labels: map[string]string{"kubeaddons.mesosphere.io/name": "kommander"}},
+		{Name: "fluentbit", Labels: map[string]string{"kubeaddons.mesosphere.io/name": "fluentbit"}},
+		{Name: "nginx", Labels: map[string]string{ProvidesLabel: "ingress"}},
+		{Name: "kommander-ui", Requires: []map[string]string{{"kubeaddons.mesosphere.io/name": "kommander-karma"}}},
+		{Name: "kommander-karma", Labels: map[string]string{"kubeaddons.mesosphere.io/name": "kommander-karma"}, Requires: []map[string]string{{"kubeaddons.mesosphere.io/name": "kommander"}}},
+		{Name: "velero"},
+	}
+
+	expected := []Suggestion{
+		{Addon: "kommander-karma", Group: "kommander", Reason: "requires kommander"},
+		{Addon: "kommander-ui", Group: "kommander", Reason: "requires kommander-karma"},
+		{Addon: "nginx", Group: "kommander", Reason: "provides ingress like traefik"},
+		{Addon: "velero", Group: "velero", New: true, Reason: "not related to an addon of any testing group"},
+	}
+	if actual := Suggest(g, catalog); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestApply(t *testing.T) {
+	suggestions := []Suggestion{
+		{Addon: "nginx", Group: "kommander"},
+		{Addon: "velero", Group: "velero", New: true},
+	}
+	actual, err := Apply([]byte(groupsYAML), suggestions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `# testing groups
+kommander:
+    - "traefik"
+    - "kommander"
+    - "nginx"
+
+# logging
+logging:
+    - "fluentbit"
+
+velero:
+    - "velero"
+`
+	if string(actual) != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, actual)
+	}
+
+	expectedDiff := `--- a/groups.yaml
++++ b/groups.yaml
+@@ -2,7 +2,11 @@
+ kommander:
+     - "traefik"
+     - "kommander"
++    - "nginx"
+ 
+ # logging
+ logging:
+     - "fluentbit"
++
++velero:
++    - "velero"
+`
+	if diff := Diff("groups.yaml", []byte(groupsYAML), actual); diff != expectedDiff {
+		t.Errorf("expected:\n%s\ngot:\n%s", expectedDiff, diff)
+	}
+
+	if _, err := Apply([]byte(groupsYAML), []Suggestion{{Addon: "nginx", Group: "ingress"}}); err == nil {
+		t.Error("expected a suggestion for a missing group to be rejected")
+	}
+}
@@ -0,0 +1,185 @@
+package scale
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+const (
+	// Workspace and Project are the kinds of objects whose reconciliation is
+	// measured.
+	Workspace = "workspace"
+	Project   = "project"
+)
+
+// Config is the load a scale scenario puts on the management plane of
+// kommander, and the thresholds of the latency of its controllers.
+type Config struct {
+	// Workspaces is the number of workspaces to create.
+	Workspaces int
+
+	// ProjectsPerWorkspace is the number of projects to create in each
+	// workspace.
+	ProjectsPerWorkspace int
+
+	// Concurrency is the number of workspaces which are created at once.
+	Concurrency int
+
+	// Thresholds are the maximum latencies of the reconciliation of the
+	// objects by kind.
+	Thresholds map[string]Threshold
+}
+
+// Threshold is the maximum latency of the reconciliation of the objects of a
+// kind, by percentile.
+type Threshold struct {
+	MaxP50 time.Duration
+	MaxP95 time.Duration
+}
+
+// Load reads the configuration of the scale scenario in a file. Every kind
+// must have a threshold of its 95th percentile.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Workspaces           int `yaml:"workspaces"`
+		ProjectsPerWorkspace int `yaml:"projectsPerWorkspace"`
+		Concurrency          int `yaml:"concurrency"`
+		Thresholds           map[string]struct {
+			MaxP50 string `yaml:"maxP50"`
+			MaxP95 string `yaml:"maxP95"`
+		} `yaml:"thresholds"`
+	}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse scale configuration %s: %w", path, err)
+	}
+	if file.Workspaces <= 0 || file.ProjectsPerWorkspace < 0 || file.Concurrency <= 0 {
+		return nil, fmt.Errorf("scale configuration %s needs a positive number of workspaces and concurrency", path)
+	}
+
+	c := &Config{
+		Workspaces:           file.Workspaces,
+		ProjectsPerWorkspace: file.ProjectsPerWorkspace,
+		Concurrency:          file.Concurrency,
+		Thresholds:           make(map[string]Threshold),
+	}
+	for _, kind := range []string{Workspace, Project} {
+		threshold, ok := file.Thresholds[kind]
+		if !ok || threshold.MaxP95 == "" {
+			return nil, fmt.Errorf("scale configuration %s has no maxP95 of %s", path, kind)
+		}
+		delete(file.Thresholds, kind)
+
+		var parsed Threshold
+		if parsed.MaxP95, err = time.ParseDuration(threshold.MaxP95); err != nil {
+			return nil, fmt.Errorf("scale configuration %s has an invalid maxP95 of %s: %w", path, kind, err)
+		}
+		if threshold.MaxP50 != "" {
+			if parsed.MaxP50, err = time.ParseDuration(threshold.MaxP50); err != nil {
+				return nil, fmt.Errorf("scale configuration %s has an invalid maxP50 of %s: %w", path, kind, err)
+			}
+		}
+		c.Thresholds[kind] = parsed
+	}
+	if len(file.Thresholds) > 0 {
+		unknown := make([]string, 0, len(file.Thresholds))
+		for kind := range file.Thresholds {
+			unknown = append(unknown, kind)
+		}
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("scale configuration %s has thresholds of unknown kinds %v", path, unknown)
+	}
+	return c, nil
+}
+
+// Summary summarizes the latencies of the reconciliation of the objects of a
+// kind.
+type Summary struct {
+	Kind  string
+	Count int
+	P50   time.Duration
+	P95   time.Duration
+	Max   time.Duration
+}
+
+func (s Summary) String() string {
+	return fmt.Sprintf("%d %ss: p50 %s, p95 %s, max %s", s.Count, s.Kind, s.P50, s.P95, s.Max)
+}
+
+// Summarize summarizes latencies by their nearest-rank percentiles.
+func Summarize(kind string, latencies []time.Duration) Summary {
+	summary := Summary{Kind: kind, Count: len(latencies)}
+	if len(latencies) == 0 {
+		return summary
+	}
+	sorted := append([]time.Duration{}, latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p int) time.Duration {
+		rank := (p*len(sorted) + 99) / 100
+		return sorted[rank-1]
+	}
+	summary.P50, summary.P95, summary.Max = percentile(50), percentile(95), sorted[len(sorted)-1]
+	return summary
+}
+
+// Exceeded returns the summaries which exceed their thresholds.
+func (c *Config) Exceeded(summaries []Summary) []string {
+	var exceeded []string
+	for _, s := range summaries {
+		threshold := c.Thresholds[s.Kind]
+		if s.P95 > threshold.MaxP95 {
+			exceeded = append(exceeded, fmt.Sprintf("the p95 latency of %ss is %s, more than %s", s.Kind, s.P95, threshold.MaxP95))
+		}
+		if threshold.MaxP50 > 0 && s.P50 > threshold.MaxP50 {
+			exceeded = append(exceeded, fmt.Sprintf("the p50 latency of %ss is %s, more than %s", s.Kind, s.P50, threshold.MaxP50))
+		}
+	}
+	return exceeded
+}
+
+// Latency returns the time between the creation of an object and of the
+// object its controller created for it, by their creation timestamps (e.g.
+// of a workspace and its namespace), which doesn't depend on how often the
+// test polls for the latter. Each object is given by the arguments of
+// `kubectl get`.
+func Latency(k kubectl.Client, object, reconciled []string) (time.Duration, error) {
+	created, err := creationTimestamp(k, object)
+	if err != nil {
+		return 0, err
+	}
+	ready, err := creationTimestamp(k, reconciled)
+	if err != nil {
+		return 0, err
+	}
+	if ready.Before(created) {
+		return 0, nil
+	}
+	return ready.Sub(created), nil
+}
+
+func creationTimestamp(k kubectl.Client, args []string) (time.Time, error) {
+	out, err := k.Output(append(append([]string{"get"}, args...), "--output", "jsonpath={.metadata.creationTimestamp}")...)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseTimestamp(string(out))
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse creation timestamp %q: %w", s, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,111 @@
+package scale
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tc := range []struct {
+		content string
+		err     string
+	}{
+		{
+			content: "workspaces: 30\nprojectsPerWorkspace: 3\nconcurrency: 5\nthresholds:\n  workspace:\n    maxP50: 5s\n    maxP95: 30s\n  project:\n    maxP95: 1m\n",
+		},
+		{
+			content: "workspaces: 0\nconcurrency: 5\n",
+			err:     "positive number of workspaces",
+		},
+		{
+			content: "workspaces: 3\nconcurrency: 1\nthresholds:\n  workspace:\n    maxP95: 30s\n",
+			err:     "no maxP95 of project",
+		},
+		{
+			content: "workspaces: 3\nconcurrency: 1\nthresholds:\n  workspace:\n    maxP95: soon\n  project:\n    maxP95: 1m\n",
+			err:     "invalid maxP95 of workspace",
+		},
+		{
+			content: "workspaces: 3\nconcurrency: 1\nthresholds:\n  workspace:\n    maxP95: 30s\n  project:\n    maxP95: 1m\n  cluster:\n    maxP95: 1m\n",
+			err:     "unknown kinds [cluster]",
+		},
+		{
+			content: "workspaces: 3\nconcurrency: 1\nclusters: 2\n",
+			err:     "could not parse",
+		},
+	} {
+		path := filepath.Join(dir, "scale.yaml")
+		if err := ioutil.WriteFile(path, []byte(tc.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		c, err := Load(path)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("expected an error containing %q for %q, got %v", tc.err, tc.content, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.content, err)
+			continue
+		}
+		expected := Threshold{MaxP50: 5 * time.Second, MaxP95: 30 * time.Second}
+		if c.Workspaces != 30 || c.ProjectsPerWorkspace != 3 || c.Concurrency != 5 || c.Thresholds[Workspace] != expected || c.Thresholds[Project].MaxP95 != time.Minute {
+			t.Errorf("unexpected configuration %+v", c)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var latencies []time.Duration
+	for i := 20; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Second)
+	}
+	summary := Summarize(Project, latencies)
+	expected := Summary{Kind: Project, Count: 20, P50: 10 * time.Second, P95: 19 * time.Second, Max: 20 * time.Second}
+	if summary != expected {
+		t.Errorf("expected %+v, got %+v", expected, summary)
+	}
+	if latencies[0] != 20*time.Second {
+		t.Errorf("expected the latencies not to be sorted in place")
+	}
+	if empty := Summarize(Workspace, nil); empty != (Summary{Kind: Workspace}) {
+		t.Errorf("expected an empty summary, got %+v", empty)
+	}
+
+	c := &Config{Thresholds: map[string]Threshold{
+		Workspace: {MaxP95: 30 * time.Second},
+		Project:   {MaxP50: 5 * time.Second, MaxP95: 30 * time.Second},
+	}}
+	exceeded := c.Exceeded([]Summary{summary, {Kind: Workspace, Count: 1, P50: time.Minute, P95: time.Minute, Max: time.Minute}})
+	expectedExceeded := []string{
+		"the p50 latency of projects is 10s, more than 5s",
+		"the p95 latency of workspaces is 1m0s, more than 30s",
+	}
+	if strings.Join(exceeded, "\n") != strings.Join(expectedExceeded, "\n") {
+		t.Errorf("expected %q, got %q", expectedExceeded, exceeded)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := parseTimestamp("2020-06-01T10:00:05Z\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2020, 6, 1, 10, 0, 5, 0, time.UTC); !ts.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, ts)
+	}
+	if _, err := parseTimestamp(""); err == nil {
+		t.Error("expected an error for an empty timestamp")
+	}
+}
@@ -0,0 +1,245 @@
+package scenario
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/clock"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/containerd"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/ipfamily"
+)
+
+// Scenario composes a cluster topology, testing groups, override layers and
+// checks into a named test run.
+type Scenario struct {
+	// Name is the name of the scenario, taken from its file name.
+	Name string `yaml:"-"`
+
+	Description string `yaml:"description"`
+
+	// Test is the go test which runs the scenario along with the steps it
+	// needs beyond deploying and checking its addons (e.g. applying and
+	// revoking a license), empty for standalone scenarios. Such scenarios are
+	// only run by their test.
+	Test string `yaml:"test"`
+
+	// Cluster is the topology of the cluster the scenario runs on.
+	Cluster Cluster `yaml:"cluster"`
+
+	// Groups are the testing groups (see groups.yaml) whose addons are
+	// deployed together, and whose checks and seeds are run.
+	Groups []string `yaml:"groups"`
+
+	// Addons are additional catalog addons (e.g. kubernetes base addons which
+	// are not part of a testing group) deployed along with the groups.
+	Addons []string `yaml:"addons"`
+
+	// Overrides are the names of the override layers (directories in
+	// overrides/) which are merged over the values of the addons, in order.
+	Overrides []string `yaml:"overrides"`
+
+	// Checks are the names of additional checks files (in checks/) to run.
+	Checks []string `yaml:"checks"`
+
+	// Prerequisites are the names of seeds (directories in seeds/) which are
+	// deployed before the addons, e.g. an object store the addons are
+	// configured to use, and removed after them.
+	Prerequisites []string `yaml:"prerequisites"`
+
+	// Requires are the capabilities (e.g. node-images) the cluster provider
+	// must have to run the scenario, in addition to those its topology needs.
+	// Scenarios are skipped on providers which lack them.
+	Requires []string `yaml:"requires"`
+
+	// EgressProxy restricts the outbound traffic of the nodes to an HTTP
+	// proxy, as EGRESS_PROXY=true does for every scenario.
+	EgressProxy bool `yaml:"egressProxy"`
+
+	// Backend deploys the addons, either the kubeaddons controller (the
+	// default) or helm releases installed directly.
+	Backend string `yaml:"backend"`
+
+	// DeployFirst are addons of the scenario (e.g. istio) which are deployed
+	// in a wave of their own before all other addons.
+	DeployFirst []string `yaml:"deployFirst"`
+
+	// NamespaceLabels are applied to the namespaces of the addons before they
+	// are deployed (e.g. istio-injection: enabled), except to the namespaces
+	// of the addons deployed first.
+	NamespaceLabels map[string]string `yaml:"namespaceLabels"`
+
+	// Sidecar is the name of a container which must have been injected into
+	// every pod in the labeled namespaces (e.g. istio-proxy).
+	Sidecar string `yaml:"sidecar"`
+
+	// HardenedImages is a regular expression every image of the rendered
+	// addons must match (e.g. -fips$ for the tags of FIPS variants). Images
+	// which don't fail the scenario before its addons are deployed.
+	HardenedImages string `yaml:"hardenedImages"`
+}
+
+// Standalone returns whether the scenario is run by deploying and checking its
+// addons alone, rather than by a test of its own.
+func (s *Scenario) Standalone() bool {
+	return s.Test == ""
+}
+
+const (
+	// ControllerBackend deploys addons with the kubeaddons controller.
+	ControllerBackend = "controller"
+
+	// HelmBackend installs the charts of addons as helm releases directly,
+	// without the kubeaddons controller.
+	HelmBackend = "helm"
+)
+
+// Cluster is the topology of a kind cluster.
+type Cluster struct {
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+	ControlPlanes     int    `yaml:"controlPlanes"`
+	Workers           int    `yaml:"workers"`
+
+	// IPFamily is the address family of the cluster network: ipv4 (the
+	// default), ipv6 or dual.
+	IPFamily string `yaml:"ipFamily"`
+
+	// Containerd is the configuration of the container runtime of the nodes
+	// (cgroup driver, registry auth, image gc), the default of kind if empty.
+	Containerd containerd.Config `yaml:"containerd"`
+
+	// Clock is the timezone and locale of the nodes, UTC and the POSIX
+	// locale if empty.
+	Clock clock.Config `yaml:"clock"`
+}
+
+// Load reads the scenario with the given name from <dir>/<name>.yaml.
+func Load(dir, name string) (*Scenario, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("scenario %s does not exist in %s", name, dir)
+		}
+		return nil, err
+	}
+
+	s := &Scenario{}
+	if err := yaml.UnmarshalStrict(b, s); err != nil {
+		return nil, fmt.Errorf("could not parse scenario %s: %w", name, err)
+	}
+	s.Name = name
+
+	if len(s.Groups) == 0 {
+		return nil, fmt.Errorf("scenario %s has no groups", name)
+	}
+	if s.Test != "" && !strings.HasPrefix(s.Test, "Test") {
+		return nil, fmt.Errorf("scenario %s has test %s, which is not the name of a go test", name, s.Test)
+	}
+	switch s.Backend {
+	case "":
+		s.Backend = ControllerBackend
+	case ControllerBackend, HelmBackend:
+	default:
+		return nil, fmt.Errorf("scenario %s has unknown backend %s, expected %s or %s", name, s.Backend, ControllerBackend, HelmBackend)
+	}
+	if s.Sidecar != "" && len(s.NamespaceLabels) == 0 {
+		return nil, fmt.Errorf("scenario %s has a sidecar but no namespaceLabels injecting it", name)
+	}
+	if _, err := regexp.Compile(s.HardenedImages); err != nil {
+		return nil, fmt.Errorf("scenario %s has invalid hardenedImages: %w", name, err)
+	}
+	family, err := ipfamily.Parse(s.Cluster.IPFamily)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", name, err)
+	}
+	s.Cluster.IPFamily = string(family)
+	if err := s.Cluster.Containerd.Validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", name, err)
+	}
+	if err := s.Cluster.Clock.Validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", name, err)
+	}
+	if s.Cluster.ControlPlanes == 0 {
+		s.Cluster.ControlPlanes = 1
+	}
+
+	return s, nil
+}
+
+// List returns the names of the scenarios in dir.
+func List(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".yaml"))
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ListStandalone returns the names of the standalone scenarios in dir (see
+// Standalone) and those of the scenarios which are run by tests of their own.
+func ListStandalone(dir string) (standalone, stepDriven []string, err error) {
+	names, err := List(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range names {
+		s, err := Load(dir, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if s.Standalone() {
+			standalone = append(standalone, name)
+		} else {
+			stepDriven = append(stepDriven, name)
+		}
+	}
+	return standalone, stepDriven, nil
+}
+
+// Layer is a set of values, keyed by addon name, which are merged over the
+// values of those addons.
+type Layer map[string]string
+
+// LoadLayer reads the override layer in a directory, where each <addon>.yaml
+// file contains the values layered over that addon.
+func LoadLayer(dir string) (Layer, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("override layer %s does not exist", dir)
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("override layer %s is not a directory", dir)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	layer := make(Layer, len(files))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		layer[strings.TrimSuffix(filepath.Base(file), ".yaml")] = string(b)
+	}
+
+	return layer, nil
+}
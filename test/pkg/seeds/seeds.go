@@ -0,0 +1,217 @@
+package seeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Label is set on every seeded object, with the name of the seed as its value.
+const Label = "kubeaddons-kommander.mesosphere.io/seed"
+
+// waitKinds are the kinds of seeded objects which are waited for, mapped to
+// the kubectl arguments which wait for them (with the object appended).
+var waitKinds = map[string][]string{
+	"Deployment":  {"rollout", "status"},
+	"DaemonSet":   {"rollout", "status"},
+	"StatefulSet": {"rollout", "status"},
+	"Job":         {"wait", "--for", "condition=complete"},
+}
+
+// Seed is a set of sample workloads which are deployed so that dashboards and
+// APIs have realistic data to look at when checks run.
+type Seed struct {
+	Name    string
+	Objects []render.Object
+}
+
+// Params parameterize the manifests of seeds, e.g. for air-gapped runs which
+// pull images from a mirror.
+type Params struct {
+	// Registry replaces the registry of the images of seeds, e.g.
+	// "mirror.example.com" pulls "nginx:1.17.8-alpine" from
+	// "mirror.example.com/nginx:1.17.8-alpine".
+	Registry string
+
+	// NamespacePrefix prefixes the namespaces of seeds, e.g. "qa" deploys the
+	// seed-sample-apps namespace as "qa-seed-sample-apps". Each namespace of
+	// a seed is derived from its own name, so seeds (and the prerequisites of
+	// scenarios) never share a namespace which one of them removes.
+	NamespacePrefix string
+
+	// QueryTarget is the URL of the Prometheus API of thanos query which
+	// seeds verify the data they wrote with.
+	QueryTarget string
+}
+
+// Namespace returns the namespace a namespace of seeds is deployed as.
+func (p Params) Namespace(namespace string) string {
+	if p.NamespacePrefix == "" {
+		return namespace
+	}
+	return p.NamespacePrefix + "-" + namespace
+}
+
+// Load reads the seeds in a directory: each YAML file contains the manifests of
+// one seed, which is named after the file. The files are Go templates, which
+// are rendered with params (see funcs). A directory which does not exist
+// contains no seeds.
+func Load(dir string, params Params) ([]Seed, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	seeds := make([]Seed, 0, len(files))
+	for _, file := range files {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		manifests, err := execute(file, string(b), params)
+		if err != nil {
+			return nil, err
+		}
+		objects, err := render.Decode(bytes.NewReader(manifests))
+		if err != nil {
+			return nil, fmt.Errorf("could not decode seed %s: %w", file, err)
+		}
+
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		for _, obj := range objects {
+			setLabel(obj.Content, Label, name)
+		}
+		seeds = append(seeds, Seed{Name: name, Objects: objects})
+	}
+
+	return seeds, nil
+}
+
+// execute renders the manifests of a seed. Besides the params (e.g.
+// {{ .QueryTarget }}), templates can use:
+//
+//	{{ image "nginx:1.17.8-alpine" }}        the image, pulled from the registry
+//	{{ namespace "seed-sample-apps" }}       a namespace of the seeds, prefixed
+//	{{ addonNamespace "istio-system" }}      a namespace of an addon, as is
+//
+// Every namespace of a seed is one of the two: seeds own the namespaces they
+// create, while the namespaces of addons are only written to, and never
+// prefixed since the addons are deployed to them.
+func execute(file, text string, params Params) ([]byte, error) {
+	funcs := template.FuncMap{
+		"image": func(image string) string {
+			return Image(params.Registry, image)
+		},
+		"namespace":      params.Namespace,
+		"addonNamespace": func(namespace string) string { return namespace },
+	}
+
+	tmpl, err := template.New(filepath.Base(file)).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse seed %s: %w", file, err)
+	}
+
+	b := new(bytes.Buffer)
+	if err := tmpl.Execute(b, params); err != nil {
+		return nil, fmt.Errorf("could not render seed %s: %w", file, err)
+	}
+	return b.Bytes(), nil
+}
+
+// Image returns the reference of an image in a registry, replacing the
+// registry of the image (if any). An empty registry leaves the image as is.
+func Image(registry, image string) string {
+	if registry == "" {
+		return image
+	}
+	if i := strings.Index(image, "/"); i >= 0 {
+		// as in docker, the first component is a registry if it looks like a
+		// host name
+		if host := image[:i]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			image = image[i+1:]
+		}
+	}
+	return strings.TrimSuffix(registry, "/") + "/" + image
+}
+
+// Apply creates (or updates) the objects of the seed.
+func (s Seed) Apply(k kubectl.Client) error {
+	list := map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": s.items()}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return k.Apply(b)
+}
+
+// Wait waits for the workloads of the seed to be rolled out (or for jobs to
+// complete) within the timeout.
+func (s Seed) Wait(k kubectl.Client, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, obj := range s.Objects {
+		args, ok := waitKinds[obj.Kind]
+		if !ok {
+			continue
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("seed %s was not ready within %s", s.Name, timeout)
+		}
+
+		args = append(args, strings.ToLower(obj.Kind)+"/"+obj.Name, "--timeout", remaining.String())
+		if obj.Namespace != "" {
+			args = append(args, "--namespace", obj.Namespace)
+		}
+		if _, err := k.Output(args...); err != nil {
+			return fmt.Errorf("seed %s was not ready: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes the objects of the seed, in the reverse order they were
+// declared in (so that namespaces are removed last).
+func (s Seed) Delete(k kubectl.Client) error {
+	items := s.items()
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+
+	list := map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items}
+	b, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return k.RunWithInput(b, "delete", "--ignore-not-found", "--wait", "--filename", "-")
+}
+
+func (s Seed) items() []interface{} {
+	items := make([]interface{}, 0, len(s.Objects))
+	for _, obj := range s.Objects {
+		items = append(items, obj.Content)
+	}
+	return items
+}
+
+func setLabel(content map[string]interface{}, key, value string) {
+	metadata, ok := content["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		content["metadata"] = metadata
+	}
+	labels, ok := metadata["labels"].(map[string]interface{})
+	if !ok {
+		labels = make(map[string]interface{})
+		metadata["labels"] = labels
+	}
+	labels[key] = value
+}
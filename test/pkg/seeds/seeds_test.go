@@ -0,0 +1,60 @@
+package seeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecute(t *testing.T) {
+	text := `metadata:
+  namespace: {{ namespace "seed-sample-apps" }}
+spec:
+  image: {{ image "nginx:1.17.8-alpine" }}
+  mesh: {{ addonNamespace "istio-system" }}
+  query: {{ .QueryTarget }}
+`
+
+	b, err := execute("sample.yaml", text, Params{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(b); !strings.Contains(out, "namespace: seed-sample-apps") || !strings.Contains(out, "image: nginx:1.17.8-alpine") {
+		t.Errorf("expected the defaults without params, got:\n%s", out)
+	}
+
+	params := Params{Registry: "mirror.example.com:5000", NamespacePrefix: "qa", QueryTarget: "http://thanos-query.kommander.svc:10902"}
+	b, err = execute("sample.yaml", text, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"namespace: qa-seed-sample-apps",
+		"image: mirror.example.com:5000/nginx:1.17.8-alpine",
+		"mesh: istio-system",
+		"query: http://thanos-query.kommander.svc:10902",
+	} {
+		if out := string(b); !strings.Contains(out, expected) {
+			t.Errorf("expected %q with the params applied, got:\n%s", expected, out)
+		}
+	}
+
+	if _, err := execute("sample.yaml", "{{ .Unknown }}", Params{}); err == nil {
+		t.Error("expected an error for an unknown parameter")
+	}
+}
+
+func TestImage(t *testing.T) {
+	for _, tc := range []struct {
+		registry, image, expected string
+	}{
+		{"", "nginx:1.17.8-alpine", "nginx:1.17.8-alpine"},
+		{"mirror.example.com", "nginx:1.17.8-alpine", "mirror.example.com/nginx:1.17.8-alpine"},
+		{"mirror.example.com/", "library/busybox:1.31.1", "mirror.example.com/library/busybox:1.31.1"},
+		{"mirror.example.com", "quay.io/prometheus/prometheus:v2.15.2", "mirror.example.com/prometheus/prometheus:v2.15.2"},
+		{"mirror.example.com", "localhost/sample:latest", "mirror.example.com/sample:latest"},
+	} {
+		if actual := Image(tc.registry, tc.image); actual != tc.expected {
+			t.Errorf("Image(%q, %q): expected %q, got %q", tc.registry, tc.image, tc.expected, actual)
+		}
+	}
+}
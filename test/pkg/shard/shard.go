@@ -0,0 +1,124 @@
+package shard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/history"
+)
+
+// Shard is the part of the scenarios one of several CI agents runs.
+type Shard struct {
+	// Index is the index of the shard, from 0 to Total-1.
+	Index int
+
+	Total int
+}
+
+// Parse returns the shard of the given index of total shards, a single shard
+// running everything when both are empty.
+func Parse(index, total string) (Shard, error) {
+	if index == "" && total == "" {
+		return Shard{Index: 0, Total: 1}, nil
+	}
+	s := Shard{}
+	var err error
+	if s.Index, err = strconv.Atoi(index); err != nil {
+		return s, fmt.Errorf("the shard index %q is not a number", index)
+	}
+	if s.Total, err = strconv.Atoi(total); err != nil || s.Total < 1 {
+		return s, fmt.Errorf("the number of shards %q is not a positive number", total)
+	}
+	if s.Index < 0 || s.Index >= s.Total {
+		return s, fmt.Errorf("the shard index %d is not between 0 and %d", s.Index, s.Total-1)
+	}
+	return s, nil
+}
+
+func (s Shard) String() string {
+	return fmt.Sprintf("%d/%d", s.Index, s.Total)
+}
+
+// Plan is the assignment of scenarios to shards.
+type Plan struct {
+	// Shards are the scenarios of every shard, sorted.
+	Shards [][]string
+
+	// Durations are the estimated durations of the shards.
+	Durations []time.Duration
+}
+
+// Partition assigns scenarios to total shards, balanced by their durations:
+// the longest scenarios are assigned first, every scenario to the shard with
+// the shortest duration so far (the lowest index of equally long shards).
+// Scenarios without a duration are estimated at the mean duration of the
+// others, equally if none has one. The plan only depends on its arguments, so
+// every agent partitions the same scenarios and durations alike.
+func Partition(scenarios []string, durations map[string]time.Duration, total int) Plan {
+	estimates := make(map[string]time.Duration, len(scenarios))
+	var sum time.Duration
+	known := 0
+	for _, name := range scenarios {
+		if d, ok := durations[name]; ok {
+			sum += d
+			known++
+		}
+	}
+	estimate := time.Minute
+	if known > 0 {
+		estimate = sum / time.Duration(known)
+	}
+	for _, name := range scenarios {
+		if d, ok := durations[name]; ok {
+			estimates[name] = d
+		} else {
+			estimates[name] = estimate
+		}
+	}
+
+	sorted := append([]string{}, scenarios...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if estimates[sorted[i]] != estimates[sorted[j]] {
+			return estimates[sorted[i]] > estimates[sorted[j]]
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	plan := Plan{Shards: make([][]string, total), Durations: make([]time.Duration, total)}
+	for _, name := range sorted {
+		shortest := 0
+		for i := range plan.Durations {
+			if plan.Durations[i] < plan.Durations[shortest] {
+				shortest = i
+			}
+		}
+		plan.Shards[shortest] = append(plan.Shards[shortest], name)
+		plan.Durations[shortest] += estimates[name]
+	}
+	for _, names := range plan.Shards {
+		sort.Strings(names)
+	}
+	return plan
+}
+
+// Contains returns whether a scenario is assigned to a shard.
+func (p Plan) Contains(s Shard, scenario string) bool {
+	for _, name := range p.Shards[s.Index] {
+		if name == scenario {
+			return true
+		}
+	}
+	return false
+}
+
+// Durations returns the mean durations of the scenarios in the last runs of a
+// history of runs.
+func Durations(runs []history.Run) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+	for _, timing := range history.Timings(runs) {
+		durations[timing.Scenario] = timing.Mean
+	}
+	return durations
+}
@@ -0,0 +1,94 @@
+package shard
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/history"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		index, total string
+		expected     Shard
+		valid        bool
+	}{
+		{"", "", Shard{Index: 0, Total: 1}, true},
+		{"2", "3", Shard{Index: 2, Total: 3}, true},
+		{"3", "3", Shard{}, false},
+		{"-1", "3", Shard{}, false},
+		{"0", "0", Shard{}, false},
+		{"", "3", Shard{}, false},
+		{"first", "3", Shard{}, false},
+	} {
+		s, err := Parse(tc.index, tc.total)
+		if (err == nil) != tc.valid || (tc.valid && s != tc.expected) {
+			t.Errorf("expected shard %q of %q to be %v (valid: %t), got %v, %v", tc.index, tc.total, tc.expected, tc.valid, s, err)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	scenarios := []string{"kommander-minimal", "kommander-full-ha", "kommander-istio", "kommander-ipv6", "kommander-fips", "kommander-new"}
+	durations := map[string]time.Duration{
+		"kommander-minimal": 20 * time.Minute,
+		"kommander-full-ha": 50 * time.Minute,
+		"kommander-istio":   40 * time.Minute,
+		"kommander-ipv6":    25 * time.Minute,
+		"kommander-fips":    15 * time.Minute,
+		"kommander-removed": 90 * time.Minute,
+	}
+
+	// the new scenario is estimated at the mean of 30m
+	plan := Partition(scenarios, durations, 3)
+	expected := Plan{
+		Shards:    [][]string{{"kommander-fips", "kommander-full-ha"}, {"kommander-istio", "kommander-minimal"}, {"kommander-ipv6", "kommander-new"}},
+		Durations: []time.Duration{65 * time.Minute, 60 * time.Minute, 55 * time.Minute},
+	}
+	if !reflect.DeepEqual(expected, plan) {
+		t.Fatalf("expected %v, got %v", expected, plan)
+	}
+	if !plan.Contains(Shard{Index: 1, Total: 3}, "kommander-istio") || plan.Contains(Shard{Index: 0, Total: 3}, "kommander-istio") {
+		t.Error("expected kommander-istio to be assigned to shard 1 only")
+	}
+
+	// the order of the scenarios doesn't matter
+	reversed := make([]string, len(scenarios))
+	for i, name := range scenarios {
+		reversed[len(scenarios)-1-i] = name
+	}
+	if again := Partition(reversed, durations, 3); !reflect.DeepEqual(plan, again) {
+		t.Errorf("expected the same plan for reordered scenarios, got %v", again)
+	}
+
+	// without durations the scenarios are spread evenly
+	even := Partition(scenarios, nil, 4)
+	for i, names := range even.Shards {
+		if expected := 1 + btoi(i < 2); len(names) != expected {
+			t.Errorf("expected shard %d to have %d scenarios, got %v", i, expected, names)
+		}
+	}
+
+	if single := Partition(scenarios, durations, 1); len(single.Shards[0]) != len(scenarios) {
+		t.Errorf("expected a single shard to run every scenario, got %v", single)
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestDurations(t *testing.T) {
+	runs := []history.Run{
+		{Scenarios: map[string]time.Duration{"kommander-minimal": 20 * time.Minute, "kommander-fips": 10 * time.Minute}},
+		{Scenarios: map[string]time.Duration{"kommander-minimal": 30 * time.Minute}},
+	}
+	expected := map[string]time.Duration{"kommander-minimal": 25 * time.Minute, "kommander-fips": 10 * time.Minute}
+	if durations := Durations(runs); !reflect.DeepEqual(expected, durations) {
+		t.Errorf("expected %v, got %v", expected, durations)
+	}
+}
@@ -0,0 +1,292 @@
+package spend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	// RunTag is the tag of cloud resources with the name of the test run
+	// which created them.
+	RunTag = "kubeaddons-kommander.mesosphere.io/run"
+
+	// DeadlineTag is the tag of cloud resources with the time (RFC 3339) after
+	// which they are swept.
+	DeadlineTag = "kubeaddons-kommander.mesosphere.io/deadline"
+)
+
+// Limits are the limits of test runs on cloud providers.
+type Limits struct {
+	// MaxDuration is how long a run may use cloud resources, e.g. 3h.
+	MaxDuration time.Duration `yaml:"-"`
+
+	// MaxInstances is the maximum number of instances of a cluster.
+	MaxInstances int `yaml:"maxInstances"`
+
+	// InstanceTypes are the instance types clusters may use.
+	InstanceTypes []string `yaml:"instanceTypes"`
+
+	// Tags are added to all cloud resources, in addition to RunTag and
+	// DeadlineTag.
+	Tags map[string]string `yaml:"tags"`
+}
+
+// Load reads the limits in a file. The maximum duration and the maximum number
+// of instances are mandatory.
+func Load(path string) (*Limits, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Limits      `yaml:",inline"`
+		MaxDuration string `yaml:"maxDuration"`
+	}
+	if err := yaml.UnmarshalStrict(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse spend limits %s: %w", path, err)
+	}
+
+	l := file.Limits
+	if file.MaxDuration == "" {
+		return nil, fmt.Errorf("spend limits %s have no maxDuration", path)
+	}
+	if l.MaxDuration, err = time.ParseDuration(file.MaxDuration); err != nil {
+		return nil, fmt.Errorf("spend limits %s have an invalid maxDuration: %w", path, err)
+	}
+	if l.MaxInstances <= 0 {
+		return nil, fmt.Errorf("spend limits %s have no maxInstances", path)
+	}
+	return &l, nil
+}
+
+// Budget is the budget of a test run, which started at a point in time.
+type Budget struct {
+	Limits
+
+	// Run is the name of the test run.
+	Run string
+
+	// Deadline is the time after which the cloud resources of the run are
+	// torn down.
+	Deadline time.Time
+}
+
+// Start returns the budget of a run starting at a point in time.
+func (l Limits) Start(run string, start time.Time) *Budget {
+	return &Budget{Limits: l, Run: run, Deadline: start.Add(l.MaxDuration)}
+}
+
+// Remaining returns the time left until the deadline.
+func (b *Budget) Remaining(now time.Time) time.Duration {
+	return b.Deadline.Sub(now)
+}
+
+// Tags returns the tags of the cloud resources of the run.
+func (b *Budget) Tags() map[string]string {
+	tags := make(map[string]string, len(b.Limits.Tags)+2)
+	for key, value := range b.Limits.Tags {
+		tags[key] = value
+	}
+	tags[RunTag] = b.Run
+	tags[DeadlineTag] = b.Deadline.UTC().Format(time.RFC3339)
+	return tags
+}
+
+// Check verifies that the Cluster API objects of a cluster stay within the
+// limits: the replicas of its control plane and machine deployments (the
+// instances) and the instance types of its machine templates.
+func (b *Budget) Check(objects []render.Object) error {
+	allowed := make(map[string]bool, len(b.InstanceTypes))
+	for _, instanceType := range b.InstanceTypes {
+		allowed[instanceType] = true
+	}
+
+	instances := 0
+	var errs []string
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "KubeadmControlPlane", "MachineDeployment", "MachinePool":
+			replicas, _ := lookup(obj.Content, "spec", "replicas").(int)
+			instances += replicas
+		case "AWSMachineTemplate":
+			instanceType, _ := lookup(obj.Content, "spec", "template", "spec", "instanceType").(string)
+			if len(allowed) > 0 && !allowed[instanceType] {
+				errs = append(errs, fmt.Sprintf("%s %s uses instance type %q, allowed are %s", obj.Kind, obj.Name, instanceType, strings.Join(b.InstanceTypes, ", ")))
+			}
+		}
+	}
+	if instances > b.MaxInstances {
+		errs = append(errs, fmt.Sprintf("%d instances exceed the maximum of %d", instances, b.MaxInstances))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("cluster exceeds the spend limits: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Tag adds the tags of the run to the AWSCluster objects, which the Cluster
+// API provider for AWS adds to all resources it creates for the cluster.
+func (b *Budget) Tag(objects []render.Object) {
+	for _, obj := range objects {
+		if obj.Kind != "AWSCluster" {
+			continue
+		}
+		spec, ok := obj.Content["spec"].(map[string]interface{})
+		if !ok {
+			spec = make(map[string]interface{})
+			obj.Content["spec"] = spec
+		}
+		tags, ok := spec["additionalTags"].(map[string]interface{})
+		if !ok {
+			tags = make(map[string]interface{})
+			spec["additionalTags"] = tags
+		}
+		for key, value := range b.Tags() {
+			tags[key] = value
+		}
+	}
+}
+
+func lookup(content map[string]interface{}, path ...string) interface{} {
+	var value interface{} = content
+	for _, key := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// Resource is a tagged cloud resource.
+type Resource struct {
+	ARN      string
+	Deadline time.Time
+}
+
+// Expired returns the resources of an AWS region whose deadline passed.
+func Expired(region string, now time.Time) ([]Resource, error) {
+	out, err := aws(region, "resourcegroupstaggingapi", "get-resources", "--tag-filters", "Key="+DeadlineTag, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	resources, err := decode(out)
+	if err != nil {
+		return nil, err
+	}
+
+	var expired []Resource
+	for _, resource := range resources {
+		if now.After(resource.Deadline) {
+			expired = append(expired, resource)
+		}
+	}
+	return expired, nil
+}
+
+func decode(b []byte) ([]Resource, error) {
+	var list struct {
+		ResourceTagMappingList []struct {
+			ResourceARN string
+			Tags        []struct {
+				Key   string
+				Value string
+			}
+		}
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("could not decode tagged resources: %w", err)
+	}
+
+	var resources []Resource
+	for _, mapping := range list.ResourceTagMappingList {
+		for _, tag := range mapping.Tags {
+			if tag.Key != DeadlineTag {
+				continue
+			}
+			deadline, err := time.Parse(time.RFC3339, tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("resource %s has an invalid deadline: %w", mapping.ResourceARN, err)
+			}
+			resources = append(resources, Resource{ARN: mapping.ResourceARN, Deadline: deadline})
+		}
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return sweepOrder(resources[i].ARN) < sweepOrder(resources[j].ARN)
+	})
+	return resources, nil
+}
+
+// sweepers delete resources by the resource type of their ARN, in the order
+// they depend on each other (addresses are released once the NAT gateways
+// which use them are gone).
+var sweepers = []struct {
+	resourceType string
+	args         func(id string) []string
+}{
+	{"instance", func(id string) []string { return []string{"ec2", "terminate-instances", "--instance-ids", id} }},
+	{"loadbalancer", func(id string) []string { return []string{"elb", "delete-load-balancer", "--load-balancer-name", id} }},
+	{"natgateway", func(id string) []string { return []string{"ec2", "delete-nat-gateway", "--nat-gateway-id", id} }},
+	{"elastic-ip", func(id string) []string { return []string{"ec2", "release-address", "--allocation-id", id} }},
+}
+
+// resourceID returns the type and id of the resource of an ARN, e.g.
+// instance and i-0123 for arn:aws:ec2:us-west-2:123456789012:instance/i-0123.
+func resourceID(arn string) (string, string) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) < 6 {
+		return "", ""
+	}
+	resource := strings.SplitN(parts[5], "/", 2)
+	if len(resource) < 2 {
+		return resource[0], ""
+	}
+	return resource[0], resource[1]
+}
+
+func sweepOrder(arn string) int {
+	resourceType, _ := resourceID(arn)
+	for i, sweeper := range sweepers {
+		if sweeper.resourceType == resourceType {
+			return i
+		}
+	}
+	return len(sweepers)
+}
+
+// Sweep deletes a resource in an AWS region. It returns false for resources
+// which are not deleted by type (e.g. VPCs and subnets), which don't incur
+// costs themselves and are left to be removed manually.
+func Sweep(region string, resource Resource) (bool, error) {
+	resourceType, id := resourceID(resource.ARN)
+	for _, sweeper := range sweepers {
+		if sweeper.resourceType == resourceType {
+			_, err := aws(region, sweeper.args(id)...)
+			return true, err
+		}
+	}
+	return false, nil
+}
+
+func aws(region string, args ...string) ([]byte, error) {
+	var stdout, stderr strings.Builder
+	cmd := exec.Command("aws", append([]string{"--region", region}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("aws %s failed: %w: %s", strings.Join(args[:2], " "), err, stderr.String())
+	}
+	return []byte(stdout.String()), nil
+}
@@ -0,0 +1,71 @@
+package spend
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestCheck(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	budget := Limits{MaxDuration: 3 * time.Hour, MaxInstances: 4, InstanceTypes: []string{"t3.large"}}.Start("kommander", start)
+
+	objects := []render.Object{
+		{Kind: "KubeadmControlPlane", Name: "kommander-control-plane", Content: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}}},
+		{Kind: "MachineDeployment", Name: "kommander-md-0", Content: map[string]interface{}{"spec": map[string]interface{}{"replicas": 1}}},
+		{Kind: "AWSMachineTemplate", Name: "kommander-md-0", Content: map[string]interface{}{"spec": map[string]interface{}{
+			"template": map[string]interface{}{"spec": map[string]interface{}{"instanceType": "t3.large"}},
+		}}},
+	}
+	if err := budget.Check(objects); err != nil {
+		t.Errorf("expected cluster within the limits, got %s", err)
+	}
+
+	objects[1].Content["spec"].(map[string]interface{})["replicas"] = 2
+	objects[2].Content["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"].(map[string]interface{})["instanceType"] = "p3.16xlarge"
+	err := budget.Check(objects)
+	if err == nil || !strings.Contains(err.Error(), `"p3.16xlarge"`) || !strings.Contains(err.Error(), "5 instances exceed the maximum of 4") {
+		t.Errorf("expected both limits to be exceeded, got %v", err)
+	}
+}
+
+func TestTag(t *testing.T) {
+	start := time.Date(2020, 3, 1, 10, 0, 0, 0, time.UTC)
+	budget := Limits{MaxDuration: 3 * time.Hour, Tags: map[string]string{"team": "kommander-qa"}}.Start("kommander", start)
+
+	cluster := render.Object{Kind: "AWSCluster", Name: "kommander", Content: map[string]interface{}{"spec": map[string]interface{}{"region": "us-west-2"}}}
+	budget.Tag([]render.Object{cluster})
+
+	tags := cluster.Content["spec"].(map[string]interface{})["additionalTags"].(map[string]interface{})
+	if tags["team"] != "kommander-qa" || tags[RunTag] != "kommander" || tags[DeadlineTag] != "2020-03-01T13:00:00Z" {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
+
+const resourcesJSON = `{"ResourceTagMappingList": [
+  {"ResourceARN": "arn:aws:ec2:us-west-2:123456789012:elastic-ip/eipalloc-0a1b", "Tags": [{"Key": "kubeaddons-kommander.mesosphere.io/deadline", "Value": "2020-03-01T13:00:00Z"}]},
+  {"ResourceARN": "arn:aws:ec2:us-west-2:123456789012:vpc/vpc-0c2d", "Tags": [{"Key": "kubeaddons-kommander.mesosphere.io/deadline", "Value": "2020-03-01T13:00:00Z"}]},
+  {"ResourceARN": "arn:aws:ec2:us-west-2:123456789012:instance/i-0e3f", "Tags": [{"Key": "kubeaddons-kommander.mesosphere.io/deadline", "Value": "2020-03-01T13:00:00Z"}]}
+]}`
+
+func TestDecode(t *testing.T) {
+	resources, err := decode([]byte(resourcesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("expected 3 resources, got %d", len(resources))
+	}
+
+	// instances are swept first, resources which are not swept last
+	for i, expected := range []string{"instance", "elastic-ip", "vpc"} {
+		if resourceType, _ := resourceID(resources[i].ARN); resourceType != expected {
+			t.Errorf("expected %s at %d, got %s", expected, i, resources[i].ARN)
+		}
+	}
+	if _, id := resourceID(resources[0].ARN); id != "i-0e3f" {
+		t.Errorf("expected instance id i-0e3f, got %s", id)
+	}
+}
@@ -0,0 +1,197 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxLogLines is the number of recent log lines which are kept.
+const maxLogLines = 200
+
+// Scenario is the current state of a running scenario.
+type Scenario struct {
+	Name    string            `json:"name"`
+	Phase   string            `json:"phase"`
+	Since   time.Time         `json:"since"`
+	Addons  map[string]string `json:"addons"`
+	Started time.Time         `json:"started"`
+}
+
+// LogLine is a line logged by the harness.
+type LogLine struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Server exposes the progress of a test run over HTTP as JSON (/status.json)
+// and HTML (/). All methods are safe to call on a nil *Server, which does
+// nothing, so that callers don't need to check whether it is enabled.
+type Server struct {
+	lock      sync.Mutex
+	started   time.Time
+	scenarios map[string]*Scenario
+	logs      []LogLine
+
+	listener net.Listener
+}
+
+// Start listens on addr (e.g. ":8080") and serves the status in the background.
+func Start(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{started: time.Now(), scenarios: make(map[string]*Scenario), listener: listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status.json", s.serveJSON)
+	mux.HandleFunc("/", s.serveHTML)
+	go http.Serve(listener, mux)
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	if s == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop stops serving.
+func (s *Server) Stop() error {
+	if s == nil {
+		return nil
+	}
+	return s.listener.Close()
+}
+
+// SetPhase records the phase a scenario is in.
+func (s *Server) SetPhase(scenario, phase string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sc := s.scenario(scenario)
+	sc.Phase = phase
+	sc.Since = time.Now()
+	s.log(fmt.Sprintf("scenario %s: %s", scenario, phase))
+}
+
+// SetAddonStatus records the status of an addon of a scenario.
+func (s *Server) SetAddonStatus(scenario, addon, status string) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.scenario(scenario).Addons[addon] = status
+}
+
+// Logf records a log line.
+func (s *Server) Logf(format string, args ...interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.log(fmt.Sprintf(format, args...))
+}
+
+func (s *Server) log(message string) {
+	s.logs = append(s.logs, LogLine{Time: time.Now(), Message: message})
+	if len(s.logs) > maxLogLines {
+		s.logs = s.logs[len(s.logs)-maxLogLines:]
+	}
+}
+
+func (s *Server) scenario(name string) *Scenario {
+	sc, ok := s.scenarios[name]
+	if !ok {
+		sc = &Scenario{Name: name, Addons: make(map[string]string), Started: time.Now()}
+		s.scenarios[name] = sc
+	}
+	return sc
+}
+
+type snapshot struct {
+	Started   time.Time  `json:"started"`
+	Scenarios []Scenario `json:"scenarios"`
+	Logs      []LogLine  `json:"logs"`
+}
+
+func (s *Server) snapshot() snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	snap := snapshot{Started: s.started, Logs: append([]LogLine(nil), s.logs...)}
+	for _, sc := range s.scenarios {
+		addons := make(map[string]string, len(sc.Addons))
+		for k, v := range sc.Addons {
+			addons[k] = v
+		}
+		scenario := *sc
+		scenario.Addons = addons
+		snap.Scenarios = append(snap.Scenarios, scenario)
+	}
+	sort.Slice(snap.Scenarios, func(i, j int) bool { return snap.Scenarios[i].Name < snap.Scenarios[j].Name })
+
+	return snap
+}
+
+func (s *Server) serveJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var page = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="10">
+<title>Addon Tests</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ccc; padding: 0.3em 0.8em; text-align: left; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>Addon Tests</h1>
+<p>Running since {{ .Started.Format "15:04:05" }}</p>
+{{ range .Scenarios }}
+<h2>{{ .Name }}: {{ .Phase }} (since {{ .Since.Format "15:04:05" }})</h2>
+<table>
+<tr><th>Addon</th><th>Status</th></tr>
+{{ range $addon, $status := .Addons }}<tr><td>{{ $addon }}</td><td>{{ $status }}</td></tr>
+{{ end }}
+</table>
+{{ end }}
+<h2>Recent Logs</h2>
+<pre>{{ range .Logs }}{{ .Time.Format "15:04:05" }} {{ .Message }}
+{{ end }}</pre>
+</body>
+</html>
+`))
+
+func (s *Server) serveHTML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := page.Execute(w, s.snapshot()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,55 @@
+package status
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s, err := Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	s.SetPhase("kommander-minimal", "deploying")
+	s.SetAddonStatus("kommander-minimal", "traefik", "deploying")
+	s.Logf("deploying %d addons", 8)
+
+	resp, err := http.Get("http://" + s.Addr() + "/status.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	snap := snapshot{}
+	if err := json.NewDecoder(resp.Body).Decode(&snap); err != nil {
+		t.Fatal(err)
+	}
+	if len(snap.Scenarios) != 1 || snap.Scenarios[0].Phase != "deploying" || snap.Scenarios[0].Addons["traefik"] != "deploying" {
+		t.Errorf("unexpected scenarios in status: %+v", snap.Scenarios)
+	}
+	if len(snap.Logs) != 2 || snap.Logs[1].Message != "deploying 8 addons" {
+		t.Errorf("unexpected logs in status: %+v", snap.Logs)
+	}
+
+	resp, err = http.Get("http://" + s.Addr() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected HTML status page, got %s", resp.Status)
+	}
+}
+
+func TestNilServer(t *testing.T) {
+	var s *Server
+	s.SetPhase("kommander-minimal", "deploying")
+	s.SetAddonStatus("kommander-minimal", "traefik", "deploying")
+	s.Logf("nothing happens")
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
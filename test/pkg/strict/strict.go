@@ -0,0 +1,112 @@
+package strict
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	jsonUnmarshaler = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// UnknownFields returns the paths (e.g. "spec.chartReferance") of all fields in
+// content which don't exist in the JSON representation of the type of v. The
+// content is a generic decoding of a YAML or JSON document (maps, slices and
+// scalars). Types which implement their own unmarshaling are not inspected.
+func UnknownFields(content interface{}, v interface{}) []string {
+	var unknown []string
+	walk(content, reflect.TypeOf(v), "", &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func walk(content interface{}, t reflect.Type, path string, unknown *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshaler) || reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := content.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, value := range m {
+			field, ok := fields[key]
+			if !ok {
+				*unknown = append(*unknown, join(path, key))
+				continue
+			}
+			walk(value, field, join(path, key), unknown)
+		}
+	case reflect.Map:
+		m, ok := content.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for key, value := range m {
+			walk(value, t.Elem(), join(path, key), unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := content.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+	}
+}
+
+// jsonFields returns the types of the fields of a struct keyed by their JSON
+// names, including the fields of embedded and inlined structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		inline := strings.Contains(tag, ",inline") || (field.Anonymous && name == "")
+		if inline {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,63 @@
+package strict
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type meta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type chartReference struct {
+	Chart  string  `json:"chart"`
+	Values *string `json:"values,omitempty"`
+}
+
+type TypeMeta struct {
+	Kind string `json:"kind"`
+}
+
+type addon struct {
+	TypeMeta `json:",inline"`
+	Metadata meta `json:"metadata"`
+	Spec     struct {
+		ChartReference *chartReference `json:"chartReference,omitempty"`
+		Requires       []meta          `json:"requires,omitempty"`
+		Created        time.Time       `json:"created,omitempty"`
+	} `json:"spec"`
+	Ignored string `json:"-"`
+}
+
+func TestUnknownFields(t *testing.T) {
+	content := map[string]interface{}{
+		"kind": "ClusterAddon",
+		"metadata": map[string]interface{}{
+			"name":   "kommander",
+			"labels": map[string]interface{}{"any": "label"},
+			"label":  "typo",
+		},
+		"spec": map[string]interface{}{
+			"chartReferance": map[string]interface{}{"chart": "kommander"},
+			"requires": []interface{}{
+				map[string]interface{}{"name": "cert-manager"},
+				map[string]interface{}{"matchLabel": "typo"},
+			},
+			"created": "2020-01-01T00:00:00Z",
+		},
+		"Ignored": "not a known field",
+	}
+
+	expected := []string{
+		"Ignored",
+		"metadata.label",
+		"spec.chartReferance",
+		"spec.requires[1].matchLabel",
+	}
+
+	if actual := UnknownFields(content, addon{}); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected unknown fields %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,147 @@
+package teardown
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// dashboardLabel marks the configmaps the grafana sidecar loads dashboards
+// from.
+const dashboardLabel = "grafana_dashboard"
+
+// Monitoring is the monitoring configuration of a cluster: its PrometheusRules,
+// ServiceMonitors, grafana dashboard configmaps and alertmanager configuration
+// secrets, as <kind>/<namespace>/<name> mapped to a digest of the alertmanager
+// configuration (empty for the other kinds, whose existence is what matters).
+type Monitoring map[string]string
+
+// MonitoringSnapshot returns the monitoring configuration of the cluster of
+// a kubectl client. Kinds whose CRDs are not installed have none.
+func MonitoringSnapshot(k kubectl.Client) (Monitoring, error) {
+	m := make(Monitoring)
+	for _, kind := range []string{"prometheusrule", "servicemonitor"} {
+		names, err := list(k, kind, "--all-namespaces")
+		if err != nil {
+			if strings.Contains(err.Error(), "the server doesn't have a resource type") {
+				continue
+			}
+			return nil, err
+		}
+		for _, name := range names {
+			m[kind+"/"+name] = ""
+		}
+	}
+
+	dashboards, err := list(k, "configmap", "--all-namespaces", "--selector", dashboardLabel)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range dashboards {
+		m["configmap/"+name] = ""
+	}
+
+	b, err := k.Output("get", "secrets", "--all-namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	secrets := secretList{}
+	if err := json.Unmarshal(b, &secrets); err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets.Items {
+		config, ok := secret.Data["alertmanager.yaml"]
+		if !ok || !strings.HasPrefix(secret.Metadata.Name, "alertmanager-") {
+			continue
+		}
+		m["secret/"+secret.Metadata.Namespace+"/"+secret.Metadata.Name] = fmt.Sprintf("%x", sha256.Sum256([]byte(config)))
+	}
+	return m, nil
+}
+
+type secretList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Data map[string]string `json:"data"`
+	} `json:"items"`
+}
+
+// list lists the resources of a kind as <namespace>/<name>.
+func list(k kubectl.Client, kind string, args ...string) ([]string, error) {
+	args = append([]string{"get", kind, "--output", "jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name}{\"\\n\"}{end}"}, args...)
+	b, err := k.Output(args...)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line != "" {
+			names = append(names, line)
+		}
+	}
+	return names, nil
+}
+
+// VerifyMonitoring waits up to timeout for the monitoring configuration of the
+// cluster to return to the baseline taken before the addons were deployed,
+// then reports the monitoring resources the addons left behind and the
+// alertmanager configurations which differ from the baseline. Resources are
+// attributed to the addons whose rendered manifests contain them.
+func VerifyMonitoring(k kubectl.Client, baseline Monitoring, objects []render.Object, timeout time.Duration) ([]Problem, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		current, err := MonitoringSnapshot(k)
+		if err != nil {
+			return nil, err
+		}
+		problems := baseline.diff(current, objects)
+		if len(problems) == 0 || time.Now().After(deadline) {
+			return problems, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// diff returns the problems of a monitoring configuration compared to the
+// baseline.
+func (baseline Monitoring) diff(current Monitoring, objects []render.Object) []Problem {
+	// rendered objects often lack the namespace they are deployed to, so they
+	// are matched by kind and name only
+	owners := make(map[string]string)
+	for _, obj := range objects {
+		owners[strings.ToLower(obj.Kind)+"/"+obj.Name] = obj.Addon
+	}
+	owner := func(resource string) string {
+		parts := strings.Split(resource, "/")
+		return owners[parts[0]+"/"+parts[len(parts)-1]]
+	}
+
+	resources := make([]string, 0, len(current))
+	for resource := range current {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+
+	var problems []Problem
+	for _, resource := range resources {
+		digest, existed := baseline[resource]
+		switch {
+		case !existed && strings.HasPrefix(resource, "secret/"):
+			problems = append(problems, Problem{Resource: resource, Addon: owner(resource), Reason: "alertmanager configuration was not removed"})
+		case !existed:
+			problems = append(problems, Problem{Resource: resource, Addon: owner(resource), Reason: "was not removed"})
+		case digest != current[resource]:
+			problems = append(problems, Problem{Resource: resource, Addon: owner(resource), Reason: "alertmanager configuration differs from before the addons were deployed"})
+		}
+	}
+	return problems
+}
@@ -0,0 +1,76 @@
+package teardown
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// removed returns the rendered objects which are expected to be gone once
+// their addons were removed, as <kind>[.<group>] mapped to the objects of
+// that resource. Namespaces (which the controller keeps), CRDs (see Verify),
+// hooks (which helm doesn't delete) and objects with a "keep" resource policy
+// remain.
+func removed(objects []render.Object) map[string][]render.Object {
+	resources := make(map[string][]render.Object)
+	for _, obj := range objects {
+		if obj.Kind == "Namespace" || obj.Kind == "CustomResourceDefinition" || obj.Name == "" {
+			continue
+		}
+		metadata, _ := obj.Content["metadata"].(map[string]interface{})
+		annotations, _ := metadata["annotations"].(map[string]interface{})
+		if policy, _ := annotations[resourcePolicyAnnotation].(string); policy == "keep" {
+			continue
+		}
+		if _, hook := annotations[hookAnnotation]; hook {
+			continue
+		}
+		resource := strings.ToLower(obj.Kind)
+		if i := strings.Index(obj.APIVersion, "/"); i >= 0 {
+			resource += "." + obj.APIVersion[:i]
+		}
+		resources[resource] = append(resources[resource], obj)
+	}
+	return resources
+}
+
+// VerifyObjects waits up to timeout for the rendered objects of removed addons
+// to be gone (see removed), then reports those which remain. As rendered
+// objects often lack the namespace they are deployed to, objects are matched
+// by resource and name only.
+func VerifyObjects(k kubectl.Client, objects []render.Object, timeout time.Duration) ([]Problem, error) {
+	resources := removed(objects)
+	names := make([]string, 0, len(resources))
+	for resource := range resources {
+		names = append(names, resource)
+	}
+	sort.Strings(names)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		var problems []Problem
+		for _, resource := range names {
+			for _, obj := range resources[resource] {
+				out, err := k.Output("get", resource, "--all-namespaces", "--field-selector", "metadata.name="+obj.Name, "--output", "name")
+				if err != nil {
+					// the resource went away along with the CRD defining it
+					if strings.Contains(err.Error(), "the server doesn't have a resource type") {
+						break
+					}
+					return nil, err
+				}
+				if strings.TrimSpace(string(out)) != "" {
+					problems = append(problems, Problem{Resource: fmt.Sprintf("%s/%s", resource, obj.Name), Addon: obj.Addon, Reason: "was not removed"})
+				}
+			}
+		}
+		if len(problems) == 0 || time.Now().After(deadline) {
+			return problems, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
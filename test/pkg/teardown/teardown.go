@@ -0,0 +1,231 @@
+package teardown
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	resourcePolicyAnnotation = "helm.sh/resource-policy"
+	hookAnnotation           = "helm.sh/hook"
+)
+
+// CRD is a CustomResourceDefinition shipped by an addon.
+type CRD struct {
+	Name  string
+	Addon string
+
+	// Keep is true for CRDs which are expected to remain after the addon has
+	// been removed: those with a "keep" resource policy and those installed
+	// by a helm crd-install hook or from the crds/ directory of a chart
+	// (which helm never deletes).
+	Keep bool
+}
+
+// OwnedCRDs maps the CRDs in the rendered manifests of addons to the addon
+// which ships them.
+func OwnedCRDs(objects []render.Object) []CRD {
+	var crds []CRD
+	for _, obj := range objects {
+		if obj.Kind != "CustomResourceDefinition" {
+			continue
+		}
+		annotations := map[string]interface{}{}
+		if metadata, ok := obj.Content["metadata"].(map[string]interface{}); ok {
+			if a, ok := metadata["annotations"].(map[string]interface{}); ok {
+				annotations = a
+			}
+		}
+		policy, _ := annotations[resourcePolicyAnnotation].(string)
+		hook, _ := annotations[hookAnnotation].(string)
+		crds = append(crds, CRD{
+			Name:  obj.Name,
+			Addon: obj.Addon,
+			Keep:  policy == "keep" || strings.Contains(hook, "crd-install") || chartCRD(obj.Source),
+		})
+	}
+	sort.Slice(crds, func(i, j int) bool { return crds[i].Name < crds[j].Name })
+	return crds
+}
+
+// chartCRD returns whether a template is in the crds/ directory of a chart or
+// of one of its subcharts.
+func chartCRD(source string) bool {
+	return strings.Contains("/"+source, "/crds/")
+}
+
+// Namespaces returns the namespaces the rendered objects of addons are in or
+// create, sorted.
+func Namespaces(objects []render.Object) []string {
+	set := make(map[string]bool)
+	for _, obj := range objects {
+		if obj.Namespace != "" {
+			set[obj.Namespace] = true
+		}
+		if obj.Kind == "Namespace" && obj.Name != "" {
+			set[obj.Name] = true
+		}
+	}
+	namespaces := make([]string, 0, len(set))
+	for namespace := range set {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// Problem is a resource which was not torn down properly.
+type Problem struct {
+	Resource string
+	Addon    string
+	Reason   string
+}
+
+func (p Problem) String() string {
+	if p.Addon == "" {
+		return fmt.Sprintf("%s: %s", p.Resource, p.Reason)
+	}
+	return fmt.Sprintf("%s (addon %s): %s", p.Resource, p.Addon, p.Reason)
+}
+
+type crdList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string     `json:"name"`
+			DeletionTimestamp *time.Time `json:"deletionTimestamp"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+type namespaceList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Phase      string `json:"phase"`
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ClusterCRDs lists the names of the CRDs in the cluster of a kubectl
+// client, mapped to whether they are being deleted.
+func ClusterCRDs(k kubectl.Client) (map[string]bool, error) {
+	b, err := k.Output("get", "customresourcedefinitions", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	list := crdList{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	crds := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		crds[item.Metadata.Name] = item.Metadata.DeletionTimestamp != nil
+	}
+	return crds, nil
+}
+
+// Verify waits up to timeout for the CRDs which are slated for removal (those
+// which the addons don't keep) to be gone and for none of the namespaces of
+// the addons (see Namespaces) to be stuck terminating, then reports every
+// remaining problem: slated CRDs which still exist along with the custom
+// resources left over for them, and namespaces stuck terminating along with
+// the content that blocks them.
+func Verify(k kubectl.Client, crds []CRD, namespaces []string, timeout time.Duration) ([]Problem, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		problems, err := check(k, crds, namespaces)
+		if err != nil {
+			return nil, err
+		}
+		if len(problems) == 0 || time.Now().After(deadline) {
+			return problems, nil
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func check(k kubectl.Client, crds []CRD, namespaces []string) ([]Problem, error) {
+	var problems []Problem
+
+	existing, err := ClusterCRDs(k)
+	if err != nil {
+		return nil, err
+	}
+	for _, crd := range crds {
+		terminating, exists := existing[crd.Name]
+		if crd.Keep || !exists {
+			continue
+		}
+
+		reason := "was not removed"
+		if terminating {
+			reason = "is stuck terminating"
+		}
+		if orphans, err := customResources(k, crd.Name); err == nil && len(orphans) > 0 {
+			reason += fmt.Sprintf(", orphaned custom resources remain: %s", strings.Join(orphans, ", "))
+		}
+		problems = append(problems, Problem{Resource: "customresourcedefinition/" + crd.Name, Addon: crd.Addon, Reason: reason})
+	}
+
+	if len(namespaces) == 0 {
+		return problems, nil
+	}
+	addonNamespaces := make(map[string]bool, len(namespaces))
+	for _, namespace := range namespaces {
+		addonNamespaces[namespace] = true
+	}
+	b, err := k.Output("get", "namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	list := namespaceList{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	for _, ns := range list.Items {
+		if !addonNamespaces[ns.Metadata.Name] || ns.Status.Phase != "Terminating" {
+			continue
+		}
+		var blockers []string
+		for _, condition := range ns.Status.Conditions {
+			if condition.Status == "True" && condition.Message != "" {
+				blockers = append(blockers, condition.Message)
+			}
+		}
+		reason := "is stuck terminating"
+		if len(blockers) > 0 {
+			reason += ": " + strings.Join(blockers, "; ")
+		}
+		problems = append(problems, Problem{Resource: "namespace/" + ns.Metadata.Name, Reason: reason})
+	}
+
+	return problems, nil
+}
+
+// customResources lists the custom resources of a CRD as [<namespace>/]<name>.
+func customResources(k kubectl.Client, crd string) ([]string, error) {
+	b, err := k.Output("get", crd, "--all-namespaces", "--output", "jsonpath={range .items[*]}{.metadata.namespace}/{.metadata.name}{\"\\n\"}{end}")
+	if err != nil {
+		return nil, err
+	}
+	var resources []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if line = strings.TrimPrefix(line, "/"); line != "" {
+			resources = append(resources, line)
+		}
+	}
+	return resources, nil
+}
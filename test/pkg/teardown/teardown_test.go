@@ -0,0 +1,104 @@
+package teardown
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func crd(addon, name string, annotations map[string]interface{}) render.Object {
+	return render.Object{
+		Addon: addon,
+		Kind:  "CustomResourceDefinition",
+		Name:  name,
+		Content: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "annotations": annotations},
+		},
+	}
+}
+
+func TestOwnedCRDs(t *testing.T) {
+	objects := []render.Object{
+		crd("cert-manager", "certificates.cert-manager.io", map[string]interface{}{"helm.sh/hook": "crd-install"}),
+		crd("kommander", "workspaces.kommander.mesosphere.io", nil),
+		crd("kommander", "kubefedconfigs.core.kubefed.io", map[string]interface{}{"helm.sh/resource-policy": "keep"}),
+		{Addon: "kommander", Kind: "Deployment", Name: "kommander", Namespace: "kommander"},
+	}
+	chartCRD := crd("traefik", "middlewares.traefik.containo.us", nil)
+	chartCRD.Source = "traefik/crds/middlewares.yaml"
+	objects = append(objects, chartCRD, render.Object{Addon: "traefik", Kind: "Namespace", Name: "traefik"})
+
+	expected := []CRD{
+		{Name: "certificates.cert-manager.io", Addon: "cert-manager", Keep: true},
+		{Name: "kubefedconfigs.core.kubefed.io", Addon: "kommander", Keep: true},
+		{Name: "middlewares.traefik.containo.us", Addon: "traefik", Keep: true},
+		{Name: "workspaces.kommander.mesosphere.io", Addon: "kommander"},
+	}
+	if actual := OwnedCRDs(objects); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %+v, got %+v", expected, actual)
+	}
+	if namespaces := Namespaces(objects); !reflect.DeepEqual(namespaces, []string{"kommander", "traefik"}) {
+		t.Errorf("expected the namespaces kommander and traefik, got %v", namespaces)
+	}
+}
+
+func TestMonitoringDiff(t *testing.T) {
+	baseline := Monitoring{
+		"prometheusrule/kubeaddons/prometheus-kubeaddons-rules": "",
+		"secret/kubeaddons/alertmanager-prometheus-kubeaddons":  "a1",
+	}
+	current := Monitoring{
+		"prometheusrule/kubeaddons/prometheus-kubeaddons-rules": "",
+		"servicemonitor/kommander/kommander-kubeaddons-grafana": "",
+		"configmap/kommander/kommander-grafana-dashboards":      "",
+		"secret/kubeaddons/alertmanager-prometheus-kubeaddons":  "b2",
+	}
+	objects := []render.Object{
+		{Addon: "kommander-grafana", Kind: "ServiceMonitor", Name: "kommander-kubeaddons-grafana"},
+	}
+
+	expected := []Problem{
+		{Resource: "configmap/kommander/kommander-grafana-dashboards", Reason: "was not removed"},
+		{Resource: "secret/kubeaddons/alertmanager-prometheus-kubeaddons", Reason: "alertmanager configuration differs from before the addons were deployed"},
+		{Resource: "servicemonitor/kommander/kommander-kubeaddons-grafana", Addon: "kommander-grafana", Reason: "was not removed"},
+	}
+	if actual := baseline.diff(current, objects); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+	if problems := baseline.diff(baseline, objects); len(problems) != 0 {
+		t.Errorf("expected no problems for the baseline, got %+v", problems)
+	}
+}
+
+func TestRemoved(t *testing.T) {
+	object := func(apiVersion, kind, name string, annotations map[string]interface{}) render.Object {
+		return render.Object{Addon: "kommander", APIVersion: apiVersion, Kind: kind, Name: name, Content: map[string]interface{}{
+			"metadata": map[string]interface{}{"name": name, "annotations": annotations},
+		}}
+	}
+	objects := []render.Object{
+		object("v1", "Namespace", "kommander", nil),
+		object("apiextensions.k8s.io/v1beta1", "CustomResourceDefinition", "workspaces.kommander.mesosphere.io", nil),
+		object("apps/v1", "Deployment", "kommander-kubeaddons", nil),
+		object("apps/v1", "Deployment", "kommander-kubeaddons-grafana", nil),
+		object("v1", "ConfigMap", "kommander-kubeaddons-config", nil),
+		object("v1", "Secret", "kommander-kubeaddons-license", map[string]interface{}{"helm.sh/resource-policy": "keep"}),
+		object("batch/v1", "Job", "kommander-kubeaddons-init", map[string]interface{}{"helm.sh/hook": "post-install"}),
+	}
+
+	resources := removed(objects)
+	actual := make(map[string][]string, len(resources))
+	for resource, objs := range resources {
+		for _, obj := range objs {
+			actual[resource] = append(actual[resource], obj.Name)
+		}
+	}
+	expected := map[string][]string{
+		"deployment.apps": {"kommander-kubeaddons", "kommander-kubeaddons-grafana"},
+		"configmap":       {"kommander-kubeaddons-config"},
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
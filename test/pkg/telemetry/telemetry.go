@@ -0,0 +1,290 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Config are the external destinations addons may contact (see
+// telemetry.yaml). A destination with a leading dot allows its subdomains too.
+type Config struct {
+	// Default are the destinations every addon may contact.
+	Default []string `yaml:"default"`
+
+	// Addons are the destinations of each addon.
+	Addons map[string][]string `yaml:"addons"`
+}
+
+// Load reads the allowed destinations from a YAML file.
+func Load(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Allowed returns whether an addon may contact a destination.
+func (c *Config) Allowed(addon, destination string) bool {
+	for _, allowed := range append(append([]string{}, c.Default...), c.Addons[addon]...) {
+		if destination == strings.TrimPrefix(allowed, ".") || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(destination, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableQueryLog makes CoreDNS log the queries of the cluster of a kubectl
+// client, by adding the log plugin to its Corefile and restarting
+// it. The log plugin is left in place, as it only adds to the logs.
+func EnableQueryLog(k kubectl.Client, timeout time.Duration) error {
+	b, err := k.Output("get", "configmap", "coredns", "--namespace", "kube-system", "--output", "jsonpath={.data.Corefile}")
+	if err != nil {
+		return err
+	}
+	corefile, changed := withLog(string(b))
+	if !changed {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{"data": map[string]string{"Corefile": corefile}})
+	if err != nil {
+		return err
+	}
+	if err := k.Run("patch", "configmap", "coredns", "--namespace", "kube-system", "--type", "merge", "--patch", string(patch)); err != nil {
+		return err
+	}
+	if err := k.Run("rollout", "restart", "deployment/coredns", "--namespace", "kube-system"); err != nil {
+		return err
+	}
+	return k.Run("rollout", "status", "deployment/coredns", "--namespace", "kube-system", "--timeout", timeout.String())
+}
+
+// withLog adds the log plugin to the server block of the root zone of a
+// Corefile, returning whether it changed.
+func withLog(corefile string) (string, bool) {
+	lines := strings.Split(corefile, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), ".:") {
+			continue
+		}
+		for _, plugin := range lines[i+1:] {
+			if strings.TrimSpace(plugin) == "}" {
+				break
+			}
+			if strings.TrimSpace(plugin) == "log" {
+				return corefile, false
+			}
+		}
+		lines = append(lines[:i+1], append([]string{"    log"}, lines[i+1:]...)...)
+		return strings.Join(lines, "\n"), true
+	}
+	return corefile, false
+}
+
+// Query is a DNS query logged by CoreDNS.
+type Query struct {
+	// Client is the IP address of the pod which sent the query.
+	Client string
+
+	// Name is the queried name, without the trailing dot.
+	Name string
+}
+
+// query matches the queries CoreDNS logs, e.g.
+//
+//	[INFO] 10.244.0.5:41234 - 12345 "A IN github.com. udp 28 false 512" NOERROR qr,rd,ra 104 0.012s
+var query = regexp.MustCompile(`\[INFO\] \[?([0-9a-fA-F.:]+?)\]?:\d+ - \d+ "\S+ IN (\S+) [^"]*" (\S+)`)
+
+// Queries returns the external queries CoreDNS logged since a time, omitting
+// the names of the cluster (e.g. of services), reverse lookups and names which
+// don't exist (e.g. external names expanded with the search domains of the
+// nodes), which can't be contacted.
+func Queries(k kubectl.Client, since time.Time) ([]Query, error) {
+	b, err := k.Output("logs", "--namespace", "kube-system", "--selector", "k8s-app=kube-dns", "--tail", "-1", "--since-time", since.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	return parseQueries(string(b)), nil
+}
+
+func parseQueries(logs string) []Query {
+	var queries []Query
+	for _, line := range strings.Split(logs, "\n") {
+		match := query.FindStringSubmatch(line)
+		if match == nil || match[3] == "NXDOMAIN" {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSuffix(match[2], "."))
+		if internal(name) {
+			continue
+		}
+		queries = append(queries, Query{Client: match[1], Name: name})
+	}
+	return queries
+}
+
+// internal returns whether a name is resolved within the cluster: names of
+// the cluster domain (including external names expanded with the search
+// domains of pods), reverse lookups and names without a domain.
+func internal(name string) bool {
+	for _, suffix := range []string{".cluster.local", ".in-addr.arpa", ".ip6.arpa"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return !strings.Contains(name, ".") || name == "localhost"
+}
+
+type podList struct {
+	Items []pod `json:"items"`
+}
+
+type pod struct {
+	Metadata struct {
+		Namespace string            `json:"namespace"`
+		Labels    map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+// workloadKinds are the kinds whose pods are selected by spec.selector.
+var workloadKinds = map[string]bool{
+	"Deployment":  true,
+	"DaemonSet":   true,
+	"StatefulSet": true,
+}
+
+// Clients returns the addons of the current pods of the workloads in the
+// rendered objects of addons, by the IP address of the pod. Pods on the host
+// network share the addresses of their nodes and are omitted.
+func Clients(k kubectl.Client, objects []render.Object) (map[string]string, error) {
+	b, err := k.Output("get", "pods", "--all-namespaces", "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+	var pods podList
+	if err := json.Unmarshal(b, &pods); err != nil {
+		return nil, err
+	}
+	nodes, err := k.Output("get", "nodes", "--output", "jsonpath={.items[*].status.addresses[*].address}")
+	if err != nil {
+		return nil, err
+	}
+	return clients(objects, pods.Items, strings.Fields(string(nodes))), nil
+}
+
+func clients(objects []render.Object, pods []pod, nodes []string) map[string]string {
+	host := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		host[node] = true
+	}
+	addons := make(map[string]string)
+	for _, obj := range objects {
+		if !workloadKinds[obj.Kind] {
+			continue
+		}
+		selector := matchLabels(obj)
+		if len(selector) == 0 {
+			continue
+		}
+		for _, pod := range pods {
+			if pod.Metadata.Namespace != obj.Namespace || pod.Status.PodIP == "" || host[pod.Status.PodIP] || !matches(pod.Metadata.Labels, selector) {
+				continue
+			}
+			addons[pod.Status.PodIP] = obj.Addon
+		}
+	}
+	return addons
+}
+
+func matchLabels(obj render.Object) map[string]string {
+	spec, _ := obj.Content["spec"].(map[string]interface{})
+	sel, _ := spec["selector"].(map[string]interface{})
+	labels, _ := sel["matchLabels"].(map[string]interface{})
+	selector := make(map[string]string, len(labels))
+	for k, v := range labels {
+		selector[k] = fmt.Sprint(v)
+	}
+	return selector
+}
+
+func matches(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Destinations returns the external names queried by each addon, sorted, and
+// those queried by clients which aren't pods of an addon (e.g. pods which were
+// replaced since) under the empty addon.
+func Destinations(queries []Query, clients map[string]string) map[string][]string {
+	seen := make(map[string]map[string]bool)
+	for _, q := range queries {
+		addon := clients[q.Client]
+		if seen[addon] == nil {
+			seen[addon] = make(map[string]bool)
+		}
+		seen[addon][q.Name] = true
+	}
+	destinations := make(map[string][]string, len(seen))
+	for addon, names := range seen {
+		for name := range names {
+			destinations[addon] = append(destinations[addon], name)
+		}
+		sort.Strings(destinations[addon])
+	}
+	return destinations
+}
+
+// Violation is a destination an addon contacted which it isn't allowed to.
+type Violation struct {
+	Addon       string
+	Destination string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("addon %s contacted %s", v.Addon, v.Destination)
+}
+
+// Violations returns the destinations of addons which aren't allowed, sorted
+// by addon. Destinations of unknown clients can't be attributed to an addon
+// and are no violations.
+func (c *Config) Violations(destinations map[string][]string) []Violation {
+	var violations []Violation
+	for addon, names := range destinations {
+		if addon == "" {
+			continue
+		}
+		for _, name := range names {
+			if !c.Allowed(addon, name) {
+				violations = append(violations, Violation{Addon: addon, Destination: name})
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool {
+		if violations[i].Addon != violations[j].Addon {
+			return violations[i].Addon < violations[j].Addon
+		}
+		return violations[i].Destination < violations[j].Destination
+	})
+	return violations
+}
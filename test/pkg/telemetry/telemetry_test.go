@@ -0,0 +1,90 @@
+package telemetry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestWithLog(t *testing.T) {
+	corefile := ".:53 {\n    errors\n    health\n    forward . /etc/resolv.conf\n    reload\n}\n"
+	expected := ".:53 {\n    log\n    errors\n    health\n    forward . /etc/resolv.conf\n    reload\n}\n"
+	actual, changed := withLog(corefile)
+	if !changed || actual != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, actual)
+	}
+	if _, changed := withLog(actual); changed {
+		t.Error("expected a Corefile with the log plugin to be left as it is")
+	}
+}
+
+func TestParseQueries(t *testing.T) {
+	logs := `.:53
+[INFO] plugin/reload: Running configuration MD5 = 4e235fcc3696966e76816bcd9034ebc7
+[INFO] 10.244.0.5:41234 - 12345 "A IN github.com. udp 28 false 512" NOERROR qr,rd,ra 104 0.012s
+[INFO] 10.244.0.5:41235 - 12346 "AAAA IN Stats.Example.COM. udp 35 false 512" NOERROR qr,rd,ra 35 0.020s
+[INFO] 10.244.0.5:41236 - 12347 "A IN kommander.kommander.svc.cluster.local. udp 55 false 512" NOERROR qr,aa,rd 106 0.000s
+[INFO] 10.244.0.5:41237 - 12348 "A IN github.com.localdomain. udp 40 false 512" NXDOMAIN qr,rd,ra 40 0.010s
+[INFO] 10.244.0.6:41238 - 12349 "PTR IN 5.0.244.10.in-addr.arpa. udp 41 false 512" NOERROR qr,aa,rd 116 0.000s
+[INFO] [fd00:10:244::7]:52000 - 4711 "A IN quay.io. udp 25 false 512" NOERROR qr,rd,ra 60 0.011s
+`
+	expected := []Query{
+		{Client: "10.244.0.5", Name: "github.com"},
+		{Client: "10.244.0.5", Name: "stats.example.com"},
+		{Client: "fd00:10:244::7", Name: "quay.io"},
+	}
+	if actual := parseQueries(logs); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestViolations(t *testing.T) {
+	objects := []render.Object{
+		{Addon: "kommander", Kind: "Deployment", Namespace: "kommander", Content: map[string]interface{}{
+			"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "kubeaddons-catalog"}}},
+		}},
+		{Addon: "traefik", Kind: "DaemonSet", Namespace: "kubeaddons", Content: map[string]interface{}{
+			"spec": map[string]interface{}{"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "traefik"}}},
+		}},
+	}
+	var pods []pod
+	for _, p := range []struct{ namespace, app, ip string }{
+		{"kommander", "kubeaddons-catalog", "10.244.0.5"},
+		{"kubeaddons", "traefik", "10.244.0.6"},
+		{"kubeaddons", "traefik", "172.18.0.2"},
+	} {
+		var pod pod
+		pod.Metadata.Namespace, pod.Metadata.Labels, pod.Status.PodIP = p.namespace, map[string]string{"app": p.app}, p.ip
+		pods = append(pods, pod)
+	}
+
+	clients := clients(objects, pods, []string{"172.18.0.2", "kind-control-plane"})
+	if expected := map[string]string{"10.244.0.5": "kommander", "10.244.0.6": "traefik"}; !reflect.DeepEqual(expected, clients) {
+		t.Fatalf("expected clients %v, got %v", expected, clients)
+	}
+
+	destinations := Destinations([]Query{
+		{Client: "10.244.0.5", Name: "raw.githubusercontent.com"},
+		{Client: "10.244.0.5", Name: "github.com"},
+		{Client: "10.244.0.5", Name: "github.com"},
+		{Client: "10.244.0.6", Name: "stats.example.com"},
+		{Client: "172.18.0.2", Name: "stats.example.com"},
+	}, clients)
+	expected := map[string][]string{
+		"kommander": {"github.com", "raw.githubusercontent.com"},
+		"traefik":   {"stats.example.com"},
+		"":          {"stats.example.com"},
+	}
+	if !reflect.DeepEqual(expected, destinations) {
+		t.Fatalf("expected destinations %v, got %v", expected, destinations)
+	}
+
+	c := &Config{Addons: map[string][]string{"kommander": {"github.com", ".githubusercontent.com"}}}
+	if violations := c.Violations(destinations); !reflect.DeepEqual([]Violation{{Addon: "traefik", Destination: "stats.example.com"}}, violations) {
+		t.Errorf("expected traefik to violate, got %v", violations)
+	}
+	if c.Allowed("kommander", "evilgithub.com") || !c.Allowed("kommander", "githubusercontent.com") {
+		t.Error("expected a leading dot to allow the domain and its subdomains only")
+	}
+}
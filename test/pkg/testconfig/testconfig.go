@@ -0,0 +1,293 @@
+package testconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the configuration of test runs, read from a config file (see
+// config.yaml) and overridden by environment variables.
+type Config struct {
+	// KBAURL and KBARef are the repository and the ref of the kubernetes base
+	// addons, which complete the catalog of this repository.
+	KBAURL string
+	KBARef string
+
+	// ControllerBundle is the bundle of the kubeaddons controller deployed to
+	// the clusters of scenarios, e.g. kind.
+	ControllerBundle string
+
+	// KubernetesVersion is the version of the clusters of scenarios which
+	// don't set one.
+	KubernetesVersion semver.Version
+
+	Timeouts Timeouts
+
+	// BudgetFactor is the factor by which scenarios may exceed the duration
+	// budget of their testing groups (see groups.yaml).
+	BudgetFactor float64
+
+	// BudgetFails makes scenarios which exceed their budget fail, rather than
+	// only warn.
+	BudgetFails bool
+
+	// ExtraRepositories are additional addon repositories merged into the
+	// catalog of runs, none by default.
+	ExtraRepositories []Repository
+
+	// ImageMirror is the registry mirroring Docker Hub which addons whose
+	// image pulls were rate limited fall back to, none when empty.
+	ImageMirror string
+
+	// SeedQueryTarget is the URL of the Prometheus API of thanos query which
+	// seeds verify the data they wrote with.
+	SeedQueryTarget string
+}
+
+// DefaultGroup is the testing group the addons of extra repositories join
+// unless they name another one.
+const DefaultGroup = "kommander"
+
+// Repository is an additional addon repository, e.g. of addons developed
+// outside of this repository, either a local directory or a git repository.
+type Repository struct {
+	// Path is the directory of a local repository, relative to the test
+	// directory.
+	Path string `yaml:"path"`
+
+	// URL and Ref are the git repository and its ref otherwise.
+	URL string `yaml:"url"`
+	Ref string `yaml:"ref"`
+
+	// Group is the testing group the addons of the repository join.
+	Group string `yaml:"group"`
+}
+
+func (r Repository) String() string {
+	if r.Path != "" {
+		return r.Path
+	}
+	return r.URL + "@" + r.Ref
+}
+
+// parseRepositories parses the value of EXTRA_REPOSITORIES, a comma separated
+// list of local paths and git URLs with a ref (<url>@<ref>).
+func parseRepositories(value string) []Repository {
+	var repos []Repository
+	for _, location := range strings.Split(value, ",") {
+		location = strings.TrimSpace(location)
+		if !strings.Contains(location, "://") {
+			repos = append(repos, Repository{Path: location})
+			continue
+		}
+		repo := Repository{URL: location}
+		if i := strings.LastIndex(location, "@"); i > strings.Index(location, "://") {
+			repo.URL, repo.Ref = location[:i], location[i+1:]
+		}
+		repos = append(repos, repo)
+	}
+	return repos
+}
+
+func (r *Repository) validate() error {
+	if r.Group == "" {
+		r.Group = DefaultGroup
+	}
+	switch {
+	case r.Path != "" && r.URL != "":
+		return fmt.Errorf("%s is both a local and a git repository", r)
+	case r.Path != "":
+		return nil
+	case r.URL == "":
+		return fmt.Errorf("an extra repository has neither a path nor a URL")
+	}
+	if u, err := url.Parse(r.URL); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q is not the URL of a git repository", r.URL)
+	}
+	if r.Ref == "" {
+		return fmt.Errorf("git repository %s has no ref", r.URL)
+	}
+	return nil
+}
+
+// Timeouts are the timeouts of the phases of scenarios. Addons extend them
+// with their own timeouts in groups.yaml.
+type Timeouts struct {
+	// ClusterReady is how long a new cluster may take to be ready (its
+	// nodes, cluster DNS and default service account) before anything is
+	// applied to it.
+	ClusterReady time.Duration
+
+	// Seed is how long seeds may take to be ready.
+	Seed time.Duration
+
+	// Teardown is how long the removal of addons may take to be complete.
+	Teardown time.Duration
+
+	// Wait is how long the wait conditions of an addon may take to be met.
+	Wait time.Duration
+
+	// HelmInstall is how long a helm install of an addon may take (helm
+	// backend).
+	HelmInstall time.Duration
+
+	// Redeploy is how long a redeployment of an addon may take in watch mode.
+	Redeploy time.Duration
+
+	// Policy is how long gatekeeper may take to be installed and audit.
+	Policy time.Duration
+
+	// License is how long kommander may take to report a license.
+	License time.Duration
+
+	// Federation is how long kommander may take to join an attached cluster
+	// and to propagate a federated addon to it.
+	Federation time.Duration
+}
+
+// file is the YAML representation of the configuration.
+type file struct {
+	KBA struct {
+		URL string `yaml:"url"`
+		Ref string `yaml:"ref"`
+	} `yaml:"kba"`
+	ControllerBundle  string `yaml:"controllerBundle"`
+	KubernetesVersion string `yaml:"kubernetesVersion"`
+	Budget            struct {
+		Factor   string `yaml:"factor"`
+		Exceeded string `yaml:"exceeded"`
+	} `yaml:"budget"`
+	Timeouts struct {
+		ClusterReady string `yaml:"clusterReady"`
+		Seed         string `yaml:"seed"`
+		Teardown     string `yaml:"teardown"`
+		Wait         string `yaml:"wait"`
+		HelmInstall  string `yaml:"helmInstall"`
+		Redeploy     string `yaml:"redeploy"`
+		Policy       string `yaml:"policy"`
+		License      string `yaml:"license"`
+		Federation   string `yaml:"federation"`
+	} `yaml:"timeouts"`
+	Seeds struct {
+		QueryTarget string `yaml:"queryTarget"`
+	} `yaml:"seeds"`
+	ExtraRepositories []Repository `yaml:"extraRepositories"`
+	ImageMirror       string       `yaml:"imageMirror"`
+}
+
+// setting is a setting of the config file along with the environment
+// variable which overrides it and, for timeouts, the timeout it configures.
+type setting struct {
+	key     string
+	env     string
+	value   *string
+	timeout *time.Duration
+}
+
+func (f *file) settings(c *Config) []setting {
+	return []setting{
+		{key: "kba.url", env: "KBA_URL", value: &f.KBA.URL},
+		{key: "kba.ref", env: "KBA_REF", value: &f.KBA.Ref},
+		{key: "controllerBundle", env: "CONTROLLER_BUNDLE", value: &f.ControllerBundle},
+		{key: "kubernetesVersion", env: "KUBERNETES_VERSION", value: &f.KubernetesVersion},
+		{key: "budget.factor", env: "BUDGET_FACTOR", value: &f.Budget.Factor},
+		{key: "budget.exceeded", env: "BUDGET_EXCEEDED", value: &f.Budget.Exceeded},
+		{key: "timeouts.clusterReady", env: "CLUSTER_READY_TIMEOUT", value: &f.Timeouts.ClusterReady, timeout: &c.Timeouts.ClusterReady},
+		{key: "timeouts.seed", env: "SEED_TIMEOUT", value: &f.Timeouts.Seed, timeout: &c.Timeouts.Seed},
+		{key: "timeouts.teardown", env: "TEARDOWN_TIMEOUT", value: &f.Timeouts.Teardown, timeout: &c.Timeouts.Teardown},
+		{key: "timeouts.wait", env: "WAIT_TIMEOUT", value: &f.Timeouts.Wait, timeout: &c.Timeouts.Wait},
+		{key: "timeouts.helmInstall", env: "HELM_INSTALL_TIMEOUT", value: &f.Timeouts.HelmInstall, timeout: &c.Timeouts.HelmInstall},
+		{key: "timeouts.redeploy", env: "REDEPLOY_TIMEOUT", value: &f.Timeouts.Redeploy, timeout: &c.Timeouts.Redeploy},
+		{key: "timeouts.policy", env: "POLICY_TIMEOUT", value: &f.Timeouts.Policy, timeout: &c.Timeouts.Policy},
+		{key: "timeouts.license", env: "LICENSE_TIMEOUT", value: &f.Timeouts.License, timeout: &c.Timeouts.License},
+		{key: "timeouts.federation", env: "FEDERATION_TIMEOUT", value: &f.Timeouts.Federation, timeout: &c.Timeouts.Federation},
+		{key: "seeds.queryTarget", env: "SEED_QUERY_TARGET", value: &f.Seeds.QueryTarget},
+	}
+}
+
+// Load reads the configuration in a file and applies the overrides of the
+// environment, looked up with getenv (e.g. os.Getenv). Every setting is
+// mandatory, either in the file or in the environment.
+func Load(path string, getenv func(string) string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &file{}
+	if err := yaml.UnmarshalStrict(b, f); err != nil {
+		return nil, fmt.Errorf("could not parse test configuration %s: %w", path, err)
+	}
+
+	c := &Config{}
+	sources := make(map[string]string)
+	for _, s := range f.settings(c) {
+		sources[s.key] = fmt.Sprintf("%s of %s", s.key, path)
+		if env := getenv(s.env); env != "" {
+			*s.value = env
+			sources[s.key] = s.env
+		}
+		if *s.value == "" {
+			return nil, fmt.Errorf("test configuration %s has no %s (overridden by %s)", path, s.key, s.env)
+		}
+		if s.timeout == nil {
+			continue
+		}
+		if *s.timeout, err = time.ParseDuration(*s.value); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", sources[s.key], *s.value, err)
+		}
+		if *s.timeout <= 0 {
+			return nil, fmt.Errorf("invalid %s %q, timeouts must be positive", sources[s.key], *s.value)
+		}
+	}
+
+	c.KBAURL, c.KBARef, c.ControllerBundle = f.KBA.URL, f.KBA.Ref, f.ControllerBundle
+	if u, err := url.Parse(c.KBAURL); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s %q, expected the URL of a git repository", sources["kba.url"], c.KBAURL)
+	}
+	c.SeedQueryTarget = f.Seeds.QueryTarget
+	if u, err := url.Parse(c.SeedQueryTarget); err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid %s %q, expected the URL of the API of thanos query", sources["seeds.queryTarget"], c.SeedQueryTarget)
+	}
+	if c.KubernetesVersion, err = semver.Parse(f.KubernetesVersion); err != nil {
+		return nil, fmt.Errorf("invalid %s %q: %w", sources["kubernetesVersion"], f.KubernetesVersion, err)
+	}
+	if c.BudgetFactor, err = strconv.ParseFloat(f.Budget.Factor, 64); err != nil || c.BudgetFactor < 1 {
+		return nil, fmt.Errorf("invalid %s %q, expected a factor of at least 1", sources["budget.factor"], f.Budget.Factor)
+	}
+	switch f.Budget.Exceeded {
+	case "warn":
+	case "fail":
+		c.BudgetFails = true
+	default:
+		return nil, fmt.Errorf("invalid %s %q, expected warn or fail", sources["budget.exceeded"], f.Budget.Exceeded)
+	}
+
+	// extra repositories are optional
+	source := fmt.Sprintf("extraRepositories of %s", path)
+	c.ExtraRepositories = f.ExtraRepositories
+	if env := getenv("EXTRA_REPOSITORIES"); env != "" {
+		source, c.ExtraRepositories = "EXTRA_REPOSITORIES", parseRepositories(env)
+	}
+	for i := range c.ExtraRepositories {
+		if err := c.ExtraRepositories[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", source, err)
+		}
+	}
+
+	// as is the image mirror
+	source, c.ImageMirror = fmt.Sprintf("imageMirror of %s", path), f.ImageMirror
+	if env := getenv("IMAGE_MIRROR"); env != "" {
+		source, c.ImageMirror = "IMAGE_MIRROR", env
+	}
+	if strings.Contains(c.ImageMirror, "://") || strings.HasSuffix(c.ImageMirror, "/") {
+		return nil, fmt.Errorf("invalid %s %q, expected a registry host, e.g. mirror.gcr.io", source, c.ImageMirror)
+	}
+	return c, nil
+}
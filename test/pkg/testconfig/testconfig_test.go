@@ -0,0 +1,153 @@
+package testconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+const config = `
+kba:
+  url: https://github.com/mesosphere/kubernetes-base-addons
+  ref: master
+controllerBundle: kind
+kubernetesVersion: 1.16.4
+budget:
+  factor: 1.5
+  exceeded: warn
+timeouts:
+  clusterReady: 5m
+  seed: 5m
+  teardown: 5m
+  wait: 5m
+  helmInstall: 10m
+  redeploy: 10m
+  policy: 5m
+  license: 2m
+  federation: 10m
+seeds:
+  queryTarget: http://kommander-kubeaddons-thanos-query.kommander.svc:10902
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testconfig-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{"KBA_REF": "release-2.0", "SEED_TIMEOUT": "15m"}
+	getenv := func(key string) string { return env[key] }
+	c, err := Load(path, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.KBARef != "release-2.0" || c.Timeouts.Seed != 15*time.Minute {
+		t.Errorf("expected the environment to override the file, got %+v", c)
+	}
+	if !c.KubernetesVersion.Equals(semver.MustParse("1.16.4")) || c.Timeouts.HelmInstall != 10*time.Minute || c.ControllerBundle != "kind" || c.BudgetFactor != 1.5 || c.BudgetFails {
+		t.Errorf("expected the settings of the file, got %+v", c)
+	}
+
+	for override, expected := range map[string]string{
+		"KUBERNETES_VERSION": "invalid KUBERNETES_VERSION",
+		"TEARDOWN_TIMEOUT":   "invalid TEARDOWN_TIMEOUT",
+		"KBA_URL":            "invalid KBA_URL",
+		"BUDGET_EXCEEDED":    "invalid BUDGET_EXCEEDED",
+		"SEED_QUERY_TARGET":  "invalid SEED_QUERY_TARGET",
+	} {
+		env = map[string]string{override: "1.16"}
+		if _, err := Load(path, getenv); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q, got %v", expected, err)
+		}
+	}
+
+	if c.ImageMirror != "" {
+		t.Errorf("expected no image mirror, got %s", c.ImageMirror)
+	}
+	env = map[string]string{"IMAGE_MIRROR": "mirror.gcr.io"}
+	if c, err := Load(path, getenv); err != nil || c.ImageMirror != "mirror.gcr.io" {
+		t.Errorf("expected the image mirror of the environment, got %v", err)
+	}
+	env = map[string]string{"IMAGE_MIRROR": "https://mirror.gcr.io"}
+	if _, err := Load(path, getenv); err == nil || !strings.Contains(err.Error(), "invalid IMAGE_MIRROR") {
+		t.Errorf("expected an image mirror URL to be rejected, got %v", err)
+	}
+
+	env = map[string]string{"BUDGET_FACTOR": "0.5"}
+	if _, err := Load(path, getenv); err == nil || !strings.Contains(err.Error(), "invalid BUDGET_FACTOR") {
+		t.Errorf("expected a budget factor below 1 to be rejected, got %v", err)
+	}
+	env = map[string]string{"BUDGET_EXCEEDED": "fail"}
+	if c, err := Load(path, getenv); err != nil || !c.BudgetFails {
+		t.Errorf("expected exceeded budgets to fail, got %v", err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(strings.Replace(config, "  license: 2m\n", "", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env = nil
+	if _, err := Load(path, getenv); err == nil || !strings.Contains(err.Error(), "has no timeouts.license") {
+		t.Errorf("expected the missing license timeout to be rejected, got %v", err)
+	}
+}
+
+func TestExtraRepositories(t *testing.T) {
+	dir, err := ioutil.TempDir("", "testconfig-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	extra := config + `
+extraRepositories:
+  - path: ../../kommander-gitops-addons
+  - url: https://github.com/mesosphere/kommander-catalog-addons
+    ref: v1.2.0
+    group: catalog
+`
+	if err := ioutil.WriteFile(path, []byte(extra), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	c, err := Load(path, getenv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Repository{
+		{Path: "../../kommander-gitops-addons", Group: DefaultGroup},
+		{URL: "https://github.com/mesosphere/kommander-catalog-addons", Ref: "v1.2.0", Group: "catalog"},
+	}
+	if !reflect.DeepEqual(expected, c.ExtraRepositories) {
+		t.Errorf("expected %+v, got %+v", expected, c.ExtraRepositories)
+	}
+
+	env["EXTRA_REPOSITORIES"] = "/src/addons, https://git.example.com/team/addons.git@feature/sharding"
+	if c, err = Load(path, getenv); err != nil {
+		t.Fatal(err)
+	}
+	expected = []Repository{
+		{Path: "/src/addons", Group: DefaultGroup},
+		{URL: "https://git.example.com/team/addons.git", Ref: "feature/sharding", Group: DefaultGroup},
+	}
+	if !reflect.DeepEqual(expected, c.ExtraRepositories) {
+		t.Errorf("expected EXTRA_REPOSITORIES to replace the repositories of the file, got %+v", c.ExtraRepositories)
+	}
+
+	env["EXTRA_REPOSITORIES"] = "https://git.example.com/team/addons.git"
+	if _, err := Load(path, getenv); err == nil || !strings.Contains(err.Error(), "invalid EXTRA_REPOSITORIES: git repository https://git.example.com/team/addons.git has no ref") {
+		t.Errorf("expected a git repository without a ref to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package throttle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/retry"
+)
+
+// pullBackoff retries pulls and imports of images for about five minutes,
+// long enough for the rate limits of registries (e.g. docker hub) to reset.
+var pullBackoff = retry.Backoff{
+	Initial:  10 * time.Second,
+	Max:      2 * time.Minute,
+	Factor:   2,
+	Jitter:   0.5,
+	Attempts: 5,
+}
+
+// Load pulls images into the docker daemon of the host (unless they are
+// present already) and streams them onto the nodes of a kind cluster which
+// don't have them yet, working on at most concurrency images at a time. With a
+// docker cache shared by CI jobs every image is pulled from its registry once.
+//
+// Failed pulls and imports are retried with backoff, and an image which still
+// fails doesn't stop the others from being loaded. Images already on a node
+// are skipped, so a failed load resumes where it left off when it is repeated
+// (e.g. when the scenario is resumed). The returned error lists the images
+// which could not be loaded.
+func Load(images []string, cluster string, concurrency int) error {
+	out, err := exec.Command("kind", "get", "nodes", "--name", cluster).Output()
+	if err != nil {
+		return fmt.Errorf("could not list the nodes of kind cluster %s: %w", cluster, err)
+	}
+	nodes := strings.Fields(string(out))
+	if len(nodes) == 0 {
+		return fmt.Errorf("kind cluster %s has no nodes", cluster)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		failed = make(map[string]error)
+		tokens = make(chan struct{}, concurrency)
+	)
+	for _, image := range images {
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			tokens <- struct{}{}
+			defer func() { <-tokens }()
+
+			if err := loadImage(image, nodes); err != nil {
+				lock.Lock()
+				failed[image] = err
+				lock.Unlock()
+			}
+		}(image)
+	}
+	wg.Wait()
+
+	if len(failed) == 0 {
+		return nil
+	}
+	errs := make([]string, 0, len(failed))
+	for image, err := range failed {
+		errs = append(errs, fmt.Sprintf("%s: %s", image, err))
+	}
+	sort.Strings(errs)
+	return fmt.Errorf("could not load %d of %d images onto kind cluster %s: %s", len(failed), len(images), cluster, strings.Join(errs, "; "))
+}
+
+// loadImage loads an image onto the nodes which don't have it yet.
+func loadImage(image string, nodes []string) error {
+	var missing []string
+	for _, node := range nodes {
+		// kind nodes are docker containers named after the node
+		if exec.Command("docker", "exec", node, "crictl", "inspecti", "--quiet", image).Run() != nil {
+			missing = append(missing, node)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if exec.Command("docker", "image", "inspect", image).Run() != nil {
+		if err := retry.Do(pullBackoff, func() error {
+			return retry.Transient(run(nil, "docker", "pull", image))
+		}); err != nil {
+			return err
+		}
+	}
+	for _, node := range missing {
+		if err := retry.Do(pullBackoff, func() error {
+			return retry.Transient(stream(image, node))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stream imports an image of the host into the containerd of a node, piping
+// `docker save` into `ctr import` without writing the archive to disk.
+func stream(image, node string) error {
+	r, w := io.Pipe()
+	save := exec.Command("docker", "save", image)
+	save.Stdout = w
+	stderr := new(bytes.Buffer)
+	save.Stderr = stderr
+	if err := save.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		err := save.Wait()
+		if err != nil {
+			err = fmt.Errorf("docker save %s failed: %w: %s", image, err, stderr.String())
+		}
+		w.CloseWithError(err)
+		done <- err
+	}()
+
+	err := run(r, "docker", "exec", "--interactive", node, "ctr", "--namespace=k8s.io", "images", "import", "-")
+	// unblock docker save if the import failed before reading everything
+	r.Close()
+	if saveErr := <-done; err == nil {
+		return saveErr
+	}
+	return fmt.Errorf("could not import %s on node %s: %w", image, node, err)
+}
+
+// run runs a command with the given stdin, returning its stderr in the error
+// when it fails.
+func run(stdin io.Reader, name string, args ...string) error {
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = stdin
+	cmd.Stderr = io.MultiWriter(os.Stderr, stderr)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %w: %s", name, args[0], err, stderr.String())
+	}
+	return nil
+}
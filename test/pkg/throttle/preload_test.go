@@ -0,0 +1,92 @@
+package throttle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/retry"
+)
+
+// fakeDocker is a docker CLI which keeps the images of the host and of the
+// nodes in files of $STATE, fails to pull images named "missing" and consumes
+// the archives it imports.
+const fakeDocker = `#!/bin/sh
+case "$1" in
+exec)
+  shift
+  [ "$1" = "--interactive" ] && shift
+  node=$1
+  shift
+  case "$1" in
+  crictl) grep -qx "$4" "$STATE/$node" 2>/dev/null ;;
+  ctr) image=$(cat) && echo "$image" >> "$STATE/$node" ;;
+  esac ;;
+image) grep -qx "$3" "$STATE/host" 2>/dev/null ;;
+pull)
+  case "$2" in *missing*) echo "toomanyrequests" >&2; exit 1 ;; esac
+  echo "$2" >> "$STATE/pulls"
+  echo "$2" >> "$STATE/host" ;;
+save) echo "$2" ;;
+esac
+`
+
+const fakeKind = `#!/bin/sh
+echo "kind-control-plane kind-worker"
+`
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preload-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, script := range map[string]string{"docker": fakeDocker, "kind": fakeKind} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	defer os.Setenv("STATE", os.Getenv("STATE"))
+	os.Setenv("STATE", dir)
+
+	defer func(b retry.Backoff) { pullBackoff = b }(pullBackoff)
+	pullBackoff = retry.Backoff{Initial: time.Millisecond, Max: time.Millisecond, Factor: 1, Attempts: 2}
+
+	// the control plane has the dex image already, which is on the host too
+	if err := ioutil.WriteFile(filepath.Join(dir, "kind-control-plane"), []byte("dex:v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "host"), []byte("dex:v2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = Load([]string{"dex:v2", "traefik:1.7", "missing:1"}, "kind", 2)
+	if err == nil || !strings.Contains(err.Error(), "could not load 1 of 3 images") || !strings.Contains(err.Error(), "missing:1") {
+		t.Errorf("expected the missing image to fail, got %v", err)
+	}
+	for node, expected := range map[string]string{"kind-control-plane": "dex:v2\ntraefik:1.7\n", "kind-worker": "dex:v2\ntraefik:1.7\n"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines := strings.Split(strings.TrimSpace(string(b)), "\n"); len(lines) != 2 || !strings.Contains(expected, lines[0]) || !strings.Contains(expected, lines[1]) {
+			t.Errorf("expected %s to have the images %q, got %q", node, expected, b)
+		}
+	}
+
+	// a repeated load resumes with the images which are still missing
+	if err := os.Remove(filepath.Join(dir, "pulls")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load([]string{"dex:v2", "traefik:1.7"}, "kind", 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pulls")); !os.IsNotExist(err) {
+		t.Errorf("expected no images to be pulled again, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package throttle
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Pods estimates the number of pods the rendered objects of an addon create
+// once deployed to a cluster with the given number of nodes: the replicas of
+// deployments and statefulsets (default 1), one pod per node for daemonsets and
+// one per job and pod.
+func Pods(objects []render.Object, nodes int) int {
+	pods := 0
+	for _, obj := range objects {
+		switch obj.Kind {
+		case "Deployment", "StatefulSet":
+			pods += replicas(obj)
+		case "DaemonSet":
+			pods += nodes
+		case "Job", "Pod":
+			pods++
+		}
+	}
+	return pods
+}
+
+func replicas(obj render.Object) int {
+	spec, _ := obj.Content["spec"].(map[string]interface{})
+	switch r := spec["replicas"].(type) {
+	case int:
+		return r
+	case float64:
+		return int(r)
+	}
+	return 1
+}
+
+// Waves splits addons into waves which are deployed one after the other, so
+// that no more than maxPods new pods are created at once. Every addon is
+// placed in a wave after the waves of the addons it must follow (requires maps
+// an addon to those), addons which exceed maxPods on their own get a wave of
+// their own.
+func Waves(addons []string, pods map[string]int, requires map[string][]string, maxPods int) [][]string {
+	var waves [][]string
+	var sizes []int
+	waveOf := make(map[string]int)
+
+	for _, addon := range addons {
+		earliest := 0
+		for _, required := range requires[addon] {
+			if w, ok := waveOf[required]; ok && w+1 > earliest {
+				earliest = w + 1
+			}
+		}
+
+		w := earliest
+		for ; w < len(waves); w++ {
+			if sizes[w]+pods[addon] <= maxPods {
+				break
+			}
+		}
+		if w == len(waves) {
+			waves = append(waves, nil)
+			sizes = append(sizes, 0)
+		}
+		waves[w] = append(waves[w], addon)
+		sizes[w] += pods[addon]
+		waveOf[addon] = w
+	}
+
+	return waves
+}
+
+// Prepull pulls images onto every node of the kind cluster of a kubectl
+// client, with at most concurrency pulls at a time, so that the pods of
+// the addons don't all pull their images at once when they are deployed.
+func Prepull(k kubectl.Client, images []string, concurrency int) error {
+	out, err := k.Output("get", "nodes", "--output", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return err
+	}
+	nodes := strings.Fields(string(out))
+
+	var (
+		wg     sync.WaitGroup
+		lock   sync.Mutex
+		errs   []string
+		tokens = make(chan struct{}, concurrency)
+	)
+	for _, node := range nodes {
+		for _, image := range images {
+			wg.Add(1)
+			go func(node, image string) {
+				defer wg.Done()
+				tokens <- struct{}{}
+				defer func() { <-tokens }()
+
+				// kind nodes are docker containers named after the node
+				cmd := exec.Command("docker", "exec", node, "crictl", "pull", image)
+				cmd.Stderr = os.Stderr
+				if err := cmd.Run(); err != nil {
+					lock.Lock()
+					errs = append(errs, fmt.Sprintf("%s on %s: %s", image, node, err))
+					lock.Unlock()
+				}
+			}(node, image)
+		}
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("could not pull images: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
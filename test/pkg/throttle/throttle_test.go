@@ -0,0 +1,36 @@
+package throttle
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+func TestPods(t *testing.T) {
+	objects := []render.Object{
+		{Kind: "Deployment", Content: map[string]interface{}{"spec": map[string]interface{}{"replicas": 3}}},
+		{Kind: "StatefulSet", Content: map[string]interface{}{"spec": map[string]interface{}{}}},
+		{Kind: "DaemonSet", Content: map[string]interface{}{}},
+		{Kind: "Job", Content: map[string]interface{}{}},
+		{Kind: "Service", Content: map[string]interface{}{}},
+	}
+	if expected, actual := 3+1+2+1, Pods(objects, 2); expected != actual {
+		t.Errorf("expected %d pods, got %d", expected, actual)
+	}
+}
+
+func TestWaves(t *testing.T) {
+	addons := []string{"cert-manager", "traefik", "dex", "reloader", "kommander"}
+	pods := map[string]int{"cert-manager": 3, "traefik": 2, "dex": 1, "reloader": 1, "kommander": 12}
+	requires := map[string][]string{"kommander": {"cert-manager"}, "dex": {"traefik"}}
+
+	expected := [][]string{
+		{"cert-manager", "traefik", "reloader"},
+		{"dex"},
+		{"kommander"},
+	}
+	if actual := Waves(addons, pods, requires, 6); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected waves %v, got %v", expected, actual)
+	}
+}
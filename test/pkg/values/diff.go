@@ -0,0 +1,109 @@
+package values
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces the values of keys which look like secrets.
+const Redacted = "<redacted>"
+
+var (
+	// secretKey matches the keys of values which look like secrets, e.g.
+	// adminPassword, clientSecret or privateKey.
+	secretKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|credential|key$)`)
+
+	// referenceKey matches the keys of values which refer to secrets rather
+	// than contain them, e.g. existingSecretName.
+	referenceKey = regexp.MustCompile(`(?i)(name|ref)$`)
+)
+
+// Change is a value which differs from the default values of a chart. Default
+// is nil for values which are not set by default.
+type Change struct {
+	Path    string
+	Default interface{}
+	Value   interface{}
+}
+
+// Diff returns the values which differ from the defaults (the default values
+// of a chart), sorted by path. Maps are compared key by key, all other values
+// (including lists) as a whole, and defaults which values don't set are
+// ignored.
+func Diff(defaults, values map[string]interface{}) ([]Change, error) {
+	normalizedDefaults, err := normalize(defaults)
+	if err != nil {
+		return nil, err
+	}
+	normalizedValues, err := normalize(values)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	diff(normalizedDefaults, normalizedValues, "", &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func diff(defaults, values map[string]interface{}, path string, changes *[]Change) {
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		defaultValue := defaults[key]
+		valueMap, valueIsMap := value.(map[string]interface{})
+		defaultMap, defaultIsMap := defaultValue.(map[string]interface{})
+		switch {
+		case valueIsMap && (defaultIsMap || defaultValue == nil):
+			diff(defaultMap, valueMap, keyPath, changes)
+		case !reflect.DeepEqual(defaultValue, value):
+			*changes = append(*changes, Change{Path: keyPath, Default: defaultValue, Value: value})
+		}
+	}
+}
+
+// normalize converts values to the types of JSON (e.g. numbers to float64),
+// so that values parsed from YAML and JSON compare equal.
+func normalize(values map[string]interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	normalized := make(map[string]interface{})
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// Redact replaces the values (and defaults) of the changes of keys which look
+// like secrets with Redacted.
+func Redact(changes []Change) []Change {
+	redacted := make([]Change, len(changes))
+	for i, change := range changes {
+		redacted[i] = change
+		if !secret(change.Path) {
+			continue
+		}
+		redacted[i].Value = Redacted
+		if change.Default != nil {
+			redacted[i].Default = Redacted
+		}
+	}
+	return redacted
+}
+
+func secret(path string) bool {
+	for _, key := range strings.Split(path, ".") {
+		if secretKey.MatchString(key) && !referenceKey.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package values
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// documentedKey matches the keys in the first column of the values tables of
+// chart READMEs, e.g. | `image.tag` | The image tag | `1.0` |.
+var documentedKey = regexp.MustCompile("^`?([A-Za-z0-9_.\\-\\[\\]]+)`?$")
+
+// Keys returns the sorted dot separated paths of the values which are not
+// maps (or are empty maps), e.g. image.tag.
+func Keys(values map[string]interface{}) []string {
+	var keys []string
+	collectKeys(values, "", &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(values map[string]interface{}, path string, keys *[]string) {
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			collectKeys(m, keyPath, keys)
+			continue
+		}
+		*keys = append(*keys, keyPath)
+	}
+}
+
+// Documentation returns the descriptions of the values documented in the
+// tables of the README of a chart (the first column holds the key, the second
+// its description), keyed by their paths.
+func Documentation(readme []byte) map[string]string {
+	docs := make(map[string]string)
+	for _, line := range strings.Split(string(readme), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+		if len(cells) < 2 {
+			continue
+		}
+		match := documentedKey.FindStringSubmatch(strings.TrimSpace(cells[0]))
+		if match == nil || strings.Trim(match[1], "-") == "" {
+			continue
+		}
+		docs[match[1]] = strings.TrimSpace(cells[1])
+	}
+	return docs
+}
+
+// Reference documents the values of the chart of an addon: the defaults of
+// the chart and the values the addon and the override layers set.
+type Reference struct {
+	Addon string         `json:"addon"`
+	Chart string         `json:"chart"`
+	Keys  []KeyReference `json:"keys"`
+}
+
+// KeyReference documents a key of the values of a chart.
+type KeyReference struct {
+	Path string `json:"path"`
+
+	// Default is the default value of the chart, if the chart has the key.
+	Default interface{} `json:"default,omitempty"`
+
+	// Description is the documentation of the key in the README of the chart.
+	Description string `json:"description,omitempty"`
+
+	// SetBy are the sources which set the key (the addon, or an override
+	// layer), sorted.
+	SetBy []string `json:"setBy,omitempty"`
+
+	// Stale is whether the key is set, but does not exist in the chart.
+	Stale bool `json:"stale,omitempty"`
+}
+
+// NewReference returns the reference of the values of a chart with the given
+// defaults and documentation, and of the values which sources (e.g. "addon"
+// or "overrides/ha") set. Keys set below a default which is free-form (see
+// UnknownKeys) are not stale.
+func NewReference(addon, chart string, defaults map[string]interface{}, docs map[string]string, sources map[string]map[string]interface{}) Reference {
+	keys := make(map[string]*KeyReference)
+	reference := func(path string) *KeyReference {
+		if key, ok := keys[path]; ok {
+			return key
+		}
+		key := &KeyReference{Path: path, Description: docs[path]}
+		if value, ok := Lookup(defaults, path); ok {
+			key.Default = value
+		}
+		keys[path] = key
+		return key
+	}
+	for _, path := range Keys(defaults) {
+		reference(path)
+	}
+
+	for source, set := range sources {
+		unknown := UnknownKeys(set, defaults)
+		for _, path := range Keys(set) {
+			key := reference(path)
+			key.SetBy = append(key.SetBy, source)
+			for _, prefix := range unknown {
+				if path == prefix || strings.HasPrefix(path, prefix+".") {
+					key.Stale = true
+				}
+			}
+		}
+	}
+
+	r := Reference{Addon: addon, Chart: chart, Keys: make([]KeyReference, 0, len(keys))}
+	for _, key := range keys {
+		sort.Strings(key.SetBy)
+		r.Keys = append(r.Keys, *key)
+	}
+	sort.Slice(r.Keys, func(i, j int) bool { return r.Keys[i].Path < r.Keys[j].Path })
+	return r
+}
+
+// Stale returns the keys which are set, but don't exist in the chart.
+func (r Reference) Stale() []KeyReference {
+	var stale []KeyReference
+	for _, key := range r.Keys {
+		if key.Stale {
+			stale = append(stale, key)
+		}
+	}
+	return stale
+}
+
+// WriteMarkdown writes the references as markdown, with a table of the keys of
+// each addon.
+func WriteMarkdown(w io.Writer, references []Reference) error {
+	var b strings.Builder
+	b.WriteString("# Addon Values\n")
+	for _, r := range references {
+		fmt.Fprintf(&b, "\n## %s\n\nChart: %s\n\n", r.Addon, r.Chart)
+		b.WriteString("| Key | Default | Set By | Description |\n")
+		b.WriteString("| --- | ------- | ------ | ----------- |\n")
+		for _, key := range r.Keys {
+			def := ""
+			if key.Default != nil {
+				encoded, err := json.Marshal(key.Default)
+				if err != nil {
+					return err
+				}
+				def = "`" + string(encoded) + "`"
+			}
+			setBy := strings.Join(key.SetBy, ", ")
+			if key.Stale {
+				setBy += " (not in the chart)"
+			}
+			fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", key.Path, escapeCell(def), setBy, escapeCell(key.Description))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
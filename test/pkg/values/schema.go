@@ -0,0 +1,159 @@
+package values
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// schema is the subset of JSON schema used by helm chart values schemas which
+// is validated: types, properties (required and additional), items and enums.
+type schema struct {
+	Type                 interface{}        `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties interface{}        `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+}
+
+// ValidateSchema validates values against a chart's JSON schema and returns a
+// description of every violation found.
+func ValidateSchema(values map[string]interface{}, schemaJSON []byte) ([]string, error) {
+	s := &schema{}
+	if err := json.Unmarshal(schemaJSON, s); err != nil {
+		return nil, fmt.Errorf("could not parse values schema: %w", err)
+	}
+
+	// round trip through JSON so that values have the same representation as
+	// the enums of the schema
+	b, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	s.validate(normalized, "", &violations)
+	sort.Strings(violations)
+
+	return violations, nil
+}
+
+func (s *schema) validate(v interface{}, path string, violations *[]string) {
+	at := path
+	if at == "" {
+		at = "(root)"
+	}
+
+	if types := s.types(); len(types) > 0 && !matchesType(v, types) {
+		*violations = append(*violations, fmt.Sprintf("%s: expected %v, got %s", at, types, describe(v)))
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+			}
+		}
+		if !found {
+			*violations = append(*violations, fmt.Sprintf("%s: %v is not one of %v", at, v, s.Enum))
+		}
+	}
+
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for _, required := range s.Required {
+			if _, ok := value[required]; !ok {
+				*violations = append(*violations, fmt.Sprintf("%s: missing required property %s", at, required))
+			}
+		}
+		for key, child := range value {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if property, ok := s.Properties[key]; ok {
+				property.validate(child, childPath, violations)
+				continue
+			}
+			switch additional := s.AdditionalProperties.(type) {
+			case bool:
+				if !additional {
+					*violations = append(*violations, fmt.Sprintf("%s: additional property is not allowed", childPath))
+				}
+			case map[string]interface{}:
+				b, _ := json.Marshal(additional)
+				additionalSchema := &schema{}
+				if err := json.Unmarshal(b, additionalSchema); err == nil {
+					additionalSchema.validate(child, childPath, violations)
+				}
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				s.Items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	}
+}
+
+func (s *schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var types []string
+		for _, item := range t {
+			if str, ok := item.(string); ok {
+				types = append(types, str)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesType(v interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "null":
+			if v == nil {
+				return true
+			}
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package values
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Requirement is a values path the Kommander UI expects an addon to have, e.g.
+// for one of its configuration screens.
+type Requirement struct {
+	// Path is the dot separated path of the value.
+	Path string `yaml:"path"`
+
+	// Type is the type the value must have (string, number, boolean, object or
+	// array), any type when empty.
+	Type string `yaml:"type"`
+
+	// Screen is the UI screen which uses the value.
+	Screen string `yaml:"screen"`
+}
+
+// LoadRequirements reads the values paths the UI requires of each addon.
+func LoadRequirements(path string) (map[string][]Requirement, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	requirements := make(map[string][]Requirement)
+	if err := yaml.Unmarshal(b, &requirements); err != nil {
+		return nil, fmt.Errorf("could not parse ui values contract %s: %w", path, err)
+	}
+	for addon, addonRequirements := range requirements {
+		for _, r := range addonRequirements {
+			if r.Path == "" {
+				return nil, fmt.Errorf("ui values contract %s has a requirement of addon %s without a path", path, addon)
+			}
+			switch r.Type {
+			case "", "string", "number", "boolean", "object", "array":
+			default:
+				return nil, fmt.Errorf("ui values contract %s requires %s of addon %s to have unknown type %s", path, r.Path, addon, r.Type)
+			}
+		}
+	}
+	return requirements, nil
+}
+
+// Unmet returns a description of every requirement the values don't meet,
+// because the value is missing or has another type.
+func Unmet(values map[string]interface{}, requirements []Requirement) []string {
+	var unmet []string
+	for _, r := range requirements {
+		v, ok := Lookup(values, r.Path)
+		switch {
+		case !ok:
+			unmet = append(unmet, fmt.Sprintf("%s is missing%s", r.Path, usedBy(r)))
+		case r.Type != "" && describe(v) != r.Type:
+			unmet = append(unmet, fmt.Sprintf("%s is %s, expected %s%s", r.Path, describe(v), r.Type, usedBy(r)))
+		}
+	}
+	return unmet
+}
+
+func usedBy(r Requirement) string {
+	if r.Screen == "" {
+		return ""
+	}
+	return " (used by the " + r.Screen + " screen)"
+}
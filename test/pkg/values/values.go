@@ -0,0 +1,104 @@
+package values
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parse parses a YAML values block into JSON compatible values.
+func Parse(values string) (map[string]interface{}, error) {
+	parsed := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
+
+// UnknownKeys returns the paths of the keys set in values which do not exist in
+// the defaults (the default values of a chart). Keys below a default which is
+// empty or not a map (e.g. `annotations: {}`) are free-form and never unknown.
+func UnknownKeys(values, defaults map[string]interface{}) []string {
+	var unknown []string
+	unknownKeys(values, defaults, "", &unknown)
+	sort.Strings(unknown)
+	return unknown
+}
+
+func unknownKeys(values, defaults map[string]interface{}, path string, unknown *[]string) {
+	for key, value := range values {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		defaultValue, ok := defaults[key]
+		if !ok {
+			*unknown = append(*unknown, keyPath)
+			continue
+		}
+
+		valueMap, valueIsMap := value.(map[string]interface{})
+		defaultMap, defaultIsMap := defaultValue.(map[string]interface{})
+		if valueIsMap && defaultIsMap && len(defaultMap) > 0 {
+			unknownKeys(valueMap, defaultMap, keyPath, unknown)
+		}
+	}
+}
+
+func describe(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64, float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// Merge deep merges overlay over base, with maps being merged recursively and
+// all other values of the overlay replacing those of the base. Neither is
+// modified.
+func Merge(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[k] = Merge(baseMap, overlayMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// Lookup returns the value at a dot separated path of keys (e.g.
+// kommander-ui.kubernetesVersionsSelection) and whether it exists.
+func Lookup(values map[string]interface{}, path string) (interface{}, bool) {
+	var v interface{} = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
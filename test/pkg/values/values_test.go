@@ -0,0 +1,210 @@
+package values
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicas":    1,
+		"annotations": map[string]interface{}{},
+		"configInline": map[string]interface{}{
+			"address-pools": []interface{}{},
+		},
+		"image": map[string]interface{}{"repository": "metallb/speaker", "tag": "v0.8.1"},
+	}
+	values := map[string]interface{}{
+		"replicas":     3,
+		"annotations":  map[string]interface{}{"free": "form"},
+		"configInline": map[string]interface{}{"address-pools": []interface{}{"x"}},
+		"image":        map[string]interface{}{"repo": "typo"},
+		"unknown":      true,
+	}
+
+	expected := []string{"image.repo", "unknown"}
+	if actual := UnknownKeys(values, defaults); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected unknown keys %v, got %v", expected, actual)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	schemaJSON := []byte(`{
+		"type": "object",
+		"required": ["image"],
+		"additionalProperties": false,
+		"properties": {
+			"replicas": {"type": "integer"},
+			"pullPolicy": {"type": "string", "enum": ["Always", "IfNotPresent"]},
+			"ports": {"type": "array", "items": {"type": "integer"}},
+			"image": {"type": "object"}
+		}
+	}`)
+
+	values := map[string]interface{}{
+		"replicas":   1.5,
+		"pullPolicy": "Sometimes",
+		"ports":      []interface{}{80, "443"},
+		"extra":      true,
+	}
+
+	violations, err := ValidateSchema(values, schemaJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"(root): missing required property image",
+		"extra: additional property is not allowed",
+		"ports[1]: expected [integer], got string",
+		"pullPolicy: Sometimes is not one of [Always IfNotPresent]",
+		"replicas: expected [integer], got number",
+	}
+	if !reflect.DeepEqual(expected, violations) {
+		t.Fatalf("expected violations %v, got %v", expected, violations)
+	}
+
+	// a partial override satisfies the required properties the defaults set
+	defaults := map[string]interface{}{"image": map[string]interface{}{"tag": "v1"}, "replicas": 1}
+	override := map[string]interface{}{"pullPolicy": "Always"}
+	if violations, err := ValidateSchema(Merge(defaults, override), schemaJSON); err != nil || len(violations) != 0 {
+		t.Errorf("expected no violations of the merged values, got %v (%v)", violations, err)
+	}
+}
+
+func TestUnmet(t *testing.T) {
+	defaults := map[string]interface{}{
+		"kommander-ui": map[string]interface{}{"kubernetesVersionsSelection": "[]", "replicas": 1},
+		"kommander-cluster-lifecycle": map[string]interface{}{
+			"konvoy": map[string]interface{}{"allowUnofficialReleases": false},
+		},
+	}
+	addonValues := map[string]interface{}{
+		"kommander-ui": map[string]interface{}{"kubernetesVersionsSelection": `["1.16.4"]`},
+	}
+	merged := Merge(defaults, addonValues)
+	if v, _ := Lookup(merged, "kommander-ui.replicas"); v != 1 {
+		t.Errorf("expected the defaults to be kept, got %v", v)
+	}
+	if v, _ := Lookup(defaults, "kommander-ui.kubernetesVersionsSelection"); v != "[]" {
+		t.Errorf("expected the defaults not to be modified, got %v", v)
+	}
+
+	requirements := []Requirement{
+		{Path: "kommander-ui.kubernetesVersionsSelection", Type: "string", Screen: "cluster creation"},
+		{Path: "kommander-cluster-lifecycle.konvoy.allowUnofficialReleases", Type: "boolean"},
+		{Path: "kommander-cluster-lifecycle.konvoy", Type: "string"},
+		{Path: "kommander-ui.kubernetesVersions", Screen: "cluster creation"},
+	}
+	expected := []string{
+		"kommander-cluster-lifecycle.konvoy is object, expected string",
+		"kommander-ui.kubernetesVersions is missing (used by the cluster creation screen)",
+	}
+	if unmet := Unmet(merged, requirements); !reflect.DeepEqual(unmet, expected) {
+		t.Errorf("expected unmet requirements %v, got %v", expected, unmet)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicas": 1,
+		"image":    map[string]interface{}{"repository": "traefik", "tag": "1.7.24"},
+		"ssl":      map[string]interface{}{"enabled": false, "defaultKey": "", "secretName": ""},
+		"args":     []interface{}{"--api"},
+		"dashboard": map[string]interface{}{
+			"ingress": map[string]interface{}{"annotations": map[string]interface{}{}},
+		},
+	}
+	computed := map[string]interface{}{
+		"replicas": float64(2),
+		"image":    map[string]interface{}{"repository": "traefik", "tag": "1.7.24"},
+		"ssl":      map[string]interface{}{"enabled": true, "defaultKey": "LS0tLS1CRUdJTi", "secretName": "traefik-tls"},
+		"args":     []interface{}{"--api", "--ping"},
+		"dashboard": map[string]interface{}{
+			"ingress": map[string]interface{}{"annotations": map[string]interface{}{"class": "traefik"}},
+		},
+		"adminPassword": "hunter2",
+	}
+
+	changes, err := Diff(defaults, computed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Change{
+		{Path: "adminPassword", Value: "hunter2"},
+		{Path: "args", Default: []interface{}{"--api"}, Value: []interface{}{"--api", "--ping"}},
+		{Path: "dashboard.ingress.annotations.class", Value: "traefik"},
+		{Path: "replicas", Default: float64(1), Value: float64(2)},
+		{Path: "ssl.defaultKey", Default: "", Value: "LS0tLS1CRUdJTi"},
+		{Path: "ssl.enabled", Default: false, Value: true},
+		{Path: "ssl.secretName", Default: "", Value: "traefik-tls"},
+	}
+	if !reflect.DeepEqual(expected, changes) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+
+	redacted := Redact(changes)
+	expected[0].Value = Redacted
+	expected[4].Default, expected[4].Value = Redacted, Redacted
+	if !reflect.DeepEqual(expected, redacted) {
+		t.Errorf("expected redacted changes %+v, got %+v", expected, redacted)
+	}
+	if changes[0].Value != "hunter2" {
+		t.Errorf("expected the changes not to be modified")
+	}
+}
+
+func TestReference(t *testing.T) {
+	defaults := map[string]interface{}{
+		"replicas":    1,
+		"annotations": map[string]interface{}{},
+		"image":       map[string]interface{}{"repository": "traefik", "tag": "1.7.24"},
+	}
+	readme := []byte(`## Configuration
+
+| Parameter          | Description                 | Default   |
+| ------------------ | --------------------------- | --------- |
+| ` + "`replicas`" + `         | Number of replicas          | ` + "`1`" + `     |
+| image.tag          | Image tag                   | ` + "`1.7.24`" + ` |
+`)
+	docs := Documentation(readme)
+	if docs["replicas"] != "Number of replicas" || docs["image.tag"] != "Image tag" {
+		t.Errorf("expected the documentation of replicas and image.tag, got %v", docs)
+	}
+
+	sources := map[string]map[string]interface{}{
+		"addon":        {"image": map[string]interface{}{"tag": "1.7.26"}, "annotations": map[string]interface{}{"free": "form"}},
+		"overrides/ha": {"replicas": 3, "rbac": map[string]interface{}{"enabled": true}},
+	}
+	r := NewReference("traefik", "traefik 1.86.2", defaults, docs, sources)
+	expected := []KeyReference{
+		{Path: "annotations", Default: map[string]interface{}{}},
+		{Path: "annotations.free", SetBy: []string{"addon"}},
+		{Path: "image.repository", Default: "traefik"},
+		{Path: "image.tag", Default: "1.7.24", Description: "Image tag", SetBy: []string{"addon"}},
+		{Path: "rbac.enabled", SetBy: []string{"overrides/ha"}, Stale: true},
+		{Path: "replicas", Default: 1, Description: "Number of replicas", SetBy: []string{"overrides/ha"}},
+	}
+	if !reflect.DeepEqual(expected, r.Keys) {
+		t.Fatalf("expected keys %+v, got %+v", expected, r.Keys)
+	}
+	if stale := r.Stale(); len(stale) != 1 || stale[0].Path != "rbac.enabled" {
+		t.Errorf("expected rbac.enabled to be stale, got %+v", stale)
+	}
+
+	var b bytes.Buffer
+	if err := WriteMarkdown(&b, []Reference{r}); err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"## traefik",
+		"| `image.tag` | `\"1.7.24\"` | addon | Image tag |",
+		"| `rbac.enabled` |  | overrides/ha (not in the chart) |  |",
+	} {
+		if !strings.Contains(b.String(), row) {
+			t.Errorf("expected the markdown to contain %q, got:\n%s", row, b.String())
+		}
+	}
+}
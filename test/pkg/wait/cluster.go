@@ -0,0 +1,135 @@
+package wait
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// gate is a component of a new cluster which must be ready before anything is
+// applied to it, met when it returns no error. Its arguments select the
+// cluster (e.g. --context).
+type gate struct {
+	name string
+	met  func(k kubectl.Client, args []string) error
+}
+
+// gates are the components of a new cluster which are waited for, in order:
+// the API server, the nodes (their CNI is ready once they are), cluster DNS
+// and the default service account, without which pods can't be created.
+var gates = []gate{
+	{name: "API server", met: func(k kubectl.Client, args []string) error {
+		_, err := k.Output(append(args, "get", "--raw", "/healthz")...)
+		return err
+	}},
+	{name: "nodes", met: func(k kubectl.Client, args []string) error {
+		b, err := k.Output(append(args, "get", "nodes", "--output", "json")...)
+		if err != nil {
+			return err
+		}
+		return nodesReady(b)
+	}},
+	{name: "CoreDNS", met: func(k kubectl.Client, args []string) error {
+		b, err := k.Output(append(args, "get", "deployments", "--namespace", "kube-system", "--selector", "k8s-app=kube-dns", "--output", "json")...)
+		if err != nil {
+			return err
+		}
+		return dnsReady(b)
+	}},
+	{name: "default service account", met: func(k kubectl.Client, args []string) error {
+		_, err := k.Output(append(args, "get", "serviceaccount", "default", "--namespace", "default")...)
+		return err
+	}},
+}
+
+// Cluster waits until the API server, all nodes, cluster DNS and the default
+// service account of a new cluster are ready, returning the error of the first
+// component which is not ready when the timeout expires. The arguments select
+// the cluster, the current context of the client if there are none.
+func Cluster(k kubectl.Client, timeout, interval time.Duration, args ...string) error {
+	deadline := time.Now().Add(timeout)
+	for _, g := range gates {
+		for {
+			err := g.met(k, args)
+			if err == nil {
+				break
+			}
+			if time.Now().Add(interval).After(deadline) {
+				return fmt.Errorf("the %s of the cluster did not become ready within %s: %w", g.name, timeout, err)
+			}
+			time.Sleep(interval)
+		}
+	}
+	return nil
+}
+
+type objectList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas int `json:"readyReplicas"`
+			Conditions    []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// nodesReady returns an error unless there are nodes and all of them are
+// ready.
+func nodesReady(b []byte) error {
+	var nodes objectList
+	if err := json.Unmarshal(b, &nodes); err != nil {
+		return err
+	}
+	if len(nodes.Items) == 0 {
+		return fmt.Errorf("there are no nodes")
+	}
+	var notReady []string
+	for _, node := range nodes.Items {
+		ready := false
+		for _, c := range node.Status.Conditions {
+			if c.Type == "Ready" {
+				ready = c.Status == "True"
+			}
+		}
+		if !ready {
+			notReady = append(notReady, node.Metadata.Name)
+		}
+	}
+	if len(notReady) > 0 {
+		return fmt.Errorf("nodes %s are not ready", strings.Join(notReady, ", "))
+	}
+	return nil
+}
+
+// dnsReady returns an error unless there is a cluster DNS deployment whose
+// replicas are all ready.
+func dnsReady(b []byte) error {
+	var deployments objectList
+	if err := json.Unmarshal(b, &deployments); err != nil {
+		return err
+	}
+	if len(deployments.Items) == 0 {
+		return fmt.Errorf("there is no cluster DNS deployment")
+	}
+	for _, d := range deployments.Items {
+		replicas := 1
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		if d.Status.ReadyReplicas < replicas || replicas == 0 {
+			return fmt.Errorf("%d/%d replicas of deployment %s are ready", d.Status.ReadyReplicas, replicas, d.Metadata.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package wait
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+)
+
+// states are the states conditions wait for by kind of resource.
+var states = map[string]string{
+	"job":        "complete",
+	"crd":        "established",
+	"apiservice": "available",
+	"webhook":    "serving",
+}
+
+// Condition is a state an addon waits for after it was deployed, beyond the
+// rollout of its workloads, of the form "<kind>/[<namespace>/]<name> <state>":
+//
+//	job/<namespace>/<name> complete
+//	crd/<name> established
+//	apiservice/<name> available
+//	webhook/<name> serving
+//
+// Webhooks are validating or mutating webhook configurations, which serve
+// once the services of all their webhooks have ready endpoints.
+type Condition struct {
+	Kind      string
+	Namespace string
+	Name      string
+	State     string
+}
+
+// Parse parses a condition.
+func Parse(s string) (Condition, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return Condition{}, fmt.Errorf("condition %q must be of the form <kind>/[<namespace>/]<name> <state>", s)
+	}
+	ref := strings.Split(fields[0], "/")
+	c := Condition{Kind: strings.ToLower(ref[0]), State: strings.ToLower(fields[1])}
+
+	state, ok := states[c.Kind]
+	if !ok {
+		return Condition{}, fmt.Errorf("unknown kind %q of condition %q, expected job, crd, apiservice or webhook", ref[0], s)
+	}
+	if c.State != state {
+		return Condition{}, fmt.Errorf("condition %q waits for %s %s, which can only be %s", s, c.Kind, fields[1], state)
+	}
+	switch {
+	case c.Kind == "job" && len(ref) == 3:
+		c.Namespace, c.Name = ref[1], ref[2]
+	case c.Kind != "job" && len(ref) == 2:
+		c.Name = ref[1]
+	}
+	if c.Name == "" || (c.Kind == "job" && c.Namespace == "") {
+		if c.Kind == "job" {
+			return Condition{}, fmt.Errorf("job of condition %q must be of the form job/<namespace>/<name>", s)
+		}
+		return Condition{}, fmt.Errorf("resource of condition %q must be of the form %s/<name>", s, c.Kind)
+	}
+	return c, nil
+}
+
+// UnmarshalYAML parses conditions of groups.yaml.
+func (c *Condition) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+func (c Condition) String() string {
+	if c.Namespace != "" {
+		return fmt.Sprintf("%s/%s/%s %s", c.Kind, c.Namespace, c.Name, c.State)
+	}
+	return fmt.Sprintf("%s/%s %s", c.Kind, c.Name, c.State)
+}
+
+// Met returns an error unless the condition is met.
+func (c Condition) Met(k kubectl.Client) error {
+	switch c.Kind {
+	case "job":
+		return conditionTrue(k, "job/"+c.Name, "Complete", "-n", c.Namespace)
+	case "crd":
+		return conditionTrue(k, "crd/"+c.Name, "Established")
+	case "apiservice":
+		return conditionTrue(k, "apiservice/"+c.Name, "Available")
+	default:
+		return webhookServing(k, c.Name)
+	}
+}
+
+// conditionTrue returns an error unless a condition of a resource is true.
+func conditionTrue(k kubectl.Client, ref, condition string, args ...string) error {
+	_, err := k.Output(append(args, "wait", ref, "--for", "condition="+condition, "--timeout", "1s")...)
+	return err
+}
+
+// webhookServing returns an error unless the services of all webhooks of the
+// validating or mutating webhook configuration with the given name have ready
+// endpoints. Webhooks called by URL are not checked.
+func webhookServing(k kubectl.Client, name string) error {
+	const services = `{range .webhooks[*]}{.clientConfig.service.namespace}/{.clientConfig.service.name}{"\n"}{end}`
+
+	var out []byte
+	var err error
+	for _, kind := range []string{"validatingwebhookconfiguration", "mutatingwebhookconfiguration"} {
+		if out, err = k.Output("get", kind, name, "--ignore-not-found", "-o", "jsonpath="+services); err != nil {
+			return err
+		}
+		if len(out) > 0 {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return fmt.Errorf("there is no webhook configuration %s", name)
+	}
+
+	for _, service := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		ref := strings.SplitN(service, "/", 2)
+		if len(ref) != 2 || ref[1] == "" {
+			continue
+		}
+		addresses, err := k.Output("-n", ref[0], "get", "endpoints", ref[1], "-o", "jsonpath={.subsets[*].addresses[*].ip}")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(addresses)) == "" {
+			return fmt.Errorf("service %s of webhook configuration %s has no ready endpoints", service, name)
+		}
+	}
+	return nil
+}
+
+// Until waits until all conditions are met, returning the error of the first
+// condition which is not met when the timeout expires.
+func Until(k kubectl.Client, conditions []Condition, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, c := range conditions {
+		for {
+			err := c.Met(k)
+			if err == nil {
+				break
+			}
+			if time.Now().Add(interval).After(deadline) {
+				return fmt.Errorf("%s was not met within %s: %w", c, timeout, err)
+			}
+			time.Sleep(interval)
+		}
+	}
+	return nil
+}
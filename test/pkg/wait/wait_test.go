@@ -0,0 +1,70 @@
+package wait
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		in       string
+		expected Condition
+		invalid  bool
+	}{
+		{in: "crd/prometheuses.monitoring.coreos.com established", expected: Condition{Kind: "crd", Name: "prometheuses.monitoring.coreos.com", State: "established"}},
+		{in: "APIService/v1beta1.metrics.k8s.io Available", expected: Condition{Kind: "apiservice", Name: "v1beta1.metrics.k8s.io", State: "available"}},
+		{in: "job/kubeaddons/kommander-kubeaddons-hook complete", expected: Condition{Kind: "job", Namespace: "kubeaddons", Name: "kommander-kubeaddons-hook", State: "complete"}},
+		{in: "webhook/cert-manager-webhook serving", expected: Condition{Kind: "webhook", Name: "cert-manager-webhook", State: "serving"}},
+		{in: "job/kommander-kubeaddons-hook complete", invalid: true},
+		{in: "crd/kubeaddons/addons.kubeaddons.mesosphere.io established", invalid: true},
+		{in: "crd/addons.kubeaddons.mesosphere.io available", invalid: true},
+		{in: "deployment/grafana ready", invalid: true},
+		{in: "crd/ established", invalid: true},
+		{in: "webhook/cert-manager-webhook", invalid: true},
+	} {
+		actual, err := Parse(tc.in)
+		if tc.invalid {
+			if err == nil {
+				t.Errorf("expected %q to be invalid", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tc.in, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("expected %q to parse as %+v, got %+v", tc.in, tc.expected, actual)
+		}
+		if reparsed, err := Parse(actual.String()); err != nil || reparsed != actual {
+			t.Errorf("expected %s to parse as itself, got %+v and %v", actual, reparsed, err)
+		}
+	}
+}
+
+func TestNodesReady(t *testing.T) {
+	for nodes, expected := range map[string]string{
+		`{"items": []}`: "there are no nodes",
+		`{"items": [
+			{"metadata": {"name": "kind-control-plane"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}},
+			{"metadata": {"name": "kind-worker"}, "status": {"conditions": [{"type": "Ready", "status": "False"}]}},
+			{"metadata": {"name": "kind-worker2"}, "status": {}}
+		]}`: "nodes kind-worker, kind-worker2 are not ready",
+		`{"items": [{"metadata": {"name": "kind-control-plane"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}}]}`: "",
+	} {
+		err := nodesReady([]byte(nodes))
+		if (expected == "" && err != nil) || (expected != "" && (err == nil || err.Error() != expected)) {
+			t.Errorf("expected %q, got %v", expected, err)
+		}
+	}
+}
+
+func TestDNSReady(t *testing.T) {
+	for deployments, expected := range map[string]string{
+		`{"items": []}`: "there is no cluster DNS deployment",
+		`{"items": [{"metadata": {"name": "coredns"}, "spec": {"replicas": 2}, "status": {"readyReplicas": 1}}]}`: "1/2 replicas of deployment coredns are ready",
+		`{"items": [{"metadata": {"name": "coredns"}, "spec": {"replicas": 2}, "status": {"readyReplicas": 2}}]}`: "",
+	} {
+		err := dnsReady([]byte(deployments))
+		if (expected == "" && err != nil) || (expected != "" && (err == nil || err.Error() != expected)) {
+			t.Errorf("expected %q, got %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot is the modification time of every file in a set of directories.
+type Snapshot map[string]time.Time
+
+// Take takes a snapshot of the files in the directories. Directories which do
+// not exist contain no files.
+func Take(dirs ...string) (Snapshot, error) {
+	snapshot := make(Snapshot)
+	for _, dir := range dirs {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if !info.IsDir() {
+				snapshot[path] = info.ModTime()
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// Changed returns the files which were added, modified or removed between two
+// snapshots, sorted.
+func Changed(before, after Snapshot) []string {
+	var changed []string
+	for path, modified := range after {
+		if previous, ok := before[path]; !ok || !previous.Equal(modified) {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Addons returns the addons affected by changed files, sorted: the addon of
+// files in <addonsDir>/<addon>/ and of the value files <overridesDir>/<layer>/<addon>.yaml
+// of the given override layers.
+func Addons(changed []string, addonsDir, overridesDir string, layers []string) []string {
+	layerSet := make(map[string]bool, len(layers))
+	for _, layer := range layers {
+		layerSet[layer] = true
+	}
+
+	set := make(map[string]bool)
+	for _, path := range changed {
+		if rel, ok := relative(addonsDir, path); ok {
+			if parts := strings.Split(rel, "/"); len(parts) > 1 {
+				set[parts[0]] = true
+			}
+			continue
+		}
+		if rel, ok := relative(overridesDir, path); ok {
+			parts := strings.Split(rel, "/")
+			if len(parts) == 2 && layerSet[parts[0]] && strings.HasSuffix(parts[1], ".yaml") {
+				set[strings.TrimSuffix(parts[1], ".yaml")] = true
+			}
+		}
+	}
+
+	addons := make([]string, 0, len(set))
+	for addon := range set {
+		addons = append(addons, addon)
+	}
+	sort.Strings(addons)
+	return addons
+}
+
+func relative(dir, path string) (string, bool) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
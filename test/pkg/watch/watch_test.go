@@ -0,0 +1,61 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChanged(t *testing.T) {
+	now := time.Now()
+	before := Snapshot{"a": now, "b": now, "c": now}
+	after := Snapshot{"a": now, "b": now.Add(time.Second), "d": now}
+
+	expected := []string{"b", "c", "d"}
+	if changed := Changed(before, after); !reflect.DeepEqual(changed, expected) {
+		t.Errorf("expected %v, got %v", expected, changed)
+	}
+}
+
+func TestTake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "kommander", "1.x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "kommander", "1.x", "kommander.yaml")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := Take(dir, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := snapshot[file]; !ok || len(snapshot) != 1 {
+		t.Errorf("expected a snapshot of %s, got %v", file, snapshot)
+	}
+}
+
+func TestAddons(t *testing.T) {
+	changed := []string{
+		"../addons/kommander/1.x/kommander.yaml",
+		"../addons/README.md",
+		"overrides/ha/traefik.yaml",
+		"overrides/ha/README.md",
+		"overrides/kommander-scope-cluster/kommander.yaml",
+		"checks/kommander.yaml",
+	}
+
+	expected := []string{"kommander", "traefik"}
+	if addons := Addons(changed, "../addons", "overrides", []string{"ha"}); !reflect.DeepEqual(addons, expected) {
+		t.Errorf("expected %v, got %v", expected, addons)
+	}
+}
@@ -0,0 +1,172 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+// Injection is a mutating admission webhook which is installed before the
+// addons are deployed, e.g. a sidecar injector or an image rewriter as
+// enterprise clusters run them, so that the addons are deployed with their
+// pods mutated.
+type Injection struct {
+	// Name is the name of the injection, taken from its file name.
+	Name string `yaml:"-"`
+
+	Description string `yaml:"description"`
+
+	// Install is the manifest of the webhook (a file or URL accepted by
+	// `kubectl apply --filename`).
+	Install string `yaml:"install"`
+
+	// Deployments are the deployments of the webhook as
+	// <namespace>/<name>, which must be rolled out before it is configured.
+	Deployments []string `yaml:"deployments"`
+
+	// Configuration are the manifests which configure the mutations of the
+	// webhook, e.g. the policies of a policy engine.
+	Configuration string `yaml:"configuration"`
+
+	// Marker is the annotation the webhook sets on the pods it mutated.
+	Marker string `yaml:"marker"`
+
+	objects []render.Object
+}
+
+// Load reads the injection with the given name from <dir>/<name>.yaml.
+func Load(dir, name string) (*Injection, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, name+".yaml"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("webhook injection %s does not exist in %s", name, dir)
+		}
+		return nil, err
+	}
+
+	i := &Injection{}
+	if err := yaml.UnmarshalStrict(b, i); err != nil {
+		return nil, fmt.Errorf("could not parse webhook injection %s: %w", name, err)
+	}
+	i.Name = name
+
+	if i.Install == "" {
+		return nil, fmt.Errorf("webhook injection %s has no install manifest", name)
+	}
+	if i.Marker == "" {
+		return nil, fmt.Errorf("webhook injection %s has no marker annotation", name)
+	}
+	for _, deployment := range i.Deployments {
+		if parts := strings.Split(deployment, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("webhook injection %s: deployment %q is not <namespace>/<name>", name, deployment)
+		}
+	}
+	if i.objects, err = render.Decode(strings.NewReader(i.Configuration)); err != nil {
+		return nil, fmt.Errorf("could not parse the configuration of webhook injection %s: %w", name, err)
+	}
+	if len(i.objects) == 0 {
+		return nil, fmt.Errorf("webhook injection %s has no configuration", name)
+	}
+	return i, nil
+}
+
+// Apply installs the webhook, waits up to timeout for its deployments to be
+// rolled out and configures its mutations.
+func (i *Injection) Apply(k kubectl.Client, timeout time.Duration) error {
+	if err := k.ApplyFile(i.Install); err != nil {
+		return fmt.Errorf("could not install webhook injection %s: %w", i.Name, err)
+	}
+	for _, deployment := range i.Deployments {
+		parts := strings.Split(deployment, "/")
+		if err := k.Run("rollout", "status", "deployment/"+parts[1], "--namespace", parts[0], "--timeout", timeout.String()); err != nil {
+			return fmt.Errorf("webhook injection %s did not become ready: %w", i.Name, err)
+		}
+	}
+
+	// the CRDs of the configuration may be established by the webhook once it
+	// runs, applying it is retried until they exist
+	b, err := i.manifests()
+	if err != nil {
+		return err
+	}
+	if err := k.Apply(b); err != nil {
+		return fmt.Errorf("could not configure webhook injection %s: %w", i.Name, err)
+	}
+	return nil
+}
+
+// Remove removes the configuration and the webhook.
+func (i *Injection) Remove(k kubectl.Client) error {
+	b, err := i.manifests()
+	if err != nil {
+		return err
+	}
+	if err := k.RunWithInput(b, "delete", "--filename", "-", "--ignore-not-found"); err != nil {
+		return err
+	}
+	return k.Run("delete", "--filename", i.Install, "--ignore-not-found")
+}
+
+func (i *Injection) manifests() ([]byte, error) {
+	items := make([]map[string]interface{}, 0, len(i.objects))
+	for _, obj := range i.objects {
+		items = append(items, obj.Content)
+	}
+	return json.Marshal(map[string]interface{}{"apiVersion": "v1", "kind": "List", "items": items})
+}
+
+// Pods returns the pods in the namespaces which the webhook mutated and those
+// it didn't, by their marker annotation, sorted.
+func (i *Injection) Pods(k kubectl.Client, namespaces []string) ([]string, []string, error) {
+	var mutated, unmutated []string
+	for _, namespace := range namespaces {
+		out, err := k.Output("get", "pods", "--namespace", namespace, "--output", "json")
+		if err != nil {
+			return nil, nil, err
+		}
+		m, u, err := marked(out, i.Marker)
+		if err != nil {
+			return nil, nil, err
+		}
+		mutated, unmutated = append(mutated, m...), append(unmutated, u...)
+	}
+	sort.Strings(mutated)
+	sort.Strings(unmutated)
+	return mutated, unmutated, nil
+}
+
+func marked(b []byte, marker string) ([]string, []string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Namespace   string            `json:"namespace"`
+				Name        string            `json:"name"`
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, nil, fmt.Errorf("could not decode pods: %w", err)
+	}
+
+	var mutated, unmutated []string
+	for _, pod := range list.Items {
+		name := pod.Metadata.Namespace + "/" + pod.Metadata.Name
+		if _, ok := pod.Metadata.Annotations[marker]; ok {
+			mutated = append(mutated, name)
+		} else {
+			unmutated = append(unmutated, name)
+		}
+	}
+	return mutated, unmutated, nil
+}
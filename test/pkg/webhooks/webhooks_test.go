@@ -0,0 +1,84 @@
+package webhooks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "webhooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"valid": `
+install: https://example.com/install.yaml
+deployments: [kyverno/kyverno]
+marker: example.com/mutated
+configuration: |
+  apiVersion: kyverno.io/v1
+  kind: ClusterPolicy
+  metadata:
+    name: mutate
+  ---
+  apiVersion: kyverno.io/v1
+  kind: ClusterPolicy
+  metadata:
+    name: rewrite
+`,
+		"no-install":       "marker: example.com/mutated\nconfiguration: 'kind: ClusterPolicy'\n",
+		"no-marker":        "install: install.yaml\nconfiguration: 'kind: ClusterPolicy'\n",
+		"no-configuration": "install: install.yaml\nmarker: example.com/mutated\n",
+		"bad-deployment":   "install: install.yaml\nmarker: example.com/mutated\ndeployments: [kyverno]\nconfiguration: 'kind: ClusterPolicy'\n",
+		"unknown-field":    "install: install.yaml\nmarker: example.com/mutated\nconfiguration: 'kind: ClusterPolicy'\nnamespaces: [kubeaddons]\n",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	injection, err := Load(dir, "valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if injection.Name != "valid" || len(injection.objects) != 2 {
+		t.Errorf("expected injection valid with 2 objects, got %s with %d", injection.Name, len(injection.objects))
+	}
+
+	for name, expected := range map[string]string{
+		"missing":          "does not exist",
+		"no-install":       "no install manifest",
+		"no-marker":        "no marker annotation",
+		"no-configuration": "no configuration",
+		"bad-deployment":   `deployment "kyverno" is not <namespace>/<name>`,
+		"unknown-field":    "could not parse",
+	} {
+		if _, err := Load(dir, name); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected an error containing %q for %s, got %v", expected, name, err)
+		}
+	}
+}
+
+func TestMarked(t *testing.T) {
+	pods := `{"items": [
+		{"metadata": {"namespace": "kubeaddons", "name": "traefik-1", "annotations": {"example.com/mutated": "injected"}}},
+		{"metadata": {"namespace": "kubeaddons", "name": "hook-1"}},
+		{"metadata": {"namespace": "kubeaddons", "name": "dex-1", "annotations": {"other": "x"}}}
+	]}`
+	mutated, unmutated, err := marked([]byte(pods), "example.com/mutated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"kubeaddons/traefik-1"}; !reflect.DeepEqual(mutated, expected) {
+		t.Errorf("expected mutated pods %v, got %v", expected, mutated)
+	}
+	if expected := []string{"kubeaddons/hook-1", "kubeaddons/dex-1"}; !reflect.DeepEqual(unmutated, expected) {
+		t.Errorf("expected unmutated pods %v, got %v", expected, unmutated)
+	}
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/podsecurity"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const podSecurityFile = "podsecurity.yaml"
+
+// TestValidatePodSecurity fails for every workload of the addons whose pods do
+// not pass the pod security level declared for its namespace (see
+// podsecurity.yaml), and for namespaces rendered by the addons which do not
+// carry the required labels.
+func TestValidatePodSecurity(t *testing.T) {
+	config, err := podsecurity.Load(podSecurityFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var objects []render.Object
+	for _, addon := range addons {
+		rendered, err := render.Addon(addon)
+		if err != nil {
+			t.Fatal(err)
+		}
+		objects = append(objects, rendered...)
+	}
+
+	for _, violation := range config.Check(objects) {
+		t.Error(violation)
+	}
+	for _, obj := range objects {
+		if obj.Kind != "Namespace" {
+			continue
+		}
+		metadata, _ := obj.Content["metadata"].(map[string]interface{})
+		labels := make(map[string]string)
+		if rendered, ok := metadata["labels"].(map[string]interface{}); ok {
+			for k, v := range rendered {
+				labels[k], _ = v.(string)
+			}
+		}
+		for _, label := range podsecurity.MissingLabels(labels, config.Namespace(obj.Name).RequiredLabels()) {
+			t.Errorf("namespace %s of addon %s does not carry the label %s", obj.Name, obj.Addon, label)
+		}
+	}
+}
+
+// verifyNamespaceLabels verifies that the namespaces of the deployed addons
+// carry the labels required by podsecurity.yaml. It only runs when
+// POD_SECURITY=true is set.
+func verifyNamespaceLabels(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface) error {
+	if os.Getenv("POD_SECURITY") != "true" {
+		return nil
+	}
+
+	config, err := podsecurity.Load(podSecurityFile)
+	if err != nil {
+		return err
+	}
+
+	for _, namespace := range addonNamespaces(addons) {
+		labels, err := podsecurity.Labels(k, namespace)
+		if err != nil {
+			return err
+		}
+		for _, label := range podsecurity.MissingLabels(labels, config.Namespace(namespace).RequiredLabels()) {
+			t.Errorf("namespace %s does not carry the label %s", namespace, label)
+		}
+	}
+	return nil
+}
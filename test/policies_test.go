@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/policy"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const gatekeeperManifest = "https://raw.githubusercontent.com/open-policy-agent/gatekeeper/v3.1.0-beta.7/deploy/gatekeeper.yaml"
+
+// auditPolicies installs gatekeeper along with the policies in policies/ and
+// fails the test for every violation of the policies by resources of the addons
+// which rendered the given objects.
+func auditPolicies(t *testing.T, k kubectl.Client, objects []render.Object) error {
+	policies, err := policy.Load("policies")
+	if err != nil {
+		return err
+	}
+	if len(policies.Constraints) == 0 {
+		return nil
+	}
+
+	if err := policies.Install(k, gatekeeperManifest, testConfig.Timeouts.Policy); err != nil {
+		return err
+	}
+
+	violations, truncated, err := policy.Audit(k, testConfig.Timeouts.Policy)
+	if err != nil {
+		return err
+	}
+	for _, constraint := range truncated {
+		logf(t, "WARNING: gatekeeper did not record all violations of %s", constraint)
+	}
+	for _, violation := range policy.Owned(violations, objects) {
+		t.Errorf("policy violation: %s", violation)
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/priority"
+)
+
+const (
+	priorityClassesFile = "priorityclasses.yaml"
+
+	// bulkNamespace is the namespace of the bulk workloads of the preemption
+	// stress test.
+	bulkNamespace = "kommander-bulk"
+
+	// bulkCPU is the cpu every bulk pod requests, in millicores.
+	bulkCPU = 250
+
+	// preemptionSettle is how long the scheduler is given to preempt pods
+	// for the bulk workloads.
+	preemptionSettle = 2 * time.Minute
+)
+
+// TestValidatePriorityClasses fails for every critical workload of the
+// kommander group (see priorityclasses.yaml) which does not run with one of
+// the priority classes of critical workloads, and for classes which are
+// neither builtin nor shipped by an addon of the group.
+func TestValidatePriorityClasses(t *testing.T) {
+	config, err := priority.Load(priorityClassesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := addons(addonTestingGroups.Addons("kommander")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := renderAddons(addons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, violation := range config.Check(objects, names(addons)) {
+		t.Error(violation)
+	}
+}
+
+// verifyPriorityClasses verifies that the priority classes of the critical
+// workloads exist once the addons are deployed. It only runs when
+// PRIORITY_CLASSES=true is set and a critical addon is deployed.
+func verifyPriorityClasses(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface) error {
+	if os.Getenv("PRIORITY_CLASSES") != "true" {
+		return nil
+	}
+	config, err := priority.Load(priorityClassesFile)
+	if err != nil {
+		return err
+	}
+	critical := false
+	for _, name := range names(addons) {
+		_, ok := config.Critical[name]
+		critical = critical || ok
+	}
+	if !critical {
+		return nil
+	}
+
+	missing, err := config.Missing(k)
+	if err != nil {
+		return err
+	}
+	for _, class := range missing {
+		t.Errorf("priority class %s of critical workloads does not exist", class)
+	}
+	return nil
+}
+
+// TestKommanderPreemption deploys the kommander group to a single worker and
+// fills the cluster with bulk workloads of a priority between the default and
+// that of the critical workloads, which the scheduler preempts other pods
+// for. The critical pods of kommander must neither be preempted nor restarted.
+// The test is skipped unless PREEMPTION is true.
+func TestKommanderPreemption(t *testing.T) {
+	if os.Getenv("PREEMPTION") != "true" {
+		t.Skip("PREEMPTION is not true")
+	}
+	config, err := priority.Load(priorityClassesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testscenario(t, "kommander-preemption", func(t *testing.T, k kubectl.Client) error {
+		return testPreemption(t, k, config)
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testPreemption(t *testing.T, k kubectl.Client, config *priority.Config) error {
+	before, err := config.CriticalPods(k)
+	if err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		t.Errorf("no pods run with the priority classes of critical workloads")
+		return nil
+	}
+
+	allocatable, err := priority.AllocatableCPU(k)
+	if err != nil {
+		return err
+	}
+	bulk, err := config.Bulk(bulkNamespace, allocatable, bulkCPU)
+	if err != nil {
+		return err
+	}
+	if err := k.Run("create", "namespace", bulkNamespace); err != nil {
+		return err
+	}
+	defer func() {
+		if err := k.Run("delete", "namespace", bulkNamespace, "--wait", "--timeout", testConfig.Timeouts.Wait.String()); err != nil {
+			logf(t, "WARNING: could not remove the bulk workloads: %s", err)
+		}
+		if err := k.Run("delete", "priorityclass", priority.BulkClass, "--ignore-not-found"); err != nil {
+			logf(t, "WARNING: could not remove priority class %s: %s", priority.BulkClass, err)
+		}
+	}()
+	logf(t, "filling %dm of allocatable cpu with bulk pods requesting %dm each", allocatable, bulkCPU)
+	if err := k.Apply(bulk); err != nil {
+		return err
+	}
+	time.Sleep(preemptionSettle)
+
+	after, err := config.CriticalPods(k)
+	if err != nil {
+		return err
+	}
+	for _, disrupted := range priority.Disrupted(before, after) {
+		t.Error(disrupted)
+	}
+	return nil
+}
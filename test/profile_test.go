@@ -0,0 +1,61 @@
+package test
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/profile"
+)
+
+// applyProfile selects the tests of the profile named in PROFILE (see
+// profiles/) and sets its environment. It returns nil when PROFILE is unset.
+func applyProfile() (*profile.Profile, error) {
+	name := os.Getenv("PROFILE")
+	if name == "" {
+		return nil, nil
+	}
+
+	p, err := profile.Load("profiles", name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Apply(); err != nil {
+		return nil, err
+	}
+
+	if !flag.Parsed() {
+		flag.Parse()
+	}
+	if run := flag.Lookup("test.run"); run != nil && run.Value.String() != "" {
+		fmt.Fprintf(os.Stderr, "WARNING: -run %s is replaced by the tests of profile %s\n", run.Value, p.Name)
+	}
+	if err := flag.Set("test.run", p.Pattern()); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("running profile %s: %s\n", p.Name, p.Description)
+	return p, nil
+}
+
+// signOff writes the sign off of a profile run to dir, signed with the gpg key
+// in RELEASE_SIGNING_KEY when set.
+func signOff(p *profile.Profile, passed bool, dir string) error {
+	revision, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return fmt.Errorf("could not determine the revision: %w", err)
+	}
+
+	path, err := profile.NewSignOff(p, strings.TrimSpace(string(revision)), passed, runReport).Write(dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote sign off of profile %s to %s\n", p.Name, path)
+
+	if key := os.Getenv("RELEASE_SIGNING_KEY"); key != "" {
+		return profile.Sign(path, key)
+	}
+	return nil
+}
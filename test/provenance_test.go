@@ -0,0 +1,77 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provenance"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/repositories"
+)
+
+// unverifiedAddons are the errors verifying the signatures of the addons of
+// the remote catalog, by addon name.
+var unverifiedAddons sync.Map
+
+// catalogVerifier returns the verifier of the signatures of the addons of the
+// remote catalog: gpg with the keyring in CATALOG_GPG_KEYRING or cosign with
+// the public key in CATALOG_COSIGN_KEY. It returns nil when neither is set,
+// and the catalog is not verified.
+func catalogVerifier() provenance.Verifier {
+	if keyring := os.Getenv("CATALOG_GPG_KEYRING"); keyring != "" {
+		return provenance.GPG{Keyring: keyring}
+	}
+	if key := os.Getenv("CATALOG_COSIGN_KEY"); key != "" {
+		return provenance.Cosign{Key: key}
+	}
+	return nil
+}
+
+// verifyCatalog verifies the signatures of the latest revisions of the addons
+// of a remote repository before overrides are applied to them, and records
+// those which fail for verifyProvenance.
+func verifyCatalog(repo repositories.Repository) error {
+	verifier := catalogVerifier()
+	if verifier == nil {
+		return nil
+	}
+	addons, err := repo.ListAddons()
+	if err != nil {
+		return err
+	}
+	for name, revisions := range addons {
+		if len(revisions) == 0 {
+			continue
+		}
+		if err := provenance.Verify(revisions[0], verifier); err != nil {
+			unverifiedAddons.Store(name, err)
+		} else {
+			unverifiedAddons.Delete(name)
+		}
+	}
+	return nil
+}
+
+// verifyProvenance fails when addons of the remote catalog which are about to
+// be deployed are unsigned or don't match their signature.
+func verifyProvenance(t *testing.T, s *scenario.Scenario, addons []v1beta1.AddonInterface) error {
+	if catalogVerifier() == nil {
+		return nil
+	}
+	setPhase(s.Name, "validating provenance")
+	var failures []string
+	for _, addon := range addons {
+		if err, ok := unverifiedAddons.Load(addon.GetName()); ok {
+			failures = append(failures, err.(error).Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("addons of the catalog failed provenance verification:\n%s", strings.Join(failures, "\n"))
+	}
+	logf(t, "verified the provenance of the catalog addons of scenario %s", s.Name)
+	return nil
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/quarantine"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// TestValidateQuarantine verifies the entries of quarantine.yaml: they must not
+// have expired, so that flakes are fixed (or their quarantine deliberately
+// extended) rather than quarantined forever, and they must refer to existing
+// checks, addons and scenarios, so that renaming a check doesn't silently
+// lift its quarantine.
+func TestValidateQuarantine(t *testing.T) {
+	quarantined, err := quarantine.Load("quarantine.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join("checks", "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNames := make(map[string]bool)
+	for _, file := range files {
+		fileChecks, err := checks.Load(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, check := range fileChecks {
+			checkNames[check.Name] = true
+		}
+	}
+	addonNames := make(map[string]bool)
+	for groupname := range addonTestingGroups {
+		for _, name := range addonTestingGroups.Addons(groupname) {
+			addonNames[name] = true
+		}
+	}
+	scenarioNames := make(map[string]bool)
+	names, err := scenario.List("scenarios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		scenarioNames[name] = true
+	}
+
+	for _, entry := range quarantined {
+		if entry.Expired(time.Now()) {
+			t.Errorf("the quarantine of %s expired on %s, fix the check or extend the quarantine: %s", entry, entry.Expires.Format(quarantine.DateFormat), entry.Issue)
+		}
+		if entry.Check != "" && !checkNames[entry.Check] {
+			t.Errorf("quarantine of %s refers to an unknown check", entry)
+		}
+		if entry.Addon != "" && !addonNames[entry.Addon] {
+			t.Errorf("quarantine of %s refers to an addon of no testing group", entry)
+		}
+		if entry.Scenario != "" && !scenarioNames[entry.Scenario] {
+			t.Errorf("quarantine of %s refers to an unknown scenario", entry)
+		}
+	}
+}
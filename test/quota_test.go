@@ -0,0 +1,46 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/quota"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// applyQuotaEnvelope applies the quota envelope named in QUOTA_ENVELOPE (see
+// quotas/) to the namespaces of the addons before they are deployed, and logs
+// the addons which are estimated to exceed it. The returned function removes
+// the envelope again. Nothing is applied when QUOTA_ENVELOPE is unset.
+func applyQuotaEnvelope(t *testing.T, k kubectl.Client, s *scenario.Scenario, addons []v1beta1.AddonInterface, objects []render.Object) (func(), error) {
+	name := os.Getenv("QUOTA_ENVELOPE")
+	if name == "" {
+		return func() {}, nil
+	}
+
+	envelope, err := quota.Load("quotas", name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, excess := range envelope.Check(objects, s.Cluster.ControlPlanes+s.Cluster.Workers) {
+		logf(t, "WARNING: quota envelope %s is exceeded: %s", envelope.Name, excess)
+	}
+
+	namespaces := addonNamespaces(addons)
+
+	setPhase(s.Name, "applying quota envelope")
+	logf(t, "applying quota envelope %s to namespaces %v", envelope.Name, namespaces)
+	if err := envelope.Apply(k, namespaces); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := quota.Remove(k, namespaces); err != nil {
+			logf(t, "WARNING: could not remove quota envelope %s: %s", envelope.Name, err)
+		}
+	}, nil
+}
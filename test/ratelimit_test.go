@@ -0,0 +1,178 @@
+package test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/events"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/images"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/registry"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+)
+
+const (
+	// rateLimitedRegistry is the registry whose pull rate limits addons fall
+	// back to the image mirror for.
+	rateLimitedRegistry = "docker.io"
+
+	// rateLimitInterval is how often the events of the addons of a wave are
+	// checked for rate limited pulls while the kubeaddons controller deploys
+	// them.
+	rateLimitInterval = 15 * time.Second
+)
+
+// mirrorFallback pulls the images of the addons of a scenario from the image
+// mirror of the test configuration (see imageMirror in config.yaml) once
+// their pulls from Docker Hub were rate limited, rewriting their repositories
+// with the values of registries.yaml.
+type mirrorFallback struct {
+	t       *testing.T
+	kubectl kubectl.Client
+	objects []render.Object
+	since   time.Time
+
+	lock sync.Mutex
+
+	// detected are the addons whose pulls were rate limited.
+	detected map[string]bool
+}
+
+func newMirrorFallback(t *testing.T, k kubectl.Client, objects []render.Object, since time.Time) *mirrorFallback {
+	return &mirrorFallback{t: t, kubectl: k, objects: objects, since: since, detected: make(map[string]bool)}
+}
+
+// rateLimitEvents returns the events of failed pulls since the deployment
+// started.
+func (f *mirrorFallback) rateLimitEvents() ([]events.Event, error) {
+	return events.Find(f.kubectl, events.Query{Reasons: []string{"Failed"}, Since: f.since})
+}
+
+// fallBack layers the values which pull the images of an addon from the image
+// mirror over the addon when the events show that pulls of its images from
+// Docker Hub were rate limited, and returns whether it did. Addons fall back
+// once, and only warn when there is no image mirror.
+func (f *mirrorFallback) fallBack(addon v1beta1.AddonInterface, found []events.Event) (bool, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	name := addon.GetName()
+	if f.detected[name] {
+		return false, nil
+	}
+	limited := f.rateLimited(name, found)
+	if len(limited) == 0 {
+		return false, nil
+	}
+	f.detected[name] = true
+
+	if testConfig.ImageMirror == "" {
+		logf(f.t, "WARNING: pulls of %v of addon %s were rate limited, set imageMirror in config.yaml (IMAGE_MIRROR) to fall back to a mirror", limited, name)
+		return false, nil
+	}
+	if addon.GetAddonSpec().ChartReference == nil {
+		logf(f.t, "WARNING: pulls of %v of addon %s were rate limited, which can't fall back to %s without a chart", limited, name, testConfig.ImageMirror)
+		return false, nil
+	}
+
+	config, err := registry.Load(registriesFile)
+	if err != nil {
+		return false, err
+	}
+	current, err := chartValues(addon)
+	if err != nil {
+		return false, err
+	}
+	overlay, paths, err := config.Override(name).Mirror(testConfig.ImageMirror, rateLimitedRegistry, current)
+	if err != nil {
+		return false, err
+	}
+	if len(paths) == 0 {
+		logf(f.t, "WARNING: pulls of %v of addon %s were rate limited, but none of its repositories in %s pull from %s", limited, name, registriesFile, rateLimitedRegistry)
+		return false, nil
+	}
+	if err := layerValues(addon, overlay); err != nil {
+		return false, err
+	}
+	logf(f.t, "pulls of %v of addon %s were rate limited by %s, pulling %v from %s instead", limited, name, rateLimitedRegistry, paths, testConfig.ImageMirror)
+	return true, nil
+}
+
+// rateLimited returns the images of an addon on Docker Hub whose pulls were
+// rate limited according to the events.
+func (f *mirrorFallback) rateLimited(addon string, found []events.Event) []string {
+	var objects []render.Object
+	for _, obj := range f.objects {
+		if obj.Addon == addon {
+			objects = append(objects, obj)
+		}
+	}
+	own := make(map[string]bool)
+	for _, image := range images.Extract(objects) {
+		if ref, err := images.Parse(image); err == nil {
+			own[ref.String()] = true
+		}
+	}
+
+	var limited []string
+	for _, image := range events.RateLimitedImages(found) {
+		if ref, err := images.Parse(image); err == nil && ref.Registry == rateLimitedRegistry && own[ref.String()] {
+			limited = append(limited, image)
+		}
+	}
+	return limited
+}
+
+// watch falls back the addons of a wave while the kubeaddons controller
+// deploys them, applying the addons which fell back again so that the
+// controller upgrades their releases. It returns a function which stops
+// watching, which may be called more than once.
+func (f *mirrorFallback) watch(wave []v1beta1.AddonInterface) func() {
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(rateLimitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			found, err := f.rateLimitEvents()
+			if err != nil {
+				logf(f.t, "WARNING: could not check for rate limited pulls: %s", err)
+				continue
+			}
+			for _, addon := range wave {
+				if err := f.retry(addon, found); err != nil {
+					logf(f.t, "WARNING: addon %s could not fall back to the image mirror: %s", addon.GetName(), err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			<-done
+		})
+	}
+}
+
+// retry applies an addon again once it fell back to the image mirror.
+func (f *mirrorFallback) retry(addon v1beta1.AddonInterface, found []events.Event) error {
+	ok, err := f.fallBack(addon, found)
+	if err != nil || !ok {
+		return err
+	}
+	b, _, err := addonManifest(addon)
+	if err != nil {
+		return err
+	}
+	return f.kubectl.Apply(b)
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/registry"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+const (
+	registriesFile = "registries.yaml"
+
+	// fakeRegistry is the registry the images of the addons are rewritten to,
+	// which never resolves.
+	fakeRegistry = "registry.kommander.invalid:5000"
+)
+
+// TestValidateImagePullPolicies fails for every container of the kommander
+// addons which pulls its image with a pull policy registries.yaml doesn't
+// allow.
+func TestValidateImagePullPolicies(t *testing.T) {
+	config, err := registry.Load(registriesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := addons(addonTestingGroups.Addons("kommander")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, err := renderAddons(addons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, violation := range config.PullPolicyViolations(objects) {
+		t.Error(violation)
+	}
+}
+
+// TestValidateImageRegistries rewrites the images of the kommander addons to a
+// fake registry with the values of registries.yaml and fails for every image
+// which is still pulled from another registry, i.e. which is hardcoded in the
+// templates of a chart and can't be pulled from a private registry.
+func TestValidateImageRegistries(t *testing.T) {
+	config, err := registry.Load(registriesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := addons(addonTestingGroups.Addons("kommander")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addon := range addons {
+		override := config.Override(addon.GetName())
+		ref := addon.GetAddonSpec().ChartReference
+		if ref == nil {
+			continue
+		}
+
+		current := map[string]interface{}{}
+		if len(override.Repositories) > 0 {
+			if current, err = chartValues(addon); err != nil {
+				t.Error(err)
+				continue
+			}
+		}
+		overlay, err := override.Values(fakeRegistry, current)
+		if err != nil {
+			t.Errorf("addon %s (chart %s %s): %s", addon.GetName(), ref.Chart, ref.Version, err)
+			continue
+		}
+		if err := layerValues(addon, overlay); err != nil {
+			t.Error(err)
+			continue
+		}
+
+		objects, err := render.Addon(addon)
+		if err != nil {
+			t.Errorf("could not render addon %s with the registry %s: %s", addon.GetName(), fakeRegistry, err)
+			continue
+		}
+		for _, image := range override.Remaining(objects, fakeRegistry) {
+			t.Errorf("addon %s (chart %s %s) pulls %s, whose registry can not be overridden, see %s", addon.GetName(), ref.Chart, ref.Version, image, registriesFile)
+		}
+	}
+}
+
+// chartValues returns the values of an addon merged over the default values of
+// its chart.
+func chartValues(addon v1beta1.AddonInterface) (map[string]interface{}, error) {
+	ref := addon.GetAddonSpec().ChartReference
+	chart, err := render.PullChart(ref)
+	if err != nil {
+		return nil, fmt.Errorf("could not pull chart %s %s of addon %s: %w", ref.Chart, ref.Version, addon.GetName(), err)
+	}
+	addonValues := map[string]interface{}{}
+	if ref.Values != nil {
+		if addonValues, err = values.Parse(*ref.Values); err != nil {
+			return nil, fmt.Errorf("could not parse the values of addon %s: %w", addon.GetName(), err)
+		}
+	}
+	return values.Merge(chart.Values, addonValues), nil
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/helm"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// recordReleaseValues records the computed values of the helm releases of the
+// deployed addons (after the processing of the kubeaddons controller and all
+// override layers) which differ from the default values of their charts in the
+// run report, with the values of secrets redacted, when RELEASE_VALUES is
+// true. Values which can't be captured are logged.
+func recordReleaseValues(t *testing.T, k kubectl.Client, s *scenario.Scenario, addons []v1beta1.AddonInterface) {
+	if os.Getenv("RELEASE_VALUES") != "true" {
+		return
+	}
+
+	for _, addon := range addons {
+		ref := addon.GetAddonSpec().ChartReference
+		if ref == nil {
+			continue
+		}
+		release, err := releaseValues(k, s, addon, ref)
+		if err != nil {
+			logf(t, "WARNING: could not record the values of addon %s: %s", addon.GetName(), err)
+			continue
+		}
+		logf(t, "addon %s was deployed with %d values which differ from the defaults of chart %s", addon.GetName(), len(release.Changes), release.Chart)
+		runReport.AddValues(release)
+	}
+}
+
+func releaseValues(k kubectl.Client, s *scenario.Scenario, addon v1beta1.AddonInterface, ref *v1beta1.ChartReference) (report.ReleaseValues, error) {
+	release := report.ReleaseValues{Scenario: s.Name, Addon: addon.GetName(), Chart: fmt.Sprintf("%s %s", ref.Chart, ref.Version)}
+
+	chart, err := render.PullChart(ref)
+	if err != nil {
+		return release, err
+	}
+	computed, err := helm.Values(k, addon)
+	if err != nil {
+		return release, err
+	}
+	changes, err := values.Diff(chart.Values, computed)
+	if err != nil {
+		return release, err
+	}
+
+	for _, change := range values.Redact(changes) {
+		value, err := json.Marshal(change.Value)
+		if err != nil {
+			return release, err
+		}
+		recorded := report.ValueChange{Path: change.Path, Value: string(value)}
+		if change.Default != nil {
+			def, err := json.Marshal(change.Default)
+			if err != nil {
+				return release, err
+			}
+			recorded.Default = string(def)
+		}
+		release.Changes = append(release.Changes, recorded)
+	}
+	return release, nil
+}
@@ -0,0 +1,38 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/impact"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// analyzeRemoval logs the resources which still use the given addons before
+// they are removed, warns about resources of one addon which need another addon
+// (removing the needed addon first breaks the dependent one) and logs an order
+// in which the addons can be removed safely.
+func analyzeRemoval(t *testing.T, k kubectl.Client, addons []v1beta1.AddonInterface, objects []render.Object, crds []teardown.CRD) {
+	deps, err := impact.Analyze(k, objects, crds)
+	if err != nil {
+		logf(t, "WARNING: could not analyze the impact of removing the addons: %s", err)
+		return
+	}
+
+	for _, dep := range deps {
+		if dep.Hazard() {
+			logf(t, "WARNING: removal hazard: %s", dep)
+		} else {
+			logf(t, "removal impact: %s", dep)
+		}
+	}
+
+	order, err := impact.RemovalOrder(names(addons), deps)
+	if err != nil {
+		logf(t, "WARNING: no safe removal order: %s", err)
+		return
+	}
+	logf(t, "safe removal order: %v", order)
+}
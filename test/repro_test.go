@@ -0,0 +1,157 @@
+package test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blang/semver"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/container"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/repro"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// writeReproBundle writes the minimal reproduction of a failed scenario to
+// REPORT_DIR/repro/<scenario>.tar.gz (see pkg/repro) when REPRO_BUNDLE=true:
+// the addons it deployed wave by wave (all addons in one wave if it failed
+// before they were ordered), the controller and the kind configuration of its
+// cluster, with a script which recreates the failing state on a fresh kind
+// cluster.
+func writeReproBundle(t *testing.T, k kubectl.Client, s *scenario.Scenario, version semver.Version, arch architecture, addons []v1beta1.AddonInterface, waves [][]v1beta1.AddonInterface) {
+	if os.Getenv("REPRO_BUNDLE") != "true" {
+		return
+	}
+	setPhase(s.Name, "writing reproduction bundle")
+	failure, _ := runReport.Failure(s.Name)
+	if failure.Message == "" {
+		failure.Message = "see the log of the test"
+	}
+
+	if len(waves) == 0 {
+		ordered, err := requirements.Order(addons)
+		if err != nil {
+			ordered = addons
+		}
+		waves = [][]v1beta1.AddonInterface{ordered}
+	}
+	b := &repro.Bundle{
+		Scenario:          s.Name,
+		Phase:             failure.Phase,
+		Failure:           failure.Message,
+		KubernetesVersion: version.String(),
+		NodeImage:         arch.nodeImage(version),
+		Backend:           s.Backend,
+		Timeout:           testConfig.Timeouts.HelmInstall,
+	}
+	for _, wave := range waves {
+		b.Waves = append(b.Waves, names(wave))
+		for _, addon := range wave {
+			a, err := reproAddon(addon)
+			if err != nil {
+				logf(t, "WARNING: could not write the reproduction bundle of scenario %s: %s", s.Name, err)
+				return
+			}
+			b.Addons = append(b.Addons, a)
+		}
+	}
+
+	if err := reproCluster(s, b); err != nil {
+		logf(t, "WARNING: could not write the reproduction bundle of scenario %s: %s", s.Name, err)
+		return
+	}
+	if s.Backend != scenario.HelmBackend {
+		b.ControllerBundle = testConfig.ControllerBundle
+		var err error
+		if b.Controller, b.ControllerImages, err = repro.ExportController(k, "kubeaddons"); err != nil {
+			logf(t, "WARNING: could not export the controller of scenario %s, the reproduction bundle lacks it: %s", s.Name, err)
+		}
+	}
+
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	path := filepath.Join(dir, "repro", s.Name+".tar.gz")
+	if err := b.Write(path); err != nil {
+		logf(t, "WARNING: could not write the reproduction bundle of scenario %s: %s", s.Name, err)
+		return
+	}
+	logf(t, "wrote the reproduction bundle of scenario %s to %s", s.Name, path)
+}
+
+// reproAddon returns an addon of a reproduction bundle, with the values of its
+// chart as they were merged with the override layers of the scenario.
+func reproAddon(addon v1beta1.AddonInterface) (repro.Addon, error) {
+	manifest, kind, err := addonManifest(addon)
+	if err != nil {
+		return repro.Addon{}, err
+	}
+	a := repro.Addon{
+		Name:             addon.GetName(),
+		Kind:             kind,
+		Manifest:         manifest,
+		Release:          render.ReleaseName(addon),
+		ReleaseNamespace: render.Namespace(addon),
+	}
+	if kind == "Addon" {
+		a.Namespace = addon.GetNamespace()
+	}
+	if ref := addon.GetAddonSpec().ChartReference; ref != nil {
+		a.Chart, a.Version = ref.Chart, ref.Version
+		if ref.Repo != nil {
+			a.Repo = *ref.Repo
+		}
+		if ref.Values != nil {
+			a.Values = *ref.Values
+		}
+	}
+	return a, nil
+}
+
+// reproCluster adds the kind configuration of the cluster of a scenario to a
+// reproduction bundle, along with the files it mounts into the nodes.
+func reproCluster(s *scenario.Scenario, b *repro.Bundle) error {
+	dir, err := ioutil.TempDir("", "kubeaddons-repro")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	config, err := kindConfig(s.Cluster, dir)
+	if err != nil {
+		return err
+	}
+	config.Kind, config.APIVersion = "Cluster", "kind.sigs.k8s.io/v1alpha3"
+	if b.KindConfig, err = json.MarshalIndent(config, "", "  "); err != nil {
+		return err
+	}
+	// the mounted files are moved to nodes/ of the extracted bundle
+	hostDir, err := container.HostPath(dir)
+	if err != nil {
+		return err
+	}
+	nodes := repro.Placeholder + "/nodes"
+	b.KindConfig = []byte(strings.Replace(strings.Replace(string(b.KindConfig), hostDir, nodes, -1), dir, nodes, -1))
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	b.NodeFiles = make(map[string][]byte)
+	for _, f := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return err
+		}
+		b.NodeFiles[f.Name()] = content
+	}
+	return nil
+}
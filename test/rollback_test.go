@@ -0,0 +1,82 @@
+package test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+)
+
+// rollbackOnFailure returns whether the addons of a scenario which fail to
+// deploy or to become ready are rolled back, with ROLLBACK_ON_FAILURE=true.
+func rollbackOnFailure() bool {
+	return os.Getenv("ROLLBACK_ON_FAILURE") == "true"
+}
+
+// rollback removes the addons of a scenario which were deployed before its
+// deployment failed, one at a time in the reverse order of their deployment,
+// the way customers have to recover from a failed upgrade, and verifies that
+// the cluster returned to the baseline taken before they were deployed: their
+// objects, the CRDs they don't keep and their monitoring configuration must
+// be gone. The failure and the outcome of the rollback are recorded in the
+// run report, and what the addons left behind fails the test.
+func rollback(t *testing.T, k kubectl.Client, s *scenario.Scenario, failure error, deployed []v1beta1.AddonInterface, objects []render.Object, crds []teardown.CRD, monitoring teardown.Monitoring) {
+	result := report.Rollback{Scenario: s.Name, Failure: runReport.Phase(s.Name), Status: report.Passed}
+	if failure != nil {
+		result.Failure += ": " + failure.Error()
+	}
+
+	setPhase(s.Name, "rolling back")
+	rolledBack := make(map[string]bool, len(deployed))
+	for i := len(deployed) - 1; i >= 0; i-- {
+		addon := deployed[i]
+		logf(t, "rolling back addon %s", addon.GetName())
+		rolledBack[addon.GetName()] = true
+		result.Addons = append(result.Addons, addon.GetName())
+		if err := deleteAddon(k, addon, testConfig.Timeouts.Teardown); err != nil {
+			result.Residue = append(result.Residue, err.Error())
+		}
+	}
+
+	setPhase(s.Name, "verifying rollback")
+	var removedObjects []render.Object
+	for _, obj := range objects {
+		if rolledBack[obj.Addon] {
+			removedObjects = append(removedObjects, obj)
+		}
+	}
+	var removedCRDs []teardown.CRD
+	for _, crd := range crds {
+		if rolledBack[crd.Addon] {
+			removedCRDs = append(removedCRDs, crd)
+		}
+	}
+	residue := func(problems []teardown.Problem, err error) {
+		if err != nil {
+			result.Residue = append(result.Residue, err.Error())
+		}
+		for _, problem := range problems {
+			result.Residue = append(result.Residue, problem.String())
+		}
+	}
+	residue(teardown.VerifyObjects(k, removedObjects, testConfig.Timeouts.Teardown))
+	residue(teardown.Verify(k, removedCRDs, teardown.Namespaces(removedObjects), testConfig.Timeouts.Teardown))
+	if monitoring != nil {
+		residue(teardown.VerifyMonitoring(k, monitoring, removedObjects, testConfig.Timeouts.Teardown))
+	}
+
+	if len(result.Residue) > 0 {
+		result.Status = report.Failed
+	}
+	for _, residue := range result.Residue {
+		t.Errorf("rollback of scenario %s incomplete: %s", s.Name, residue)
+	}
+	logf(t, "rolled back addons %v of scenario %s after it failed in %s: %s", result.Addons, s.Name, result.Failure, result.Status)
+	runReport.AddRollback(result)
+}
@@ -0,0 +1,66 @@
+package test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scaffold"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+)
+
+// TestScaffoldGroups suggests testing groups for the addons of this
+// repository which are not in any group, based on the capabilities they
+// provide and the addons they require, and prints the suggested changes of
+// groups.yaml as a diff. With SCAFFOLD_GROUPS=write groups.yaml is updated
+// instead. Also available as `go run scripts/test-wrapper.go scaffold [-w]`.
+func TestScaffoldGroups(t *testing.T) {
+	mode := os.Getenv("SCAFFOLD_GROUPS")
+	if mode == "" {
+		t.Skip("SCAFFOLD_GROUPS is not set")
+	}
+
+	repo, err := local.NewRepository("base", "../addons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := repo.ListAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalog := make([]scaffold.Addon, 0, len(revisions))
+	for _, addons := range revisions {
+		addon := scaffold.Addon{Name: addons[0].GetName(), Labels: addons[0].GetLabels()}
+		for _, selector := range addons[0].GetAddonSpec().Requires {
+			addon.Requires = append(addon.Requires, selector.MatchLabels)
+		}
+		catalog = append(catalog, addon)
+	}
+
+	suggestions := scaffold.Suggest(addonTestingGroups, catalog)
+	if len(suggestions) == 0 {
+		fmt.Println("every addon is in a testing group")
+		return
+	}
+	for _, s := range suggestions {
+		fmt.Println(s)
+	}
+
+	before, err := ioutil.ReadFile("groups.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := scaffold.Apply(before, suggestions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode == "write" {
+		if err := ioutil.WriteFile("groups.yaml", after, 0644); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Println("updated groups.yaml")
+		return
+	}
+	fmt.Print(scaffold.Diff("test/groups.yaml", before, after))
+}
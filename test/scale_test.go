@@ -0,0 +1,166 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/events"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/faults"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/federation"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scale"
+)
+
+const (
+	// scaleConfig configures the load of TestKommanderScale.
+	scaleConfig = "scale.yaml"
+
+	// scalePrefix prefixes the workspaces and projects created by
+	// TestKommanderScale.
+	scalePrefix = "scale-"
+
+	// kommanderNamespace is the namespace of the controllers of kommander.
+	kommanderNamespace = "kommander"
+)
+
+// TestKommanderScale runs the kommander-scale scenario, which creates the
+// workspaces and projects of scale.yaml through the Kommander API and
+// measures the latency with which the controllers of Kommander reconcile
+// them. The test fails when a latency exceeds its threshold, when an object
+// is not reconciled, when the controllers restarted or when warnings were
+// reported for the objects. The test is skipped unless KOMMANDER_SCALE is
+// true.
+func TestKommanderScale(t *testing.T) {
+	if os.Getenv("KOMMANDER_SCALE") != "true" {
+		t.Skip("KOMMANDER_SCALE is not true")
+	}
+
+	if err := testscenario(t, "kommander-scale", func(t *testing.T, k kubectl.Client) error {
+		return testScale(t, k, "kommander-scale")
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testScale(t *testing.T, k kubectl.Client, scenario string) error {
+	config, err := scale.Load(scaleConfig)
+	if err != nil {
+		return err
+	}
+	before, _, err := faults.CurrentRestarts(k, []string{kommanderNamespace})
+	if err != nil {
+		return err
+	}
+	since := time.Now()
+
+	var (
+		lock      sync.Mutex
+		wg        sync.WaitGroup
+		latencies = make(map[string][]time.Duration)
+		created   []string
+		failures  []error
+	)
+	defer func() {
+		for _, workspace := range created {
+			if err := federation.DeleteWorkspace(k, workspace); err != nil {
+				logf(t, "WARNING: could not delete workspace %s: %s", workspace, err)
+			}
+		}
+	}()
+
+	logf(t, "creating %d workspaces with %d projects each, %d workspaces at a time", config.Workspaces, config.ProjectsPerWorkspace, config.Concurrency)
+	slots := make(chan struct{}, config.Concurrency)
+	for i := 0; i < config.Workspaces; i++ {
+		workspace := fmt.Sprintf("%s%02d", scalePrefix, i)
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			measured, err := createTenant(k, workspace, config.ProjectsPerWorkspace)
+
+			lock.Lock()
+			defer lock.Unlock()
+			created = append(created, workspace)
+			for kind, durations := range measured {
+				latencies[kind] = append(latencies[kind], durations...)
+			}
+			if err != nil {
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var summaries []scale.Summary
+	for _, kind := range []string{scale.Workspace, scale.Project} {
+		summary := scale.Summarize(kind, latencies[kind])
+		logf(t, "reconciled %s", summary)
+		runReport.AddScale(report.ScaleResult{Scenario: scenario, Kind: kind, Count: summary.Count, P50: summary.P50, P95: summary.P95, Max: summary.Max})
+		summaries = append(summaries, summary)
+	}
+	for _, err := range failures {
+		t.Error(err)
+	}
+	for _, exceeded := range config.Exceeded(summaries) {
+		t.Error(exceeded)
+	}
+
+	after, crashLooping, err := faults.CurrentRestarts(k, []string{kommanderNamespace})
+	if err != nil {
+		return err
+	}
+	for _, container := range faults.Increased(before, after) {
+		t.Errorf("container %s restarted under load", container)
+	}
+	for _, container := range crashLooping {
+		t.Errorf("container %s is crash looping under load", container)
+	}
+	for _, kind := range []string{"Workspace", "Project"} {
+		found, err := events.Find(k, events.Query{Kind: kind, Name: scalePrefix, Since: since})
+		if err != nil {
+			return err
+		}
+		for _, e := range found {
+			if e.Type == "Warning" {
+				t.Errorf("warning under load: %s", e)
+			}
+		}
+	}
+	return nil
+}
+
+// createTenant creates a workspace with projects, and returns the latencies
+// with which they were reconciled by kind.
+func createTenant(k kubectl.Client, workspace string, projects int) (map[string][]time.Duration, error) {
+	latencies := make(map[string][]time.Duration)
+	if err := federation.CreateWorkspace(k, workspace, workspace, testConfig.Timeouts.Federation); err != nil {
+		return latencies, err
+	}
+	latency, err := scale.Latency(k, []string{"workspace", workspace}, []string{"namespace", workspace})
+	if err != nil {
+		return latencies, err
+	}
+	latencies[scale.Workspace] = append(latencies[scale.Workspace], latency)
+
+	for i := 0; i < projects; i++ {
+		project := federation.Project{
+			Name:      fmt.Sprintf("%s-%d", workspace, i),
+			Workspace: workspace,
+			Namespace: fmt.Sprintf("%s-%d", workspace, i),
+		}
+		if err := federation.CreateProject(k, project, testConfig.Timeouts.Federation); err != nil {
+			return latencies, err
+		}
+		latency, err := scale.Latency(k, []string{"project", project.Name, "--namespace", workspace}, []string{"federatednamespace", project.Namespace, "--namespace", project.Namespace})
+		if err != nil {
+			return latencies, err
+		}
+		latencies[scale.Project] = append(latencies[scale.Project], latency)
+	}
+	return latencies, nil
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+)
+
+// TestValidateScenarioTests fails when the test of a scenario (see the test
+// field of scenarios) does not run it, or when a test runs a scenario with
+// steps which doesn't name the test, so that it would be run without them.
+func TestValidateScenarioTests(t *testing.T) {
+	steps, err := scenarioSteps()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := scenario.List("scenarios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		s, err := scenario.Load("scenarios", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		test, ok := steps[name]
+		switch {
+		case !s.Standalone() && test != s.Test:
+			t.Errorf("scenario %s names test %s, which does not run it with steps", name, s.Test)
+		case s.Standalone() && ok:
+			t.Errorf("scenario %s is run with steps by %s, but does not name it as its test", name, test)
+		}
+	}
+}
+
+// scenarioSteps maps the scenarios the tests of the package run with steps to
+// the test running them.
+func scenarioSteps() (map[string]string, error) {
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		return nil, err
+	}
+	steps := make(map[string]string)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok || len(call.Args) < 3 {
+					return true
+				}
+				if ident, ok := call.Fun.(*ast.Ident); !ok || ident.Name != "testscenario" {
+					return true
+				}
+				if lit, ok := call.Args[1].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if name, err := strconv.Unquote(lit.Value); err == nil {
+						steps[name] = fn.Name.Name
+					}
+				}
+				return true
+			})
+		}
+	}
+	return steps, nil
+}
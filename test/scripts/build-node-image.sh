@@ -0,0 +1,23 @@
+#!/bin/sh
+# Builds the kind node image <image>:v<kubernetes version> for the architecture
+# of the host with the kind version of go.mod: its base image from the sources
+# of that kind, and the node image from the sources of the kubernetes release.
+#
+# usage: build-node-image.sh <kubernetes version> <image>
+set -eu
+
+version=$1
+image=$2
+
+work=$(mktemp -d)
+trap 'rm -rf "$work"' EXIT
+
+go build -o "$work/kind" sigs.k8s.io/kind
+kind_source=$(go list -m -f '{{.Dir}}' sigs.k8s.io/kind)
+kind_version=$(go list -m -f '{{.Version}}' sigs.k8s.io/kind)
+
+git clone --quiet --depth 1 --branch "v$version" https://github.com/kubernetes/kubernetes "$work/kubernetes"
+
+"$work/kind" build base-image --source "$kind_source/images/base" --image "$image-base:$kind_version"
+"$work/kind" build node-image --type docker --kube-root "$work/kubernetes" \
+	--base-image "$image-base:$kind_version" --image "$image:v$version"
@@ -0,0 +1,43 @@
+#!/bin/sh
+# The entrypoint of the image of the harness (see Dockerfile). It prepares the
+# docker daemon of the mode in HARNESS_CONTAINER and runs its arguments:
+#
+#   dind   - starts a docker daemon in the container, which has to be privileged
+#   socket - uses the docker daemon of the host through /var/run/docker.sock;
+#            files mounted into containers are created in TMPDIR, which has to
+#            be in a directory of the host listed in HOST_PATHS
+set -e
+
+case "${HARNESS_CONTAINER}" in
+dind)
+	dockerd >/var/log/dockerd.log 2>&1 &
+	tries=0
+	until docker info >/dev/null 2>&1; do
+		tries=$((tries + 1))
+		if [ "${tries}" -gt 60 ]; then
+			echo "the docker daemon did not start:" >&2
+			cat /var/log/dockerd.log >&2
+			exit 1
+		fi
+		sleep 1
+	done
+	;;
+socket)
+	if ! docker info >/dev/null 2>&1; then
+		echo "the docker daemon of the host is not reachable, mount /var/run/docker.sock" >&2
+		exit 1
+	fi
+	if [ -z "${HOST_PATHS}" ]; then
+		echo "HOST_PATHS must map the mounted directories to their paths on the host, e.g. /work:/home/ci/kubeaddons-kommander" >&2
+		exit 1
+	fi
+	export TMPDIR="${TMPDIR:-/work/test/tmp}"
+	mkdir -p "${TMPDIR}"
+	;;
+*)
+	echo "HARNESS_CONTAINER must be dind or socket, not '${HARNESS_CONTAINER}'" >&2
+	exit 1
+	;;
+esac
+
+exec "$@"
@@ -6,19 +6,81 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/history"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provenance"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/shard"
 )
 
 type groupName string
 type addonName string
-type groups map[groupName][]addonName
 
 var re = regexp.MustCompile(`^addons/([a-z]+)/?`)
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "list":
+			if err := listGroups(); err != nil {
+				panic(err)
+			}
+			return
+		case "describe":
+			if err := describeGroups(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "scaffold":
+			if err := scaffoldGroups(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "images":
+			if err := imageManifest(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "values":
+			if err := valuesReference(os.Args[2:]); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "payload":
+			if err := payloadCommand(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case "report":
+			if err := reportCommand(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case "history":
+			if err := historyCommand(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		case "shards":
+			if err := shardsCommand(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	modifiedAddons, err := getModifiedAddons()
 	if err != nil {
 		panic(err)
@@ -60,21 +122,17 @@ func getModifiedAddons() ([]addonName, error) {
 }
 
 func getGroupsToTest(modifiedAddons []addonName) ([]groupName, error) {
-	b, err := ioutil.ReadFile("groups.yaml")
+	g, err := groups.Load("groups.yaml")
 	if err != nil {
 		return nil, err
 	}
 
-	g := make(groups)
-	if err := yaml.Unmarshal(b, &g); err != nil {
-		return nil, err
-	}
-
 	testGroups := make([]groupName, 0)
 	for _, modifiedAddonName := range modifiedAddons {
-		for group, addons := range g {
-			for _, name := range addons {
-				if name == modifiedAddonName {
+		for name, addons := range g {
+			group := groupName(name)
+			for _, addon := range addons {
+				if addonName(addon.Name) == modifiedAddonName {
 					exists := false
 					for _, existingGroup := range testGroups {
 						if group == existingGroup {
@@ -95,3 +153,267 @@ func getGroupsToTest(modifiedAddons []addonName) ([]groupName, error) {
 
 	return testGroups, nil
 }
+
+// listGroups prints the names of the testing groups.
+func listGroups() error {
+	g, err := groups.Load("groups.yaml")
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// describeGroups prints the resolved contents of the given testing groups (all
+// groups if none are given), see TestDescribeGroups.
+func describeGroups(names []string) error {
+	describe := "all"
+	if len(names) > 0 {
+		describe = strings.Join(names, ",")
+	}
+
+	cmd := exec.Command("go", "test", "-count", "1", "-run", "^TestDescribeGroups$", ".")
+	cmd.Env = append(os.Environ(), "DESCRIBE_GROUPS="+describe)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// scaffoldGroups prints the suggested testing groups of the addons which are
+// not in any group as a diff of groups.yaml, or updates groups.yaml with -w,
+// see TestScaffoldGroups.
+func scaffoldGroups(args []string) error {
+	mode := "diff"
+	if len(args) > 0 && args[0] == "-w" {
+		mode = "write"
+	}
+
+	cmd := exec.Command("go", "test", "-count", "1", "-run", "^TestScaffoldGroups$", ".")
+	cmd.Env = append(os.Environ(), "SCAFFOLD_GROUPS="+mode)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// imageManifest writes the images required by the testing groups and
+// scenarios to the given file (images.txt if none is given), see
+// TestImageManifest.
+func imageManifest(args []string) error {
+	path := "images.txt"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	cmd := exec.Command("go", "test", "-count", "1", "-run", "^TestImageManifest$", ".")
+	cmd.Env = append(os.Environ(), "IMAGE_MANIFEST="+path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// valuesReference writes the reference of the values of the addons to the
+// given file (values.md if none is given), see TestValuesReference.
+func valuesReference(args []string) error {
+	path := "values.md"
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	cmd := exec.Command("go", "test", "-count", "1", "-run", "^TestValuesReference$", ".")
+	cmd.Env = append(os.Environ(), "VALUES_REFERENCE="+path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// defaultRegressionThreshold is the relative slowdown of a phase reported as a
+// regression by `report diff` when no threshold is given.
+const defaultRegressionThreshold = 0.2
+
+// reportCommand runs a report subcommand: `report diff <base> <head>
+// [threshold]`, which prints the difference between two run reports
+// (report.json), e.g. of master and a pull request, or `report html <report
+// dir> [output]`, which renders a report directory (e.g. downloaded from CI)
+// with the logs of its addons as a single HTML page.
+func reportCommand(args []string) error {
+	if len(args) > 0 && args[0] == "html" {
+		return reportHTML(args[1:])
+	}
+	if len(args) < 3 || len(args) > 4 || args[0] != "diff" {
+		return fmt.Errorf("usage: report <diff <base report.json> <head report.json> [threshold]|html <report dir> [output]>")
+	}
+
+	threshold := defaultRegressionThreshold
+	if len(args) == 4 {
+		var err error
+		if threshold, err = strconv.ParseFloat(args[3], 64); err != nil || threshold < 0 {
+			return fmt.Errorf("the threshold must be a positive number (e.g. 0.2 for 20%%), got %q", args[3])
+		}
+	}
+
+	base, err := report.Load(args[1])
+	if err != nil {
+		return err
+	}
+	head, err := report.Load(args[2])
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(report.Compare(base, head, threshold))
+	return nil
+}
+
+// reportHTML writes the page of the report in a report directory, along with
+// the logs of its addons, to the given file (report.html in the directory if
+// none is given).
+func reportHTML(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: report html <report dir> [output]")
+	}
+	dir := args[0]
+	path := filepath.Join(dir, "report.html")
+	if len(args) == 2 {
+		path = args[1]
+	}
+
+	r, err := report.Load(filepath.Join(dir, "report.json"))
+	if err != nil {
+		return err
+	}
+	logs, err := report.LoadLogs(dir)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := r.WriteHTML(f, logs); err != nil {
+		return err
+	}
+	fmt.Printf("wrote the report of %s with the logs of %d addons to %s\n", dir, len(logs), path)
+	return nil
+}
+
+// defaultHistoryRuns is the number of recent runs the history is summarized
+// over.
+const defaultHistoryRuns = 30
+
+// minFlakyRuns is the least number of runs an addon or check must have been
+// tested in to be reported as flaky.
+const minFlakyRuns = 5
+
+// historyCommand summarizes the last runs of a history of runs (see
+// RUN_HISTORY): the pass rate of every addon and check and the duration of
+// every scenario, the pass rate of an addon or check, or the addons and
+// checks which are flaky, candidates for quarantine.yaml.
+func historyCommand(args []string) error {
+	usage := fmt.Errorf("usage: history <summary|flaky|pass-rate <addon or scenario/check>> <history file or s3 URL> [runs]")
+	if len(args) < 2 {
+		return usage
+	}
+	command, args := args[0], args[1:]
+	name := ""
+	if command == "pass-rate" {
+		name, args = args[0], args[1:]
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return usage
+	}
+
+	last := defaultHistoryRuns
+	if len(args) == 2 {
+		var err error
+		if last, err = strconv.Atoi(args[1]); err != nil || last <= 0 {
+			return fmt.Errorf("the number of runs must be a positive number, got %q", args[1])
+		}
+	}
+	runs, err := history.Open(args[0]).Runs()
+	if err != nil {
+		return err
+	}
+	runs = history.Last(runs, last)
+	addons, checks := history.Addons(runs), history.Checks(runs)
+
+	switch command {
+	case "summary":
+		fmt.Printf("last %d runs\n\n", len(runs))
+		fmt.Print(history.Table(addons, checks, history.Timings(runs)))
+	case "flaky":
+		for _, s := range history.Flaky(append(addons, checks...), minFlakyRuns) {
+			fmt.Println(s)
+		}
+	case "pass-rate":
+		s, ok := history.Lookup(append(addons, checks...), name)
+		if !ok {
+			return fmt.Errorf("%s was not tested in the last %d runs", name, len(runs))
+		}
+		fmt.Println(s)
+	default:
+		return usage
+	}
+	return nil
+}
+
+// shardsCommand prints the scenarios every one of a number of shards runs
+// (see SHARD_INDEX and SHARD_TOTAL), balanced by the durations of the last runs
+// of a history of runs if given, and the estimated duration of every shard:
+// the standalone scenarios it runs with SCENARIO=all and, separately, those it
+// runs by their tests.
+func shardsCommand(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: shards <total> [history file or s3 URL]")
+	}
+	total, err := strconv.Atoi(args[0])
+	if err != nil || total <= 0 {
+		return fmt.Errorf("the number of shards must be a positive number, got %q", args[0])
+	}
+	standalone, stepDriven, err := scenario.ListStandalone("scenarios")
+	if err != nil {
+		return err
+	}
+	var durations map[string]time.Duration
+	if len(args) == 2 {
+		runs, err := history.Open(args[1]).Runs()
+		if err != nil {
+			return err
+		}
+		durations = shard.Durations(history.Last(runs, defaultHistoryRuns))
+	}
+
+	plan := shard.Partition(standalone, durations, total)
+	stepPlan := shard.Partition(stepDriven, durations, total)
+	for i, names := range plan.Shards {
+		estimate := plan.Durations[i] + stepPlan.Durations[i]
+		fmt.Printf("%d\t%s\t%s\t%s\n", i, estimate.Round(time.Minute), strings.Join(names, ","), strings.Join(stepPlan.Shards[i], ","))
+	}
+	return nil
+}
+
+// payloadCommand prints the payload of an addon manifest which is signed for
+// the provenance verification of the catalog (see pkg/provenance).
+func payloadCommand(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: payload <addon manifest>")
+	}
+	manifest, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	payload, err := provenance.ManifestPayload(manifest)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(payload)
+	return err
+}
@@ -0,0 +1,403 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/groups"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/report"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/throttle"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// The TestSelftest tests verify the logic of the harness itself against the
+// stub catalog and cluster in testdata/selftest, without docker, kind or
+// network access, so refactors of the harness can be validated in seconds:
+//
+//   make selftest
+
+const selftestDir = "testdata/selftest"
+
+// selftestAddons returns the addons of the stub catalog.
+func selftestAddons(t *testing.T) []v1beta1.AddonInterface {
+	repo, err := local.NewRepository("selftest", filepath.Join(selftestDir, "addons"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := latestCatalogAddons(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addons
+}
+
+func selftestAddon(t *testing.T, addons []v1beta1.AddonInterface, name string) v1beta1.AddonInterface {
+	for _, addon := range addons {
+		if addon.GetName() == name {
+			return addon
+		}
+	}
+	t.Fatalf("addon %s is not in the stub catalog", name)
+	return nil
+}
+
+func TestSelftestGroups(t *testing.T) {
+	selftestGroups, err := groups.Load(filepath.Join(selftestDir, "groups.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := selftestGroups.Addons("selftest"); !reflect.DeepEqual(names, []string{"selftest-ui", "selftest-app", "selftest-database", "selftest-broken"}) {
+		t.Errorf("unexpected addons of group selftest: %v", names)
+	}
+	skipped := selftestGroups.Skipped("selftest-database")
+	if skipped["selftest-broken"] != "never deployable" || skipped["selftest-database"] == "" {
+		t.Errorf("unexpected skipped addons: %v", skipped)
+	}
+
+	addons := selftestAddons(t)
+	required, err := requirements.Resolve("selftest-ui", addons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := names(required); !reflect.DeepEqual(names, []string{"selftest-database", "selftest-app", "selftest-ui"}) {
+		t.Errorf("expected the requirements of selftest-ui before it, got %v", names)
+	}
+	if _, err := requirements.Resolve("selftest-broken", addons); err == nil {
+		t.Error("expected an error for an addon which is not in the catalog")
+	}
+}
+
+func TestSelftestOverrides(t *testing.T) {
+	addon := selftestAddon(t, selftestAddons(t), "selftest-app")
+	layer, err := scenario.LoadLayer(filepath.Join(selftestDir, "overrides", "ha"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := layerValues(addon, layer["selftest-app"]); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := values.Parse(*addon.GetAddonSpec().ChartReference.Values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"replicas":  3,
+		"resources": map[string]interface{}{"limits": map[string]interface{}{"cpu": "100m", "memory": "256Mi"}},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected the layer to be merged over the values of the addon, got %v", merged)
+	}
+}
+
+func TestSelftestOrdering(t *testing.T) {
+	addons := selftestAddons(t)
+	reversed := []v1beta1.AddonInterface{
+		selftestAddon(t, addons, "selftest-ui"),
+		selftestAddon(t, addons, "selftest-app"),
+		selftestAddon(t, addons, "selftest-database"),
+	}
+	ordered, err := requirements.Order(reversed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := names(ordered); !reflect.DeepEqual(names, []string{"selftest-database", "selftest-app", "selftest-ui"}) {
+		t.Errorf("expected addons after their requirements, got %v", names)
+	}
+
+	requires := map[string][]string{"selftest-app": {"selftest-database"}, "selftest-ui": {"selftest-app"}}
+	pods := map[string]int{"selftest-database": 3, "selftest-app": 3, "selftest-ui": 2}
+	waves := throttle.Waves(names(ordered), pods, requires, 4)
+	if !reflect.DeepEqual(waves, [][]string{{"selftest-database"}, {"selftest-app"}, {"selftest-ui"}}) {
+		t.Errorf("unexpected deployment waves: %v", waves)
+	}
+}
+
+func TestSelftestReport(t *testing.T) {
+	defer func(r *report.Report) { runReport = r }(runReport)
+	runReport = report.New()
+
+	addons := selftestAddons(t)
+	groupOf := map[string]string{"selftest-app": "selftest", "selftest-database": "selftest", "selftest-ui": "selftest"}
+	deployed := deployable(t, "selftest", addons, groupOf, map[string]string{"selftest-database": "SKIP_ADDONS"})
+	if names := names(deployed); len(names) != 2 {
+		t.Errorf("expected selftest-database to be skipped, got %v", names)
+	}
+	for _, addon := range deployed {
+		record(result("selftest", "selftest", addon, report.Passed, ""))
+	}
+	setPhase("selftest", "checking")
+	runReport.Flake("selftest/app is ready", 2)
+
+	dir, err := ioutil.TempDir("", "selftest-report-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := runReport.Write(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written report.Report
+	if err := json.Unmarshal(b, &written); err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Addons) != 3 || written.Flakes["selftest/app is ready"] != 2 || len(written.Phases) != 1 {
+		t.Errorf("unexpected report: %s", b)
+	}
+	if table := runReport.Table(); !strings.Contains(table, "selftest-database") || !strings.Contains(table, "SKIP_ADDONS") {
+		t.Errorf("expected the skipped addon in the table:\n%s", table)
+	}
+	if page, err := ioutil.ReadFile(filepath.Join(dir, "report.html")); err != nil || !strings.Contains(string(page), "SKIP_ADDONS") {
+		t.Errorf("expected the skipped addon in report.html: %v", err)
+	}
+}
+
+func TestSelftestChecks(t *testing.T) {
+	stop, err := startFakeCluster(filepath.Join(selftestDir, "cluster.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	selftestChecks, err := checks.Load(filepath.Join(selftestDir, "checks.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &checks.Env{}
+	for _, check := range selftestChecks {
+		check.Interval = 100 * time.Millisecond
+		err := check.Run(env)
+		if check.Addon == "selftest-ui" {
+			if err == nil {
+				t.Errorf("expected check %q to fail, the deployment is not ready", check.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("check %q failed: %s", check.Name, err)
+		}
+	}
+}
+
+// -----------------------------------------------------------------------------
+// Private - Fake Cluster
+// -----------------------------------------------------------------------------
+
+// startFakeCluster puts a fake kubectl on the PATH which serves the objects
+// in path (a JSON list) to the harness. It is the test binary itself, which
+// runs fakeKubectl instead of the tests (see TestMain). The returned function
+// removes the fake kubectl again.
+func startFakeCluster(path string) (func(), error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	state, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "selftest-kubectl-")
+	if err != nil {
+		return nil, err
+	}
+
+	script := fmt.Sprintf("#!/bin/sh\nFAKE_CLUSTER='%s' exec '%s' \"$@\"\n", state, exe)
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	previous := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+previous)
+	return func() {
+		os.Setenv("PATH", previous)
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// fakeKubectlFlags are the kubectl flags the fake understands which take a
+// value.
+var fakeKubectlFlags = map[string]bool{
+	"--kubeconfig": true, "--context": true, "--namespace": true, "-n": true,
+	"--output": true, "-o": true, "--timeout": true, "--for": true,
+}
+
+// fakeKubectl runs `kubectl get`, `kubectl rollout status` and `kubectl wait`
+// against the objects in the file state, returning the exit code.
+func fakeKubectl(state string, args []string) int {
+	flags := make(map[string]string)
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if kv := strings.SplitN(arg, "=", 2); len(kv) == 2 && fakeKubectlFlags[kv[0]] {
+			flags[kv[0]] = kv[1]
+			continue
+		}
+		if fakeKubectlFlags[arg] && i+1 < len(args) {
+			flags[arg] = args[i+1]
+			i++
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+	namespace := flags["--namespace"]
+	if namespace == "" {
+		namespace = flags["-n"]
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	output := flags["--output"]
+	if output == "" {
+		output = flags["-o"]
+	}
+
+	b, err := ioutil.ReadFile(state)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(b, &objects); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var ref string
+	switch {
+	case len(positional) == 2 && positional[0] == "get":
+		ref = positional[1]
+	case len(positional) == 3 && positional[0] == "rollout" && positional[1] == "status":
+		ref = positional[2]
+	case len(positional) == 2 && positional[0] == "wait":
+		ref = positional[1]
+	default:
+		fmt.Fprintf(os.Stderr, "the fake cluster does not support kubectl %s\n", strings.Join(args, " "))
+		return 1
+	}
+
+	obj := fakeObject(objects, ref, namespace)
+	if obj == nil {
+		fmt.Fprintf(os.Stderr, "Error from server (NotFound): %s not found\n", ref)
+		return 1
+	}
+
+	switch positional[0] {
+	case "rollout":
+		replicas, ok := fakeLookup(obj, "spec.replicas").(float64)
+		if !ok {
+			replicas = 1
+		}
+		if ready, _ := fakeLookup(obj, "status.readyReplicas").(float64); ready < replicas {
+			fmt.Fprintf(os.Stderr, "Waiting for rollout of %s: %v of %v updated replicas are available...\n", ref, ready, replicas)
+			return 1
+		}
+		fmt.Printf("%s successfully rolled out\n", ref)
+	case "wait":
+		conditions, _ := fakeLookup(obj, "status.conditions").([]interface{})
+		for _, c := range conditions {
+			if condition, ok := c.(map[string]interface{}); ok && condition["type"] == "Ready" && condition["status"] == "True" {
+				fmt.Printf("%s condition met\n", ref)
+				return 0
+			}
+		}
+		fmt.Fprintf(os.Stderr, "error: timed out waiting for the condition on %s\n", ref)
+		return 1
+	default:
+		if strings.HasPrefix(output, "jsonpath=") {
+			path := strings.Trim(strings.TrimPrefix(output, "jsonpath="), "{}")
+			fmt.Print(fakeFormat(fakeLookup(obj, strings.TrimPrefix(path, "."))))
+			return 0
+		}
+		out, _ := json.Marshal(obj)
+		fmt.Println(string(out))
+	}
+	return 0
+}
+
+// fakeObject returns the object of a <resource>/<name> reference in a
+// namespace, where resources are kinds in any case, optionally pluralized and
+// qualified with their group. Objects without a namespace are cluster-scoped.
+func fakeObject(objects []map[string]interface{}, ref, namespace string) map[string]interface{} {
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 {
+		return nil
+	}
+	resource := strings.ToLower(strings.SplitN(parts[0], ".", 2)[0])
+	for _, obj := range objects {
+		kind, _ := obj["kind"].(string)
+		kind = strings.ToLower(kind)
+		if resource != kind && resource != kind+"s" {
+			continue
+		}
+		name, _ := fakeLookup(obj, "metadata.name").(string)
+		objNamespace, _ := fakeLookup(obj, "metadata.namespace").(string)
+		if name == parts[1] && (objNamespace == "" || objNamespace == namespace) {
+			return obj
+		}
+	}
+	return nil
+}
+
+// fakeLookup returns the value of a dotted path (e.g. status.conditions[0])
+// in an object.
+func fakeLookup(obj map[string]interface{}, path string) interface{} {
+	var value interface{} = obj
+	for _, field := range strings.Split(path, ".") {
+		index := -1
+		if i := strings.Index(field, "["); i >= 0 && strings.HasSuffix(field, "]") {
+			index, _ = strconv.Atoi(field[i+1 : len(field)-1])
+			field = field[:i]
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[field]
+		if index >= 0 {
+			items, ok := value.([]interface{})
+			if !ok || index >= len(items) {
+				return nil
+			}
+			value = items[index]
+		}
+	}
+	return value
+}
+
+// fakeFormat formats a value like kubectl formats the results of JSONPath
+// expressions.
+func fakeFormat(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/history"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/shard"
+)
+
+// shardHistoryRuns is the number of recent runs of SHARD_HISTORY the
+// durations of the scenarios are estimated from.
+const shardHistoryRuns = 30
+
+var (
+	shardOnce sync.Once
+	shardPlan shard.Plan
+	stepPlan  shard.Plan
+	thisShard shard.Shard
+	shardErr  error
+)
+
+// inShard returns whether a scenario is run by the shard of this run. With
+// SHARD_INDEX and SHARD_TOTAL set, the standalone scenarios of scenarios/ are
+// partitioned across SHARD_TOTAL shards, balanced by their mean durations in
+// the history of runs at SHARD_HISTORY (see RUN_HISTORY) if set, which must be
+// the same for all shards. The scenarios run by tests of their own are
+// partitioned separately, so each of them runs once, by its test. Every
+// scenario is run by this run otherwise.
+func inShard(name string) (bool, error) {
+	shardOnce.Do(func() {
+		if thisShard, shardErr = shard.Parse(os.Getenv("SHARD_INDEX"), os.Getenv("SHARD_TOTAL")); shardErr != nil {
+			return
+		}
+		var standalone, stepDriven []string
+		if standalone, stepDriven, shardErr = scenario.ListStandalone("scenarios"); shardErr != nil {
+			return
+		}
+		var durations map[string]time.Duration
+		if location := os.Getenv("SHARD_HISTORY"); location != "" {
+			runs, err := history.Open(location).Runs()
+			if err != nil {
+				shardErr = fmt.Errorf("could not read the history of runs to shard by: %w", err)
+				return
+			}
+			durations = shard.Durations(history.Last(runs, shardHistoryRuns))
+		}
+		shardPlan = shard.Partition(standalone, durations, thisShard.Total)
+		stepPlan = shard.Partition(stepDriven, durations, thisShard.Total)
+		if thisShard.Total > 1 {
+			estimate := shardPlan.Durations[thisShard.Index] + stepPlan.Durations[thisShard.Index]
+			fmt.Printf("shard %s runs scenarios %v and the tests of scenarios %v (estimated %s)\n", thisShard, shardPlan.Shards[thisShard.Index], stepPlan.Shards[thisShard.Index], estimate.Round(time.Minute))
+		}
+	})
+	if shardErr != nil {
+		return false, shardErr
+	}
+	return shardPlan.Contains(thisShard, name) || stepPlan.Contains(thisShard, name), nil
+}
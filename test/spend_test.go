@@ -0,0 +1,44 @@
+package test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/spend"
+)
+
+// TestSweepCloudResources deletes the cloud resources of test runs in the AWS
+// region AWS_REGION whose deadline passed, e.g. of nightly runs which were
+// killed before they could delete their clusters. It only runs when
+// SWEEP_CLOUD_RESOURCES is true, and only lists the resources when
+// SWEEP_DRY_RUN is true.
+func TestSweepCloudResources(t *testing.T) {
+	if os.Getenv("SWEEP_CLOUD_RESOURCES") != "true" {
+		t.Skip("SWEEP_CLOUD_RESOURCES is not true")
+	}
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		t.Fatal("AWS_REGION is not set")
+	}
+
+	expired, err := spend.Expired(region, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, resource := range expired {
+		if os.Getenv("SWEEP_DRY_RUN") == "true" {
+			logf(t, "would sweep %s (deadline %s)", resource.ARN, resource.Deadline)
+			continue
+		}
+		swept, err := spend.Sweep(region, resource)
+		switch {
+		case err != nil:
+			t.Errorf("could not sweep %s: %s", resource.ARN, err)
+		case swept:
+			logf(t, "swept %s (deadline %s)", resource.ARN, resource.Deadline)
+		default:
+			logf(t, "WARNING: %s is past its deadline %s and has to be removed manually", resource.ARN, resource.Deadline)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/strict"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// addonKinds are the types addon manifests are strictly decoded against.
+var addonKinds = map[string]interface{}{
+	"Addon":        v1beta1.Addon{},
+	"ClusterAddon": v1beta1.ClusterAddon{},
+}
+
+// TestValidateStrictAddons fails for any field in an addon manifest which does
+// not exist in the kubeaddons API (e.g. a misspelled "chartReferance", which
+// would otherwise silently deploy the chart defaults) and reports every
+// offending file and field path.
+func TestValidateStrictAddons(t *testing.T) {
+	var report []string
+	err := filepath.Walk("../addons", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !(strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")) {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		decoder := yaml.NewDecoder(f)
+		for doc := 1; ; doc++ {
+			var content map[string]interface{}
+			if err := decoder.Decode(&content); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+
+			kind, _ := content["kind"].(string)
+			v, ok := addonKinds[kind]
+			if !ok {
+				t.Errorf("%s (document %d): unexpected kind %q", path, doc, kind)
+				continue
+			}
+
+			for _, field := range strict.UnknownFields(content, v) {
+				report = append(report, path+": "+field)
+			}
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report) > 0 {
+		t.Errorf("the following fields in addon manifests are unknown:\n%s", strings.Join(report, "\n"))
+	}
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/teardown"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// renderAddons renders the charts of the given addons.
+func renderAddons(addons []v1beta1.AddonInterface) ([]render.Object, error) {
+	var objects []render.Object
+	for _, addon := range addons {
+		rendered, err := render.Addon(addon)
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, rendered...)
+	}
+	return objects, nil
+}
+
+// logCRDOwners logs the addon which installed each CRD in the cluster, CRDs
+// which none of the given CRDs account for were created at runtime (e.g. by an
+// operator) or existed before the addons were deployed.
+func logCRDOwners(t *testing.T, k kubectl.Client, crds []teardown.CRD) {
+	existing, err := teardown.ClusterCRDs(k)
+	if err != nil {
+		logf(t, "WARNING: could not list CRDs: %s", err)
+		return
+	}
+
+	owners := make(map[string]string, len(crds))
+	for _, crd := range crds {
+		owners[crd.Name] = crd.Addon
+	}
+
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		owner, ok := owners[name]
+		if !ok {
+			owner = "unknown (not shipped by an addon)"
+		}
+		logf(t, "CRD %s is owned by %s", name, owner)
+	}
+}
+
+// verifyTeardown fails the test when CRDs shipped by the addons which are
+// slated for removal remain after cleanup (along with any orphaned custom
+// resources) or when the namespaces of the rendered objects are stuck
+// terminating.
+func verifyTeardown(t *testing.T, k kubectl.Client, groupname string, crds []teardown.CRD, objects []render.Object) {
+	setPhase(groupname, "verifying teardown")
+	problems, err := teardown.Verify(k, crds, teardown.Namespaces(objects), testConfig.Timeouts.Teardown)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, problem := range problems {
+		t.Errorf("teardown of group %s incomplete: %s", groupname, problem)
+	}
+}
+
+// verifyMonitoringTeardown fails the test when the addons left PrometheusRules,
+// ServiceMonitors or grafana dashboards behind after cleanup, or changed the
+// alertmanager configuration, compared to the baseline taken before they were
+// deployed. It does nothing when no baseline was taken.
+func verifyMonitoringTeardown(t *testing.T, k kubectl.Client, groupname string, baseline teardown.Monitoring, objects []render.Object) {
+	if baseline == nil {
+		return
+	}
+	setPhase(groupname, "verifying monitoring teardown")
+	problems, err := teardown.VerifyMonitoring(k, baseline, objects, testConfig.Timeouts.Teardown)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, problem := range problems {
+		t.Errorf("monitoring teardown of group %s incomplete: %s", groupname, problem)
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/telemetry"
+)
+
+// telemetryAudit returns whether the external destinations of the addons are
+// observed, with TELEMETRY_AUDIT=true.
+func telemetryAudit() bool {
+	return os.Getenv("TELEMETRY_AUDIT") == "true"
+}
+
+// observeTelemetry makes CoreDNS log the queries of the cluster before the
+// addons of a scenario, which rendered the given objects, are deployed. The
+// returned function reports the external names each addon resolved since and
+// fails for names the addon isn't allowed to contact (see telemetry.yaml).
+func observeTelemetry(t *testing.T, k kubectl.Client, s *scenario.Scenario, objects []render.Object) (func() error, error) {
+	if !telemetryAudit() {
+		return func() error { return nil }, nil
+	}
+	config, err := telemetry.Load("telemetry.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if err := telemetry.EnableQueryLog(k, testConfig.Timeouts.ClusterReady); err != nil {
+		return nil, fmt.Errorf("could not enable the query log of CoreDNS: %w", err)
+	}
+	start := time.Now()
+
+	return func() error {
+		queries, err := telemetry.Queries(k, start)
+		if err != nil {
+			return err
+		}
+		clients, err := telemetry.Clients(k, objects)
+		if err != nil {
+			return err
+		}
+		destinations := telemetry.Destinations(queries, clients)
+		for addon, names := range destinations {
+			if addon == "" {
+				addon = "pods which aren't part of an addon (any more)"
+			}
+			logf(t, "telemetry: %s resolved %v", addon, names)
+		}
+		if err := writeTelemetry(s, destinations); err != nil {
+			return err
+		}
+
+		violations := config.Violations(destinations)
+		for _, v := range violations {
+			t.Errorf("telemetry: %s, which telemetry.yaml doesn't allow", v)
+		}
+		return nil
+	}, nil
+}
+
+// writeTelemetry writes the external names resolved by the addons of a
+// scenario to REPORT_DIR/telemetry-<scenario>.json.
+func writeTelemetry(s *scenario.Scenario, destinations map[string][]string) error {
+	dir := os.Getenv("REPORT_DIR")
+	if dir == "" {
+		dir = "reports"
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(destinations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "telemetry-"+s.Name+".json"), b, 0644)
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/images"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/provider"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/requirements"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/throttle"
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+)
+
+// defaultImagePulls is the number of concurrent pulls when images are loaded
+// with PRELOAD_IMAGES but MAX_IMAGE_PULLS is unset.
+const defaultImagePulls = 4
+
+// deploymentWaves splits the addons of a scenario into waves which are
+// deployed one after the other, so that no more than MAX_NEW_PODS pods are
+// created at once. All addons are deployed at once when MAX_NEW_PODS is unset,
+// and one after the other when their install isolation is verified.
+func deploymentWaves(t *testing.T, s *scenario.Scenario, addons []v1beta1.AddonInterface, objects []render.Object) ([][]v1beta1.AddonInterface, error) {
+	maxPods, err := envInt("MAX_NEW_PODS")
+	if err != nil || (maxPods == 0 && !isolationAudit()) {
+		return [][]v1beta1.AddonInterface{addons}, err
+	}
+
+	byAddon := make(map[string][]render.Object)
+	for _, obj := range objects {
+		byAddon[obj.Addon] = append(byAddon[obj.Addon], obj)
+	}
+
+	byName := make(map[string]v1beta1.AddonInterface, len(addons))
+	pods := make(map[string]int, len(addons))
+	requires := make(map[string][]string)
+	for _, addon := range addons {
+		byName[addon.GetName()] = addon
+		pods[addon.GetName()] = throttle.Pods(byAddon[addon.GetName()], s.Cluster.ControlPlanes+s.Cluster.Workers)
+		for _, required := range requirements.Required(addon, addons) {
+			requires[addon.GetName()] = append(requires[addon.GetName()], required.GetName())
+		}
+	}
+
+	if isolationAudit() {
+		// every addon gets a wave of its own, so that the changes to the
+		// cluster made by each wave are attributed to a single addon
+		for name := range pods {
+			pods[name] = 1
+		}
+		maxPods = 1
+	}
+
+	var waves [][]v1beta1.AddonInterface
+	for _, wave := range throttle.Waves(names(addons), pods, requires, maxPods) {
+		var waveAddons []v1beta1.AddonInterface
+		podCount := 0
+		for _, name := range wave {
+			waveAddons = append(waveAddons, byName[name])
+			podCount += pods[name]
+		}
+		logf(t, "wave %d creates ~%d pods: %v", len(waves)+1, podCount, wave)
+		waves = append(waves, waveAddons)
+	}
+	return waves, nil
+}
+
+// prepullImages pulls the images of the rendered objects onto the nodes of the
+// cluster before the addons are deployed, with at most MAX_IMAGE_PULLS pulls at
+// a time. Kind clusters are loaded from the host (reusing its docker cache),
+// so that every image is pulled from its registry once rather than by every
+// node, and with PRELOAD_IMAGES=true even without MAX_IMAGE_PULLS. The nodes
+// of other providers pull the images themselves. Nothing is pulled when
+// neither is set.
+func prepullImages(t *testing.T, k kubectl.Client, s *scenario.Scenario, clusterName string, objects []render.Object) error {
+	concurrency, err := envInt("MAX_IMAGE_PULLS")
+	if err != nil {
+		return err
+	}
+	preload := os.Getenv("PRELOAD_IMAGES") == "true"
+	if concurrency == 0 && !preload {
+		return nil
+	}
+
+	if clusterProvider() == provider.Kind {
+		if concurrency == 0 {
+			concurrency = defaultImagePulls
+		}
+		setPhase(s.Name, "loading images")
+		imageNames := images.Extract(objects)
+		logf(t, "loading %d images onto the nodes with at most %d images at a time", len(imageNames), concurrency)
+		return throttle.Load(imageNames, clusterName, concurrency)
+	}
+	if preload && concurrency == 0 {
+		logf(t, "WARNING: not loading images, only kind clusters can load images from the host")
+		return nil
+	}
+
+	if !provider.Has(clusterProvider(), provider.ImagePreload) {
+		logf(t, "WARNING: not pulling images, the %s cluster provider can't preload images", clusterProvider())
+		return nil
+	}
+
+	setPhase(s.Name, "pulling images")
+	imageNames := images.Extract(objects)
+	logf(t, "pulling %d images with at most %d pulls at a time", len(imageNames), concurrency)
+	return throttle.Prepull(k, imageNames, concurrency)
+}
+
+// names returns the names of addons.
+func names(addons []v1beta1.AddonInterface) []string {
+	n := make([]string, 0, len(addons))
+	for _, addon := range addons {
+		n = append(n, addon.GetName())
+	}
+	return n
+}
+
+// envInt returns the value of a numeric environment variable, zero if unset.
+func envInt(name string) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%s must be a positive number, got %q", name, v)
+	}
+	return i, nil
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// TestValidateUIValues verifies that the values of the addons expose the
+// values paths the Kommander UI expects (see ui-values.yaml): the default
+// values of the chart of each addon, merged with the values of the addon, must
+// have every required path with the required type, so that a chart upgrade
+// which removes or renames a key the UI configures fails here instead of in the
+// UI.
+func TestValidateUIValues(t *testing.T) {
+	requirements, err := values.LoadRequirements("ui-values.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addons, err := latestAddons()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(requirements))
+	for name := range requirements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		var found bool
+		for _, addon := range addons {
+			if addon.GetName() != name {
+				continue
+			}
+			found = true
+
+			ref := addon.GetAddonSpec().ChartReference
+			if ref == nil {
+				t.Errorf("addon %s has ui values requirements but does not reference a chart", name)
+				break
+			}
+			chart, err := render.PullChart(ref)
+			if err != nil {
+				t.Errorf("could not pull chart %s %s of addon %s: %s", ref.Chart, ref.Version, name, err)
+				break
+			}
+			addonValues := map[string]interface{}{}
+			if ref.Values != nil {
+				if addonValues, err = values.Parse(*ref.Values); err != nil {
+					t.Errorf("could not parse the values of addon %s: %s", name, err)
+					break
+				}
+			}
+			for _, unmet := range values.Unmet(values.Merge(chart.Values, addonValues), requirements[name]) {
+				t.Errorf("addon %s (chart %s %s): %s", name, ref.Chart, ref.Version, unmet)
+			}
+			break
+		}
+		if !found {
+			t.Errorf("ui-values.yaml has requirements of addon %s, which does not exist", name)
+		}
+	}
+}
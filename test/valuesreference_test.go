@@ -0,0 +1,128 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/render"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/values"
+)
+
+// TestValidateValuesCoverage fails for every key the values of an addon or an
+// override layer (see overrides/) set which does not exist in the chart of
+// the addon anymore, e.g. because a chart upgrade renamed it, which silently
+// makes the chart ignore it.
+func TestValidateValuesCoverage(t *testing.T) {
+	references, err := valuesReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range references {
+		for _, key := range r.Stale() {
+			t.Errorf("addon %s (chart %s): %v set %s, which does not exist in the chart", r.Addon, r.Chart, key.SetBy, key.Path)
+		}
+	}
+}
+
+// TestValuesReference writes the reference of the values of every addon to
+// the file VALUES_REFERENCE ("-" for stdout), as markdown or as JSON when it
+// ends in .json: the keys of the default values of its chart with their
+// documentation in the README of the chart, and the keys the addon and the
+// override layers set, e.g. for support engineers. Also available as `go run
+// scripts/test-wrapper.go values`.
+func TestValuesReference(t *testing.T) {
+	path := os.Getenv("VALUES_REFERENCE")
+	if path == "" {
+		t.Skip("VALUES_REFERENCE is not set")
+	}
+
+	references, err := valuesReferences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(w io.Writer) error {
+		if filepath.Ext(path) == ".json" {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(references)
+		}
+		return values.WriteMarkdown(w, references)
+	}
+	if path == "-" {
+		if err := write(os.Stdout); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := write(f); err != nil {
+		t.Fatal(err)
+	}
+	logf(t, "wrote the values reference of %d addons to %s", len(references), path)
+}
+
+// valuesReferences returns the references of the values of the addons which
+// reference a chart, sorted by addon.
+func valuesReferences() ([]values.Reference, error) {
+	addons, err := latestAddons()
+	if err != nil {
+		return nil, err
+	}
+	layers, err := filepath.Glob(filepath.Join("overrides", "*"))
+	if err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]scenario.Layer, len(layers))
+	for _, dir := range layers {
+		if overrides[filepath.Base(dir)], err = scenario.LoadLayer(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var references []values.Reference
+	for _, addon := range addons {
+		name := addon.GetName()
+		ref := addon.GetAddonSpec().ChartReference
+		if ref == nil {
+			continue
+		}
+
+		sources := make(map[string]map[string]interface{})
+		if ref.Values != nil {
+			if sources["addon"], err = values.Parse(*ref.Values); err != nil {
+				return nil, fmt.Errorf("could not parse the values of addon %s: %w", name, err)
+			}
+		}
+		for layer, layerValues := range overrides {
+			override, ok := layerValues[name]
+			if !ok {
+				continue
+			}
+			source := filepath.Join("overrides", layer)
+			if sources[source], err = values.Parse(override); err != nil {
+				return nil, fmt.Errorf("could not parse the values of addon %s in %s: %w", name, source, err)
+			}
+		}
+
+		chart, err := render.PullChart(ref)
+		if err != nil {
+			return nil, err
+		}
+		chartName := fmt.Sprintf("%s %s", ref.Chart, ref.Version)
+		references = append(references, values.NewReference(name, chartName, chart.Values, values.Documentation(chart.Readme), sources))
+	}
+	sort.Slice(references, func(i, j int) bool { return references[i].Addon < references[j].Addon })
+	return references, nil
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+	"github.com/mesosphere/kubeaddons/pkg/repositories/local"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/checks"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/helm"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/watch"
+)
+
+const watchInterval = 2 * time.Second
+
+// TestWatch deploys the scenario named in WATCH and keeps its cluster until it
+// is interrupted, re-deploying addons whose manifests (in ../addons) or values
+// (in the override layers of the scenario) change and re-running their checks.
+func TestWatch(t *testing.T) {
+	name := os.Getenv("WATCH")
+	if name == "" {
+		t.Skip("WATCH is not set")
+	}
+
+	s, err := scenario.Load("scenarios", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testscenario(t, name, watchStep(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// watchStep returns the step which watches for changes of the addons of a
+// scenario until the test is interrupted.
+func watchStep(s *scenario.Scenario) func(t *testing.T, k kubectl.Client) error {
+	return func(t *testing.T, k kubectl.Client) error {
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		defer signal.Stop(interrupt)
+
+		deployed, _, err := scenarioaddons(s)
+		if err != nil {
+			return err
+		}
+		deployedSet := make(map[string]bool, len(deployed))
+		for _, addon := range deployed {
+			deployedSet[addon.GetName()] = true
+		}
+
+		before, err := watch.Take("../addons", "overrides")
+		if err != nil {
+			return err
+		}
+		setPhase(s.Name, "watching")
+		logf(t, "watching ../addons and overrides for changes, interrupt to clean up")
+
+		ticker := time.NewTicker(watchInterval)
+		defer ticker.Stop()
+		for i := 1; ; {
+			select {
+			case <-interrupt:
+				logf(t, "interrupted, cleaning up")
+				return nil
+			case <-ticker.C:
+			}
+
+			after, err := watch.Take("../addons", "overrides")
+			if err != nil {
+				return err
+			}
+			changed := watch.Changed(before, after)
+			before = after
+
+			var names []string
+			for _, name := range watch.Addons(changed, "../addons", "overrides", s.Overrides) {
+				if deployedSet[name] {
+					names = append(names, name)
+				}
+			}
+			if len(names) == 0 {
+				continue
+			}
+
+			t.Run(fmt.Sprintf("redeploy-%d", i), func(t *testing.T) {
+				if err := redeploy(t, k, s, names); err != nil {
+					t.Error(err)
+				}
+			})
+			i++
+			setPhase(s.Name, "watching")
+		}
+	}
+}
+
+// redeploy reloads the named addons, deploys them again with the backend of
+// the scenario and runs their checks.
+func redeploy(t *testing.T, k kubectl.Client, s *scenario.Scenario, names []string) error {
+	setPhase(s.Name, "redeploying "+strings.Join(names, ", "))
+	logf(t, "redeploying %v", names)
+
+	addons, err := reloadAddons(s, names)
+	if err != nil {
+		return err
+	}
+	for _, addon := range addons {
+		if s.Backend == scenario.HelmBackend {
+			err = helm.Install(k, addon, testConfig.Timeouts.Redeploy)
+		} else {
+			err = applyAddon(k, addon, testConfig.Timeouts.Redeploy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	loaded, err := scenarioChecks(s)
+	if err != nil {
+		return err
+	}
+	nameSet := make(map[string]bool, len(names))
+	for _, name := range names {
+		nameSet[name] = true
+	}
+	var addonChecks []checks.Check
+	for _, check := range loaded {
+		if nameSet[check.Addon] {
+			addonChecks = append(addonChecks, check)
+		}
+	}
+	return runcheckset(t, k, s, addonChecks, skippedAddons())
+}
+
+// reloadAddons reads the named addons again with the CI overrides and the
+// override layers of the scenario applied. Addons of this repository are read
+// from ../addons alone, which is quicker than fetching the whole catalog.
+func reloadAddons(s *scenario.Scenario, names []string) ([]v1beta1.AddonInterface, error) {
+	repo, err := local.NewRepository("base", "../addons")
+	if err != nil {
+		return nil, err
+	}
+	latest, err := latestCatalogAddons(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var reloaded []v1beta1.AddonInterface
+	for _, name := range names {
+		found := false
+		for _, addon := range latest {
+			if addon.GetName() == name {
+				reloaded, found = append(reloaded, addon), true
+			}
+		}
+		if !found {
+			// e.g. a kubernetes base addon whose override layer changed
+			if reloaded, err = addons(names...); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if err := applyLayers(s, reloaded); err != nil {
+		return nil, err
+	}
+	return reloaded, nil
+}
+
+// applyAddon applies an addon for the kubeaddons controller to deploy, and
+// waits for it to be ready again.
+func applyAddon(k kubectl.Client, addon v1beta1.AddonInterface, timeout time.Duration) error {
+	b, kind, err := addonManifest(addon)
+	if err != nil {
+		return err
+	}
+	if err := k.Apply(b); err != nil {
+		return fmt.Errorf("could not apply addon %s: %w", addon.GetName(), err)
+	}
+
+	ref := strings.ToLower(kind) + "/" + addon.GetName()
+	args := []string{"get", ref, "--output", "jsonpath={.status.ready}"}
+	if kind == "Addon" {
+		args = append(args, "--namespace", addon.GetNamespace())
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		out, err := k.Output(args...)
+		if err == nil && strings.TrimSpace(string(out)) == "true" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("addon %s was not ready within %s", addon.GetName(), timeout)
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// addonManifest returns an addon as the object the kubeaddons controller
+// deploys, without its status, and its kind (Addon or ClusterAddon).
+func addonManifest(addon v1beta1.AddonInterface) ([]byte, string, error) {
+	kind := "Addon"
+	if _, ok := addon.(*v1beta1.ClusterAddon); ok {
+		kind = "ClusterAddon"
+	}
+
+	b, err := json.Marshal(addon)
+	if err != nil {
+		return nil, "", err
+	}
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, "", err
+	}
+	obj["apiVersion"] = "kubeaddons.mesosphere.io/v1beta1"
+	obj["kind"] = kind
+	// the status is managed by the controller
+	delete(obj, "status")
+	if b, err = json.Marshal(obj); err != nil {
+		return nil, "", err
+	}
+	return b, kind, nil
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/mesosphere/kubeaddons/pkg/api/v1beta1"
+
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/kubectl"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/scenario"
+	"github.com/mesosphere/kubeaddons-kommander-addons/test/pkg/webhooks"
+)
+
+// injectWebhook installs the mutating webhook named in MUTATING_WEBHOOK (see
+// webhooks/) before the addons are deployed, so that their pods are mutated
+// as in enterprise clusters. The returned functions verify that the webhook
+// mutated pods of the addons once they are deployed, and remove the webhook
+// again. Nothing is installed when MUTATING_WEBHOOK is unset.
+func injectWebhook(t *testing.T, k kubectl.Client, s *scenario.Scenario) (func([]v1beta1.AddonInterface) error, func(), error) {
+	name := os.Getenv("MUTATING_WEBHOOK")
+	if name == "" {
+		return func([]v1beta1.AddonInterface) error { return nil }, func() {}, nil
+	}
+
+	injection, err := webhooks.Load("webhooks", name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setPhase(s.Name, "injecting webhook")
+	logf(t, "installing mutating webhook %s: %s", injection.Name, injection.Description)
+	if err := injection.Apply(k, testConfig.Timeouts.Wait); err != nil {
+		return nil, nil, err
+	}
+
+	verify := func(addons []v1beta1.AddonInterface) error {
+		mutated, unmutated, err := injection.Pods(k, addonNamespaces(addons))
+		if err != nil {
+			return err
+		}
+		if len(mutated) == 0 {
+			return fmt.Errorf("mutating webhook %s did not mutate any pod of the addons", injection.Name)
+		}
+		logf(t, "mutating webhook %s mutated %d pods of the addons, not %v", injection.Name, len(mutated), unmutated)
+		return nil
+	}
+	remove := func() {
+		if err := injection.Remove(k); err != nil {
+			logf(t, "WARNING: could not remove mutating webhook %s: %s", injection.Name, err)
+		}
+	}
+	return verify, remove, nil
+}